@@ -0,0 +1,284 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ContentKind identifies one component of a `content` property value.
+type ContentKind int
+
+const (
+	ContentString ContentKind = iota
+	ContentAttr
+	ContentCounter
+	ContentCounters
+	ContentOpenQuote
+	ContentCloseQuote
+	ContentNoOpenQuote
+	ContentNoCloseQuote
+)
+
+// ContentItem is a parsed component of `content` (CSS Generated Content §2).
+type ContentItem struct {
+	Kind      ContentKind
+	Value     string // string text, attribute name, or counter name
+	Separator string // counters() separator
+	ListStyle string // counter()/counters() list-style-type, empty = decimal
+}
+
+// HasGeneratedContent reports whether a content value generates a box.
+// "normal" and "none" suppress ::before/::after boxes.
+func HasGeneratedContent(value string) bool {
+	v := strings.ToLower(strings.TrimSpace(value))
+	return v != "" && v != "none" && v != "normal"
+}
+
+// ParseContent splits a `content` value into its components. Unknown tokens
+// (e.g. url() images) are skipped.
+func ParseContent(value string) []ContentItem {
+	var items []ContentItem
+	s := strings.TrimSpace(value)
+
+	for len(s) > 0 {
+		switch {
+		case s[0] == '"' || s[0] == '\'':
+			text, rest := parseCSSString(s)
+			items = append(items, ContentItem{Kind: ContentString, Value: text})
+			s = rest
+		case s[0] == ' ' || s[0] == '\t' || s[0] == '\n':
+			s = s[1:]
+		default:
+			end := strings.IndexAny(s, " \t\n\"'(")
+			if end == -1 {
+				end = len(s)
+			}
+			ident := strings.ToLower(s[:end])
+			s = s[end:]
+
+			if strings.HasPrefix(s, "(") {
+				closeIdx := strings.Index(s, ")")
+				if closeIdx == -1 {
+					closeIdx = len(s) - 1
+				}
+				args := splitContentArgs(s[1:closeIdx])
+				s = s[closeIdx+1:]
+				if item, ok := contentFunction(ident, args); ok {
+					items = append(items, item)
+				}
+				continue
+			}
+
+			switch ident {
+			case "open-quote":
+				items = append(items, ContentItem{Kind: ContentOpenQuote})
+			case "close-quote":
+				items = append(items, ContentItem{Kind: ContentCloseQuote})
+			case "no-open-quote":
+				items = append(items, ContentItem{Kind: ContentNoOpenQuote})
+			case "no-close-quote":
+				items = append(items, ContentItem{Kind: ContentNoCloseQuote})
+			}
+		}
+	}
+	return items
+}
+
+// contentFunction builds an item for attr(), counter() and counters().
+func contentFunction(name string, args []string) (ContentItem, bool) {
+	switch name {
+	case "attr":
+		if len(args) >= 1 && args[0] != "" {
+			return ContentItem{Kind: ContentAttr, Value: strings.ToLower(args[0])}, true
+		}
+	case "counter":
+		if len(args) >= 1 && args[0] != "" {
+			item := ContentItem{Kind: ContentCounter, Value: args[0]}
+			if len(args) >= 2 {
+				item.ListStyle = strings.ToLower(args[1])
+			}
+			return item, true
+		}
+	case "counters":
+		if len(args) >= 2 && args[0] != "" {
+			item := ContentItem{Kind: ContentCounters, Value: args[0], Separator: unquoteCSSString(args[1])}
+			if len(args) >= 3 {
+				item.ListStyle = strings.ToLower(args[2])
+			}
+			return item, true
+		}
+	}
+	return ContentItem{}, false
+}
+
+// splitContentArgs splits function arguments on commas outside of quotes.
+func splitContentArgs(s string) []string {
+	var args []string
+	var current strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			current.WriteByte(c)
+		case c == '"' || c == '\'':
+			quote = c
+			current.WriteByte(c)
+		case c == ',':
+			args = append(args, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	args = append(args, strings.TrimSpace(current.String()))
+	return args
+}
+
+// unquoteCSSString returns the text of a quoted CSS string, or s unchanged.
+func unquoteCSSString(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') {
+		text, _ := parseCSSString(s)
+		return text
+	}
+	return s
+}
+
+// parseCSSString reads a quoted string starting at s[0], resolving escapes
+// (CSS Syntax §4.3.7: "\" + 1–6 hex digits + optional space, or "\" + char).
+// Returns the text and the remainder after the closing quote.
+func parseCSSString(s string) (string, string) {
+	quote := s[0]
+	var sb strings.Builder
+	i := 1
+	for i < len(s) {
+		c := s[i]
+		if c == quote {
+			return sb.String(), s[i+1:]
+		}
+		if c == '\\' && i+1 < len(s) {
+			j := i + 1
+			for j < len(s) && j-i <= 6 && isHexDigit(s[j]) {
+				j++
+			}
+			if j > i+1 {
+				if code, err := strconv.ParseUint(s[i+1:j], 16, 32); err == nil && code != 0 {
+					sb.WriteRune(rune(code))
+				}
+				if j < len(s) && s[j] == ' ' {
+					j++
+				}
+				i = j
+				continue
+			}
+			if s[i+1] != '\n' {
+				sb.WriteByte(s[i+1])
+			}
+			i += 2
+			continue
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String(), ""
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// CounterOp is one name/value pair of counter-reset, counter-increment or
+// counter-set.
+type CounterOp struct {
+	Name  string
+	Value int
+}
+
+// ParseCounterOps parses a counter property value ("name [int]..."), using
+// defaultValue for names without an explicit integer. "none" yields nil.
+func ParseCounterOps(value string, defaultValue int) []CounterOp {
+	var ops []CounterOp
+	for _, field := range strings.Fields(value) {
+		if n, err := strconv.Atoi(field); err == nil {
+			if len(ops) > 0 {
+				ops[len(ops)-1].Value = n
+			}
+			continue
+		}
+		if strings.EqualFold(field, "none") {
+			continue
+		}
+		ops = append(ops, CounterOp{Name: field, Value: defaultValue})
+	}
+	return ops
+}
+
+// FormatCounter renders a counter value in a list-style-type (CSS Counter
+// Styles §6). Unknown styles fall back to decimal.
+func FormatCounter(value int, listStyle string) string {
+	switch strings.ToLower(listStyle) {
+	case ListStyleNone:
+		return ""
+	case ListStyleDisc:
+		return "•"
+	case ListStyleCircle:
+		return "◦"
+	case ListStyleSquare:
+		return "■"
+	case "decimal-leading-zero":
+		if value >= 0 && value < 10 {
+			return "0" + strconv.Itoa(value)
+		}
+	case ListStyleLowerAlpha, ListStyleLowerLatin:
+		return alphabeticCounter(value, 'a', 26)
+	case ListStyleUpperAlpha, ListStyleUpperLatin:
+		return alphabeticCounter(value, 'A', 26)
+	case "lower-greek":
+		return alphabeticCounter(value, 'α', 24)
+	case ListStyleLowerRoman:
+		return strings.ToLower(romanCounter(value))
+	case ListStyleUpperRoman:
+		return romanCounter(value)
+	}
+	return strconv.Itoa(value)
+}
+
+// alphabeticCounter uses bijective base-n numbering (a..z, aa, ab...);
+// values below 1 fall back to decimal.
+func alphabeticCounter(value int, first rune, n int) string {
+	if value < 1 {
+		return strconv.Itoa(value)
+	}
+	var digits []rune
+	for value > 0 {
+		value--
+		r := first + rune(value%n)
+		if first == 'α' && r >= 'ς' { // skip final sigma
+			r++
+		}
+		digits = append([]rune{r}, digits...)
+		value /= n
+	}
+	return string(digits)
+}
+
+// romanCounter formats 1..3999 as upper-case roman numerals, other values
+// as decimal.
+func romanCounter(value int) string {
+	if value <= 0 || value > 3999 {
+		return strconv.Itoa(value)
+	}
+	vals := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	syms := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var sb strings.Builder
+	for i, v := range vals {
+		for value >= v {
+			sb.WriteString(syms[i])
+			value -= v
+		}
+	}
+	return sb.String()
+}
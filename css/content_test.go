@@ -0,0 +1,125 @@
+package css
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []ContentItem
+	}{
+		{"double quoted string", `"Note: "`, []ContentItem{{Kind: ContentString, Value: "Note: "}}},
+		{"single quoted string", `'→'`, []ContentItem{{Kind: ContentString, Value: "→"}}},
+		{"empty string", `""`, []ContentItem{{Kind: ContentString, Value: ""}}},
+		{"hex escape", `"\f101"`, []ContentItem{{Kind: ContentString, Value: ""}}},
+		{"hex escape consumes one space", `"\2014 x"`, []ContentItem{{Kind: ContentString, Value: "—x"}}},
+		{"escaped quote", `"say \"hi\""`, []ContentItem{{Kind: ContentString, Value: `say "hi"`}}},
+		{"attr", `attr(title)`, []ContentItem{{Kind: ContentAttr, Value: "title"}}},
+		{
+			"string and attr",
+			`" (" attr(href) ")"`,
+			[]ContentItem{
+				{Kind: ContentString, Value: " ("},
+				{Kind: ContentAttr, Value: "href"},
+				{Kind: ContentString, Value: ")"},
+			},
+		},
+		{"counter", `counter(item)`, []ContentItem{{Kind: ContentCounter, Value: "item"}}},
+		{"counter with style", `counter(item, upper-roman)`, []ContentItem{{Kind: ContentCounter, Value: "item", ListStyle: "upper-roman"}}},
+		{"counters", `counters(item, ".")`, []ContentItem{{Kind: ContentCounters, Value: "item", Separator: "."}}},
+		{"quotes", `open-quote close-quote`, []ContentItem{{Kind: ContentOpenQuote}, {Kind: ContentCloseQuote}}},
+		{"unknown function skipped", `url(a.png) "x"`, []ContentItem{{Kind: ContentString, Value: "x"}}},
+		{"none", `none`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseContent(tt.value))
+		})
+	}
+}
+
+func TestHasGeneratedContent(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"", false},
+		{"none", false},
+		{"normal", false},
+		{"NONE", false},
+		{`""`, true},
+		{`"x"`, true},
+		{"attr(title)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HasGeneratedContent(tt.value))
+		})
+	}
+}
+
+func TestParseCounterOps(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		defaultValue int
+		expected     []CounterOp
+	}{
+		{"single name", "section", 1, []CounterOp{{Name: "section", Value: 1}}},
+		{"name with value", "section 5", 1, []CounterOp{{Name: "section", Value: 5}}},
+		{"negative value", "item -1", 0, []CounterOp{{Name: "item", Value: -1}}},
+		{
+			"multiple names",
+			"chapter section 2",
+			0,
+			[]CounterOp{{Name: "chapter", Value: 0}, {Name: "section", Value: 2}},
+		},
+		{"none", "none", 0, nil},
+		{"empty", "", 0, nil},
+		{"names are case-sensitive", "Section", 0, []CounterOp{{Name: "Section", Value: 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseCounterOps(tt.value, tt.defaultValue))
+		})
+	}
+}
+
+func TestFormatCounter(t *testing.T) {
+	tests := []struct {
+		value     int
+		listStyle string
+		expected  string
+	}{
+		{3, "", "3"},
+		{3, "decimal", "3"},
+		{-2, "decimal", "-2"},
+		{7, "decimal-leading-zero", "07"},
+		{12, "decimal-leading-zero", "12"},
+		{1, "lower-alpha", "a"},
+		{26, "lower-alpha", "z"},
+		{27, "lower-alpha", "aa"},
+		{2, "upper-latin", "B"},
+		{0, "lower-alpha", "0"},
+		{4, "lower-roman", "iv"},
+		{1999, "upper-roman", "MCMXCIX"},
+		{1, "lower-greek", "α"},
+		{18, "lower-greek", "σ"},
+		{1, "disc", "•"},
+		{1, "none", ""},
+		{5, "unknown-style", "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listStyle, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatCounter(tt.value, tt.listStyle))
+		})
+	}
+}
@@ -52,12 +52,12 @@ const (
 type Style struct {
 	Color            color.Color
 	BackgroundColor  color.Color
-	BackgroundImage  string
+	BackgroundImages []string // url per background layer, topmost first; "" for none
 	BackgroundSize   string
 	FontSize         float64
 	FontVariant      string
 	LineHeight       float64
-	Bold             bool
+	FontWeight       int // 1–1000 (CSS Fonts §2.2); 0 inherits
 	Italic           bool
 	MarginTop        float64
 	MarginBottom     float64
@@ -65,6 +65,8 @@ type Style struct {
 	MarginRight      float64
 	MarginLeftAuto   bool
 	MarginRightAuto  bool
+	MarginTopAuto    bool
+	MarginBottomAuto bool
 	PaddingTop       float64
 	PaddingBottom    float64
 	PaddingLeft      float64
@@ -72,6 +74,7 @@ type Style struct {
 	TextAlign        string
 	TextIndent       string // raw CSS value, resolved at layout time (supports %, em, px)
 	WhiteSpace       string
+	Hyphens          string // "none", "manual" or "auto"; "" inherits, then manual
 	Overflow         string
 	OverflowX        string
 	OverflowY        string
@@ -87,6 +90,7 @@ type Style struct {
 	Bottom           float64
 	TextDecoration   string
 	Opacity          float64
+	Filters          []Filter // filter functions, applied in order; not inherited
 	Visibility       string
 	Cursor           string
 	TextTransform    string
@@ -94,16 +98,46 @@ type Style struct {
 	LetterSpacingSet bool
 	WordSpacing      float64
 	WordSpacingSet   bool
+	TextShadows      []TextShadow
+	TextShadowSet    bool // text-shadow was declared; none clears inherited shadows
 	Width            float64
-	WidthPercent     float64 // percentage width (e.g., 25 means 25%)
+	WidthPercent     float64     // percentage width (e.g., 25 means 25%)
+	WidthFitContent  bool        // width: fit-content shrinks absolutely positioned boxes to their content
+	WidthMath        *MathLength // min()/max()/clamp() width with percentages, resolved in layout
 	Height           float64
+	AspectRatio      float64 // preferred width/height ratio; 0 is auto
 	MinWidth         float64
 	MaxWidth         float64
+	MinWidthMath     *MathLength // as WidthMath, for min-width
+	MaxWidthMath     *MathLength // as WidthMath, for max-width
 	MinHeight        float64
 	MaxHeight        float64
 	FontFamily       []string
 	BoxSizing        string
 
+	// Background image placement (CSS Backgrounds §3.4-3.9); BackgroundSize is
+	// above. Each holds a comma-separated list with one entry per layer.
+	BackgroundRepeat     string // "" is the initial value, repeat
+	BackgroundPosition   string // "" is the initial value, 0% 0%
+	BackgroundAttachment string // "" is the initial value, scroll
+
+	// Flexbox properties (CSS Flexible Box Layout Level 1)
+	FlexDirection  string // row (default), row-reverse, column, column-reverse
+	FlexWrap       string // nowrap (default), wrap, wrap-reverse
+	JustifyContent string
+	AlignItems     string
+	FlexGrow       float64
+	FlexShrink     float64
+	FlexShrinkSet  bool   // flex-shrink defaults to 1 when unset
+	FlexBasis      string // raw CSS value, resolved at layout time (auto, content, px, %)
+	RowGap         float64
+	ColumnGap      float64
+	ColumnGapSet   bool // false for column-gap: normal, which is 1em between columns
+
+	// Multi-column layout (CSS Multi-column Layout Level 1)
+	ColumnCount int     // 0 is auto
+	ColumnWidth float64 // 0 is auto
+
 	// Border properties
 	BorderTopWidth          float64
 	BorderRightWidth        float64
@@ -123,14 +157,39 @@ type Style struct {
 	BorderBottomLeftRadius  float64
 	BorderBottomRightRadius float64
 
+	// Outline (CSS Basic UI §5): a ring outside the border box that takes
+	// no space in layout
+	OutlineWidth  float64
+	OutlineStyle  string      // "" and "none" paint nothing; "auto" paints solid
+	OutlineColor  color.Color // nil means currentColor
+	OutlineOffset float64
+
+	// Table borders (CSS 2.1 §17.6)
+	BorderCollapse   string  // "separate" or "collapse"; "" inherits, then separate
+	BorderSpacingX   float64 // gap between cells in the separated model
+	BorderSpacingY   float64
+	BorderSpacingSet bool   // false defers to the cellspacing attribute
+	CaptionSide      string // "top" or "bottom"; "" inherits, then top
+
 	TopSet    bool
 	LeftSet   bool
 	RightSet  bool
 	BottomSet bool
 
+	ZIndex    int  // stack level of positioned boxes (CSS 2.1 §9.9.1)
+	ZIndexSet bool // false for z-index: auto
+
 	ListStyleType string
 
+	Content string // raw `content` value; only meaningful on ::before/::after styles
+
+	CounterReset     string // raw counter-reset value, see ParseCounterOps
+	CounterIncrement string
+	CounterSet       string
+
 	FirstLineStyle *Style // styles from ::first-line pseudo-element rules
+	BeforeStyle    *Style // styles from ::before rules, nil when no content is generated
+	AfterStyle     *Style // styles from ::after rules, nil when no content is generated
 }
 
 // EffectiveOverflowX returns the effective horizontal overflow value,
@@ -151,13 +210,46 @@ func (s Style) EffectiveOverflowY() string {
 	return s.Overflow
 }
 
+// BackgroundLayer is one image layer of a box's background.
+type BackgroundLayer struct {
+	Image      string
+	Position   string
+	Size       string
+	Repeat     string
+	Attachment string // fixed layers are positioned in the viewport
+}
+
+// BackgroundLayers pairs each background image with its position, size,
+// repeat and attachment values, topmost layer first. Lists shorter than the image list
+// repeat (CSS Backgrounds §2.2); layers whose image is none are dropped.
+func (s Style) BackgroundLayers() []BackgroundLayer {
+	positions := splitBackgroundLayers(s.BackgroundPosition)
+	sizes := splitBackgroundLayers(s.BackgroundSize)
+	repeats := splitBackgroundLayers(s.BackgroundRepeat)
+	attachments := splitBackgroundLayers(s.BackgroundAttachment)
+	var layers []BackgroundLayer
+	for i, image := range s.BackgroundImages {
+		if image == "" {
+			continue
+		}
+		layers = append(layers, BackgroundLayer{
+			Image:      image,
+			Position:   positions[i%len(positions)],
+			Size:       sizes[i%len(sizes)],
+			Repeat:     repeats[i%len(repeats)],
+			Attachment: attachments[i%len(attachments)],
+		})
+	}
+	return layers
+}
+
 func DefaultStyle() Style {
 	return Style{
-		FontSize:   DefaultFontSize,
-		Bold:       false,
-		Italic:     false,
-		Opacity:    1.0,
-		WhiteSpace: "normal",
+		FontSize:     DefaultFontSize,
+		Italic:       false,
+		Opacity:      1.0,
+		WhiteSpace:   "normal",
+		OutlineWidth: 3, // medium
 	}
 }
 
@@ -233,14 +325,14 @@ func ParseInlineStyle(styleAttr string) Style {
 
 // parseBorderShorthand parses "1px solid black" into width, style, color
 func parseBorderShorthand(value string, fontSize, viewportWidth, viewportHeight float64) (float64, string, color.Color) {
-	parts := strings.Fields(value)
+	parts := valueFields(value)
 	var width float64
 	var borderStyle string
 	var borderColor color.Color
 	for _, part := range parts {
 		if w := parseBorderWidthValue(part, fontSize, viewportWidth, viewportHeight); w > 0 {
 			width = w
-		} else if part == "solid" || part == "dashed" || part == "dotted" || part == "none" {
+		} else if borderStyleKeywords[part] {
 			borderStyle = part
 		} else if c := ParseColor(part); c != nil {
 			borderColor = c
@@ -249,6 +341,85 @@ func parseBorderShorthand(value string, fontSize, viewportWidth, viewportHeight
 	return width, borderStyle, borderColor
 }
 
+// borderStyleKeywords are the border-style values (CSS Backgrounds §4.2).
+var borderStyleKeywords = map[string]bool{
+	"none": true, "hidden": true, "dotted": true, "dashed": true, "solid": true,
+	"double": true, "groove": true, "ridge": true, "inset": true, "outset": true,
+}
+
+// parseBorderSpacing parses border-spacing: one length for both axes, or
+// horizontal then vertical. Negative lengths are invalid.
+func parseBorderSpacing(value string, fontSize, viewportWidth, viewportHeight float64) (x, y float64, ok bool) {
+	parts := valueFields(value)
+	if len(parts) == 0 || len(parts) > 2 {
+		return 0, 0, false
+	}
+	var lengths []float64
+	for _, part := range parts {
+		if strings.EqualFold(part, "normal") {
+			return 0, 0, false
+		}
+		length, ok := parseSpacingWithContext(part, fontSize, viewportWidth, viewportHeight)
+		if !ok || length < 0 {
+			return 0, 0, false
+		}
+		lengths = append(lengths, length)
+	}
+	if len(lengths) == 1 {
+		return lengths[0], lengths[0], true
+	}
+	return lengths[0], lengths[1], true
+}
+
+// parseBorderStyles expands a one- to four-value border-style into its
+// top, right, bottom and left styles. Reports false for invalid values.
+func parseBorderStyles(value string) (top, right, bottom, left string, ok bool) {
+	parts := strings.Fields(strings.ToLower(value))
+	for _, part := range parts {
+		if !borderStyleKeywords[part] {
+			return "", "", "", "", false
+		}
+	}
+	switch len(parts) {
+	case 1:
+		return parts[0], parts[0], parts[0], parts[0], true
+	case 2:
+		return parts[0], parts[1], parts[0], parts[1], true
+	case 3:
+		return parts[0], parts[1], parts[2], parts[1], true
+	case 4:
+		return parts[0], parts[1], parts[2], parts[3], true
+	}
+	return "", "", "", "", false
+}
+
+// parseOutlineShorthand parses an outline shorthand such as "2px dashed red"
+// into width, style and color. Omitted parts take their initial values
+// (medium, none and currentColor). Reports false for invalid values.
+func parseOutlineShorthand(value string, fontSize, viewportWidth, viewportHeight float64) (float64, string, color.Color, bool) {
+	width, outlineStyle := 3.0, "none"
+	var outlineColor color.Color
+	for _, part := range strings.Fields(strings.ToLower(value)) {
+		switch {
+		case part == "auto" || borderStyleKeywords[part]:
+			outlineStyle = part
+		case part == "0":
+			width = 0
+		case part == "invert" || part == "currentcolor":
+			outlineColor = nil
+		default:
+			if w := parseBorderWidthValue(part, fontSize, viewportWidth, viewportHeight); w > 0 {
+				width = w
+			} else if c := ParseColor(part); c != nil {
+				outlineColor = c
+			} else {
+				return 0, "", nil, false
+			}
+		}
+	}
+	return width, outlineStyle, outlineColor, true
+}
+
 // parseBorderWidthValue resolves a single border-width value, supporting
 // CSS keywords thin (1px), medium (3px), thick (5px) in addition to
 // length values handled by ParseSizeWithContext.
@@ -272,6 +443,10 @@ func ParseSize(value string) float64 {
 func ParseSizeWithContext(value string, baseFontSize float64, viewportWidth, viewportHeight float64) float64 {
 	value = strings.TrimSpace(strings.ToLower(value))
 
+	if size, ok := parseMathFunction(value, baseFontSize, viewportWidth, viewportHeight); ok {
+		return size
+	}
+
 	if strings.HasSuffix(value, UnitVh) {
 		num := strings.TrimSuffix(value, UnitVh)
 		if percent, err := strconv.ParseFloat(num, 64); err == nil {
@@ -578,6 +753,33 @@ func selectorSpecificity(sel Selector) Specificity {
 type MatchContext struct {
 	IsVisited  func(url string) bool    // returns true if url has been visited
 	ResolveURL func(href string) string // resolves relative hrefs to absolute (optional)
+	Hovered    *dom.Node                // deepest node under the pointer, from hit testing (optional)
+	Focused    *dom.Node                // element with keyboard focus (optional)
+
+	ColorScheme string // preferred color scheme for prefers-color-scheme; "" is light
+	Media       string // media type @media rules are matched against; "" is screen
+}
+
+// HasFocusWithin returns true if node is the focused element or one of its
+// ancestors (CSS Selectors §9.5).
+func (ctx MatchContext) HasFocusWithin(node *dom.Node) bool {
+	for n := ctx.Focused; n != nil; n = n.Parent {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHovered returns true if node is in the hovered chain: the hovered node
+// itself or any of its ancestors (CSS Selectors §9.2).
+func (ctx MatchContext) IsHovered(node *dom.Node) bool {
+	for n := ctx.Hovered; n != nil; n = n.Parent {
+		if n == node {
+			return true
+		}
+	}
+	return false
 }
 
 type Declaration struct {
@@ -589,11 +791,28 @@ type Declaration struct {
 type Rule struct {
 	Selectors    []Selector
 	Declarations []Declaration
+	Media        []MediaQuery // enclosing @media query list; nil outside @media
 }
 
 type Stylesheet struct {
-	Imports []string // @import URLs, in declaration order
-	Rules   []Rule
+	Imports   []string // @import URLs, in declaration order
+	Rules     []Rule
+	FontFaces []FontFace // @font-face rules, in declaration order
+}
+
+// HasPseudoClass returns true if any selector in the sheet (including
+// ancestor compounds) uses the given pseudo-class.
+func (sheet Stylesheet) HasPseudoClass(name string) bool {
+	for _, rule := range sheet.Rules {
+		for _, sel := range rule.Selectors {
+			for s := &sel; s != nil; s = s.Ancestor {
+				if s.PseudoClass == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
 }
 
 // MatchSelector checks if a selector matches a DOM node
@@ -673,8 +892,20 @@ func MatchSelectorNode(sel Selector, node *dom.Node, ctx MatchContext) bool {
 			if ctx.IsVisited == nil || !ctx.IsVisited(resolvedHref) {
 				return false
 			}
+		case "hover":
+			if !ctx.IsHovered(node) {
+				return false
+			}
+		case "focus", "focus-visible":
+			if ctx.Focused != node {
+				return false
+			}
+		case "focus-within":
+			if !ctx.HasFocusWithin(node) {
+				return false
+			}
 		default:
-			// :hover, :focus, :active, etc. — not yet supported
+			// :active, etc. — not yet supported
 			return false
 		}
 	}
@@ -709,6 +940,9 @@ func ApplyStylesheet(sheet Stylesheet, tagName string, id string, classes []stri
 
 	// Check each rule
 	for _, rule := range sheet.Rules {
+		if !(MatchContext{}).matchesMedia(rule.Media) {
+			continue
+		}
 		// Check if any selector matches
 		matches := false
 		for _, sel := range rule.Selectors {
@@ -784,24 +1018,77 @@ func ParseFontFamily(value string) []string {
 	return fonts
 }
 
-func parseFontWeightValue(value string) (bool, bool) {
+// IsMonospaceFamily reports whether a font-family list names a monospace
+// font, whose text is set with fixed-width glyphs.
+func IsMonospaceFamily(families []string) bool {
+	for _, family := range families {
+		switch strings.ToLower(family) {
+		case "monospace", "courier", "courier new", "consolas", "monaco", "menlo":
+			return true
+		}
+	}
+	return false
+}
+
+// Font weights of the normal and bold keywords (CSS Fonts §2.2).
+const (
+	FontWeightNormal = 400
+	FontWeightBold   = 700
+)
+
+// IsBold reports whether text of weight is drawn with a bold face when
+// only regular and bold faces exist (CSS Fonts §5.2 weight matching).
+func IsBold(weight int) bool {
+	return weight >= 600
+}
+
+// parseFontWeightValue parses an absolute font-weight: normal, bold or a
+// number from 1 to 1000.
+func parseFontWeightValue(value string) (int, bool) {
 	v := strings.TrimSpace(strings.ToLower(value))
 	switch v {
-	case "normal", "lighter":
-		return false, true
-	case "bold", "bolder":
-		return true, true
+	case "normal":
+		return FontWeightNormal, true
+	case "bold":
+		return FontWeightBold, true
 	}
 
 	n, err := strconv.Atoi(v)
-	if err != nil {
-		return false, false
-	}
-	if n < 100 || n > 900 || n%100 != 0 {
-		return false, false
+	if err != nil || n < 1 || n > 1000 {
+		return 0, false
 	}
+	return n, true
+}
 
-	return n >= 600, true
+// relativeFontWeight resolves bolder and lighter against the inherited
+// weight (CSS Fonts §2.2.1). An inherited weight of 0 means normal.
+func relativeFontWeight(value string, inherited int) (int, bool) {
+	if inherited == 0 {
+		inherited = FontWeightNormal
+	}
+	switch strings.TrimSpace(strings.ToLower(value)) {
+	case "bolder":
+		switch {
+		case inherited < 350:
+			return 400, true
+		case inherited < 550:
+			return 700, true
+		case inherited < 900:
+			return 900, true
+		}
+		return inherited, true
+	case "lighter":
+		switch {
+		case inherited < 100:
+			return inherited, true
+		case inherited < 550:
+			return 100, true
+		case inherited < 750:
+			return 400, true
+		}
+		return 700, true
+	}
+	return 0, false
 }
 
 func isFontStyleToken(token string) bool {
@@ -824,7 +1111,7 @@ func isFontVariantToken(token string) bool {
 
 func isFontWeightToken(token string) bool {
 	_, ok := parseFontWeightValue(token)
-	return ok
+	return ok || token == "bolder" || token == "lighter"
 }
 
 func isFontSizeToken(token string) bool {
@@ -1054,6 +1341,39 @@ func parseMarginValue(value string, fontSize, vw, vh float64) (float64, bool) {
 	return ParseSizeWithContext(value, fontSize, vw, vh), false
 }
 
+// parseAspectRatio parses an aspect-ratio value: "auto", a ratio such as
+// "16 / 9" or "1.5", or both. Boxes here have no natural ratio before their
+// content loads, so "auto <ratio>" behaves as the ratio. A zero term makes
+// the ratio degenerate, which is auto.
+func parseAspectRatio(value string) (float64, bool) {
+	var ratio []string
+	for _, part := range strings.Fields(strings.ToLower(strings.ReplaceAll(value, "/", " / "))) {
+		if part != "auto" {
+			ratio = append(ratio, part)
+		}
+	}
+	if len(ratio) == 0 {
+		return 0, strings.TrimSpace(value) != ""
+	}
+	if len(ratio) != 1 && (len(ratio) != 3 || ratio[1] != "/") {
+		return 0, false
+	}
+	width, err := strconv.ParseFloat(ratio[0], 64)
+	if err != nil || width < 0 {
+		return 0, false
+	}
+	height := 1.0
+	if len(ratio) == 3 {
+		if height, err = strconv.ParseFloat(ratio[2], 64); err != nil || height < 0 {
+			return 0, false
+		}
+	}
+	if width == 0 || height == 0 {
+		return 0, true
+	}
+	return width / height, true
+}
+
 // parseSpacingWithContext parses spacing values for letter/word spacing.
 // Supports: normal, px, em, ex, vh/vw, pt, and unitless numeric values.
 func parseSpacingWithContext(value string, fontSize, viewportWidth, viewportHeight float64) (float64, bool) {
@@ -1064,6 +1384,9 @@ func parseSpacingWithContext(value string, fontSize, viewportWidth, viewportHeig
 	if v == "normal" {
 		return 0, true
 	}
+	if size, ok := parseMathFunction(v, fontSize, viewportWidth, viewportHeight); ok {
+		return size, true
+	}
 	num := v
 	switch {
 	case strings.HasSuffix(v, UnitPx):
@@ -1104,35 +1427,47 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 			style.BackgroundColor = c
 		}
 	case "background-image":
-		if strings.HasPrefix(value, "url(") && strings.HasSuffix(value, ")") {
-			url := value[4 : len(value)-1]
-			url = strings.Trim(url, `"'`)
-			url = strings.TrimSpace(url)
-			style.BackgroundImage = url
-		} else if value == "none" {
-			style.BackgroundImage = ""
-		}
-	case "background":
-		bgColor, bgImage := parseBackgroundShorthand(value)
-		if bgColor != nil {
-			style.BackgroundColor = bgColor
+		// Layers without a url(): none, gradients and typos alike, keep
+		// an empty slot so the other background lists stay aligned
+		layers := splitBackgroundLayers(value)
+		images := make([]string, len(layers))
+		hasImage := false
+		for i, layer := range layers {
+			if url, ok := backgroundURL(layer); ok {
+				images[i] = url
+				hasImage = true
+			}
 		}
-		if bgImage != "" {
-			style.BackgroundImage = bgImage
+		if !hasImage {
+			images = nil
 		}
+		style.BackgroundImages = images
+	case "background":
+		applyBackgroundShorthand(style, value)
 	case "background-size":
-		v := strings.TrimSpace(strings.ToLower(value))
-		style.BackgroundSize = v
+		style.BackgroundSize = normalizeBackgroundList(value)
+	case "background-repeat":
+		style.BackgroundRepeat = normalizeBackgroundList(value)
+	case "background-position":
+		style.BackgroundPosition = normalizeBackgroundList(value)
+	case "background-attachment":
+		style.BackgroundAttachment = normalizeBackgroundList(value)
 	case "font-size":
 		// font-size em is relative to PARENT's font-size (baseFontSize)
 		if size := parseFontSizeWithContext(value, baseFontSize, viewportWidth, viewportHeight); size > 0 {
 			style.FontSize = size
 		}
 	case "line-height":
-		style.LineHeight = parseLineHeight(value, style.FontSize)
+		if size, ok := parseMathFunction(value, style.FontSize, viewportWidth, viewportHeight); ok {
+			style.LineHeight = size
+		} else {
+			style.LineHeight = parseLineHeight(value, style.FontSize)
+		}
 	case "font-weight":
-		if bold, ok := parseFontWeightValue(value); ok {
-			style.Bold = bold
+		if weight, ok := parseFontWeightValue(value); ok {
+			style.FontWeight = weight
+		} else if weight, ok := relativeFontWeight(value, style.FontWeight); ok {
+			style.FontWeight = weight
 		}
 	case "font-style":
 		style.Italic = (value == "italic")
@@ -1140,30 +1475,38 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 		style.FontFamily = ParseFontFamily(value)
 	case "font-variant":
 		style.FontVariant = value
+	case "content":
+		style.Content = value
+	case "counter-reset":
+		style.CounterReset = value
+	case "counter-increment":
+		style.CounterIncrement = value
+	case "counter-set":
+		style.CounterSet = value
 	case "margin":
-		parts := strings.Fields(value)
+		parts := valueFields(value)
 		var top, right, bottom, left float64
-		var rightAuto, leftAuto bool
+		var topAuto, rightAuto, bottomAuto, leftAuto bool
 
 		switch len(parts) {
 		case 1:
 			m, isAuto := parseMarginValue(parts[0], style.FontSize, viewportWidth, viewportHeight)
 			top, right, bottom, left = m, m, m, m
-			rightAuto, leftAuto = isAuto, isAuto
+			topAuto, rightAuto, bottomAuto, leftAuto = isAuto, isAuto, isAuto, isAuto
 		case 2:
-			top, _ = parseMarginValue(parts[0], style.FontSize, viewportWidth, viewportHeight)
-			bottom = top
+			top, topAuto = parseMarginValue(parts[0], style.FontSize, viewportWidth, viewportHeight)
+			bottom, bottomAuto = top, topAuto
 			right, rightAuto = parseMarginValue(parts[1], style.FontSize, viewportWidth, viewportHeight)
 			left, leftAuto = right, rightAuto
 		case 3:
-			top, _ = parseMarginValue(parts[0], style.FontSize, viewportWidth, viewportHeight)
+			top, topAuto = parseMarginValue(parts[0], style.FontSize, viewportWidth, viewportHeight)
 			right, rightAuto = parseMarginValue(parts[1], style.FontSize, viewportWidth, viewportHeight)
-			bottom, _ = parseMarginValue(parts[2], style.FontSize, viewportWidth, viewportHeight)
+			bottom, bottomAuto = parseMarginValue(parts[2], style.FontSize, viewportWidth, viewportHeight)
 			left, leftAuto = right, rightAuto
 		case 4:
-			top, _ = parseMarginValue(parts[0], style.FontSize, viewportWidth, viewportHeight)
+			top, topAuto = parseMarginValue(parts[0], style.FontSize, viewportWidth, viewportHeight)
 			right, rightAuto = parseMarginValue(parts[1], style.FontSize, viewportWidth, viewportHeight)
-			bottom, _ = parseMarginValue(parts[2], style.FontSize, viewportWidth, viewportHeight)
+			bottom, bottomAuto = parseMarginValue(parts[2], style.FontSize, viewportWidth, viewportHeight)
 			left, leftAuto = parseMarginValue(parts[3], style.FontSize, viewportWidth, viewportHeight)
 		}
 
@@ -1171,12 +1514,24 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 		style.MarginRight = right
 		style.MarginBottom = bottom
 		style.MarginLeft = left
+		style.MarginTopAuto = topAuto
 		style.MarginRightAuto = rightAuto
+		style.MarginBottomAuto = bottomAuto
 		style.MarginLeftAuto = leftAuto
 	case "margin-top":
-		style.MarginTop = ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight)
+		if strings.ToLower(value) == "auto" {
+			style.MarginTopAuto = true
+		} else {
+			style.MarginTop = ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight)
+			style.MarginTopAuto = false
+		}
 	case "margin-bottom":
-		style.MarginBottom = ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight)
+		if strings.ToLower(value) == "auto" {
+			style.MarginBottomAuto = true
+		} else {
+			style.MarginBottom = ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight)
+			style.MarginBottomAuto = false
+		}
 	case "margin-left":
 		if strings.ToLower(value) == "auto" {
 			style.MarginLeftAuto = true
@@ -1192,7 +1547,7 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 			style.MarginRightAuto = false
 		}
 	case "padding":
-		parts := strings.Fields(value)
+		parts := valueFields(value)
 		var top, right, bottom, left float64
 		parse := func(v string) float64 {
 			return ParseSizeWithContext(v, style.FontSize, viewportWidth, viewportHeight)
@@ -1237,9 +1592,13 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 		style.TextIndent = value
 	case "white-space":
 		switch value {
-		case "normal", "nowrap":
+		case "normal", "nowrap", "pre", "pre-wrap", "pre-line":
 			style.WhiteSpace = value
 		}
+	case "hyphens", "-webkit-hyphens":
+		if v := strings.ToLower(strings.TrimSpace(value)); v == "none" || v == "manual" || v == "auto" {
+			style.Hyphens = v
+		}
 	case "text-overflow":
 		switch value {
 		case "clip", "ellipsis":
@@ -1285,8 +1644,65 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 	case "bottom":
 		style.Bottom = ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight)
 		style.BottomSet = true
+	case "z-index":
+		if value == "auto" {
+			style.ZIndex, style.ZIndexSet = 0, false
+		} else if n, err := strconv.Atoi(value); err == nil {
+			style.ZIndex, style.ZIndexSet = n, true
+		}
 	case "box-sizing":
 		style.BoxSizing = value
+	case "flex-direction":
+		switch value {
+		case "row", "row-reverse", "column", "column-reverse":
+			style.FlexDirection = value
+		}
+	case "flex-wrap":
+		switch value {
+		case "nowrap", "wrap", "wrap-reverse":
+			style.FlexWrap = value
+		}
+	case "flex-flow":
+		applyFlexFlow(style, value)
+	case "justify-content":
+		if flexJustifyValues[value] {
+			style.JustifyContent = value
+		}
+	case "align-items":
+		if flexAlignValues[value] {
+			style.AlignItems = value
+		}
+	case "flex-grow":
+		if n, err := strconv.ParseFloat(value, 64); err == nil && n >= 0 {
+			style.FlexGrow = n
+		}
+	case "flex-shrink":
+		if n, err := strconv.ParseFloat(value, 64); err == nil && n >= 0 {
+			style.FlexShrink = n
+			style.FlexShrinkSet = true
+		}
+	case "flex-basis":
+		style.FlexBasis = value
+	case "flex":
+		applyFlexShorthand(style, value)
+	case "gap":
+		parts := valueFields(value)
+		if len(parts) == 1 || len(parts) == 2 {
+			style.RowGap = parseGap(parts[0], style.FontSize, viewportWidth, viewportHeight)
+			style.ColumnGap = parseGap(parts[len(parts)-1], style.FontSize, viewportWidth, viewportHeight)
+			style.ColumnGapSet = parts[len(parts)-1] != "normal"
+		}
+	case "row-gap":
+		style.RowGap = parseGap(value, style.FontSize, viewportWidth, viewportHeight)
+	case "column-gap":
+		style.ColumnGap = parseGap(value, style.FontSize, viewportWidth, viewportHeight)
+		style.ColumnGapSet = value != "normal"
+	case "column-count":
+		style.ColumnCount, _ = parseColumnCount(value)
+	case "column-width":
+		style.ColumnWidth, _ = parseColumnWidth(value, style.FontSize, viewportWidth, viewportHeight)
+	case "columns":
+		applyColumnsShorthand(style, value, viewportWidth, viewportHeight)
 	case "text-decoration":
 		style.TextDecoration = value
 	case "text-transform":
@@ -1301,6 +1717,11 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 			style.WordSpacing = ws
 			style.WordSpacingSet = true
 		}
+	case "text-shadow":
+		if shadows, ok := parseTextShadow(value, style.FontSize, viewportWidth, viewportHeight); ok {
+			style.TextShadows = shadows
+			style.TextShadowSet = true
+		}
 	case "opacity":
 		if op, err := strconv.ParseFloat(value, 64); err == nil {
 			if op < 0 {
@@ -1310,6 +1731,10 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 			}
 			style.Opacity = op
 		}
+	case "filter":
+		if filters, ok := parseFilter(value, style.FontSize, viewportWidth, viewportHeight); ok {
+			style.Filters = filters
+		}
 	case "visibility":
 		style.Visibility = value
 	case "cursor":
@@ -1329,7 +1754,7 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 		style.BorderBottomColor = c
 		style.BorderLeftColor = c
 	case "border-width":
-		parts := strings.Fields(value)
+		parts := valueFields(value)
 		parse := func(v string) float64 {
 			return parseBorderWidthValue(v, style.FontSize, viewportWidth, viewportHeight)
 		}
@@ -1366,10 +1791,57 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 			style.BorderLeftColor = c
 		}
 	case "border-style":
-		style.BorderTopStyle = value
-		style.BorderRightStyle = value
-		style.BorderBottomStyle = value
-		style.BorderLeftStyle = value
+		if top, right, bottom, left, ok := parseBorderStyles(value); ok {
+			style.BorderTopStyle = top
+			style.BorderRightStyle = right
+			style.BorderBottomStyle = bottom
+			style.BorderLeftStyle = left
+		}
+	case "border-collapse":
+		if v := strings.ToLower(strings.TrimSpace(value)); v == "collapse" || v == "separate" {
+			style.BorderCollapse = v
+		}
+	case "caption-side":
+		if v := strings.ToLower(strings.TrimSpace(value)); v == "top" || v == "bottom" {
+			style.CaptionSide = v
+		}
+	case "border-spacing":
+		if x, y, ok := parseBorderSpacing(value, style.FontSize, viewportWidth, viewportHeight); ok {
+			style.BorderSpacingX, style.BorderSpacingY, style.BorderSpacingSet = x, y, true
+		}
+	case "outline":
+		if w, st, c, ok := parseOutlineShorthand(value, style.FontSize, viewportWidth, viewportHeight); ok {
+			style.OutlineWidth, style.OutlineStyle, style.OutlineColor = w, st, c
+		}
+	case "outline-width":
+		style.OutlineWidth = parseBorderWidthValue(value, style.FontSize, viewportWidth, viewportHeight)
+	case "outline-style":
+		if v := strings.ToLower(strings.TrimSpace(value)); v == "auto" || borderStyleKeywords[v] {
+			style.OutlineStyle = v
+		}
+	case "outline-color":
+		if c := ParseColor(value); c != nil {
+			style.OutlineColor = c
+		}
+	case "outline-offset":
+		if offset, ok := parseSpacingWithContext(value, style.FontSize, viewportWidth, viewportHeight); ok {
+			style.OutlineOffset = offset
+		}
+	case "border-top-style", "border-right-style", "border-bottom-style", "border-left-style":
+		v := strings.ToLower(strings.TrimSpace(value))
+		if !borderStyleKeywords[v] {
+			break
+		}
+		switch property {
+		case "border-top-style":
+			style.BorderTopStyle = v
+		case "border-right-style":
+			style.BorderRightStyle = v
+		case "border-bottom-style":
+			style.BorderBottomStyle = v
+		case "border-left-style":
+			style.BorderLeftStyle = v
+		}
 	case "border-top-width":
 		style.BorderTopWidth = parseBorderWidthValue(value, style.FontSize, viewportWidth, viewportHeight)
 	case "border-right-width":
@@ -1413,7 +1885,12 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 	case "list-style-type":
 		style.ListStyleType = value
 	case "width":
-		if strings.HasSuffix(strings.TrimSpace(value), "%") {
+		style.WidthMath = nil
+		if strings.EqualFold(strings.TrimSpace(value), "fit-content") {
+			style.WidthFitContent = true
+		} else if m, ok := parseMathLength(value, style.FontSize, viewportWidth, viewportHeight); ok && m.HasPercent() {
+			style.WidthMath = m
+		} else if strings.HasSuffix(strings.TrimSpace(value), "%") {
 			num := strings.TrimSuffix(strings.TrimSpace(value), "%")
 			if pct, err := strconv.ParseFloat(num, 64); err == nil && pct > 0 {
 				style.WidthPercent = pct
@@ -1425,12 +1902,22 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 		if h := ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight); h > 0 {
 			style.Height = h
 		}
+	case "aspect-ratio":
+		if ratio, ok := parseAspectRatio(value); ok {
+			style.AspectRatio = ratio
+		}
 	case "min-width":
-		if w := ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight); w > 0 {
+		style.MinWidthMath = nil
+		if m, ok := parseMathLength(value, style.FontSize, viewportWidth, viewportHeight); ok && m.HasPercent() {
+			style.MinWidthMath = m
+		} else if w := ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight); w > 0 {
 			style.MinWidth = w
 		}
 	case "max-width":
-		if w := ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight); w > 0 {
+		style.MaxWidthMath = nil
+		if m, ok := parseMathLength(value, style.FontSize, viewportWidth, viewportHeight); ok && m.HasPercent() {
+			style.MaxWidthMath = m
+		} else if w := ParseSizeWithContext(value, style.FontSize, viewportWidth, viewportHeight); w > 0 {
 			style.MaxWidth = w
 		}
 	case "min-height":
@@ -1442,7 +1929,7 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 			style.MaxHeight = h
 		}
 	case "border-radius":
-		parts := strings.Fields(value)
+		parts := valueFields(value)
 		parse := func(v string) float64 {
 			return ParseSizeWithContext(v, style.FontSize, viewportWidth, viewportHeight)
 		}
@@ -1481,93 +1968,136 @@ func applyDeclarationWithContext(style *Style, property, value string, baseFontS
 
 // ApplyStylesheetWithContext applies matching rules with parent font-size for em units
 func ApplyStylesheetWithContext(sheet Stylesheet, node *dom.Node, parentFontSize, viewportWidth, viewportHeight float64, ctx MatchContext) Style {
-	tagName := node.TagName
-	style := DefaultStyle()
-	importantProps := make(map[string]bool)
-	specificities := make(map[string]Specificity) // winning specificity per property
+	parent := DefaultStyle()
+	parent.FontSize = parentFontSize
+	return ApplyStylesheetWithParent(sheet, node, &parent, viewportWidth, viewportHeight, ctx)
+}
 
-	// Apply user-agent default styles based on tag
-	applyUserAgentDefaults(&style, tagName, parentFontSize, node, ctx)
+// inlineSpecificity ranks style attribute declarations above any selector
+// (CSS Cascade §6.4: element-attached declarations win over rules).
+var inlineSpecificity = Specificity{1 << 16, 0, 0}
+
+// matchedDecl is a declaration that applies to an element, with the
+// specificity of the selector that matched it.
+type matchedDecl struct {
+	decl Declaration
+	sp   Specificity
+}
 
-	// ruleSpecificity returns the highest specificity among the rule's matching selectors.
-	ruleSpecificity := func(rule Rule) (Specificity, bool) {
+// cascade tracks the winning declaration per property: !important beats
+// normal, then higher specificity, then later in document order.
+type cascade struct {
+	important   map[string]bool
+	specificity map[string]Specificity
+}
+
+func newCascade() *cascade {
+	return &cascade{important: make(map[string]bool), specificity: make(map[string]Specificity)}
+}
+
+// admit reports whether m overrides the current winner for its property,
+// recording it as the new winner if so.
+func (c *cascade) admit(m matchedDecl) bool {
+	prop := m.decl.Property
+	if prev, seen := c.specificity[prop]; seen {
+		if c.important[prop] && !m.decl.Important {
+			return false
+		}
+		if c.important[prop] == m.decl.Important && m.sp.LessThan(prev) {
+			return false
+		}
+	}
+	c.important[prop] = m.decl.Important
+	c.specificity[prop] = m.sp
+	return true
+}
+
+// matchingDeclarations returns the declarations of node's matching rules in
+// document order, followed by those of its style attribute.
+func matchingDeclarations(sheet Stylesheet, node *dom.Node, ctx MatchContext) []matchedDecl {
+	var matched []matchedDecl
+	for _, rule := range sheet.Rules {
+		if !ctx.matchesMedia(rule.Media) {
+			continue
+		}
+		// A rule's specificity is the highest among its matching selectors
 		best := Specificity{}
 		found := false
 		for _, sel := range rule.Selectors {
 			if MatchSelectorNode(sel, node, ctx) {
-				sp := selectorSpecificity(sel)
-				if !found || best.LessThan(sp) {
+				if sp := selectorSpecificity(sel); !found || best.LessThan(sp) {
 					best = sp
 					found = true
 				}
 			}
 		}
-		return best, found
-	}
-
-	// First pass: find font-size (uses parent's font-size for em)
-	for _, rule := range sheet.Rules {
-		sp, matches := ruleSpecificity(rule)
-		if !matches {
+		if !found {
 			continue
 		}
-
 		for _, decl := range rule.Declarations {
-			if decl.Property == "font-size" {
-				if importantProps["font-size"] && !decl.Important {
-					continue
-				}
-				if !decl.Important && specificities["font-size"] != (Specificity{}) && sp.LessThan(specificities["font-size"]) {
-					continue
-				}
+			matched = append(matched, matchedDecl{decl: decl, sp: best})
+		}
+	}
+	if styleAttr, ok := node.Attributes["style"]; ok {
+		for _, decl := range parseInlineDeclarations(styleAttr) {
+			matched = append(matched, matchedDecl{decl: decl, sp: inlineSpecificity})
+		}
+	}
+	return matched
+}
 
-				if size := parseFontSizeWithContext(decl.Value, parentFontSize, viewportWidth, viewportHeight); size > 0 {
-					style.FontSize = size
-				}
+// ApplyStylesheetWithParent computes node's style from user-agent defaults,
+// presentational attributes, matching rules and its style attribute. The
+// parent's computed style resolves em units and inherit/unset keywords;
+// parent is nil for the root element.
+func ApplyStylesheetWithParent(sheet Stylesheet, node *dom.Node, parent *Style, viewportWidth, viewportHeight float64, ctx MatchContext) Style {
+	parentFontSize := DefaultFontSize
+	if parent != nil && parent.FontSize > 0 {
+		parentFontSize = parent.FontSize
+	}
+	style := DefaultStyle()
+	style.FontSize = parentFontSize // font-size inherits
+	if parent != nil && parent.WhiteSpace != "" {
+		style.WhiteSpace = parent.WhiteSpace
+	}
 
-				if decl.Important {
-					importantProps["font-size"] = true
-				} else {
-					specificities["font-size"] = sp
-				}
+	// Apply user-agent default styles based on tag
+	applyUserAgentDefaults(&style, node.TagName, parentFontSize, node, ctx)
+	applyPresentationalHints(&style, node)
+
+	matched := matchingDeclarations(sheet, node, ctx)
+	winners := newCascade()
+
+	// First pass: find font-size (uses parent's font-size for em)
+	for _, m := range matched {
+		if m.decl.Property != "font-size" || !winners.admit(m) {
+			continue
+		}
+		if !applyCSSWideKeyword(&style, "font-size", m.decl.Value, parent) {
+			if size := parseFontSizeWithContext(m.decl.Value, parentFontSize, viewportWidth, viewportHeight); size > 0 {
+				style.FontSize = size
 			}
 		}
 	}
 
-	// If no font-size was set, inherit from patrain_ratiorent
-	if style.FontSize == 0 {
-		style.FontSize = parentFontSize
-	}
-
 	// Second pass: apply other properties (using computed font-size for em)
-	for _, rule := range sheet.Rules {
-		sp, matches := ruleSpecificity(rule)
-		if !matches {
+	for _, m := range matched {
+		if m.decl.Property == "font-size" || !winners.admit(m) {
 			continue
 		}
+		applyCascadedValue(&style, parent, m.decl.Property, m.decl.Value, style.FontSize, viewportWidth, viewportHeight)
+	}
 
-		for _, decl := range rule.Declarations {
-			if decl.Property != "font-size" {
-				if importantProps[decl.Property] && !decl.Important {
-					continue
-				}
-				if !decl.Important && specificities[decl.Property] != (Specificity{}) && sp.LessThan(specificities[decl.Property]) {
-					continue
-				}
-
-				applyDeclarationWithContext(&style, decl.Property, decl.Value, style.FontSize, viewportWidth, viewportHeight)
-
-				if decl.Important {
-					importantProps[decl.Property] = true
-				} else {
-					specificities[decl.Property] = sp
-				}
-			}
-		}
+	// font-weight inherits; bolder and lighter were resolved against it
+	if style.FontWeight == 0 && parent != nil {
+		style.FontWeight = parent.FontWeight
 	}
 
 	// Third pass: collect ::first-line pseudo-element declarations
 	for _, rule := range sheet.Rules {
+		if !ctx.matchesMedia(rule.Media) {
+			continue
+		}
 		for _, sel := range rule.Selectors {
 			if !isFirstLinePseudo(sel) {
 				continue
@@ -1591,9 +2121,101 @@ func ApplyStylesheetWithContext(sheet Stylesheet, node *dom.Node, parentFontSize
 		}
 	}
 
+	// Fourth pass: ::before and ::after generated content
+	style.BeforeStyle = pseudoElementStyle(sheet, node, "before", &style, viewportWidth, viewportHeight, ctx)
+	style.AfterStyle = pseudoElementStyle(sheet, node, "after", &style, viewportWidth, viewportHeight, ctx)
+
 	return style
 }
 
+// applyPresentationalHints maps legacy HTML attributes (align, valign,
+// bgcolor) to styles. They rank below every author rule (HTML §15.2).
+func applyPresentationalHints(style *Style, node *dom.Node) {
+	if align, ok := node.Attributes["align"]; ok {
+		switch strings.ToLower(align) {
+		case "left", "right", "center", "justify":
+			style.TextAlign = strings.ToLower(align)
+		}
+	}
+
+	if valign, ok := node.Attributes["valign"]; ok {
+		switch strings.ToLower(valign) {
+		case "top", "middle", "bottom", "baseline":
+			style.VerticalAlign = strings.ToLower(valign)
+		}
+	}
+
+	if bgcolor, ok := node.Attributes["bgcolor"]; ok {
+		if c := ParseColor(bgcolor); c != nil {
+			style.BackgroundColor = c
+		}
+	}
+}
+
+// pseudoElementStyle cascades the rules targeting node::pseudo (before/after)
+// and returns their style, or nil if they generate no content. Font-relative
+// units and inherit keywords resolve against the originating element's style.
+func pseudoElementStyle(sheet Stylesheet, node *dom.Node, pseudo string, element *Style, viewportWidth, viewportHeight float64, ctx MatchContext) *Style {
+	var matched []matchedDecl
+	for _, rule := range sheet.Rules {
+		if !ctx.matchesMedia(rule.Media) {
+			continue
+		}
+		best := Specificity{}
+		found := false
+		for _, sel := range rule.Selectors {
+			if sel.PseudoClass != pseudo {
+				continue
+			}
+			// A bare ::before has an empty base, which matches any element
+			if !MatchSelectorNode(selectorWithoutPseudo(sel), node, ctx) {
+				continue
+			}
+			if sp := selectorSpecificity(sel); !found || best.LessThan(sp) {
+				best = sp
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		for _, decl := range rule.Declarations {
+			matched = append(matched, matchedDecl{decl: decl, sp: best})
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	style := DefaultStyle()
+	style.FontSize = element.FontSize
+	winners := newCascade()
+	for _, m := range matched {
+		if m.decl.Property != "font-size" || !winners.admit(m) {
+			continue
+		}
+		if !applyCSSWideKeyword(&style, "font-size", m.decl.Value, element) {
+			if size := parseFontSizeWithContext(m.decl.Value, element.FontSize, viewportWidth, viewportHeight); size > 0 {
+				style.FontSize = size
+			}
+		}
+	}
+	for _, m := range matched {
+		if m.decl.Property == "font-size" || !winners.admit(m) {
+			continue
+		}
+		applyCascadedValue(&style, element, m.decl.Property, m.decl.Value, style.FontSize, viewportWidth, viewportHeight)
+	}
+	if style.FontWeight == 0 {
+		style.FontWeight = element.FontWeight
+	}
+
+	if !HasGeneratedContent(style.Content) {
+		return nil
+	}
+	return &style
+}
+
 // ParseInlineStyleWithContext parses inline style with font-size context for em units
 func ParseInlineStyleWithContext(styleAttr string, parentFontSize, viewportWidth, viewportHeight float64) Style {
 	style := DefaultStyle()
@@ -1643,8 +2265,40 @@ func ParseInlineStyleWithContext(styleAttr string, parentFontSize, viewportWidth
 	return style
 }
 
+// userAgentFontScale is the font-size of headings and <small> relative to
+// their parent's.
+var userAgentFontScale = map[string]float64{
+	"h1": 2, "h2": 1.5, "h3": 1.125, "h4": 1, "h5": 0.875, "h6": 0.75,
+	"small": 0.75,
+}
+
 // applyUserAgentDefaults applies browser default styles for HTML elements
 func applyUserAgentDefaults(style *Style, tagName string, fontSize float64, node *dom.Node, ctx MatchContext) {
+	// Focused links and buttons get a focus ring; pages remove it with
+	// :focus { outline: none }. Form fields show focus with their own border.
+	if node != nil && node == ctx.Focused && hasFocusRing(node) {
+		style.OutlineStyle = "auto"
+		style.OutlineWidth = 2
+		style.OutlineColor = FocusRingColor
+		style.OutlineOffset = 1
+	}
+
+	switch tagName {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "b", "strong", "th":
+		style.FontWeight = FontWeightBold
+	}
+	if scale, ok := userAgentFontScale[tagName]; ok {
+		style.FontSize = fontSize * scale
+	}
+	switch tagName {
+	case "pre", "listing", "xmp", "plaintext":
+		style.WhiteSpace = "pre"
+	case "textarea":
+		style.WhiteSpace = "pre-wrap"
+	case "caption":
+		style.TextAlign = "center"
+	}
+
 	switch tagName {
 	case "p", "dl":
 		style.MarginTop = fontSize
@@ -1679,6 +2333,15 @@ func applyUserAgentDefaults(style *Style, tagName string, fontSize float64, node
 	case "hr":
 		style.MarginTop = fontSize * 0.5
 		style.MarginBottom = fontSize * 0.5
+	case "dialog":
+		applyDialogDefaults(style, fontSize, node)
+	case "audio":
+		// Without controls audio has nothing to show (HTML §15.4.1)
+		if node != nil {
+			if _, ok := node.Attributes["controls"]; !ok {
+				style.Display = "none"
+			}
+		}
 	case "a":
 		// UA default link styling — overridable by user CSS rules via specificity cascade
 		if node != nil {
@@ -1699,6 +2362,58 @@ func applyUserAgentDefaults(style *Style, tagName string, fontSize float64, node
 	}
 }
 
+// applyDialogDefaults styles a <dialog> (WHATWG 15.3.3): hidden until
+// opened, then a bordered box centred horizontally where it was declared,
+// or centred in the viewport above the page when shown modally.
+func applyDialogDefaults(style *Style, fontSize float64, node *dom.Node) {
+	if node == nil {
+		return
+	}
+	if _, open := node.Attributes["open"]; !open {
+		style.Display = "none"
+		return
+	}
+	style.Position = "absolute"
+	style.LeftSet, style.RightSet = true, true
+	style.MarginLeftAuto, style.MarginRightAuto = true, true
+	style.WidthFitContent = true
+	if node.Modal {
+		style.Position = "fixed"
+		style.TopSet, style.BottomSet = true, true
+		style.MarginTopAuto, style.MarginBottomAuto = true, true
+	}
+	style.BorderTopWidth, style.BorderRightWidth, style.BorderBottomWidth, style.BorderLeftWidth = 3, 3, 3, 3
+	style.BorderTopStyle, style.BorderRightStyle, style.BorderBottomStyle, style.BorderLeftStyle = "solid", "solid", "solid", "solid"
+	style.PaddingTop, style.PaddingRight, style.PaddingBottom, style.PaddingLeft = fontSize, fontSize, fontSize, fontSize
+	black := color.RGBA{A: 0xff}
+	style.BorderTopColor, style.BorderRightColor, style.BorderBottomColor, style.BorderLeftColor = black, black, black, black
+	style.BackgroundColor = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	style.Color = black
+}
+
+// FocusRingColor is the color of the default focus ring.
+var FocusRingColor = color.RGBA{R: 0x10, G: 0x6b, B: 0xd6, A: 0xff}
+
+// hasFocusRing reports whether the UA stylesheet draws a focus ring around
+// node when it is focused.
+func hasFocusRing(node *dom.Node) bool {
+	if _, ok := node.Attributes["tabindex"]; ok {
+		return true
+	}
+	switch node.TagName {
+	case "a":
+		return node.Attributes["href"] != ""
+	case "button", "summary":
+		return true
+	case "input":
+		switch strings.ToLower(node.Attributes["type"]) {
+		case "button", "submit", "reset", "checkbox", "radio", "file":
+			return true
+		}
+	}
+	return false
+}
+
 // parseLineHeight handles: unitless (1.5), px (24px), pt (12pt), normal
 func parseLineHeight(value string, fontSize float64) float64 {
 	value = strings.TrimSpace(strings.ToLower(value))
@@ -1782,10 +2497,8 @@ func parseBackgroundShorthand(value string) (color.Color, string) {
 			continue
 		}
 
-		if strings.HasPrefix(part, "url(") && strings.HasSuffix(part, ")") {
-			url := part[4 : len(part)-1]
-			url = strings.Trim(url, `"'`)
-			bgImage = strings.TrimSpace(url)
+		if url, ok := backgroundURL(part); ok {
+			bgImage = url
 			continue
 		}
 
@@ -1807,6 +2520,154 @@ func parseBackgroundShorthand(value string) (color.Color, string) {
 	return bgColor, bgImage
 }
 
+// applyBackgroundShorthand sets every background longhand from a
+// background shorthand. Layers are comma-separated, topmost first; only the
+// final layer may carry the color. Longhands a layer omits take their
+// initial values.
+func applyBackgroundShorthand(style *Style, value string) {
+	layers := splitBackgroundLayers(value)
+	images := make([]string, len(layers))
+	positions := make([]string, len(layers))
+	sizes := make([]string, len(layers))
+	repeats := make([]string, len(layers))
+	attachments := make([]string, len(layers))
+	hasImage := false
+	for i, layer := range layers {
+		bgColor, bgImage := parseBackgroundShorthand(layer)
+		if bgColor != nil && i == len(layers)-1 {
+			style.BackgroundColor = bgColor
+		}
+		images[i] = bgImage
+		hasImage = hasImage || bgImage != ""
+		positions[i], sizes[i], repeats[i], attachments[i] = parseBackgroundLayout(layer)
+	}
+	if !hasImage {
+		images = nil
+	}
+	style.BackgroundImages = images
+	style.BackgroundPosition = joinBackgroundLayers(positions, "0% 0%")
+	style.BackgroundSize = joinBackgroundLayers(sizes, "auto")
+	style.BackgroundRepeat = joinBackgroundLayers(repeats, "repeat")
+	style.BackgroundAttachment = joinBackgroundLayers(attachments, "scroll")
+}
+
+// joinBackgroundLayers joins per-layer values into a list, filling layers
+// that omit the value with initial. Returns "" when no layer sets it.
+func joinBackgroundLayers(values []string, initial string) string {
+	set := false
+	for _, v := range values {
+		set = set || v != ""
+	}
+	if !set {
+		return ""
+	}
+	filled := make([]string, len(values))
+	for i, v := range values {
+		filled[i] = v
+		if v == "" {
+			filled[i] = initial
+		}
+	}
+	return strings.Join(filled, ", ")
+}
+
+// splitBackgroundLayers splits a background list on the commas that
+// separate layers, ignoring commas inside functions such as url().
+func splitBackgroundLayers(value string) []string {
+	var layers []string
+	depth, start := 0, 0
+	for i, ch := range value {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				layers = append(layers, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(layers, strings.TrimSpace(value[start:]))
+}
+
+// backgroundURL returns the address of a url() value.
+func backgroundURL(value string) (string, bool) {
+	if !strings.HasPrefix(value, "url(") || !strings.HasSuffix(value, ")") {
+		return "", false
+	}
+	url := strings.Trim(value[4:len(value)-1], `"'`)
+	return strings.TrimSpace(url), true
+}
+
+// normalizeBackgroundList lowercases a per-layer background list and
+// collapses its whitespace, so "Left  Top,center" becomes "left top, center".
+func normalizeBackgroundList(value string) string {
+	layers := splitBackgroundLayers(strings.ToLower(value))
+	for i, layer := range layers {
+		layers[i] = strings.Join(strings.Fields(layer), " ")
+	}
+	return strings.Join(layers, ", ")
+}
+
+// backgroundRepeatKeywords are the background-repeat values (CSS Backgrounds §3.4).
+var backgroundRepeatKeywords = map[string]bool{
+	"repeat": true, "repeat-x": true, "repeat-y": true,
+	"no-repeat": true, "space": true, "round": true,
+}
+
+// backgroundAttachmentKeywords are the background-attachment values (CSS
+// Backgrounds §3.5).
+var backgroundAttachmentKeywords = map[string]bool{
+	"scroll": true, "fixed": true, "local": true,
+}
+
+// backgroundPositionKeywords are the keywords of background-position.
+var backgroundPositionKeywords = map[string]bool{
+	"left": true, "right": true, "top": true, "bottom": true, "center": true,
+}
+
+// isBackgroundLength reports whether token is a length or percentage.
+func isBackgroundLength(token string) bool {
+	if token == "" {
+		return false
+	}
+	c := token[0]
+	return (c >= '0' && c <= '9') || c == '.' || c == '-' || c == '+'
+}
+
+// parseBackgroundLayout extracts the position, size and repeat components
+// of a background shorthand (position and size are separated by "/").
+// Components the shorthand omits are returned empty.
+func parseBackgroundLayout(value string) (position, size, repeat, attachment string) {
+	var positionParts, sizeParts, repeatParts []string
+	inSize := false
+	for _, part := range splitBackgroundValue(value) {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if strings.HasPrefix(part, "url(") {
+			continue
+		}
+		for i, token := range strings.Split(part, "/") {
+			if i > 0 {
+				inSize = true
+			}
+			switch {
+			case token == "":
+			case backgroundRepeatKeywords[token]:
+				repeatParts = append(repeatParts, token)
+			case backgroundAttachmentKeywords[token]:
+				attachment = token
+			case inSize && (token == "cover" || token == "contain" || token == "auto" || isBackgroundLength(token)):
+				sizeParts = append(sizeParts, token)
+			case backgroundPositionKeywords[token] || isBackgroundLength(token):
+				positionParts = append(positionParts, token)
+			}
+		}
+	}
+	return strings.Join(positionParts, " "), strings.Join(sizeParts, " "), strings.Join(repeatParts, " "), attachment
+}
+
 func parseListStyleShorthand(value string) (string, bool) {
 	for _, token := range strings.Fields(value) {
 		token = strings.ToLower(token)
@@ -2,7 +2,9 @@ package css
 
 import (
 	"browser/dom"
+	"fmt"
 	"image/color"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -421,6 +423,251 @@ func TestMatchSelectorNodePseudoClass(t *testing.T) {
 	}
 }
 
+func TestMatchSelectorNodeHover(t *testing.T) {
+	div := &dom.Node{Type: dom.Element, TagName: "div", Attributes: map[string]string{"class": "card"}}
+	link := &dom.Node{Type: dom.Element, TagName: "a", Attributes: map[string]string{"href": "/x"}, Parent: div}
+	text := &dom.Node{Type: dom.Text, Text: "go", Parent: link}
+	sibling := &dom.Node{Type: dom.Element, TagName: "a", Attributes: map[string]string{"href": "/y"}, Parent: div}
+	div.Children = []*dom.Node{link, sibling}
+	link.Children = []*dom.Node{text}
+
+	tests := []struct {
+		name     string
+		sel      Selector
+		node     *dom.Node
+		hovered  *dom.Node
+		expected bool
+	}{
+		{"hovered element matches", Selector{TagName: "a", PseudoClass: "hover"}, link, link, true},
+		{"element over its text node matches", Selector{TagName: "a", PseudoClass: "hover"}, link, text, true},
+		{"ancestor of hovered node matches", Selector{Classes: []string{"card"}, PseudoClass: "hover"}, div, text, true},
+		{"sibling of hovered node does not match", Selector{TagName: "a", PseudoClass: "hover"}, sibling, link, false},
+		{"no hovered node", Selector{TagName: "a", PseudoClass: "hover"}, link, nil, false},
+		{
+			"descendant of hovered ancestor",
+			Selector{TagName: "a", Ancestor: &Selector{Classes: []string{"card"}, PseudoClass: "hover"}},
+			sibling, link, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := MatchContext{Hovered: tt.hovered}
+			assert.Equal(t, tt.expected, MatchSelectorNode(tt.sel, tt.node, ctx))
+		})
+	}
+}
+
+func TestMatchSelectorNodeFocus(t *testing.T) {
+	form := &dom.Node{Type: dom.Element, TagName: "form", Attributes: map[string]string{}}
+	button := &dom.Node{Type: dom.Element, TagName: "button", Attributes: map[string]string{}, Parent: form}
+	other := &dom.Node{Type: dom.Element, TagName: "button", Attributes: map[string]string{}, Parent: form}
+	form.Children = []*dom.Node{button, other}
+
+	tests := []struct {
+		name     string
+		sel      Selector
+		node     *dom.Node
+		focused  *dom.Node
+		expected bool
+	}{
+		{"focused element matches", Selector{TagName: "button", PseudoClass: "focus"}, button, button, true},
+		{"other element does not match", Selector{TagName: "button", PseudoClass: "focus"}, other, button, false},
+		{"ancestor does not match :focus", Selector{TagName: "form", PseudoClass: "focus"}, form, button, false},
+		{"focus-visible follows focus", Selector{TagName: "button", PseudoClass: "focus-visible"}, button, button, true},
+		{"focus-within matches ancestors", Selector{TagName: "form", PseudoClass: "focus-within"}, form, button, true},
+		{"focus-within matches the focused element", Selector{TagName: "button", PseudoClass: "focus-within"}, button, button, true},
+		{"no focused element", Selector{TagName: "button", PseudoClass: "focus"}, button, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := MatchContext{Focused: tt.focused}
+			assert.Equal(t, tt.expected, MatchSelectorNode(tt.sel, tt.node, ctx))
+		})
+	}
+}
+
+func TestParseOutline(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	tests := []struct {
+		name   string
+		input  string
+		width  float64
+		style  string
+		color  color.Color
+		offset float64
+	}{
+		{"initial values", "", 3, "", nil, 0},
+		{"shorthand", "outline: 2px dashed red", 2, "dashed", red, 0},
+		{"shorthand in any order", "outline: red solid thin", 1, "solid", red, 0},
+		{"shorthand defaults width to medium", "outline: auto", 3, "auto", nil, 0},
+		{"none", "outline: 2px solid red; outline: none", 3, "none", nil, 0},
+		{"zero", "outline: 0", 0, "none", nil, 0},
+		{"invalid shorthand is ignored", "outline: 2px solid red; outline: 2px wavy", 2, "solid", red, 0},
+		{"longhands", "outline-style: dotted; outline-width: thick; outline-color: red; outline-offset: -2px", 5, "dotted", red, -2},
+		{"offset in em", "outline-offset: 0.25em", 3, "", nil, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := ParseInlineStyle(tt.input)
+			assert.Equal(t, tt.width, style.OutlineWidth)
+			assert.Equal(t, tt.style, style.OutlineStyle)
+			assert.True(t, colorsEqual(tt.color, style.OutlineColor), "expected %v, got %v", tt.color, style.OutlineColor)
+			assert.Equal(t, tt.offset, style.OutlineOffset)
+		})
+	}
+}
+
+func TestParseTableBorders(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		collapse   string
+		spacingX   float64
+		spacingY   float64
+		spacingSet bool
+	}{
+		{"initial values", "", "", 0, 0, false},
+		{"collapse", "border-collapse: collapse", "collapse", 0, 0, false},
+		{"separate", "border-collapse: SEPARATE", "separate", 0, 0, false},
+		{"invalid collapse is ignored", "border-collapse: collapse; border-collapse: merge", "collapse", 0, 0, false},
+		{"one length", "border-spacing: 4px", "", 4, 4, true},
+		{"horizontal and vertical", "border-spacing: 2px 0.5em", "", 2, 8, true},
+		{"zero", "border-spacing: 0", "", 0, 0, true},
+		{"negative is invalid", "border-spacing: -2px", "", 0, 0, false},
+		{"three lengths are invalid", "border-spacing: 1px 2px 3px", "", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := ParseInlineStyle(tt.input)
+			assert.Equal(t, tt.collapse, style.BorderCollapse)
+			assert.Equal(t, tt.spacingX, style.BorderSpacingX)
+			assert.Equal(t, tt.spacingY, style.BorderSpacingY)
+			assert.Equal(t, tt.spacingSet, style.BorderSpacingSet)
+		})
+	}
+}
+
+func TestParseCaptionSide(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"caption-side: bottom", "bottom"},
+		{"caption-side: TOP", "top"},
+		{"caption-side: bottom; caption-side: left", "bottom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseInlineStyle(tt.input).CaptionSide)
+		})
+	}
+}
+
+func TestParseHyphens(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"hyphens: auto", "auto"},
+		{"hyphens: NONE", "none"},
+		{"-webkit-hyphens: manual", "manual"},
+		{"hyphens: auto; hyphens: all", "auto"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseInlineStyle(tt.input).Hyphens)
+		})
+	}
+}
+
+func TestParseAspectRatio(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		ok       bool
+	}{
+		{"auto", 0, true},
+		{"16 / 9", 16.0 / 9, true},
+		{"16/9", 16.0 / 9, true},
+		{"1.5", 1.5, true},
+		{"auto 4 / 2", 2, true},
+		{"4 / 2 auto", 2, true},
+		{"0 / 1", 0, true},
+		{"16 /", 0, false},
+		{"16 9", 0, false},
+		{"-1", 0, false},
+		{"wide", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ratio, ok := parseAspectRatio(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.expected, ratio, 1e-9)
+		})
+	}
+	assert.Equal(t, 2.0, ParseInlineStyle("aspect-ratio: 2 / 1").AspectRatio)
+}
+
+func TestFocusRingDefault(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<a href="/x">link</a><button>go</button><input><p tabindex="0">para</p>`))
+	link := dom.FindElementsByTagName(doc, "a")
+	button := dom.FindElementsByTagName(doc, "button")
+	input := dom.FindElementsByTagName(doc, "input")
+	para := dom.FindElementsByTagName(doc, "p")
+
+	tests := []struct {
+		name     string
+		css      string
+		node     *dom.Node
+		focused  *dom.Node
+		expected string
+	}{
+		{"focused link", "", link, link, "auto"},
+		{"focused button", "", button, button, "auto"},
+		{"focused element with tabindex", "", para, para, "auto"},
+		{"unfocused link", "", link, button, ""},
+		{"text fields show focus with their border", "", input, input, ""},
+		{"pages can remove the ring", "a:focus { outline: none; }", link, link, "none"},
+		{"pages can restyle the ring", "button:focus { outline: 3px dashed red; }", button, button, "dashed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := MatchContext{Focused: tt.focused}
+			style := ApplyStylesheetWithContext(Parse(tt.css), tt.node, DefaultFontSize, 0, 0, ctx)
+			assert.Equal(t, tt.expected, style.OutlineStyle)
+		})
+	}
+}
+
+func TestStylesheetHasPseudoClass(t *testing.T) {
+	tests := []struct {
+		name     string
+		css      string
+		expected bool
+	}{
+		{"no hover rules", `a { color: red; } a:visited { color: purple; }`, false},
+		{"hover on subject", `a:hover { color: red; }`, true},
+		{"hover on ancestor", `li:hover a { color: red; }`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Parse(tt.css).HasPseudoClass("hover"))
+		})
+	}
+}
+
 func TestSpecificityCascade(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -486,7 +733,7 @@ func TestParseInlineStyle(t *testing.T) {
 				expectedColor := color.RGBA{0, 0, 255, 255}
 				assert.True(t, colorsEqual(s.Color, expectedColor), "Color mismatch")
 				assert.Equal(t, 24.0, s.FontSize)
-				assert.True(t, s.Bold)
+				assert.Equal(t, FontWeightBold, s.FontWeight)
 			},
 		},
 		{
@@ -768,7 +1015,7 @@ func TestParseInlineStyle(t *testing.T) {
 				assert.Equal(t, 18.0, s.FontSize)
 				assert.Equal(t, 27.0, s.LineHeight)
 				assert.True(t, s.Italic)
-				assert.True(t, s.Bold)
+				assert.Equal(t, 700, s.FontWeight)
 				assert.Equal(t, "small-caps", s.FontVariant)
 				assert.Equal(t, []string{"Open Sans", "serif"}, s.FontFamily)
 			},
@@ -780,7 +1027,7 @@ func TestParseInlineStyle(t *testing.T) {
 				assert.Equal(t, 16.0, s.FontSize)
 				assert.Equal(t, 19.2, s.LineHeight)
 				assert.False(t, s.Italic)
-				assert.False(t, s.Bold)
+				assert.Equal(t, FontWeightNormal, s.FontWeight)
 				assert.Equal(t, "normal", s.FontVariant)
 				assert.Equal(t, []string{"Arial"}, s.FontFamily)
 			},
@@ -790,7 +1037,7 @@ func TestParseInlineStyle(t *testing.T) {
 			input: "color: blue; font: nonsense 18px Arial; font-size: 20px",
 			verify: func(t *testing.T, s Style) {
 				assert.Equal(t, 20.0, s.FontSize)
-				assert.False(t, s.Bold)
+				assert.Zero(t, s.FontWeight)
 				assert.False(t, s.Italic)
 				assert.True(t, colorsEqual(s.Color, color.RGBA{0, 0, 255, 255}))
 			},
@@ -958,6 +1205,45 @@ func TestBorderWidthKeywords(t *testing.T) {
 				assert.Equal(t, 5.0, s.BorderLeftWidth)
 			},
 		},
+		{
+			name:  "border shorthand with double style",
+			input: "border: 6px double black",
+			verify: func(t *testing.T, s Style) {
+				assert.Equal(t, "double", s.BorderTopStyle)
+				assert.Equal(t, "double", s.BorderLeftStyle)
+			},
+		},
+		{
+			name:  "border-style with two values",
+			input: "border-style: dotted Solid",
+			verify: func(t *testing.T, s Style) {
+				assert.Equal(t, []string{"dotted", "solid", "dotted", "solid"},
+					[]string{s.BorderTopStyle, s.BorderRightStyle, s.BorderBottomStyle, s.BorderLeftStyle})
+			},
+		},
+		{
+			name:  "border-style with four values",
+			input: "border-style: solid dashed double none",
+			verify: func(t *testing.T, s Style) {
+				assert.Equal(t, []string{"solid", "dashed", "double", "none"},
+					[]string{s.BorderTopStyle, s.BorderRightStyle, s.BorderBottomStyle, s.BorderLeftStyle})
+			},
+		},
+		{
+			name:  "invalid border-style is ignored",
+			input: "border-style: solid; border-style: wavy",
+			verify: func(t *testing.T, s Style) {
+				assert.Equal(t, "solid", s.BorderTopStyle)
+			},
+		},
+		{
+			name:  "per-edge border style",
+			input: "border-style: solid; border-left-style: dashed",
+			verify: func(t *testing.T, s Style) {
+				assert.Equal(t, "solid", s.BorderTopStyle)
+				assert.Equal(t, "dashed", s.BorderLeftStyle)
+			},
+		},
 		{
 			name:  "border-top shorthand with keyword",
 			input: "border-top: thick solid green",
@@ -1163,6 +1449,27 @@ func TestWhiteSpaceWithContext(t *testing.T) {
 		style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
 		assert.Equal(t, "normal", style.WhiteSpace)
 	})
+
+	t.Run("supports the preserving values", func(t *testing.T) {
+		for _, value := range []string{"pre", "pre-wrap", "pre-line"} {
+			sheet := Parse(`p { white-space: ` + value + `; }`)
+			style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+			assert.Equal(t, value, style.WhiteSpace)
+		}
+	})
+
+	t.Run("inherits", func(t *testing.T) {
+		parent := DefaultStyle()
+		parent.WhiteSpace = "nowrap"
+		style := ApplyStylesheetWithParent(Parse(""), node, &parent, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+		assert.Equal(t, "nowrap", style.WhiteSpace)
+	})
+
+	t.Run("pre preserves white space by default", func(t *testing.T) {
+		pre := &dom.Node{Type: dom.Element, TagName: "pre", Attributes: map[string]string{}}
+		style := ApplyStylesheetWithParent(Parse(""), pre, nil, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+		assert.Equal(t, "pre", style.WhiteSpace)
+	})
 }
 
 func TestTextOverflowWithContext(t *testing.T) {
@@ -1317,32 +1624,96 @@ func TestFontWeightValues(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
-		expected bool
+		expected int
 	}{
-		{"normal keyword", "font-weight: normal", false},
-		{"bold keyword", "font-weight: bold", true},
-		{"lighter keyword", "font-weight: lighter", false},
-		{"bolder keyword", "font-weight: bolder", true},
-		{"numeric 500", "font-weight: 500", false},
-		{"numeric 700", "font-weight: 700", true},
-		{"invalid token ignored", "font-weight: bold; font-weight: banana", true},
-		{"out of range numeric ignored", "font-weight: bold; font-weight: 950", true},
-		{"invalid numeric step ignored", "font-weight: bold; font-weight: 650", true},
+		{"normal keyword", "font-weight: normal", 400},
+		{"bold keyword", "font-weight: bold", 700},
+		{"lighter keyword", "font-weight: lighter", 100},
+		{"bolder keyword", "font-weight: bolder", 700},
+		{"numeric 300", "font-weight: 300", 300},
+		{"numeric 500", "font-weight: 500", 500},
+		{"numeric 600", "font-weight: 600", 600},
+		{"numeric 800", "font-weight: 800", 800},
+		{"numbers between steps", "font-weight: 650", 650},
+		{"not declared", "color: red", 0},
+		{"invalid token ignored", "font-weight: bold; font-weight: banana", 700},
+		{"out of range numeric ignored", "font-weight: bold; font-weight: 1001", 700},
+		{"zero ignored", "font-weight: bold; font-weight: 0", 700},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			style := ParseInlineStyle(tt.input)
-			assert.Equal(t, tt.expected, style.Bold)
+			assert.Equal(t, tt.expected, style.FontWeight)
 		})
 	}
 }
 
+func TestRelativeFontWeight(t *testing.T) {
+	tests := []struct {
+		keyword   string
+		inherited int
+		expected  int
+	}{
+		{"bolder", 100, 400},
+		{"bolder", 0, 700},
+		{"bolder", 400, 700},
+		{"bolder", 600, 900},
+		{"bolder", 900, 900},
+		{"bolder", 950, 950},
+		{"lighter", 50, 50},
+		{"lighter", 400, 100},
+		{"lighter", 600, 400},
+		{"lighter", 700, 400},
+		{"lighter", 800, 700},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s than %d", tt.keyword, tt.inherited), func(t *testing.T) {
+			weight, ok := relativeFontWeight(tt.keyword, tt.inherited)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expected, weight)
+		})
+	}
+
+	_, ok := relativeFontWeight("bold", 400)
+	assert.False(t, ok)
+}
+
+func TestIsMonospaceFamily(t *testing.T) {
+	assert.True(t, IsMonospaceFamily([]string{"Menlo", "monospace"}))
+	assert.True(t, IsMonospaceFamily([]string{"Fira Code", "Courier New"}))
+	assert.False(t, IsMonospaceFamily([]string{"Helvetica", "sans-serif"}))
+	assert.False(t, IsMonospaceFamily(nil))
+}
+
+func TestFontWeightCascade(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<h1><span class="light">a</span><em>b</em></h1><p><strong class="more">c</strong></p>`))
+	h1 := dom.FindElementsByTagName(doc, "h1")
+	span := dom.FindElementsByTagName(doc, "span")
+	em := dom.FindElementsByTagName(doc, "em")
+	strong := dom.FindElementsByTagName(doc, "strong")
+	sheet := Parse(`.light { font-weight: lighter } .more { font-weight: bolder } p { font-weight: 300 }`)
+
+	h1Style := ApplyStylesheetWithParent(sheet, h1, nil, 0, 0, MatchContext{})
+	assert.Equal(t, FontWeightBold, h1Style.FontWeight, "headings are bold by default")
+
+	spanStyle := ApplyStylesheetWithParent(sheet, span, &h1Style, 0, 0, MatchContext{})
+	assert.Equal(t, 400, spanStyle.FontWeight, "lighter is relative to the parent")
+
+	emStyle := ApplyStylesheetWithParent(sheet, em, &h1Style, 0, 0, MatchContext{})
+	assert.Equal(t, FontWeightBold, emStyle.FontWeight, "the weight is inherited")
+
+	pStyle := ApplyStylesheetWithParent(sheet, strong.Parent, nil, 0, 0, MatchContext{})
+	strongStyle := ApplyStylesheetWithParent(sheet, strong, &pStyle, 0, 0, MatchContext{})
+	assert.Equal(t, 400, strongStyle.FontWeight, "bolder than 300 is 400, overriding the UA bold")
+}
+
 func TestInlineFontShorthandImportant(t *testing.T) {
 	style := ParseInlineStyle(`font: italic bold 20px/2 Arial !important; font-weight: normal; line-height: 1`)
 	assert.Equal(t, 20.0, style.FontSize)
 	assert.Equal(t, 40.0, style.LineHeight)
-	assert.True(t, style.Bold)
+	assert.Equal(t, FontWeightBold, style.FontWeight)
 	assert.True(t, style.Italic)
 	assert.Equal(t, []string{"Arial"}, style.FontFamily)
 }
@@ -1352,7 +1723,7 @@ func TestParseInlineStyleWithContextFontShorthand(t *testing.T) {
 	assert.Equal(t, 30.0, style.FontSize)
 	assert.Equal(t, 60.0, style.LineHeight)
 	assert.True(t, style.Italic)
-	assert.False(t, style.Bold)
+	assert.Equal(t, FontWeightNormal, style.FontWeight)
 	assert.Equal(t, "normal", style.FontVariant)
 	assert.Equal(t, []string{"Open Sans", "serif"}, style.FontFamily)
 }
@@ -1380,7 +1751,7 @@ func TestStylesheetFontShorthandCascade(t *testing.T) {
 		style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
 		assert.Equal(t, 20.0, style.FontSize)
 		assert.Equal(t, 40.0, style.LineHeight)
-		assert.True(t, style.Bold)
+		assert.Equal(t, FontWeightBold, style.FontWeight)
 		assert.True(t, style.Italic)
 		assert.Equal(t, []string{"Arial"}, style.FontFamily)
 	})
@@ -1470,40 +1841,40 @@ func TestParseBackgroundShorthand(t *testing.T) {
 
 func TestBackgroundShorthandInlineStyle(t *testing.T) {
 	tests := []struct {
-		name          string
-		input         string
-		expectedColor color.Color
-		expectedImage string
+		name           string
+		input          string
+		expectedColor  color.Color
+		expectedImages []string
 	}{
 		{
-			name:          "background color only",
-			input:         "background: red",
-			expectedColor: color.RGBA{255, 0, 0, 255},
-			expectedImage: "",
+			name:           "background color only",
+			input:          "background: red",
+			expectedColor:  color.RGBA{255, 0, 0, 255},
+			expectedImages: nil,
 		},
 		{
-			name:          "background hex color",
-			input:         "background: #0000ff",
-			expectedColor: color.RGBA{0, 0, 255, 255},
-			expectedImage: "",
+			name:           "background hex color",
+			input:          "background: #0000ff",
+			expectedColor:  color.RGBA{0, 0, 255, 255},
+			expectedImages: nil,
 		},
 		{
-			name:          "background url only",
-			input:         "background: url(test.png)",
-			expectedColor: nil,
-			expectedImage: "test.png",
+			name:           "background url only",
+			input:          "background: url(test.png)",
+			expectedColor:  nil,
+			expectedImages: []string{"test.png"},
 		},
 		{
-			name:          "background color and url",
-			input:         "background: green url(bg.jpg)",
-			expectedColor: color.RGBA{0, 128, 0, 255},
-			expectedImage: "bg.jpg",
+			name:           "background color and url",
+			input:          "background: green url(bg.jpg)",
+			expectedColor:  color.RGBA{0, 128, 0, 255},
+			expectedImages: []string{"bg.jpg"},
 		},
 		{
-			name:          "background url and color reversed",
-			input:         "background: url(bg.jpg) purple",
-			expectedColor: color.RGBA{128, 0, 128, 255},
-			expectedImage: "bg.jpg",
+			name:           "background url and color reversed",
+			input:          "background: url(bg.jpg) purple",
+			expectedColor:  color.RGBA{128, 0, 128, 255},
+			expectedImages: []string{"bg.jpg"},
 		},
 	}
 
@@ -1512,8 +1883,8 @@ func TestBackgroundShorthandInlineStyle(t *testing.T) {
 			style := ParseInlineStyle(tt.input)
 			assert.True(t, colorsEqual(style.BackgroundColor, tt.expectedColor),
 				"BackgroundColor mismatch: expected %v, got %v", tt.expectedColor, style.BackgroundColor)
-			assert.Equal(t, tt.expectedImage, style.BackgroundImage,
-				"BackgroundImage mismatch")
+			assert.Equal(t, tt.expectedImages, style.BackgroundImages,
+				"BackgroundImages mismatch")
 		})
 	}
 }
@@ -1541,6 +1912,121 @@ func TestBackgroundSize(t *testing.T) {
 	}
 }
 
+func TestBackgroundRepeatAndPosition(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		expectedRepeat   string
+		expectedPosition string
+		expectedSize     string
+	}{
+		{"repeat longhand", "background-repeat: Repeat-X", "repeat-x", "", ""},
+		{"two-value repeat", "background-repeat: repeat  no-repeat", "repeat no-repeat", "", ""},
+		{"position keywords", "background-position: Right Bottom", "", "right bottom", ""},
+		{"position lengths", "background-position: 10px 50%", "", "10px 50%", ""},
+		{"shorthand repeat and position", "background: url(a/b.png) no-repeat center", "no-repeat", "center", ""},
+		{"shorthand position and size", "background: red url(x.png) left top / cover repeat-y", "repeat-y", "left top", "cover"},
+		{"shorthand size without spaces", "background: url(x.png) center/50% auto", "", "center", "50% auto"},
+		{"shorthand color only", "background: #fff", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := ParseInlineStyle(tt.input)
+			assert.Equal(t, tt.expectedRepeat, style.BackgroundRepeat)
+			assert.Equal(t, tt.expectedPosition, style.BackgroundPosition)
+			assert.Equal(t, tt.expectedSize, style.BackgroundSize)
+		})
+	}
+}
+
+func TestBackgroundLayers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []BackgroundLayer
+	}{
+		{
+			name:     "single image",
+			input:    "background-image: url(a.png)",
+			expected: []BackgroundLayer{{Image: "a.png"}},
+		},
+		{
+			name:  "image list with placement lists",
+			input: "background-image: url(a.png), url('b.png'); background-position: Left  Top,center; background-repeat: no-repeat, repeat-x",
+			expected: []BackgroundLayer{
+				{Image: "a.png", Position: "left top", Repeat: "no-repeat"},
+				{Image: "b.png", Position: "center", Repeat: "repeat-x"},
+			},
+		},
+		{
+			name:  "shorter lists repeat",
+			input: "background-image: url(a.png), url(b.png), url(c.png); background-size: cover, 10px",
+			expected: []BackgroundLayer{
+				{Image: "a.png", Size: "cover"},
+				{Image: "b.png", Size: "10px"},
+				{Image: "c.png", Size: "cover"},
+			},
+		},
+		{
+			name:     "none layers are skipped",
+			input:    "background-image: none, url(b.png)",
+			expected: []BackgroundLayer{{Image: "b.png"}},
+		},
+		{
+			name:     "unsupported layers keep their place",
+			input:    "background-image: linear-gradient(red, blue), url(b.png), NONE; background-repeat: repeat-x, no-repeat, repeat-y",
+			expected: []BackgroundLayer{{Image: "b.png", Repeat: "no-repeat"}},
+		},
+		{
+			name:  "shorthand layers",
+			input: "background: url(top.png) no-repeat right / 20px, url(sprite.png?v=1,2) repeat-x, navy",
+			expected: []BackgroundLayer{
+				{Image: "top.png", Position: "right", Size: "20px", Repeat: "no-repeat"},
+				{Image: "sprite.png?v=1,2", Position: "0% 0%", Size: "auto", Repeat: "repeat-x"},
+			},
+		},
+		{
+			name:  "attachment",
+			input: "background-image: url(a.png), url(b.png); background-attachment: FIXED, scroll",
+			expected: []BackgroundLayer{
+				{Image: "a.png", Attachment: "fixed"},
+				{Image: "b.png", Attachment: "scroll"},
+			},
+		},
+		{
+			name:  "shorthand attachment",
+			input: "background: url(hero.jpg) center / cover fixed, url(b.png)",
+			expected: []BackgroundLayer{
+				{Image: "hero.jpg", Position: "center", Size: "cover", Attachment: "fixed"},
+				{Image: "b.png", Position: "0% 0%", Size: "auto", Attachment: "scroll"},
+			},
+		},
+		{
+			name:     "none",
+			input:    "background-image: none",
+			expected: nil,
+		},
+		{
+			name:     "uppercase none",
+			input:    "background-image: None, NONE",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := ParseInlineStyle(tt.input)
+			assert.Equal(t, tt.expected, style.BackgroundLayers())
+		})
+	}
+
+	style := ParseInlineStyle("background: url(a.png), url(b.png) red")
+	assert.True(t, colorsEqual(style.BackgroundColor, color.RGBA{255, 0, 0, 255}), "color comes from the final layer")
+	style = ParseInlineStyle("background: url(a.png) red, url(b.png)")
+	assert.Nil(t, style.BackgroundColor, "color outside the final layer is ignored")
+}
+
 func TestChildSelectorMatching(t *testing.T) {
 	// Build DOM: <ul><li>direct</li></ul>
 	ul := &dom.Node{Type: dom.Element, TagName: "ul"}
@@ -1665,7 +2151,7 @@ func TestFirstLineStyleCollection(t *testing.T) {
 					"expected first-line color to match")
 			}
 			if tt.checkBold {
-				assert.True(t, style.FirstLineStyle.Bold, "expected first-line bold")
+				assert.Equal(t, FontWeightBold, style.FirstLineStyle.FontWeight, "expected first-line bold")
 			}
 			if tt.checkBgColor != nil {
 				assert.True(t, colorsEqual(tt.checkBgColor, style.FirstLineStyle.BackgroundColor),
@@ -1692,3 +2178,258 @@ func TestFirstLineStyleFiltersMarginPadding(t *testing.T) {
 	// color should be applied
 	assert.True(t, colorsEqual(color.RGBA{0, 128, 0, 255}, style.FirstLineStyle.Color))
 }
+
+func TestBeforeAfterStyleCollection(t *testing.T) {
+	tests := []struct {
+		name          string
+		cssText       string
+		classes       string
+		expectBefore  string // expected Content, empty = no BeforeStyle
+		expectAfter   string
+		expectedColor color.Color
+	}{
+		{"p::before with content", `p::before { content: "A"; }`, "", `"A"`, "", nil},
+		{"single colon p:after", `p:after { content: "Z"; }`, "", "", `"Z"`, nil},
+		{"no content generates nothing", `p::before { color: red; }`, "", "", "", nil},
+		{"content none generates nothing", `p::before { content: none; }`, "", "", "", nil},
+		{"bare ::before matches any element", `::before { content: "*"; }`, "", `"*"`, "", nil},
+		{"class does not match", `.note::before { content: "!"; }`, "", "", "", nil},
+		{"class matches", `.note::before { content: "!"; color: red; }`, "note", `"!"`, "", color.RGBA{255, 0, 0, 255}},
+		{
+			"higher specificity wins",
+			`p.note::before { content: "B"; } p::before { content: "A"; }`,
+			"note", `"B"`, "", nil,
+		},
+		{
+			"later rule wins at equal specificity",
+			`p::after { content: "1"; } p::after { content: "2"; }`,
+			"", "", `"2"`, nil,
+		},
+		{
+			"important wins",
+			`p::before { content: "A" !important; } p.note::before { content: "B"; }`,
+			"note", `"A"`, "", nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &dom.Node{
+				Type:       dom.Element,
+				TagName:    "p",
+				Attributes: map[string]string{"class": tt.classes},
+			}
+			style := ApplyStylesheetWithContext(Parse(tt.cssText), node, 16.0, 800, 600, MatchContext{})
+
+			if tt.expectBefore == "" {
+				assert.Nil(t, style.BeforeStyle)
+			} else if assert.NotNil(t, style.BeforeStyle) {
+				assert.Equal(t, tt.expectBefore, style.BeforeStyle.Content)
+				if tt.expectedColor != nil {
+					assert.True(t, colorsEqual(tt.expectedColor, style.BeforeStyle.Color))
+				}
+			}
+			if tt.expectAfter == "" {
+				assert.Nil(t, style.AfterStyle)
+			} else if assert.NotNil(t, style.AfterStyle) {
+				assert.Equal(t, tt.expectAfter, style.AfterStyle.Content)
+			}
+			// Pseudo-element rules never style the element itself
+			assert.Equal(t, "", style.Content)
+		})
+	}
+}
+
+func TestBeforeStyleEmUnits(t *testing.T) {
+	node := &dom.Node{Type: dom.Element, TagName: "p", Attributes: map[string]string{}}
+	sheet := Parse(`p { font-size: 20px; } p::before { content: "x"; font-size: 0.5em; }`)
+	style := ApplyStylesheetWithContext(sheet, node, 16.0, 800, 600, MatchContext{})
+
+	assert.NotNil(t, style.BeforeStyle)
+	assert.Equal(t, 10.0, style.BeforeStyle.FontSize)
+}
+
+func TestBeforeStyleBaseSelector(t *testing.T) {
+	section := &dom.Node{Type: dom.Element, TagName: "section", Attributes: map[string]string{}}
+	node := &dom.Node{Type: dom.Element, TagName: "p", Attributes: map[string]string{}, Parent: section}
+	section.Children = []*dom.Node{node}
+
+	tests := []struct {
+		name     string
+		cssText  string
+		ctx      MatchContext
+		expected string // expected Content, empty = no BeforeStyle
+	}{
+		{"direct parent matches", `section > ::before { content: "S"; }`, MatchContext{}, `"S"`},
+		{"direct parent does not match", `div > ::before { content: "D"; }`, MatchContext{}, ""},
+		{"parent not hovered", `section:hover > ::before { content: "H"; }`, MatchContext{}, ""},
+		{"parent hovered", `section:hover > ::before { content: "H"; }`, MatchContext{Hovered: node}, `"H"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style := ApplyStylesheetWithContext(Parse(tt.cssText), node, 16.0, 800, 600, tt.ctx)
+			if tt.expected == "" {
+				assert.Nil(t, style.BeforeStyle)
+			} else if assert.NotNil(t, style.BeforeStyle) {
+				assert.Equal(t, tt.expected, style.BeforeStyle.Content)
+			}
+		})
+	}
+}
+
+func TestCSSWideKeywords(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	parent := DefaultStyle()
+	parent.FontSize = 20
+	parent.Color = red
+	parent.BackgroundColor = red
+	parent.MarginLeft = 12
+	parent.TextAlign = "center"
+	parent.BorderTopWidth = 3
+
+	tests := []struct {
+		name  string
+		css   string
+		check func(t *testing.T, s Style)
+	}{
+		{"inherit color", "p { color: blue; color: inherit; }", func(t *testing.T, s Style) {
+			assert.Equal(t, red, s.Color)
+		}},
+		{"inherit non-inherited property", "p { background-color: inherit; }", func(t *testing.T, s Style) {
+			assert.Equal(t, red, s.BackgroundColor)
+		}},
+		{"inherit font-size", "p { font-size: inherit; }", func(t *testing.T, s Style) {
+			assert.Equal(t, 20.0, s.FontSize)
+		}},
+		{"initial font-size", "p { font-size: initial; }", func(t *testing.T, s Style) {
+			assert.Equal(t, DefaultFontSize, s.FontSize)
+		}},
+		{"initial resets UA margin", "p { margin: initial; }", func(t *testing.T, s Style) {
+			assert.Equal(t, 0.0, s.MarginTop)
+			assert.Equal(t, 0.0, s.MarginBottom)
+		}},
+		{"initial color", "p { color: initial; }", func(t *testing.T, s Style) {
+			assert.Equal(t, color.Black, s.Color)
+		}},
+		{"unset inherited property inherits", "p { text-align: right; text-align: unset; }", func(t *testing.T, s Style) {
+			assert.Equal(t, "center", s.TextAlign)
+		}},
+		{"unset non-inherited property resets", "p { margin-left: 5px; margin-left: unset; }", func(t *testing.T, s Style) {
+			assert.Equal(t, 0.0, s.MarginLeft)
+		}},
+		{"inherit shorthand", "p { border: inherit; }", func(t *testing.T, s Style) {
+			assert.Equal(t, 3.0, s.BorderTopWidth)
+		}},
+		{"keyword loses to more specific rule", "p.x { color: blue; } p { color: inherit; }", func(t *testing.T, s Style) {
+			assert.Equal(t, color.RGBA{0, 0, 255, 255}, s.Color)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &dom.Node{Type: dom.Element, TagName: "p", Attributes: map[string]string{"class": "x"}}
+			style := ApplyStylesheetWithParent(Parse(tt.css), node, &parent, 800, 600, MatchContext{})
+			tt.check(t, style)
+		})
+	}
+}
+
+func TestCSSWideKeywordsWithoutParent(t *testing.T) {
+	node := &dom.Node{Type: dom.Element, TagName: "html", Attributes: map[string]string{}}
+	style := ApplyStylesheetWithParent(Parse("html { color: inherit; font-size: inherit; }"), node, nil, 800, 600, MatchContext{})
+
+	assert.Equal(t, color.Black, style.Color)
+	assert.Equal(t, DefaultFontSize, style.FontSize)
+}
+
+func TestBeforeStyleInherit(t *testing.T) {
+	node := &dom.Node{Type: dom.Element, TagName: "p", Attributes: map[string]string{}}
+	sheet := Parse(`p { border-top: 2px solid red; } p::before { content: "x"; border-top: inherit; }`)
+	style := ApplyStylesheetWithContext(sheet, node, 16.0, 800, 600, MatchContext{})
+
+	assert.NotNil(t, style.BeforeStyle)
+	assert.Equal(t, 2.0, style.BeforeStyle.BorderTopWidth)
+}
+
+func TestInlineStyleCascade(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	tests := []struct {
+		name     string
+		css      string
+		inline   string
+		expected color.Color
+	}{
+		{"inline beats id selector", "#x { color: red; }", "color: blue", blue},
+		{"important rule beats inline", "p { color: red !important; }", "color: blue", red},
+		{"important inline beats important rule", "#x { color: red !important; }", "color: blue !important", blue},
+		{"later inline declaration wins", "", "color: red; color: blue", blue},
+		{"important inline beats later inline", "", "color: blue !important; color: red", blue},
+		{"more specific important rule wins", "#x { color: blue !important; } p { color: red !important; }", "", blue},
+		{"zero inline value still overrides", "p { color: red; }", "color: initial", color.Black},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := map[string]string{"id": "x"}
+			if tt.inline != "" {
+				attrs["style"] = tt.inline
+			}
+			node := &dom.Node{Type: dom.Element, TagName: "p", Attributes: attrs}
+			style := ApplyStylesheetWithContext(Parse(tt.css), node, 16.0, 800, 600, MatchContext{})
+			assert.Equal(t, tt.expected, style.Color)
+		})
+	}
+}
+
+func TestInlineStyleResolvesAgainstParent(t *testing.T) {
+	parent := DefaultStyle()
+	parent.FontSize = 20
+	parent.PaddingLeft = 8
+	node := &dom.Node{Type: dom.Element, TagName: "p", Attributes: map[string]string{
+		"style": "font-size: 2em; margin: 0; padding-left: inherit; width: 2em",
+	}}
+	style := ApplyStylesheetWithParent(Parse("p { margin: 10px; }"), node, &parent, 800, 600, MatchContext{})
+
+	assert.Equal(t, 40.0, style.FontSize)
+	assert.Equal(t, 0.0, style.MarginTop, "inline zero overrides the stylesheet")
+	assert.Equal(t, 8.0, style.PaddingLeft)
+	assert.Equal(t, 80.0, style.Width, "em resolves against the element's own font-size")
+}
+
+func TestPresentationalHintsRankBelowRules(t *testing.T) {
+	node := &dom.Node{Type: dom.Element, TagName: "td", Attributes: map[string]string{"align": "center", "valign": "top", "bgcolor": "red"}}
+
+	style := ApplyStylesheetWithContext(Parse(""), node, 16.0, 800, 600, MatchContext{})
+	assert.Equal(t, "center", style.TextAlign)
+	assert.Equal(t, "top", style.VerticalAlign)
+	assert.Equal(t, color.RGBA{255, 0, 0, 255}, style.BackgroundColor)
+
+	style = ApplyStylesheetWithContext(Parse("td { text-align: right; }"), node, 16.0, 800, 600, MatchContext{})
+	assert.Equal(t, "right", style.TextAlign)
+}
+
+func TestZIndex(t *testing.T) {
+	tests := []struct {
+		value string
+		z     int
+		set   bool
+	}{
+		{"10", 10, true},
+		{"-1", -1, true},
+		{"0", 0, true},
+		{"auto", 0, false},
+		{"1.5", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var style Style
+			applyDeclaration(&style, "z-index", tt.value)
+			assert.Equal(t, tt.z, style.ZIndex)
+			assert.Equal(t, tt.set, style.ZIndexSet)
+		})
+	}
+}
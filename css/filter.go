@@ -0,0 +1,78 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Filter is one function of a filter list (Filter Effects 1 §5). Amount is
+// the function's argument with percentages turned into fractions; for blur
+// it is the standard deviation in pixels.
+type Filter struct {
+	Name   string
+	Amount float64
+}
+
+// parseFilter parses a filter value into its functions, applied in order.
+// "none" yields no filters; an unknown function or bad argument invalidates
+// the whole value.
+func parseFilter(value string, fontSize, viewportWidth, viewportHeight float64) ([]Filter, bool) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "none" {
+		return nil, true
+	}
+	var filters []Filter
+	for value != "" {
+		open := strings.IndexByte(value, '(')
+		end := strings.IndexByte(value, ')')
+		if open <= 0 || end < open {
+			return nil, false
+		}
+		name := value[:open]
+		arg := strings.TrimSpace(value[open+1 : end])
+		value = strings.TrimSpace(value[end+1:])
+
+		filter := Filter{Name: name}
+		switch name {
+		case "blur":
+			if arg != "" {
+				radius, ok := parseShadowLength(arg, fontSize, viewportWidth, viewportHeight)
+				if !ok || radius < 0 {
+					return nil, false
+				}
+				filter.Amount = radius
+			}
+		case "grayscale", "invert", "sepia", "opacity", "brightness", "contrast":
+			filter.Amount = 1
+			if arg != "" {
+				amount, ok := parseFilterAmount(arg)
+				if !ok {
+					return nil, false
+				}
+				// These interpolate towards a full effect; more is clamped
+				if name != "brightness" && name != "contrast" && amount > 1 {
+					amount = 1
+				}
+				filter.Amount = amount
+			}
+		default:
+			return nil, false
+		}
+		filters = append(filters, filter)
+	}
+	return filters, len(filters) > 0
+}
+
+// parseFilterAmount parses a non-negative number or percentage argument.
+func parseFilterAmount(arg string) (float64, bool) {
+	scale := 1.0
+	if strings.HasSuffix(arg, "%") {
+		arg = strings.TrimSuffix(arg, "%")
+		scale = 0.01
+	}
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n * scale, true
+}
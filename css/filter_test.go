@@ -0,0 +1,50 @@
+package css
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Filter
+		ok       bool
+	}{
+		{"none", nil, true},
+		{"grayscale(100%)", []Filter{{"grayscale", 1}}, true},
+		{"grayscale()", []Filter{{"grayscale", 1}}, true},
+		{"Grayscale(0.5)", []Filter{{"grayscale", 0.5}}, true},
+		{"grayscale(150%)", []Filter{{"grayscale", 1}}, true},
+		{"brightness(150%)", []Filter{{"brightness", 1.5}}, true},
+		{"contrast(2)", []Filter{{"contrast", 2}}, true},
+		{"blur(4px)", []Filter{{"blur", 4}}, true},
+		{"blur(0.5em)", []Filter{{"blur", 8}}, true},
+		{"blur()", []Filter{{"blur", 0}}, true},
+		{"grayscale(1) blur(2px) opacity(50%)", []Filter{{"grayscale", 1}, {"blur", 2}, {"opacity", 0.5}}, true},
+		{"blur(4)", nil, false},
+		{"brightness(-1)", nil, false},
+		{"grayscale(1) hue-rotate(90deg)", nil, false},
+		{"url(#svg-filter)", nil, false},
+		{"grayscale(1", nil, false},
+		{"", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			filters, ok := parseFilter(tt.input, 16, 0, 0)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, filters)
+		})
+	}
+}
+
+func TestFilterNotInherited(t *testing.T) {
+	style := ParseInlineStyle("filter: grayscale(1) brightness(0.5)")
+	assert.Equal(t, []Filter{{"grayscale", 1}, {"brightness", 0.5}}, style.Filters)
+	assert.False(t, inheritedProperties["filter"])
+
+	style = ParseInlineStyle("filter: grayscale(1) bogus(2)")
+	assert.Nil(t, style.Filters, "invalid values are ignored")
+}
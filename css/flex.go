@@ -0,0 +1,83 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Keyword values accepted by justify-content and align-items. The
+// start/end/left/right forms from CSS Box Alignment map onto the flex-*
+// ones at layout time.
+var (
+	flexJustifyValues = map[string]bool{
+		"flex-start": true, "flex-end": true, "center": true, "start": true, "end": true,
+		"left": true, "right": true, "space-between": true, "space-around": true,
+		"space-evenly": true, "normal": true,
+	}
+	flexAlignValues = map[string]bool{
+		"flex-start": true, "flex-end": true, "center": true, "start": true, "end": true,
+		"self-start": true, "self-end": true, "baseline": true, "stretch": true, "normal": true,
+	}
+)
+
+// applyFlexFlow sets flex-direction and flex-wrap from the flex-flow
+// shorthand; the two may appear in either order.
+func applyFlexFlow(style *Style, value string) {
+	for _, token := range strings.Fields(value) {
+		switch token {
+		case "row", "row-reverse", "column", "column-reverse":
+			style.FlexDirection = token
+		case "nowrap", "wrap", "wrap-reverse":
+			style.FlexWrap = token
+		}
+	}
+}
+
+// applyFlexShorthand expands the flex shorthand (CSS Flexbox §7.1). A
+// unitless number is flex-grow then flex-shrink; anything else is the
+// basis, which is 0 when the shorthand gives numbers only.
+func applyFlexShorthand(style *Style, value string) {
+	switch value {
+	case "none":
+		style.FlexGrow, style.FlexShrink, style.FlexBasis = 0, 0, "auto"
+		style.FlexShrinkSet = true
+		return
+	case "auto":
+		style.FlexGrow, style.FlexShrink, style.FlexBasis = 1, 1, "auto"
+		style.FlexShrinkSet = true
+		return
+	}
+
+	grow, shrink, basis := 1.0, 1.0, "0"
+	var numbers int
+	for _, token := range strings.Fields(value) {
+		n, err := strconv.ParseFloat(token, 64)
+		switch {
+		case err == nil && numbers == 0:
+			grow = n
+			numbers++
+		case err == nil && numbers == 1:
+			shrink = n
+			numbers++
+		default:
+			basis = token
+		}
+	}
+	if grow < 0 || shrink < 0 {
+		return
+	}
+	style.FlexGrow, style.FlexShrink, style.FlexBasis = grow, shrink, basis
+	style.FlexShrinkSet = true
+}
+
+// parseGap resolves a row-gap or column-gap length; "normal" is 0 for
+// flex containers.
+func parseGap(value string, fontSize, viewportWidth, viewportHeight float64) float64 {
+	if value == "normal" {
+		return 0
+	}
+	if g := ParseSizeWithContext(value, fontSize, viewportWidth, viewportHeight); g > 0 {
+		return g
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package css
+
+import (
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlexShorthand(t *testing.T) {
+	tests := []struct {
+		value     string
+		grow      float64
+		shrink    float64
+		basis     string
+		shrinkSet bool
+	}{
+		{"1", 1, 1, "0", true},
+		{"2 3", 2, 3, "0", true},
+		{"1 0 200px", 1, 0, "200px", true},
+		{"30%", 1, 1, "30%", true},
+		{"0 1 auto", 0, 1, "auto", true},
+		{"none", 0, 0, "auto", true},
+		{"auto", 1, 1, "auto", true},
+		{"-1", 0, 0, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var style Style
+			applyDeclaration(&style, "flex", tt.value)
+			assert.Equal(t, tt.grow, style.FlexGrow)
+			assert.Equal(t, tt.shrink, style.FlexShrink)
+			assert.Equal(t, tt.basis, style.FlexBasis)
+			assert.Equal(t, tt.shrinkSet, style.FlexShrinkSet)
+		})
+	}
+}
+
+func TestFlexContainerProperties(t *testing.T) {
+	node := &dom.Node{Type: dom.Element, TagName: "div", Attributes: map[string]string{}}
+
+	t.Run("longhands", func(t *testing.T) {
+		sheet := Parse(`div { display: flex; flex-direction: column; flex-wrap: wrap; justify-content: space-between; align-items: center; row-gap: 4px; column-gap: 1em; }`)
+		style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+		assert.Equal(t, "flex", style.Display)
+		assert.Equal(t, "column", style.FlexDirection)
+		assert.Equal(t, "wrap", style.FlexWrap)
+		assert.Equal(t, "space-between", style.JustifyContent)
+		assert.Equal(t, "center", style.AlignItems)
+		assert.Equal(t, 4.0, style.RowGap)
+		assert.Equal(t, 16.0, style.ColumnGap)
+	})
+
+	t.Run("flex-flow in either order", func(t *testing.T) {
+		sheet := Parse(`div { flex-flow: wrap-reverse row-reverse; }`)
+		style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+		assert.Equal(t, "row-reverse", style.FlexDirection)
+		assert.Equal(t, "wrap-reverse", style.FlexWrap)
+	})
+
+	t.Run("gap sets both axes", func(t *testing.T) {
+		sheet := Parse(`div { gap: 8px 12px; }`)
+		style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+		assert.Equal(t, 8.0, style.RowGap)
+		assert.Equal(t, 12.0, style.ColumnGap)
+	})
+
+	t.Run("invalid keywords are ignored", func(t *testing.T) {
+		sheet := Parse(`div { flex-direction: sideways; justify-content: middle; }`)
+		style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+		assert.Empty(t, style.FlexDirection)
+		assert.Empty(t, style.JustifyContent)
+	})
+}
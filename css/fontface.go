@@ -0,0 +1,89 @@
+package css
+
+import "strings"
+
+// FontFace is an @font-face rule (CSS Fonts §4): a downloadable font that
+// pages use by naming Family in font-family.
+type FontFace struct {
+	Family  string
+	Sources []FontSource // in preference order
+	Weight  int          // font-weight descriptor; normal when absent
+	Italic  bool
+	BaseURL string // URL of the stylesheet the rule came from, for relative sources; "" if unknown
+}
+
+// sheetBaseRule names the at-rule SheetBase writes.
+const sheetBaseRule = "-browser-sheet-base"
+
+// SheetBase returns the line to put before a stylesheet loaded from
+// baseURL when several sheets are joined into one string. Parse records it
+// as the BaseURL of the @font-face rules that follow, up to the next one.
+func SheetBase(baseURL string) string {
+	return "@" + sheetBaseRule + " \"" + strings.ReplaceAll(baseURL, `"`, "%22") + "\";\n"
+}
+
+// FontSource is one entry of an @font-face src list: a url() with an
+// optional format() hint, or a local() font name.
+type FontSource struct {
+	URL    string
+	Format string // lowercased format() hint, e.g. "woff2"; "" if absent
+	Local  string // installed font name for local() sources
+}
+
+// parseFontFace builds a FontFace from the declarations of an @font-face
+// block. Rules without a font-family or a usable src are dropped.
+func parseFontFace(decls []Declaration) (FontFace, bool) {
+	face := FontFace{Weight: FontWeightNormal}
+	for _, decl := range decls {
+		switch strings.ToLower(decl.Property) {
+		case "font-family":
+			if families := ParseFontFamily(decl.Value); len(families) == 1 {
+				face.Family = families[0]
+			}
+		case "src":
+			face.Sources = parseFontSources(decl.Value)
+		case "font-weight":
+			// Ranges ("100 900") describe variable fonts; match on the low end
+			if fields := strings.Fields(decl.Value); len(fields) > 0 {
+				if weight, ok := parseFontWeightValue(fields[0]); ok {
+					face.Weight = weight
+				}
+			}
+		case "font-style":
+			style := strings.ToLower(strings.TrimSpace(decl.Value))
+			face.Italic = strings.HasPrefix(style, "italic") || strings.HasPrefix(style, "oblique")
+		}
+	}
+	return face, face.Family != "" && len(face.Sources) > 0
+}
+
+// parseFontSources parses an @font-face src list. Entries that are neither
+// url() nor local() are skipped.
+func parseFontSources(value string) []FontSource {
+	var sources []FontSource
+	for _, item := range splitBackgroundLayers(value) {
+		parts := splitBackgroundValue(item)
+		if len(parts) == 0 {
+			continue
+		}
+		if url, ok := backgroundURL(parts[0]); ok && url != "" {
+			source := FontSource{URL: url}
+			for _, part := range parts[1:] {
+				lower := strings.ToLower(part)
+				if strings.HasPrefix(lower, "format(") && strings.HasSuffix(lower, ")") {
+					source.Format = strings.Trim(strings.TrimSpace(lower[7:len(lower)-1]), `"'`)
+				}
+			}
+			sources = append(sources, source)
+			continue
+		}
+		lower := strings.ToLower(item)
+		if strings.HasPrefix(lower, "local(") && strings.HasSuffix(lower, ")") {
+			name := strings.Trim(strings.TrimSpace(item[6:len(item)-1]), `"'`)
+			if name != "" {
+				sources = append(sources, FontSource{Local: name})
+			}
+		}
+	}
+	return sources
+}
@@ -0,0 +1,89 @@
+package css
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFontFace(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  []FontFace
+		wantRules int
+	}{
+		{
+			name: "url sources with format hints",
+			input: `@font-face {
+				font-family: "Open Sans";
+				src: url("/fonts/open-sans.woff2") format("woff2"), url('/fonts/open-sans.woff') format('woff');
+			}
+			p { font-family: "Open Sans", sans-serif; }`,
+			expected: []FontFace{{
+				Family: "Open Sans",
+				Sources: []FontSource{
+					{URL: "/fonts/open-sans.woff2", Format: "woff2"},
+					{URL: "/fonts/open-sans.woff", Format: "woff"},
+				},
+				Weight: 400,
+			}},
+			wantRules: 1,
+		},
+		{
+			name:  "local source and descriptors",
+			input: `@font-face { font-family: Brand; src: local("Brand Bold"), url(brand-bold.ttf); font-weight: 700; font-style: italic }`,
+			expected: []FontFace{{
+				Family:  "Brand",
+				Sources: []FontSource{{Local: "Brand Bold"}, {URL: "brand-bold.ttf"}},
+				Weight:  700,
+				Italic:  true,
+			}},
+		},
+		{
+			name:  "weight range matches on its low end",
+			input: `@font-face { font-family: Var; src: url(var.ttf) format("truetype"); font-weight: 100 900; }`,
+			expected: []FontFace{{
+				Family:  "Var",
+				Sources: []FontSource{{URL: "var.ttf", Format: "truetype"}},
+				Weight:  100,
+			}},
+		},
+		{
+			name:     "data url keeps its comma",
+			input:    `@font-face { font-family: Inline; src: url(data:font/woff;base64,d09GRg==) format("woff"); }`,
+			expected: []FontFace{{Family: "Inline", Sources: []FontSource{{URL: "data:font/woff;base64,d09GRg==", Format: "woff"}}, Weight: 400}},
+		},
+		{
+			name:  "multiple faces of one family",
+			input: `@font-face { font-family: F; src: url(f.ttf); } @font-face { font-family: F; src: url(f-bold.ttf); font-weight: bold; }`,
+			expected: []FontFace{
+				{Family: "F", Sources: []FontSource{{URL: "f.ttf"}}, Weight: 400},
+				{Family: "F", Sources: []FontSource{{URL: "f-bold.ttf"}}, Weight: 700},
+			},
+		},
+		{
+			name: "sheet base markers",
+			input: SheetBase("https://cdn.example.com/css/site.css") + `@font-face { font-family: A; src: url(a.ttf); } p { color: red; }` +
+				SheetBase("") + `@font-face { font-family: B; src: url(b.ttf); }`,
+			expected: []FontFace{
+				{Family: "A", Sources: []FontSource{{URL: "a.ttf"}}, Weight: 400, BaseURL: "https://cdn.example.com/css/site.css"},
+				{Family: "B", Sources: []FontSource{{URL: "b.ttf"}}, Weight: 400},
+			},
+			wantRules: 1,
+		},
+		{
+			name:      "rules without family or src are dropped",
+			input:     `@font-face { src: url(a.ttf); } @font-face { font-family: B; } @font-face { font-family: C; src: none; } h1 { color: red; }`,
+			wantRules: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet := Parse(tt.input)
+			assert.Equal(t, tt.expected, sheet.FontFaces)
+			assert.Len(t, sheet.Rules, tt.wantRules)
+		})
+	}
+}
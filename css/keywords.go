@@ -0,0 +1,285 @@
+package css
+
+import (
+	"image/color"
+	"strings"
+)
+
+// CSS-wide keywords (CSS Cascade §7.3) accepted by every property.
+const (
+	KeywordInherit = "inherit"
+	KeywordInitial = "initial"
+	KeywordUnset   = "unset"
+)
+
+// inheritedProperties lists the supported properties that inherit by
+// default; `unset` behaves as `inherit` for these and `initial` otherwise.
+var inheritedProperties = map[string]bool{
+	"color": true, "font": true, "font-family": true, "font-size": true,
+	"font-style": true, "font-variant": true, "font-weight": true,
+	"line-height": true, "letter-spacing": true, "word-spacing": true,
+	"text-align": true, "text-indent": true, "text-transform": true, "text-shadow": true,
+	"white-space": true, "hyphens": true, "visibility": true, "cursor": true,
+	"list-style": true, "list-style-type": true,
+	"border-collapse": true, "border-spacing": true, "caption-side": true,
+}
+
+// propertyFields copies the Style fields a property (or shorthand) sets
+// from src to dst.
+var propertyFields = map[string]func(dst, src *Style){
+	"color":                 func(d, s *Style) { d.Color = s.Color },
+	"background-color":      func(d, s *Style) { d.BackgroundColor = s.BackgroundColor },
+	"background-image":      func(d, s *Style) { d.BackgroundImages = s.BackgroundImages },
+	"background-size":       func(d, s *Style) { d.BackgroundSize = s.BackgroundSize },
+	"background-repeat":     func(d, s *Style) { d.BackgroundRepeat = s.BackgroundRepeat },
+	"background-position":   func(d, s *Style) { d.BackgroundPosition = s.BackgroundPosition },
+	"background-attachment": func(d, s *Style) { d.BackgroundAttachment = s.BackgroundAttachment },
+	"background": func(d, s *Style) {
+		d.BackgroundColor, d.BackgroundImages, d.BackgroundSize = s.BackgroundColor, s.BackgroundImages, s.BackgroundSize
+		d.BackgroundRepeat, d.BackgroundPosition, d.BackgroundAttachment = s.BackgroundRepeat, s.BackgroundPosition, s.BackgroundAttachment
+	},
+
+	"font-size":    func(d, s *Style) { d.FontSize = s.FontSize },
+	"font-variant": func(d, s *Style) { d.FontVariant = s.FontVariant },
+	"font-weight":  func(d, s *Style) { d.FontWeight = s.FontWeight },
+	"font-style":   func(d, s *Style) { d.Italic = s.Italic },
+	"font-family":  func(d, s *Style) { d.FontFamily = s.FontFamily },
+	"line-height":  func(d, s *Style) { d.LineHeight = s.LineHeight },
+	"font": func(d, s *Style) {
+		d.FontSize, d.FontVariant, d.FontWeight, d.Italic = s.FontSize, s.FontVariant, s.FontWeight, s.Italic
+		d.FontFamily, d.LineHeight = s.FontFamily, s.LineHeight
+	},
+
+	"margin-top":    func(d, s *Style) { d.MarginTop, d.MarginTopAuto = s.MarginTop, s.MarginTopAuto },
+	"margin-bottom": func(d, s *Style) { d.MarginBottom, d.MarginBottomAuto = s.MarginBottom, s.MarginBottomAuto },
+	"margin-left":   func(d, s *Style) { d.MarginLeft, d.MarginLeftAuto = s.MarginLeft, s.MarginLeftAuto },
+	"margin-right":  func(d, s *Style) { d.MarginRight, d.MarginRightAuto = s.MarginRight, s.MarginRightAuto },
+	"margin": func(d, s *Style) {
+		d.MarginTop, d.MarginTopAuto = s.MarginTop, s.MarginTopAuto
+		d.MarginBottom, d.MarginBottomAuto = s.MarginBottom, s.MarginBottomAuto
+		d.MarginLeft, d.MarginLeftAuto = s.MarginLeft, s.MarginLeftAuto
+		d.MarginRight, d.MarginRightAuto = s.MarginRight, s.MarginRightAuto
+	},
+	"padding-top":    func(d, s *Style) { d.PaddingTop = s.PaddingTop },
+	"padding-bottom": func(d, s *Style) { d.PaddingBottom = s.PaddingBottom },
+	"padding-left":   func(d, s *Style) { d.PaddingLeft = s.PaddingLeft },
+	"padding-right":  func(d, s *Style) { d.PaddingRight = s.PaddingRight },
+	"padding": func(d, s *Style) {
+		d.PaddingTop, d.PaddingRight, d.PaddingBottom, d.PaddingLeft = s.PaddingTop, s.PaddingRight, s.PaddingBottom, s.PaddingLeft
+	},
+
+	"text-align":      func(d, s *Style) { d.TextAlign = s.TextAlign },
+	"text-indent":     func(d, s *Style) { d.TextIndent = s.TextIndent },
+	"white-space":     func(d, s *Style) { d.WhiteSpace = s.WhiteSpace },
+	"hyphens":         func(d, s *Style) { d.Hyphens = s.Hyphens },
+	"text-overflow":   func(d, s *Style) { d.TextOverflow = s.TextOverflow },
+	"text-decoration": func(d, s *Style) { d.TextDecoration = s.TextDecoration },
+	"text-transform":  func(d, s *Style) { d.TextTransform = s.TextTransform },
+	"letter-spacing":  func(d, s *Style) { d.LetterSpacing, d.LetterSpacingSet = s.LetterSpacing, s.LetterSpacingSet },
+	"word-spacing":    func(d, s *Style) { d.WordSpacing, d.WordSpacingSet = s.WordSpacing, s.WordSpacingSet },
+	"text-shadow":     func(d, s *Style) { d.TextShadows, d.TextShadowSet = s.TextShadows, s.TextShadowSet },
+	"overflow": func(d, s *Style) {
+		d.Overflow, d.OverflowX, d.OverflowY = s.Overflow, s.OverflowX, s.OverflowY
+	},
+	"overflow-x":     func(d, s *Style) { d.OverflowX = s.OverflowX },
+	"overflow-y":     func(d, s *Style) { d.OverflowY = s.OverflowY },
+	"vertical-align": func(d, s *Style) { d.VerticalAlign = s.VerticalAlign },
+
+	"display":    func(d, s *Style) { d.Display = s.Display },
+	"float":      func(d, s *Style) { d.Float = s.Float },
+	"clear":      func(d, s *Style) { d.Clear = s.Clear },
+	"position":   func(d, s *Style) { d.Position = s.Position },
+	"top":        func(d, s *Style) { d.Top, d.TopSet = s.Top, s.TopSet },
+	"left":       func(d, s *Style) { d.Left, d.LeftSet = s.Left, s.LeftSet },
+	"right":      func(d, s *Style) { d.Right, d.RightSet = s.Right, s.RightSet },
+	"bottom":     func(d, s *Style) { d.Bottom, d.BottomSet = s.Bottom, s.BottomSet },
+	"z-index":    func(d, s *Style) { d.ZIndex, d.ZIndexSet = s.ZIndex, s.ZIndexSet },
+	"box-sizing": func(d, s *Style) { d.BoxSizing = s.BoxSizing },
+	"opacity":    func(d, s *Style) { d.Opacity = s.Opacity },
+	"filter":     func(d, s *Style) { d.Filters = s.Filters },
+	"visibility": func(d, s *Style) { d.Visibility = s.Visibility },
+	"cursor":     func(d, s *Style) { d.Cursor = s.Cursor },
+
+	"width": func(d, s *Style) {
+		d.Width, d.WidthPercent, d.WidthFitContent, d.WidthMath = s.Width, s.WidthPercent, s.WidthFitContent, s.WidthMath
+	},
+	"height":       func(d, s *Style) { d.Height = s.Height },
+	"aspect-ratio": func(d, s *Style) { d.AspectRatio = s.AspectRatio },
+	"min-width":    func(d, s *Style) { d.MinWidth, d.MinWidthMath = s.MinWidth, s.MinWidthMath },
+	"max-width":    func(d, s *Style) { d.MaxWidth, d.MaxWidthMath = s.MaxWidth, s.MaxWidthMath },
+	"min-height":   func(d, s *Style) { d.MinHeight = s.MinHeight },
+	"max-height":   func(d, s *Style) { d.MaxHeight = s.MaxHeight },
+
+	"flex-direction":  func(d, s *Style) { d.FlexDirection = s.FlexDirection },
+	"flex-wrap":       func(d, s *Style) { d.FlexWrap = s.FlexWrap },
+	"flex-flow":       func(d, s *Style) { d.FlexDirection, d.FlexWrap = s.FlexDirection, s.FlexWrap },
+	"justify-content": func(d, s *Style) { d.JustifyContent = s.JustifyContent },
+	"align-items":     func(d, s *Style) { d.AlignItems = s.AlignItems },
+	"flex-grow":       func(d, s *Style) { d.FlexGrow = s.FlexGrow },
+	"flex-shrink":     func(d, s *Style) { d.FlexShrink, d.FlexShrinkSet = s.FlexShrink, s.FlexShrinkSet },
+	"flex-basis":      func(d, s *Style) { d.FlexBasis = s.FlexBasis },
+	"flex": func(d, s *Style) {
+		d.FlexGrow, d.FlexShrink, d.FlexShrinkSet, d.FlexBasis = s.FlexGrow, s.FlexShrink, s.FlexShrinkSet, s.FlexBasis
+	},
+	"gap": func(d, s *Style) {
+		d.RowGap, d.ColumnGap, d.ColumnGapSet = s.RowGap, s.ColumnGap, s.ColumnGapSet
+	},
+	"row-gap":      func(d, s *Style) { d.RowGap = s.RowGap },
+	"column-gap":   func(d, s *Style) { d.ColumnGap, d.ColumnGapSet = s.ColumnGap, s.ColumnGapSet },
+	"column-count": func(d, s *Style) { d.ColumnCount = s.ColumnCount },
+	"column-width": func(d, s *Style) { d.ColumnWidth = s.ColumnWidth },
+	"columns":      func(d, s *Style) { d.ColumnCount, d.ColumnWidth = s.ColumnCount, s.ColumnWidth },
+
+	"border-top-width":    func(d, s *Style) { d.BorderTopWidth = s.BorderTopWidth },
+	"border-right-width":  func(d, s *Style) { d.BorderRightWidth = s.BorderRightWidth },
+	"border-bottom-width": func(d, s *Style) { d.BorderBottomWidth = s.BorderBottomWidth },
+	"border-left-width":   func(d, s *Style) { d.BorderLeftWidth = s.BorderLeftWidth },
+	"border-width": func(d, s *Style) {
+		d.BorderTopWidth, d.BorderRightWidth, d.BorderBottomWidth, d.BorderLeftWidth = s.BorderTopWidth, s.BorderRightWidth, s.BorderBottomWidth, s.BorderLeftWidth
+	},
+	"border-color": func(d, s *Style) {
+		d.BorderTopColor, d.BorderRightColor, d.BorderBottomColor, d.BorderLeftColor = s.BorderTopColor, s.BorderRightColor, s.BorderBottomColor, s.BorderLeftColor
+	},
+	"border-top-style":    func(d, s *Style) { d.BorderTopStyle = s.BorderTopStyle },
+	"border-right-style":  func(d, s *Style) { d.BorderRightStyle = s.BorderRightStyle },
+	"border-bottom-style": func(d, s *Style) { d.BorderBottomStyle = s.BorderBottomStyle },
+	"border-left-style":   func(d, s *Style) { d.BorderLeftStyle = s.BorderLeftStyle },
+	"border-style": func(d, s *Style) {
+		d.BorderTopStyle, d.BorderRightStyle, d.BorderBottomStyle, d.BorderLeftStyle = s.BorderTopStyle, s.BorderRightStyle, s.BorderBottomStyle, s.BorderLeftStyle
+	},
+	"border-top": func(d, s *Style) {
+		d.BorderTopWidth, d.BorderTopStyle, d.BorderTopColor = s.BorderTopWidth, s.BorderTopStyle, s.BorderTopColor
+	},
+	"border-right": func(d, s *Style) {
+		d.BorderRightWidth, d.BorderRightStyle, d.BorderRightColor = s.BorderRightWidth, s.BorderRightStyle, s.BorderRightColor
+	},
+	"border-bottom": func(d, s *Style) {
+		d.BorderBottomWidth, d.BorderBottomStyle, d.BorderBottomColor = s.BorderBottomWidth, s.BorderBottomStyle, s.BorderBottomColor
+	},
+	"border-left": func(d, s *Style) {
+		d.BorderLeftWidth, d.BorderLeftStyle, d.BorderLeftColor = s.BorderLeftWidth, s.BorderLeftStyle, s.BorderLeftColor
+	},
+	"border-top-left-radius":     func(d, s *Style) { d.BorderTopLeftRadius = s.BorderTopLeftRadius },
+	"border-top-right-radius":    func(d, s *Style) { d.BorderTopRightRadius = s.BorderTopRightRadius },
+	"border-bottom-left-radius":  func(d, s *Style) { d.BorderBottomLeftRadius = s.BorderBottomLeftRadius },
+	"border-bottom-right-radius": func(d, s *Style) { d.BorderBottomRightRadius = s.BorderBottomRightRadius },
+	"border-radius": func(d, s *Style) {
+		d.BorderRadius = s.BorderRadius
+		d.BorderTopLeftRadius, d.BorderTopRightRadius = s.BorderTopLeftRadius, s.BorderTopRightRadius
+		d.BorderBottomLeftRadius, d.BorderBottomRightRadius = s.BorderBottomLeftRadius, s.BorderBottomRightRadius
+	},
+	"outline": func(d, s *Style) {
+		d.OutlineWidth, d.OutlineStyle, d.OutlineColor = s.OutlineWidth, s.OutlineStyle, s.OutlineColor
+	},
+	"outline-width":  func(d, s *Style) { d.OutlineWidth = s.OutlineWidth },
+	"outline-style":  func(d, s *Style) { d.OutlineStyle = s.OutlineStyle },
+	"outline-color":  func(d, s *Style) { d.OutlineColor = s.OutlineColor },
+	"outline-offset": func(d, s *Style) { d.OutlineOffset = s.OutlineOffset },
+
+	"border-collapse": func(d, s *Style) { d.BorderCollapse = s.BorderCollapse },
+	"caption-side":    func(d, s *Style) { d.CaptionSide = s.CaptionSide },
+	"border-spacing": func(d, s *Style) {
+		d.BorderSpacingX, d.BorderSpacingY, d.BorderSpacingSet = s.BorderSpacingX, s.BorderSpacingY, s.BorderSpacingSet
+	},
+
+	"list-style":        func(d, s *Style) { d.ListStyleType = s.ListStyleType },
+	"list-style-type":   func(d, s *Style) { d.ListStyleType = s.ListStyleType },
+	"content":           func(d, s *Style) { d.Content = s.Content },
+	"counter-reset":     func(d, s *Style) { d.CounterReset = s.CounterReset },
+	"counter-increment": func(d, s *Style) { d.CounterIncrement = s.CounterIncrement },
+	"counter-set":       func(d, s *Style) { d.CounterSet = s.CounterSet },
+}
+
+func init() {
+	borderSides := []string{"border-top", "border-right", "border-bottom", "border-left"}
+	propertyFields["border"] = func(d, s *Style) {
+		for _, side := range borderSides {
+			propertyFields[side](d, s)
+		}
+	}
+}
+
+// InitialStyle returns the initial value of every property, as opposed to
+// DefaultStyle which is the starting point of the cascade.
+func InitialStyle() Style {
+	style := DefaultStyle()
+	style.Color = color.Black
+	style.Display = "inline"
+	style.TextAlign = "left"
+	style.Visibility = "visible"
+	style.ListStyleType = ListStyleDisc
+	style.FontWeight = FontWeightNormal
+	style.LetterSpacingSet = true
+	style.WordSpacingSet = true
+	style.BorderCollapse = "separate"
+	style.BorderSpacingSet = true
+	style.CaptionSide = "top"
+	style.Hyphens = "manual"
+	return style
+}
+
+// InheritedStyle returns the style of an anonymous block box inside an
+// element styled parent: the initial values, with parent's values for the
+// properties that inherit (CSS 2.1 §9.2.1.1).
+func InheritedStyle(parent *Style) Style {
+	style := InitialStyle()
+	style.Display = "block"
+	for property := range inheritedProperties {
+		propertyFields[property](&style, parent)
+	}
+	return style
+}
+
+// isCSSWideKeyword reports whether value is inherit, initial or unset.
+func isCSSWideKeyword(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case KeywordInherit, KeywordInitial, KeywordUnset:
+		return true
+	}
+	return false
+}
+
+// applyCSSWideKeyword resolves property: inherit/initial/unset by copying
+// the property's fields from parent or from InitialStyle. A nil parent (the
+// root element) inherits initial values. Returns false if value is not a
+// CSS-wide keyword or the property is unsupported.
+func applyCSSWideKeyword(style *Style, property, value string, parent *Style) bool {
+	if !isCSSWideKeyword(value) {
+		return false
+	}
+	copyFields, ok := propertyFields[property]
+	if !ok {
+		return false
+	}
+
+	keyword := strings.ToLower(strings.TrimSpace(value))
+	if keyword == KeywordUnset {
+		keyword = KeywordInitial
+		if inheritedProperties[property] {
+			keyword = KeywordInherit
+		}
+	}
+
+	initial := InitialStyle()
+	src := &initial
+	if keyword == KeywordInherit && parent != nil {
+		src = parent
+	}
+	copyFields(style, src)
+	return true
+}
+
+// applyCascadedValue applies a declaration that won the cascade, resolving
+// CSS-wide keywords against parent.
+func applyCascadedValue(style, parent *Style, property, value string, baseFontSize, viewportWidth, viewportHeight float64) {
+	if applyCSSWideKeyword(style, property, value, parent) {
+		return
+	}
+	if property == "font-weight" && parent != nil {
+		if weight, ok := relativeFontWeight(value, parent.FontWeight); ok {
+			style.FontWeight = weight
+			return
+		}
+	}
+	applyDeclarationWithContext(style, property, value, baseFontSize, viewportWidth, viewportHeight)
+}
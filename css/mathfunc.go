@@ -0,0 +1,156 @@
+package css
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MathLength is a min(), max() or clamp() length (CSS Values 4 §10.2)
+// whose percentage arguments are kept until layout knows the containing
+// block; see Resolve.
+type MathLength struct {
+	fn   string // "min", "max" or "clamp"
+	args []mathArgument
+}
+
+// mathArgument is one argument of a MathLength: a length in pixels, a
+// percentage, or a nested function.
+type mathArgument struct {
+	px        float64
+	percent   float64
+	isPercent bool
+	nested    *MathLength
+}
+
+// parseMathFunction evaluates a min(), max() or clamp() length. Arguments
+// are lengths or nested math functions; ok is false when value is not such
+// a function or an argument cannot be resolved. Percentages need a basis
+// the size parser does not have, so functions using them are left to
+// parseMathLength.
+func parseMathFunction(value string, fontSize, viewportWidth, viewportHeight float64) (float64, bool) {
+	m, ok := parseMathLength(value, fontSize, viewportWidth, viewportHeight)
+	if !ok || m.HasPercent() {
+		return 0, false
+	}
+	return m.Resolve(0), true
+}
+
+// parseMathLength parses a min(), max() or clamp() length, resolving every
+// argument but percentages.
+func parseMathLength(value string, fontSize, viewportWidth, viewportHeight float64) (*MathLength, bool) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	open := strings.IndexByte(value, '(')
+	if open < 0 || !strings.HasSuffix(value, ")") {
+		return nil, false
+	}
+	name := value[:open]
+	if name != "min" && name != "max" && name != "clamp" {
+		return nil, false
+	}
+
+	m := &MathLength{fn: name}
+	for _, arg := range splitBackgroundLayers(value[open+1 : len(value)-1]) {
+		a, ok := parseMathArgument(arg, fontSize, viewportWidth, viewportHeight)
+		if !ok {
+			return nil, false
+		}
+		m.args = append(m.args, a)
+	}
+	if len(m.args) == 0 || (name == "clamp" && len(m.args) != 3) {
+		return nil, false
+	}
+	return m, true
+}
+
+// parseMathArgument parses one argument of a math function.
+func parseMathArgument(arg string, fontSize, viewportWidth, viewportHeight float64) (mathArgument, bool) {
+	arg = strings.TrimSpace(arg)
+	if strings.HasSuffix(arg, ")") {
+		nested, ok := parseMathLength(arg, fontSize, viewportWidth, viewportHeight)
+		return mathArgument{nested: nested}, ok
+	}
+	if arg == "normal" {
+		return mathArgument{}, false
+	}
+	if num, ok := strings.CutSuffix(arg, "%"); ok {
+		percent, err := strconv.ParseFloat(num, 64)
+		return mathArgument{percent: percent, isPercent: true}, err == nil
+	}
+	px, ok := parseSpacingWithContext(arg, fontSize, viewportWidth, viewportHeight)
+	return mathArgument{px: px}, ok
+}
+
+// HasPercent reports whether any argument of m, nested ones included, is a
+// percentage.
+func (m *MathLength) HasPercent() bool {
+	for _, a := range m.args {
+		if a.isPercent || (a.nested != nil && a.nested.HasPercent()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve evaluates m, taking percentages of basis.
+func (m *MathLength) Resolve(basis float64) float64 {
+	values := make([]float64, len(m.args))
+	for i, a := range m.args {
+		switch {
+		case a.nested != nil:
+			values[i] = a.nested.Resolve(basis)
+		case a.isPercent:
+			values[i] = basis * a.percent / 100
+		default:
+			values[i] = a.px
+		}
+	}
+
+	switch m.fn {
+	case "min":
+		result := values[0]
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+		return result
+	case "max":
+		result := values[0]
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+		return result
+	default:
+		// The minimum wins when it exceeds the maximum
+		return math.Max(values[0], math.Min(values[1], values[2]))
+	}
+}
+
+// valueFields splits a multi-value property such as margin into its
+// components like strings.Fields, keeping function arguments together so
+// "clamp(1em, 2vw, 2em) 0" is two values.
+func valueFields(value string) []string {
+	var fields []string
+	depth, start := 0, -1
+	for i, ch := range value {
+		switch {
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case unicode.IsSpace(ch) && depth == 0:
+			if start >= 0 {
+				fields = append(fields, value[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, value[start:])
+	}
+	return fields
+}
@@ -0,0 +1,102 @@
+package css
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMathFunction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		ok       bool
+	}{
+		{"min(10px, 20px)", 10, true},
+		{"max(10px, 20px, 5px)", 20, true},
+		{"MIN(2em, 40px)", 32, true},
+		{"min(50vw, 300px)", 300, true},
+		{"max(1em, 5vw)", 40, true},
+		{"clamp(1em, 2vw, 1.5em)", 16, true},
+		{"clamp(10px, 2vw, 100px)", 16, true},
+		{"clamp(10px, 50vw, 100px)", 100, true},
+		{"clamp(30px, 10px, 20px)", 30, true},
+		{"min(100px, max(20px, 10vw))", 80, true},
+		{"min( 12pt , 20px )", 16, true},
+		{"min(0, 10px)", 0, true},
+		{"min(-5px, 10px)", -5, true},
+		{"clamp(1px, 2px)", 0, false},
+		{"min()", 0, false},
+		{"min(100%, 600px)", 0, false}, // resolved in layout; see TestParseMathLength
+		{"min(10px, auto)", 0, false},
+		{"calc(10px + 2px)", 0, false},
+		{"10px", 0, false},
+		{"min(10px, 20px", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, ok := parseMathFunction(tt.input, 16, 800, 600)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.expected, v, 1e-9)
+		})
+	}
+}
+
+func TestParseMathLength(t *testing.T) {
+	tests := []struct {
+		input    string
+		basis    float64
+		expected float64
+	}{
+		{"min(100%, 1200px)", 800, 800},
+		{"min(100%, 1200px)", 1600, 1200},
+		{"clamp(1em, 2.5%, 2em)", 800, 20},
+		{"clamp(1em, 2.5%, 2em)", 200, 16},
+		{"max(50%, min(10em, 25%))", 1000, 500},
+	}
+	for _, tt := range tests {
+		m, ok := parseMathLength(tt.input, 16, 800, 600)
+		if assert.True(t, ok, tt.input) {
+			assert.True(t, m.HasPercent())
+			assert.InDelta(t, tt.expected, m.Resolve(tt.basis), 1e-9, "%s of %v", tt.input, tt.basis)
+		}
+	}
+
+	m, ok := parseMathLength("min(10px, 1em)", 16, 800, 600)
+	assert.True(t, ok)
+	assert.False(t, m.HasPercent())
+	_, ok = parseMathLength("min(10%, auto)", 16, 800, 600)
+	assert.False(t, ok)
+
+	style := ParseInlineStyle("width: min(100%, 1200px); max-width: clamp(1em, 2.5%, 2em)")
+	assert.Zero(t, style.Width)
+	assert.InDelta(t, 500, style.WidthMath.Resolve(500), 1e-9)
+	assert.InDelta(t, 25, style.MaxWidthMath.Resolve(1000), 1e-9)
+	style = ParseInlineStyle("width: min(100%, 1200px); width: 300px")
+	assert.Nil(t, style.WidthMath, "a later width replaces it")
+}
+
+func TestValueFields(t *testing.T) {
+	assert.Equal(t, []string{"clamp(1em, 2vw, 2em)", "0"}, valueFields(" clamp(1em, 2vw, 2em)\t0 "))
+	assert.Equal(t, []string{"1px", "solid", "rgb(0, 0, 0)"}, valueFields("1px solid rgb(0, 0, 0)"))
+	assert.Empty(t, valueFields("  "))
+}
+
+func TestMathFunctionDeclarations(t *testing.T) {
+	style := ParseInlineStyle("font-size: clamp(12px, 1em, 2em); width: min(400px, 30em); line-height: max(20px, 1.5em)")
+	assert.Equal(t, 16.0, style.FontSize)
+	assert.Equal(t, 400.0, style.Width)
+	assert.Equal(t, 24.0, style.LineHeight)
+
+	style = ParseInlineStyle("margin: max(4px, 1em) 0; padding: min(2px, 1em) clamp(4px, 1em, 8px)")
+	assert.Equal(t, 16.0, style.MarginTop)
+	assert.Equal(t, 16.0, style.MarginBottom)
+	assert.Equal(t, 2.0, style.PaddingTop)
+	assert.Equal(t, 8.0, style.PaddingLeft)
+
+	style = ParseInlineStyle("letter-spacing: max(1px, 0.1em); border-width: min(2px, 1em)")
+	assert.True(t, style.LetterSpacingSet)
+	assert.InDelta(t, 1.6, style.LetterSpacing, 1e-9)
+	assert.Equal(t, 2.0, style.BorderTopWidth)
+}
@@ -0,0 +1,168 @@
+package css
+
+import "strings"
+
+// Color schemes a user can prefer (Media Queries 5 §11.5).
+const (
+	ColorSchemeLight = "light"
+	ColorSchemeDark  = "dark"
+)
+
+// Media types a page is rendered for (Media Queries 4 §2.3).
+const (
+	MediaScreen = "screen"
+	MediaPrint  = "print"
+)
+
+// MediaQuery is one query of an @media prelude (Media Queries 4 §3): an
+// optional media type and features joined by "and".
+type MediaQuery struct {
+	Not      bool
+	Type     string // lowercased media type; "" means all
+	Features []MediaFeature
+}
+
+// MediaFeature is a parenthesized "(name: value)" test. Value is "" for a
+// feature in boolean context, e.g. "(prefers-color-scheme)".
+type MediaFeature struct {
+	Name  string
+	Value string
+}
+
+// notAll is what an unparseable query becomes (Media Queries 4 §3.2).
+var notAll = MediaQuery{Not: true, Type: "all"}
+
+// parseMediaQueryList parses the comma-separated queries of an @media
+// prelude. An empty prelude is a single query matching all media.
+func parseMediaQueryList(prelude string) []MediaQuery {
+	var queries []MediaQuery
+	for _, part := range strings.Split(prelude, ",") {
+		queries = append(queries, parseMediaQuery(part))
+	}
+	return queries
+}
+
+// parseMediaQuery parses "[not|only] type [and (feature)]*" or
+// "(feature) [and (feature)]*".
+func parseMediaQuery(text string) MediaQuery {
+	var q MediaQuery
+	rest := strings.ToLower(strings.TrimSpace(text))
+	first := true
+	needAnd := false
+	for rest != "" {
+		if rest[0] == '(' {
+			end := strings.IndexByte(rest, ')')
+			if end < 0 || needAnd {
+				return notAll
+			}
+			name, value, _ := strings.Cut(rest[1:end], ":")
+			q.Features = append(q.Features, MediaFeature{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+			rest = strings.TrimSpace(rest[end+1:])
+			first, needAnd = false, true
+			continue
+		}
+		end := strings.IndexAny(rest, " \t\n(")
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = strings.TrimSpace(rest[end:])
+		switch {
+		case word == "and" && needAnd:
+			needAnd = false
+		case first && (word == "not" || word == "only"):
+			q.Not = word == "not"
+			first = false
+		case q.Type == "" && len(q.Features) == 0 && !needAnd && word != "and":
+			q.Type = word
+			first, needAnd = false, true
+		default:
+			return notAll
+		}
+	}
+	if !needAnd && !first {
+		// A dangling "and", "not" or "only"
+		return notAll
+	}
+	return q
+}
+
+// matches reports whether q applies to the medium described by ctx.
+// Features this browser does not evaluate make the query false, negated
+// or not, so rules for unknown conditions never apply.
+func (q MediaQuery) matches(ctx MatchContext) bool {
+	result := q.Type == "" || q.Type == "all" || q.Type == ctx.mediaType()
+	for _, f := range q.Features {
+		match, known := f.matches(ctx)
+		if !known {
+			return false
+		}
+		result = result && match
+	}
+	if q.Not {
+		return !result
+	}
+	return result
+}
+
+// matches evaluates the feature; known is false for unsupported features
+// and values.
+func (f MediaFeature) matches(ctx MatchContext) (match, known bool) {
+	switch f.Name {
+	case "prefers-color-scheme":
+		switch f.Value {
+		case "":
+			return true, true
+		case ColorSchemeLight, ColorSchemeDark:
+			return f.Value == ctx.colorScheme(), true
+		}
+	}
+	return false, false
+}
+
+// colorScheme returns the preferred color scheme, light unless the host
+// asked for dark.
+func (ctx MatchContext) colorScheme() string {
+	if ctx.ColorScheme == ColorSchemeDark {
+		return ColorSchemeDark
+	}
+	return ColorSchemeLight
+}
+
+// mediaType returns the media type rendered for, the screen unless the
+// host is printing.
+func (ctx MatchContext) mediaType() string {
+	if ctx.Media == MediaPrint {
+		return MediaPrint
+	}
+	return MediaScreen
+}
+
+// matchesMedia reports whether a rule with the given media query list
+// applies. Rules outside @media have a nil list and always apply.
+func (ctx MatchContext) matchesMedia(media []MediaQuery) bool {
+	if media == nil {
+		return true
+	}
+	for _, q := range media {
+		if q.matches(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasColorSchemeRules reports whether any rule is conditioned on
+// prefers-color-scheme: scheme, meaning the page styles itself for it.
+func (sheet Stylesheet) HasColorSchemeRules(scheme string) bool {
+	for _, rule := range sheet.Rules {
+		for _, q := range rule.Media {
+			for _, f := range q.Features {
+				if f.Name == "prefers-color-scheme" && f.Value == scheme {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package css
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMediaQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected MediaQuery
+	}{
+		{"", MediaQuery{}},
+		{"screen", MediaQuery{Type: "screen"}},
+		{"ONLY Screen", MediaQuery{Type: "screen"}},
+		{"not print", MediaQuery{Not: true, Type: "print"}},
+		{"(prefers-color-scheme: dark)", MediaQuery{Features: []MediaFeature{{"prefers-color-scheme", "dark"}}}},
+		{"screen and (prefers-color-scheme:light)", MediaQuery{Type: "screen", Features: []MediaFeature{{"prefers-color-scheme", "light"}}}},
+		{"(prefers-color-scheme) and (min-width: 600px)", MediaQuery{Features: []MediaFeature{{"prefers-color-scheme", ""}, {"min-width", "600px"}}}},
+		{"screen and", notAll},
+		{"screen print", notAll},
+		{"(prefers-color-scheme: dark) (min-width: 1px)", notAll},
+		{"not", notAll},
+		{"(prefers-color-scheme: dark", notAll},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseMediaQuery(tt.input))
+		})
+	}
+}
+
+func TestMatchesMedia(t *testing.T) {
+	light := MatchContext{}
+	dark := MatchContext{ColorScheme: ColorSchemeDark}
+	tests := []struct {
+		query     string
+		wantLight bool
+		wantDark  bool
+	}{
+		{"", true, true},
+		{"screen", true, true},
+		{"print", false, false},
+		{"not print", true, true},
+		{"all, print", true, true},
+		{"(prefers-color-scheme: dark)", false, true},
+		{"(prefers-color-scheme: light)", true, false},
+		{"(prefers-color-scheme)", true, true},
+		{"not all and (prefers-color-scheme: dark)", true, false},
+		{"print, (prefers-color-scheme: dark)", false, true},
+		{"(prefers-color-scheme: sepia)", false, false},
+		{"(min-width: 600px)", false, false},
+		{"not screen and (min-width: 600px)", false, false},
+		{"screen and", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			media := parseMediaQueryList(tt.query)
+			assert.Equal(t, tt.wantLight, light.matchesMedia(media), "light")
+			assert.Equal(t, tt.wantDark, dark.matchesMedia(media), "dark")
+		})
+	}
+	assert.True(t, light.matchesMedia(nil), "rules outside @media always apply")
+}
+
+func TestMatchesPrintMedia(t *testing.T) {
+	printing := MatchContext{Media: MediaPrint}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"print", true},
+		{"screen", false},
+		{"not screen", true},
+		{"all", true},
+		{"print and (prefers-color-scheme: light)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, printing.matchesMedia(parseMediaQueryList(tt.query)))
+		})
+	}
+}
+
+func TestParseAtMedia(t *testing.T) {
+	sheet := Parse(`p { color: black }
+		@media (prefers-color-scheme: dark) {
+			body { background: black }
+			@supports (display: grid) { div { color: red } }
+			p { color: white }
+		}
+		@import "late.css";
+		a { color: blue }`)
+
+	assert.Nil(t, sheet.Imports, "@import after @media is ignored")
+	assert.Len(t, sheet.Rules, 4)
+	assert.Nil(t, sheet.Rules[0].Media)
+	assert.Equal(t, "body", sheet.Rules[1].Selectors[0].TagName)
+	assert.Equal(t, "p", sheet.Rules[2].Selectors[0].TagName)
+	assert.Equal(t, []MediaQuery{{Features: []MediaFeature{{"prefers-color-scheme", "dark"}}}}, sheet.Rules[2].Media)
+	assert.Nil(t, sheet.Rules[3].Media)
+	assert.True(t, sheet.HasColorSchemeRules(ColorSchemeDark))
+	assert.False(t, sheet.HasColorSchemeRules(ColorSchemeLight))
+	assert.False(t, Parse(`@media print { p { color: black } }`).HasColorSchemeRules(ColorSchemeDark))
+}
+
+func TestPrefersColorSchemeCascade(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<p>text</p>`))
+	p := dom.FindElementsByTagName(doc, "p")
+	sheet := Parse(`p { color: black }
+		@media (prefers-color-scheme: dark) { p { color: white } p::before { content: "☾" } }`)
+
+	lightStyle := ApplyStylesheetWithParent(sheet, p, nil, 800, 600, MatchContext{})
+	assert.True(t, colorsEqual(color.Black, lightStyle.Color))
+	assert.Nil(t, lightStyle.BeforeStyle)
+
+	darkStyle := ApplyStylesheetWithParent(sheet, p, nil, 800, 600, MatchContext{ColorScheme: ColorSchemeDark})
+	assert.True(t, colorsEqual(color.White, darkStyle.Color))
+	assert.NotNil(t, darkStyle.BeforeStyle)
+}
@@ -0,0 +1,46 @@
+package css
+
+import "strconv"
+
+// parseColumnCount parses a column-count value: a positive integer, or
+// auto (0). It reports false for anything else.
+func parseColumnCount(value string) (int, bool) {
+	if value == "auto" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseColumnWidth parses a column-width value: a positive length, or auto
+// (0). It reports false for anything else.
+func parseColumnWidth(value string, fontSize, viewportWidth, viewportHeight float64) (float64, bool) {
+	if value == "auto" {
+		return 0, true
+	}
+	w := ParseSizeWithContext(value, fontSize, viewportWidth, viewportHeight)
+	return w, w > 0
+}
+
+// applyColumnsShorthand sets column-width and column-count from the
+// columns shorthand; the two may appear in either order, and an omitted
+// one is auto.
+func applyColumnsShorthand(style *Style, value string, viewportWidth, viewportHeight float64) {
+	count, width := 0, 0.0
+	for _, token := range valueFields(value) {
+		if token == "auto" {
+			continue
+		}
+		if n, ok := parseColumnCount(token); ok {
+			count = n
+		} else if w, ok := parseColumnWidth(token, style.FontSize, viewportWidth, viewportHeight); ok {
+			width = w
+		} else {
+			return
+		}
+	}
+	style.ColumnCount, style.ColumnWidth = count, width
+}
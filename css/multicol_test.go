@@ -0,0 +1,41 @@
+package css
+
+import (
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnProperties(t *testing.T) {
+	node := &dom.Node{Type: dom.Element, TagName: "div"}
+
+	tests := []struct {
+		decls  string
+		count  int
+		width  float64
+		gap    float64
+		gapSet bool
+	}{
+		{"column-count: 3", 3, 0, 0, false},
+		{"column-width: 12em", 0, 192, 0, false},
+		{"columns: 200px 2", 2, 200, 0, false},
+		{"columns: 4 auto", 4, 0, 0, false},
+		{"columns: 3; column-gap: 2em", 3, 0, 32, true},
+		{"columns: 3; column-gap: normal", 3, 0, 0, false},
+		{"column-count: 0", 0, 0, 0, false},
+		{"column-count: 2; columns: 3 thin", 2, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.decls, func(t *testing.T) {
+			sheet := Parse(`div { ` + tt.decls + `; }`)
+			style := ApplyStylesheetWithContext(sheet, node, 16, DefaultViewportWidth, DefaultViewportHeight, MatchContext{})
+			assert.Equal(t, tt.count, style.ColumnCount)
+			assert.Equal(t, tt.width, style.ColumnWidth)
+			assert.Equal(t, tt.gap, style.ColumnGap)
+			assert.Equal(t, tt.gapSet, style.ColumnGapSet)
+		})
+	}
+}
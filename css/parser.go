@@ -18,6 +18,8 @@ func Parse(input string) Stylesheet {
 func (p *Parser) parseStylesheet() Stylesheet {
 	var imports []string
 	var rules []Rule
+	var fontFaces []FontFace
+	base := ""
 	seenRule := false
 	for p.pos < len(p.input) {
 		p.skipWhitespace()
@@ -27,6 +29,22 @@ func (p *Parser) parseStylesheet() Stylesheet {
 		// Handle at-rules (@import, @charset, etc.)
 		if p.input[p.pos] == '@' {
 			p.pos++ // skip '@'
+			if sheetURL, handled := p.parseAtSheetBase(); handled {
+				base = sheetURL
+				continue
+			}
+			if face, ok, handled := p.parseAtFontFace(); handled {
+				if ok {
+					face.BaseURL = base
+					fontFaces = append(fontFaces, face)
+				}
+				continue
+			}
+			if mediaRules, handled := p.parseAtMedia(); handled {
+				rules = append(rules, mediaRules...)
+				seenRule = true
+				continue
+			}
 			importURL := p.parseAtImport()
 			if importURL != "" && !seenRule {
 				imports = append(imports, importURL)
@@ -37,7 +55,7 @@ func (p *Parser) parseStylesheet() Stylesheet {
 		rule := p.parseRule()
 		rules = append(rules, rule)
 	}
-	return Stylesheet{Imports: imports, Rules: rules}
+	return Stylesheet{Imports: imports, Rules: rules, FontFaces: fontFaces}
 }
 
 func (p *Parser) parseRule() Rule {
@@ -224,15 +242,37 @@ func (p *Parser) parseIdentifier() string {
 	return p.input[start:p.pos]
 }
 
+// parseValue reads a declaration value up to ';' or '}', ignoring both inside
+// quoted strings (e.g. content: ";") and ';' inside parentheses (data URLs).
 func (p *Parser) parseValue() string {
 	start := p.pos
+	var quote byte
+	depth := 0
 	for p.pos < len(p.input) {
 		c := p.input[p.pos]
-		if c == ';' || c == '}' {
+		if quote != 0 {
+			if c == '\\' {
+				p.pos++
+			} else if c == quote || c == '\n' {
+				quote = 0 // unterminated strings end at newline
+			}
+			p.pos++
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+		} else if c == '(' {
+			depth++
+		} else if c == ')' && depth > 0 {
+			depth--
+		} else if c == '}' || (depth == 0 && c == ';') {
 			break
 		}
 		p.pos++
 	}
+	if p.pos > len(p.input) {
+		p.pos = len(p.input)
+	}
 	return strings.TrimSpace(p.input[start:p.pos])
 }
 
@@ -284,6 +324,76 @@ func (p *Parser) parseAtImport() string {
 	return importURL
 }
 
+// parseAtSheetBase parses a SheetBase marker after the '@' has been
+// consumed. handled is false, with the position unchanged, for other at-rules.
+func (p *Parser) parseAtSheetBase() (sheetURL string, handled bool) {
+	start := p.pos
+	if !strings.EqualFold(p.parseIdentifier(), sheetBaseRule) {
+		p.pos = start
+		return "", false
+	}
+	p.skipWhitespace()
+	if p.pos < len(p.input) && (p.input[p.pos] == '"' || p.input[p.pos] == '\'') {
+		sheetURL = p.parseQuotedString(p.input[p.pos])
+	}
+	p.skipToSemicolon()
+	return sheetURL, true
+}
+
+// parseAtFontFace parses an @font-face block after the '@' has been
+// consumed. handled is false, with the position unchanged, for other at-rules.
+func (p *Parser) parseAtFontFace() (face FontFace, ok, handled bool) {
+	start := p.pos
+	if !strings.EqualFold(p.parseIdentifier(), "font-face") {
+		p.pos = start
+		return FontFace{}, false, false
+	}
+	face, ok = parseFontFace(p.parseDeclarations())
+	return face, ok, true
+}
+
+// parseAtMedia parses an @media block after the '@' has been consumed,
+// tagging each of its rules with the block's media query list. At-rules
+// nested inside are skipped. handled is false, with the position
+// unchanged, for other at-rules.
+func (p *Parser) parseAtMedia() (rules []Rule, handled bool) {
+	start := p.pos
+	if !strings.EqualFold(p.parseIdentifier(), "media") {
+		p.pos = start
+		return nil, false
+	}
+	preludeStart := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '{' && p.input[p.pos] != ';' {
+		p.pos++
+	}
+	if p.pos >= len(p.input) || p.input[p.pos] == ';' {
+		p.skipToSemicolon()
+		return nil, true
+	}
+	media := parseMediaQueryList(p.input[preludeStart:p.pos])
+	p.pos++ // skip {
+
+	for p.pos < len(p.input) {
+		p.skipWhitespace()
+		if p.pos >= len(p.input) {
+			break
+		}
+		if p.input[p.pos] == '}' {
+			p.pos++
+			break
+		}
+		if p.input[p.pos] == '@' {
+			p.pos++
+			p.skipAtRule()
+			continue
+		}
+		rule := p.parseRule()
+		rule.Media = media
+		rules = append(rules, rule)
+	}
+	return rules, true
+}
+
 // parseQuotedString reads a string between matching quotes. The opening quote char
 // must be at p.pos. Returns the content between quotes.
 func (p *Parser) parseQuotedString(quote byte) string {
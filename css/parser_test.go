@@ -613,7 +613,7 @@ func TestParseAtImport(t *testing.T) {
 		},
 		{
 			name:        "block at-rule skipped",
-			input:       `@media screen { body { color: red; } } div { color: blue; }`,
+			input:       `@supports (display: grid) { body { color: red; } } div { color: blue; }`,
 			wantImports: nil,
 			wantRules:   1,
 		},
@@ -633,3 +633,25 @@ func TestParseAtImport(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQuotedValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"semicolon inside string", `p::before { content: ";"; }`, `";"`},
+		{"brace inside string", `p::before { content: "}"; }`, `"}"`},
+		{"escaped quote", `p::before { content: "a\"b"; }`, `"a\"b"`},
+		{"semicolon inside parens", `div { background: url(data:image/png;base64,AAA); }`, `url(data:image/png;base64,AAA)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet := Parse(tt.input)
+			assert.Len(t, sheet.Rules, 1)
+			assert.Len(t, sheet.Rules[0].Declarations, 1)
+			assert.Equal(t, tt.expected, sheet.Rules[0].Declarations[0].Value)
+		})
+	}
+}
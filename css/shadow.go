@@ -0,0 +1,62 @@
+package css
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// TextShadow is one shadow of a text-shadow list (CSS Text Decoration §4).
+type TextShadow struct {
+	OffsetX float64
+	OffsetY float64
+	Blur    float64
+	Color   color.Color // nil means currentColor
+}
+
+// parseTextShadow parses a text-shadow value into its shadows, topmost
+// first. "none" yields no shadows; an invalid value reports false.
+func parseTextShadow(value string, fontSize, viewportWidth, viewportHeight float64) ([]TextShadow, bool) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "none" {
+		return nil, true
+	}
+	var shadows []TextShadow
+	for _, item := range splitBackgroundLayers(value) {
+		var lengths []float64
+		var shadow TextShadow
+		for _, token := range splitBackgroundValue(item) {
+			if length, ok := parseShadowLength(token, fontSize, viewportWidth, viewportHeight); ok {
+				lengths = append(lengths, length)
+			} else if c := ParseColor(token); c != nil && shadow.Color == nil {
+				shadow.Color = c
+			} else if token != "currentcolor" {
+				return nil, false
+			}
+		}
+		if len(lengths) < 2 || len(lengths) > 3 {
+			return nil, false
+		}
+		shadow.OffsetX, shadow.OffsetY = lengths[0], lengths[1]
+		if len(lengths) == 3 {
+			if lengths[2] < 0 {
+				return nil, false
+			}
+			shadow.Blur = lengths[2]
+		}
+		shadows = append(shadows, shadow)
+	}
+	return shadows, true
+}
+
+// parseShadowLength parses a shadow offset or blur radius. Lengths need a
+// unit unless they are zero.
+func parseShadowLength(token string, fontSize, viewportWidth, viewportHeight float64) (float64, bool) {
+	if n, err := strconv.ParseFloat(token, 64); err == nil {
+		return 0, n == 0
+	}
+	if token == "normal" {
+		return 0, false
+	}
+	return parseSpacingWithContext(token, fontSize, viewportWidth, viewportHeight)
+}
@@ -0,0 +1,67 @@
+package css
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTextShadow(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []TextShadow
+		ok       bool
+	}{
+		{"none", "none", nil, true},
+		{"offsets only", "1px 2px", []TextShadow{{OffsetX: 1, OffsetY: 2}}, true},
+		{"offsets and blur", "1px 2px 3px", []TextShadow{{OffsetX: 1, OffsetY: 2, Blur: 3}}, true},
+		{"color first", "red 0 1px", []TextShadow{{OffsetY: 1, Color: color.RGBA{255, 0, 0, 255}}}, true},
+		{"color last", "-1px -1px 2px #000", []TextShadow{{OffsetX: -1, OffsetY: -1, Blur: 2, Color: color.RGBA{0, 0, 0, 255}}}, true},
+		{"em lengths", "0.5em 0 1em", []TextShadow{{OffsetX: 8, Blur: 16}}, true},
+		{"currentcolor", "1px 1px currentColor", []TextShadow{{OffsetX: 1, OffsetY: 1}}, true},
+		{
+			"shadow list",
+			"1px 1px #333, 0 0 4px white",
+			[]TextShadow{
+				{OffsetX: 1, OffsetY: 1, Color: color.RGBA{0x33, 0x33, 0x33, 255}},
+				{Blur: 4, Color: color.White},
+			},
+			true,
+		},
+		{"one length", "1px red", nil, false},
+		{"four lengths", "1px 1px 1px 1px", nil, false},
+		{"negative blur", "1px 1px -2px", nil, false},
+		{"unitless length", "1 2", nil, false},
+		{"unknown keyword", "1px 1px glow", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadows, ok := parseTextShadow(tt.input, 16, 0, 0)
+			assert.Equal(t, tt.ok, ok)
+			if !assert.Len(t, shadows, len(tt.expected)) {
+				return
+			}
+			for i, want := range tt.expected {
+				got := shadows[i]
+				assert.Equal(t, []float64{want.OffsetX, want.OffsetY, want.Blur}, []float64{got.OffsetX, got.OffsetY, got.Blur})
+				assert.True(t, colorsEqual(want.Color, got.Color), "shadow %d color: expected %v, got %v", i, want.Color, got.Color)
+			}
+		})
+	}
+}
+
+func TestTextShadowInlineStyle(t *testing.T) {
+	style := ParseInlineStyle("text-shadow: 2px 2px 4px gray")
+	assert.True(t, style.TextShadowSet)
+	assert.Len(t, style.TextShadows, 1)
+
+	style = ParseInlineStyle("text-shadow: none")
+	assert.True(t, style.TextShadowSet, "none overrides inherited shadows")
+	assert.Empty(t, style.TextShadows)
+
+	style = ParseInlineStyle("text-shadow: 2px")
+	assert.False(t, style.TextShadowSet, "invalid values are ignored")
+}
@@ -0,0 +1,60 @@
+package dom
+
+import "strings"
+
+// Autofill is the effective autocomplete state of a form control
+// (HTML §4.10.18.7).
+type Autofill struct {
+	Off  bool   // suggestions must not be offered for the field
+	Hint string // autofill field name such as "email" or "current-password"
+}
+
+// FieldAutofill resolves a control's autocomplete attribute. A field
+// without a valid value of its own uses its form owner's on/off state.
+// "new-password" fields get no suggestions either, so saved passwords are
+// never offered where a new one is being chosen.
+func FieldAutofill(field *Node) Autofill {
+	tokens := strings.Fields(strings.ToLower(field.Attributes["autocomplete"]))
+	if len(tokens) > 0 {
+		// The field name is the last token; section-*, shipping/billing
+		// and home/work prefixes only scope it.
+		switch hint := tokens[len(tokens)-1]; hint {
+		case "off":
+			return Autofill{Off: true}
+		case "on":
+			return Autofill{}
+		case "new-password":
+			return Autofill{Off: true, Hint: hint}
+		default:
+			return Autofill{Hint: hint}
+		}
+	}
+
+	if form := FormOwner(field); form != nil {
+		if strings.EqualFold(strings.TrimSpace(form.Attributes["autocomplete"]), "off") {
+			return Autofill{Off: true}
+		}
+	}
+	return Autofill{}
+}
+
+// FormOwner returns the form a control belongs to: the form named by its
+// form attribute, else its nearest ancestor <form>.
+func FormOwner(field *Node) *Node {
+	if id := field.Attributes["form"]; id != "" {
+		root := field
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		if form := FindByID(root, id); form != nil && form.TagName == TagForm {
+			return form
+		}
+		return nil
+	}
+	for n := field.Parent; n != nil; n = n.Parent {
+		if n.Type == Element && n.TagName == TagForm {
+			return n
+		}
+	}
+	return nil
+}
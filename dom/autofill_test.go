@@ -0,0 +1,45 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldAutofill(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected Autofill
+	}{
+		{"default on", `<form><input id="f"></form>`, Autofill{}},
+		{"field off", `<form><input id="f" autocomplete="off"></form>`, Autofill{Off: true}},
+		{"form off", `<form autocomplete="off"><input id="f"></form>`, Autofill{Off: true}},
+		{"field on overrides form off", `<form autocomplete="off"><input id="f" autocomplete="on"></form>`, Autofill{}},
+		{"hint overrides form off", `<form autocomplete="off"><input id="f" autocomplete="email"></form>`, Autofill{Hint: "email"}},
+		{"new-password", `<form><input id="f" type="password" autocomplete="new-password"></form>`, Autofill{Off: true, Hint: "new-password"}},
+		{"current-password", `<form><input id="f" type="password" autocomplete="current-password"></form>`, Autofill{Hint: "current-password"}},
+		{"scoped hint", `<input id="f" autocomplete="section-a shipping Postal-Code">`, Autofill{Hint: "postal-code"}},
+		{"form attribute owner", `<form id="login" autocomplete="off"></form><input id="f" form="login">`, Autofill{Off: true}},
+		{"no form", `<input id="f">`, Autofill{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Parse(strings.NewReader(tt.html))
+			field := FindByID(doc, "f")
+			assert.NotNil(t, field)
+			assert.Equal(t, tt.expected, FieldAutofill(field))
+		})
+	}
+}
+
+func TestFormOwner(t *testing.T) {
+	doc := Parse(strings.NewReader(`<form id="a"><input id="inner"></form><input id="outer" form="a"><input id="bad" form="missing">`))
+	form := FindByID(doc, "a")
+
+	assert.Equal(t, form, FormOwner(FindByID(doc, "inner")))
+	assert.Equal(t, form, FormOwner(FindByID(doc, "outer")))
+	assert.Nil(t, FormOwner(FindByID(doc, "bad")))
+}
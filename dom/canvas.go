@@ -0,0 +1,45 @@
+package dom
+
+import (
+	"image"
+	"strconv"
+	"strings"
+)
+
+// Size of a canvas's bitmap without valid width and height attributes
+// (HTML §4.12.5).
+const (
+	DefaultCanvasWidth  = 300
+	DefaultCanvasHeight = 150
+)
+
+// maxCanvasArea bounds a canvas's bitmap, in pixels; larger canvases get
+// an empty one.
+const maxCanvasArea = 1 << 26
+
+// CanvasSize returns the size of the <canvas> element n's bitmap, from
+// its width and height attributes.
+func (n *Node) CanvasSize() (width, height int) {
+	dimension := func(name string, fallback int) int {
+		v, err := strconv.Atoi(strings.TrimSpace(n.Attributes[name]))
+		if err != nil || v < 0 {
+			return fallback
+		}
+		return v
+	}
+	return dimension("width", DefaultCanvasWidth), dimension("height", DefaultCanvasHeight)
+}
+
+// CanvasBitmap returns the bitmap of the <canvas> element n, transparent
+// black until drawn into. One whose size no longer matches the attributes
+// is replaced by a cleared one.
+func (n *Node) CanvasBitmap() *image.NRGBA {
+	width, height := n.CanvasSize()
+	if width*height > maxCanvasArea {
+		width, height = 0, 0
+	}
+	if n.Bitmap == nil || n.Bitmap.Rect.Dx() != width || n.Bitmap.Rect.Dy() != height {
+		n.Bitmap = image.NewNRGBA(image.Rect(0, 0, width, height))
+	}
+	return n.Bitmap
+}
@@ -0,0 +1,22 @@
+package dom
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanvasBitmap(t *testing.T) {
+	canvas := NewElement(TagCanvas, map[string]string{"width": "4", "height": "junk"})
+	bitmap := canvas.CanvasBitmap()
+	assert.Equal(t, image.Rect(0, 0, 4, DefaultCanvasHeight), bitmap.Rect)
+	assert.Same(t, bitmap, canvas.CanvasBitmap(), "kept while the size holds")
+
+	canvas.Attributes["height"] = "2"
+	assert.Equal(t, image.Rect(0, 0, 4, 2), canvas.CanvasBitmap().Rect, "resized")
+
+	canvas.Attributes["width"] = "100000"
+	canvas.Attributes["height"] = "100000"
+	assert.True(t, canvas.CanvasBitmap().Rect.Empty(), "too large to allocate")
+}
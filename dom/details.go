@@ -0,0 +1,58 @@
+package dom
+
+// DetailsSummary returns the summary of a <details> element, its first
+// <summary> child, or nil when it has none.
+func DetailsSummary(details *Node) *Node {
+	for _, child := range details.Children {
+		if child.Type == Element && child.TagName == TagSummary {
+			return child
+		}
+	}
+	return nil
+}
+
+// IsDetailsOpen reports whether a <details> element shows its contents.
+func IsDetailsOpen(details *Node) bool {
+	_, open := details.Attributes["open"]
+	return open
+}
+
+// ToggleDetails opens a closed <details> element or closes an open one,
+// and reports whether it is now open.
+func ToggleDetails(details *Node) bool {
+	if IsDetailsOpen(details) {
+		delete(details.Attributes, "open")
+		return false
+	}
+	if details.Attributes == nil {
+		details.Attributes = make(map[string]string)
+	}
+	details.Attributes["open"] = ""
+	return true
+}
+
+// ClickedSummary returns the <summary> whose activation toggles its
+// <details> when n is clicked: the summary n is in, unless a link or form
+// control inside it takes the click first. It returns nil otherwise.
+func ClickedSummary(n *Node) *Node {
+	for ; n != nil; n = n.Parent {
+		if n.Type != Element {
+			continue
+		}
+		switch n.TagName {
+		case TagA, TagButton, TagInput, TagSelect, TagTextarea, TagLabel:
+			return nil
+		case TagSummary:
+			details := n.Parent
+			if details == nil || details.TagName != TagDetails {
+				return nil
+			}
+			// A summary the browser supplies is not among the children
+			if s := DetailsSummary(details); s != n && s != nil {
+				return nil
+			}
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToggleDetails(t *testing.T) {
+	doc := Parse(strings.NewReader(`<details><summary>More</summary><p>Body</p></details>`))
+	details := FindElementsByTagName(doc, "details")
+
+	assert.False(t, IsDetailsOpen(details))
+	assert.True(t, ToggleDetails(details))
+	assert.True(t, IsDetailsOpen(details))
+	assert.False(t, ToggleDetails(details))
+	assert.False(t, IsDetailsOpen(details))
+}
+
+func TestClickedSummary(t *testing.T) {
+	doc := Parse(strings.NewReader(`<details><summary>More <b>info</b> <a href="/x">link</a></summary>` +
+		`<summary>Second</summary><p>Body</p></details><summary>Loose</summary>`))
+	details := FindElementsByTagName(doc, "details")
+	summary := DetailsSummary(details)
+	second := details.Children[1]
+	bold := FindElementsByTagName(doc, "b")
+	link := FindElementsByTagName(doc, "a")
+	body := FindElementsByTagName(doc, "p")
+	loose := FindElementsByTagName(doc, "body").Children[1]
+
+	assert.Equal(t, summary, ClickedSummary(summary))
+	assert.Equal(t, summary, ClickedSummary(bold.Children[0]), "text inside the summary")
+	assert.Nil(t, ClickedSummary(link), "links take the click")
+	assert.Nil(t, ClickedSummary(second), "only the first summary toggles")
+	assert.Nil(t, ClickedSummary(body))
+	assert.Nil(t, ClickedSummary(loose), "summary outside details")
+
+	// The summary supplied for details without one is not a child
+	bare := Parse(strings.NewReader(`<details><p>Body</p></details>`))
+	bareDetails := FindElementsByTagName(bare, "details")
+	supplied := &Node{Type: Element, TagName: TagSummary, Parent: bareDetails}
+	assert.Equal(t, supplied, ClickedSummary(supplied))
+}
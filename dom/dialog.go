@@ -0,0 +1,64 @@
+package dom
+
+// IsDialogOpen reports whether a <dialog> element is shown.
+func IsDialogOpen(dialog *Node) bool {
+	_, open := dialog.Attributes["open"]
+	return open
+}
+
+// ShowDialog opens a closed <dialog>, as a modal dialog above the page when
+// modal is set. It reports false, changing nothing, when the dialog is
+// already open.
+func ShowDialog(dialog *Node, modal bool) bool {
+	if IsDialogOpen(dialog) {
+		return false
+	}
+	if dialog.Attributes == nil {
+		dialog.Attributes = make(map[string]string)
+	}
+	dialog.Attributes["open"] = ""
+	dialog.Modal = modal
+	return true
+}
+
+// CloseDialog closes an open <dialog> and reports whether it was open.
+func CloseDialog(dialog *Node) bool {
+	if !IsDialogOpen(dialog) {
+		return false
+	}
+	delete(dialog.Attributes, "open")
+	dialog.Modal = false
+	return true
+}
+
+// TopModalDialog returns the open modal <dialog> in the tree under root
+// that was shown last, or nil when there is none. Later dialogs in
+// document order are taken to be on top.
+func TopModalDialog(root *Node) *Node {
+	var top *Node
+	for n := range Elements(root, TagDialog) {
+		if n.Modal && IsDialogOpen(n) {
+			top = n
+		}
+	}
+	return top
+}
+
+// BlockedByModal reports whether n is inert because a modal <dialog> it is
+// not inside is open: clicks and focus do not reach the rest of the page.
+func BlockedByModal(n *Node) bool {
+	root := n
+	for a := range n.Ancestors() {
+		root = a
+	}
+	modal := TopModalDialog(root)
+	if modal == nil {
+		return false
+	}
+	for a := n; a != nil; a = a.Parent {
+		if a == modal {
+			return false
+		}
+	}
+	return true
+}
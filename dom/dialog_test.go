@@ -0,0 +1,36 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowDialog(t *testing.T) {
+	doc := Parse(strings.NewReader(`<p>Page</p><dialog id="a"><button>OK</button></dialog><dialog id="b">B</dialog>`))
+	a := FindElementsByTagName(doc, "dialog")
+	b := a.NextSibling()
+	page := FindElementsByTagName(doc, "p")
+	button := FindElementsByTagName(doc, "button")
+
+	assert.False(t, IsDialogOpen(a))
+	assert.Nil(t, TopModalDialog(doc))
+	assert.False(t, BlockedByModal(page))
+
+	assert.True(t, ShowDialog(a, true))
+	assert.False(t, ShowDialog(a, false), "an open dialog is not shown again")
+	assert.True(t, a.Modal)
+	assert.Equal(t, a, TopModalDialog(doc))
+	assert.True(t, BlockedByModal(page))
+	assert.False(t, BlockedByModal(button))
+
+	assert.True(t, ShowDialog(b, false))
+	assert.Equal(t, a, TopModalDialog(doc), "non-modal dialogs are not on top")
+
+	assert.True(t, CloseDialog(a))
+	assert.False(t, CloseDialog(a))
+	assert.False(t, a.Modal)
+	assert.Nil(t, TopModalDialog(doc))
+	assert.False(t, BlockedByModal(page))
+}
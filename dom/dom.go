@@ -1,5 +1,11 @@
 package dom
 
+import (
+	"image"
+	"slices"
+	"strings"
+)
+
 type NodeType int
 
 const (
@@ -21,6 +27,8 @@ type Node struct {
 	NaturalHeight int
 	ImageComplete bool
 	CurrentSrc    string
+	Modal         bool         // an open <dialog> shown with showModal (see dialog.go)
+	Bitmap        *image.NRGBA // a <canvas>'s backing store (see canvas.go)
 }
 
 func NewElement(tagName string, tags map[string]string) *Node {
@@ -44,6 +52,19 @@ func (n *Node) AppendChild(child *Node) {
 	n.Children = append(n.Children, child)
 }
 
+// InsertBefore inserts child before ref among n's children, or appends it
+// when ref is nil or not a child of n.
+func (n *Node) InsertBefore(child, ref *Node) {
+	for i, c := range n.Children {
+		if c == ref {
+			child.Parent = n
+			n.Children = append(n.Children[:i], append([]*Node{child}, n.Children[i:]...)...)
+			return
+		}
+	}
+	n.AppendChild(child)
+}
+
 func (n *Node) RemoveChild(child *Node) {
 	for i, c := range n.Children {
 		if c == child {
@@ -68,7 +89,7 @@ func FindTitle(node *Node) string {
 	if node.TagName == TagTitle {
 		for _, child := range node.Children {
 			if child.Type == Text {
-				return child.Text
+				return CollapseWhitespace(child.Text)
 			}
 		}
 	}
@@ -117,6 +138,101 @@ func FindStylesheetLinks(node *Node) []string {
 	return links
 }
 
+// IconLink is a <link> naming an icon for the page (HTML §4.6.7.8).
+type IconLink struct {
+	Href  string
+	Sizes string // space-separated WxH sizes, or "any" for scalable icons
+	Type  string
+}
+
+// FindIconLinks returns the <link> elements whose rel includes the icon
+// keyword, such as rel="icon" and rel="shortcut icon", in document order.
+func FindIconLinks(node *Node) []IconLink {
+	var links []IconLink
+	if node.TagName == "link" {
+		href, ok := node.Attributes["href"]
+		if ok && slices.Contains(strings.Fields(strings.ToLower(node.Attributes["rel"])), "icon") {
+			links = append(links, IconLink{Href: href, Sizes: node.Attributes["sizes"], Type: node.Attributes["type"]})
+		}
+	}
+	for _, child := range node.Children {
+		links = append(links, FindIconLinks(child)...)
+	}
+	return links
+}
+
+// FindMetaCharset returns the encoding label the first <meta charset> or
+// <meta http-equiv="content-type"> declares, "" if there is none.
+func FindMetaCharset(node *Node) string {
+	for n := range Walk(node) {
+		if n.Type != Element || n.TagName != "meta" {
+			continue
+		}
+		if label := strings.TrimSpace(n.Attributes["charset"]); label != "" {
+			return label
+		}
+		if strings.EqualFold(strings.TrimSpace(n.Attributes["http-equiv"]), "content-type") {
+			if label := charsetFromContent(n.Attributes["content"]); label != "" {
+				return label
+			}
+		}
+	}
+	return ""
+}
+
+// FindMetaReferrer returns the referrer policy the last
+// <meta name="referrer"> declares, "" if there is none.
+func FindMetaReferrer(node *Node) string {
+	policy := ""
+	for n := range Walk(node) {
+		if n.Type == Element && n.TagName == "meta" && strings.EqualFold(strings.TrimSpace(n.Attributes["name"]), "referrer") {
+			if content := strings.TrimSpace(n.Attributes["content"]); content != "" {
+				policy = content
+			}
+		}
+	}
+	return policy
+}
+
+// charsetFromContent extracts the encoding label from a Content-Type value
+// (HTML §2.6.4, "extracting a character encoding from a meta element").
+func charsetFromContent(content string) string {
+	// Lowered byte for byte, so indexes into it hold for content
+	folded := []byte(content)
+	for i, c := range folded {
+		if 'A' <= c && c <= 'Z' {
+			folded[i] = c + 'a' - 'A'
+		}
+	}
+	lower := string(folded)
+	for {
+		i := strings.Index(lower, "charset")
+		if i < 0 {
+			return ""
+		}
+		content, lower = content[i+len("charset"):], lower[i+len("charset"):]
+		rest := strings.TrimLeft(lower, " \t\n\f\r")
+		if !strings.HasPrefix(rest, "=") {
+			continue // not this "charset"; look for the next
+		}
+		value := strings.TrimLeft(content[len(content)-len(rest)+1:], " \t\n\f\r")
+		if value == "" {
+			return ""
+		}
+		if quote := value[0]; quote == '"' || quote == '\'' {
+			end := strings.IndexByte(value[1:], quote)
+			if end < 0 {
+				return ""
+			}
+			return value[1 : end+1]
+		}
+		if end := strings.IndexAny(value, " \t\n\f\r;"); end >= 0 {
+			value = value[:end]
+		}
+		return value
+	}
+}
+
 func FindByID(node *Node, id string) *Node {
 	if node == nil {
 		return nil
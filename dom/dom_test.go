@@ -1,6 +1,7 @@
 package dom
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -115,6 +116,23 @@ func TestAppendChild(t *testing.T) {
 	})
 }
 
+func TestInsertBefore(t *testing.T) {
+	parent := NewElement("ul", nil)
+	first := NewElement("li", nil)
+	last := NewElement("li", nil)
+	parent.AppendChild(first)
+	parent.AppendChild(last)
+
+	middle := NewElement("li", nil)
+	parent.InsertBefore(middle, last)
+	assert.Equal(t, []*Node{first, middle, last}, parent.Children)
+	assert.Same(t, parent, middle.Parent)
+
+	end := NewElement("li", nil)
+	parent.InsertBefore(end, nil)
+	assert.Same(t, end, parent.Children[3], "nil ref appends")
+}
+
 func TestFindTitle(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -398,6 +416,26 @@ func TestFindStylesheetLinks(t *testing.T) {
 	}
 }
 
+func TestFindIconLinks(t *testing.T) {
+	html := NewElement("html", nil)
+	head := NewElement("head", nil)
+	for _, attrs := range []map[string]string{
+		{"rel": "stylesheet", "href": "styles.css"},
+		{"rel": "Shortcut Icon", "href": "/favicon.ico"},
+		{"rel": "apple-touch-icon", "href": "/touch.png"},
+		{"rel": "icon", "href": "/icon.png", "sizes": "32x32", "type": "image/png"},
+		{"rel": "icon"},
+	} {
+		head.AppendChild(NewElement("link", attrs))
+	}
+	html.AppendChild(head)
+
+	assert.Equal(t, []IconLink{
+		{Href: "/favicon.ico"},
+		{Href: "/icon.png", Sizes: "32x32", Type: "image/png"},
+	}, FindIconLinks(html))
+}
+
 func TestFindElementsByTagName(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -573,3 +611,32 @@ func TestFindBaseHref(t *testing.T) {
 		})
 	}
 }
+
+func TestFindMetaCharset(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"meta charset", `<head><meta charset=" Shift_JIS "></head>`, "Shift_JIS"},
+		{"http-equiv", `<meta http-equiv="Content-Type" content="text/html; CHARSET=iso-8859-2">`, "iso-8859-2"},
+		{"http-equiv quoted", `<meta http-equiv="content-type" content="text/html; charset = 'koi8-r'">`, "koi8-r"},
+		{"first wins", `<meta charset="gbk"><meta charset="big5">`, "gbk"},
+		{"late in body", `<p>text</p><meta charset="windows-1251">`, "windows-1251"},
+		{"content without charset", `<meta http-equiv="content-type" content="text/html">`, ""},
+		{"unterminated quote", `<meta http-equiv="content-type" content="text/html; charset='utf-8">`, ""},
+		{"no meta", `<p>text</p>`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FindMetaCharset(Parse(strings.NewReader(tt.html))))
+		})
+	}
+}
+
+func TestFindMetaReferrer(t *testing.T) {
+	assert.Equal(t, "origin", FindMetaReferrer(Parse(strings.NewReader(`<meta name="Referrer" content=" origin ">`))))
+	assert.Equal(t, "no-referrer", FindMetaReferrer(Parse(strings.NewReader(`<meta name="referrer" content="origin"><meta name="referrer" content="no-referrer">`))), "the last wins")
+	assert.Equal(t, "", FindMetaReferrer(Parse(strings.NewReader(`<meta name="description" content="origin">`))))
+}
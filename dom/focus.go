@@ -0,0 +1,84 @@
+package dom
+
+import "strconv"
+
+// IsFocusable reports whether n can receive focus (HTML 6.6.3): links with
+// an href, enabled form controls, summary, and any element with a tabindex.
+func IsFocusable(n *Node) bool {
+	if n == nil || n.Type != Element {
+		return false
+	}
+	if _, ok := n.Attributes["tabindex"]; ok {
+		return true
+	}
+	switch n.TagName {
+	case TagA:
+		return n.Attributes["href"] != ""
+	case "input":
+		if n.Attributes["type"] == "hidden" {
+			return false
+		}
+		fallthrough
+	case "button", "select", "textarea":
+		_, disabled := n.Attributes["disabled"]
+		return !disabled
+	case "summary":
+		return true
+	}
+	return false
+}
+
+// InTabOrder reports whether sequential focus navigation (the Tab key)
+// visits n: it is focusable and its tabindex, if any, is not negative.
+func InTabOrder(n *Node) bool {
+	if !IsFocusable(n) {
+		return false
+	}
+	if v, ok := n.Attributes["tabindex"]; ok {
+		if i, err := strconv.Atoi(v); err == nil && i < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FocusTarget returns the element that takes focus when n is clicked: n or
+// its nearest focusable ancestor. Returns nil if there is none.
+func FocusTarget(n *Node) *Node {
+	if IsFocusable(n) {
+		return n
+	}
+	if n == nil {
+		return nil
+	}
+	for a := range n.Ancestors() {
+		if IsFocusable(a) {
+			return a
+		}
+	}
+	return nil
+}
+
+// NextInTabOrder returns the element after current in root's tab order,
+// wrapping around to the first. Elements are visited in tree order.
+// Returns nil if nothing in root is in the tab order.
+func NextInTabOrder(root, current *Node) *Node {
+	var first *Node
+	passed := current == nil
+	for n := range Walk(root) {
+		if n == current {
+			passed = true
+			continue
+		}
+		if !InTabOrder(n) {
+			continue
+		}
+		if passed {
+			return n
+		}
+		if first == nil {
+			first = n
+		}
+	}
+	return first
+}
@@ -0,0 +1,62 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsFocusable(t *testing.T) {
+	tests := []struct {
+		name      string
+		node      *Node
+		focusable bool
+		inOrder   bool
+	}{
+		{"link", NewElement("a", map[string]string{"href": "/"}), true, true},
+		{"anchor without href", NewElement("a", map[string]string{}), false, false},
+		{"button", NewElement("button", map[string]string{}), true, true},
+		{"disabled button", NewElement("button", map[string]string{"disabled": ""}), false, false},
+		{"text input", NewElement("input", map[string]string{}), true, true},
+		{"hidden input", NewElement("input", map[string]string{"type": "hidden"}), false, false},
+		{"div", NewElement("div", map[string]string{}), false, false},
+		{"div with tabindex", NewElement("div", map[string]string{"tabindex": "0"}), true, true},
+		{"negative tabindex", NewElement("div", map[string]string{"tabindex": "-1"}), true, false},
+		{"text", NewText("hi"), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.focusable, IsFocusable(tt.node))
+			assert.Equal(t, tt.inOrder, InTabOrder(tt.node))
+		})
+	}
+}
+
+func TestFocusTarget(t *testing.T) {
+	doc := Parse(strings.NewReader(`<p><a href="/x"><b>bold</b></a> plain</p>`))
+	link := FindElementsByTagName(doc, "a")
+	bold := FindElementsByTagName(doc, "b")
+	p := FindElementsByTagName(doc, "p")
+
+	assert.Equal(t, link, FocusTarget(link))
+	assert.Equal(t, link, FocusTarget(bold), "clicks inside a link focus the link")
+	assert.Nil(t, FocusTarget(p))
+	assert.Nil(t, FocusTarget(nil))
+}
+
+func TestNextInTabOrder(t *testing.T) {
+	doc := Parse(strings.NewReader(`<a href="/1">one</a><div tabindex="-1">skip</div><button>two</button><input type="hidden"><textarea></textarea>`))
+	link := FindElementsByTagName(doc, "a")
+	button := FindElementsByTagName(doc, "button")
+	textarea := FindElementsByTagName(doc, "textarea")
+	skipped := FindElementsByTagName(doc, "div")
+
+	assert.Equal(t, link, NextInTabOrder(doc, nil), "starts at the first element")
+	assert.Equal(t, button, NextInTabOrder(doc, link))
+	assert.Equal(t, textarea, NextInTabOrder(doc, button))
+	assert.Equal(t, link, NextInTabOrder(doc, textarea), "wraps around")
+	assert.Equal(t, button, NextInTabOrder(doc, skipped), "continues after a focused element outside the tab order")
+	assert.Nil(t, NextInTabOrder(Parse(strings.NewReader(`<p>none</p>`)), nil))
+}
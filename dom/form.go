@@ -0,0 +1,108 @@
+package dom
+
+import "strings"
+
+// submittableElements are the elements whose values make up a form's
+// entry list (HTML §4.10.2).
+var submittableElements = map[string]bool{
+	TagButton: true, TagInput: true, TagSelect: true, TagTextarea: true,
+}
+
+// FormControls returns the submittable elements form owns, in tree order:
+// those inside it and those naming it with a form attribute.
+func FormControls(form *Node) []*Node {
+	root := form
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	var controls []*Node
+	for n := range Elements(root, "") {
+		if submittableElements[n.TagName] && FormOwner(n) == form {
+			controls = append(controls, n)
+		}
+	}
+	return controls
+}
+
+// ButtonType returns what activating n does, "submit", "reset" or
+// "button", for buttons and button inputs; "" for anything else. A
+// <button> with a missing or unknown type submits.
+func ButtonType(n *Node) string {
+	if n == nil || n.Type != Element {
+		return ""
+	}
+	buttonType := strings.ToLower(strings.TrimSpace(n.Attributes["type"]))
+	switch n.TagName {
+	case TagButton:
+		if buttonType == "reset" || buttonType == "button" {
+			return buttonType
+		}
+		return "submit"
+	case TagInput:
+		switch buttonType {
+		case "submit", "image":
+			return "submit"
+		case "reset", "button":
+			return buttonType
+		}
+	}
+	return ""
+}
+
+// ClickedButton returns the button a click on n activates: n or the
+// button it is in, unless a link inside the button takes the click first.
+// It returns nil otherwise.
+func ClickedButton(n *Node) *Node {
+	for ; n != nil; n = n.Parent {
+		if n.Type != Element {
+			continue
+		}
+		if ButtonType(n) != "" {
+			return n
+		}
+		if n.TagName == TagA {
+			return nil
+		}
+	}
+	return nil
+}
+
+// DefaultButton returns form's default button, the first submit button
+// it owns in tree order, or nil when it has none.
+func DefaultButton(form *Node) *Node {
+	for _, control := range FormControls(form) {
+		if ButtonType(control) == "submit" {
+			return control
+		}
+	}
+	return nil
+}
+
+// IsControlDisabled reports whether form control n is disabled: by its
+// own disabled attribute, or by a disabled <fieldset> it is in, unless it
+// is in that fieldset's first <legend>.
+func IsControlDisabled(n *Node) bool {
+	if _, disabled := n.Attributes["disabled"]; disabled {
+		return true
+	}
+	child := n
+	for a := range n.Ancestors() {
+		if a.Type == Element && a.TagName == TagFieldSet {
+			if _, disabled := a.Attributes["disabled"]; disabled && child != firstLegend(a) {
+				return true
+			}
+		}
+		child = a
+	}
+	return false
+}
+
+// firstLegend returns the first <legend> child of fieldset, or nil.
+func firstLegend(fieldset *Node) *Node {
+	for _, child := range fieldset.Children {
+		if child.Type == Element && child.TagName == TagLegend {
+			return child
+		}
+	}
+	return nil
+}
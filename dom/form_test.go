@@ -0,0 +1,77 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormControls(t *testing.T) {
+	doc := Parse(strings.NewReader(`<form id="f"><input id="a"><p><select id="b"></select></p><label>x</label><textarea id="c"></textarea></form>` +
+		`<form id="g"><input id="other"></form><button id="d" form="f">Go</button><input id="loose">`))
+	ids := func(nodes []*Node) []string {
+		var out []string
+		for _, n := range nodes {
+			out = append(out, n.Attributes["id"])
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "d"}, ids(FormControls(FindByID(doc, "f"))))
+	assert.Equal(t, []string{"other"}, ids(FormControls(FindByID(doc, "g"))))
+}
+
+func TestButtonType(t *testing.T) {
+	tests := []struct {
+		html     string
+		expected string
+	}{
+		{`<button>Go</button>`, "submit"},
+		{`<button type="bogus">Go</button>`, "submit"},
+		{`<button type="RESET">Go</button>`, "reset"},
+		{`<button type="button">Go</button>`, "button"},
+		{`<input type="submit">`, "submit"},
+		{`<input type="image">`, "submit"},
+		{`<input type="reset">`, "reset"},
+		{`<input type="text">`, ""},
+		{`<input>`, ""},
+		{`<a href="/">Go</a>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.html, func(t *testing.T) {
+			body := FindElementsByTagName(Parse(strings.NewReader(tt.html)), "body")
+			assert.Equal(t, tt.expected, ButtonType(body.Children[0]))
+		})
+	}
+}
+
+func TestClickedButton(t *testing.T) {
+	doc := Parse(strings.NewReader(`<form><button id="b">Send <b>now</b></button><button id="l"><a href="/x">link</a></button><p>text</p></form>`))
+	button := FindByID(doc, "b")
+	bold := FindElementsByTagName(doc, "b")
+
+	assert.Equal(t, button, ClickedButton(button))
+	assert.Equal(t, button, ClickedButton(bold.Children[0]), "text inside the button")
+	assert.Nil(t, ClickedButton(FindElementsByTagName(doc, "a")), "links take the click")
+	assert.Nil(t, ClickedButton(FindElementsByTagName(doc, "p")))
+}
+
+func TestDefaultButton(t *testing.T) {
+	doc := Parse(strings.NewReader(`<form id="f"><button type="button">A</button><input type="text"><input id="go" type="submit"><button>B</button></form>` +
+		`<form id="none"><button type="reset">R</button></form>`))
+
+	assert.Equal(t, FindByID(doc, "go"), DefaultButton(FindByID(doc, "f")))
+	assert.Nil(t, DefaultButton(FindByID(doc, "none")))
+}
+
+func TestIsControlDisabled(t *testing.T) {
+	doc := Parse(strings.NewReader(`<input id="off" disabled><input id="on">` +
+		`<fieldset disabled><legend><input id="legend"></legend><legend><input id="second"></legend><input id="inside"></fieldset>`))
+
+	assert.True(t, IsControlDisabled(FindByID(doc, "off")))
+	assert.False(t, IsControlDisabled(FindByID(doc, "on")))
+	assert.True(t, IsControlDisabled(FindByID(doc, "inside")), "in a disabled fieldset")
+	assert.False(t, IsControlDisabled(FindByID(doc, "legend")), "in the fieldset's first legend")
+	assert.True(t, IsControlDisabled(FindByID(doc, "second")))
+}
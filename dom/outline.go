@@ -0,0 +1,71 @@
+package dom
+
+import "strings"
+
+// OutlineEntry is a heading in the document outline.
+type OutlineEntry struct {
+	Level    int    // 1–6 for h1–h6
+	Text     string // heading text with whitespace collapsed
+	ID       string // id attribute, empty if the heading has none
+	Node     *Node
+	Children []*OutlineEntry
+}
+
+// HeadingLevel returns 1–6 for h1–h6 elements and 0 for anything else.
+func HeadingLevel(n *Node) int {
+	if n == nil || n.Type != Element || len(n.TagName) != 2 || n.TagName[0] != 'h' {
+		return 0
+	}
+	if level := int(n.TagName[1] - '0'); level >= 1 && level <= 6 {
+		return level
+	}
+	return 0
+}
+
+// Outline returns the document's h1–h6 hierarchy in document order. Each
+// heading nests under the nearest preceding heading of a lower level;
+// skipped levels (h1 followed by h3) nest directly.
+func Outline(root *Node) []*OutlineEntry {
+	var roots []*OutlineEntry
+	var stack []*OutlineEntry
+
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if level := HeadingLevel(n); level > 0 {
+			entry := &OutlineEntry{
+				Level: level,
+				Text:  strings.Join(strings.Fields(n.InnerText()), " "),
+				ID:    n.Attributes["id"],
+				Node:  n,
+			}
+			for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				roots = append(roots, entry)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, entry)
+			}
+			stack = append(stack, entry)
+			return // headings don't contain headings
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	if root != nil {
+		walk(root)
+	}
+	return roots
+}
+
+// FindHeading returns the nearest h1–h6 element at or above n, or nil.
+func FindHeading(n *Node) *Node {
+	for ; n != nil; n = n.Parent {
+		if HeadingLevel(n) > 0 {
+			return n
+		}
+	}
+	return nil
+}
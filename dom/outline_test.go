@@ -0,0 +1,100 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// outlineString renders an outline as "level:text#id" lines indented by depth
+func outlineString(entries []*OutlineEntry, depth int) string {
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(strings.Repeat("  ", depth))
+		sb.WriteString(string(rune('0'+e.Level)) + ":" + e.Text)
+		if e.ID != "" {
+			sb.WriteString("#" + e.ID)
+		}
+		sb.WriteString("\n")
+		sb.WriteString(outlineString(e.Children, depth+1))
+	}
+	return sb.String()
+}
+
+func TestOutline(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name:     "no headings",
+			html:     `<p>text</p>`,
+			expected: "",
+		},
+		{
+			name: "nested levels",
+			html: `<h1 id="top">Guide</h1><h2 id="install">Install</h2><h3>Linux</h3><h3>macOS</h3><h2 id="usage">Usage</h2>`,
+			expected: "1:Guide#top\n" +
+				"  2:Install#install\n" +
+				"    3:Linux\n" +
+				"    3:macOS\n" +
+				"  2:Usage#usage\n",
+		},
+		{
+			name:     "skipped level nests directly",
+			html:     `<h1>A</h1><h3>B</h3><h2>C</h2>`,
+			expected: "1:A\n  3:B\n  2:C\n",
+		},
+		{
+			name:     "headings without a parent level are roots",
+			html:     `<h2>A</h2><h1>B</h1><h2>C</h2>`,
+			expected: "2:A\n1:B\n  2:C\n",
+		},
+		{
+			name:     "headings inside sections and inline markup",
+			html:     `<section><h2 id="x">Hello <code>world</code></h2><div><h3>  Deep   text </h3></div></section>`,
+			expected: "2:Hello world#x\n  3:Deep text\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Parse(strings.NewReader(tt.html))
+			assert.Equal(t, tt.expected, outlineString(Outline(doc), 0))
+		})
+	}
+}
+
+func TestHeadingLevel(t *testing.T) {
+	tests := []struct {
+		node     *Node
+		expected int
+	}{
+		{NewElement("h1", nil), 1},
+		{NewElement("h6", nil), 6},
+		{NewElement("h7", nil), 0},
+		{NewElement("hr", nil), 0},
+		{NewElement("header", nil), 0},
+		{NewText("h1"), 0},
+		{nil, 0},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, HeadingLevel(tt.node))
+	}
+}
+
+func TestFindHeading(t *testing.T) {
+	heading := NewElement("h2", map[string]string{"id": "s"})
+	code := NewElement("code", nil)
+	text := NewText("x")
+	heading.AppendChild(code)
+	code.AppendChild(text)
+	p := NewElement("p", nil)
+
+	assert.Same(t, heading, FindHeading(text))
+	assert.Same(t, heading, FindHeading(heading))
+	assert.Nil(t, FindHeading(p))
+}
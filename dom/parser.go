@@ -8,6 +8,14 @@ import (
 	"golang.org/x/net/html/atom"
 )
 
+// Namespace URIs of HTML elements and of the SVG and MathML elements
+// embedded in HTML, by the short names the parser gives foreign content
+var namespaces = map[string]string{
+	"":     "http://www.w3.org/1999/xhtml",
+	"svg":  "http://www.w3.org/2000/svg",
+	"math": "http://www.w3.org/1998/Math/MathML",
+}
+
 func Parse(r io.Reader) *Node {
 	doc, err := html.Parse(r)
 	if err != nil {
@@ -17,47 +25,37 @@ func Parse(r io.Reader) *Node {
 	return convertNode(doc)
 }
 
+// convertNode converts a parsed HTML node and its subtree. Text is kept as
+// written: white space is collapsed by layout, which knows the white-space
+// property of the text's element.
 func convertNode(n *html.Node) *Node {
-	return convertNodeWithContext(n, false)
-}
-
-func convertNodeWithContext(n *html.Node, preserveWhitespace bool) *Node {
 	var node *Node
 
-	// Check if this element preserves whitespace
-	isPreserving := preserveWhitespace
-	if n.Type == html.ElementNode && (n.Data == "pre" || n.Data == "script" || n.Data == "style") {
-		isPreserving = true
-	}
-
 	switch n.Type {
 	case html.DocumentNode:
 		node = &Node{Type: Document, Children: []*Node{}}
 	case html.ElementNode:
 		attrs := make(map[string]string)
 		for _, attr := range n.Attr {
+			if attr.Namespace != "" {
+				// xlink:href, xml:space and the like in foreign content
+				attr.Key = attr.Namespace + ":" + attr.Key
+			}
 			attrs[attr.Key] = attr.Val
 		}
 		node = NewElement(n.Data, attrs)
-		node.Namespace = "http://www.w3.org/1999/xhtml"
+		node.Namespace = namespaces[n.Namespace]
 	case html.TextNode:
-		var text string
-		if preserveWhitespace {
-			// Keep all whitespace for <pre> content
-			text = n.Data
-		} else {
-			text = normalizeWhitespace(n.Data)
-		}
-		if text == "" {
+		if n.Data == "" {
 			return nil
 		}
-		node = NewText(text)
+		node = NewText(n.Data)
 	default:
 		return nil
 	}
 
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		child := convertNodeWithContext(c, isPreserving)
+		child := convertNode(c)
 		if child != nil {
 			node.AppendChild(child)
 		}
@@ -66,42 +64,6 @@ func convertNodeWithContext(n *html.Node, preserveWhitespace bool) *Node {
 	return node
 }
 
-// normalizeWhitespace collapses whitespace sequences to single spaces
-// while preserving space boundaries for inline element separation
-func normalizeWhitespace(s string) string {
-	if len(s) == 0 {
-		return ""
-	}
-
-	// Check boundaries - only actual spaces (not newlines) indicate inline separation
-	startsWithSpace := s[0] == ' ' || s[0] == '\t'
-	endsWithSpace := s[len(s)-1] == ' ' || s[len(s)-1] == '\t'
-
-	// Collapse all whitespace to single spaces
-	words := strings.Fields(s)
-	if len(words) == 0 {
-		// Text was all whitespace
-		// Only keep if it's purely spaces/tabs (inline separator)
-		// Discard if it contains newlines (HTML formatting)
-		if strings.ContainsAny(s, "\n\r") {
-			return ""
-		}
-		return " "
-	}
-
-	result := strings.Join(words, " ")
-
-	// Preserve boundary spaces for inline element separation
-	if startsWithSpace {
-		result = " " + result
-	}
-	if endsWithSpace {
-		result = result + " "
-	}
-
-	return result
-}
-
 // ParseFragment parses an HTML fragment (not a full document)
 // Returns a slice of nodes that were parsed
 func ParseFragment(htmlContent string) []*Node {
@@ -125,4 +87,3 @@ func ParseFragment(htmlContent string) []*Node {
 	}
 	return result
 }
-
@@ -123,56 +123,23 @@ func printTree(n *Node, indent string) string {
 	return sb.String()
 }
 
-func TestNormalizeWhitespace(t *testing.T) {
+func TestCollapseWhitespace(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
 		expected string
 	}{
-		// Empty input
 		{"empty string", "", ""},
-
-		// All whitespace with newlines (HTML formatting between blocks)
-		{"newlines only", "\n\n", ""},
-		{"newlines with spaces", "\n    \n", ""},
-		{"tabs and newlines", "\t\n\t", ""},
-
-		// All whitespace without newlines (inline separator)
-		{"single space", " ", " "},
-		{"multiple spaces", "   ", " "},
-		{"tabs only", "\t\t", " "},
-
-		// Text with leading space (inline element after text)
-		{"leading space", " hello", " hello"},
-		{"leading tab", "\thello", " hello"},
-
-		// Text with trailing space (text before inline element)
-		{"trailing space", "hello ", "hello "},
-		{"trailing tab", "hello\t", "hello "},
-
-		// Text with both leading and trailing space
-		{"both spaces", " hello ", " hello "},
-
-		// Text with internal whitespace (should collapse)
-		{"internal spaces", "hello    world", "hello world"},
-		{"internal newline", "hello\nworld", "hello world"},
-		{"internal mixed", "hello  \n  world", "hello world"},
-
-		// Text starting/ending with newline (no boundary space)
-		{"starts with newline", "\nhello", "hello"},
-		{"ends with newline", "hello\n", "hello"},
-		{"both newlines", "\nhello\n", "hello"},
-
-		// Real-world HTML scenarios
-		{"before inline tag", "This is ", "This is "},
-		{"after inline tag", " using strong tag.", " using strong tag."},
-		{"between block tags", "\n    \n    ", ""},
+		{"whitespace only", " \n\t ", ""},
+		{"leading and trailing", "  hello  ", "hello"},
+		{"internal runs", "hello  \n  world", "hello world"},
+		{"form feed", "a\f\fb", "a b"},
+		{"nbsp kept", "\u00a0a\u00a0\u00a0b", "\u00a0a\u00a0\u00a0b"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := normalizeWhitespace(tt.input)
-			assert.Equal(t, tt.expected, result, "normalizeWhitespace(%q)", tt.input)
+			assert.Equal(t, tt.expected, CollapseWhitespace(tt.input))
 		})
 	}
 }
@@ -275,7 +242,7 @@ func TestParse(t *testing.T) {
 			}(),
 		},
 		{
-			name:  "text with whitespace normalization",
+			name:  "text keeps its whitespace",
 			input: "<p>  hello   world  </p>",
 			expected: func() *Node {
 				doc := &Node{Type: Document, Children: []*Node{}}
@@ -283,8 +250,8 @@ func TestParse(t *testing.T) {
 				head := NewElement("head", nil)
 				body := NewElement("body", nil)
 				p := NewElement("p", nil)
-				// normalizeWhitespace preserves boundary spaces for inline element separation
-				text := NewText(" hello world ")
+				// Layout collapses white space; the DOM keeps the source text
+				text := NewText("  hello   world  ")
 
 				p.AppendChild(text)
 				body.AppendChild(p)
@@ -379,6 +346,47 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseFormattingWhitespace(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string // text of the body's child nodes, "<tag>" for elements
+	}{
+		{"between inline elements", "<b>a</b>\n  <i>b</i>", []string{"<b>", "\n  ", "<i>"}},
+		{"between text and inline element", "a\n<b>b</b>", []string{"a\n", "<b>"}},
+		{"between block elements", "<p>a</p>\n  <p>b</p>", []string{"<p>", "\n  ", "<p>"}},
+		{"between block and inline", "<div>a</div>\n<b>b</b>", []string{"<div>", "\n", "<b>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Parse(strings.NewReader("<html><body>" + tt.input + "</body></html>"))
+			body := FindElementsByTagName(doc, TagBody)
+			var got []string
+			for _, child := range body.Children {
+				if child.Type == Text {
+					got = append(got, child.Text)
+				} else {
+					got = append(got, "<"+child.TagName+">")
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSVG(t *testing.T) {
+	doc := Parse(strings.NewReader(`<p><svg viewBox="0 0 24 24"><linearGradient id="g"></linearGradient><use xlink:href="#g"/></svg></p>`))
+	svg := FindElementsByTagName(doc, TagSvg)
+	if assert.NotNil(t, svg) {
+		assert.Equal(t, "http://www.w3.org/2000/svg", svg.Namespace)
+		assert.Equal(t, "0 0 24 24", svg.Attributes["viewBox"], "attribute names keep their SVG case")
+		assert.Equal(t, "linearGradient", svg.Children[0].TagName)
+		assert.Equal(t, "#g", svg.Children[1].Attributes["xlink:href"])
+	}
+	assert.Equal(t, "http://www.w3.org/1999/xhtml", FindElementsByTagName(doc, TagP).Namespace)
+}
+
 func TestParseFragment(t *testing.T) {
 	tests := []struct {
 		name     string
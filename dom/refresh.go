@@ -0,0 +1,91 @@
+package dom
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Refresh is a declarative refresh: load URL after Delay, or the document
+// again when URL is "".
+type Refresh struct {
+	Delay time.Duration
+	URL   string // as written, to resolve against the document's URL
+}
+
+// maxRefreshDigits bounds the delay's digits, so it cannot overflow.
+const maxRefreshDigits = 9
+
+// ParseRefresh parses the value of a Refresh header or the content of a
+// <meta http-equiv="refresh">: "5", "0; url=next.html", "3,URL='/home'"
+// (HTML §4.2.5.3, "shared declarative refresh steps"). ok is false when
+// content is not a refresh.
+func ParseRefresh(content string) (refresh Refresh, ok bool) {
+	s := strings.TrimLeft(content, asciiWhitespace)
+	digits := len(s) - len(strings.TrimLeft(s, "0123456789"))
+	if digits == 0 && !strings.HasPrefix(s, ".") {
+		return Refresh{}, false
+	}
+	seconds := 0
+	if digits > 0 {
+		seconds, _ = strconv.Atoi(s[:min(digits, maxRefreshDigits)])
+	}
+	refresh.Delay = time.Duration(seconds) * time.Second
+	// A fraction is ignored
+	s = strings.TrimLeft(s[digits:], "0123456789.")
+
+	if s == "" {
+		return refresh, true
+	}
+	if !strings.ContainsAny(s[:1], ";,"+asciiWhitespace) {
+		return Refresh{}, false
+	}
+	s = strings.TrimLeft(s, asciiWhitespace)
+	if strings.HasPrefix(s, ";") || strings.HasPrefix(s, ",") {
+		s = strings.TrimLeft(s[1:], asciiWhitespace)
+	}
+	if s == "" {
+		return refresh, true
+	}
+
+	// An optional url= before the URL, which may be quoted
+	s = cutURLKey(s)
+	if s != "" && (s[0] == '\'' || s[0] == '"') {
+		quote := s[0]
+		s = s[1:]
+		if end := strings.IndexByte(s, quote); end >= 0 {
+			s = s[:end]
+		}
+	}
+	refresh.URL = strings.TrimSpace(s)
+	return refresh, true
+}
+
+// cutURLKey returns s past a leading "url =", in any case.
+func cutURLKey(s string) string {
+	if len(s) < 3 || !strings.EqualFold(s[:3], "url") {
+		return s
+	}
+	rest := strings.TrimLeft(s[3:], asciiWhitespace)
+	if !strings.HasPrefix(rest, "=") {
+		return s
+	}
+	return strings.TrimLeft(rest[1:], asciiWhitespace)
+}
+
+// asciiWhitespace is HTML's ASCII whitespace.
+const asciiWhitespace = " \t\n\f\r"
+
+// FindMetaRefresh returns the refresh the first <meta http-equiv="refresh">
+// with valid content declares.
+func FindMetaRefresh(node *Node) (Refresh, bool) {
+	for n := range Walk(node) {
+		if n.Type != Element || n.TagName != "meta" || !strings.EqualFold(strings.TrimSpace(n.Attributes["http-equiv"]), "refresh") {
+			continue
+		}
+		if refresh, ok := ParseRefresh(n.Attributes["content"]); ok {
+			return refresh, true
+		}
+	}
+	return Refresh{}, false
+}
@@ -0,0 +1,49 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRefresh(t *testing.T) {
+	tests := []struct {
+		content string
+		ok      bool
+		delay   time.Duration
+		url     string
+	}{
+		{"5", true, 5 * time.Second, ""},
+		{"0; url=next.html", true, 0, "next.html"},
+		{"0;URL='/home?a=1'", true, 0, "/home?a=1"},
+		{` 3 , url = "page.html" trailing`, true, 3 * time.Second, "page.html"},
+		{"2.5; /next", true, 2 * time.Second, "/next"},
+		{".5", true, 0, ""},
+		{"1 https://example.com/", true, time.Second, "https://example.com/"},
+		{"0; urlish", true, 0, "urlish"},
+		{"0;", true, 0, ""},
+		{"", false, 0, ""},
+		{"soon; url=x", false, 0, ""},
+		{"5x", false, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			refresh, ok := ParseRefresh(tt.content)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.delay, refresh.Delay)
+			assert.Equal(t, tt.url, refresh.URL)
+		})
+	}
+}
+
+func TestFindMetaRefresh(t *testing.T) {
+	doc := Parse(strings.NewReader(`<head><meta http-equiv="Refresh" content="soon"><meta http-equiv="refresh" content="0; url=/moved"><meta http-equiv="refresh" content="9"></head>`))
+	refresh, ok := FindMetaRefresh(doc)
+	assert.True(t, ok)
+	assert.Equal(t, Refresh{URL: "/moved"}, refresh, "the first valid one")
+
+	_, ok = FindMetaRefresh(Parse(strings.NewReader(`<meta name="refresh" content="0">`)))
+	assert.False(t, ok)
+}
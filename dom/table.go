@@ -0,0 +1,229 @@
+package dom
+
+// Table helpers shared by the HTMLTableElement bindings and table layout
+// (WHATWG 4.9). Functions that take an index follow the DOM methods they back:
+// -1 means "the end", and an out-of-range index fails (IndexSizeError in script).
+
+// isSection reports whether n is a thead, tbody or tfoot element.
+func isSection(n *Node) bool {
+	return n.Type == Element && (n.TagName == TagTHead || n.TagName == TagTBody || n.TagName == TagTFoot)
+}
+
+func isCell(n *Node) bool {
+	return n.Type == Element && (n.TagName == TagTD || n.TagName == TagTH)
+}
+
+// ChildElement returns n's first child element named tagName, or nil.
+func ChildElement(n *Node, tagName string) *Node {
+	for _, child := range n.Children {
+		if child.Type == Element && child.TagName == tagName {
+			return child
+		}
+	}
+	return nil
+}
+
+// childElements returns n's child elements accepted by match, in tree order.
+func childElements(n *Node, match func(*Node) bool) []*Node {
+	var out []*Node
+	for _, child := range n.Children {
+		if match(child) {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// Sections returns the table's thead, tbody and tfoot children in rendering
+// order: every thead, then every tbody in tree order, then every tfoot.
+func Sections(table *Node) []*Node {
+	var heads, bodies, foots []*Node
+	for _, child := range table.Children {
+		if child.Type != Element {
+			continue
+		}
+		switch child.TagName {
+		case TagTHead:
+			heads = append(heads, child)
+		case TagTBody:
+			bodies = append(bodies, child)
+		case TagTFoot:
+			foots = append(foots, child)
+		}
+	}
+	return append(append(heads, bodies...), foots...)
+}
+
+// SectionRows returns the tr children of a table section (or of the table itself).
+func SectionRows(section *Node) []*Node {
+	return childElements(section, func(n *Node) bool {
+		return n.Type == Element && n.TagName == TagTR
+	})
+}
+
+// RowsInOrder returns the rows of table.rows: thead rows first, then rows
+// that are direct children of the table or of a tbody in tree order, then
+// tfoot rows.
+func RowsInOrder(table *Node) []*Node {
+	var heads, bodies, foots []*Node
+	for _, child := range table.Children {
+		if child.Type != Element {
+			continue
+		}
+		switch child.TagName {
+		case TagTHead:
+			heads = append(heads, SectionRows(child)...)
+		case TagTBody:
+			bodies = append(bodies, SectionRows(child)...)
+		case TagTFoot:
+			foots = append(foots, SectionRows(child)...)
+		case TagTR:
+			bodies = append(bodies, child)
+		}
+	}
+	return append(append(heads, bodies...), foots...)
+}
+
+// OwnerTable returns the table whose rows collection contains row: its
+// parent table, or the table holding its parent section. Returns nil otherwise.
+func OwnerTable(row *Node) *Node {
+	p := row.Parent
+	if p != nil && isSection(p) {
+		p = p.Parent
+	}
+	if p == nil || p.Type != Element || p.TagName != TagTable {
+		return nil
+	}
+	return p
+}
+
+// RowIndex returns row's position in its table's rows, or -1.
+func RowIndex(row *Node) int {
+	table := OwnerTable(row)
+	if table == nil {
+		return -1
+	}
+	return indexOf(RowsInOrder(table), row)
+}
+
+// SectionRowIndex returns row's position among its parent's rows, or -1.
+func SectionRowIndex(row *Node) int {
+	if row.Parent == nil {
+		return -1
+	}
+	return indexOf(SectionRows(row.Parent), row)
+}
+
+func indexOf(nodes []*Node, n *Node) int {
+	for i, c := range nodes {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// InsertRow creates a tr at index in parent's rows and returns it, or nil if
+// index is out of range. parent is a table (rows in RowsInOrder order) or a
+// table section. Appending to a table without rows uses its last tbody,
+// creating one if there is none.
+func InsertRow(parent *Node, index int) *Node {
+	isTable := parent.TagName == TagTable
+	rows := SectionRows(parent)
+	if isTable {
+		rows = RowsInOrder(parent)
+	}
+	if index < -1 || index > len(rows) {
+		return nil
+	}
+
+	row := NewElement(TagTR, map[string]string{})
+	switch {
+	case index != -1 && index < len(rows):
+		target := rows[index]
+		target.Parent.InsertBefore(row, target)
+	case !isTable:
+		parent.AppendChild(row)
+	case len(rows) > 0:
+		rows[len(rows)-1].Parent.AppendChild(row)
+	default:
+		var tbody *Node
+		for _, child := range parent.Children {
+			if child.Type == Element && child.TagName == TagTBody {
+				tbody = child
+			}
+		}
+		if tbody == nil {
+			tbody = NewElement(TagTBody, map[string]string{})
+			parent.AppendChild(tbody)
+		}
+		tbody.AppendChild(row)
+	}
+	return row
+}
+
+// DeleteRow removes the row at index in parent's rows (as for InsertRow).
+// -1 removes the last row, if any. Reports false if index is out of range.
+func DeleteRow(parent *Node, index int) bool {
+	rows := SectionRows(parent)
+	if parent.TagName == TagTable {
+		rows = RowsInOrder(parent)
+	}
+	if index == -1 {
+		if len(rows) > 0 {
+			rows[len(rows)-1].Remove()
+		}
+		return true
+	}
+	if index < 0 || index >= len(rows) {
+		return false
+	}
+	rows[index].Remove()
+	return true
+}
+
+// Cells returns the td and th children of row.
+func Cells(row *Node) []*Node {
+	return childElements(row, isCell)
+}
+
+// CellIndex returns cell's position among its row's cells, or -1.
+func CellIndex(cell *Node) int {
+	if cell.Parent == nil || cell.Parent.TagName != TagTR {
+		return -1
+	}
+	return indexOf(Cells(cell.Parent), cell)
+}
+
+// InsertCell creates a td at index in row's cells and returns it, or nil if
+// index is out of range.
+func InsertCell(row *Node, index int) *Node {
+	cells := Cells(row)
+	if index < -1 || index > len(cells) {
+		return nil
+	}
+	cell := NewElement(TagTD, map[string]string{})
+	if index == -1 || index == len(cells) {
+		row.AppendChild(cell)
+	} else {
+		row.InsertBefore(cell, cells[index])
+	}
+	return cell
+}
+
+// DeleteCell removes the cell at index in row's cells; -1 removes the last
+// cell, if any. Reports false if index is out of range.
+func DeleteCell(row *Node, index int) bool {
+	cells := Cells(row)
+	if index == -1 {
+		if len(cells) > 0 {
+			cells[len(cells)-1].Remove()
+		}
+		return true
+	}
+	if index < 0 || index >= len(cells) {
+		return false
+	}
+	cells[index].Remove()
+	return true
+}
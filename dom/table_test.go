@@ -0,0 +1,149 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parseTable parses html and returns its first table element
+func parseTable(t *testing.T, html string) *Node {
+	t.Helper()
+	doc := Parse(strings.NewReader(html))
+	for n := range Elements(doc, TagTable) {
+		return n
+	}
+	t.Fatal("no table in " + html)
+	return nil
+}
+
+// rowIDs joins the id attributes of rows
+func rowIDs(rows []*Node) string {
+	var ids []string
+	for _, r := range rows {
+		ids = append(ids, r.Attributes["id"])
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestRowsInOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"implicit tbody", `<table><tr id="a"></tr><tr id="b"></tr></table>`, "a,b"},
+		{"head and foot move", `<table><tfoot><tr id="f"></tr></tfoot><tbody><tr id="b"></tr></tbody><thead><tr id="h"></tr></thead></table>`, "h,b,f"},
+		{"several bodies", `<table><tbody><tr id="b1"></tr></tbody><thead><tr id="h"></tr></thead><tbody><tr id="b2"></tr></tbody></table>`, "h,b1,b2"},
+		{"nested table rows excluded", `<table><tr id="a"><td><table><tr id="inner"></tr></table></td></tr></table>`, "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rowIDs(RowsInOrder(parseTable(t, tt.html))))
+		})
+	}
+}
+
+func TestSections(t *testing.T) {
+	table := parseTable(t, `<table><caption>c</caption><tfoot></tfoot><tbody id="1"></tbody><thead></thead><tbody id="2"></tbody></table>`)
+	var tags []string
+	for _, s := range Sections(table) {
+		tags = append(tags, s.TagName+s.Attributes["id"])
+	}
+	assert.Equal(t, []string{"thead", "tbody1", "tbody2", "tfoot"}, tags)
+	assert.Equal(t, "caption", ChildElement(table, TagCaption).TagName)
+	assert.Nil(t, ChildElement(table, TagColgroup))
+}
+
+func TestRowAndCellIndex(t *testing.T) {
+	table := parseTable(t, `<table><tbody><tr id="b"><td>1</td><th>2</th></tr></tbody><thead><tr id="h"></tr></thead></table>`)
+	rows := RowsInOrder(table)
+	head, body := rows[0], rows[1]
+
+	assert.Equal(t, 0, RowIndex(head))
+	assert.Equal(t, 1, RowIndex(body))
+	assert.Equal(t, 0, SectionRowIndex(body))
+	assert.Same(t, table, OwnerTable(body))
+	assert.Equal(t, -1, RowIndex(NewElement(TagTR, nil)))
+
+	cells := Cells(body)
+	assert.Len(t, cells, 2)
+	assert.Equal(t, 1, CellIndex(cells[1]))
+	assert.Equal(t, -1, CellIndex(NewElement(TagTD, nil)))
+}
+
+func TestInsertRow(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		index    int
+		expected string // row ids after insertion, new row as "new"; "" when rejected
+	}{
+		{"append to last row's section", `<table><tbody><tr id="a"></tr></tbody><tfoot><tr id="f"></tr></tfoot></table>`, -1, "a,f,new"},
+		{"insert before index", `<table><thead><tr id="h"></tr></thead><tbody><tr id="a"></tr></tbody></table>`, 1, "h,new,a"},
+		{"append at length", `<table><tr id="a"></tr></table>`, 1, "a,new"},
+		{"empty table gets tbody", `<table></table>`, -1, "new"},
+		{"out of range", `<table><tr id="a"></tr></table>`, 3, ""},
+		{"below -1", `<table></table>`, -2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := parseTable(t, tt.html)
+			row := InsertRow(table, tt.index)
+			if tt.expected == "" {
+				assert.Nil(t, row)
+				return
+			}
+			row.Attributes["id"] = "new"
+			assert.Equal(t, tt.expected, rowIDs(RowsInOrder(table)))
+			assert.Same(t, table, OwnerTable(row))
+		})
+	}
+
+	t.Run("empty table reuses last tbody", func(t *testing.T) {
+		table := parseTable(t, `<table><tbody id="1"></tbody><tbody id="2"></tbody></table>`)
+		row := InsertRow(table, 0)
+		assert.Equal(t, "2", row.Parent.Attributes["id"])
+		assert.Len(t, Sections(table), 2)
+	})
+
+	t.Run("section", func(t *testing.T) {
+		table := parseTable(t, `<table><tbody><tr id="a"></tr><tr id="b"></tr></tbody></table>`)
+		tbody := Sections(table)[0]
+		InsertRow(tbody, 1).Attributes["id"] = "new"
+		assert.Equal(t, "a,new,b", rowIDs(SectionRows(tbody)))
+		assert.Nil(t, InsertRow(tbody, 4))
+	})
+}
+
+func TestDeleteRow(t *testing.T) {
+	table := parseTable(t, `<table><tbody><tr id="a"></tr></tbody><thead><tr id="h"></tr></thead><tfoot><tr id="f"></tr></tfoot></table>`)
+
+	assert.True(t, DeleteRow(table, 1))
+	assert.Equal(t, "h,f", rowIDs(RowsInOrder(table)))
+	assert.True(t, DeleteRow(table, -1))
+	assert.Equal(t, "h", rowIDs(RowsInOrder(table)))
+	assert.False(t, DeleteRow(table, 1))
+	assert.False(t, DeleteRow(table, -2))
+	assert.True(t, DeleteRow(table, 0))
+	assert.True(t, DeleteRow(table, -1), "-1 on an empty table is a no-op")
+}
+
+func TestInsertDeleteCell(t *testing.T) {
+	table := parseTable(t, `<table><tr><td id="a"></td><th id="b"></th></tr></table>`)
+	row := RowsInOrder(table)[0]
+
+	InsertCell(row, 1).Attributes["id"] = "new"
+	assert.Equal(t, "a,new,b", rowIDs(Cells(row)))
+	InsertCell(row, -1).Attributes["id"] = "end"
+	assert.Equal(t, "a,new,b,end", rowIDs(Cells(row)))
+	assert.Nil(t, InsertCell(row, 9))
+
+	assert.True(t, DeleteCell(row, 0))
+	assert.True(t, DeleteCell(row, -1))
+	assert.Equal(t, "new,b", rowIDs(Cells(row)))
+	assert.False(t, DeleteCell(row, 2))
+}
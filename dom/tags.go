@@ -43,7 +43,13 @@ const (
 	TagMenu = "menu"
 
 	// Media
-	TagImg = "img"
+	TagImg    = "img"
+	TagVideo  = "video"
+	TagAudio  = "audio"
+	TagEmbed  = "embed"
+	TagObject = "object"
+	TagSvg    = "svg"
+	TagCanvas = "canvas"
 
 	// Structure
 	TagHeader     = "header"
@@ -88,6 +94,10 @@ const (
 
 	TagSearch = "search"
 
+	TagDetails = "details"
+	TagSummary = "summary"
+	TagDialog  = "dialog"
+
 	TagData = "data"
 	TagTime = "time"
 
@@ -35,6 +35,24 @@ var skipElements = map[string]bool{
 	"meta": true, "link": true, "noscript": true,
 }
 
+// CollapseWhitespace strips leading and trailing document whitespace from s
+// and collapses the runs inside it to single spaces, the way titles and
+// option labels read. Non-breaking spaces are kept.
+func CollapseWhitespace(s string) string {
+	return strings.Join(strings.FieldsFunc(s, IsCollapsibleSpace), " ")
+}
+
+// IsCollapsibleSpace reports whether r is document whitespace, which CSS
+// collapses unless white-space preserves it: space, tab, line feed,
+// carriage return and form feed. Unlike unicode.IsSpace it excludes U+00A0.
+func IsCollapsibleSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\f':
+		return true
+	}
+	return false
+}
+
 func (n *Node) InnerText() string {
 	var sb strings.Builder
 	n.innerTextRecursive(&sb, false)
@@ -0,0 +1,342 @@
+package dom
+
+import "iter"
+
+// WhatToShow bits select the node types a TreeWalker visits (DOM §6.3, NodeFilter).
+const (
+	ShowAll      uint32 = 0xFFFFFFFF
+	ShowElement  uint32 = 0x1
+	ShowText     uint32 = 0x4
+	ShowDocument uint32 = 0x100
+)
+
+// FilterResult is a TreeWalker filter's verdict on a node.
+type FilterResult int
+
+const (
+	FilterAccept FilterResult = 1 // visit the node
+	FilterReject FilterResult = 2 // skip the node and its subtree
+	FilterSkip   FilterResult = 3 // skip the node but visit its children
+)
+
+// Walk yields root and every node below it in tree order (depth-first, pre-order).
+func Walk(root *Node) iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		if root != nil {
+			walk(root, yield)
+		}
+	}
+}
+
+func walk(n *Node, yield func(*Node) bool) bool {
+	if !yield(n) {
+		return false
+	}
+	for _, child := range n.Children {
+		if !walk(child, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Descendants yields every node below n in tree order, excluding n itself.
+func (n *Node) Descendants() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		for _, child := range n.Children {
+			if !walk(child, yield) {
+				return
+			}
+		}
+	}
+}
+
+// Elements yields the element descendants of root in tree order, or only
+// those named tagName when it is non-empty.
+func Elements(root *Node, tagName string) iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		if root == nil {
+			return
+		}
+		for n := range root.Descendants() {
+			if n.Type == Element && (tagName == "" || n.TagName == tagName) && !yield(n) {
+				return
+			}
+		}
+	}
+}
+
+// Ancestors yields n's parent, grandparent and so on up to the root.
+func (n *Node) Ancestors() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		for p := n.Parent; p != nil; p = p.Parent {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// ClosestAncestor returns the nearest ancestor element named tagName, or nil.
+func (n *Node) ClosestAncestor(tagName string) *Node {
+	for p := range n.Ancestors() {
+		if p.Type == Element && p.TagName == tagName {
+			return p
+		}
+	}
+	return nil
+}
+
+// NextSibling returns the node after n in its parent's children, or nil.
+func (n *Node) NextSibling() *Node {
+	if i := n.index(); i >= 0 && i+1 < len(n.Parent.Children) {
+		return n.Parent.Children[i+1]
+	}
+	return nil
+}
+
+// PreviousSibling returns the node before n in its parent's children, or nil.
+func (n *Node) PreviousSibling() *Node {
+	if i := n.index(); i > 0 {
+		return n.Parent.Children[i-1]
+	}
+	return nil
+}
+
+// index returns n's position among its parent's children, or -1 if detached.
+func (n *Node) index() int {
+	if n.Parent == nil {
+		return -1
+	}
+	for i, c := range n.Parent.Children {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+func (n *Node) firstChild() *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
+
+func (n *Node) lastChild() *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[len(n.Children)-1]
+}
+
+// TreeWalker moves a cursor over the filtered view of the subtree at Root
+// (DOM §6.2). Nodes whose type is not in WhatToShow are skipped but their
+// children are still visited, as if Filter had returned FilterSkip.
+type TreeWalker struct {
+	Root        *Node
+	WhatToShow  uint32
+	Filter      func(*Node) FilterResult // nil accepts every shown node
+	CurrentNode *Node
+}
+
+// NewTreeWalker returns a walker positioned at root.
+func NewTreeWalker(root *Node, whatToShow uint32, filter func(*Node) FilterResult) *TreeWalker {
+	return &TreeWalker{
+		Root:        root,
+		WhatToShow:  whatToShow,
+		Filter:      filter,
+		CurrentNode: root,
+	}
+}
+
+func (w *TreeWalker) accept(n *Node) FilterResult {
+	var bit uint32
+	switch n.Type {
+	case Element:
+		bit = ShowElement
+	case Text:
+		bit = ShowText
+	case Document:
+		bit = ShowDocument
+	}
+	if w.WhatToShow&bit == 0 {
+		return FilterSkip
+	}
+	if w.Filter == nil {
+		return FilterAccept
+	}
+	return w.Filter(n)
+}
+
+// All yields every accepted node after CurrentNode in tree order, advancing
+// CurrentNode as it goes.
+func (w *TreeWalker) All() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		for n := w.NextNode(); n != nil; n = w.NextNode() {
+			if !yield(n) {
+				return
+			}
+		}
+	}
+}
+
+// ParentNode moves to the nearest accepted ancestor of CurrentNode within Root.
+func (w *TreeWalker) ParentNode() *Node {
+	for n := w.CurrentNode; n != nil && n != w.Root; {
+		n = n.Parent
+		if n != nil && w.accept(n) == FilterAccept {
+			w.CurrentNode = n
+			return n
+		}
+	}
+	return nil
+}
+
+// FirstChild moves to CurrentNode's first accepted child.
+func (w *TreeWalker) FirstChild() *Node {
+	return w.traverseChildren(true)
+}
+
+// LastChild moves to CurrentNode's last accepted child.
+func (w *TreeWalker) LastChild() *Node {
+	return w.traverseChildren(false)
+}
+
+// NextSibling moves to CurrentNode's next accepted sibling.
+func (w *TreeWalker) NextSibling() *Node {
+	return w.traverseSiblings(true)
+}
+
+// PreviousSibling moves to CurrentNode's previous accepted sibling.
+func (w *TreeWalker) PreviousSibling() *Node {
+	return w.traverseSiblings(false)
+}
+
+func (w *TreeWalker) traverseChildren(first bool) *Node {
+	child, next := (*Node).lastChild, (*Node).PreviousSibling
+	if first {
+		child, next = (*Node).firstChild, (*Node).NextSibling
+	}
+
+	n := child(w.CurrentNode)
+	for n != nil {
+		switch w.accept(n) {
+		case FilterAccept:
+			w.CurrentNode = n
+			return n
+		case FilterSkip:
+			if c := child(n); c != nil {
+				n = c
+				continue
+			}
+		}
+		for n != nil {
+			if sibling := next(n); sibling != nil {
+				n = sibling
+				break
+			}
+			parent := n.Parent
+			if parent == nil || parent == w.Root || parent == w.CurrentNode {
+				return nil
+			}
+			n = parent
+		}
+	}
+	return nil
+}
+
+func (w *TreeWalker) traverseSiblings(forward bool) *Node {
+	child, next := (*Node).lastChild, (*Node).PreviousSibling
+	if forward {
+		child, next = (*Node).firstChild, (*Node).NextSibling
+	}
+
+	n := w.CurrentNode
+	if n == w.Root {
+		return nil
+	}
+	for {
+		sibling := next(n)
+		for sibling != nil {
+			n = sibling
+			result := w.accept(n)
+			if result == FilterAccept {
+				w.CurrentNode = n
+				return n
+			}
+			sibling = child(n)
+			if result == FilterReject || sibling == nil {
+				sibling = next(n)
+			}
+		}
+		n = n.Parent
+		if n == nil || n == w.Root || w.accept(n) == FilterAccept {
+			return nil
+		}
+	}
+}
+
+// NextNode moves to the accepted node following CurrentNode in tree order.
+func (w *TreeWalker) NextNode() *Node {
+	n := w.CurrentNode
+	result := FilterAccept
+	for {
+		for result != FilterReject && len(n.Children) > 0 {
+			n = n.Children[0]
+			result = w.accept(n)
+			if result == FilterAccept {
+				w.CurrentNode = n
+				return n
+			}
+		}
+		var sibling *Node
+		for temp := n; temp != nil; temp = temp.Parent {
+			if temp == w.Root {
+				return nil
+			}
+			if sibling = temp.NextSibling(); sibling != nil {
+				break
+			}
+		}
+		if sibling == nil {
+			return nil
+		}
+		n = sibling
+		result = w.accept(n)
+		if result == FilterAccept {
+			w.CurrentNode = n
+			return n
+		}
+	}
+}
+
+// PreviousNode moves to the accepted node preceding CurrentNode in tree order.
+func (w *TreeWalker) PreviousNode() *Node {
+	n := w.CurrentNode
+	for n != w.Root {
+		sibling := n.PreviousSibling()
+		for sibling != nil {
+			n = sibling
+			result := w.accept(n)
+			for result != FilterReject && len(n.Children) > 0 {
+				n = n.lastChild()
+				result = w.accept(n)
+			}
+			if result == FilterAccept {
+				w.CurrentNode = n
+				return n
+			}
+			sibling = n.PreviousSibling()
+		}
+		if n == w.Root || n.Parent == nil {
+			return nil
+		}
+		n = n.Parent
+		if w.accept(n) == FilterAccept {
+			w.CurrentNode = n
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package dom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// label names a node as its tag or its quoted text
+func label(n *Node) string {
+	switch n.Type {
+	case Text:
+		return `"` + n.Text + `"`
+	case Document:
+		return "#document"
+	}
+	return n.TagName
+}
+
+func labels(nodes []*Node) string {
+	var parts []string
+	for _, n := range nodes {
+		parts = append(parts, label(n))
+	}
+	return strings.Join(parts, " ")
+}
+
+// walkTree builds <div><p>a<b>b</b></p><span>c</span><p>d</p></div>
+func walkTree() *Node {
+	div := NewElement("div", nil)
+	p1 := NewElement("p", nil)
+	p1.AppendChild(NewText("a"))
+	b := NewElement("b", nil)
+	b.AppendChild(NewText("b"))
+	p1.AppendChild(b)
+	span := NewElement("span", nil)
+	span.AppendChild(NewText("c"))
+	p2 := NewElement("p", nil)
+	p2.AppendChild(NewText("d"))
+	div.AppendChild(p1)
+	div.AppendChild(span)
+	div.AppendChild(p2)
+	return div
+}
+
+func TestWalkIterators(t *testing.T) {
+	root := walkTree()
+
+	var all []*Node
+	for n := range Walk(root) {
+		all = append(all, n)
+	}
+	assert.Equal(t, `div p "a" b "b" span "c" p "d"`, labels(all))
+
+	var below []*Node
+	for n := range root.Descendants() {
+		below = append(below, n)
+	}
+	assert.Equal(t, `p "a" b "b" span "c" p "d"`, labels(below))
+
+	var ps []*Node
+	for n := range Elements(root, "p") {
+		ps = append(ps, n)
+	}
+	assert.Equal(t, "p p", labels(ps))
+
+	var first *Node
+	for n := range Elements(root, "") {
+		first = n
+		break
+	}
+	assert.Same(t, root.Children[0], first, "stops when the loop breaks")
+
+	text := root.Children[0].Children[1].Children[0]
+	var up []*Node
+	for n := range text.Ancestors() {
+		up = append(up, n)
+	}
+	assert.Equal(t, "b p div", labels(up))
+	assert.Same(t, root, text.ClosestAncestor("div"))
+	assert.Nil(t, text.ClosestAncestor("table"))
+}
+
+func TestSiblings(t *testing.T) {
+	root := walkTree()
+	p1, span, p2 := root.Children[0], root.Children[1], root.Children[2]
+
+	assert.Same(t, span, p1.NextSibling())
+	assert.Same(t, p2, span.NextSibling())
+	assert.Nil(t, p2.NextSibling())
+	assert.Same(t, p1, span.PreviousSibling())
+	assert.Nil(t, p1.PreviousSibling())
+	assert.Nil(t, root.NextSibling(), "detached")
+}
+
+func TestTreeWalker(t *testing.T) {
+	tests := []struct {
+		name       string
+		whatToShow uint32
+		filter     func(*Node) FilterResult
+		forward    string
+		backward   string
+	}{
+		{
+			name:       "all nodes",
+			whatToShow: ShowAll,
+			forward:    `p "a" b "b" span "c" p "d"`,
+			backward:   `p "c" span "b" b "a" p div`,
+		},
+		{
+			name:       "elements only",
+			whatToShow: ShowElement,
+			forward:    "p b span p",
+			backward:   "p span b p div",
+		},
+		{
+			name:       "text only",
+			whatToShow: ShowText,
+			forward:    `"a" "b" "c" "d"`,
+			backward:   `"c" "b" "a"`,
+		},
+		{
+			name:       "reject skips subtree",
+			whatToShow: ShowAll,
+			filter: func(n *Node) FilterResult {
+				if n.TagName == "b" {
+					return FilterReject
+				}
+				return FilterAccept
+			},
+			forward:  `p "a" span "c" p "d"`,
+			backward: `p "c" span "a" p div`,
+		},
+		{
+			name:       "skip keeps children",
+			whatToShow: ShowAll,
+			filter: func(n *Node) FilterResult {
+				if n.TagName == "p" {
+					return FilterSkip
+				}
+				return FilterAccept
+			},
+			forward:  `"a" b "b" span "c" "d"`,
+			backward: `"c" span "b" b "a" div`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := walkTree()
+			w := NewTreeWalker(root, tt.whatToShow, tt.filter)
+
+			var forward []*Node
+			for n := range w.All() {
+				forward = append(forward, n)
+			}
+			assert.Equal(t, tt.forward, labels(forward))
+
+			var backward []*Node
+			w.CurrentNode = root.Children[2].Children[0]
+			for n := w.PreviousNode(); n != nil; n = w.PreviousNode() {
+				backward = append(backward, n)
+			}
+			assert.Equal(t, tt.backward, labels(backward))
+		})
+	}
+}
+
+func TestTreeWalkerNavigation(t *testing.T) {
+	root := walkTree()
+	p1, span, p2 := root.Children[0], root.Children[1], root.Children[2]
+	w := NewTreeWalker(root, ShowElement, nil)
+
+	assert.Same(t, p1, w.FirstChild())
+	assert.Same(t, p1.Children[1], w.FirstChild(), "text children are not shown")
+	assert.Nil(t, w.FirstChild())
+	assert.Same(t, p1, w.ParentNode())
+	assert.Same(t, span, w.NextSibling())
+	assert.Same(t, p2, w.NextSibling())
+	assert.Nil(t, w.NextSibling())
+	assert.Same(t, span, w.PreviousSibling())
+	assert.Same(t, root, w.ParentNode())
+	assert.Nil(t, w.ParentNode(), "never leaves the root")
+	assert.Nil(t, w.NextSibling())
+	assert.Same(t, p2, w.LastChild())
+
+	// skipped elements are transparent to sibling moves
+	w = NewTreeWalker(root, ShowElement, func(n *Node) FilterResult {
+		if n.TagName == "p" {
+			return FilterSkip
+		}
+		return FilterAccept
+	})
+	assert.Same(t, p1.Children[1], w.FirstChild())
+	assert.Same(t, span, w.NextSibling())
+	assert.Nil(t, w.NextSibling())
+}
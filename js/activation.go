@@ -0,0 +1,143 @@
+package js
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	// transientActivationDuration is how long a click or key press lets the
+	// page open a popup (HTML §6.4.1 leaves it to the browser; Chrome uses 5s).
+	transientActivationDuration = 5 * time.Second
+	// maxDialogsWithoutActivation is how many alert/confirm/prompt dialogs a
+	// page may show between user activations before further ones are dropped.
+	maxDialogsWithoutActivation = 3
+)
+
+// userActivation tracks whether the user recently interacted with the page
+// (HTML §6.4). Trusted clicks and key presses grant transient activation,
+// which window.open consumes, so one click opens at most one popup.
+type userActivation struct {
+	mu      sync.Mutex
+	last    time.Time // zero when there is no unconsumed activation
+	dialogs int       // dialogs shown since the last activation
+	now     func() time.Time
+}
+
+func newUserActivation() *userActivation {
+	return &userActivation{now: time.Now}
+}
+
+// activate records a trusted user interaction.
+func (a *userActivation) activate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.last = a.now()
+	a.dialogs = 0
+}
+
+// isActive reports whether the page has transient activation.
+func (a *userActivation) isActive() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.activeLocked()
+}
+
+// consume reports whether the page had transient activation and uses it up.
+func (a *userActivation) consume() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.activeLocked() {
+		return false
+	}
+	a.last = time.Time{}
+	return true
+}
+
+// allowDialog reports whether a dialog may be shown: always while the page
+// has transient activation, otherwise only the first few since the last one.
+func (a *userActivation) allowDialog() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.activeLocked() {
+		return true
+	}
+	a.dialogs++
+	return a.dialogs <= maxDialogsWithoutActivation
+}
+
+func (a *userActivation) activeLocked() bool {
+	return !a.last.IsZero() && a.now().Sub(a.last) < transientActivationDuration
+}
+
+// NotifyUserActivation records a trusted key press or other interaction that
+// the shell handled without dispatching a click.
+func (rt *JSRuntime) NotifyUserActivation() {
+	rt.Events.activation.activate()
+}
+
+// SetOpenWindowHandler sets the callback used by window.open.
+func (rt *JSRuntime) SetOpenWindowHandler(handler func(url string)) {
+	rt.onOpenWindow = handler
+}
+
+// SetPopupBlockedHandler sets the callback told about blocked popups. The
+// URL is empty when a dialog (alert, confirm, prompt) was suppressed.
+func (rt *JSRuntime) SetPopupBlockedHandler(handler func(url string)) {
+	rt.onPopupBlocked = handler
+}
+
+// SetPopupPermission sets the per-site override that lets a page open
+// popups without user activation.
+func (rt *JSRuntime) SetPopupPermission(allowed func(pageURL string) bool) {
+	rt.popupsAllowed = allowed
+}
+
+// windowOpen implements window.open(url). Popups need transient activation
+// unless the site is allowed; blocked calls return null like other browsers.
+func (rt *JSRuntime) windowOpen(call goja.FunctionCall) goja.Value {
+	target := "about:blank"
+	if len(call.Arguments) > 0 && !goja.IsUndefined(call.Arguments[0]) && call.Arguments[0].String() != "" {
+		target = rt.resolveScriptURL(call.Arguments[0].String())
+	}
+
+	allowed := rt.popupsAllowed != nil && rt.popupsAllowed(rt.currentURL)
+	if !rt.Events.activation.consume() && !allowed {
+		if rt.onPopupBlocked != nil {
+			rt.onPopupBlocked(target)
+		}
+		return goja.Null()
+	}
+	if rt.onOpenWindow != nil {
+		rt.onOpenWindow(target)
+	}
+	return rt.vm.Get("window")
+}
+
+// allowDialog reports whether alert/confirm/prompt may be shown, notifying
+// the shell when a dialog storm is being suppressed.
+func (rt *JSRuntime) allowDialog() bool {
+	if rt.Events.activation.allowDialog() {
+		return true
+	}
+	if rt.onPopupBlocked != nil {
+		rt.onPopupBlocked("")
+	}
+	return false
+}
+
+// resolveScriptURL resolves a URL passed to a script API against the page.
+func (rt *JSRuntime) resolveScriptURL(ref string) string {
+	base, err := url.Parse(rt.currentURL)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(refURL).String()
+}
@@ -0,0 +1,43 @@
+package js
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserActivation(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := newUserActivation()
+	a.now = func() time.Time { return clock }
+
+	assert.False(t, a.isActive(), "no activation before any interaction")
+	assert.False(t, a.consume())
+
+	a.activate()
+	clock = clock.Add(2 * time.Second)
+	assert.True(t, a.isActive())
+	assert.True(t, a.consume(), "one popup per activation")
+	assert.False(t, a.consume())
+
+	a.activate()
+	clock = clock.Add(transientActivationDuration)
+	assert.False(t, a.consume(), "activation expires")
+}
+
+func TestUserActivationDialogs(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := newUserActivation()
+	a.now = func() time.Time { return clock }
+
+	for i := 0; i < maxDialogsWithoutActivation; i++ {
+		assert.True(t, a.allowDialog(), "dialog %d", i+1)
+	}
+	assert.False(t, a.allowDialog(), "storm is blocked")
+
+	a.activate()
+	assert.True(t, a.allowDialog(), "activation allows dialogs again")
+	clock = clock.Add(time.Minute)
+	assert.True(t, a.allowDialog(), "and resets the count")
+}
@@ -0,0 +1,110 @@
+package js
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// frameCallback is a callback queued by requestAnimationFrame.
+type frameCallback struct {
+	id       int64
+	callback goja.Callable
+}
+
+// animationFrames is the page's list of animation frame callbacks
+// (HTML §8.10). Callbacks queued while a frame runs wait for the next one.
+type animationFrames struct {
+	mu        sync.Mutex
+	nextID    int64
+	callbacks []frameCallback
+}
+
+// add queues callback and returns its handle.
+func (f *animationFrames) add(callback goja.Callable) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	f.callbacks = append(f.callbacks, frameCallback{id: f.nextID, callback: callback})
+	return f.nextID
+}
+
+// cancel removes the callback with the given handle, if still queued.
+func (f *animationFrames) cancel(id int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, cb := range f.callbacks {
+		if cb.id == id {
+			f.callbacks = append(f.callbacks[:i], f.callbacks[i+1:]...)
+			return
+		}
+	}
+}
+
+// take removes and returns every queued callback.
+func (f *animationFrames) take() []frameCallback {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	callbacks := f.callbacks
+	f.callbacks = nil
+	return callbacks
+}
+
+// pending reports whether any callback waits for a frame.
+func (f *animationFrames) pending() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.callbacks) > 0
+}
+
+// SetFrameRequestHandler sets the callback that asks the host for an
+// animation frame when the page calls requestAnimationFrame.
+func (rt *JSRuntime) SetFrameRequestHandler(handler func()) {
+	rt.onFrameRequest = handler
+}
+
+// HasPendingAnimationFrames reports whether the page is waiting for a frame.
+func (rt *JSRuntime) HasPendingAnimationFrames() bool {
+	return rt.frames.pending()
+}
+
+// RunAnimationFrames runs the callbacks queued before this frame, passing
+// the frame time in milliseconds since the page loaded, then reflows. It
+// reports whether any callback ran.
+func (rt *JSRuntime) RunAnimationFrames(frameTime time.Time) bool {
+	callbacks := rt.frames.take()
+	if len(callbacks) == 0 {
+		return false
+	}
+	rt.vmMu.Lock()
+	timestamp := rt.vm.ToValue(float64(frameTime.Sub(rt.timeOrigin)) / float64(time.Millisecond))
+	for _, cb := range callbacks {
+		if _, err := cb.callback(goja.Undefined(), timestamp); err != nil {
+			fmt.Println("requestAnimationFrame callback error:", err)
+		}
+	}
+	rt.vmMu.Unlock()
+
+	rt.reflow(nil)
+	return true
+}
+
+func (rt *JSRuntime) requestAnimationFrame(call goja.FunctionCall) goja.Value {
+	callback, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(rt.vm.NewTypeError("requestAnimationFrame: argument is not a function"))
+	}
+	id := rt.frames.add(callback)
+	// Under virtual time frames only run from AdvanceTime
+	if _, virtual := rt.clock.(*virtualClock); !virtual && rt.onFrameRequest != nil {
+		rt.onFrameRequest()
+	}
+	return rt.vm.ToValue(id)
+}
+
+func (rt *JSRuntime) cancelAnimationFrame(call goja.FunctionCall) goja.Value {
+	rt.frames.cancel(call.Argument(0).ToInteger())
+	return goja.Undefined()
+}
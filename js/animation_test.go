@@ -0,0 +1,32 @@
+package js
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnimationFrames(t *testing.T) {
+	var f animationFrames
+	assert.False(t, f.pending())
+
+	first := f.add(nil)
+	second := f.add(nil)
+	third := f.add(nil)
+	assert.NotEqual(t, first, second)
+	assert.True(t, f.pending())
+
+	f.cancel(second)
+	f.cancel(second) // cancelling twice is harmless
+
+	taken := f.take()
+	assert.Equal(t, 2, len(taken))
+	assert.Equal(t, first, taken[0].id)
+	assert.Equal(t, third, taken[1].id)
+	assert.False(t, f.pending(), "callbacks run once")
+
+	// Callbacks queued while a frame runs wait for the next one
+	next := f.add(nil)
+	assert.True(t, next > third, "handles are not reused")
+	assert.Equal(t, 1, len(f.take()))
+}
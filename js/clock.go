@@ -0,0 +1,183 @@
+package js
+
+import (
+	"sync"
+	"time"
+)
+
+// VirtualFrameInterval is the time between animation frames under virtual
+// time, matching a 60Hz display.
+var VirtualFrameInterval = time.Second / 60
+
+// clock tells the time and runs timer callbacks. The runtime uses the wall
+// clock unless UseVirtualTime swaps in a virtualClock.
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) stopper
+}
+
+// stopper cancels a scheduled callback, reporting whether it was pending.
+type stopper interface {
+	Stop() bool
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
+
+func (wallClock) AfterFunc(d time.Duration, f func()) stopper {
+	return time.AfterFunc(d, f)
+}
+
+// virtualClock is a clock that stands still until advanced. Timers due at
+// the same instant fire in the order they were scheduled.
+type virtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int64
+	timers []*virtualTimer
+}
+
+type virtualTimer struct {
+	clock *virtualClock
+	due   time.Time
+	seq   int64
+	f     func()
+}
+
+func newVirtualClock(start time.Time) *virtualClock {
+	return &virtualClock{now: start}
+}
+
+func (c *virtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *virtualClock) AfterFunc(d time.Duration, f func()) stopper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &virtualTimer{clock: c, due: c.now.Add(max(d, 0)), seq: c.seq, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *virtualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+// remove drops t from the pending timers. The caller holds c.mu.
+func (c *virtualClock) remove(t *virtualTimer) bool {
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// earliest returns the timer that fires next, or nil when none is pending.
+// The caller holds c.mu.
+func (c *virtualClock) earliest() *virtualTimer {
+	var first *virtualTimer
+	for _, t := range c.timers {
+		if first == nil || t.due.Before(first.due) || (t.due.Equal(first.due) && t.seq < first.seq) {
+			first = t
+		}
+	}
+	return first
+}
+
+// nextDue returns when the next timer fires.
+func (c *virtualClock) nextDue() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t := c.earliest(); t != nil {
+		return t.due, true
+	}
+	return time.Time{}, false
+}
+
+// fireNext moves the clock to the next timer due no later than limit and
+// runs it, reporting whether there was one.
+func (c *virtualClock) fireNext(limit time.Time) bool {
+	c.mu.Lock()
+	t := c.earliest()
+	if t == nil || t.due.After(limit) {
+		c.mu.Unlock()
+		return false
+	}
+	c.remove(t)
+	c.now = t.due
+	c.mu.Unlock()
+
+	t.f()
+	return true
+}
+
+// set moves the clock to at, never backwards.
+func (c *virtualClock) set(at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at.After(c.now) {
+		c.now = at
+	}
+}
+
+// UseVirtualTime stops the page's clock: from now on Date, setTimeout and
+// requestAnimationFrame only see time pass through AdvanceTime, making
+// timer-driven and animated pages deterministic to test. Call it before
+// running the page's scripts; timers already scheduled keep wall time.
+func (rt *JSRuntime) UseVirtualTime() {
+	vc := newVirtualClock(rt.timeOrigin)
+	rt.clock = vc
+	rt.lastFrame = rt.timeOrigin
+	rt.vm.SetTimeSource(vc.Now)
+}
+
+// AdvanceTime moves virtual time forward by d. Timers fire in due order and
+// animation frames run at each VirtualFrameInterval boundary on the way,
+// each at its own instant, so callbacks that schedule more work within d
+// see it run too. It must not be called from page script, and does nothing
+// unless UseVirtualTime was called.
+func (rt *JSRuntime) AdvanceTime(d time.Duration) {
+	vc, ok := rt.clock.(*virtualClock)
+	if !ok {
+		return
+	}
+	end := vc.Now().Add(d)
+	for {
+		timerDue, hasTimer := vc.nextDue()
+		frameDue, hasFrame := rt.nextVirtualFrame(vc.Now())
+		if hasFrame && !frameDue.After(end) && (!hasTimer || frameDue.Before(timerDue)) {
+			vc.set(frameDue)
+			rt.lastFrame = frameDue
+			rt.RunAnimationFrames(frameDue)
+			continue
+		}
+		if !vc.fireNext(end) {
+			break
+		}
+	}
+	vc.set(end)
+}
+
+// nextVirtualFrame returns the first frame boundary at or after now that
+// has not run yet, when animation frame callbacks are waiting.
+func (rt *JSRuntime) nextVirtualFrame(now time.Time) (time.Time, bool) {
+	if !rt.frames.pending() || VirtualFrameInterval <= 0 {
+		return time.Time{}, false
+	}
+	elapsed := now.Sub(rt.timeOrigin)
+	frames := (elapsed + VirtualFrameInterval - 1) / VirtualFrameInterval
+	next := rt.timeOrigin.Add(frames * VirtualFrameInterval)
+	if !next.After(rt.lastFrame) {
+		next = rt.lastFrame.Add(VirtualFrameInterval)
+	}
+	return next, true
+}
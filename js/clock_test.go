@@ -0,0 +1,70 @@
+package js
+
+import (
+	"browser/dom"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtualClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := newVirtualClock(start)
+	var fired []string
+	record := func(name string) func() { return func() { fired = append(fired, name) } }
+
+	c.AfterFunc(20*time.Millisecond, record("late"))
+	c.AfterFunc(10*time.Millisecond, record("early"))
+	c.AfterFunc(10*time.Millisecond, record("early, scheduled second"))
+	cancelled := c.AfterFunc(5*time.Millisecond, record("cancelled"))
+	assert.True(t, cancelled.Stop())
+	assert.False(t, cancelled.Stop(), "already stopped")
+
+	assert.Equal(t, start, c.Now(), "time stands still")
+	for c.fireNext(start.Add(15 * time.Millisecond)) {
+	}
+	assert.Equal(t, []string{"early", "early, scheduled second"}, fired)
+	assert.Equal(t, start.Add(10*time.Millisecond), c.Now())
+
+	due, ok := c.nextDue()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(20*time.Millisecond), due)
+
+	c.set(start) // never backwards
+	assert.Equal(t, start.Add(10*time.Millisecond), c.Now())
+}
+
+func TestAdvanceTime(t *testing.T) {
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, nil)
+	rt.UseVirtualTime()
+	requested := false
+	rt.SetFrameRequestHandler(func() { requested = true })
+
+	assert.NoError(t, rt.Execute(`
+		var log = [];
+		var start = Date.now();
+		setTimeout(function() {
+			log.push("timeout " + (Date.now() - start));
+			setTimeout(function() { log.push("nested " + (Date.now() - start)); }, 50);
+		}, 100);
+		var cancelled = setTimeout(function() { log.push("cancelled"); }, 10);
+		clearTimeout(cancelled);
+		requestAnimationFrame(function(ts) { log.push("frame " + Math.round(ts)); });
+	`))
+	assert.False(t, requested, "frames wait for AdvanceTime")
+
+	log := func() string { return rt.vm.Get("log").String() }
+	assert.Equal(t, "", log())
+
+	rt.AdvanceTime(99 * time.Millisecond)
+	assert.Equal(t, "frame 17", log())
+
+	rt.AdvanceTime(time.Millisecond)
+	assert.Equal(t, "frame 17,timeout 100", log())
+
+	rt.AdvanceTime(time.Second)
+	assert.Equal(t, "frame 17,timeout 100,nested 150", log())
+	assert.NoError(t, rt.Execute(`var elapsed = Date.now() - start;`))
+	assert.Equal(t, int64(1100), rt.vm.Get("elapsed").ToInteger())
+}
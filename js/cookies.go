@@ -0,0 +1,36 @@
+package js
+
+import (
+	"net/url"
+
+	"browser/utils"
+
+	"github.com/dop251/goja"
+)
+
+// installDocumentCookie defines document.cookie over utils.Cookies, the
+// jar HTTP requests use. Pages not loaded over HTTP have no cookies:
+// reading gives "" and writing is ignored.
+func (rt *JSRuntime) installDocumentCookie(docObj *goja.Object) {
+	pageURL := func() *url.URL {
+		u, err := url.Parse(rt.currentURL)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+			return nil
+		}
+		return u
+	}
+	docObj.DefineAccessorProperty("cookie",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if u := pageURL(); u != nil {
+				return rt.vm.ToValue(utils.Cookies.DocumentCookie(u))
+			}
+			return rt.vm.ToValue("")
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if u := pageURL(); u != nil && len(call.Arguments) > 0 {
+				utils.Cookies.SetDocumentCookie(u, call.Arguments[0].String())
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
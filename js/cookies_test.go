@@ -0,0 +1,31 @@
+package js
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"browser/dom"
+	"browser/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentCookie(t *testing.T) {
+	t.Cleanup(func() { utils.Cookies.Clear("cookies.test", time.Time{}) })
+	page, _ := url.Parse("https://cookies.test/app")
+	utils.Cookies.SetCookies(page, []*http.Cookie{{Name: "sid", Value: "1", HttpOnly: true}})
+
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, nil)
+	rt.SetCurrentURL(page.String())
+	require.NoError(t, rt.Execute(`document.cookie = "theme=dark"; document.cookie = "lang=en; max-age=60"; var seen = document.cookie;`))
+	assert.ElementsMatch(t, []string{"theme=dark", "lang=en"}, strings.Split(rt.vm.Get("seen").String(), "; "))
+	assert.Len(t, utils.Cookies.Cookies(page), 3, "script cookies go with requests too")
+
+	rt.SetCurrentURL("about:blank")
+	require.NoError(t, rt.Execute(`document.cookie = "x=1"; var blank = document.cookie;`))
+	assert.Equal(t, "", rt.vm.Get("blank").String())
+}
@@ -46,9 +46,7 @@ func (e *Element) SetTextContent(text string) {
 		e.node.AppendChild(textNode)
 	}
 
-	if e.rt.onReflow != nil {
-		e.rt.onReflow()
-	}
+	e.rt.reflow(e.node)
 }
 
 func (e *Element) GetInnerHTML() string {
@@ -68,9 +66,7 @@ func (e *Element) SetInnerHTML(htmlContent string) {
 		e.node.AppendChild(child)
 	}
 
-	if e.rt != nil && e.rt.onReflow != nil {
-		e.rt.onReflow()
-	}
+	e.rt.reflow(e.node)
 }
 
 func (e *Element) getClasses() []string {
@@ -97,9 +93,7 @@ func (e *Element) ClassListAdd(className string) {
 	classes = append(classes, className)
 	e.setClasses(classes)
 
-	if e.rt.onReflow != nil {
-		e.rt.onReflow()
-	}
+	e.rt.reflow(e.node)
 }
 
 func (e *Element) ClassListRemove(className string) {
@@ -109,9 +103,7 @@ func (e *Element) ClassListRemove(className string) {
 	})
 	e.setClasses(classes)
 
-	if e.rt.onReflow != nil {
-		e.rt.onReflow()
-	}
+	e.rt.reflow(e.node)
 }
 
 // serializeNode converts a DOM node back to HTML string
@@ -152,13 +144,11 @@ func collectText(node *dom.Node) string {
 		return ""
 	}
 
-	if node.Type == dom.Text {
-		return node.Text
-	}
-
 	var sb strings.Builder
-	for _, child := range node.Children {
-		sb.WriteString(collectText(child))
+	for n := range dom.Walk(node) {
+		if n.Type == dom.Text {
+			sb.WriteString(n.Text)
+		}
 	}
 	return sb.String()
 }
@@ -185,3 +175,12 @@ func unwrapNode(rt *JSRuntime, val goja.Value) *dom.Node {
 
 	return elem.node
 }
+
+// wrapElements wraps nodes as a script array, in order.
+func (rt *JSRuntime) wrapElements(nodes []*dom.Node) goja.Value {
+	wrapped := make([]any, 0, len(nodes))
+	for _, n := range nodes {
+		wrapped = append(wrapped, rt.wrapElement(n))
+	}
+	return rt.vm.NewArray(wrapped...)
+}
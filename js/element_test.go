@@ -295,3 +295,43 @@ func TestImageNaturalSize(t *testing.T) {
 	assert.Equal(t, int64(640), obj.Get("naturalWidth").ToInteger())
 	assert.Equal(t, int64(480), obj.Get("naturalHeight").ToInteger())
 }
+
+func TestDialog(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<dialog id="d"><form>Sure?</form></dialog>`))
+	dialog := dom.FindElementsByTagName(doc, "dialog")
+
+	var reflowed []*dom.Node
+	rt := NewJSRuntime(doc, func(changed *dom.Node) { reflowed = append(reflowed, changed) })
+	err := rt.Execute(`
+		var d = document.getElementById("d");
+		var log = [];
+		var keepOpen = true;
+		d.addEventListener("close", function() { log.push("close:" + d.returnValue); });
+		d.addEventListener("cancel", function(e) { log.push("cancel"); if (keepOpen) e.preventDefault(); });
+		d.showModal();
+	`)
+	assert.NoError(t, err)
+	assert.True(t, dom.IsDialogOpen(dialog))
+	assert.True(t, dialog.Modal)
+	assert.Equal(t, []*dom.Node{dialog}, reflowed)
+
+	_, err = rt.vm.RunString(`d.show()`)
+	assert.Error(t, err, "a modal dialog cannot be shown non-modally")
+
+	_, err = rt.vm.RunString(`d.close("yes"); d.close("twice"); log.push(String(d.open));`)
+	assert.NoError(t, err)
+	assert.False(t, dialog.Modal)
+
+	_, err = rt.vm.RunString(`d.show(); log.push(String(d.open));`)
+	assert.NoError(t, err)
+	assert.False(t, dialog.Modal)
+	rt.CancelDialog(dialog)
+	assert.True(t, dom.IsDialogOpen(dialog), "cancel was prevented")
+	_, err = rt.vm.RunString(`keepOpen = false`)
+	assert.NoError(t, err)
+	rt.CancelDialog(dialog)
+	assert.False(t, dom.IsDialogOpen(dialog))
+
+	assert.Equal(t, "close:yes,false,true,cancel,cancel,close:yes", rt.vm.Get("log").String())
+	assert.Equal(t, "HTMLDialogElement", interfaceName(dialog))
+}
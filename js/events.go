@@ -16,11 +16,14 @@ type EventListener struct {
 type EventManager struct {
 	// Map from DOM node -> event type -> list of listeners
 	listeners map[*dom.Node]map[string][]EventListener
+	// activation is the page's user-activation state (HTML §6.4)
+	activation *userActivation
 }
 
 func NewEventManager() *EventManager {
 	return &EventManager{
-		listeners: make(map[*dom.Node]map[string][]EventListener),
+		listeners:  make(map[*dom.Node]map[string][]EventListener),
+		activation: newUserActivation(),
 	}
 }
 
@@ -38,6 +41,17 @@ func (em *EventManager) AddEventListener(node *dom.Node, eventType string, callb
 // Dispatch fires all listeners for the given node and event type.
 // Returns true if any handler called preventDefault().
 func (em *EventManager) Dispatch(rt *JSRuntime, node *dom.Node, eventType string) bool {
+	return em.dispatch(rt, node, eventType, nil, true)
+}
+
+// DispatchAtTarget is like Dispatch for events that do not bubble, such as
+// toggle: only node's own listeners run. It also sets props (e.g.
+// "newState" for toggle) on every event object passed to the listeners.
+func (em *EventManager) DispatchAtTarget(rt *JSRuntime, node *dom.Node, eventType string, props map[string]interface{}) bool {
+	return em.dispatch(rt, node, eventType, props, false)
+}
+
+func (em *EventManager) dispatch(rt *JSRuntime, node *dom.Node, eventType string, props map[string]interface{}, bubbles bool) bool {
 	fmt.Printf("Dispatch: eventType=%s, node=%p, tagName=%s\n", eventType, node, node.TagName)
 	fmt.Printf("  Total registered nodes: %d\n", len(em.listeners))
 	for n := range em.listeners {
@@ -64,10 +78,16 @@ func (em *EventManager) Dispatch(rt *JSRuntime, node *dom.Node, eventType string
 				event.Set("preventDefault", func() {
 					defaultPrevented = true
 				})
+				for name, value := range props {
+					event.Set(name, value)
+				}
 
 				l.callback(goja.Undefined(), event)
 			}
 		}
+		if !bubbles {
+			break
+		}
 		current = current.Parent
 	}
 
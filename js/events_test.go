@@ -0,0 +1,30 @@
+package js
+
+import (
+	"strings"
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatchToggle(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<div id="outer"><details id="d"><summary>More</summary></details></div>`))
+	details := dom.FindElementsByTagName(doc, "details")
+
+	rt := NewJSRuntime(doc, nil)
+	err := rt.Execute(`
+		var log = [];
+		document.getElementById("d").addEventListener("toggle", function(e) { log.push(e.oldState + ">" + e.newState); });
+		document.getElementById("outer").addEventListener("toggle", function() { log.push("bubbled"); });
+	`)
+	assert.NoError(t, err)
+
+	dom.ToggleDetails(details)
+	rt.DispatchToggle(details)
+	dom.ToggleDetails(details)
+	rt.DispatchToggle(details)
+
+	assert.Equal(t, "closed>open,open>closed", rt.vm.Get("log").String(), "toggle does not bubble")
+}
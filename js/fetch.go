@@ -0,0 +1,443 @@
+package js
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"browser/utils"
+
+	"github.com/dop251/goja"
+)
+
+// Networking for scripts: fetch() and XMLHttpRequest, over
+// utils.FetchFromScript, which enforces the same-origin policy. Requests
+// run in the background and settle under the VM lock, like timers; they
+// are cancelled, and never settle, when the page is left.
+
+// setupNetwork defines fetch and XMLHttpRequest on window and the global
+// object.
+func (rt *JSRuntime) setupNetwork(window *goja.Object) {
+	fetch := rt.vm.ToValue(rt.fetch)
+	xhr := rt.vm.ToValue(rt.newXMLHttpRequest)
+	for _, obj := range []*goja.Object{window, rt.vm.GlobalObject()} {
+		obj.Set("fetch", fetch)
+		obj.Set("XMLHttpRequest", xhr)
+	}
+}
+
+// scriptRequest builds the request of a script on the current page. A
+// string body without a Content-Type is sent as text.
+func (rt *JSRuntime) scriptRequest(method, rawURL string, header http.Header, body goja.Value) utils.ScriptRequest {
+	req := utils.ScriptRequest{
+		Method:  strings.ToUpper(method),
+		URL:     rt.resolveScriptURL(rawURL),
+		Header:  header,
+		PageURL: rt.currentURL,
+		Context: utils.Subresources.Context(),
+	}
+	if body != nil && !goja.IsUndefined(body) && !goja.IsNull(body) && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		req.Body = []byte(body.String())
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "text/plain;charset=UTF-8")
+		}
+	}
+	return req
+}
+
+// settle runs fn, which calls back into scripts, under the VM lock once a
+// background request is done, and reflows if it changed the page.
+func (rt *JSRuntime) settle(fn func()) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			fmt.Println("network callback panic:", recovered)
+		}
+	}()
+	rt.deferReflows(fn)
+}
+
+// logNetworkError reports why a script request failed on the console;
+// scripts only learn that it did.
+func logNetworkError(err error) {
+	var corsErr *utils.CORSError
+	if errors.As(err, &corsErr) {
+		fmt.Println("CORS error:", corsErr)
+		return
+	}
+	fmt.Println("Network error:", err)
+}
+
+// fetch implements window.fetch(url, init). init may give the method,
+// headers, body, mode, credentials and referrerPolicy.
+func (rt *JSRuntime) fetch(call goja.FunctionCall) goja.Value {
+	promise, resolve, reject := rt.vm.NewPromise()
+	if len(call.Arguments) == 0 {
+		reject(rt.vm.NewTypeError("fetch: 1 argument required"))
+		return rt.vm.ToValue(promise)
+	}
+
+	method, header, body := "GET", http.Header{}, goja.Value(nil)
+	var mode, credentials, referrerPolicy string
+	if init, ok := call.Argument(1).(*goja.Object); ok {
+		if v := init.Get("method"); v != nil && !goja.IsUndefined(v) {
+			method = v.String()
+		}
+		if headers, ok := init.Get("headers").(*goja.Object); ok {
+			for _, name := range headers.Keys() {
+				header.Add(name, headers.Get(name).String())
+			}
+		}
+		body = init.Get("body")
+		if v := init.Get("mode"); v != nil && !goja.IsUndefined(v) {
+			mode = v.String()
+		}
+		if v := init.Get("credentials"); v != nil && !goja.IsUndefined(v) {
+			credentials = v.String()
+		}
+		if v := init.Get("referrerPolicy"); v != nil && !goja.IsUndefined(v) {
+			referrerPolicy = v.String()
+		}
+	}
+	req := rt.scriptRequest(method, call.Arguments[0].String(), header, body)
+	req.Mode = utils.RequestMode(mode)
+	req.Credentials = utils.CredentialsMode(credentials)
+	req.ReferrerPolicy = referrerPolicy
+
+	go func() {
+		resp, err := utils.FetchFromScript(req)
+		if err != nil && req.Context.Err() != nil {
+			return // the page was left
+		}
+		rt.settle(func() {
+			if err != nil {
+				logNetworkError(err)
+				reject(rt.vm.NewTypeError("Failed to fetch"))
+				return
+			}
+			resolve(rt.newResponse(resp))
+		})
+	}()
+	return rt.vm.ToValue(promise)
+}
+
+// newResponse returns the Response object for resp: its status, URL,
+// readable headers, and body through text, json and arrayBuffer, once.
+func (rt *JSRuntime) newResponse(resp *utils.ScriptResponse) *goja.Object {
+	obj := rt.vm.NewObject()
+	obj.Set("type", resp.Type)
+	obj.Set("url", resp.URL)
+	obj.Set("redirected", resp.Redirected)
+	obj.Set("status", resp.Status)
+	obj.Set("statusText", resp.StatusText)
+	obj.Set("ok", resp.Status >= 200 && resp.Status <= 299)
+	obj.Set("headers", rt.newHeaders(resp.Header))
+
+	used := false
+	obj.DefineAccessorProperty("bodyUsed",
+		rt.vm.ToValue(func(goja.FunctionCall) goja.Value { return rt.vm.ToValue(used) }),
+		nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	read := func(convert func(body []byte) (goja.Value, error)) func(goja.FunctionCall) goja.Value {
+		return func(goja.FunctionCall) goja.Value {
+			promise, resolve, reject := rt.vm.NewPromise()
+			if used {
+				reject(rt.vm.NewTypeError("Body has already been consumed."))
+				return rt.vm.ToValue(promise)
+			}
+			used = true
+			value, err := convert(resp.Body)
+			var exception *goja.Exception
+			switch {
+			case errors.As(err, &exception):
+				reject(exception.Value())
+			case err != nil:
+				reject(rt.vm.NewTypeError(err.Error()))
+			default:
+				resolve(value)
+			}
+			return rt.vm.ToValue(promise)
+		}
+	}
+	obj.Set("text", read(func(body []byte) (goja.Value, error) {
+		return rt.vm.ToValue(string(body)), nil
+	}))
+	obj.Set("json", read(func(body []byte) (goja.Value, error) {
+		parse, _ := goja.AssertFunction(rt.vm.Get("JSON").ToObject(rt.vm).Get("parse"))
+		return parse(goja.Undefined(), rt.vm.ToValue(string(body)))
+	}))
+	obj.Set("arrayBuffer", read(func(body []byte) (goja.Value, error) {
+		return rt.vm.ToValue(rt.vm.NewArrayBuffer(slices.Clone(body))), nil
+	}))
+	return obj
+}
+
+// newHeaders returns a read-only Headers object over header.
+func (rt *JSRuntime) newHeaders(header http.Header) *goja.Object {
+	obj := rt.vm.NewObject()
+	obj.Set("get", func(call goja.FunctionCall) goja.Value {
+		values := header.Values(call.Argument(0).String())
+		if len(values) == 0 {
+			return goja.Null()
+		}
+		return rt.vm.ToValue(strings.Join(values, ", "))
+	})
+	obj.Set("has", func(call goja.FunctionCall) goja.Value {
+		return rt.vm.ToValue(len(header.Values(call.Argument(0).String())) > 0)
+	})
+	obj.Set("forEach", func(call goja.FunctionCall) goja.Value {
+		callback, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(rt.vm.NewTypeError("Headers.forEach: argument is not a function"))
+		}
+		for _, name := range sortedHeaderNames(header) {
+			if _, err := callback(goja.Undefined(), rt.vm.ToValue(strings.Join(header[name], ", ")), rt.vm.ToValue(strings.ToLower(name))); err != nil {
+				panic(err)
+			}
+		}
+		return goja.Undefined()
+	})
+	return obj
+}
+
+func sortedHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// XMLHttpRequest ready states.
+const (
+	xhrUnsent = iota
+	xhrOpened
+	xhrHeadersReceived
+	xhrLoading
+	xhrDone
+)
+
+// xmlHttpRequest is the state of an XMLHttpRequest object.
+type xmlHttpRequest struct {
+	rt         *JSRuntime
+	obj        *goja.Object
+	readyState int
+	method     string
+	url        string
+	async      bool
+	header     http.Header
+	response   *utils.ScriptResponse
+	cancel     context.CancelFunc // aborts the request in flight
+	listeners  map[string][]goja.Callable
+}
+
+// newXMLHttpRequest constructs an XMLHttpRequest: open, setRequestHeader
+// and send, withCredentials, the response through status, responseText
+// and the response headers, and events through on* properties and
+// addEventListener.
+func (rt *JSRuntime) newXMLHttpRequest(call goja.ConstructorCall) *goja.Object {
+	x := &xmlHttpRequest{rt: rt, obj: call.This, listeners: make(map[string][]goja.Callable)}
+	obj := call.This
+	obj.Set("withCredentials", false)
+	for name, value := range map[string]int{"UNSENT": xhrUnsent, "OPENED": xhrOpened, "HEADERS_RECEIVED": xhrHeadersReceived, "LOADING": xhrLoading, "DONE": xhrDone} {
+		obj.Set(name, value)
+	}
+	accessor := func(name string, get func() any) {
+		obj.DefineAccessorProperty(name,
+			rt.vm.ToValue(func(goja.FunctionCall) goja.Value { return rt.vm.ToValue(get()) }),
+			nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+	accessor("readyState", func() any { return x.readyState })
+	accessor("status", func() any {
+		if x.response == nil {
+			return 0
+		}
+		return x.response.Status
+	})
+	accessor("statusText", func() any {
+		if x.response == nil {
+			return ""
+		}
+		return x.response.StatusText
+	})
+	accessor("responseURL", func() any {
+		if x.response == nil {
+			return ""
+		}
+		return x.response.URL
+	})
+	responseText := func() any {
+		if x.response == nil {
+			return ""
+		}
+		return string(x.response.Body)
+	}
+	accessor("responseText", responseText)
+	accessor("response", responseText)
+
+	obj.Set("open", x.open)
+	obj.Set("setRequestHeader", x.setRequestHeader)
+	obj.Set("send", x.send)
+	obj.Set("abort", x.abort)
+	obj.Set("getResponseHeader", func(call goja.FunctionCall) goja.Value {
+		if x.response == nil {
+			return goja.Null()
+		}
+		values := x.response.Header.Values(call.Argument(0).String())
+		if len(values) == 0 {
+			return goja.Null()
+		}
+		return rt.vm.ToValue(strings.Join(values, ", "))
+	})
+	obj.Set("getAllResponseHeaders", func(goja.FunctionCall) goja.Value {
+		if x.response == nil {
+			return rt.vm.ToValue("")
+		}
+		var sb strings.Builder
+		for _, name := range sortedHeaderNames(x.response.Header) {
+			fmt.Fprintf(&sb, "%s: %s\r\n", strings.ToLower(name), strings.Join(x.response.Header[name], ", "))
+		}
+		return rt.vm.ToValue(sb.String())
+	})
+	obj.Set("addEventListener", func(call goja.FunctionCall) goja.Value {
+		if listener, ok := goja.AssertFunction(call.Argument(1)); ok {
+			eventType := call.Argument(0).String()
+			x.listeners[eventType] = append(x.listeners[eventType], listener)
+		}
+		return goja.Undefined()
+	})
+	return nil
+}
+
+func (x *xmlHttpRequest) open(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) < 2 {
+		panic(x.rt.vm.NewTypeError("XMLHttpRequest.open: 2 arguments required"))
+	}
+	x.abortInFlight()
+	x.method = strings.ToUpper(call.Arguments[0].String())
+	x.url = call.Arguments[1].String()
+	x.async = len(call.Arguments) < 3 || call.Arguments[2].ToBoolean()
+	x.header = http.Header{}
+	x.response = nil
+	x.setReadyState(xhrOpened)
+	return goja.Undefined()
+}
+
+func (x *xmlHttpRequest) setRequestHeader(call goja.FunctionCall) goja.Value {
+	if x.readyState != xhrOpened {
+		panic(x.rt.vm.NewTypeError("InvalidStateError: The object's state must be OPENED."))
+	}
+	x.header.Add(call.Argument(0).String(), call.Argument(1).String())
+	return goja.Undefined()
+}
+
+func (x *xmlHttpRequest) send(call goja.FunctionCall) goja.Value {
+	if x.readyState != xhrOpened {
+		panic(x.rt.vm.NewTypeError("InvalidStateError: The object's state must be OPENED."))
+	}
+	req := x.rt.scriptRequest(x.method, x.url, x.header, call.Argument(0))
+	req.Mode = utils.ModeCORS
+	req.Credentials = utils.CredentialsSameOrigin
+	if x.obj.Get("withCredentials").ToBoolean() {
+		req.Credentials = utils.CredentialsInclude
+	}
+
+	if !x.async {
+		// The script waits, holding the VM lock
+		resp, err := utils.FetchFromScript(req)
+		if err != nil {
+			logNetworkError(err)
+			x.fail()
+			panic(x.rt.vm.NewTypeError("NetworkError: Failed to execute 'send' on 'XMLHttpRequest'."))
+		}
+		x.complete(resp)
+		return goja.Undefined()
+	}
+
+	ctx, cancel := context.WithCancel(req.Context)
+	req.Context, x.cancel = ctx, cancel
+	go func() {
+		defer cancel()
+		resp, err := utils.FetchFromScript(req)
+		if err != nil && ctx.Err() != nil {
+			return // aborted, or the page was left
+		}
+		x.rt.settle(func() {
+			if ctx.Err() != nil {
+				return // aborted while settling
+			}
+			if err != nil {
+				logNetworkError(err)
+				x.fail()
+				return
+			}
+			x.complete(resp)
+		})
+	}()
+	return goja.Undefined()
+}
+
+// abort cancels the request in flight, which then never loads.
+func (x *xmlHttpRequest) abort(goja.FunctionCall) goja.Value {
+	if x.abortInFlight() {
+		x.response = nil
+		x.setReadyState(xhrUnsent)
+		x.dispatch("abort")
+		x.dispatch("loadend")
+	}
+	return goja.Undefined()
+}
+
+// abortInFlight cancels the request in flight, reporting whether there was
+// one.
+func (x *xmlHttpRequest) abortInFlight() bool {
+	if x.cancel == nil {
+		return false
+	}
+	x.cancel()
+	x.cancel = nil
+	return true
+}
+
+func (x *xmlHttpRequest) complete(resp *utils.ScriptResponse) {
+	x.cancel = nil
+	x.response = resp
+	x.setReadyState(xhrHeadersReceived)
+	x.setReadyState(xhrLoading)
+	x.setReadyState(xhrDone)
+	x.dispatch("load")
+	x.dispatch("loadend")
+}
+
+func (x *xmlHttpRequest) fail() {
+	x.cancel = nil
+	x.response = nil
+	x.setReadyState(xhrDone)
+	x.dispatch("error")
+	x.dispatch("loadend")
+}
+
+func (x *xmlHttpRequest) setReadyState(state int) {
+	x.readyState = state
+	x.dispatch("readystatechange")
+}
+
+// dispatch fires an event of eventType at the object: its on<eventType>
+// handler, then its listeners.
+func (x *xmlHttpRequest) dispatch(eventType string) {
+	event := x.rt.vm.NewObject()
+	event.Set("type", eventType)
+	event.Set("target", x.obj)
+	event.Set("currentTarget", x.obj)
+	handlers := slices.Clone(x.listeners[eventType])
+	if handler, ok := goja.AssertFunction(x.obj.Get("on" + eventType)); ok {
+		handlers = append([]goja.Callable{handler}, handlers...)
+	}
+	for _, handler := range handlers {
+		if _, err := handler(x.obj, event); err != nil {
+			fmt.Println("XMLHttpRequest", eventType, "handler error:", err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package js
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"browser/dom"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitFor waits for the script global name to be set, and returns it.
+func waitFor(t *testing.T, rt *JSRuntime, name string) goja.Value {
+	var value goja.Value
+	require.Eventually(t, func() bool {
+		rt.vmMu.Lock()
+		defer rt.vmMu.Unlock()
+		value = rt.vm.Get(name)
+		return value != nil && !goja.IsUndefined(value)
+	}, 5*time.Second, 5*time.Millisecond, "%s is never set", name)
+	return value
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"method": "`+r.Method+`", "body": "`+string(body)+`", "type": "`+r.Header.Get("Content-Type")+`"}`)
+	}))
+	defer server.Close()
+
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, nil)
+	rt.SetCurrentURL(server.URL + "/app/page.html")
+	require.NoError(t, rt.Execute(`
+		fetch("../api", {method: "post", body: "hi"}).then(function (r) {
+			meta = [r.ok, r.status, r.type, r.url, r.headers.get("content-type")].join(" ");
+			return r.json();
+		}).then(function (data) { result = data.method + " " + data.body + " " + data.type; });
+	`))
+	assert.Equal(t, "POST hi text/plain;charset=UTF-8", waitFor(t, rt, "result").String())
+	assert.Equal(t, "true 200 basic "+server.URL+"/api application/json", waitFor(t, rt, "meta").String())
+}
+
+func TestSettleReflowsOnlyChanges(t *testing.T) {
+	reflows := 0
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, func(*dom.Node) { reflows++ })
+	rt.settle(func() { rt.vm.RunString(`var done = true`) })
+	assert.Equal(t, 0, reflows, "the callback changed nothing")
+
+	rt.settle(func() { rt.vm.RunString(`var p = document.createElement("p"); p.className = "a"; p.textContent = "b"`) })
+	assert.Equal(t, 1, reflows)
+}
+
+func TestFetchBlockedCrossOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secret")
+	}))
+	defer server.Close()
+
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, nil)
+	rt.SetCurrentURL("https://app.example/")
+	require.NoError(t, rt.Execute(`
+		fetch("`+server.URL+`").then(function () { outcome = "read"; },
+			function (err) { outcome = err.name + ": " + err.message; });
+	`))
+	assert.Equal(t, "TypeError: Failed to fetch", waitFor(t, rt, "outcome").String())
+}
+
+func TestXMLHttpRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-Token", r.Header.Get("X-Token"))
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, nil)
+	rt.SetCurrentURL(server.URL + "/")
+	require.NoError(t, rt.Execute(`
+		var states = [];
+		var xhr = new XMLHttpRequest();
+		xhr.onreadystatechange = function () { states.push(xhr.readyState); };
+		xhr.open("GET", "/data");
+		xhr.setRequestHeader("X-Token", "abc");
+		xhr.addEventListener("load", function (e) {
+			loaded = [e.type, xhr.status, xhr.responseText, xhr.getResponseHeader("x-token"), states.join(",")].join(" ");
+		});
+		xhr.send();
+
+		var sync = new XMLHttpRequest();
+		sync.open("GET", "/missing", false);
+		sync.send();
+		var syncStatus = sync.status;
+	`))
+	assert.Equal(t, "load 200 hello abc 1,2,3,4", waitFor(t, rt, "loaded").String())
+	assert.Equal(t, int64(404), waitFor(t, rt, "syncStatus").ToInteger())
+
+	rt.SetCurrentURL("https://app.example/")
+	require.NoError(t, rt.Execute(`
+		var blocked = new XMLHttpRequest();
+		blocked.onerror = function () { failed = blocked.readyState + " " + blocked.status; };
+		blocked.open("GET", "`+server.URL+`/data");
+		blocked.send();
+	`))
+	assert.Equal(t, "4 0", waitFor(t, rt, "failed").String())
+}
@@ -0,0 +1,123 @@
+package js
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// installCanvas adds HTMLCanvasElement: width, height, toDataURL and toBlob
+// (WHATWG 4.12.5). Setting width or height resets the bitmap to
+// transparent black, even to the same size.
+func (rt *JSRuntime) installCanvas(obj *goja.Object, elem *Element) {
+	node := elem.node
+	for _, name := range []string{"width", "height"} {
+		obj.DefineAccessorProperty(name,
+			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				width, height := node.CanvasSize()
+				if name == "width" {
+					return rt.vm.ToValue(width)
+				}
+				return rt.vm.ToValue(height)
+			}),
+			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				if len(call.Arguments) > 0 {
+					node.Attributes[name] = strconv.FormatInt(max(call.Arguments[0].ToInteger(), 0), 10)
+					node.Bitmap = nil
+					rt.reflow(nil)
+				}
+				return goja.Undefined()
+			}),
+			goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+
+	obj.Set("toDataURL", func(call goja.FunctionCall) goja.Value {
+		bitmap := node.CanvasBitmap()
+		if bitmap.Rect.Empty() {
+			return rt.vm.ToValue("data:,")
+		}
+		mimeType, data := encodeCanvas(bitmap, call.Argument(0), call.Argument(1))
+		return rt.vm.ToValue("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data))
+	})
+
+	obj.Set("toBlob", func(call goja.FunctionCall) goja.Value {
+		callback, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(rt.vm.NewTypeError("toBlob: callback is not a function"))
+		}
+		// The bitmap is encoded as it is now; the callback runs as a task
+		bitmap := node.CanvasBitmap()
+		var mimeType string
+		var data []byte
+		if !bitmap.Rect.Empty() {
+			mimeType, data = encodeCanvas(bitmap, call.Argument(1), call.Argument(2))
+		}
+		rt.clock.AfterFunc(0, func() {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					fmt.Println("toBlob callback panic:", recovered)
+				}
+			}()
+			rt.vmMu.Lock()
+			blob := goja.Null()
+			if data != nil {
+				blob = rt.newBlob(data, mimeType)
+			}
+			_, err := callback(goja.Undefined(), blob)
+			rt.vmMu.Unlock()
+			if err != nil {
+				fmt.Println("toBlob callback error:", err)
+			}
+			rt.reflow(nil)
+		})
+		return goja.Undefined()
+	})
+}
+
+// encodeCanvas encodes bitmap as the image type asked for, PNG unless it
+// is image/jpeg, which takes a quality from 0 to 1. It returns the type
+// used and the encoded image.
+func encodeCanvas(bitmap image.Image, typeArg, qualityArg goja.Value) (string, []byte) {
+	var buf bytes.Buffer
+	if !goja.IsUndefined(typeArg) && strings.EqualFold(typeArg.String(), "image/jpeg") {
+		quality := 92
+		if goja.IsNumber(qualityArg) {
+			if q := qualityArg.ToFloat(); q >= 0 && q <= 1 {
+				quality = int(q*100 + 0.5)
+			}
+		}
+		// Transparent pixels come out black, as JPEG has no alpha
+		if jpeg.Encode(&buf, bitmap, &jpeg.Options{Quality: quality}) == nil {
+			return "image/jpeg", buf.Bytes()
+		}
+		buf.Reset()
+	}
+	png.Encode(&buf, bitmap)
+	return "image/png", buf.Bytes()
+}
+
+// newBlob returns a Blob of data: its size and type, and its bytes through
+// arrayBuffer and text.
+func (rt *JSRuntime) newBlob(data []byte, mimeType string) *goja.Object {
+	blob := rt.vm.NewObject()
+	blob.Set("size", len(data))
+	blob.Set("type", mimeType)
+	blob.Set("arrayBuffer", func(call goja.FunctionCall) goja.Value {
+		promise, resolve, _ := rt.vm.NewPromise()
+		resolve(rt.vm.NewArrayBuffer(bytes.Clone(data)))
+		return rt.vm.ToValue(promise)
+	})
+	blob.Set("text", func(call goja.FunctionCall) goja.Value {
+		promise, resolve, _ := rt.vm.NewPromise()
+		resolve(string(data))
+		return rt.vm.ToValue(promise)
+	})
+	return blob
+}
@@ -0,0 +1,68 @@
+package js
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanvas(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<canvas id="c" width="2" height="1"></canvas>`))
+	rt := NewJSRuntime(doc, nil)
+	rt.UseVirtualTime()
+	canvas := dom.FindByID(doc, "c")
+	canvas.CanvasBitmap().SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+
+	t.Run("toDataURL encodes the bitmap", func(t *testing.T) {
+		require.NoError(t, rt.Execute(`var c = document.getElementById("c"); var url = c.toDataURL();`))
+		url := rt.vm.Get("url").String()
+		require.True(t, strings.HasPrefix(url, "data:image/png;base64,"), url)
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(url, "data:image/png;base64,"))
+		require.NoError(t, err)
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 2, 1), img.Bounds())
+		assert.Equal(t, color.NRGBA{255, 0, 0, 255}, color.NRGBAModel.Convert(img.At(0, 0)))
+	})
+
+	t.Run("other types", func(t *testing.T) {
+		require.NoError(t, rt.Execute(`var jpeg = c.toDataURL("image/jpeg", 0.5); var webp = c.toDataURL("image/webp");`))
+		assert.True(t, strings.HasPrefix(rt.vm.Get("jpeg").String(), "data:image/jpeg;base64,"))
+		assert.True(t, strings.HasPrefix(rt.vm.Get("webp").String(), "data:image/png;base64,"), "unsupported types fall back to PNG")
+	})
+
+	t.Run("toBlob calls back as a task", func(t *testing.T) {
+		require.NoError(t, rt.Execute(`
+			var blob = null, text = "";
+			c.toBlob(function(b) { blob = b; b.text().then(function(s) { text = s }) });
+			var early = blob === null;
+		`))
+		assert.True(t, rt.vm.Get("early").ToBoolean())
+		rt.AdvanceTime(time.Millisecond)
+		require.NoError(t, rt.Execute(`var type = blob.type, size = blob.size;`))
+		assert.Equal(t, "image/png", rt.vm.Get("type").String())
+		assert.Positive(t, rt.vm.Get("size").ToInteger())
+		assert.True(t, strings.HasPrefix(rt.vm.Get("text").String(), "\x89PNG"))
+	})
+
+	t.Run("resizing clears the bitmap", func(t *testing.T) {
+		require.NoError(t, rt.Execute(`c.width = 2; var w = c.width, h = c.height;`))
+		assert.Equal(t, int64(2), rt.vm.Get("w").ToInteger())
+		assert.Equal(t, int64(1), rt.vm.Get("h").ToInteger())
+		assert.Equal(t, color.NRGBA{}, canvas.CanvasBitmap().NRGBAAt(0, 0))
+
+		require.NoError(t, rt.Execute(`c.height = 0; var empty = c.toDataURL(); var instance = c instanceof HTMLCanvasElement;`))
+		assert.Equal(t, "data:,", rt.vm.Get("empty").String())
+		assert.True(t, rt.vm.Get("instance").ToBoolean())
+	})
+}
@@ -0,0 +1,97 @@
+package js
+
+import (
+	"browser/dom"
+
+	"github.com/dop251/goja"
+)
+
+// installDialog adds HTMLDialogElement: open, returnValue, show, showModal
+// and close (WHATWG 4.11.4).
+func (rt *JSRuntime) installDialog(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("open",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(dom.IsDialogOpen(node))
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) == 0 {
+				return goja.Undefined()
+			}
+			// Setting open neither makes the dialog modal nor fires close
+			if call.Arguments[0].ToBoolean() {
+				dom.ShowDialog(node, false)
+			} else if dom.IsDialogOpen(node) {
+				delete(node.Attributes, "open")
+				node.Modal = false
+			}
+			rt.reflow(node)
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.Set("returnValue", "")
+
+	obj.Set("show", func(call goja.FunctionCall) goja.Value {
+		if node.Modal && dom.IsDialogOpen(node) {
+			panic(rt.vm.NewTypeError("InvalidStateError: The dialog is already open as a modal dialog."))
+		}
+		if dom.ShowDialog(node, false) {
+			rt.reflow(node)
+		}
+		return goja.Undefined()
+	})
+
+	obj.Set("showModal", func(call goja.FunctionCall) goja.Value {
+		if dom.IsDialogOpen(node) {
+			if node.Modal {
+				return goja.Undefined()
+			}
+			panic(rt.vm.NewTypeError("InvalidStateError: The dialog is already open as a non-modal dialog."))
+		}
+		if node.Parent == nil {
+			panic(rt.vm.NewTypeError("InvalidStateError: The dialog is not in a document."))
+		}
+		dom.ShowDialog(node, true)
+		rt.reflow(node)
+		return goja.Undefined()
+	})
+
+	obj.Set("close", func(call goja.FunctionCall) goja.Value {
+		var returnValue *string
+		if len(call.Arguments) > 0 && !goja.IsUndefined(call.Arguments[0]) {
+			v := call.Arguments[0].String()
+			returnValue = &v
+		}
+		rt.closeDialog(node, obj, returnValue)
+		return goja.Undefined()
+	})
+}
+
+// closeDialog closes an open dialog, sets its returnValue when given one,
+// and fires close at it.
+func (rt *JSRuntime) closeDialog(node *dom.Node, obj *goja.Object, returnValue *string) {
+	if !dom.CloseDialog(node) {
+		return
+	}
+	if returnValue != nil {
+		obj.Set("returnValue", *returnValue)
+	}
+	rt.reflow(node)
+	rt.executeInlineEventLocked(node, "close")
+	rt.Events.DispatchAtTarget(rt, node, "close", nil)
+}
+
+// CancelDialog is the Escape key pressed over a modal dialog: it fires
+// cancel at the dialog and closes it unless a listener called
+// preventDefault.
+func (rt *JSRuntime) CancelDialog(node *dom.Node) {
+	rt.vmMu.Lock()
+	defer rt.vmMu.Unlock()
+
+	rt.executeInlineEventLocked(node, "cancel")
+	if rt.Events.DispatchAtTarget(rt, node, "cancel", nil) {
+		return
+	}
+	rt.closeDialog(node, rt.wrapElement(node).ToObject(rt.vm), nil)
+}
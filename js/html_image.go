@@ -0,0 +1,82 @@
+package js
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// installImage adds HTMLImageElement: dimensions, load state and sources (WHATWG 4.8.4).
+func (rt *JSRuntime) installImage(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("width",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			v, _ := strconv.Atoi(node.Attributes["width"])
+			return rt.vm.ToValue(v)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				v := call.Arguments[0].ToInteger()
+
+				node.Attributes["width"] = strconv.FormatInt(v, 10)
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("height",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			v, _ := strconv.Atoi(node.Attributes["height"])
+			return rt.vm.ToValue(v)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				v := call.Arguments[0].ToInteger()
+
+				node.Attributes["height"] = strconv.FormatInt(v, 10)
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("naturalWidth",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(node.NaturalWidth)
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("naturalHeight",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(node.NaturalHeight)
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("complete",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if strings.TrimSpace(node.Attributes["src"]) == "" {
+				return rt.vm.ToValue(true)
+			}
+			return rt.vm.ToValue(node.ImageComplete)
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("src",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(node.Attributes["src"])
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("currentSrc",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(node.CurrentSrc)
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
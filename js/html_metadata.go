@@ -0,0 +1,37 @@
+package js
+
+import (
+	"github.com/dop251/goja"
+)
+
+// installTitle adds HTMLTitleElement.text (WHATWG 4.2.2).
+func (rt *JSRuntime) installTitle(obj *goja.Object, elem *Element) {
+	obj.DefineAccessorProperty("text",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(elem.GetTextContent())
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				elem.SetTextContent(call.Arguments[0].String())
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// installStyle adds HTMLStyleElement.disabled (WHATWG 4.2.6).
+func (rt *JSRuntime) installStyle(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("disabled",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(node.Disabled)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				node.Disabled = call.Arguments[0].ToBoolean()
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
@@ -0,0 +1,499 @@
+package js
+
+import (
+	"browser/dom"
+	"strconv"
+
+	"github.com/dop251/goja"
+)
+
+// installTable adds HTMLTableElement: caption, tHead, tFoot, tBodies, rows
+// and the create/delete methods (WHATWG 4.9.1).
+func (rt *JSRuntime) installTable(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("caption",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			// Return first caption child, or null
+			if existing := dom.ChildElement(node, "caption"); existing != nil {
+				return rt.wrapElement(existing)
+			}
+			return goja.Null()
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				// Remove existing caption first
+				for _, child := range node.Children {
+					if child.Type == dom.Element && child.TagName == "caption" {
+						node.RemoveChild(child)
+						break
+					}
+				}
+
+				// If new value is not null, insert as first child
+				if !goja.IsNull(call.Arguments[0]) && !goja.IsUndefined(call.Arguments[0]) {
+					newCaption := unwrapNode(rt, call.Arguments[0])
+					if newCaption != nil {
+						newCaption.Parent = node
+						node.Children = append([]*dom.Node{newCaption}, node.Children...)
+					}
+				}
+
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// HTMLTableElement.createCaption() (WHATWG 4.9.1)
+	obj.Set("createCaption", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		// Return existing caption if one exists
+		if existing := dom.ChildElement(node, "caption"); existing != nil {
+			return rt.wrapElement(existing)
+		}
+		// Create new caption and insert as first child
+		newCaption := dom.NewElement("caption", map[string]string{})
+		newCaption.Parent = node
+		node.Children = append([]*dom.Node{newCaption}, node.Children...)
+		rt.reflow(nil)
+		return rt.wrapElement(newCaption)
+	}))
+
+	// HTMLTableElement.deleteCaption() (WHATWG 4.9.1)
+	obj.Set("deleteCaption", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		for _, child := range node.Children {
+			if child.Type == dom.Element && child.TagName == "caption" {
+				node.RemoveChild(child)
+				rt.reflow(nil)
+				break
+			}
+		}
+		return goja.Undefined()
+	}))
+
+	obj.DefineAccessorProperty("tHead",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if existing := dom.ChildElement(node, "thead"); existing != nil {
+				return rt.wrapElement(existing)
+			}
+			return goja.Null()
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				// Remove existing thead first
+				for _, child := range node.Children {
+					if child.Type == dom.Element && child.TagName == "thead" {
+						node.RemoveChild(child)
+						break
+					}
+				}
+
+				// Find insertion index: after all caption and colgroup elements
+				insertIdx := 0
+				for _, child := range node.Children {
+					if child.Type == dom.Element && (child.TagName == "caption" || child.TagName == "colgroup") {
+						insertIdx++
+					} else {
+						break
+					}
+				}
+
+				// If new value is not null, insert at computed index
+				if !goja.IsNull(call.Arguments[0]) && !goja.IsUndefined(call.Arguments[0]) {
+					newTHead := unwrapNode(rt, call.Arguments[0])
+					if newTHead != nil {
+						newTHead.Parent = node
+						node.Children = append(
+							node.Children[:insertIdx],
+							append([]*dom.Node{newTHead}, node.Children[insertIdx:]...)...)
+					}
+				}
+
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// HTMLTableElement.createTHead() (WHATWG 4.9.1)
+	obj.Set("createTHead", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		// Return existing thead if one exists
+		if existing := dom.ChildElement(node, "thead"); existing != nil {
+			return rt.wrapElement(existing)
+		}
+		// Create new thead and insert after caption/colgroup
+		newTHead := dom.NewElement("thead", map[string]string{})
+		newTHead.Parent = node
+		insertIdx := 0
+		for _, child := range node.Children {
+			if child.Type == dom.Element && (child.TagName == "caption" || child.TagName == "colgroup") {
+				insertIdx++
+			} else {
+				break
+			}
+		}
+		node.Children = append(
+			node.Children[:insertIdx],
+			append([]*dom.Node{newTHead}, node.Children[insertIdx:]...)...)
+		rt.reflow(nil)
+		return rt.wrapElement(newTHead)
+	}))
+
+	// HTMLTableElement.deleteTHead() (WHATWG 4.9.1)
+	obj.Set("deleteTHead", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		for _, child := range node.Children {
+			if child.Type == dom.Element && child.TagName == "thead" {
+				node.RemoveChild(child)
+				rt.reflow(nil)
+				break
+			}
+		}
+		return goja.Undefined()
+	}))
+
+	obj.DefineAccessorProperty("tFoot",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if existing := dom.ChildElement(node, "tfoot"); existing != nil {
+				return rt.wrapElement(existing)
+			}
+			return goja.Null()
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				for _, child := range node.Children {
+					if child.Type == dom.Element && child.TagName == "tfoot" {
+						node.RemoveChild(child)
+						break
+					}
+				}
+
+				if !goja.IsNull(call.Arguments[0]) && !goja.IsUndefined(call.Arguments[0]) {
+					newTFoot := unwrapNode(rt, call.Arguments[0])
+					if newTFoot != nil {
+						newTFoot.Parent = node
+						node.Children = append(node.Children, newTFoot)
+					}
+				}
+
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// HTMLTableElement.createTFoot() (WHATWG 4.9.1)
+	obj.Set("createTFoot", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		// Return existing tfoot if one exists
+		if existing := dom.ChildElement(node, "tfoot"); existing != nil {
+			return rt.wrapElement(existing)
+		}
+		// Create new tfoot and append at end
+		newTFoot := dom.NewElement("tfoot", map[string]string{})
+		newTFoot.Parent = node
+		node.Children = append(node.Children, newTFoot)
+		rt.reflow(nil)
+		return rt.wrapElement(newTFoot)
+	}))
+
+	// HTMLTableElement.deleteTFoot() (WHATWG 4.9.1)
+	obj.Set("deleteTFoot", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		for _, child := range node.Children {
+			if child.Type == dom.Element && child.TagName == "tfoot" {
+				node.RemoveChild(child)
+				rt.reflow(nil)
+				break
+			}
+		}
+		return goja.Undefined()
+	}))
+
+	// HTMLTableElement.tBodies (WHATWG 4.9.1) - returns HTMLCollection of tbody elements
+	obj.DefineAccessorProperty("tBodies",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			var tbodies []*dom.Node
+			for _, section := range dom.Sections(node) {
+				if section.TagName == "tbody" {
+					tbodies = append(tbodies, section)
+				}
+			}
+			return rt.wrapElements(tbodies)
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// HTMLTableElement.rows (WHATWG 4.9.1) - returns HTMLCollection of all tr elements
+	// Order: thead rows first, then tbody/direct tr rows in tree order, then tfoot rows
+	obj.DefineAccessorProperty("rows",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.wrapElements(dom.RowsInOrder(node))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.Set("createTBody", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		newTBody := dom.NewElement("tbody", map[string]string{})
+		newTBody.Parent = node
+
+		insertIdx := len(node.Children)
+		for i := len(node.Children) - 1; i >= 0; i-- {
+			if node.Children[i].Type == dom.Element && node.Children[i].TagName == "tbody" {
+				insertIdx = i + 1
+				break
+			}
+		}
+
+		node.Children = append(
+			node.Children[:insertIdx],
+			append([]*dom.Node{newTBody}, node.Children[insertIdx:]...)...)
+
+		rt.reflow(nil)
+		return rt.wrapElement(newTBody)
+	}))
+
+	rt.installRowMethods(obj, elem)
+}
+
+// installTableSection adds HTMLTableSectionElement for thead, tbody and
+// tfoot (WHATWG 4.9.5-4.9.7).
+func (rt *JSRuntime) installTableSection(obj *goja.Object, elem *Element) {
+	node := elem.node
+	// rows - HTMLCollection of tr elements within this section only
+	obj.DefineAccessorProperty("rows",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.wrapElements(dom.SectionRows(node))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	rt.installRowMethods(obj, elem)
+}
+
+// installRowMethods adds insertRow and deleteRow, shared by tables and
+// table sections (WHATWG 4.9.1, 4.9.5).
+func (rt *JSRuntime) installRowMethods(obj *goja.Object, elem *Element) {
+	node := elem.node
+	// insertRow(index) - creates a new tr at index; -1 or omitted appends.
+	// Out-of-range returns undefined (spec: IndexSizeError).
+	obj.Set("insertRow", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		index := -1
+		if len(call.Arguments) > 0 {
+			index = int(call.Argument(0).ToInteger())
+		}
+		newRow := dom.InsertRow(node, index)
+		if newRow == nil {
+			return goja.Undefined()
+		}
+		rt.reflow(nil)
+		return rt.wrapElement(newRow)
+	}))
+
+	// deleteRow(index) - removes the tr at index; -1 removes the last row.
+	// Out-of-range does nothing (spec: IndexSizeError).
+	obj.Set("deleteRow", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		index := -1
+		if len(call.Arguments) > 0 {
+			index = int(call.Argument(0).ToInteger())
+		}
+		if dom.DeleteRow(node, index) {
+			rt.reflow(nil)
+		}
+		return goja.Undefined()
+	}))
+}
+
+// installTableRow adds HTMLTableRowElement (WHATWG 4.9.8).
+func (rt *JSRuntime) installTableRow(obj *goja.Object, elem *Element) {
+	node := elem.node
+	// tr.cells - returns HTMLCollection of td/th elements in document order
+	obj.DefineAccessorProperty("cells",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.wrapElements(dom.Cells(node))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// tr.rowIndex - returns the position of the row in the table's rows collection, or -1
+	obj.DefineAccessorProperty("rowIndex",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(dom.RowIndex(node))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// tr.sectionRowIndex - position of the row within its parent section
+	obj.DefineAccessorProperty("sectionRowIndex",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(dom.SectionRowIndex(node))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// tr.insertCell(index) - inserts a new td cell at the given index, returns the new cell
+	obj.Set("insertCell", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		index := -1
+		if len(call.Arguments) > 0 {
+			index = int(call.Argument(0).ToInteger())
+		}
+		newCell := dom.InsertCell(node, index)
+		if newCell == nil {
+			return goja.Undefined()
+		}
+		rt.reflow(nil)
+		return rt.wrapElement(newCell)
+	}))
+
+	// tr.deleteCell(index) - removes the cell at the given index
+	obj.Set("deleteCell", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		index := -1
+		if len(call.Arguments) > 0 {
+			index = int(call.Argument(0).ToInteger())
+		}
+		if dom.DeleteCell(node, index) {
+			rt.reflow(nil)
+		}
+		return goja.Undefined()
+	}))
+}
+
+// installTableCell adds HTMLTableCellElement for td and th (WHATWG 4.9.11).
+func (rt *JSRuntime) installTableCell(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("colSpan",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			colspanAttr := node.Attributes["colspan"]
+			if colspanAttr == "" {
+				return rt.vm.ToValue(1)
+			}
+			colspan, err := strconv.Atoi(colspanAttr)
+			if err != nil {
+				return rt.vm.ToValue(1)
+			}
+
+			if colspan < 1 {
+				return rt.vm.ToValue(1)
+			}
+
+			return rt.vm.ToValue(colspan)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				v, err := strconv.Atoi(call.Arguments[0].String())
+				if err == nil {
+					if v < 1 {
+						v = 1
+					} else if v > 1000 {
+						v = 1000
+					}
+					node.Attributes["colspan"] = strconv.Itoa(v)
+				}
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("rowSpan",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			rowspanAttr := node.Attributes["rowspan"]
+			if rowspanAttr == "" {
+				return rt.vm.ToValue(1)
+			}
+			rowspan, err := strconv.Atoi(rowspanAttr)
+			if err != nil {
+				return rt.vm.ToValue(1)
+			}
+			return rt.vm.ToValue(rowspan)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				v, err := strconv.Atoi(call.Arguments[0].String())
+				if err == nil {
+					if v < 0 {
+						v = 0
+					} else if v > 65534 {
+						v = 65534
+					}
+					node.Attributes["rowspan"] = strconv.Itoa(v)
+				}
+				rt.reflow(nil)
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("cellIndex",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(dom.CellIndex(node))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.DefineAccessorProperty("headers",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			headersAttr := node.Attributes["headers"]
+			if headersAttr == "" {
+				return rt.vm.ToValue("")
+			}
+			return rt.vm.ToValue(headersAttr)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				node.Attributes["headers"] = call.Arguments[0].String()
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// scope - enumerated attribute, limited to known values per WHATWG 4.9.11
+	// valid values: "row", "col", "rowgroup", "colgroup"; invalid/missing → ""
+	obj.DefineAccessorProperty("scope",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			switch node.Attributes["scope"] {
+			case "row", "col", "rowgroup", "colgroup":
+				return rt.vm.ToValue(node.Attributes["scope"])
+			default:
+				return rt.vm.ToValue("")
+			}
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				node.Attributes["scope"] = call.Arguments[0].String()
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// installTableCol adds HTMLTableColElement for col and colgroup (WHATWG 4.9.3-4.9.4).
+func (rt *JSRuntime) installTableCol(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("span",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			spanAttr := node.Attributes["span"]
+			if spanAttr == "" {
+				return rt.vm.ToValue(1)
+			}
+			v, err := strconv.Atoi(spanAttr)
+			if err != nil || v < 1 {
+				return rt.vm.ToValue(1)
+			}
+			if v > 1000 {
+				return rt.vm.ToValue(1000)
+			}
+			return rt.vm.ToValue(v)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				v, err := strconv.Atoi(call.Arguments[0].String())
+				if err == nil {
+					if v < 1 {
+						v = 1
+					} else if v > 1000 {
+						v = 1000
+					}
+					node.Attributes["span"] = strconv.Itoa(v)
+				}
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
@@ -0,0 +1,97 @@
+package js
+
+import (
+	"browser/dom"
+	"net/url"
+
+	"github.com/dop251/goja"
+)
+
+// installQuote adds HTMLQuoteElement.cite (WHATWG 4.4.4, 4.5.7).
+func (rt *JSRuntime) installQuote(obj *goja.Object, elem *Element) {
+	rt.installCite(obj, elem)
+}
+
+// installMod adds HTMLModElement: cite and dateTime (WHATWG 4.7.1, 4.7.2).
+func (rt *JSRuntime) installMod(obj *goja.Object, elem *Element) {
+	rt.installCite(obj, elem)
+	rt.installDateTime(obj, elem)
+}
+
+// installTime adds HTMLTimeElement.dateTime (WHATWG 4.5.14).
+func (rt *JSRuntime) installTime(obj *goja.Object, elem *Element) {
+	rt.installDateTime(obj, elem)
+}
+
+// installData adds HTMLDataElement.value (WHATWG 4.5.13).
+func (rt *JSRuntime) installData(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("value",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if val, ok := node.Attributes["value"]; ok {
+				return rt.vm.ToValue(val)
+			}
+			return rt.vm.ToValue("")
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				node.Attributes["value"] = call.Arguments[0].String()
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// installCite adds the cite URL attribute shared by quote and mod elements.
+func (rt *JSRuntime) installCite(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("cite",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			cite := node.Attributes["cite"]
+			if cite == "" {
+				return goja.Undefined()
+			}
+
+			// Resolve URL relative to document base
+			baseHref := dom.FindBaseHref(rt.document)
+			if baseHref != "" {
+				baseURL, err := url.Parse(baseHref)
+				if err == nil {
+					refURL, err := url.Parse(cite)
+					if err == nil {
+						resolved := baseURL.ResolveReference(refURL)
+						return rt.vm.ToValue(resolved.String())
+					}
+				}
+			}
+
+			return rt.vm.ToValue(cite)
+
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				node.Attributes["cite"] = call.Arguments[0].String()
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// installDateTime adds the datetime attribute shared by time and mod elements.
+func (rt *JSRuntime) installDateTime(obj *goja.Object, elem *Element) {
+	node := elem.node
+	obj.DefineAccessorProperty("dateTime",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if val, ok := node.Attributes["datetime"]; ok {
+				return rt.vm.ToValue(val)
+			}
+			return rt.vm.ToValue("")
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if len(call.Arguments) > 0 {
+				node.Attributes["datetime"] = call.Arguments[0].String()
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
@@ -0,0 +1,91 @@
+package js
+
+import (
+	"browser/dom"
+
+	"github.com/dop251/goja"
+)
+
+// elementInterface is a DOM interface exposed to script. Each interface
+// gets a global constructor whose prototype chains to its parent's, so
+// wrappers support instanceof; install adds the interface's own properties
+// and methods to a wrapper. Interfaces are listed after their parents.
+type elementInterface struct {
+	name    string
+	parent  string
+	tags    []string // HTML elements implementing the interface
+	install func(rt *JSRuntime, obj *goja.Object, elem *Element)
+}
+
+var elementInterfaces = []elementInterface{
+	{name: "Node"},
+	{name: "CharacterData", parent: "Node"},
+	{name: "Text", parent: "CharacterData"},
+	{name: "Element", parent: "Node"},
+	{name: "HTMLElement", parent: "Element"},
+
+	{name: "HTMLAnchorElement", parent: "HTMLElement", tags: []string{dom.TagA}, install: (*JSRuntime).installAnchor},
+	{name: "HTMLCanvasElement", parent: "HTMLElement", tags: []string{dom.TagCanvas}, install: (*JSRuntime).installCanvas},
+	{name: "HTMLDataElement", parent: "HTMLElement", tags: []string{dom.TagData}, install: (*JSRuntime).installData},
+	{name: "HTMLDialogElement", parent: "HTMLElement", tags: []string{dom.TagDialog}, install: (*JSRuntime).installDialog},
+	{name: "HTMLImageElement", parent: "HTMLElement", tags: []string{dom.TagImg}, install: (*JSRuntime).installImage},
+	{name: "HTMLModElement", parent: "HTMLElement", tags: []string{dom.TagIns, dom.TagDel}, install: (*JSRuntime).installMod},
+	{name: "HTMLOListElement", parent: "HTMLElement", tags: []string{dom.TagOL}, install: (*JSRuntime).installOList},
+	{name: "HTMLQuoteElement", parent: "HTMLElement", tags: []string{dom.TagBlockquote, dom.TagQ}, install: (*JSRuntime).installQuote},
+	{name: "HTMLStyleElement", parent: "HTMLElement", tags: []string{"style"}, install: (*JSRuntime).installStyle},
+	{name: "HTMLTableCellElement", parent: "HTMLElement", tags: []string{dom.TagTD, dom.TagTH}, install: (*JSRuntime).installTableCell},
+	{name: "HTMLTableColElement", parent: "HTMLElement", tags: []string{dom.TagCol, dom.TagColgroup}, install: (*JSRuntime).installTableCol},
+	{name: "HTMLTableElement", parent: "HTMLElement", tags: []string{dom.TagTable}, install: (*JSRuntime).installTable},
+	{name: "HTMLTableRowElement", parent: "HTMLElement", tags: []string{dom.TagTR}, install: (*JSRuntime).installTableRow},
+	{name: "HTMLTableSectionElement", parent: "HTMLElement", tags: []string{dom.TagTHead, dom.TagTBody, dom.TagTFoot}, install: (*JSRuntime).installTableSection},
+	{name: "HTMLTimeElement", parent: "HTMLElement", tags: []string{dom.TagTime}, install: (*JSRuntime).installTime},
+	{name: "HTMLTitleElement", parent: "HTMLElement", tags: []string{dom.TagTitle}, install: (*JSRuntime).installTitle},
+}
+
+// interfacesByTag maps a tag name to the interface its elements implement.
+var interfacesByTag = map[string]*elementInterface{}
+
+func init() {
+	for i := range elementInterfaces {
+		for _, tag := range elementInterfaces[i].tags {
+			interfacesByTag[tag] = &elementInterfaces[i]
+		}
+	}
+}
+
+// interfaceName returns the name of the most derived interface node implements.
+func interfaceName(node *dom.Node) string {
+	if node.Type == dom.Text {
+		return "Text"
+	}
+	if iface, ok := interfacesByTag[node.TagName]; ok {
+		return iface.name
+	}
+	return "HTMLElement"
+}
+
+// setupInterfaces defines a global constructor for every interface. Script
+// cannot construct elements directly, so each constructor throws.
+func (rt *JSRuntime) setupInterfaces() {
+	rt.prototypes = make(map[string]*goja.Object, len(elementInterfaces))
+	for _, iface := range elementInterfaces {
+		ctor := rt.vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+			panic(rt.vm.NewTypeError("Illegal constructor"))
+		}).(*goja.Object)
+		proto := ctor.Get("prototype").ToObject(rt.vm)
+		if parent, ok := rt.prototypes[iface.parent]; ok {
+			proto.SetPrototype(parent)
+		}
+		rt.prototypes[iface.name] = proto
+		rt.vm.Set(iface.name, ctor)
+	}
+}
+
+// installInterface adds node's interface-specific API to its wrapper and
+// links the wrapper to the interface prototype.
+func (rt *JSRuntime) installInterface(obj *goja.Object, elem *Element) {
+	if iface, ok := interfacesByTag[elem.node.TagName]; ok && elem.node.Type == dom.Element {
+		iface.install(rt, obj, elem)
+	}
+	obj.SetPrototype(rt.prototypes[interfaceName(elem.node)])
+}
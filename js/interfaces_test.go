@@ -0,0 +1,42 @@
+package js
+
+import (
+	"browser/dom"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElementInterfaces(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<html><body><a id="link" href="/x">x</a><table id="t"><tr id="r"><td id="c">1</td></tr></table><div id="d">text</div></body></html>`))
+	rt := NewJSRuntime(doc, nil)
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected bool
+	}{
+		{"anchor is its interface", `document.getElementById("link") instanceof HTMLAnchorElement`, true},
+		{"anchor inherits HTMLElement", `document.getElementById("link") instanceof HTMLElement`, true},
+		{"anchor inherits Node", `document.getElementById("link") instanceof Node`, true},
+		{"anchor is not a table", `document.getElementById("link") instanceof HTMLTableElement`, false},
+		{"table", `document.getElementById("t") instanceof HTMLTableElement`, true},
+		{"row", `document.getElementById("r") instanceof HTMLTableRowElement`, true},
+		{"cell", `document.getElementById("c") instanceof HTMLTableCellElement`, true},
+		{"implicit tbody", `document.getElementById("r").parentElement instanceof HTMLTableSectionElement`, true},
+		{"unknown tag is HTMLElement", `document.getElementById("d") instanceof HTMLElement`, true},
+		{"div has no anchor API", `document.getElementById("d").protocol === undefined`, true},
+		{"text node", `document.createTextNode("x") instanceof Text && !(document.createTextNode("x") instanceof Element)`, true},
+		{"created element", `document.createElement("img") instanceof HTMLImageElement`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, rt.Execute("var result = "+tt.expr+";"))
+			assert.Equal(t, tt.expected, rt.vm.Get("result").ToBoolean())
+		})
+	}
+
+	assert.Error(t, rt.Execute(`new HTMLElement();`), "interfaces cannot be constructed")
+}
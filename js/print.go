@@ -0,0 +1,58 @@
+package js
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// Printing (HTML §8.9.1.6). window.print() has the host print the
+// document, firing beforeprint at window first, so the page can adjust
+// itself, and afterprint once the document has been captured. The host's
+// own print command goes through Print, which fires the same events.
+
+// SetPrintHandler sets the callback that prints the document.
+func (rt *JSRuntime) SetPrintHandler(handler func()) {
+	rt.onPrint = handler
+}
+
+// Print prints the document at the host's request, then reflows if a
+// print listener ran.
+func (rt *JSRuntime) Print() {
+	rt.vmMu.Lock()
+	ran := rt.printLocked()
+	rt.vmMu.Unlock()
+	if ran {
+		rt.reflow(nil)
+	}
+}
+
+// windowPrint implements window.print. Like alert, it is dropped while
+// dialogs are suppressed.
+func (rt *JSRuntime) windowPrint(goja.FunctionCall) goja.Value {
+	if rt.allowDialog() && rt.printLocked() {
+		rt.reflow(nil)
+	}
+	return goja.Undefined()
+}
+
+// printLocked fires beforeprint, prints and fires afterprint. It reports
+// whether any listener ran.
+func (rt *JSRuntime) printLocked() bool {
+	ran := rt.firePrintEvent("beforeprint")
+	if rt.onPrint != nil {
+		rt.onPrint()
+	}
+	return rt.firePrintEvent("afterprint") || ran
+}
+
+// firePrintEvent calls window's listeners for eventType.
+func (rt *JSRuntime) firePrintEvent(eventType string) bool {
+	listeners := rt.printListeners[eventType]
+	for _, listener := range listeners {
+		if _, err := listener(goja.Undefined()); err != nil {
+			fmt.Println(eventType, "listener error:", err)
+		}
+	}
+	return len(listeners) > 0
+}
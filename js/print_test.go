@@ -0,0 +1,31 @@
+package js
+
+import (
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowPrint(t *testing.T) {
+	reflows := 0
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, func(*dom.Node) { reflows++ })
+	var seen []string
+	rt.SetPrintHandler(func() { seen = append(seen, "print") })
+	assert.NoError(t, rt.Execute(`
+		var events = [];
+		window.addEventListener("afterprint", function() { events.push("after") });
+		window.addEventListener("beforeprint", function() { events.push("before") });
+		window.print();
+		var order = events.join();
+	`))
+	assert.Equal(t, []string{"print"}, seen)
+	assert.Equal(t, "before,after", rt.vm.Get("order").String())
+	assert.Equal(t, 1, reflows, "listeners may have changed the page")
+
+	rt.Print()
+	assert.Equal(t, []string{"print", "print"}, seen, "the host's print command")
+	assert.NoError(t, rt.Execute("order = events.join()"))
+	assert.Equal(t, "before,after,before,after", rt.vm.Get("order").String())
+}
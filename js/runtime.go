@@ -4,9 +4,9 @@ import (
 	"browser/dom"
 	"fmt"
 	"net/url"
-	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dop251/goja"
@@ -16,82 +16,62 @@ type JSRuntime struct {
 	vm                  *goja.Runtime
 	vmMu                sync.Mutex
 	document            *dom.Node
-	onReflow            func()
+	onReflow            func(changed *dom.Node)
 	onAlert             func(message string)
 	Events              *EventManager
 	onConfirm           func(string) bool
+	onConfirmLeave      func(string) bool
 	currentURL          string
 	onReload            func()
 	onPrompt            func(message, defaultValue string) *string
 	elementCache        map[*dom.Node]*goja.Object
+	prototypes          map[string]*goja.Object // interface name → prototype
 	onTitleChange       func(string)
 	beforeUnloadHandler goja.Callable
 	onLoadHandler       goja.Callable
 	windowLoadListeners []goja.Callable
+	scrollListeners     []goja.Callable // window's scroll listeners
+	printListeners      map[string][]goja.Callable
+	scrollPosition      func() (x, y float64)
+	onScroll            func(x, y float64, smooth bool)
+	onPrint             func()
 	timerMu             sync.Mutex
 	nextTimerID         int64
-	timers              map[int64]*time.Timer
+	timers              map[int64]stopper
+	onOpenWindow        func(url string)
+	onPopupBlocked      func(url string)
+	popupsAllowed       func(pageURL string) bool
+	frames              animationFrames
+	onFrameRequest      func()
+	timeOrigin          time.Time
+	clock               clock
+	lastFrame           time.Time   // last animation frame under virtual time
+	reflowsDeferred     atomic.Bool // DOM changes mark reflowDue instead of reflowing (see deferReflows)
+	reflowDue           atomic.Bool // a change was made while reflows were deferred
 }
 
-// collectTableRows returns all tr elements in a table node in WHATWG 4.9.1 order:
-// thead rows first, then tbody/direct tr rows in tree order, then tfoot rows.
-// collectSectionRows returns all direct tr children of a tbody/thead/tfoot node.
-func collectSectionRows(sectionNode *dom.Node) []*dom.Node {
-	var rows []*dom.Node
-	for _, child := range sectionNode.Children {
-		if child.Type == dom.Element && child.TagName == "tr" {
-			rows = append(rows, child)
-		}
-	}
-	return rows
-}
-
-func collectTableRows(tableNode *dom.Node) []*dom.Node {
-	var rows []*dom.Node
-	collectTRs := func(section *dom.Node) {
-		for _, child := range section.Children {
-			if child.Type == dom.Element && child.TagName == "tr" {
-				rows = append(rows, child)
-			}
-		}
-	}
-	for _, child := range tableNode.Children {
-		if child.Type == dom.Element && child.TagName == "thead" {
-			collectTRs(child)
-		}
-	}
-	for _, child := range tableNode.Children {
-		if child.Type == dom.Element {
-			switch child.TagName {
-			case "tbody":
-				collectTRs(child)
-			case "tr":
-				rows = append(rows, child)
-			}
-		}
-	}
-	for _, child := range tableNode.Children {
-		if child.Type == dom.Element && child.TagName == "tfoot" {
-			collectTRs(child)
-		}
-	}
-	return rows
-}
-
-func NewJSRuntime(document *dom.Node, onReflow func()) *JSRuntime {
+// NewJSRuntime returns a runtime for scripts of document. onReflow is
+// called after scripts change the DOM with the node whose subtree changed,
+// or nil when the change may be anywhere in the document.
+func NewJSRuntime(document *dom.Node, onReflow func(changed *dom.Node)) *JSRuntime {
 	rt := &JSRuntime{
-		vm:           goja.New(),
-		document:     document,
-		onReflow:     onReflow,
-		Events:       NewEventManager(),
-		elementCache: make(map[*dom.Node]*goja.Object),
-		timers:       make(map[int64]*time.Timer),
+		vm:             goja.New(),
+		document:       document,
+		onReflow:       onReflow,
+		Events:         NewEventManager(),
+		elementCache:   make(map[*dom.Node]*goja.Object),
+		timers:         make(map[int64]stopper),
+		printListeners: make(map[string][]goja.Callable),
+		timeOrigin:     time.Now(),
+		clock:          wallClock{},
 	}
 	rt.setupGlobals()
 	return rt
 }
 
 func (rt *JSRuntime) setupGlobals() {
+	rt.setupInterfaces()
+
 	console := rt.vm.NewObject()
 	console.Set("log", func(call goja.FunctionCall) goja.Value {
 		for _, arg := range call.Arguments {
@@ -177,9 +157,7 @@ func (rt *JSRuntime) setupGlobals() {
 						rt.document.AppendChild(newBodyNode)
 					}
 				}
-				if rt.onReflow != nil {
-					rt.onReflow()
-				}
+				rt.reflow(nil)
 			}
 
 			return goja.Undefined()
@@ -197,14 +175,17 @@ func (rt *JSRuntime) setupGlobals() {
 		nil,
 		goja.FLAG_FALSE, goja.FLAG_TRUE)
 
+	rt.installDocumentCookie(docObj)
+
 	rt.vm.Set("document", docObj)
+	rt.vm.Set("NodeFilter", rt.newNodeFilter())
 
 	rt.vm.Set("alert", func(call goja.FunctionCall) goja.Value {
 		message := ""
 		if len(call.Arguments) > 0 {
 			message = call.Arguments[0].String()
 		}
-		if rt.onAlert != nil {
+		if rt.onAlert != nil && rt.allowDialog() {
 			rt.onAlert(message)
 		}
 
@@ -218,7 +199,7 @@ func (rt *JSRuntime) setupGlobals() {
 		}
 
 		result := false
-		if rt.onConfirm != nil {
+		if rt.onConfirm != nil && rt.allowDialog() {
 			result = rt.onConfirm(message)
 		}
 
@@ -237,7 +218,7 @@ func (rt *JSRuntime) setupGlobals() {
 			defaultValue = call.Arguments[1].String()
 		}
 
-		if rt.onPrompt != nil {
+		if rt.onPrompt != nil && rt.allowDialog() {
 			result := rt.onPrompt(message, defaultValue)
 			if result == nil {
 				return goja.Null()
@@ -258,6 +239,8 @@ func (rt *JSRuntime) setupGlobals() {
 		return rt.wrapElement(newNode)
 	})
 
+	docObj.Set("createTreeWalker", rt.createTreeWalker(docObj))
+
 	docObj.Set("createTextNode", func(call goja.FunctionCall) goja.Value {
 		text := ""
 		if len(call.Arguments) > 0 {
@@ -329,8 +312,13 @@ func (rt *JSRuntime) setupGlobals() {
 		if !ok {
 			return goja.Undefined()
 		}
-		if eventType == "load" {
+		switch eventType {
+		case "load":
 			rt.windowLoadListeners = append(rt.windowLoadListeners, callback)
+		case "scroll":
+			rt.scrollListeners = append(rt.scrollListeners, callback)
+		case "beforeprint", "afterprint":
+			rt.printListeners[eventType] = append(rt.printListeners[eventType], callback)
 		}
 		return goja.Undefined()
 	})
@@ -359,7 +347,7 @@ func (rt *JSRuntime) setupGlobals() {
 		rt.timerMu.Unlock()
 
 		delay := time.Duration(milliseconds) * time.Millisecond
-		timer := time.AfterFunc(delay, func() {
+		timer := rt.clock.AfterFunc(delay, func() {
 			defer func() {
 				if recovered := recover(); recovered != nil {
 					fmt.Println("setTimeout callback panic:", recovered)
@@ -372,9 +360,7 @@ func (rt *JSRuntime) setupGlobals() {
 			if err != nil {
 				fmt.Println("setTimeout callback error:", err)
 			}
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
+			rt.reflow(nil)
 			rt.timerMu.Lock()
 			delete(rt.timers, timerID)
 			rt.timerMu.Unlock()
@@ -404,11 +390,57 @@ func (rt *JSRuntime) setupGlobals() {
 		return goja.Undefined()
 	})
 
+	window.Set("open", rt.windowOpen)
+	window.Set("requestAnimationFrame", rt.requestAnimationFrame)
+	window.Set("cancelAnimationFrame", rt.cancelAnimationFrame)
+	rt.setupScroll(window)
+	window.Set("print", rt.windowPrint)
+	rt.setupNetwork(window)
+
+	localStorage := rt.newLocalStorage()
+	window.Set("localStorage", localStorage)
+
 	rt.vm.Set("window", window)
+	rt.vm.Set("localStorage", localStorage)
 
+	rt.vm.Set("open", window.Get("open"))
 	rt.vm.Set("setTimeout", window.Get("setTimeout"))
 	rt.vm.Set("clearTimeout", window.Get("clearTimeout"))
+	rt.vm.Set("requestAnimationFrame", window.Get("requestAnimationFrame"))
+	rt.vm.Set("cancelAnimationFrame", window.Get("cancelAnimationFrame"))
+	rt.vm.Set("scrollTo", window.Get("scrollTo"))
+	rt.vm.Set("scrollBy", window.Get("scrollBy"))
+	rt.vm.Set("print", window.Get("print"))
+
+}
+
+// reflow tells the browser that node and its subtree changed; nil means
+// the change may be anywhere in the document.
+func (rt *JSRuntime) reflow(node *dom.Node) {
+	if rt != nil && rt.reflowsDeferred.Load() {
+		rt.reflowDue.Store(true)
+		return
+	}
+	if rt != nil && rt.onReflow != nil {
+		rt.onReflow(node)
+	}
+}
 
+// deferReflows runs fn, which calls into scripts, under the VM lock,
+// holding back the reflows the DOM and style changes it makes ask for. The
+// page reflows once afterwards if any did, so listeners for frequent events
+// that only read cost no layout.
+func (rt *JSRuntime) deferReflows(fn func()) {
+	rt.vmMu.Lock()
+	rt.reflowsDeferred.Store(true)
+	defer func() {
+		rt.reflowsDeferred.Store(false)
+		rt.vmMu.Unlock()
+		if rt.reflowDue.Swap(false) {
+			rt.reflow(nil)
+		}
+	}()
+	fn()
 }
 
 func (rt *JSRuntime) Execute(code string) error {
@@ -426,30 +458,34 @@ func (rt *JSRuntime) executeLocked(code string) error {
 	return err
 }
 
-// FindScripts extracts JavaScript code from <script> tags
-func FindScripts(node *dom.Node) []string {
-	var scripts []string
-	findScriptsRecursive(node, &scripts)
-	return scripts
+// Script is a <script> element's code: inline Code, or the file at Src to
+// fetch with the element's ReferrerPolicy.
+type Script struct {
+	Src            string
+	Code           string
+	ReferrerPolicy string
 }
 
-func findScriptsRecursive(node *dom.Node, scripts *[]string) {
-	if node == nil {
-		return
-	}
-
-	if node.Type == dom.Element && node.TagName == "script" {
+// FindScripts extracts JavaScript code from <script> tags, in document
+// order. An element with a src runs the file, ignoring its content.
+func FindScripts(node *dom.Node) []Script {
+	var scripts []Script
+	for script := range dom.Walk(node) {
+		if script.Type != dom.Element || script.TagName != "script" {
+			continue
+		}
+		if src := strings.TrimSpace(script.Attributes["src"]); src != "" {
+			scripts = append(scripts, Script{Src: src, ReferrerPolicy: script.Attributes["referrerpolicy"]})
+			continue
+		}
 		// Get inline script content
-		for _, child := range node.Children {
+		for _, child := range script.Children {
 			if child.Type == dom.Text && child.Text != "" {
-				*scripts = append(*scripts, child.Text)
+				scripts = append(scripts, Script{Code: child.Text})
 			}
 		}
 	}
-
-	for _, child := range node.Children {
-		findScriptsRecursive(child, scripts)
-	}
+	return scripts
 }
 
 func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
@@ -490,9 +526,7 @@ func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
 		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
 			if len(call.Arguments) > 0 {
 				elem.SetTextContent(call.Arguments[0].String())
-				if rt.onReflow != nil {
-					rt.onReflow()
-				}
+				rt.reflow(node)
 			}
 			return goja.Undefined()
 		}),
@@ -558,9 +592,7 @@ func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
 		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
 			if len(call.Arguments) > 0 {
 				node.SetInnerText(call.Arguments[0].String())
-				if rt.onReflow != nil {
-					rt.onReflow()
-				}
+				rt.reflow(node)
 			}
 			return goja.Undefined()
 		}),
@@ -607,1080 +639,12 @@ func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
 					node.Attributes = make(map[string]string)
 				}
 				node.Attributes["class"] = call.Arguments[0].String()
-				if rt.onReflow != nil {
-					rt.onReflow()
-				}
+				rt.reflow(node)
 			}
 			return goja.Undefined()
 		}),
 		goja.FLAG_FALSE, goja.FLAG_TRUE)
 
-	if tagName == "TITLE" {
-		obj.DefineAccessorProperty("text",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(elem.GetTextContent())
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					elem.SetTextContent(call.Arguments[0].String())
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	if tagName == "A" {
-		relList := dom.NewDOMTokenList(node, "rel")
-		relListObj := rt.vm.NewObject()
-
-		relListObj.DefineAccessorProperty("length",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(relList.Length())
-			}),
-			nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		relListObj.Set("item", rt.vm.ToValue(func(index int) string {
-			return relList.Item(index)
-		}))
-
-		relListObj.Set("contains", rt.vm.ToValue(func(token string) bool {
-			return relList.Contains(token)
-		}))
-
-		relListObj.Set("add", rt.vm.ToValue(func(token string) {
-			relList.Add(token)
-		}))
-
-		relListObj.Set("remove", rt.vm.ToValue(func(token string) {
-			relList.Remove(token)
-		}))
-
-		relListObj.Set("toggle", rt.vm.ToValue(func(token string) bool {
-			return relList.Toggle(token)
-		}))
-
-		obj.Set("relList", relListObj)
-
-		// .text property (alias for innerText)
-		obj.DefineAccessorProperty("text",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(elem.GetTextContent())
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					elem.SetTextContent(call.Arguments[0].String())
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// Helper to parse resolved href
-		getURL := func() *url.URL {
-			href := node.Attributes["href"]
-			if href == "" {
-				return nil
-			}
-			parsed, err := url.Parse(href)
-			if err != nil {
-				return nil
-			}
-			if !parsed.IsAbs() {
-				baseHref := dom.FindBaseHref(rt.document)
-				if baseHref != "" {
-					baseURL, err := url.Parse(baseHref)
-					if err == nil {
-						parsed = baseURL.ResolveReference(parsed)
-					}
-				}
-			}
-			return parsed
-		}
-
-		obj.DefineAccessorProperty("protocol",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil {
-					return rt.vm.ToValue(u.Scheme + ":")
-				}
-				return rt.vm.ToValue(":")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("username",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil && u.User != nil {
-					return rt.vm.ToValue(u.User.Username())
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("password",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil && u.User != nil {
-					pass, _ := u.User.Password()
-					return rt.vm.ToValue(pass)
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("host",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil {
-					return rt.vm.ToValue(u.Host)
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("hostname",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil {
-					return rt.vm.ToValue(u.Hostname())
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("port",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil {
-					return rt.vm.ToValue(u.Port())
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("pathname",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil {
-					return rt.vm.ToValue(u.Path)
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("search",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil && u.RawQuery != "" {
-					return rt.vm.ToValue("?" + u.RawQuery)
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("hash",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil && u.Fragment != "" {
-					return rt.vm.ToValue("#" + u.Fragment)
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("origin",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if u := getURL(); u != nil {
-					return rt.vm.ToValue(u.Scheme + "://" + u.Host)
-				}
-				return rt.vm.ToValue("")
-			}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	if tagName == "BLOCKQUOTE" || tagName == "Q" ||
-		tagName == "INS" || tagName == "DEL" {
-		obj.DefineAccessorProperty("cite",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				cite := node.Attributes["cite"]
-				if cite == "" {
-					return goja.Undefined()
-				}
-
-				// Resolve URL relative to document base
-				baseHref := dom.FindBaseHref(rt.document)
-				if baseHref != "" {
-					baseURL, err := url.Parse(baseHref)
-					if err == nil {
-						refURL, err := url.Parse(cite)
-						if err == nil {
-							resolved := baseURL.ResolveReference(refURL)
-							return rt.vm.ToValue(resolved.String())
-						}
-					}
-				}
-
-				return rt.vm.ToValue(cite)
-
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Attributes["cite"] = call.Arguments[0].String()
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	// HTMLTableElement.caption property (WHATWG 4.9.1)
-	if tagName == "TABLE" {
-		obj.DefineAccessorProperty("caption",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				// Return first caption child, or null
-				for _, child := range node.Children {
-					if child.Type == dom.Element && child.TagName == "caption" {
-						return rt.wrapElement(child)
-					}
-				}
-				return goja.Null()
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					// Remove existing caption first
-					for _, child := range node.Children {
-						if child.Type == dom.Element && child.TagName == "caption" {
-							node.RemoveChild(child)
-							break
-						}
-					}
-
-					// If new value is not null, insert as first child
-					if !goja.IsNull(call.Arguments[0]) && !goja.IsUndefined(call.Arguments[0]) {
-						newCaption := unwrapNode(rt, call.Arguments[0])
-						if newCaption != nil {
-							newCaption.Parent = node
-							node.Children = append([]*dom.Node{newCaption}, node.Children...)
-						}
-					}
-
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// HTMLTableElement.createCaption() (WHATWG 4.9.1)
-		obj.Set("createCaption", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			// Return existing caption if one exists
-			for _, child := range node.Children {
-				if child.Type == dom.Element && child.TagName == "caption" {
-					return rt.wrapElement(child)
-				}
-			}
-			// Create new caption and insert as first child
-			newCaption := dom.NewElement("caption", map[string]string{})
-			newCaption.Parent = node
-			node.Children = append([]*dom.Node{newCaption}, node.Children...)
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
-			return rt.wrapElement(newCaption)
-		}))
-
-		// HTMLTableElement.deleteCaption() (WHATWG 4.9.1)
-		obj.Set("deleteCaption", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			for _, child := range node.Children {
-				if child.Type == dom.Element && child.TagName == "caption" {
-					node.RemoveChild(child)
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-					break
-				}
-			}
-			return goja.Undefined()
-		}))
-
-		obj.DefineAccessorProperty("tHead",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				for _, child := range node.Children {
-					if child.Type == dom.Element && child.TagName == "thead" {
-						return rt.wrapElement(child)
-					}
-				}
-				return goja.Null()
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					// Remove existing thead first
-					for _, child := range node.Children {
-						if child.Type == dom.Element && child.TagName == "thead" {
-							node.RemoveChild(child)
-							break
-						}
-					}
-
-					// Find insertion index: after all caption and colgroup elements
-					insertIdx := 0
-					for _, child := range node.Children {
-						if child.Type == dom.Element && (child.TagName == "caption" || child.TagName == "colgroup") {
-							insertIdx++
-						} else {
-							break
-						}
-					}
-
-					// If new value is not null, insert at computed index
-					if !goja.IsNull(call.Arguments[0]) && !goja.IsUndefined(call.Arguments[0]) {
-						newTHead := unwrapNode(rt, call.Arguments[0])
-						if newTHead != nil {
-							newTHead.Parent = node
-							node.Children = append(
-								node.Children[:insertIdx],
-								append([]*dom.Node{newTHead}, node.Children[insertIdx:]...)...)
-						}
-					}
-
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// HTMLTableElement.createTHead() (WHATWG 4.9.1)
-		obj.Set("createTHead", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			// Return existing thead if one exists
-			for _, child := range node.Children {
-				if child.Type == dom.Element && child.TagName == "thead" {
-					return rt.wrapElement(child)
-				}
-			}
-			// Create new thead and insert after caption/colgroup
-			newTHead := dom.NewElement("thead", map[string]string{})
-			newTHead.Parent = node
-			insertIdx := 0
-			for _, child := range node.Children {
-				if child.Type == dom.Element && (child.TagName == "caption" || child.TagName == "colgroup") {
-					insertIdx++
-				} else {
-					break
-				}
-			}
-			node.Children = append(
-				node.Children[:insertIdx],
-				append([]*dom.Node{newTHead}, node.Children[insertIdx:]...)...)
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
-			return rt.wrapElement(newTHead)
-		}))
-
-		// HTMLTableElement.deleteTHead() (WHATWG 4.9.1)
-		obj.Set("deleteTHead", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			for _, child := range node.Children {
-				if child.Type == dom.Element && child.TagName == "thead" {
-					node.RemoveChild(child)
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-					break
-				}
-			}
-			return goja.Undefined()
-		}))
-
-		obj.DefineAccessorProperty("tFoot",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				for _, child := range node.Children {
-					if child.Type == dom.Element && child.TagName == "tfoot" {
-						return rt.wrapElement(child)
-					}
-				}
-				return goja.Null()
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					for _, child := range node.Children {
-						if child.Type == dom.Element && child.TagName == "tfoot" {
-							node.RemoveChild(child)
-							break
-						}
-					}
-
-					if !goja.IsNull(call.Arguments[0]) && !goja.IsUndefined(call.Arguments[0]) {
-						newTFoot := unwrapNode(rt, call.Arguments[0])
-						if newTFoot != nil {
-							newTFoot.Parent = node
-							node.Children = append(node.Children, newTFoot)
-						}
-					}
-
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// HTMLTableElement.createTFoot() (WHATWG 4.9.1)
-		obj.Set("createTFoot", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			// Return existing tfoot if one exists
-			for _, child := range node.Children {
-				if child.Type == dom.Element && child.TagName == "tfoot" {
-					return rt.wrapElement(child)
-				}
-			}
-			// Create new tfoot and append at end
-			newTFoot := dom.NewElement("tfoot", map[string]string{})
-			newTFoot.Parent = node
-			node.Children = append(node.Children, newTFoot)
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
-			return rt.wrapElement(newTFoot)
-		}))
-
-		// HTMLTableElement.deleteTFoot() (WHATWG 4.9.1)
-		obj.Set("deleteTFoot", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			for _, child := range node.Children {
-				if child.Type == dom.Element && child.TagName == "tfoot" {
-					node.RemoveChild(child)
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-					break
-				}
-			}
-			return goja.Undefined()
-		}))
-
-		// HTMLTableElement.tBodies (WHATWG 4.9.1) - returns HTMLCollection of tbody elements
-		obj.DefineAccessorProperty("tBodies",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				var tbodies []any
-				for _, child := range node.Children {
-					if child.Type == dom.Element && child.TagName == "tbody" {
-						tbodies = append(tbodies, rt.wrapElement(child))
-					}
-				}
-				return rt.vm.NewArray(tbodies...)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// HTMLTableElement.rows (WHATWG 4.9.1) - returns HTMLCollection of all tr elements
-		// Order: thead rows first, then tbody/direct tr rows in tree order, then tfoot rows
-		obj.DefineAccessorProperty("rows",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				allRows := collectTableRows(node)
-				var rows []any
-				for _, row := range allRows {
-					rows = append(rows, rt.wrapElement(row))
-				}
-				return rt.vm.NewArray(rows...)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.Set("createTBody", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			newTBody := dom.NewElement("tbody", map[string]string{})
-			newTBody.Parent = node
-
-			insertIdx := len(node.Children)
-			for i := len(node.Children) - 1; i >= 0; i-- {
-				if node.Children[i].Type == dom.Element && node.Children[i].TagName == "tbody" {
-					insertIdx = i + 1
-					break
-				}
-			}
-
-			node.Children = append(
-				node.Children[:insertIdx],
-				append([]*dom.Node{newTBody}, node.Children[insertIdx:]...)...)
-
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
-			return rt.wrapElement(newTBody)
-		}))
-
-		obj.Set("insertRow", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			index := int64(-1)
-			if len(call.Arguments) > 0 {
-				index = call.Argument(0).ToInteger()
-			}
-
-			allRows := collectTableRows(node)
-			newRow := dom.NewElement("tr", map[string]string{})
-
-			if index == -1 || index == int64(len(allRows)) {
-				// Append to parent of last row, or create tbody if no rows
-				if len(allRows) == 0 {
-					newTBody := dom.NewElement("tbody", map[string]string{})
-					newTBody.Parent = node
-					node.Children = append(node.Children, newTBody)
-					newRow.Parent = newTBody
-					newTBody.Children = append(newTBody.Children, newRow)
-				} else {
-					lastRow := allRows[len(allRows)-1]
-					parent := lastRow.Parent
-					newRow.Parent = parent
-					parent.Children = append(parent.Children, newRow)
-				}
-			} else if index >= 0 && index < int64(len(allRows)) {
-				// Insert before the row at the given index
-				targetRow := allRows[index]
-				parent := targetRow.Parent
-				newRow.Parent = parent
-				for i, child := range parent.Children {
-					if child == targetRow {
-						parent.Children = append(
-							parent.Children[:i],
-							append([]*dom.Node{newRow}, parent.Children[i:]...)...)
-						break
-					}
-				}
-			} else {
-				return goja.Undefined()
-			}
-
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
-			return rt.wrapElement(newRow)
-		}))
-
-		obj.Set("deleteRow", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			index := int64(-1)
-			if len(call.Arguments) > 0 {
-				index = call.Argument(0).ToInteger()
-			}
-
-			allRows := collectTableRows(node)
-
-			if index == -1 && len(allRows) > 0 {
-				index = int64(len(allRows) - 1)
-			}
-
-			if index >= 0 && index < int64(len(allRows)) {
-				targetRow := allRows[index]
-				targetRow.Parent.RemoveChild(targetRow)
-				if rt.onReflow != nil {
-					rt.onReflow()
-				}
-				return goja.Undefined()
-			}
-
-			return goja.Undefined()
-		}))
-
-	}
-
-	// HTMLTableSectionElement properties (WHATWG 4.9.5-4.9.7)
-	if tagName == "TBODY" || tagName == "THEAD" || tagName == "TFOOT" {
-		// rows - HTMLCollection of tr elements within this section only
-		obj.DefineAccessorProperty("rows",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				sectionRows := collectSectionRows(node)
-				var rows []any
-				for _, row := range sectionRows {
-					rows = append(rows, rt.wrapElement(row))
-				}
-				return rt.vm.NewArray(rows...)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// insertRow(index) - creates a new tr and inserts it at index within this section.
-		// index -1 or omitted appends at end. Out-of-range returns undefined (spec: IndexSizeError).
-		obj.Set("insertRow", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			index := int64(-1)
-			if len(call.Arguments) > 0 {
-				index = call.Argument(0).ToInteger()
-			}
-
-			sectionRows := collectSectionRows(node)
-			newRow := dom.NewElement("tr", map[string]string{})
-			newRow.Parent = node
-
-			if index == -1 || index == int64(len(sectionRows)) {
-				node.Children = append(node.Children, newRow)
-			} else if index >= 0 && index < int64(len(sectionRows)) {
-				targetRow := sectionRows[index]
-				for i, child := range node.Children {
-					if child == targetRow {
-						node.Children = append(
-							node.Children[:i],
-							append([]*dom.Node{newRow}, node.Children[i:]...)...)
-						break
-					}
-				}
-			} else {
-				return goja.Undefined()
-			}
-
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
-			return rt.wrapElement(newRow)
-		}))
-
-		// deleteRow(index) - removes the tr at index within this section.
-		// index -1 removes the last row. Out-of-range does nothing (spec: IndexSizeError).
-		obj.Set("deleteRow", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			index := int64(-1)
-			if len(call.Arguments) > 0 {
-				index = call.Argument(0).ToInteger()
-			}
-
-			sectionRows := collectSectionRows(node)
-
-			if index == -1 && len(sectionRows) > 0 {
-				index = int64(len(sectionRows) - 1)
-			}
-
-			if index >= 0 && index < int64(len(sectionRows)) {
-				sectionRows[index].Parent.RemoveChild(sectionRows[index])
-				if rt.onReflow != nil {
-					rt.onReflow()
-				}
-			}
-
-			return goja.Undefined()
-		}))
-	}
-
-	// HTMLTableRowElement properties (WHATWG 4.9.8)
-	if tagName == "TR" {
-		// tr.cells - returns HTMLCollection of td/th elements in document order
-		obj.DefineAccessorProperty("cells",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				var cells []any
-				for _, child := range node.Children {
-					if child.Type == dom.Element && (child.TagName == "td" || child.TagName == "th") {
-						cells = append(cells, rt.wrapElement(child))
-					}
-				}
-				return rt.vm.NewArray(cells...)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// tr.rowIndex - returns the position of the row in the table's rows collection, or -1
-		obj.DefineAccessorProperty("rowIndex",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				// Walk up to find the parent <table>
-				var tableNode *dom.Node
-				for p := node.Parent; p != nil; p = p.Parent {
-					if p.Type == dom.Element && p.TagName == "table" {
-						tableNode = p
-						break
-					}
-				}
-				if tableNode == nil {
-					return rt.vm.ToValue(-1)
-				}
-				allRows := collectTableRows(tableNode)
-				for i, row := range allRows {
-					if row == node {
-						return rt.vm.ToValue(i)
-					}
-				}
-				return rt.vm.ToValue(-1)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// tr.sectionRowIndex - position of the row within its parent section
-		obj.DefineAccessorProperty("sectionRowIndex",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				p := node.Parent
-				if p == nil {
-					return rt.vm.ToValue(-1)
-				}
-				indexCount := 0
-				for _, child := range p.Children {
-					if child.Type == dom.Element && child.TagName == "tr" {
-						if child == node {
-							return rt.vm.ToValue(indexCount)
-						}
-						indexCount++
-					}
-				}
-				return rt.vm.ToValue(-1)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// tr.insertCell(index) - inserts a new td cell at the given index, returns the new cell
-		obj.Set("insertCell", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			index := int64(-1)
-			if len(call.Arguments) > 0 {
-				index = call.Argument(0).ToInteger()
-			}
-
-			// Collect current cells (td/th only)
-			var cells []*dom.Node
-			for _, child := range node.Children {
-				if child.Type == dom.Element && (child.TagName == "td" || child.TagName == "th") {
-					cells = append(cells, child)
-				}
-			}
-
-			newCell := dom.NewElement("td", map[string]string{})
-			newCell.Parent = node
-
-			if index == -1 || index == int64(len(cells)) {
-				// Append at end
-				node.Children = append(node.Children, newCell)
-			} else if index >= 0 && index < int64(len(cells)) {
-				// Insert before the cell at the given index
-				targetCell := cells[index]
-				for i, child := range node.Children {
-					if child == targetCell {
-						node.Children = append(
-							node.Children[:i],
-							append([]*dom.Node{newCell}, node.Children[i:]...)...)
-						break
-					}
-				}
-			} else {
-				return goja.Undefined()
-			}
-
-			if rt.onReflow != nil {
-				rt.onReflow()
-			}
-			return rt.wrapElement(newCell)
-		}))
-
-		// tr.deleteCell(index) - removes the cell at the given index
-		obj.Set("deleteCell", rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			index := int64(-1)
-			if len(call.Arguments) > 0 {
-				index = call.Argument(0).ToInteger()
-			}
-
-			// Collect current cells (td/th only)
-			var cells []*dom.Node
-			for _, child := range node.Children {
-				if child.Type == dom.Element && (child.TagName == "td" || child.TagName == "th") {
-					cells = append(cells, child)
-				}
-			}
-
-			if index == -1 && len(cells) > 0 {
-				index = int64(len(cells) - 1)
-			}
-
-			if index >= 0 && index < int64(len(cells)) {
-				node.RemoveChild(cells[index])
-				if rt.onReflow != nil {
-					rt.onReflow()
-				}
-			}
-
-			return goja.Undefined()
-		}))
-	}
-
-	if tagName == "TD" || tagName == "TH" {
-		obj.DefineAccessorProperty("colSpan",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				colspanAttr := node.Attributes["colspan"]
-				if colspanAttr == "" {
-					return rt.vm.ToValue(1)
-				}
-				colspan, err := strconv.Atoi(colspanAttr)
-				if err != nil {
-					return rt.vm.ToValue(1)
-				}
-
-				if colspan < 1 {
-					return rt.vm.ToValue(1)
-				}
-
-				return rt.vm.ToValue(colspan)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					v, err := strconv.Atoi(call.Arguments[0].String())
-					if err == nil {
-						if v < 1 {
-							v = 1
-						} else if v > 1000 {
-							v = 1000
-						}
-						node.Attributes["colspan"] = strconv.Itoa(v)
-					}
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("rowSpan",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				rowspanAttr := node.Attributes["rowspan"]
-				if rowspanAttr == "" {
-					return rt.vm.ToValue(1)
-				}
-				rowspan, err := strconv.Atoi(rowspanAttr)
-				if err != nil {
-					return rt.vm.ToValue(1)
-				}
-				return rt.vm.ToValue(rowspan)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					v, err := strconv.Atoi(call.Arguments[0].String())
-					if err == nil {
-						if v < 0 {
-							v = 0
-						} else if v > 65534 {
-							v = 65534
-						}
-						node.Attributes["rowspan"] = strconv.Itoa(v)
-					}
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("cellIndex",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if node.Parent == nil || (node.Parent.TagName != "tr") {
-					return rt.vm.ToValue(-1)
-				}
-				idx := 0
-				for _, sibling := range node.Parent.Children {
-					if sibling == node {
-						return rt.vm.ToValue(idx)
-					}
-					if sibling.Type == dom.Element && (sibling.TagName == "td" || sibling.TagName == "th") {
-						idx++
-					}
-				}
-				return rt.vm.ToValue(-1)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("headers",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				headersAttr := node.Attributes["headers"]
-				if headersAttr == "" {
-					return rt.vm.ToValue("")
-				}
-				return rt.vm.ToValue(headersAttr)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Attributes["headers"] = call.Arguments[0].String()
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// scope - enumerated attribute, limited to known values per WHATWG 4.9.11
-		// valid values: "row", "col", "rowgroup", "colgroup"; invalid/missing → ""
-		obj.DefineAccessorProperty("scope",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				switch node.Attributes["scope"] {
-				case "row", "col", "rowgroup", "colgroup":
-					return rt.vm.ToValue(node.Attributes["scope"])
-				default:
-					return rt.vm.ToValue("")
-				}
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Attributes["scope"] = call.Arguments[0].String()
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-	}
-
-	if tagName == "OL" {
-		obj.DefineAccessorProperty("start",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				startAttr := node.Attributes["start"]
-				if startAttr == "" {
-					return rt.vm.ToValue(1)
-				}
-
-				start, err := strconv.Atoi(startAttr)
-				if err != nil {
-					return rt.vm.ToValue(1)
-				}
-				return rt.vm.ToValue(start)
-			}),
-
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Attributes["start"] = call.Arguments[0].String()
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE,
-		)
-
-		obj.DefineAccessorProperty("reversed",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				_, exists := node.Attributes["reversed"]
-				return rt.vm.ToValue(exists)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					if call.Arguments[0].ToBoolean() {
-						node.Attributes["reversed"] = ""
-					} else {
-						delete(node.Attributes, "reversed")
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		// type property - kind of list marker (1, a, A, i, I)
-		obj.DefineAccessorProperty("type",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				typeAttr := node.Attributes["type"]
-				if typeAttr == "" {
-					return rt.vm.ToValue("1")
-				}
-				return rt.vm.ToValue(typeAttr)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Attributes["type"] = call.Arguments[0].String()
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	if tagName == "THEAD" || tagName == "TFOOT" || tagName == "TBODY" {
-		obj.DefineAccessorProperty("rows",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				var rows []any
-				for _, child := range node.Children {
-					if child.Type == dom.Element && child.TagName == "tr" {
-						rows = append(rows, rt.wrapElement(child))
-					}
-				}
-				return rt.vm.NewArray(rows...)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	// HTMLTableColElement / HTMLTableColGroupElement (WHATWG 4.9.3-4.9.4)
-	if tagName == "COL" || tagName == "COLGROUP" {
-		obj.DefineAccessorProperty("span",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				spanAttr := node.Attributes["span"]
-				if spanAttr == "" {
-					return rt.vm.ToValue(1)
-				}
-				v, err := strconv.Atoi(spanAttr)
-				if err != nil || v < 1 {
-					return rt.vm.ToValue(1)
-				}
-				if v > 1000 {
-					return rt.vm.ToValue(1000)
-				}
-				return rt.vm.ToValue(v)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					v, err := strconv.Atoi(call.Arguments[0].String())
-					if err == nil {
-						if v < 1 {
-							v = 1
-						} else if v > 1000 {
-							v = 1000
-						}
-						node.Attributes["span"] = strconv.Itoa(v)
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	if tagName == "IMG" {
-		obj.DefineAccessorProperty("width",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				v, _ := strconv.Atoi(node.Attributes["width"])
-				return rt.vm.ToValue(v)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					v := call.Arguments[0].ToInteger()
-
-					node.Attributes["width"] = strconv.FormatInt(v, 10)
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("height",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				v, _ := strconv.Atoi(node.Attributes["height"])
-				return rt.vm.ToValue(v)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					v := call.Arguments[0].ToInteger()
-
-					node.Attributes["height"] = strconv.FormatInt(v, 10)
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("naturalWidth",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(node.NaturalWidth)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("naturalHeight",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(node.NaturalHeight)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("complete",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if strings.TrimSpace(node.Attributes["src"]) == "" {
-					return rt.vm.ToValue(true)
-				}
-				return rt.vm.ToValue(node.ImageComplete)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("src",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(node.Attributes["src"])
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-		obj.DefineAccessorProperty("currentSrc",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(node.CurrentSrc)
-			}),
-			nil,
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-
-	}
-
 	obj.DefineAccessorProperty("title",
 		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
 			title := node.Attributes["title"]
@@ -1712,9 +676,7 @@ func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
 
 		node.AppendChild(childNode)
 
-		if rt.onReflow != nil {
-			rt.onReflow()
-		}
+		rt.reflow(node)
 
 		return call.Arguments[0]
 	})
@@ -1731,19 +693,16 @@ func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
 
 		node.RemoveChild(childNode)
 
-		if rt.onReflow != nil {
-			rt.onReflow()
-		}
+		rt.reflow(node)
 
 		return call.Arguments[0]
 	})
 
 	obj.Set("remove", func(call goja.FunctionCall) goja.Value {
+		parent := node.Parent
 		node.Remove()
 
-		if rt.onReflow != nil {
-			rt.onReflow()
-		}
+		rt.reflow(parent)
 		return goja.Undefined()
 	})
 
@@ -1766,60 +725,6 @@ func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
 
 	obj.Set("_elem", elem)
 
-	// HTMLStyleElement.disabled property (spec 4.2.6)
-	if node.TagName == "style" {
-		obj.DefineAccessorProperty("disabled",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				return rt.vm.ToValue(node.Disabled)
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Disabled = call.Arguments[0].ToBoolean()
-					if rt.onReflow != nil {
-						rt.onReflow()
-					}
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	if node.TagName == "data" {
-		obj.DefineAccessorProperty("value",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if val, ok := node.Attributes["value"]; ok {
-					return rt.vm.ToValue(val)
-				}
-				return rt.vm.ToValue("")
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Attributes["value"] = call.Arguments[0].String()
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
-	// HTMLTimeElement.dateTime property (WHATWG 4.5.14)
-	// HTMLModElement.dateTime property (WHATWG 4.7.1, 4.7.2)
-	if node.TagName == "time" || node.TagName == "ins" || node.TagName == "del" {
-		obj.DefineAccessorProperty("dateTime",
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if val, ok := node.Attributes["datetime"]; ok {
-					return rt.vm.ToValue(val)
-				}
-				return rt.vm.ToValue("")
-			}),
-			rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-				if len(call.Arguments) > 0 {
-					node.Attributes["datetime"] = call.Arguments[0].String()
-				}
-				return goja.Undefined()
-			}),
-			goja.FLAG_FALSE, goja.FLAG_TRUE)
-	}
-
 	obj.DefineAccessorProperty("lang",
 		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
 			if val, ok := node.Attributes["lang"]; ok {
@@ -1835,6 +740,8 @@ func (rt *JSRuntime) wrapElement(node *dom.Node) goja.Value {
 		}),
 		goja.FLAG_FALSE, goja.FLAG_TRUE)
 
+	rt.installInterface(obj, elem)
+
 	// Cache before returning
 	rt.elementCache[node] = obj
 
@@ -1845,12 +752,28 @@ func (rt *JSRuntime) DispatchClick(node *dom.Node) bool {
 	rt.vmMu.Lock()
 	defer rt.vmMu.Unlock()
 
+	// Clicks come from the shell, so they are trusted and grant activation.
+	rt.Events.activation.activate()
 	inlinePrevented := rt.executeInlineEventLocked(node, "click")
 	listenerPrevented := rt.Events.Dispatch(rt, node, "click")
 
 	return inlinePrevented || listenerPrevented
 }
 
+// DispatchToggle fires the toggle event at a <details> element that was
+// just opened or closed.
+func (rt *JSRuntime) DispatchToggle(node *dom.Node) {
+	rt.vmMu.Lock()
+	defer rt.vmMu.Unlock()
+
+	oldState, newState := "open", "closed"
+	if dom.IsDetailsOpen(node) {
+		oldState, newState = "closed", "open"
+	}
+	rt.executeInlineEventLocked(node, "toggle")
+	rt.Events.DispatchAtTarget(rt, node, "toggle", map[string]interface{}{"oldState": oldState, "newState": newState})
+}
+
 func (rt *JSRuntime) SetAlertHandler(handler func(message string)) {
 	rt.onAlert = handler
 }
@@ -1859,6 +782,24 @@ func (rt *JSRuntime) SetConfirmHandler(handler func(string) bool) {
 	rt.onConfirm = handler
 }
 
+// SetLeaveConfirmHandler sets the dialog asked before leaving a page whose
+// beforeunload handler objects. Without one the confirm handler is used.
+func (rt *JSRuntime) SetLeaveConfirmHandler(handler func(string) bool) {
+	rt.onConfirmLeave = handler
+}
+
+// confirmLeave asks the user whether to leave the page.
+func (rt *JSRuntime) confirmLeave() bool {
+	const message = "Changes you made may not be saved. Leave anyway?"
+	if rt.onConfirmLeave != nil {
+		return rt.onConfirmLeave(message)
+	}
+	if rt.onConfirm != nil {
+		return rt.onConfirm(message)
+	}
+	return true
+}
+
 func (rt *JSRuntime) SetCurrentURL(url string) {
 	rt.currentURL = url
 }
@@ -1916,9 +857,7 @@ func (rt *JSRuntime) CheckBeforeUnload() bool {
 			return true
 		}
 		if result != nil && !goja.IsUndefined(result) && !goja.IsNull(result) {
-			if rt.onConfirm != nil {
-				return rt.onConfirm("Changes you made may not be saved. Leave anyway?")
-			}
+			return rt.confirmLeave()
 		}
 	}
 
@@ -1935,9 +874,7 @@ func (rt *JSRuntime) CheckBeforeUnload() bool {
 				return true
 			}
 			if result != nil && !goja.IsUndefined(result) && !goja.IsNull(result) {
-				if rt.onConfirm != nil {
-					return rt.onConfirm("Changes you made may not be saved. Leave anyway?")
-				}
+				return rt.confirmLeave()
 			}
 		}
 	}
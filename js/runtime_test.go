@@ -0,0 +1,23 @@
+package js
+
+import (
+	"strings"
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindScripts(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<head>
+<script src=" /app.js ">ignored()</script>
+<script>inline()</script>
+</head><body><script src="https://cdn.example.com/lib.js"></script><script></script></body>`))
+
+	assert.Equal(t, []Script{
+		{Src: "/app.js"},
+		{Code: "inline()"},
+		{Src: "https://cdn.example.com/lib.js"},
+	}, FindScripts(doc))
+}
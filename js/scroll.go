@@ -0,0 +1,118 @@
+package js
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dop251/goja"
+)
+
+// Page scrolling (CSSOM View §5.1). window.scrollX and scrollY, with their
+// pageXOffset and pageYOffset aliases, report the host's scroll position in
+// CSS pixels; scroll, scrollTo and scrollBy move it, and the host fires
+// scroll at window listeners once per frame in which the page scrolled.
+
+// SetScrollPositionHandler sets the callback that returns the page's
+// scroll position in CSS pixels.
+func (rt *JSRuntime) SetScrollPositionHandler(handler func() (x, y float64)) {
+	rt.scrollPosition = handler
+}
+
+// SetScrollHandler sets the callback that scrolls the page to (x, y) in
+// CSS pixels, gliding there when smooth is set.
+func (rt *JSRuntime) SetScrollHandler(handler func(x, y float64, smooth bool)) {
+	rt.onScroll = handler
+}
+
+// DispatchScroll fires scroll at window after the page scrolled, then
+// reflows if a listener changed the page.
+func (rt *JSRuntime) DispatchScroll() {
+	rt.deferReflows(func() {
+		for _, listener := range rt.scrollListeners {
+			if _, err := listener(goja.Undefined()); err != nil {
+				fmt.Println("scroll listener error:", err)
+			}
+		}
+	})
+}
+
+// pageScroll returns the page's scroll position, 0, 0 without a host.
+func (rt *JSRuntime) pageScroll() (x, y float64) {
+	if rt.scrollPosition == nil {
+		return 0, 0
+	}
+	return rt.scrollPosition()
+}
+
+// setupScroll defines window's scroll position and methods.
+func (rt *JSRuntime) setupScroll(window *goja.Object) {
+	position := func(vertical bool) goja.Value {
+		return rt.vm.ToValue(func(goja.FunctionCall) goja.Value {
+			x, y := rt.pageScroll()
+			if vertical {
+				return rt.vm.ToValue(y)
+			}
+			return rt.vm.ToValue(x)
+		})
+	}
+	// window is not the global object, so both get the accessors
+	for _, obj := range []*goja.Object{window, rt.vm.GlobalObject()} {
+		for _, name := range []string{"scrollX", "pageXOffset"} {
+			obj.DefineAccessorProperty(name, position(false), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+		}
+		for _, name := range []string{"scrollY", "pageYOffset"} {
+			obj.DefineAccessorProperty(name, position(true), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+		}
+	}
+
+	scrollTo := rt.windowScroll(false)
+	window.Set("scroll", scrollTo)
+	window.Set("scrollTo", scrollTo)
+	window.Set("scrollBy", rt.windowScroll(true))
+}
+
+// windowScroll returns window.scrollTo, or window.scrollBy when relative
+// is set. Both take x and y, or an options dictionary whose left and top
+// default to the current position (scrollTo) or 0 (scrollBy), and whose
+// behavior "smooth" glides to the target.
+func (rt *JSRuntime) windowScroll(relative bool) func(call goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		curX, curY := rt.pageScroll()
+		x, y := curX, curY
+		if relative {
+			x, y = 0, 0
+		}
+		smooth := false
+
+		if options, ok := call.Argument(0).(*goja.Object); ok && len(call.Arguments) == 1 {
+			if left := options.Get("left"); left != nil && !goja.IsUndefined(left) {
+				x = finiteOr(left.ToFloat(), 0)
+			}
+			if top := options.Get("top"); top != nil && !goja.IsUndefined(top) {
+				y = finiteOr(top.ToFloat(), 0)
+			}
+			if behavior := options.Get("behavior"); behavior != nil {
+				smooth = behavior.String() == "smooth"
+			}
+		} else if len(call.Arguments) >= 2 {
+			x = finiteOr(call.Argument(0).ToFloat(), 0)
+			y = finiteOr(call.Argument(1).ToFloat(), 0)
+		}
+
+		if relative {
+			x, y = curX+x, curY+y
+		}
+		if rt.onScroll != nil {
+			rt.onScroll(x, y, smooth)
+		}
+		return goja.Undefined()
+	}
+}
+
+// finiteOr returns v, or fallback when v is NaN or infinite.
+func finiteOr(v, fallback float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fallback
+	}
+	return v
+}
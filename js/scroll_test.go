@@ -0,0 +1,77 @@
+package js
+
+import (
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowScroll(t *testing.T) {
+	type scrollCall struct {
+		x, y   float64
+		smooth bool
+	}
+	tests := []struct {
+		name   string
+		script string
+		want   scrollCall
+	}{
+		{"scrollTo coordinates", "scrollTo(10, 200)", scrollCall{10, 200, false}},
+		{"scroll is scrollTo", "window.scroll(0, 50)", scrollCall{0, 50, false}},
+		{"options keep the other axis", "window.scrollTo({top: 500, behavior: 'smooth'})", scrollCall{5, 500, true}},
+		{"scrollBy is relative", "scrollBy(0, -40)", scrollCall{5, 60, false}},
+		{"scrollBy options", "window.scrollBy({left: 10, behavior: 'smooth'})", scrollCall{15, 100, true}},
+		{"non-finite values are 0", "scrollTo(NaN, Infinity)", scrollCall{0, 0, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := NewJSRuntime(&dom.Node{Type: dom.Document}, nil)
+			rt.SetScrollPositionHandler(func() (float64, float64) { return 5, 100 })
+			var got []scrollCall
+			rt.SetScrollHandler(func(x, y float64, smooth bool) { got = append(got, scrollCall{x, y, smooth}) })
+
+			assert.NoError(t, rt.Execute(tt.script))
+			assert.Equal(t, []scrollCall{tt.want}, got)
+		})
+	}
+}
+
+func TestWindowScrollPosition(t *testing.T) {
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, nil)
+	assert.NoError(t, rt.Execute("var before = window.scrollY"))
+	assert.Equal(t, int64(0), rt.vm.Get("before").ToInteger(), "0 without a host")
+
+	rt.SetScrollPositionHandler(func() (float64, float64) { return 12, 340 })
+	assert.NoError(t, rt.Execute("var pos = [scrollX, scrollY, pageXOffset, pageYOffset].join()"))
+	assert.Equal(t, "12,340,12,340", rt.vm.Get("pos").String())
+}
+
+func TestDispatchScroll(t *testing.T) {
+	reflows := 0
+	rt := NewJSRuntime(&dom.Node{Type: dom.Document}, func(*dom.Node) { reflows++ })
+	rt.DispatchScroll()
+	assert.Equal(t, 0, reflows, "nothing listens")
+
+	rt.SetScrollPositionHandler(func() (float64, float64) { return 0, 80 })
+	assert.NoError(t, rt.Execute(`
+		var seen = [];
+		window.addEventListener("scroll", function() { seen.push(window.scrollY); });`))
+	rt.DispatchScroll()
+	assert.Equal(t, "80", rt.vm.Get("seen").String())
+	assert.Equal(t, 0, reflows, "the listener only read the position")
+
+	assert.NoError(t, rt.Execute(`
+		var body = document.createElement("div");
+		window.addEventListener("scroll", function() {
+			body.className = "scrolled";
+			body.textContent = String(window.scrollY);
+		});`))
+	reflows = 0
+	rt.DispatchScroll()
+	assert.Equal(t, 1, reflows, "one reflow for every change the listeners made")
+	assert.NoError(t, rt.Execute(`body.className = "again"`))
+	assert.Equal(t, 2, reflows, "changes outside a dispatch reflow at once")
+}
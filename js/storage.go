@@ -0,0 +1,60 @@
+package js
+
+import (
+	"browser/utils"
+
+	"github.com/dop251/goja"
+)
+
+// newLocalStorage builds window.localStorage over utils.LocalStorage. The
+// origin is read on every call, so the object follows SetCurrentURL.
+func (rt *JSRuntime) newLocalStorage() *goja.Object {
+	storage := rt.vm.NewObject()
+	origin := func() string {
+		if o := utils.OriginOf(rt.currentURL); o != "" {
+			return o
+		}
+		return "null" // opaque origin: file: and about: pages share one area
+	}
+	arg := func(call goja.FunctionCall, i int) string {
+		if len(call.Arguments) <= i {
+			return "undefined"
+		}
+		return call.Arguments[i].String()
+	}
+
+	storage.Set("getItem", func(call goja.FunctionCall) goja.Value {
+		if value, ok := utils.LocalStorage.GetItem(origin(), arg(call, 0)); ok {
+			return rt.vm.ToValue(value)
+		}
+		return goja.Null()
+	})
+	storage.Set("setItem", func(call goja.FunctionCall) goja.Value {
+		utils.LocalStorage.SetItem(origin(), arg(call, 0), arg(call, 1))
+		return goja.Undefined()
+	})
+	storage.Set("removeItem", func(call goja.FunctionCall) goja.Value {
+		utils.LocalStorage.RemoveItem(origin(), arg(call, 0))
+		return goja.Undefined()
+	})
+	storage.Set("clear", func(call goja.FunctionCall) goja.Value {
+		utils.LocalStorage.ClearOrigin(origin())
+		return goja.Undefined()
+	})
+	storage.Set("key", func(call goja.FunctionCall) goja.Value {
+		keys := utils.LocalStorage.Keys(origin())
+		if len(call.Arguments) > 0 {
+			if i := call.Arguments[0].ToInteger(); i >= 0 && i < int64(len(keys)) {
+				return rt.vm.ToValue(keys[i])
+			}
+		}
+		return goja.Null()
+	})
+	storage.DefineAccessorProperty("length",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.vm.ToValue(len(utils.LocalStorage.Keys(origin())))
+		}),
+		nil,
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return storage
+}
@@ -0,0 +1,116 @@
+package js
+
+import (
+	"browser/dom"
+
+	"github.com/dop251/goja"
+)
+
+// newNodeFilter returns the NodeFilter interface object holding the
+// acceptNode results and whatToShow bits (DOM §6.3).
+func (rt *JSRuntime) newNodeFilter() *goja.Object {
+	nodeFilter := rt.vm.NewObject()
+	nodeFilter.Set("FILTER_ACCEPT", int(dom.FilterAccept))
+	nodeFilter.Set("FILTER_REJECT", int(dom.FilterReject))
+	nodeFilter.Set("FILTER_SKIP", int(dom.FilterSkip))
+	nodeFilter.Set("SHOW_ALL", dom.ShowAll)
+	nodeFilter.Set("SHOW_ELEMENT", dom.ShowElement)
+	nodeFilter.Set("SHOW_TEXT", dom.ShowText)
+	nodeFilter.Set("SHOW_DOCUMENT", dom.ShowDocument)
+	return nodeFilter
+}
+
+// createTreeWalker implements document.createTreeWalker(root, whatToShow, filter).
+// filter may be a function or an object with an acceptNode method.
+func (rt *JSRuntime) createTreeWalker(docObj *goja.Object) func(call goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		rootArg := call.Argument(0)
+		root := unwrapNode(rt, rootArg)
+		if obj, ok := rootArg.(*goja.Object); ok && obj == docObj {
+			root = rt.document
+		}
+		if root == nil {
+			panic(rt.vm.NewTypeError("Failed to execute 'createTreeWalker': parameter 1 is not of type 'Node'."))
+		}
+
+		whatToShow := dom.ShowAll
+		if arg := call.Argument(1); !goja.IsUndefined(arg) {
+			whatToShow = uint32(arg.ToInteger())
+		}
+
+		walker := dom.NewTreeWalker(root, whatToShow, rt.jsNodeFilter(call.Argument(2)))
+		return rt.wrapTreeWalker(walker, rootArg)
+	}
+}
+
+// jsNodeFilter adapts a script-supplied filter to the dom walker, or
+// returns nil when there is none.
+func (rt *JSRuntime) jsNodeFilter(arg goja.Value) func(*dom.Node) dom.FilterResult {
+	if goja.IsUndefined(arg) || goja.IsNull(arg) {
+		return nil
+	}
+	callback, ok := goja.AssertFunction(arg)
+	this := goja.Undefined()
+	if !ok {
+		obj := arg.ToObject(rt.vm)
+		callback, ok = goja.AssertFunction(obj.Get("acceptNode"))
+		if !ok {
+			return nil
+		}
+		this = obj
+	}
+	return func(n *dom.Node) dom.FilterResult {
+		result, err := callback(this, rt.wrapNode(n))
+		if err != nil {
+			panic(err)
+		}
+		switch r := dom.FilterResult(result.ToInteger()); r {
+		case dom.FilterReject, dom.FilterSkip:
+			return r
+		}
+		return dom.FilterAccept
+	}
+}
+
+// wrapNode wraps n for script, mapping the document root to the document object.
+func (rt *JSRuntime) wrapNode(n *dom.Node) goja.Value {
+	if n == rt.document {
+		return rt.vm.Get("document")
+	}
+	return rt.wrapElement(n)
+}
+
+func (rt *JSRuntime) wrapTreeWalker(walker *dom.TreeWalker, rootArg goja.Value) *goja.Object {
+	obj := rt.vm.NewObject()
+	obj.Set("root", rootArg)
+	obj.Set("whatToShow", walker.WhatToShow)
+
+	obj.DefineAccessorProperty("currentNode",
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return rt.wrapNode(walker.CurrentNode)
+		}),
+		rt.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if n := unwrapNode(rt, call.Argument(0)); n != nil {
+				walker.CurrentNode = n
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	move := func(step func() *dom.Node) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			if n := step(); n != nil {
+				return rt.wrapNode(n)
+			}
+			return goja.Null()
+		}
+	}
+	obj.Set("parentNode", move(walker.ParentNode))
+	obj.Set("firstChild", move(walker.FirstChild))
+	obj.Set("lastChild", move(walker.LastChild))
+	obj.Set("previousSibling", move(walker.PreviousSibling))
+	obj.Set("nextSibling", move(walker.NextSibling))
+	obj.Set("previousNode", move(walker.PreviousNode))
+	obj.Set("nextNode", move(walker.NextNode))
+	return obj
+}
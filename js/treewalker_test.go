@@ -0,0 +1,45 @@
+package js
+
+import (
+	"browser/dom"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTreeWalker(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<html><body><div id="list"><p>one</p><!-- --><p class="skip">two</p><span>three</span></div></body></html>`))
+	rt := NewJSRuntime(doc, nil)
+
+	assert.NoError(t, rt.Execute(`
+		var list = document.getElementById("list");
+		var w = document.createTreeWalker(list, NodeFilter.SHOW_ELEMENT, {
+			acceptNode: function(n) {
+				return n.className === "skip" ? NodeFilter.FILTER_REJECT : NodeFilter.FILTER_ACCEPT;
+			}
+		});
+		var tags = [];
+		for (var n = w.nextNode(); n; n = w.nextNode()) tags.push(n.tagName);
+		var back = w.previousNode().tagName;
+		var parent = w.parentNode() === list;
+
+		var texts = [];
+		var tw = document.createTreeWalker(list, NodeFilter.SHOW_TEXT);
+		while (tw.nextNode()) texts.push(tw.currentNode.textContent);
+
+		var all = document.createTreeWalker(document, NodeFilter.SHOW_ELEMENT, function(n) {
+			return n.tagName === "P" ? NodeFilter.FILTER_ACCEPT : NodeFilter.FILTER_SKIP;
+		});
+		var count = 0;
+		while (all.nextNode()) count++;
+		var atRoot = all.parentNode() === null && all.root === document;
+	`))
+
+	assert.Equal(t, "P,SPAN", rt.vm.Get("tags").String())
+	assert.Equal(t, "P", rt.vm.Get("back").String())
+	assert.True(t, rt.vm.Get("parent").ToBoolean())
+	assert.Equal(t, "one,two,three", rt.vm.Get("texts").String())
+	assert.Equal(t, int64(2), rt.vm.Get("count").ToInteger())
+	assert.True(t, rt.vm.Get("atRoot").ToBoolean())
+}
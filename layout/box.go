@@ -35,6 +35,7 @@ const (
 	FileInputBox
 	FieldsetBox
 	LegendBox
+	MediaBox
 )
 
 type LayoutBox struct {
@@ -48,6 +49,7 @@ type LayoutBox struct {
 	WrappedLines        []string
 	JustifyWordSpacings []float64 // per-wrapped-line extra word spacing for text-align: justify
 	TextIndentPx        float64   // resolved text-indent in pixels for first line offset
+	LineOffsets         []float64 // per-wrapped-line x offset from Rect.X for lines moved by floats
 	Parent       *LayoutBox
 	Style        css.Style
 	Position     string
@@ -58,12 +60,34 @@ type LayoutBox struct {
 	Float        string
 	Clear        string
 	TableBorder  int
+	// CollapsedBorders marks a table cell whose borders were resolved by the
+	// collapsing border model; they straddle Rect's edges (see tableborders.go)
+	CollapsedBorders bool
+	ScrollWidth  float64 // content size of a scroll container (see scroll.go)
+	ScrollHeight float64
+
+	absolutes []absoluteBox // absolute descendants awaiting this containing block (see position.go)
+	// collapsedMarginBottom is the bottom margin once collapsed with the
+	// last child's, as seen by the next sibling
+	collapsedMarginBottom float64
+	// laidOut records where computeBlockLayout last placed the box, so
+	// Relayout can put a rebuilt copy in its place (see reflow.go)
+	laidOut *blockLayout
+	// naturalWidth and naturalHeight are the decoded size of an image's
+	// node when the box was built, 0 until it loads (see ImageNeedsReflow)
+	naturalWidth, naturalHeight int
+	// brokenImage is whether an image's node had failed to load when the
+	// box was built (see brokenimage.go)
+	brokenImage bool
+	// viewportHeight is the height fixed boxes are placed in, set on the
+	// root; 0 places them against the root's own height
+	viewportHeight float64
 }
 
 // IsInline returns true if the box should flow horizontally (inline)
 func (box *LayoutBox) IsInline() bool {
 	switch box.Type {
-	case TextBox, InlineBox, ImageBox:
+	case TextBox, InlineBox, ImageBox, MediaBox:
 		return true
 	default:
 		return false
@@ -0,0 +1,30 @@
+package layout
+
+import "browser/dom"
+
+// A broken image, one that failed to load, is drawn as a bordered box with
+// an icon and its alt text. Without a width or height it shrinks to fit
+// them, and an image with an empty alt, which is decorative, to nothing.
+const (
+	BrokenImagePadding   = 4.0  // between the border and the icon and text
+	BrokenImageIconWidth = 18.0 // the icon and the gap after it
+)
+
+// imageBroken reports whether node is an image that has finished loading
+// without an image to show.
+func imageBroken(node *dom.Node) bool {
+	return node != nil && node.ImageComplete && node.NaturalWidth == 0
+}
+
+// brokenImageSize returns the size of a broken image box that neither
+// CSS nor its attributes size: its icon and alt text in the box's font,
+// inside the padding.
+func brokenImageSize(box *LayoutBox) (float64, float64) {
+	alt, ok := box.Node.Attributes["alt"]
+	if ok && alt == "" {
+		return 0, 0
+	}
+	fontSize := styleFontSize(box.Style)
+	width := MeasureStyledText(alt, fontSize, StyleFont(box.Style), box.Style.LetterSpacing, box.Style.WordSpacing)
+	return BrokenImageIconWidth + width + 2*BrokenImagePadding, fontSize*1.5 + 2*BrokenImagePadding
+}
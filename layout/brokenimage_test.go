@@ -0,0 +1,42 @@
+package layout
+
+import (
+	"testing"
+
+	"browser/css"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrokenImageSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		html          string
+		width, height float64
+	}{
+		{"shrinks to its alt text", `<p><img src="a.png" alt="Logo"></p>`, 18 + 32 + 8, 24 + 8},
+		{"just the icon without alt", `<p><img src="a.png"></p>`, 18 + 8, 24 + 8},
+		{"nothing with an empty alt", `<p><img src="a.png" alt=""></p>`, 0, 0},
+		{"alt text in the image's font", `<p><img src="a.png" alt="Logo" style="font-size: 10px"></p>`, 18 + 32*10/16.0 + 8, 15 + 8},
+		{"attributes keep their size", `<p><img src="a.png" alt="Logo" width="100" height="50"></p>`, 100, 50},
+		{"one attribute keeps the default for the other", `<p><img src="a.png" alt="Logo" width="100"></p>`, 100, DefaultImageHeight},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTML(tt.html)
+			img := findBoxByType(BuildLayoutTree(doc, css.Stylesheet{}, Viewport{}, css.MatchContext{}), ImageBox)
+			w, h := imageSize(img, 0)
+			img.Node.ImageComplete = true
+			if w != tt.width || h != tt.height {
+				assert.True(t, ImageNeedsReflow(img), "failing resizes the image")
+			}
+
+			img = findBoxByType(BuildLayoutTree(doc, css.Stylesheet{}, Viewport{}, css.MatchContext{}), ImageBox)
+			w, h = imageSize(img, 0)
+			assert.InDelta(t, tt.width, w, 0.01)
+			assert.InDelta(t, tt.height, h, 0.01)
+			assert.False(t, ImageNeedsReflow(img))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package layout
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Grapheme clusters are the characters a reader sees, which text is drawn
+// and letter-spaced by. This is the subset of UAX #29 pages need: a
+// character takes the combining marks, variation selectors and emoji
+// modifiers after it, a zero width joiner joins the emoji on either side,
+// and regional indicators pair up into flags.
+
+const zeroWidthJoiner = '\u200d'
+
+// extendsCluster reports whether r belongs to the cluster before it.
+func extendsCluster(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zeroWidthJoiner ||
+		r >= 0x1F3FB && r <= 0x1F3FF || // emoji skin tones
+		r >= 0xE0020 && r <= 0xE007F // emoji tag sequences
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// nextCluster returns the length in bytes of the first grapheme cluster
+// of text.
+func nextCluster(text string) int {
+	prev, end := utf8.DecodeRuneInString(text)
+	flag := isRegionalIndicator(prev)
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		switch {
+		case extendsCluster(r), prev == zeroWidthJoiner:
+		case flag && isRegionalIndicator(r):
+			flag = false // a flag is two indicators, no more
+		default:
+			return end
+		}
+		prev = r
+		end += size
+	}
+	return end
+}
+
+// GraphemeClusters splits text into its grapheme clusters.
+func GraphemeClusters(text string) []string {
+	var clusters []string
+	for text != "" {
+		n := nextCluster(text)
+		clusters = append(clusters, text[:n])
+		text = text[n:]
+	}
+	return clusters
+}
+
+// ClusterCount returns the number of grapheme clusters in text.
+func ClusterCount(text string) int {
+	count := 0
+	for text != "" {
+		text = text[nextCluster(text):]
+		count++
+	}
+	return count
+}
@@ -0,0 +1,35 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphemeClusters(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"letters", "abc", []string{"a", "b", "c"}},
+		{"combining accent", "e\u0301x", []string{"e\u0301", "x"}},
+		{"devanagari signs", "नमस्ते", []string{"न", "म", "स्", "ते"}},
+		{"zwj sequence", "👩\u200d💻!", []string{"👩\u200d💻", "!"}},
+		{"skin tone", "👍🏽👍", []string{"👍🏽", "👍"}},
+		{"keycap", "1\ufe0f\u20e3", []string{"1\ufe0f\u20e3"}},
+		{"flags pair up", "🇺🇸🇫🇷🇩", []string{"🇺🇸", "🇫🇷", "🇩"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GraphemeClusters(tt.text))
+			assert.Equal(t, len(tt.want), ClusterCount(tt.text))
+		})
+	}
+}
+
+func TestLetterSpacingByCluster(t *testing.T) {
+	// Two characters, one with a combining accent, have one gap between them
+	assert.Equal(t, MeasureText("ab", 16)+3, MeasureTextWithSpacing("e\u0301e", 16, 3))
+}
@@ -5,6 +5,7 @@ import (
 	"browser/dom"
 	"browser/utils"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -17,51 +18,27 @@ const (
 	ScrollbarWidth     = 12.0
 )
 
-// getDefaultLineHeight returns default line heights for different elements
-func getDefaultLineHeight(tagName string) float64 {
-	switch tagName {
-	case dom.TagH1:
-		return 40.0
-	case dom.TagH2:
-		return 32.0
-	case dom.TagH3:
-		return 26.0
-	case dom.TagH4:
-		return 24.0
-	case dom.TagH5:
-		return 22.0
-	case dom.TagH6:
-		return 20.0
-	case dom.TagSmall:
-		return 18.0
-	default:
-		return 24.0
+// styleFontSize returns the computed font-size of style, the size its text
+// is measured and painted at.
+func styleFontSize(style css.Style) float64 {
+	if style.FontSize > 0 {
+		return style.FontSize
 	}
+	return css.DefaultFontSize
 }
 
-// Font sizes for text measurement (should match render/paint.go)
-func getFontSize(tagName string) float64 {
-	switch tagName {
-	case dom.TagH1:
-		return 32.0
-	case dom.TagH2:
-		return 24.0
-	case dom.TagH3:
-		return 18.0
-	case dom.TagH4:
-		return 16.0
-	case dom.TagH5:
-		return 14.0
-	case dom.TagH6:
-		return 12.0
-	case dom.TagSmall:
-		return 12.0
-	default:
-		return 16.0
+// textFontSize returns the size a text box is measured at: the font-size of
+// the element it belongs to.
+func textFontSize(box *LayoutBox) float64 {
+	if box.Parent == nil {
+		return css.DefaultFontSize
 	}
+	return styleFontSize(box.Parent.Style)
 }
 
 func ComputeLayout(root *LayoutBox, containerWidth float64) {
+	start := utils.Profile.Start()
+	defer recordLayout(root, start)
 	computeBlockLayout(root, blockLayoutParams{
 		containerWidth: containerWidth,
 		startX:         0,
@@ -77,16 +54,47 @@ type blockLayoutParams struct {
 	startY         float64
 	parentTag      string
 	viewportWidth  float64
+	usedWidth      float64       // width fixed by a flex container, 0 when the box sizes itself
+	floats         *floatContext // floats of the enclosing formatting context, nil to start one
 }
 
-func collapsedPositiveMarginDelta(prevBottom, nextTop float64) float64 {
-	if prevBottom <= 0 || nextTop <= 0 {
-		return 0
+// collapseMargins returns the single margin that adjoining vertical margins
+// a and b collapse into (CSS 2.1 §8.3.1): the largest positive margin plus
+// the most negative one.
+func collapseMargins(a, b float64) float64 {
+	return math.Max(math.Max(a, b), 0) + math.Min(math.Min(a, b), 0)
+}
+
+// collapsedMarginDelta returns how much closer collapsing prevBottom and
+// nextTop brings two boxes than adding both margins up.
+func collapsedMarginDelta(prevBottom, nextTop float64) float64 {
+	return prevBottom + nextTop - collapseMargins(prevBottom, nextTop)
+}
+
+// marginsCollapseWithChildren reports whether box's vertical margins may
+// adjoin those of its first and last in-flow children. Formatting context
+// roots, the root element and non-block boxes keep their children's margins
+// inside; padding and borders are checked by the caller per edge.
+func marginsCollapseWithChildren(box *LayoutBox) bool {
+	if box.Type != BlockBox || establishesFormattingContext(box) {
+		return false
 	}
-	if prevBottom < nextTop {
-		return prevBottom
+	return box.Node == nil || box.Node.TagName != dom.TagHTML
+}
+
+// firstInFlowBlock returns box's first child when it is a block in normal
+// flow, skipping floats and positioned boxes, or nil.
+func firstInFlowBlock(box *LayoutBox) *LayoutBox {
+	for _, child := range box.Children {
+		if isFloated(child) || child.Position == "absolute" || child.Position == "fixed" {
+			continue
+		}
+		if child.Type == BlockBox || child.Type == FieldsetBox {
+			return child
+		}
+		return nil
 	}
-	return nextTop
+	return nil
 }
 
 func estimatedBlockTopMargin(box *LayoutBox) float64 {
@@ -116,11 +124,19 @@ func estimatedBlockTopMargin(box *LayoutBox) float64 {
 	if box.Style.MarginTop > 0 {
 		top = box.Style.MarginTop
 	}
+
+	// Nothing above the first child: its top margin collapses through
+	if box.Style.PaddingTop == 0 && box.Style.BorderTopWidth == 0 && marginsCollapseWithChildren(box) {
+		if first := firstInFlowBlock(box); first != nil && first.Clear == "" {
+			top = collapseMargins(top, estimatedBlockTopMargin(first))
+		}
+	}
 	return top
 }
 
 // resolveWidth returns the effective width for a box given its style and container width.
-// Checks Style.Width first (absolute px), then Style.WidthPercent (relative to container).
+// Checks Style.Width first (absolute px), then Style.WidthPercent (relative to container),
+// then a math function with percentages.
 func resolveWidth(style css.Style, containerWidth float64) float64 {
 	if style.Width > 0 {
 		return style.Width
@@ -128,9 +144,25 @@ func resolveWidth(style css.Style, containerWidth float64) float64 {
 	if style.WidthPercent > 0 {
 		return containerWidth * style.WidthPercent / 100.0
 	}
+	if style.WidthMath != nil {
+		return max(style.WidthMath.Resolve(containerWidth), 0)
+	}
 	return 0
 }
 
+// resolveWidthLimits returns a box's min-width and max-width in the
+// container; 0 means none.
+func resolveWidthLimits(style css.Style, containerWidth float64) (minWidth, maxWidth float64) {
+	minWidth, maxWidth = style.MinWidth, style.MaxWidth
+	if style.MinWidthMath != nil {
+		minWidth = style.MinWidthMath.Resolve(containerWidth)
+	}
+	if style.MaxWidthMath != nil {
+		maxWidth = style.MaxWidthMath.Resolve(containerWidth)
+	}
+	return minWidth, maxWidth
+}
+
 func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 	containerWidth := p.containerWidth
 	startX := p.startX
@@ -138,21 +170,37 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 	parentTag := p.parentTag
 	viewportWidth := p.viewportWidth
 
-	// Separate positioned children from normal flow
+	// Boxes establishing a formatting context keep their floats to themselves
+	floats := p.floats
+	ownsFloats := floats == nil || establishesFormattingContext(box)
+	if ownsFloats {
+		floats = &floatContext{}
+	}
+
+	// Separate positioned children from normal flow. Floats stay in the
+	// flow order so each is placed where it occurs among its siblings.
 	var positionedChildren []*LayoutBox
 	var floatedChildren []*LayoutBox
 	var normalChildren []*LayoutBox
+	var flowChildren []*LayoutBox
+	flex := isFlexContainer(box)
 
 	for _, child := range box.Children {
 		if child.Position == "absolute" || child.Position == "fixed" {
 			positionedChildren = append(positionedChildren, child)
-		} else if child.Float == "left" || child.Float == "right" {
+			continue
+		}
+		if isFloated(child) && !flex {
 			floatedChildren = append(floatedChildren, child)
 		} else {
 			normalChildren = append(normalChildren, child)
 		}
+		flowChildren = append(flowChildren, child)
 	}
 	box.Children = normalChildren
+	if establishesContainingBlock(box) {
+		box.absolutes = nil
+	}
 
 	box.Rect.X = startX
 	box.Rect.Y = startY
@@ -162,12 +210,17 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 		box.Rect.Width = w
 	}
 
-	if box.Style.MinWidth > 0 && box.Rect.Width < box.Style.MinWidth {
-		box.Rect.Width = box.Style.MinWidth
+	minWidth, maxWidth := resolveWidthLimits(box.Style, containerWidth)
+	if minWidth > 0 && box.Rect.Width < minWidth {
+		box.Rect.Width = minWidth
+	}
+
+	if maxWidth > 0 && box.Rect.Width > maxWidth {
+		box.Rect.Width = maxWidth
 	}
 
-	if box.Style.MaxWidth > 0 && box.Rect.Width > box.Style.MaxWidth {
-		box.Rect.Width = box.Style.MaxWidth
+	if p.usedWidth > 0 {
+		box.Rect.Width = p.usedWidth
 	}
 
 	innerX := startX
@@ -306,8 +359,10 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 	}
 
 	yOffset := startY + box.Margin.Top + box.Padding.Top + box.Style.BorderTopWidth
+	contentTop := yOffset
+	collapseThrough := !ownsFloats && marginsCollapseWithChildren(box)
 
-	if (box.Style.Width > 0 || box.Style.WidthPercent > 0) && box.Style.BoxSizing != "border-box" {
+	if (box.Style.Width > 0 || box.Style.WidthPercent > 0) && box.Style.BoxSizing != "border-box" && p.usedWidth == 0 {
 		w := resolveWidth(box.Style, containerWidth)
 		box.Rect.Width = w + box.Style.PaddingLeft + box.Style.PaddingRight + box.Style.BorderLeftWidth +
 			box.Style.BorderRightWidth
@@ -321,12 +376,20 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 	// Resolve text-indent for inline flow (first line of block gets indented)
 	blockTextIndent := resolveTextIndent(box.Style.TextIndent, box.Style.FontSize, innerWidth, viewportWidth)
 
-	// Line state for inline flow
+	// Line state for inline flow. Line boxes span [lineLeft, lineRight],
+	// shortened by floats beside them.
 	currentX := innerX
 	lineStartY := yOffset
 	lineHeight := 0.0
 	var lineBoxes []*LayoutBox
 	firstLineOfBlock := true
+	nominalLineHeight := getLineHeightFromStyle(box.Style)
+	lineLeft, lineRight := innerX, innerX+innerWidth
+	startLine := func() {
+		lineLeft, lineRight = floats.lineBand(lineStartY, nominalLineHeight, innerX, innerX+innerWidth)
+		currentX = lineLeft
+	}
+	startLine()
 
 	// Handle legend for fieldset
 	var legendBox *LayoutBox
@@ -343,7 +406,7 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 		// Position legend on the border
 		if legendBox != nil {
 			legendText := GetLegendText(legendBox)
-			legendWidth := MeasureText(legendText, 16) + 16 // 8px padding each side
+			legendWidth := MeasureText(legendText, styleFontSize(legendBox.Style)) + 16 // 8px padding each side
 			legendHeight := 20.0
 
 			legendBox.Rect.X = innerX + 12                              // 12px from left edge
@@ -357,7 +420,7 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 				if child.Type == TextBox {
 					child.Rect.X = textX
 					child.Rect.Y = legendBox.Rect.Y
-					child.Rect.Width = MeasureText(child.Text, 16)
+					child.Rect.Width = MeasureText(child.Text, styleFontSize(legendBox.Style))
 					child.Rect.Height = legendHeight
 				}
 			}
@@ -367,114 +430,94 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 		}
 	}
 
-	// Position floated children before normal flow so we can track their bottom edges
-	// for clear property support
-	leftFloatX := innerX
-	rightFloatX := innerX + innerWidth
-	floatY := startY + box.Padding.Top + box.Style.BorderTopWidth
-	var leftFloatBottom, rightFloatBottom float64
-
-	for _, child := range floatedChildren {
-		// Apply clear on floated elements — when floatY advances,
-		// reset horizontal accumulators since we're on a new float line
-		oldFloatY := floatY
-		if child.Clear == "left" || child.Clear == "both" {
-			if leftFloatBottom > floatY {
-				floatY = leftFloatBottom
-			}
-		}
-		if child.Clear == "right" || child.Clear == "both" {
-			if rightFloatBottom > floatY {
-				floatY = rightFloatBottom
-			}
-		}
-		if floatY > oldFloatY {
-			leftFloatX = innerX
-			rightFloatX = innerX + innerWidth
-		}
-
-		childWidth := resolveWidth(child.Style, innerWidth)
-		if childWidth <= 0 {
-			childWidth = 100 // Default width for floats without explicit width
-		}
-
-		// Compute layout to determine dimensions
-		computeBlockLayout(child, blockLayoutParams{
-			containerWidth: childWidth,
-			startX:         0,
-			startY:         0,
-			parentTag:      "",
-			viewportWidth:  viewportWidth,
-		})
-
-		switch child.Float {
-		case "left":
-			offsetBox(child, leftFloatX, floatY)
-			leftFloatX += child.Rect.Width
-		case "right":
-			offsetBox(child, rightFloatX-child.Rect.Width, floatY)
-			rightFloatX -= child.Rect.Width
-		}
+	// Flex containers place their items themselves, leaving no inline flow
+	if flex {
+		yOffset += computeFlexLayout(box, innerX, yOffset, innerWidth, viewportWidth)
+		flowChildren = nil
+	}
 
-		// Track float bottom edges for clear
-		childBottom := child.Rect.Y + child.Rect.Height
-		switch child.Float {
-		case "left":
-			if childBottom > leftFloatBottom {
-				leftFloatBottom = childBottom
-			}
-		case "right":
-			if childBottom > rightFloatBottom {
-				rightFloatBottom = childBottom
-			}
-		}
+	// Multi-column containers balance their content across columns
+	if isMulticolContainer(box) {
+		yOffset += computeMulticolLayout(box, flowChildren, innerX, yOffset, innerWidth, parentTag, viewportWidth)
+		flowChildren = nil
 	}
 
 	prevBlockMarginBottom := 0.0
 	hasPrevBlock := false
 
-	for _, child := range box.Children {
+	for _, child := range flowChildren {
 		// Skip LegendBox - already positioned above
 		if child.Type == LegendBox {
 			continue
 		}
 
+		// A float goes at the top of the current line when that line is
+		// still empty, otherwise below it; later lines flow around it
+		if isFloated(child) {
+			floatY := lineStartY
+			if len(lineBoxes) > 0 {
+				floatY += lineHeight
+			}
+			layoutFloat(child, floats, floats.clearance(child.Clear, floatY), innerX, innerWidth, viewportWidth)
+			if len(lineBoxes) == 0 {
+				startLine()
+			} else {
+				_, lineRight = floats.lineBand(lineStartY, nominalLineHeight, innerX, innerX+innerWidth)
+			}
+			continue
+		}
+
 		var childWidth, childHeight float64
 
 		switch child.Type {
 		case TextBox:
-			fontSize := getFontSize(parentTag)
-			// Check if inside a <pre> element
-			if isInsidePre(child) {
-				// Handle multi-line preformatted text
-				lines := strings.Split(child.Text, "\n")
-				lineHeight := fontSize * 1.5 // Match render/paint.go line height
-
-				// Find the widest line
-				maxWidth := 0.0
-				for _, line := range lines {
-					w := MeasureTextWithSpacingAndWordSpacing(line, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
-					if w > maxWidth {
-						maxWidth = w
-					}
-				}
-
-				childWidth = maxWidth
-				childHeight = float64(len(lines)) * lineHeight
-			} else if box.Style.WhiteSpace == "nowrap" {
+			fontSize := styleFontSize(box.Style)
+			// Preformatted text keeps its tabs and line feeds
+			if isInsidePre(child) || strings.Contains(child.Text, "\n") {
+				childWidth, childHeight = measurePreformattedText(child.Text, fontSize, preformattedFont(box.Style, child), box.Style.LetterSpacing, box.Style.WordSpacing)
+			} else if child.Text = collapseTextStart(child.Text, lineBoxes); child.Text == "" {
+				// Collapsed away entirely: takes no room on the line
+				child.Rect = Rect{X: currentX, Y: lineStartY}
+				continue
+			} else if !wrapsLines(box.Style.WhiteSpace) {
 				child.WrappedLines = nil
-				childWidth = MeasureTextWithSpacingAndWordSpacing(child.Text, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
-				childHeight = getLineHeightFromStyle(box.Style, parentTag)
+				childWidth = MeasureStyledText(child.Text, fontSize, StyleFont(box.Style), box.Style.LetterSpacing, box.Style.WordSpacing)
+				childHeight = getLineHeightFromStyle(box.Style)
 			} else {
 				// Resolve text-indent for the first line
 				textIndent := resolveTextIndent(box.Style.TextIndent, fontSize, innerWidth, viewportWidth)
 				firstLineWidth := innerWidth - textIndent
+				lineHeight := getLineHeightFromStyle(box.Style)
+				availWidth := func(line int) float64 {
+					if line == 0 {
+						return firstLineWidth
+					}
+					return innerWidth
+				}
 
-				// Wrap text to fit container width (first line has reduced width for indent)
-				child.WrappedLines = WrapTextWithIndent(child.Text, fontSize, innerWidth, firstLineWidth, box.Style.LetterSpacing, box.Style.WordSpacing)
+				text := hyphenationText(child.Text, box.Style.Hyphens)
+				child.LineOffsets = nil
+				if floats.extendsBelow(lineStartY) {
+					// Lines beside floats get the width left between them
+					availWidth = func(line int) float64 {
+						if line == 0 {
+							return lineRight - currentX - textIndent
+						}
+						l, r := floats.lineBand(lineStartY+float64(line)*lineHeight, lineHeight, innerX, innerX+innerWidth)
+						return r - l
+					}
+					child.WrappedLines = WrapTextToWidths(text, fontSize, StyleFont(box.Style), availWidth, box.Style.LetterSpacing, box.Style.WordSpacing)
+					child.LineOffsets = make([]float64, len(child.WrappedLines))
+					for i := 1; i < len(child.WrappedLines); i++ {
+						l, _ := floats.lineBand(lineStartY+float64(i)*lineHeight, lineHeight, innerX, innerX+innerWidth)
+						child.LineOffsets[i] = l - currentX
+					}
+				} else {
+					// Wrap text to fit container width (first line has reduced width for indent)
+					child.WrappedLines = WrapTextWithIndent(text, fontSize, StyleFont(box.Style), innerWidth, firstLineWidth, box.Style.LetterSpacing, box.Style.WordSpacing)
+				}
 				child.TextIndentPx = textIndent
 
-				lineHeight := getLineHeightFromStyle(box.Style, parentTag)
 				numLines := len(child.WrappedLines)
 				if numLines == 0 {
 					numLines = 1
@@ -482,8 +525,11 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 
 				// Width is the widest wrapped line
 				maxLineWidth := 0.0
-				for _, line := range child.WrappedLines {
-					w := MeasureTextWithSpacingAndWordSpacing(line, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
+				for i, line := range child.WrappedLines {
+					w := MeasureStyledText(line, fontSize, StyleFont(box.Style), box.Style.LetterSpacing, box.Style.WordSpacing)
+					if i < len(child.LineOffsets) {
+						w += child.LineOffsets[i]
+					}
 					if w > maxLineWidth {
 						maxLineWidth = w
 					}
@@ -502,13 +548,9 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 						if gaps == 0 {
 							continue
 						}
-						lineWidth := MeasureTextWithSpacingAndWordSpacing(line, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
+						lineWidth := MeasureStyledText(line, fontSize, StyleFont(box.Style), box.Style.LetterSpacing, box.Style.WordSpacing)
 						// First line has reduced available width due to text-indent
-						availWidth := innerWidth
-						if i == 0 {
-							availWidth = firstLineWidth
-						}
-						extraSpace := availWidth - lineWidth
+						extraSpace := availWidth(i) - lineWidth
 						if extraSpace > 0 {
 							child.JustifyWordSpacings[i] = extraSpace / float64(gaps)
 						}
@@ -518,42 +560,29 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 
 		case InlineBox:
 			// Compute inline box size from its content
-			childWidth, childHeight = computeInlineSize(child, parentTag)
+			childWidth, childHeight = computeInlineSize(child, parentTag, innerWidth)
 
 		case ImageBox:
-			childWidth, childHeight = getImageSize(child.Node)
+			childWidth, childHeight = imageSize(child, innerWidth)
 			childWidth += 4 // Add small right margin between images
-		case InputBox:
-			childWidth = 200.0
-			childHeight = 28.0
+		case MediaBox:
+			childWidth, childHeight = imageSize(child, innerWidth)
 		case RadioBox:
 			childWidth = 20.0
 			childHeight = 20.0
 		case CheckboxBox:
 			childWidth = 20.0
 			childHeight = 20.0
-		case ButtonBox:
-			buttonText := getButtonText(child)
-			fontSize := getFontSize(parentTag)
-			childWidth = MeasureText(buttonText, fontSize) + 24.0
-			childHeight = 32.0
-		case TextareaBox:
-			childWidth = 300.0
-			childHeight = 80.0
-		case SelectBox:
-			childWidth = 200.0
-			childHeight = 28.0
-		case FileInputBox:
-			childWidth = 250.0
-			childHeight = 32.0
+		case InputBox, ButtonBox, TextareaBox, SelectBox, FileInputBox:
+			childWidth, childHeight = formControlSize(child, parentTag, innerWidth)
 
 		case HRBox:
 			// Block element - flush line first
-			alignWidth := innerWidth
+			alignWidth := lineRight - lineLeft
 			if firstLineOfBlock && blockTextIndent != 0 {
-				alignWidth = innerWidth - blockTextIndent
+				alignWidth -= blockTextIndent
 			}
-			applyLineAlignment(lineBoxes, innerX, alignWidth, box.Style.TextAlign, false)
+			applyLineAlignment(lineBoxes, lineLeft, alignWidth, box.Style.TextAlign, false)
 			lineBoxes = nil
 			firstLineOfBlock = false
 			if lineHeight > 0 {
@@ -565,60 +594,60 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 			child.Rect.Height = 2
 			yOffset += 18
 			// Reset line state
-			currentX = innerX
 			lineStartY = yOffset
 			lineHeight = 0
+			startLine()
 			hasPrevBlock = false
 			continue
 
 		case BRBox:
 			// Line break - flush current line
-			alignWidth := innerWidth
+			alignWidth := lineRight - lineLeft
 			if firstLineOfBlock && blockTextIndent != 0 {
-				alignWidth = innerWidth - blockTextIndent
+				alignWidth -= blockTextIndent
 			}
-			applyLineAlignment(lineBoxes, innerX, alignWidth, box.Style.TextAlign, false)
+			applyLineAlignment(lineBoxes, lineLeft, alignWidth, box.Style.TextAlign, false)
 			lineBoxes = nil
 			firstLineOfBlock = false
 			if lineHeight > 0 {
 				yOffset = lineStartY + lineHeight
 			} else {
-				yOffset += getLineHeightFromStyle(box.Style, parentTag)
+				yOffset += getLineHeightFromStyle(box.Style)
 			}
 			child.Rect.X = currentX
 			child.Rect.Y = yOffset
 			child.Rect.Width = 0
 			child.Rect.Height = 0
-			currentX = innerX
 			lineStartY = yOffset
 			lineHeight = 0
+			startLine()
 			hasPrevBlock = false
 			continue
 
 		case TableBox:
-			alignWidth := innerWidth
+			alignWidth := lineRight - lineLeft
 			if firstLineOfBlock && blockTextIndent != 0 {
-				alignWidth = innerWidth - blockTextIndent
+				alignWidth -= blockTextIndent
 			}
-			applyLineAlignment(lineBoxes, innerX, alignWidth, box.Style.TextAlign, false)
+			applyLineAlignment(lineBoxes, lineLeft, alignWidth, box.Style.TextAlign, false)
 			lineBoxes = nil
 			firstLineOfBlock = false
-			computeTableLayout(child, innerWidth, innerX, yOffset)
+			computeTableLayout(child, innerWidth, innerX, yOffset, viewportWidth)
 			yOffset += child.Rect.Height
 			// Reset line state
-			currentX = innerX
 			lineStartY = yOffset
 			lineHeight = 0
+			startLine()
 			hasPrevBlock = false
 			continue
 
 		default:
 			// Block element - flush line first
-			alignWidth := innerWidth
+			alignWidth := lineRight - lineLeft
 			if firstLineOfBlock && blockTextIndent != 0 {
-				alignWidth = innerWidth - blockTextIndent
+				alignWidth -= blockTextIndent
 			}
-			applyLineAlignment(lineBoxes, innerX, alignWidth, box.Style.TextAlign, false)
+			applyLineAlignment(lineBoxes, lineLeft, alignWidth, box.Style.TextAlign, false)
 			firstLineOfBlock = false
 			lineBoxes = nil
 			if lineHeight > 0 {
@@ -626,18 +655,23 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 				lineStartY = yOffset
 				lineHeight = 0
 			}
-			currentX = innerX
 
-			// Apply clear: push yOffset below float bottom edges
-			if child.Clear == "left" || child.Clear == "both" {
-				if leftFloatBottom > yOffset {
-					yOffset = leftFloatBottom
-				}
+			// Collapse the child's top margin with the previous sibling's
+			// bottom margin, or with this box's top margin when nothing
+			// precedes it
+			collapse := 0.0
+			if hasPrevBlock {
+				collapse = collapsedMarginDelta(prevBlockMarginBottom, estimatedBlockTopMargin(child))
+			} else if collapseThrough && yOffset == contentTop && box.Padding.Top == 0 && box.Style.BorderTopWidth == 0 {
+				collapse = collapsedMarginDelta(box.Margin.Top, estimatedBlockTopMargin(child))
 			}
-			if child.Clear == "right" || child.Clear == "both" {
-				if rightFloatBottom > yOffset {
-					yOffset = rightFloatBottom
-				}
+
+			// Apply clear: push yOffset below float bottom edges. Clearance
+			// separates the margins, so they no longer collapse.
+			if cleared := floats.clearance(child.Clear, yOffset-collapse); cleared > yOffset-collapse {
+				yOffset = math.Max(cleared, yOffset)
+			} else {
+				yOffset -= collapse
 			}
 
 			childTag := ""
@@ -645,45 +679,50 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 				childTag = child.Node.TagName
 			}
 
-			if hasPrevBlock && child.Clear == "" {
-				nextTop := estimatedBlockTopMargin(child)
-				yOffset -= collapsedPositiveMarginDelta(prevBlockMarginBottom, nextTop)
-			}
-
 			computeBlockLayout(child, blockLayoutParams{
 				containerWidth: innerWidth,
 				startX:         innerX,
 				startY:         yOffset,
 				parentTag:      childTag,
 				viewportWidth:  viewportWidth,
+				floats:         floats,
 			})
 			yOffset += child.Rect.Height
 			lineStartY = yOffset
-			prevBlockMarginBottom = child.Margin.Bottom
+			startLine()
+			prevBlockMarginBottom = child.collapsedMarginBottom
 			hasPrevBlock = true
 			continue
 		}
 
-		// Inline element - check if we need to wrap
-		if box.Style.WhiteSpace != "nowrap" && currentX+childWidth > innerX+innerWidth && currentX > innerX {
+		// Inline element - check if we need to wrap. A space at the end of
+		// a line hangs past it rather than wrapping (CSS Text §4.1.3).
+		if wrapsLines(box.Style.WhiteSpace) && currentX+childWidth > lineRight && currentX > lineLeft && !isWhitespaceText(child) {
 			// Wrap to new line - apply alignment first
-			effectiveWidth := innerWidth
+			effectiveWidth := lineRight - lineLeft
 			if firstLineOfBlock && blockTextIndent != 0 {
-				effectiveWidth = innerWidth - blockTextIndent
+				effectiveWidth -= blockTextIndent
 			}
-			applyLineAlignment(lineBoxes, innerX, effectiveWidth, box.Style.TextAlign, false)
+			applyLineAlignment(lineBoxes, lineLeft, effectiveWidth, box.Style.TextAlign, false)
 			lineBoxes = nil
 			yOffset = lineStartY + lineHeight
-			currentX = innerX // subsequent lines have no indent
 			lineStartY = yOffset
 			lineHeight = 0
 			firstLineOfBlock = false
+			startLine() // subsequent lines have no indent
+		}
+
+		// Move an empty line down past floats until the box fits beside them
+		if len(lineBoxes) == 0 && child.Type != TextBox && childWidth > lineRight-lineLeft {
+			lineStartY = floats.fitLine(lineStartY, nominalLineHeight, childWidth, innerX, innerX+innerWidth)
+			yOffset = lineStartY
+			startLine()
 		}
 
 		// Apply text-indent offset on the first line
 		// TextBox children handle indent via WrapTextWithIndent + render offset,
 		// so only offset currentX for non-TextBox inline elements.
-		if firstLineOfBlock && blockTextIndent != 0 && child.Type != TextBox && currentX == innerX {
+		if firstLineOfBlock && blockTextIndent != 0 && child.Type != TextBox && currentX == lineLeft {
 			currentX += blockTextIndent
 		}
 
@@ -695,7 +734,7 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 
 		// For InlineBox, position its children within it
 		if child.Type == InlineBox {
-			layoutInlineChildren(child, parentTag)
+			layoutInlineChildren(child, parentTag, innerWidth)
 		}
 
 		// Track this element for alignment
@@ -710,19 +749,37 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 	}
 
 	// Final line
-	finalAlignWidth := innerWidth
+	finalAlignWidth := lineRight - lineLeft
 	if firstLineOfBlock && blockTextIndent != 0 {
-		finalAlignWidth = innerWidth - blockTextIndent
+		finalAlignWidth -= blockTextIndent
 	}
-	applyLineAlignment(lineBoxes, innerX, finalAlignWidth, box.Style.TextAlign, true)
+	applyLineAlignment(lineBoxes, lineLeft, finalAlignWidth, box.Style.TextAlign, true)
 	if lineHeight > 0 {
 		yOffset = lineStartY + lineHeight
 	}
 
+	// The last child's bottom margin collapses with this box's when no
+	// padding, border or height separates them
+	box.collapsedMarginBottom = box.Margin.Bottom
+	if collapseThrough && hasPrevBlock && box.Padding.Bottom == 0 && box.Style.BorderBottomWidth == 0 &&
+		box.Style.Height == 0 && box.Style.MinHeight == 0 {
+		yOffset -= collapsedMarginDelta(prevBlockMarginBottom, box.Margin.Bottom)
+		box.collapsedMarginBottom = collapseMargins(prevBlockMarginBottom, box.Margin.Bottom)
+	}
+
+	// A formatting context root grows to contain its floats
+	if ownsFloats {
+		yOffset = floats.clearance("both", yOffset)
+	}
+
 	if box.Style.Height > 0 {
 		box.Rect.Height = box.Style.Height
 	} else {
 		box.Rect.Height = yOffset - startY + box.Margin.Bottom + box.Padding.Bottom + box.Style.BorderBottomWidth
+		// Content taller than the ratio allows still grows the box
+		if h := aspectRatioHeight(box); h > box.Rect.Height {
+			box.Rect.Height = h
+		}
 	}
 
 	// Reserve space for horizontal scrollbar when overflow-x is scroll/auto
@@ -750,60 +807,23 @@ func computeBlockLayout(box *LayoutBox, p blockLayoutParams) {
 		box.Rect.Height = box.Style.MaxHeight
 	}
 
-	// Position absolute children
+	// Shift relatively positioned children, then place the absolutely
+	// positioned boxes this box is the containing block for
+	applyRelativeOffsets(box.Children)
+	applyRelativeOffsets(floatedChildren)
 	for _, child := range positionedChildren {
-		containingW := containerWidth
-		if child.Position == "fixed" {
-			containingW = viewportWidth
-		}
-		childWidth := resolveWidth(child.Style, containingW)
-		if childWidth <= 0 {
-			childWidth = containingW
-		}
-
-		// First, compute layout to determine child dimensions
-		computeBlockLayout(child, blockLayoutParams{
-			containerWidth: childWidth,
-			startX:         0,
-			startY:         0,
-			parentTag:      "",
-			viewportWidth:  viewportWidth,
-		})
-
-		containingX := startX
-		containingY := startY
-		containingWidth := box.Rect.Width
-		containingHeight := box.Rect.Height
-
-		if child.Position == "fixed" {
-			containingX = 0
-			containingY = 0
-			containingWidth = viewportWidth
-		}
-
-		childX := containingX
-		if child.Style.LeftSet {
-			childX = containingX + child.Left
-		} else if child.Style.RightSet {
-			childX = containingX + containingWidth - child.Right - child.Rect.Width
-		}
-
-		childY := containingY
-		if child.Style.TopSet {
-			childY = containingY + child.Top
-		} else if child.Style.BottomSet {
-			childY = containingY + containingHeight - child.Bottom - child.Rect.Height
-		}
-
-		// Apply final position by offsetting the entire subtree
-		offsetBox(child, childX, childY)
-
-		box.Children = append(box.Children, child)
+		deferAbsolute(containingBlockFor(box, child), box, child)
+	}
+	if establishesContainingBlock(box) {
+		layoutAbsolutes(box, viewportWidth)
 	}
+	box.Children = append(box.Children, positionedChildren...)
 
 	// Append floated children back to preserve paint order
 	box.Children = append(box.Children, floatedChildren...)
 
+	computeScrollSize(box)
+	box.laidOut = &blockLayout{params: p, rect: box.Rect}
 }
 
 // offsetBox moves a box and all its children by (dx, dy)
@@ -811,7 +831,10 @@ func offsetBox(box *LayoutBox, dx, dy float64) {
 	box.Rect.X += dx
 	box.Rect.Y += dy
 	for _, child := range box.Children {
-		offsetBox(child, dx, dy)
+		// Fixed descendants are placed against the viewport, not box
+		if child.Position != "fixed" {
+			offsetBox(child, dx, dy)
+		}
 	}
 }
 
@@ -874,35 +897,30 @@ func resolveTextIndent(raw string, fontSize, containerWidth, viewportWidth float
 	return css.ParseSizeWithContext(raw, fontSize, viewportWidth, 0)
 }
 
-// computeInlineSize calculates the total size of an inline box from its children
-func computeInlineSize(box *LayoutBox, parentTag string) (float64, float64) {
+// computeInlineSize calculates the total size of an inline box from its children.
+// containerWidth resolves percentage widths and is 0 when unknown.
+func computeInlineSize(box *LayoutBox, parentTag string, containerWidth float64) (float64, float64) {
 	var totalWidth float64
 	var maxHeight float64
 
-	// Use the inline element's tag if it affects font size (e.g., <small>)
-	tagForSize := parentTag
-	if box.Node != nil && box.Node.TagName == dom.TagSmall {
-		tagForSize = dom.TagSmall
-	}
-
 	for _, child := range box.Children {
 		var w, h float64
 		switch child.Type {
 		case TextBox:
-			fontSize := getFontSize(tagForSize)
+			fontSize := styleFontSize(box.Style)
 			text := css.ApplyTextTransform(child.Text, box.Style.TextTransform, box.Style.FontVariant)
 
 			// Check if inside a <pre> element for multi-line handling
-			if isInsidePre(box) && strings.Contains(child.Text, "\n") {
-				w, h = measurePreformattedText(child.Text, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
+			if strings.Contains(child.Text, "\n") {
+				w, h = measurePreformattedText(child.Text, fontSize, preformattedFont(box.Style, child), box.Style.LetterSpacing, box.Style.WordSpacing)
 			} else {
-				w = MeasureTextWithSpacingAndWordSpacing(text, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
-				h = getLineHeightFromStyle(box.Style, tagForSize)
+				w = MeasureStyledText(text, fontSize, StyleFont(box.Style), box.Style.LetterSpacing, box.Style.WordSpacing)
+				h = getLineHeightFromStyle(box.Style)
 			}
 		case InlineBox:
-			w, h = computeInlineSize(child, parentTag)
-		case ImageBox:
-			w, h = getImageSize(child.Node)
+			w, h = computeInlineSize(child, parentTag, containerWidth)
+		case ImageBox, MediaBox:
+			w, h = imageSize(child, containerWidth)
 		case CheckboxBox, RadioBox:
 			w = 20.0
 			h = 20.0
@@ -917,32 +935,29 @@ func computeInlineSize(box *LayoutBox, parentTag string) (float64, float64) {
 }
 
 // layoutInlineChildren positions children within an inline box
-func layoutInlineChildren(box *LayoutBox, parentTag string) {
-	// Use the inline element's tag if it affects font size (e.g., <small>)
-	tagForSize := parentTag
-	if box.Node != nil && box.Node.TagName == dom.TagSmall {
-		tagForSize = dom.TagSmall
-	}
-
+func layoutInlineChildren(box *LayoutBox, parentTag string, containerWidth float64) {
 	// Calculate vertical offset for baseline alignment
-	parentLineHeight := getDefaultLineHeight(parentTag)
-	childLineHeight := getLineHeightFromStyle(box.Style, tagForSize)
+	childLineHeight := getLineHeightFromStyle(box.Style)
+	parentLineHeight := childLineHeight
+	if box.Parent != nil {
+		parentLineHeight = getLineHeightFromStyle(box.Parent.Style)
+	}
 	baselineOffset := (parentLineHeight - childLineHeight) / 2
 
 	offsetX := 0.0
 	for _, child := range box.Children {
 		switch child.Type {
 		case TextBox:
-			fontSize := getFontSize(tagForSize)
+			fontSize := styleFontSize(box.Style)
 			text := css.ApplyTextTransform(child.Text, box.Style.TextTransform, box.Style.FontVariant)
 
 			var w, h float64
 			// Check if inside a <pre> element for multi-line handling
-			if isInsidePre(box) && strings.Contains(child.Text, "\n") {
-				w, h = measurePreformattedText(child.Text, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
+			if strings.Contains(child.Text, "\n") {
+				w, h = measurePreformattedText(child.Text, fontSize, preformattedFont(box.Style, child), box.Style.LetterSpacing, box.Style.WordSpacing)
 			} else {
-				w = MeasureTextWithSpacingAndWordSpacing(text, fontSize, box.Style.LetterSpacing, box.Style.WordSpacing)
-				h = getLineHeightFromStyle(box.Style, tagForSize)
+				w = MeasureStyledText(text, fontSize, StyleFont(box.Style), box.Style.LetterSpacing, box.Style.WordSpacing)
+				h = getLineHeightFromStyle(box.Style)
 			}
 
 			child.Rect.X = box.Rect.X + offsetX
@@ -951,15 +966,15 @@ func layoutInlineChildren(box *LayoutBox, parentTag string) {
 			child.Rect.Height = h
 			offsetX += w
 		case InlineBox:
-			w, h := computeInlineSize(child, parentTag)
+			w, h := computeInlineSize(child, parentTag, containerWidth)
 			child.Rect.X = box.Rect.X + offsetX
 			child.Rect.Y = box.Rect.Y + baselineOffset
 			child.Rect.Width = w
 			child.Rect.Height = h
-			layoutInlineChildren(child, parentTag)
+			layoutInlineChildren(child, parentTag, containerWidth)
 			offsetX += w
-		case ImageBox:
-			w, h := getImageSize(child.Node)
+		case ImageBox, MediaBox:
+			w, h := imageSize(child, containerWidth)
 			child.Rect.X = box.Rect.X + offsetX
 			child.Rect.Y = box.Rect.Y
 			child.Rect.Width = w
@@ -1062,33 +1077,8 @@ func extractColWidths(tableNode *dom.Node, tableWidth float64) []float64 {
 	return widths
 }
 
-// measureTextWidth returns the natural (unwrapped) text width of a layout subtree
-// by recursively summing all text node widths. Used for shrink-to-fit table sizing.
-func measureTextWidth(box *LayoutBox) float64 {
-	return measureTextWidthWithSpacing(box, 0, 0)
-}
-
-func measureTextWidthWithSpacing(box *LayoutBox, inheritedLetterSpacing, inheritedWordSpacing float64) float64 {
-	letterSpacing := inheritedLetterSpacing
-	wordSpacing := inheritedWordSpacing
-	if box.Style.LetterSpacingSet || box.Style.LetterSpacing != 0 {
-		letterSpacing = box.Style.LetterSpacing
-	}
-	if box.Style.WordSpacingSet || box.Style.WordSpacing != 0 {
-		wordSpacing = box.Style.WordSpacing
-	}
-	if box.Type == TextBox {
-		return MeasureTextWithSpacingAndWordSpacing(box.Text, 16.0, letterSpacing, wordSpacing)
-	}
-	total := 0.0
-	for _, child := range box.Children {
-		total += measureTextWidthWithSpacing(child, letterSpacing, wordSpacing)
-	}
-	return total
-}
-
 // computeTableLayout handles table, row, and cell positioning
-func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY float64) {
+func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY, viewportWidth float64) {
 	tableWidth := containerWidth
 	hasExplicitWidth := false
 	if table.Style.Width > 0 {
@@ -1129,6 +1119,9 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 			}
 		}
 	}
+	if table.Node != nil {
+		rows = rowsInTableOrder(table.Node, rows)
+	}
 
 	// Count max logical columns (respecting both colspan and rowspan).
 	// A cell with rowspan>1 occupies grid positions in future rows,
@@ -1175,15 +1168,7 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 		return
 	}
 
-	cellPadding := 8.0
-
-	if table.Node != nil {
-		if p, ok := table.Node.Attributes["cellpadding"]; ok {
-			if parsed, err := strconv.Atoi(p); err == nil && parsed >= 0 {
-				cellPadding = float64(parsed)
-			}
-		}
-	}
+	cellPadding := tableCellPadding(table)
 
 	cellSpacing := 0.0
 	if table.Node != nil {
@@ -1194,6 +1179,17 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 		}
 	}
 
+	// border-spacing overrides the cellspacing attribute; collapsed borders
+	// leave no space between cells (CSS 2.1 §17.6)
+	spacingX, spacingY := cellSpacing, cellSpacing
+	if table.Style.BorderSpacingSet {
+		spacingX, spacingY = table.Style.BorderSpacingX, table.Style.BorderSpacingY
+	}
+	collapse := table.Style.BorderCollapse == "collapse"
+	if collapse {
+		spacingX, spacingY = 0, 0
+	}
+
 	tableBorder := 0
 	if table.Node != nil {
 		if b, ok := table.Node.Attributes["border"]; ok {
@@ -1206,7 +1202,9 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 	// Seed per-column widths from <col>/<colgroup> elements, then let
 	// individual cell explicit widths override via max() in the scan below.
 	colWidths := make([]float64, numCols)
-	naturalColWidths := make([]float64, numCols)
+	colMin := make([]float64, numCols)
+	colMax := make([]float64, numCols)
+	var spanning []spanningCell
 	if table.Node != nil {
 		for i, w := range extractColWidths(table.Node, table.Rect.Width) {
 			if i < numCols {
@@ -1215,8 +1213,9 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 		}
 	}
 
-	// Determine per-column widths: scan all cells for explicit CSS width values.
-	// For each logical column, use the maximum explicit width found across rows.
+	// Determine per-column widths: scan all cells for explicit CSS width values
+	// and their min-content and max-content widths. For each logical column,
+	// use the maximum found across rows.
 	{
 		occupied := make(map[int]map[int]bool)
 		for rowIdx, row := range rows {
@@ -1240,7 +1239,10 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 						}
 					}
 				}
-				// Only use width from non-spanning cells for column sizing
+				minW, maxW := cellContentWidths(cell)
+				minW, maxW = minW+cellPadding*2, maxW+cellPadding*2
+				// Only use width from non-spanning cells for column sizing;
+				// spanning cells widen their columns once those are known
 				if cs == 1 && colIdx < numCols {
 					w := cell.Style.Width
 					if w == 0 && cell.Style.WidthPercent > 0 {
@@ -1249,92 +1251,83 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 					if w > colWidths[colIdx] {
 						colWidths[colIdx] = w
 					}
-					// Natural content width for shrink-to-fit tables
-					natural := measureTextWidth(cell) + cellPadding*2
-					if natural > naturalColWidths[colIdx] {
-						naturalColWidths[colIdx] = natural
-					}
+					colMin[colIdx] = max(colMin[colIdx], minW)
+					colMax[colIdx] = max(colMax[colIdx], maxW)
+				} else if cs > 1 && colIdx < numCols {
+					spanning = append(spanning, spanningCell{
+						col:  colIdx,
+						span: min(cs, numCols-colIdx),
+						min:  minW - float64(cs-1)*spacingX,
+						max:  maxW - float64(cs-1)*spacingX,
+					})
 				}
 				colIdx += cs
 			}
 		}
 	}
 
-	if !hasExplicitWidth {
-		// Shrink-to-fit: use natural content widths for auto (width=0) columns
-		for i, w := range colWidths {
-			if w == 0 {
-				nat := naturalColWidths[i]
-				if nat < 24 {
-					nat = 24
-				}
-				colWidths[i] = nat
-			}
-		}
-		total := 0.0
-		for _, w := range colWidths {
-			total += w
-		}
-		tableWidth = total + float64(numCols+1)*cellSpacing
-		table.Rect.Width = tableWidth
-	} else {
-		// Explicit table width: distribute remaining space among auto columns
-		usedWidth := 0.0
-		autoCount := 0
-		for _, w := range colWidths {
-			if w > 0 {
-				usedWidth += w
-			} else {
-				autoCount++
-			}
+	// A specified width is the column's width, unless its content needs more
+	// (CSS 2.1 §17.5.2.2); columns with neither are at least 24px wide
+	for i, w := range colWidths {
+		if w > 0 {
+			colMin[i] = max(colMin[i], w)
+			colMax[i] = colMin[i]
+		} else {
+			colMax[i] = max(colMax[i], colMin[i], 24)
 		}
-		if autoCount > 0 {
-			remaining := tableWidth - usedWidth - float64(numCols+1)*cellSpacing
-			if remaining < 0 {
-				remaining = 0
-			}
-			autoWidth := remaining / float64(autoCount)
-			for i, w := range colWidths {
-				if w == 0 {
-					colWidths[i] = autoWidth
-				}
-			}
+	}
+	for _, cell := range spanning {
+		spreadSpanningCell(colMin, colMax, colWidths, cell)
+	}
+
+	// Auto tables shrink to fit their columns' max-content widths within
+	// the container; no table is narrower than its columns' min-content
+	spacing := float64(numCols+1) * spacingX
+	sumMin, sumMax := 0.0, 0.0
+	for i := range colMin {
+		sumMin += colMin[i]
+		sumMax += colMax[i]
+	}
+	if !hasExplicitWidth {
+		tableWidth = min(sumMax+spacing, containerWidth)
+	}
+	tableWidth = max(tableWidth, sumMin+spacing)
+	// Captions are never narrower than their widest word either
+	for _, child := range table.Children {
+		if child.Type == TableCaptionBox {
+			tableWidth = max(tableWidth, minContentWidth(child, dom.TagTable))
 		}
 	}
+	table.Rect.Width = tableWidth
+	colWidths = distributeColumnWidths(colMin, colMax, colWidths, tableWidth-spacing)
 
-	// Precompute cumulative X offsets per column (accounting for cellspacing)
+	// Precompute cumulative X offsets per column (accounting for border-spacing)
 	colXOffsets := make([]float64, numCols)
-	colXOffsets[0] = cellSpacing
+	colXOffsets[0] = spacingX
 	for i := 1; i < numCols; i++ {
-		colXOffsets[i] = colXOffsets[i-1] + colWidths[i-1] + cellSpacing
+		colXOffsets[i] = colXOffsets[i-1] + colWidths[i-1] + spacingX
 	}
 
 	yOffset := startY
 
-	// Handle caption first (renders above the table rows, centered)
+	// Captions go above the rows, or below them with caption-side: bottom
+	var bottomCaptions []*LayoutBox
 	for _, child := range table.Children {
-		if child.Type == TableCaptionBox {
-			child.Rect.X = startX
-			child.Rect.Y = yOffset
-			child.Rect.Width = tableWidth
-
-			// Center the caption text
-			captionHeight := 24.0
-			for _, textChild := range child.Children {
-				if textChild.Type == TextBox {
-					fontSize := 16.0
-					textWidth := MeasureTextWithSpacingAndWordSpacing(textChild.Text, fontSize, child.Style.LetterSpacing, child.Style.WordSpacing)
-					textChild.Rect.X = startX + (tableWidth-textWidth)/2 // centered
-					textChild.Rect.Y = yOffset
-					textChild.Rect.Width = textWidth
-					textChild.Rect.Height = 24.0
-				}
-			}
-			child.Rect.Height = captionHeight
-			yOffset += captionHeight + 4
+		if child.Type != TableCaptionBox {
+			continue
+		}
+		if child.Style.CaptionSide == "bottom" {
+			bottomCaptions = append(bottomCaptions, child)
+			continue
 		}
+		yOffset += layoutCaption(child, startX, yOffset, tableWidth, viewportWidth)
+	}
+	rowsTop := yOffset
+	yOffset += spacingY
+
+	if collapse {
+		collapseTableBorders(table, rows, tableBorder)
 	}
-	yOffset += cellSpacing
 
 	// Layout each row (grid-aware for rowspan support)
 	type rowspanEntry struct {
@@ -1384,7 +1377,7 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 				cellWidth += colWidths[c]
 			}
 			if cs > 1 {
-				cellWidth += float64(cs-1) * cellSpacing
+				cellWidth += float64(cs-1) * spacingX
 			}
 			xPos := startX
 			if colIdx < numCols {
@@ -1394,10 +1387,12 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 			cell.Rect.X = xPos
 			cell.Rect.Y = yOffset
 			cell.Rect.Width = cellWidth
-			cell.TableBorder = tableBorder
+			if !collapse {
+				cell.TableBorder = tableBorder
+			}
 
 			// Compute cell content height
-			cellHeight := computeCellContent(cell, cellWidth-cellPadding*2, xPos+cellPadding, yOffset+cellPadding)
+			cellHeight := computeCellContent(cell, cellWidth-cellPadding*2, xPos+cellPadding, yOffset+cellPadding, viewportWidth)
 			cell.Rect.Height = cellHeight + cellPadding*2
 			cellContentH[cell] = cellHeight
 
@@ -1461,7 +1456,7 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 
 		row.Rect.Height = rowHeight
 		rowHeights[rowIdx] = rowHeight
-		yOffset += rowHeight + cellSpacing
+		yOffset += rowHeight + spacingY
 	}
 
 	// Resolve rowspan cell heights.
@@ -1486,13 +1481,7 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 
 	// Reposition rows and cells if row heights changed due to rowspan overflow
 	if needsReposition {
-		yOffset = startY
-		for _, child := range table.Children {
-			if child.Type == TableCaptionBox {
-				yOffset += child.Rect.Height + 4
-			}
-		}
-		yOffset += cellSpacing
+		yOffset = rowsTop + spacingY
 		for rowIdx, row := range rows {
 			row.Rect.Y = yOffset
 			row.Rect.Height = rowHeights[rowIdx]
@@ -1503,10 +1492,10 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 						cell.Rect.Height = rowHeights[rowIdx]
 					}
 					// Re-layout cell content at new position
-					computeCellContent(cell, cell.Rect.Width-cellPadding*2, cell.Rect.X+cellPadding, yOffset+cellPadding)
+					computeCellContent(cell, cell.Rect.Width-cellPadding*2, cell.Rect.X+cellPadding, yOffset+cellPadding, viewportWidth)
 				}
 			}
-			yOffset += rowHeights[rowIdx] + cellSpacing
+			yOffset += rowHeights[rowIdx] + spacingY
 		}
 	}
 
@@ -1524,183 +1513,199 @@ func computeTableLayout(table *LayoutBox, containerWidth float64, startX, startY
 		rs.cell.Rect.Y = rows[rs.startRow].Rect.Y
 	}
 
+	rowsBottom := yOffset
+	for _, caption := range bottomCaptions {
+		yOffset += layoutCaption(caption, startX, yOffset, tableWidth, viewportWidth)
+	}
+
 	table.Rect.Height = yOffset - startY
 
 	// Set dimensions on tbody/thead/tfoot wrappers so hit testing works
 	for _, wrapper := range wrappers {
 		wrapper.Rect.X = startX
-		wrapper.Rect.Y = startY
+		wrapper.Rect.Y = rowsTop
 		wrapper.Rect.Width = tableWidth
-		wrapper.Rect.Height = table.Rect.Height
+		wrapper.Rect.Height = rowsBottom - rowsTop
 	}
 }
 
-// computeCellContent layouts the content inside a table cell
-func computeCellContent(cell *LayoutBox, width float64, startX, startY float64) float64 {
-	currentX := startX
-	currentY := startY
-	lineHeight := 24.0
-	maxY := startY
+// layoutCaption lays out a table caption as a block across the table's
+// width at y, and returns the height it takes up, margins included.
+func layoutCaption(caption *LayoutBox, x, y, width, viewportWidth float64) float64 {
+	computeBlockLayout(caption, blockLayoutParams{
+		containerWidth: width,
+		startX:         x,
+		startY:         y,
+		parentTag:      dom.TagTable,
+		viewportWidth:  viewportWidth,
+	})
+	return caption.Rect.Height
+}
 
-	var layoutInline func(box *LayoutBox, inheritedLetterSpacing, inheritedWordSpacing float64, inheritedWhiteSpace string)
-	layoutInline = func(box *LayoutBox, inheritedLetterSpacing, inheritedWordSpacing float64, inheritedWhiteSpace string) {
-		letterSpacing := inheritedLetterSpacing
-		wordSpacing := inheritedWordSpacing
-		whiteSpace := inheritedWhiteSpace
-		if box.Style.LetterSpacingSet || box.Style.LetterSpacing != 0 {
-			letterSpacing = box.Style.LetterSpacing
-		}
-		if box.Style.WordSpacingSet || box.Style.WordSpacing != 0 {
-			wordSpacing = box.Style.WordSpacing
-		}
-		if box.Style.WhiteSpace != "" {
-			whiteSpace = box.Style.WhiteSpace
+// rowsInTableOrder reorders row boxes to match dom.RowsInOrder, so thead rows
+// stack first and tfoot rows last whatever their source order. Rows without a
+// DOM counterpart keep their relative order at the end.
+func rowsInTableOrder(tableNode *dom.Node, rows []*LayoutBox) []*LayoutBox {
+	byNode := make(map[*dom.Node]*LayoutBox, len(rows))
+	for _, row := range rows {
+		if row.Node != nil {
+			byNode[row.Node] = row
 		}
-		switch box.Type {
-		case TextBox:
-			fontSize := 16.0
-			box.Rect.X = currentX
-			box.Rect.Y = currentY
-			if whiteSpace == "nowrap" {
-				box.WrappedLines = nil
-				textWidth := MeasureTextWithSpacingAndWordSpacing(box.Text, fontSize, letterSpacing, wordSpacing)
-				box.Rect.Width = textWidth
-				box.Rect.Height = lineHeight
-				currentX += textWidth
-				if currentY+lineHeight > maxY {
-					maxY = currentY + lineHeight
-				}
-			} else {
-				lines := WrapTextWithSpacing(box.Text, fontSize, width, letterSpacing, wordSpacing)
-				if len(lines) > 1 {
-					box.WrappedLines = lines
-					box.Rect.Width = width
-					totalHeight := float64(len(lines)) * lineHeight
-					box.Rect.Height = totalHeight
-					currentY += totalHeight
-					currentX = startX
-					if currentY > maxY {
-						maxY = currentY
-					}
-				} else {
-					box.WrappedLines = lines
-					textWidth := MeasureTextWithSpacingAndWordSpacing(box.Text, fontSize, letterSpacing, wordSpacing)
-					box.Rect.Width = textWidth
-					box.Rect.Height = lineHeight
-					currentX += textWidth
-					if currentY+lineHeight > maxY {
-						maxY = currentY + lineHeight
-					}
-				}
-			}
-
-		case InlineBox:
-			box.Rect.X = currentX
-			box.Rect.Y = currentY
-			prevY := currentY // capture before children may advance it via nested blocks
-			childStartX := currentX
-			for _, child := range box.Children {
-				layoutInline(child, letterSpacing, wordSpacing, whiteSpace)
-			}
-			box.Rect.Width = currentX - childStartX
-			box.Rect.Height = lineHeight
-			// Use prevY (not the post-children currentY) to avoid double-counting when
-			// a block child already advanced currentY.
-			if prevY+lineHeight > maxY {
-				maxY = prevY + lineHeight
-			}
-
-		case BRBox:
-			currentY += lineHeight
-			currentX = startX
-			if currentY > maxY {
-				maxY = currentY
-			}
-
-		case BlockBox:
-			if currentX > startX {
-				currentY += lineHeight
-				currentX = startX
-			}
-			box.Rect.X = startX
-			box.Rect.Y = currentY
-			beforeY := currentY
-			for _, child := range box.Children {
-				layoutInline(child, letterSpacing, wordSpacing, whiteSpace)
-			}
-			// Advance currentY to the furthest point reached by children (maxY).
-			// If nothing was drawn (empty block), leave currentY unchanged — don't
-			// add phantom height for elements like <div class="votearrow"/>.
-			if maxY > beforeY {
-				currentY = maxY
-			}
-			currentX = startX
-			if currentY > maxY {
-				maxY = currentY
-			}
-
-		case TableBox:
-			// Nested table inside cell - layout it recursively
-			if currentX > startX {
-				currentY += lineHeight
-				currentX = startX
-			}
-			computeTableLayout(box, width, startX, currentY)
-			currentY += box.Rect.Height
-			currentX = startX
-			if currentY > maxY {
-				maxY = currentY
-			}
-
-		case ImageBox:
-			imgW, imgH := getImageSize(box.Node)
-			box.Rect.X = currentX
-			box.Rect.Y = currentY
-			box.Rect.Width = imgW
-			box.Rect.Height = imgH
-			currentX += imgW
-			if currentY+imgH > maxY {
-				maxY = currentY + imgH
-			}
-
-		default:
-			for _, child := range box.Children {
-				layoutInline(child, letterSpacing, wordSpacing, whiteSpace)
-			}
+	}
+	ordered := make([]*LayoutBox, 0, len(rows))
+	for _, n := range dom.RowsInOrder(tableNode) {
+		if row, ok := byNode[n]; ok {
+			ordered = append(ordered, row)
+			delete(byNode, n)
 		}
 	}
-
-	for _, child := range cell.Children {
-		layoutInline(child, cell.Style.LetterSpacing, cell.Style.WordSpacing, cell.Style.WhiteSpace)
+	for _, row := range rows {
+		if row.Node == nil || byNode[row.Node] != nil {
+			ordered = append(ordered, row)
+		}
 	}
+	return ordered
+}
 
-	return maxY - startY
+// computeCellContent lays out the content of a table cell in a block of
+// the given width at (startX, startY), and returns its height. A cell is a
+// block container of its own: its content gets full block layout, with
+// the margins, lists, floats and nested tables that brings.
+func computeCellContent(cell *LayoutBox, width float64, startX, startY, viewportWidth float64) float64 {
+	tag := dom.TagTD
+	if cell.Node != nil {
+		tag = cell.Node.TagName
+	}
+	content := &LayoutBox{Type: BlockBox, Parent: cell, Style: contentStyle(cell.Style), Children: cell.Children}
+	computeBlockLayout(content, blockLayoutParams{
+		containerWidth: width,
+		startX:         startX,
+		startY:         startY,
+		parentTag:      tag,
+		viewportWidth:  viewportWidth,
+	})
+	cell.Children = content.Children
+	return content.Rect.Height
 }
 
 // getImageSize reads width/height attributes or returns defaults
 func getImageSize(node *dom.Node) (float64, float64) {
+	width, height := imageAttributeSize(node, 0)
+	if width == 0 {
+		width = DefaultImageWidth
+	}
+	if height == 0 {
+		height = DefaultImageHeight
+	}
+	return width, height
+}
+
+// imageAttributeSize reads the width and height attributes, 0 when missing
+// or invalid. A percentage width resolves against containerWidth, so it is
+// 0 when containerWidth is; percentage heights have nothing to resolve
+// against and are always 0.
+func imageAttributeSize(node *dom.Node, containerWidth float64) (width, height float64) {
 	if node == nil {
-		return DefaultImageWidth, DefaultImageHeight
+		return 0, 0
+	}
+	if w, ok := node.Attributes["width"]; ok {
+		width = utils.ParseHTMLSizeAttribute(w, containerWidth)
 	}
+	if h, ok := node.Attributes["height"]; ok {
+		height = utils.ParseHTMLSizeAttribute(h, 0)
+	}
+	return max(width, 0), max(height, 0)
+}
 
-	width := DefaultImageWidth
-	height := DefaultImageHeight
+// imageSize returns the used size of an image or media box. CSS width and
+// height override the attributes; a missing dimension follows from the
+// other through aspect-ratio, or the ratio of the width and height
+// attributes, so the box takes its final size before the image loads.
+// containerWidth resolves percentage widths and is 0 when unknown.
+func imageSize(box *LayoutBox, containerWidth float64) (float64, float64) {
+	defaultWidth, defaultHeight, defaultRatio := defaultReplacedSize(box)
+	// Only absolute attributes give a ratio; a percentage width does not
+	attrW, attrH := imageAttributeSize(box.Node, 0)
+	width := resolveWidth(box.Style, containerWidth)
+	height := box.Style.Height
+	if width == 0 && height == 0 {
+		// The attributes only size an image CSS does not; otherwise they
+		// just give the ratio, as with the common "height: auto" reset
+		width, height = imageAttributeSize(box.Node, containerWidth)
+	}
+	if width == 0 && height == 0 && box.Style.AspectRatio == 0 && box.Type == ImageBox && box.brokenImage {
+		return brokenImageSize(box)
+	}
 
-	if w, ok := node.Attributes["width"]; ok {
-		if parsed := utils.ParseHTMLSizeAttribute(w, 0); parsed > 0 {
-			width = parsed
+	ratio := box.Style.AspectRatio
+	if ratio == 0 && attrW > 0 && attrH > 0 {
+		ratio = attrW / attrH
+	}
+	if ratio == 0 && defaultRatio {
+		ratio = defaultWidth / defaultHeight
+	}
+	if ratio > 0 {
+		switch {
+		case width > 0 && height == 0:
+			height = width / ratio
+		case height > 0 && width == 0:
+			width = height * ratio
+		case width == 0 && height == 0:
+			width = defaultWidth
+			height = width / ratio
 		}
 	}
 
-	if h, ok := node.Attributes["height"]; ok {
-		if parsed := utils.ParseHTMLSizeAttribute(h, 0); parsed > 0 {
-			height = parsed
-		}
+	if width == 0 {
+		width = defaultWidth
 	}
+	if height == 0 {
+		height = defaultHeight
+	}
+	return width, height
+}
 
+// formControlSize returns the used size of a form control box. CSS width and
+// height override the control's intrinsic size, with percentage widths
+// resolved against containerWidth (0 when unknown).
+func formControlSize(box *LayoutBox, parentTag string, containerWidth float64) (float64, float64) {
+	var width, height float64
+	switch box.Type {
+	case InputBox, SelectBox:
+		width, height = 200.0, 28.0
+	case TextareaBox:
+		width, height = 300.0, 80.0
+	case FileInputBox:
+		width, height = 250.0, 32.0
+	case ButtonBox:
+		width = MeasureText(getButtonText(box), styleFontSize(box.Style)) + 24.0
+		height = 32.0
+	}
+	if w := resolveWidth(box.Style, containerWidth); w > 0 {
+		width = w
+	}
+	if box.Style.Height > 0 {
+		height = box.Style.Height
+	}
 	return width, height
 }
 
+// aspectRatioHeight returns the height aspect-ratio gives a block box of
+// auto height at its used width, or 0 when no ratio applies. The ratio
+// sizes the content box unless box-sizing is border-box.
+func aspectRatioHeight(box *LayoutBox) float64 {
+	ratio := box.Style.AspectRatio
+	if ratio <= 0 || box.Style.Height > 0 {
+		return 0
+	}
+	if box.Style.BoxSizing == "border-box" {
+		return box.Rect.Width / ratio
+	}
+	horizontal := box.Padding.Left + box.Padding.Right + box.Style.BorderLeftWidth + box.Style.BorderRightWidth
+	vertical := box.Padding.Top + box.Padding.Bottom + box.Style.BorderTopWidth + box.Style.BorderBottomWidth
+	return max(box.Rect.Width-horizontal, 0)/ratio + vertical
+}
+
 func (box *LayoutBox) Print(indent int) {
 	prefix := strings.Repeat("  ", indent)
 
@@ -1712,6 +1717,8 @@ func (box *LayoutBox) Print(indent int) {
 		typeName = "Text"
 	case ImageBox:
 		typeName = "Image"
+	case MediaBox:
+		typeName = "Media"
 	}
 
 	if box.Type == TextBox {
@@ -1751,8 +1758,19 @@ func isInsidePre(box *LayoutBox) bool {
 	return false
 }
 
-// measurePreformattedText calculates width and height for multi-line text inside <pre>
-func measurePreformattedText(text string, fontSize, letterSpacing, wordSpacing float64) (width, height float64) {
+// preformattedFont returns the font preformatted text box is measured in:
+// the font of style, monospace inside <pre>.
+func preformattedFont(style css.Style, box *LayoutBox) Font {
+	font := StyleFont(style)
+	if isInsidePre(box) {
+		font.Monospace = true
+	}
+	return font
+}
+
+// measurePreformattedText calculates width and height for text whose line
+// feeds and tabs are preserved, one line per line feed
+func measurePreformattedText(text string, fontSize float64, font Font, letterSpacing, wordSpacing float64) (width, height float64) {
 	// Expand tabs to spaces for proper alignment
 	text = dom.ExpandTabs(text, 8)
 	lines := strings.Split(text, "\n")
@@ -1761,7 +1779,7 @@ func measurePreformattedText(text string, fontSize, letterSpacing, wordSpacing f
 	// Find widest line
 	maxWidth := 0.0
 	for _, line := range lines {
-		lw := MeasureTextWithSpacingAndWordSpacing(line, fontSize, letterSpacing, wordSpacing)
+		lw := MeasureStyledText(line, fontSize, font, letterSpacing, wordSpacing)
 		if lw > maxWidth {
 			maxWidth = lw
 		}
@@ -1770,6 +1788,10 @@ func measurePreformattedText(text string, fontSize, letterSpacing, wordSpacing f
 	return maxWidth, float64(len(lines)) * lineHeight
 }
 
+// DefaultButtonLabels are the labels of submit and reset inputs without a
+// value, by button type.
+var DefaultButtonLabels = map[string]string{"submit": "Submit", "reset": "Reset"}
+
 // getButtonText extracts text content from a button element
 func getButtonText(box *LayoutBox) string {
 	for _, child := range box.Children {
@@ -1781,6 +1803,9 @@ func getButtonText(box *LayoutBox) string {
 		if val, ok := box.Node.Attributes["value"]; ok {
 			return val
 		}
+		if label := DefaultButtonLabels[dom.ButtonType(box.Node)]; label != "" && box.Node.TagName == dom.TagInput {
+			return label
+		}
 	}
 	return "Button"
 }
@@ -1795,11 +1820,13 @@ func GetLegendText(box *LayoutBox) string {
 	return ""
 }
 
-func getLineHeightFromStyle(style css.Style, tagName string) float64 {
+// getLineHeightFromStyle returns the used line height: the computed
+// line-height, or `normal` derived from the font metrics at the painted size.
+func getLineHeightFromStyle(style css.Style) float64 {
 	if style.LineHeight > 0 {
 		return style.LineHeight
 	}
-	return getDefaultLineHeight(tagName)
+	return NormalLineHeight(styleFontSize(style))
 }
 
 func getCellVerticalAlign(cell *LayoutBox) string {
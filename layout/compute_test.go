@@ -153,80 +153,67 @@ func TestColWidthLayout(t *testing.T) {
 	}
 }
 
-func TestGetLineHeight(t *testing.T) {
-	tests := []struct {
-		tag      string
-		expected float64
-	}{
-		{"h1", 40.0},
-		{"h2", 32.0},
-		{"h3", 26.0},
-		{"h4", 24.0},
-		{"h5", 22.0},
-		{"h6", 20.0},
-		{"small", 18.0},
-		{"p", 24.0},
-		{"div", 24.0},
-		{"span", 24.0},
-		{"", 24.0},
-		{"unknown", 24.0},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.tag, func(t *testing.T) {
-			result := getDefaultLineHeight(tt.tag)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
 func TestGetLineHeightFromStyle(t *testing.T) {
 	tests := []struct {
 		name       string
 		lineHeight float64
-		tagName    string
+		fontSize   float64
 		expected   float64
 	}{
-		{"style has line-height", 32.0, "p", 32.0},
-		{"style has line-height overrides tag default", 50.0, "h1", 50.0},
-		{"no line-height falls back to h1 default", 0, "h1", 40.0},
-		{"no line-height falls back to h2 default", 0, "h2", 32.0},
-		{"no line-height falls back to p default", 0, "p", 24.0},
-		{"small line-height value", 12.0, "p", 12.0},
+		{"style has line-height", 32.0, 16, 32.0},
+		{"style has line-height overrides font size", 50.0, 32, 50.0},
+		{"no line-height uses normal at 32px", 0, 32, 32.0 * 1.5},
+		{"no line-height uses normal at 24px", 0, 24, 24.0 * 1.5},
+		{"no line-height uses normal at default size", 0, 0, 16.0 * 1.5},
+		{"small line-height value", 12.0, 16, 12.0},
 	}
 
+	defer func(m FontMetrics) { BaseFontMetrics = m }(BaseFontMetrics)
+	BaseFontMetrics = FontMetrics{Ascent: 1.0, Descent: 0.25, LineGap: 0.25}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			style := css.Style{LineHeight: tt.lineHeight}
-			result := getLineHeightFromStyle(style, tt.tagName)
+			style := css.Style{LineHeight: tt.lineHeight, FontSize: tt.fontSize}
+			result := getLineHeightFromStyle(style)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
-func TestGetFontSize(t *testing.T) {
+func TestTextMeasuredAtComputedFontSize(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
 	tests := []struct {
+		name     string
+		html     string
+		css      string
 		tag      string
-		expected float64
+		fontSize float64
 	}{
-		{"h1", 32.0},
-		{"h2", 24.0},
-		{"h3", 18.0},
-		{"h4", 16.0},
-		{"h5", 14.0},
-		{"h6", 12.0},
-		{"small", 12.0},
-		{"p", 16.0},
-		{"div", 16.0},
-		{"span", 16.0},
-		{"", 16.0},
-		{"unknown", 16.0},
+		{"heading", `<h1>Title</h1>`, "", "h1", 32},
+		{"small", `<p>a <small>note</small></p>`, "", "small", 12},
+		{"inherited from body", `<p>Text</p>`, "body { font-size: 20px; }", "p", 20},
+		{"author size on heading", `<h2>Title</h2>`, "h2 { font-size: 10px; }", "h2", 10},
+		{"table cell", `<table><tr><td>Cell</td></tr></table>`, "td { font-size: 24px; }", "td", 24},
+		{"button", `<button>Go</button>`, "button { font-size: 30px; }", "button", 30},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.tag, func(t *testing.T) {
-			result := getFontSize(tt.tag)
-			assert.Equal(t, tt.expected, result)
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTML(tt.html)
+			tree := BuildLayoutTree(doc, createStylesheet(tt.css), Viewport{}, css.MatchContext{})
+			ComputeLayout(tree, 800)
+
+			box := findBoxByTag(tree, tt.tag)
+			assert.Equal(t, tt.fontSize, box.Style.FontSize)
+			if box.Type == ButtonBox {
+				assert.Equal(t, MeasureText("Go", tt.fontSize)+24, box.Rect.Width)
+				return
+			}
+			text := box.Children[0]
+			assert.Equal(t, MeasureStyledText(text.Text, tt.fontSize, StyleFont(box.Style), 0, 0), text.Rect.Width)
 		})
 	}
 }
@@ -261,6 +248,115 @@ func TestGetImageSize(t *testing.T) {
 	}
 }
 
+func TestImageSize(t *testing.T) {
+	tests := []struct {
+		name           string
+		attrs          map[string]string
+		css            string
+		expectedWidth  float64
+		expectedHeight float64
+	}{
+		{"defaults", map[string]string{}, "", 200, 150},
+		{"attributes", map[string]string{"width": "400", "height": "300"}, "", 400, 300},
+		{"css width keeps the attribute ratio", map[string]string{"width": "400", "height": "300"}, "width: 200px", 200, 150},
+		{"css height keeps the attribute ratio", map[string]string{"width": "400", "height": "300"}, "height: 60px", 80, 60},
+		{"percentage width", map[string]string{"width": "400", "height": "200"}, "width: 50%", 250, 125},
+		{"css size wins over both attributes", map[string]string{"width": "400", "height": "300"}, "width: 10px; height: 20px", 10, 20},
+		{"aspect-ratio derives the height", map[string]string{"width": "320"}, "aspect-ratio: 16 / 9", 320, 180},
+		{"aspect-ratio overrides the attribute ratio", map[string]string{"width": "400", "height": "300"}, "width: 100px; aspect-ratio: 2", 100, 50},
+		{"aspect-ratio alone", map[string]string{}, "aspect-ratio: 1", 200, 200},
+		{"single attribute without a ratio", map[string]string{"width": "300"}, "", 300, 150},
+		{"percentage width attribute", map[string]string{"width": "50%"}, "", 250, 150},
+		{"percentage width attribute gives no ratio", map[string]string{"width": "50%", "height": "100"}, "", 250, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := &LayoutBox{Type: ImageBox, Node: dom.NewElement("img", tt.attrs), Style: css.ParseInlineStyle(tt.css)}
+			w, h := imageSize(box, 500)
+			assert.Equal(t, tt.expectedWidth, w)
+			assert.Equal(t, tt.expectedHeight, h)
+		})
+	}
+
+	// Once the image has loaded, its natural size stands in for the defaults
+	loaded := []struct {
+		name           string
+		attrs          map[string]string
+		css            string
+		expectedWidth  float64
+		expectedHeight float64
+	}{
+		{"natural size", map[string]string{}, "", 640, 480},
+		{"width keeps the natural ratio", map[string]string{"width": "320"}, "", 320, 240},
+		{"css height keeps the natural ratio", map[string]string{}, "height: 120px", 160, 120},
+		{"attributes win", map[string]string{"width": "100", "height": "100"}, "", 100, 100},
+	}
+	for _, tt := range loaded {
+		t.Run(tt.name, func(t *testing.T) {
+			box := &LayoutBox{Type: ImageBox, Node: dom.NewElement("img", tt.attrs), Style: css.ParseInlineStyle(tt.css)}
+			box.naturalWidth, box.naturalHeight = 640, 480
+			w, h := imageSize(box, 500)
+			assert.Equal(t, tt.expectedWidth, w)
+			assert.Equal(t, tt.expectedHeight, h)
+		})
+	}
+}
+
+func TestFormControlSize(t *testing.T) {
+	tests := []struct {
+		name           string
+		boxType        BoxType
+		css            string
+		expectedWidth  float64
+		expectedHeight float64
+	}{
+		{"input default", InputBox, "", 200, 28},
+		{"textarea default", TextareaBox, "", 300, 80},
+		{"input px width", InputBox, "width: 120px", 120, 28},
+		{"input percentage width", InputBox, "width: 50%", 250, 28},
+		{"select percentage width", SelectBox, "width: 100%", 500, 28},
+		{"textarea css height", TextareaBox, "height: 40px", 300, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := &LayoutBox{Type: tt.boxType, Style: css.ParseInlineStyle(tt.css)}
+			w, h := formControlSize(box, "", 500)
+			assert.Equal(t, tt.expectedWidth, w)
+			assert.Equal(t, tt.expectedHeight, h)
+		})
+	}
+}
+
+func TestAspectRatioLayout(t *testing.T) {
+	tests := []struct {
+		name       string
+		tag        string
+		html       string
+		css        string
+		wantHeight float64
+	}{
+		{"block height follows the width", "div", `<div></div>`, "div { width: 320px; aspect-ratio: 16 / 9 }", 180},
+		{"content box by default", "div", `<div></div>`, "div { width: 100px; padding: 10px; aspect-ratio: 1 }", 120},
+		{"border box", "div", `<div></div>`, "div { width: 100px; padding: 10px; box-sizing: border-box; aspect-ratio: 2 }", 50},
+		{"explicit height wins", "div", `<div></div>`, "div { width: 100px; height: 30px; aspect-ratio: 1 }", 30},
+		{"image in a line", "img", `<p><img src="a.png" width="640" height="480"></p>`, "img { width: 320px }", 240},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(tt.html, tt.css)
+			ComputeLayout(tree, 600)
+
+			box := findBoxByTag(tree, tt.tag)
+			if assert.NotNil(t, box) {
+				assert.Equal(t, tt.wantHeight, box.Rect.Height)
+			}
+		})
+	}
+}
+
 func TestIsInsidePre(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -344,6 +440,16 @@ func TestGetButtonText(t *testing.T) {
 			},
 			expected: "Submit",
 		},
+		{
+			name: "reset input without a value",
+			setup: func() *LayoutBox {
+				return &LayoutBox{
+					Type: ButtonBox,
+					Node: dom.NewElement("input", map[string]string{"type": "reset"}),
+				}
+			},
+			expected: "Reset",
+		},
 		{
 			name: "default when no text or value",
 			setup: func() *LayoutBox {
@@ -1117,7 +1223,7 @@ func TestTableCellTextWrapping(t *testing.T) {
 				textBox := findTextBoxInSubtree(cell, "Hi")
 				assert.NotNil(t, textBox)
 				assert.True(t, len(textBox.WrappedLines) <= 1, "short text should not have multiple wrapped lines")
-				assert.Equal(t, 24.0, textBox.Rect.Height)
+				assert.Equal(t, NormalLineHeight(16), textBox.Rect.Height)
 			},
 		},
 		{
@@ -1138,7 +1244,7 @@ func TestTableCellTextWrapping(t *testing.T) {
 			verify: func(t *testing.T, tree *LayoutBox) {
 				cell := findCellByText(tree, "Hello World")
 				assert.NotNil(t, cell)
-				lineHeight := 24.0
+				lineHeight := NormalLineHeight(16)
 				cellPadding := 8.0
 				// 2 wrapped lines + top and bottom padding
 				assert.Equal(t, 2*lineHeight+2*cellPadding, cell.Rect.Height)
@@ -1152,7 +1258,7 @@ func TestTableCellTextWrapping(t *testing.T) {
 				assert.NotNil(t, cell)
 				textBox := findTextBoxInSubtree(cell, "Hello World")
 				assert.NotNil(t, textBox)
-				lineHeight := 24.0
+				lineHeight := NormalLineHeight(16)
 				assert.Equal(t, 2*lineHeight, textBox.Rect.Height)
 			},
 		},
@@ -1167,6 +1273,68 @@ func TestTableCellTextWrapping(t *testing.T) {
 	}
 }
 
+func TestTableCellBlockContent(t *testing.T) {
+	tree := buildTree(`<table><tr><td style="width: 200px;"><h3>Title</h3><p>Para</p><div style="float: right; width: 40px;">F</div>after</td></tr></table>`)
+	ComputeLayout(tree, 600)
+
+	cell := findCellByText(tree, "Title")
+	h3, p, float := findBoxByTag(cell, "h3"), findBoxByTag(cell, "p"), findBoxByTag(cell, "div")
+	assert.NotNil(t, h3)
+	assert.NotNil(t, p)
+	assert.NotNil(t, float)
+
+	// Blocks keep their margins inside the cell's padding and stack
+	title := findTextBoxInSubtree(h3, "Title")
+	assert.Greater(t, h3.Margin.Top, 0.0)
+	assert.Equal(t, cell.Rect.Y+8+h3.Margin.Top, title.Rect.Y)
+	assert.Equal(t, 1.125*16, textFontSize(title))
+	para := findTextBoxInSubtree(p, "Para")
+	assert.Greater(t, para.Rect.Y, title.Rect.Y+title.Rect.Height)
+
+	// Floats go to the right of the cell's content box
+	assert.Equal(t, cell.Rect.X+cell.Rect.Width-8-40, float.Rect.X)
+	assert.GreaterOrEqual(t, cell.Rect.Y+cell.Rect.Height, float.Rect.Y+float.Rect.Height+8)
+}
+
+func TestTableCaption(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	layoutTable := func(html, css string) (table, caption, cell *LayoutBox) {
+		tree := buildTreeWithCSS(html, css)
+		ComputeLayout(tree, 600)
+		return findBoxByTag(tree, "table"), findBoxByTag(tree, "caption"), findCellByText(tree, "Cell")
+	}
+
+	t.Run("above the rows and centered", func(t *testing.T) {
+		table, caption, cell := layoutTable(`<table><caption>Title</caption><tr><td style="width: 200px">Cell</td></tr></table>`, "")
+		assert.Equal(t, table.Rect.Y, caption.Rect.Y)
+		assert.Equal(t, caption.Rect.Y+caption.Rect.Height, cell.Rect.Y)
+		text := caption.Children[0]
+		assert.Equal(t, caption.Rect.X+(caption.Rect.Width-text.Rect.Width)/2, text.Rect.X)
+	})
+
+	t.Run("caption-side bottom goes below the rows", func(t *testing.T) {
+		table, caption, cell := layoutTable(`<table><caption>Title</caption><tr><td>Cell</td></tr></table>`, `table { caption-side: bottom; }`)
+		assert.Equal(t, table.Rect.Y, cell.Rect.Y)
+		assert.Equal(t, cell.Rect.Y+cell.Rect.Height, caption.Rect.Y)
+		assert.Equal(t, table.Rect.Y+table.Rect.Height, caption.Rect.Y+caption.Rect.Height)
+	})
+
+	t.Run("long captions wrap to the table width", func(t *testing.T) {
+		table, caption, _ := layoutTable(`<table><caption>A caption much wider than the single narrow cell below</caption><tr><td>Cell</td></tr></table>`, "")
+		assert.Equal(t, table.Rect.Width, caption.Rect.Width)
+		assert.Greater(t, len(caption.Children[0].WrappedLines), 1)
+		assert.Greater(t, caption.Rect.Height, NormalLineHeight(16))
+	})
+
+	t.Run("styled caption", func(t *testing.T) {
+		_, caption, _ := layoutTable(`<table><caption>Title</caption><tr><td>Cell</td></tr></table>`, `caption { font-size: 24px; padding: 4px; }`)
+		assert.Equal(t, NormalLineHeight(24)+8, caption.Rect.Height)
+	})
+}
+
 func TestWhiteSpaceNoWrap(t *testing.T) {
 	t.Run("block text stays on one line with nowrap", func(t *testing.T) {
 		tree := buildTree(`<div style="width: 100px; white-space: nowrap;">Hello Wonderful World</div>`)
@@ -1178,7 +1346,7 @@ func TestWhiteSpaceNoWrap(t *testing.T) {
 		textBox := findTextBoxInSubtree(div, "Hello Wonderful World")
 		assert.NotNil(t, textBox)
 		assert.Len(t, textBox.WrappedLines, 0)
-		assert.Equal(t, 24.0, textBox.Rect.Height)
+		assert.Equal(t, NormalLineHeight(16), textBox.Rect.Height)
 		assert.Greater(t, textBox.Rect.Width, 100.0)
 	})
 
@@ -1192,7 +1360,7 @@ func TestWhiteSpaceNoWrap(t *testing.T) {
 		textBox := findTextBoxInSubtree(cell, "Hello World")
 		assert.NotNil(t, textBox)
 		assert.Len(t, textBox.WrappedLines, 0)
-		assert.Equal(t, 24.0, textBox.Rect.Height)
+		assert.Equal(t, NormalLineHeight(16), textBox.Rect.Height)
 		assert.Greater(t, textBox.Rect.Width, 84.0)
 	})
 
@@ -1220,6 +1388,117 @@ func TestWhiteSpaceNoWrap(t *testing.T) {
 	})
 }
 
+func TestWhitespaceCollapsing(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	t.Run("spaces are dropped at the start and end of a line", func(t *testing.T) {
+		tree := buildTree("<div>\n  Hello\n</div>")
+		ComputeLayout(tree, 600)
+
+		text := findBoxByType(findBoxByTag(tree, "div"), TextBox)
+		assert.Equal(t, "Hello", text.Text)
+		assert.Equal(t, 8.0, text.Rect.X)
+	})
+
+	t.Run("space after a space-ending inline is collapsed", func(t *testing.T) {
+		tree := buildTree("<div><b>a </b> b</div>")
+		ComputeLayout(tree, 600)
+
+		texts := collectTextBoxes(findBoxByTag(tree, "div"))
+		assert.Equal(t, []string{"a ", "b"}, texts)
+	})
+
+	t.Run("whitespace between inline elements keeps a space", func(t *testing.T) {
+		tree := buildTree("<div><b>a</b>\n<i>b</i></div>")
+		ComputeLayout(tree, 600)
+
+		b, i := findBoxByTag(tree, "b"), findBoxByTag(tree, "i")
+		assert.Equal(t, b.Rect.Y, i.Rect.Y)
+		assert.Equal(t, b.Rect.X+b.Rect.Width+MeasureText(" ", 16), i.Rect.X)
+	})
+
+	t.Run("whitespace after a line break takes no room", func(t *testing.T) {
+		tree := buildTree("<div>a<br> b</div>")
+		ComputeLayout(tree, 600)
+
+		texts := collectTextBoxes(findBoxByTag(tree, "div"))
+		assert.Equal(t, []string{"a", "b"}, texts)
+	})
+
+	t.Run("a space after a space in another element is collapsed", func(t *testing.T) {
+		tree := buildTree("<div>a  <b>  b  </b>  <i> c</i>\n</div>")
+		ComputeLayout(tree, 600)
+
+		texts := collectTextBoxes(findBoxByTag(tree, "div"))
+		assert.Equal(t, []string{"a ", "b ", "c"}, texts)
+	})
+
+	t.Run("spaces around a line break are dropped", func(t *testing.T) {
+		tree := buildTree("<div><span>a </span><br>\n <span> b</span></div>")
+		ComputeLayout(tree, 600)
+
+		texts := collectTextBoxes(findBoxByTag(tree, "div"))
+		assert.Equal(t, []string{"a", "b"}, texts)
+	})
+
+	t.Run("an image separates spaces", func(t *testing.T) {
+		tree := buildTree(`<div>a <img src="x.png"> b</div>`)
+		ComputeLayout(tree, 600)
+
+		texts := collectTextBoxes(findBoxByTag(tree, "div"))
+		assert.Equal(t, []string{"a ", " b"}, texts)
+	})
+
+	t.Run("white-space pre keeps spaces and line feeds", func(t *testing.T) {
+		tree := buildTreeWithCSS("<div>a   b\n  c</div>", "div { white-space: pre; }")
+		ComputeLayout(tree, 600)
+
+		text := findBoxByType(findBoxByTag(tree, "div"), TextBox)
+		assert.Equal(t, "a   b\n  c", text.Text)
+		assert.Equal(t, 2*16*1.5, text.Rect.Height)
+	})
+
+	t.Run("white-space pre-line keeps line feeds only", func(t *testing.T) {
+		tree := buildTreeWithCSS("<div>a   b \n  c</div>", "div { white-space: pre-line; }")
+		ComputeLayout(tree, 600)
+
+		text := findBoxByType(findBoxByTag(tree, "div"), TextBox)
+		assert.Equal(t, "a b\nc", text.Text)
+	})
+
+	t.Run("a space at the end of a full line does not wrap", func(t *testing.T) {
+		tree := buildTreeWithCSS("<div><span>aaaa</span> <span>b</span></div>", "div { width: 32px; }")
+		ComputeLayout(tree, 600)
+
+		div := findBoxByTag(tree, "div")
+		a, b := div.Children[0], div.Children[2]
+		assert.Equal(t, a.Rect.Y+a.Rect.Height, b.Rect.Y, "b wraps once, right below aaaa")
+		assert.Equal(t, a.Rect.X, b.Rect.X, "the space stays on the first line")
+	})
+
+	t.Run("tabs in pre are expanded to tab stops", func(t *testing.T) {
+		tree := buildTree("<pre>a\tb</pre>")
+		ComputeLayout(tree, 600)
+
+		text := findBoxByType(findBoxByTag(tree, "pre"), TextBox)
+		assert.Equal(t, MeasureFontText("a       b", 16, Font{Monospace: true}), text.Rect.Width)
+	})
+}
+
+// collectTextBoxes returns the text of every text box under box in order.
+func collectTextBoxes(box *LayoutBox) []string {
+	var texts []string
+	for _, child := range box.Children {
+		if child.Type == TextBox {
+			texts = append(texts, child.Text)
+		}
+		texts = append(texts, collectTextBoxes(child)...)
+	}
+	return texts
+}
+
 func TestTextOverflowEllipsis(t *testing.T) {
 	t.Run("text-overflow stored in style", func(t *testing.T) {
 		tree := buildTree(`<div style="width: 100px; white-space: nowrap; overflow: hidden; text-overflow: ellipsis;">Hello World This Is Long</div>`)
@@ -1245,14 +1524,12 @@ func TestTextOverflowEllipsis(t *testing.T) {
 
 func TestTableCellVerticalAlign(t *testing.T) {
 	// "Hello World" in a 100px cell wraps to 2 lines:
-	//   content height = 2 * 24 = 48px
-	//   cell height    = 48 + 16 = 64px  (+ 2 * cellPadding)
-	//   innerHeight    = 64 - 16 = 48px
-	// Short cell has 1 line = 24px content.
-	// Empty space = 48 - 24 = 24px.
+	//   content height = 2 * lineHeight
+	//   cell height    = content height + 2 * cellPadding
+	// Short cell has 1 line of content, leaving one line of empty space.
 	const cellPadding = 8.0
-	const lineHeight = 24.0
-	const rowH = 2*lineHeight + 2*cellPadding // 64
+	lineHeight := NormalLineHeight(16)
+	rowH := 2*lineHeight + 2*cellPadding
 
 	tests := []struct {
 		name   string
@@ -1267,12 +1544,12 @@ func TestTableCellVerticalAlign(t *testing.T) {
 		{
 			name:   "middle - centered",
 			html:   `<table><tr><td style="width:100px;">Hello World</td><td style="vertical-align:middle;">X</td></tr></table>`,
-			wantDY: (rowH - 2*cellPadding - lineHeight) / 2, // 12
+			wantDY: (rowH - 2*cellPadding - lineHeight) / 2,
 		},
 		{
 			name:   "bottom - full shift",
 			html:   `<table><tr><td style="width:100px;">Hello World</td><td style="vertical-align:bottom;">X</td></tr></table>`,
-			wantDY: rowH - 2*cellPadding - lineHeight, // 24
+			wantDY: rowH - 2*cellPadding - lineHeight,
 		},
 		{
 			name:   "valign middle attribute",
@@ -1297,13 +1574,13 @@ func TestTableCellVerticalAlign(t *testing.T) {
 			assert.NotNil(t, textBox)
 
 			expectedY := cell.Rect.Y + cellPadding + tt.wantDY
-			assert.Equal(t, expectedY, textBox.Rect.Y)
+			assert.InDelta(t, expectedY, textBox.Rect.Y, 1e-9)
 		})
 	}
 }
 
 func TestTableCellPaddingAttribute(t *testing.T) {
-	const lineHeight = 24.0
+	lineHeight := NormalLineHeight(16)
 
 	tests := []struct {
 		name        string
@@ -1335,8 +1612,9 @@ func TestTableCellPaddingAttribute(t *testing.T) {
 			cell := findCellByText(tree, "X")
 			assert.NotNil(t, cell)
 
-			// Cell height = content height + top padding + bottom padding
-			assert.Equal(t, lineHeight+tt.wantPadding*2, cell.Rect.Height)
+			// Cell height = content height + top padding + bottom padding,
+			// in a row at least 24px tall
+			assert.InDelta(t, max(lineHeight+tt.wantPadding*2, 24), cell.Rect.Height, 1e-9)
 
 			// Content starts at cell top + padding
 			textBox := findTextBoxInSubtree(cell, "X")
@@ -1347,7 +1625,7 @@ func TestTableCellPaddingAttribute(t *testing.T) {
 }
 
 func TestTableCellSpacingAttribute(t *testing.T) {
-	// cellPadding default = 8, lineHeight = 24
+	// cellPadding default = 8
 	// With cellspacing=S and N columns: each col gets (tableWidth - (N+1)*S) / N
 	// colXOffsets[0] = S, colXOffsets[1] = S + colWidth + S
 	tests := []struct {
@@ -1405,7 +1683,7 @@ func TestTableCellSpacingAttribute(t *testing.T) {
 			verify: func(t *testing.T, tree *LayoutBox) {
 				cell := findCellByText(tree, "X")
 				assert.NotNil(t, cell)
-				assert.Equal(t, 24.0+8.0*2, cell.Rect.Height) // lineHeight + 2*cellPadding
+				assert.Equal(t, NormalLineHeight(16)+8.0*2, cell.Rect.Height) // lineHeight + 2*cellPadding
 			},
 		},
 	}
@@ -1419,6 +1697,49 @@ func TestTableCellSpacingAttribute(t *testing.T) {
 	}
 }
 
+func TestTableBorderSpacing(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		css      string
+		wantGapX float64
+		wantGapY float64
+	}{
+		{"separate axes", `<table><tr><td>A</td><td>B</td></tr><tr><td>C</td><td>D</td></tr></table>`, "table { border-spacing: 4px 10px }", 4, 10},
+		{"overrides cellspacing", `<table cellspacing="6"><tr><td>A</td><td>B</td></tr><tr><td>C</td><td>D</td></tr></table>`, "table { border-spacing: 2px }", 2, 2},
+		{"inherited", `<div><table><tr><td>A</td><td>B</td></tr><tr><td>C</td><td>D</td></tr></table></div>`, "div { border-spacing: 3px }", 3, 3},
+		{"collapse drops spacing", `<table cellspacing="6"><tr><td>A</td><td>B</td></tr><tr><td>C</td><td>D</td></tr></table>`, "table { border-collapse: collapse; border-spacing: 5px }", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(tt.html, tt.css)
+			ComputeLayout(tree, 600)
+
+			table := findBoxByTag(tree, "table")
+			a, b, c := findCellByText(tree, "A"), findCellByText(tree, "B"), findCellByText(tree, "C")
+			assert.Equal(t, table.Rect.X+tt.wantGapX, a.Rect.X)
+			assert.Equal(t, a.Rect.X+a.Rect.Width+tt.wantGapX, b.Rect.X)
+			assert.Equal(t, a.Rect.Y+a.Rect.Height+tt.wantGapY, c.Rect.Y)
+		})
+	}
+}
+
+func TestTableSectionOrder(t *testing.T) {
+	// thead rows stack first and tfoot rows last regardless of source order
+	tree := buildTree(`<table><tfoot><tr><td>Foot</td></tr></tfoot><tbody><tr><td>Body</td></tr></tbody><thead><tr><td>Head</td></tr></thead></table>`)
+	ComputeLayout(tree, 600)
+
+	head := findCellByText(tree, "Head")
+	body := findCellByText(tree, "Body")
+	foot := findCellByText(tree, "Foot")
+	assert.NotNil(t, head)
+	assert.NotNil(t, body)
+	assert.NotNil(t, foot)
+	assert.Less(t, head.Rect.Y, body.Rect.Y)
+	assert.Less(t, body.Rect.Y, foot.Rect.Y)
+}
+
 func TestTableBorderAttribute(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -1659,6 +1980,180 @@ func TestClearFloat(t *testing.T) {
 	})
 }
 
+func TestFloatLayout(t *testing.T) {
+	t.Run("text beside a left float starts past it", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>Float</section><p>Lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua</p></div>`,
+			`section { float: left; width: 200px; height: 40px; } div { width: 400px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		floatBox := findBoxByTag(tree, "section")
+		text := findBoxByType(findBoxByTag(tree, "p"), TextBox)
+		assert.NotNil(t, text)
+		assert.Equal(t, floatBox.Rect.X+floatBox.Rect.Width, text.Rect.X,
+			"first line should start at the float's right edge")
+		assert.Greater(t, len(text.WrappedLines), 2)
+		assert.Len(t, text.LineOffsets, len(text.WrappedLines))
+
+		// Lines below the float widen back to the container's left edge
+		last := len(text.LineOffsets) - 1
+		assert.Equal(t, floatBox.Rect.X, text.Rect.X+text.LineOffsets[last])
+		for i := 0; i < last; i++ {
+			w := MeasureText(text.WrappedLines[i], 16)
+			lineY := text.Rect.Y + float64(i)*getLineHeightFromStyle(findBoxByTag(tree, "p").Style)
+			if lineY < floatBox.Rect.Y+floatBox.Rect.Height {
+				assert.LessOrEqual(t, w, 200.0, "line %d beside the float should fit the remaining width", i)
+			}
+		}
+	})
+
+	t.Run("right float shortens lines from the right", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>Float</section><p>Lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor</p></div>`,
+			`section { float: right; width: 300px; height: 40px; } div { width: 400px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		floatBox := findBoxByTag(tree, "section")
+		div := findBoxByTag(tree, "div")
+		text := findBoxByType(div, TextBox)
+		assert.Equal(t, div.Rect.X+400-300, floatBox.Rect.X)
+		assert.Equal(t, div.Rect.X, text.Rect.X)
+		assert.LessOrEqual(t, MeasureText(text.WrappedLines[0], 16), 100.0)
+	})
+
+	t.Run("floats that do not fit stack below", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>A</section><nav>B</nav><aside>C</aside></div>`,
+			`div { width: 400px; }
+			 section, nav, aside { float: left; width: 150px; height: 30px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		a := findBoxByTag(tree, "section")
+		b := findBoxByTag(tree, "nav")
+		c := findBoxByTag(tree, "aside")
+		assert.Equal(t, a.Rect.Y, b.Rect.Y)
+		assert.Equal(t, a.Rect.X+150, b.Rect.X)
+		assert.Equal(t, a.Rect.Y+a.Rect.Height, c.Rect.Y, "third float should drop below the first row")
+		assert.Equal(t, a.Rect.X, c.Rect.X)
+	})
+
+	t.Run("cleared block inside a sibling still clears", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>Float</section><article><p>Cleared</p></article></div>`,
+			`section { float: left; width: 100px; height: 60px; } p { clear: left; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		floatBox := findBoxByTag(tree, "section")
+		p := findBoxByTag(tree, "p")
+		assert.GreaterOrEqual(t, p.Rect.Y, floatBox.Rect.Y+floatBox.Rect.Height)
+	})
+
+	t.Run("formatting context root contains its floats", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>Float</section></div>`,
+			`div { overflow: hidden; } section { float: left; width: 100px; height: 80px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		floatBox := findBoxByTag(tree, "section")
+		assert.GreaterOrEqual(t, div.Rect.Y+div.Rect.Height, floatBox.Rect.Y+floatBox.Rect.Height)
+	})
+}
+
+func TestCollapseMargins(t *testing.T) {
+	assert.Equal(t, 20.0, collapseMargins(10, 20))
+	assert.Equal(t, 10.0, collapseMargins(20, -10))
+	assert.Equal(t, -20.0, collapseMargins(-5, -20))
+	assert.Equal(t, 10.0, collapsedMarginDelta(10, 20))
+	assert.Equal(t, 0.0, collapsedMarginDelta(0, 20))
+}
+
+func TestMarginCollapsing(t *testing.T) {
+	borderTop := func(box *LayoutBox) float64 { return box.Rect.Y + box.Margin.Top }
+	borderBottom := func(box *LayoutBox) float64 {
+		return box.Rect.Y + box.Rect.Height - box.Margin.Bottom
+	}
+
+	t.Run("adjacent siblings use the larger margin", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>A</section><article>B</article></div>`,
+			`section { margin-bottom: 20px; } article { margin-top: 30px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		a := findBoxByTag(tree, "section")
+		b := findBoxByTag(tree, "article")
+		assert.Equal(t, 30.0, borderTop(b)-borderBottom(a))
+	})
+
+	t.Run("first child margin collapses with parent", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>Inner</section></div>`,
+			`div { margin-top: 10px; } section { margin-top: 25px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		section := findBoxByTag(tree, "section")
+		assert.Equal(t, div.Rect.Y+25, borderTop(section),
+			"child border should sit one collapsed margin below the parent's top")
+	})
+
+	t.Run("padding keeps the child margin inside", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>Inner</section></div>`,
+			`div { margin-top: 10px; padding-top: 1px; } section { margin-top: 25px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		section := findBoxByTag(tree, "section")
+		assert.Equal(t, div.Rect.Y+10+1+25, borderTop(section))
+	})
+
+	t.Run("formatting context root keeps the child margin inside", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>Inner</section></div>`,
+			`div { margin-top: 10px; overflow: hidden; } section { margin-top: 25px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		section := findBoxByTag(tree, "section")
+		assert.Equal(t, div.Rect.Y+10+25, borderTop(section))
+	})
+
+	t.Run("last child margin collapses through parent to next sibling", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<main><div><section>Inner</section></div><article>Next</article></main>`,
+			`div { margin-bottom: 10px; } section { margin-bottom: 40px; } article { margin-top: 15px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		section := findBoxByTag(tree, "section")
+		article := findBoxByTag(tree, "article")
+		assert.Equal(t, 40.0, borderTop(article)-borderBottom(section))
+	})
+
+	t.Run("clearance prevents collapsing", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><aside>Float</aside><section>A</section><article>B</article></div>`,
+			`aside { float: left; width: 50px; height: 200px; }
+			 section { margin-bottom: 20px; } article { clear: left; margin-top: 30px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		aside := findBoxByTag(tree, "aside")
+		article := findBoxByTag(tree, "article")
+		assert.GreaterOrEqual(t, borderTop(article), aside.Rect.Y+aside.Rect.Height)
+	})
+}
+
 func TestBlockPercentageWidth(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -1694,6 +2189,43 @@ func TestBlockPercentageWidth(t *testing.T) {
 				assert.InDelta(t, 784.0, div.Rect.Width, 1.0) // 100% of 784
 			},
 		},
+		{
+			name:           "min() with a percentage",
+			html:           `<div style="width: min(100%, 300px);">A</div><p style="width: min(100%, 1200px);">B</p><section style="max-width: clamp(100px, 50%, 600px);">C</section>`,
+			containerWidth: 800,
+			verify: func(t *testing.T, tree *LayoutBox) {
+				assert.InDelta(t, 300.0, findBoxByTag(tree, "div").Rect.Width, 1.0)
+				assert.InDelta(t, 784.0, findBoxByTag(tree, "p").Rect.Width, 1.0)       // 100% of 784
+				assert.InDelta(t, 392.0, findBoxByTag(tree, "section").Rect.Width, 1.0) // 50% of 784
+			},
+		},
+		{
+			name:           "input width 50% of its block",
+			html:           `<div style="width: 400px;"><input style="width: 50%;"></div>`,
+			containerWidth: 800,
+			verify: func(t *testing.T, tree *LayoutBox) {
+				input := findBoxByType(tree, InputBox)
+				assert.InDelta(t, 200.0, input.Rect.Width, 1.0) // 50% of 400
+			},
+		},
+		{
+			name:           "image width attribute 50%",
+			html:           `<div style="width: 300px;"><img width="50%"></div>`,
+			containerWidth: 800,
+			verify: func(t *testing.T, tree *LayoutBox) {
+				img := findBoxByType(tree, ImageBox)
+				assert.InDelta(t, 150.0+4, img.Rect.Width, 1.0) // 50% of 300, plus the gap after images
+			},
+		},
+		{
+			name:           "image inside a link resolves against the block",
+			html:           `<div style="width: 300px;"><a href="#"><img style="width: 50%;"></a></div>`,
+			containerWidth: 800,
+			verify: func(t *testing.T, tree *LayoutBox) {
+				img := findBoxByType(tree, ImageBox)
+				assert.InDelta(t, 150.0, img.Rect.Width, 1.0)
+			},
+		},
 		{
 			name:           "nested percentage widths",
 			html:           `<div style="width: 50%;"><div style="width: 50%;">Inner</div></div>`,
@@ -0,0 +1,82 @@
+package layout
+
+import "browser/css"
+
+// counter is one CSS counter instance (CSS Lists §4).
+type counter struct {
+	name  string
+	value int
+}
+
+// counterState is the stack of counter instances in scope during layout tree
+// construction. A counter instantiated by an element stays in scope for the
+// element, its descendants and its following siblings, so each element pops
+// the instances created inside it once its children are built.
+type counterState struct {
+	stack []counter
+}
+
+// mark returns the current stack depth for a later popTo.
+func (cs *counterState) mark() int {
+	return len(cs.stack)
+}
+
+// popTo drops counters instantiated since mark.
+func (cs *counterState) popTo(mark int) {
+	cs.stack = cs.stack[:mark]
+}
+
+// innermost returns the innermost counter named name, or nil.
+func (cs *counterState) innermost(name string) *counter {
+	for i := len(cs.stack) - 1; i >= 0; i-- {
+		if cs.stack[i].name == name {
+			return &cs.stack[i]
+		}
+	}
+	return nil
+}
+
+// apply runs a style's counter-reset, counter-increment and counter-set, in
+// that order (CSS Lists §4.5). Incrementing or setting a counter that is not
+// in scope instantiates it first.
+func (cs *counterState) apply(style *css.Style) {
+	for _, op := range css.ParseCounterOps(style.CounterReset, 0) {
+		cs.stack = append(cs.stack, counter{name: op.Name, value: op.Value})
+	}
+	for _, op := range css.ParseCounterOps(style.CounterIncrement, 1) {
+		cs.instantiate(op.Name).value += op.Value
+	}
+	for _, op := range css.ParseCounterOps(style.CounterSet, 0) {
+		cs.instantiate(op.Name).value = op.Value
+	}
+}
+
+func (cs *counterState) instantiate(name string) *counter {
+	if c := cs.innermost(name); c != nil {
+		return c
+	}
+	cs.stack = append(cs.stack, counter{name: name})
+	return &cs.stack[len(cs.stack)-1]
+}
+
+// value returns the innermost counter's value for counter(), 0 if none.
+func (cs *counterState) value(name string) int {
+	if c := cs.innermost(name); c != nil {
+		return c.value
+	}
+	return 0
+}
+
+// values returns all nested instances of name, outermost first, for counters().
+func (cs *counterState) values(name string) []int {
+	var out []int
+	for _, c := range cs.stack {
+		if c.name == name {
+			out = append(out, c.value)
+		}
+	}
+	if out == nil {
+		out = []int{0}
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package layout
+
+import (
+	"browser/css"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterStateApply(t *testing.T) {
+	cs := &counterState{}
+	cs.apply(&css.Style{CounterReset: "section 2"})
+	cs.apply(&css.Style{CounterIncrement: "section"})
+	assert.Equal(t, 3, cs.value("section"))
+
+	cs.apply(&css.Style{CounterIncrement: "section 10"})
+	assert.Equal(t, 13, cs.value("section"))
+
+	cs.apply(&css.Style{CounterSet: "section 1"})
+	assert.Equal(t, 1, cs.value("section"))
+
+	// Incrementing a counter that is not in scope instantiates it at 0
+	cs.apply(&css.Style{CounterIncrement: "figure"})
+	assert.Equal(t, 1, cs.value("figure"))
+
+	assert.Equal(t, 0, cs.value("missing"))
+	assert.Equal(t, []int{0}, cs.values("missing"))
+}
+
+func TestCounterStateScope(t *testing.T) {
+	cs := &counterState{}
+	cs.apply(&css.Style{CounterReset: "item"})
+	cs.apply(&css.Style{CounterIncrement: "item"})
+
+	scope := cs.mark()
+	cs.apply(&css.Style{CounterReset: "item"})
+	cs.apply(&css.Style{CounterIncrement: "item 2"})
+	assert.Equal(t, []int{1, 2}, cs.values("item"))
+	assert.Equal(t, 2, cs.value("item"))
+
+	cs.popTo(scope)
+	assert.Equal(t, []int{1}, cs.values("item"))
+}
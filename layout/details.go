@@ -0,0 +1,45 @@
+package layout
+
+import "browser/dom"
+
+// renderedChildren returns the DOM children node lays out. A <details>
+// element shows its summary first and the rest only while open; one
+// without a summary gets a "Details" summary that is not in the DOM.
+func renderedChildren(node *dom.Node) []*dom.Node {
+	if node.Type != dom.Element || node.TagName != dom.TagDetails {
+		return node.Children
+	}
+	summary := dom.DetailsSummary(node)
+	if summary == nil {
+		summary = &dom.Node{Type: dom.Element, TagName: dom.TagSummary, Parent: node}
+		summary.Children = []*dom.Node{{Type: dom.Text, Text: "Details", Parent: summary}}
+	}
+	children := []*dom.Node{summary}
+	if !dom.IsDetailsOpen(node) {
+		return children
+	}
+	for _, child := range node.Children {
+		if child != summary {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// buildDisclosureMarker returns the triangle in front of the summary of a
+// <details> element, pointing down while it is open, or nil for any other
+// box and for summaries with list-style: none.
+func buildDisclosureMarker(node *dom.Node, box *LayoutBox) *LayoutBox {
+	if node.Type != dom.Element || node.TagName != dom.TagSummary || node.Parent == nil || node.Parent.TagName != dom.TagDetails {
+		return nil
+	}
+	if box.Style.ListStyleType == "none" {
+		return nil
+	}
+	text := "▶ "
+	if dom.IsDetailsOpen(node.Parent) {
+		text = "▼ "
+	}
+	textNode := &dom.Node{Type: dom.Text, Text: text, Parent: node}
+	return &LayoutBox{Node: textNode, Parent: box, Type: TextBox, Text: text}
+}
@@ -0,0 +1,31 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetailsLayout(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		css  string
+		text string
+	}{
+		{"closed shows only the summary", `<details><summary>More</summary><p>Hidden</p></details>`, "", "▶ More"},
+		{"open shows everything", `<details open><p>Body</p><summary>More</summary></details>`, "", "▼ MoreBody"},
+		{"summary is supplied", `<details><p>Hidden</p></details>`, "", "▶ Details"},
+		{"list-style none hides the marker", `<details><summary>More</summary></details>`, "summary { list-style: none; }", "More"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(tt.html, tt.css)
+			details := findBoxByTag(tree, "details")
+			assert.Equal(t, BlockBox, details.Type)
+			assert.Equal(t, BlockBox, findBoxByTag(details, "summary").Type)
+			assert.Equal(t, tt.text, collectText(details))
+		})
+	}
+}
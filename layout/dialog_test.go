@@ -0,0 +1,46 @@
+package layout
+
+import (
+	"testing"
+
+	"browser/css"
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialogLayout(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	layoutDialog := func(html string, modal bool) (*LayoutBox, *LayoutBox) {
+		doc := parseHTML(html)
+		if d := dom.FindElementsByTagName(doc, "dialog"); d != nil {
+			d.Modal = modal
+		}
+		tree := BuildLayoutTree(doc, createStylesheet(`p { height: 1000px; }`), Viewport{Width: 800, Height: 600}, css.MatchContext{})
+		ComputeLayout(tree, 800)
+		return tree, findBoxByTag(tree, "dialog")
+	}
+
+	t.Run("closed dialogs are not rendered", func(t *testing.T) {
+		_, dialog := layoutDialog(`<dialog>Hello</dialog>`, false)
+		assert.Nil(t, dialog)
+	})
+
+	t.Run("open dialog is centred horizontally and sized to its content", func(t *testing.T) {
+		tree, dialog := layoutDialog(`<p>Page</p><dialog open>Hello</dialog>`, false)
+		assert.Equal(t, "absolute", dialog.Position)
+		assert.Less(t, dialog.Rect.Width, 200.0)
+		body := findBoxByTag(tree, "body")
+		assert.InDelta(t, dialog.Rect.X-body.Rect.X, body.Rect.X+body.Rect.Width-dialog.Rect.X-dialog.Rect.Width, 0.5)
+	})
+
+	t.Run("modal dialog is centred in the viewport", func(t *testing.T) {
+		_, dialog := layoutDialog(`<p>Page</p><dialog open>Hello</dialog>`, true)
+		assert.Equal(t, "fixed", dialog.Position)
+		assert.InDelta(t, 800-dialog.Rect.Width, 2*dialog.Rect.X, 0.5)
+		assert.InDelta(t, 600-dialog.Rect.Height, 2*dialog.Rect.Y, 0.5)
+	})
+}
@@ -0,0 +1,421 @@
+package layout
+
+import "strings"
+
+// flexItem is a child of a flex container while its container is laid out.
+// Sizes are outer sizes along the main axis (width for rows, height for
+// columns), matching the box's Rect.
+type flexItem struct {
+	box            *LayoutBox
+	base           float64 // flex base size
+	hypo           float64 // base clamped by min/max
+	main           float64 // resolved main size
+	grow, shrink   float64
+	minMain        float64
+	maxMain        float64 // 0 when unbounded
+	frozen         bool
+	stretchesCross bool
+}
+
+type flexLine struct {
+	items []*flexItem
+	cross float64
+}
+
+// isFlexContainer reports whether box lays its children out as flex items.
+func isFlexContainer(box *LayoutBox) bool {
+	return box.Style.Display == "flex" || box.Style.Display == "inline-flex"
+}
+
+// blockifyFlexItems turns a flex container's children into flex items
+// (CSS Flexbox §4): inline elements become blocks, while text runs and
+// atomic inlines such as images and form controls are wrapped in anonymous
+// blocks. Whitespace-only text between items is dropped.
+func blockifyFlexItems(box *LayoutBox) {
+	items := make([]*LayoutBox, 0, len(box.Children))
+	for _, child := range box.Children {
+		if child.Position == "absolute" || child.Position == "fixed" {
+			items = append(items, child)
+			continue
+		}
+		switch child.Type {
+		case BlockBox, TableBox, FieldsetBox:
+			items = append(items, child)
+		case InlineBox:
+			child.Type = BlockBox
+			items = append(items, child)
+		case TextBox:
+			if strings.TrimSpace(child.Text) == "" {
+				continue
+			}
+			items = append(items, anonymousBlock(box, []*LayoutBox{child}))
+		default:
+			items = append(items, anonymousBlock(box, []*LayoutBox{child}))
+		}
+	}
+	box.Children = items
+}
+
+// computeFlexLayout places the children of a flex container inside its
+// content box at (x, y) with the given width, and returns the height the
+// items occupy. It follows the CSS Flexbox §9 algorithm without
+// baselines, auto margins, order or align-self.
+func computeFlexLayout(box *LayoutBox, x, y, width, viewportWidth float64) float64 {
+	style := box.Style
+	column := strings.HasPrefix(style.FlexDirection, "column")
+	wrap := style.FlexWrap == "wrap" || style.FlexWrap == "wrap-reverse"
+	mainGap, crossGap := style.ColumnGap, style.RowGap
+	if column {
+		mainGap, crossGap = crossGap, mainGap
+	}
+
+	// Definite inner height, -1 when it follows the content
+	innerHeight := -1.0
+	if style.Height > 0 {
+		innerHeight = style.Height - style.PaddingTop - style.PaddingBottom - style.BorderTopWidth - style.BorderBottomWidth
+		if innerHeight < 0 {
+			innerHeight = 0
+		}
+	}
+	availableMain := width
+	if column {
+		availableMain = innerHeight
+	}
+	align := flexAlignment(style.AlignItems)
+
+	// Anonymous items size their text like the container's own
+	containerTag := ""
+	if box.Node != nil {
+		containerTag = box.Node.TagName
+	}
+	itemTag := func(item *LayoutBox) string {
+		if item.Node != nil {
+			return item.Node.TagName
+		}
+		return containerTag
+	}
+
+	// Determine each item's flex base size (§9.2)
+	items := make([]*flexItem, 0, len(box.Children))
+	for _, child := range box.Children {
+		tag := itemTag(child)
+		item := &flexItem{box: child, grow: child.Style.FlexGrow, shrink: 1}
+		if child.Style.FlexShrinkSet {
+			item.shrink = child.Style.FlexShrink
+		}
+
+		if column {
+			item.stretchesCross = align == "stretch" && resolveWidth(child.Style, width) == 0
+			itemWidth := width
+			if !item.stretchesCross && resolveWidth(child.Style, width) == 0 {
+				itemWidth = min(maxContentWidth(child, tag), width)
+			}
+			computeBlockLayout(child, blockLayoutParams{
+				containerWidth: width,
+				usedWidth:      itemWidth,
+				parentTag:      tag,
+				viewportWidth:  viewportWidth,
+			})
+			item.base = child.Rect.Height
+			if basis, ok := resolveFlexBasis(child.Style.FlexBasis, innerHeight, child.Style.FontSize, viewportWidth); ok {
+				item.base = basis + child.Margin.Top + child.Margin.Bottom
+			}
+			item.minMain, item.maxMain = child.Style.MinHeight, child.Style.MaxHeight
+		} else {
+			item.stretchesCross = align == "stretch" && child.Style.Height == 0
+			if basis, ok := resolveFlexBasis(child.Style.FlexBasis, width, child.Style.FontSize, viewportWidth); ok {
+				item.base = basis + child.Style.MarginLeft + child.Style.MarginRight
+			} else if w := resolveWidth(child.Style, width); w > 0 {
+				item.base = w + child.Style.MarginLeft + child.Style.MarginRight
+				if child.Style.BoxSizing != "border-box" {
+					item.base += child.Style.PaddingLeft + child.Style.PaddingRight + child.Style.BorderLeftWidth + child.Style.BorderRightWidth
+				}
+			} else {
+				item.base = maxContentWidth(child, tag)
+			}
+			item.minMain, item.maxMain = child.Style.MinWidth, child.Style.MaxWidth
+		}
+		item.hypo = item.clamp(item.base)
+		items = append(items, item)
+	}
+
+	// Collect items into lines (§9.3)
+	var lines []*flexLine
+	line := &flexLine{}
+	lineMain := 0.0
+	for _, item := range items {
+		if wrap && availableMain >= 0 && len(line.items) > 0 && lineMain+mainGap+item.hypo > availableMain {
+			lines = append(lines, line)
+			line = &flexLine{}
+			lineMain = 0
+		}
+		if len(line.items) > 0 {
+			lineMain += mainGap
+		}
+		lineMain += item.hypo
+		line.items = append(line.items, item)
+	}
+	lines = append(lines, line)
+
+	// Resolve main sizes, then lay items out at them to find cross sizes
+	for _, line := range lines {
+		resolveFlexibleLengths(line.items, availableMain, mainGap)
+		for _, item := range line.items {
+			child := item.box
+			if column {
+				child.Rect.Height = item.main
+				if child.Rect.Width > line.cross {
+					line.cross = child.Rect.Width
+				}
+				continue
+			}
+			computeBlockLayout(child, blockLayoutParams{
+				containerWidth: item.main,
+				usedWidth:      item.main,
+				parentTag:      itemTag(child),
+				viewportWidth:  viewportWidth,
+			})
+			if child.Rect.Height > line.cross {
+				line.cross = child.Rect.Height
+			}
+		}
+	}
+
+	// A single-line container's line fills its definite cross size (§9.4)
+	if len(lines) == 1 && !wrap {
+		if column {
+			lines[0].cross = width
+		} else if innerHeight >= 0 {
+			lines[0].cross = innerHeight
+		}
+	}
+
+	// Main size of the container, needed to justify and to mirror reversed rows
+	containerMain := availableMain
+	if containerMain < 0 {
+		containerMain = 0
+		for _, line := range lines {
+			containerMain = max(containerMain, lineMainSize(line.items, mainGap))
+		}
+	}
+	totalCross := 0.0
+	for i, line := range lines {
+		if i > 0 {
+			totalCross += crossGap
+		}
+		totalCross += line.cross
+	}
+
+	reverseMain := strings.HasSuffix(style.FlexDirection, "-reverse")
+	reverseCross := style.FlexWrap == "wrap-reverse"
+	crossPos := 0.0
+	for _, line := range lines {
+		free := containerMain - lineMainSize(line.items, mainGap)
+		pos, spacing := justifyFlexLine(style.JustifyContent, free, len(line.items))
+		lineCross := crossPos
+		if reverseCross {
+			lineCross = totalCross - crossPos - line.cross
+		}
+		for _, item := range line.items {
+			child := item.box
+			itemCross := child.Rect.Height
+			if column {
+				itemCross = child.Rect.Width
+			}
+			if item.stretchesCross && itemCross < line.cross {
+				if column {
+					child.Rect.Width = line.cross
+				} else {
+					child.Rect.Height = line.cross
+				}
+				itemCross = line.cross
+			}
+
+			mainPos := pos
+			if reverseMain {
+				mainPos = containerMain - pos - item.main
+			}
+			crossOffset := lineCross + alignFlexItem(align, line.cross, itemCross)
+			if column {
+				offsetBox(child, x+crossOffset-child.Rect.X, y+mainPos-child.Rect.Y)
+			} else {
+				offsetBox(child, x+mainPos-child.Rect.X, y+crossOffset-child.Rect.Y)
+			}
+			pos += item.main + mainGap + spacing
+		}
+		crossPos += line.cross + crossGap
+	}
+
+	if column {
+		return containerMain
+	}
+	return totalCross
+}
+
+// resolveFlexibleLengths grows or shrinks a line's items to fill
+// availableMain (§9.7), freezing items as they hit their min or max size.
+// A negative availableMain (indefinite size) leaves items at their
+// hypothetical sizes.
+func resolveFlexibleLengths(items []*flexItem, availableMain, gap float64) {
+	for _, item := range items {
+		item.main = item.hypo
+		item.frozen = false
+	}
+	if availableMain < 0 || len(items) == 0 {
+		return
+	}
+	gaps := gap * float64(len(items)-1)
+	growing := lineMainSize(items, gap) < availableMain
+	for _, item := range items {
+		if (growing && item.grow == 0) || (!growing && item.shrink == 0) ||
+			(growing && item.base > item.hypo) || (!growing && item.base < item.hypo) {
+			item.frozen = true
+		}
+	}
+
+	targets := make([]float64, len(items))
+	for {
+		free := availableMain - gaps
+		factors := 0.0
+		active := 0
+		for _, item := range items {
+			if item.frozen {
+				free -= item.main
+				continue
+			}
+			free -= item.base
+			active++
+			if growing {
+				factors += item.grow
+			} else {
+				factors += item.shrink * item.base
+			}
+		}
+		if active == 0 {
+			return
+		}
+
+		violation := 0.0
+		for i, item := range items {
+			if item.frozen {
+				continue
+			}
+			targets[i] = item.base
+			if factors > 0 {
+				if growing {
+					targets[i] += free * item.grow / factors
+				} else {
+					targets[i] += free * item.shrink * item.base / factors
+				}
+			}
+			item.main = item.clamp(targets[i])
+			violation += item.main - targets[i]
+		}
+
+		// Freeze every item when nothing was clamped; otherwise freeze the
+		// items clamped in the direction of the total violation and retry.
+		for i, item := range items {
+			if item.frozen {
+				continue
+			}
+			switch {
+			case violation == 0:
+				item.frozen = true
+			case violation > 0 && item.main > targets[i]:
+				item.frozen = true
+			case violation < 0 && item.main < targets[i]:
+				item.frozen = true
+			}
+		}
+	}
+}
+
+// clamp applies the item's min and max main size; sizes never go negative.
+func (item *flexItem) clamp(size float64) float64 {
+	if item.maxMain > 0 && size > item.maxMain {
+		size = item.maxMain
+	}
+	if size < item.minMain {
+		size = item.minMain
+	}
+	return max(size, 0)
+}
+
+// lineMainSize is the main size a line's items and gaps take up.
+func lineMainSize(items []*flexItem, gap float64) float64 {
+	total := 0.0
+	for i, item := range items {
+		if i > 0 {
+			total += gap
+		}
+		total += item.main
+	}
+	return total
+}
+
+// justifyFlexLine returns the offset of the first item and the extra space
+// between items for justify-content, given the line's free space.
+func justifyFlexLine(justify string, free float64, count int) (offset, spacing float64) {
+	switch justify {
+	case "flex-end", "end", "right":
+		return free, 0
+	case "center":
+		return free / 2, 0
+	case "space-between":
+		if free > 0 && count > 1 {
+			return 0, free / float64(count-1)
+		}
+	case "space-around":
+		if free <= 0 {
+			return free / 2, 0
+		}
+		return free / float64(count) / 2, free / float64(count)
+	case "space-evenly":
+		if free <= 0 {
+			return free / 2, 0
+		}
+		gap := free / float64(count+1)
+		return gap, gap
+	}
+	return 0, 0
+}
+
+// flexAlignment normalizes an align-items value to stretch, flex-start,
+// flex-end or center. Baseline alignment falls back to flex-start.
+func flexAlignment(alignItems string) string {
+	switch alignItems {
+	case "", "normal", "stretch":
+		return "stretch"
+	case "flex-end", "end", "self-end":
+		return "flex-end"
+	case "center":
+		return "center"
+	default:
+		return "flex-start"
+	}
+}
+
+// alignFlexItem returns an item's offset within its line along the cross axis.
+func alignFlexItem(align string, lineCross, itemCross float64) float64 {
+	switch align {
+	case "flex-end":
+		return lineCross - itemCross
+	case "center":
+		return (lineCross - itemCross) / 2
+	}
+	return 0
+}
+
+// resolveFlexBasis resolves a flex-basis value against the container's
+// main size. ok is false for auto and content, which defer to the item's
+// width or content size, and for percentages of an indefinite size.
+func resolveFlexBasis(raw string, containerMain, fontSize, viewportWidth float64) (float64, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "auto" || raw == "content" {
+		return 0, false
+	}
+	if strings.HasSuffix(raw, "%") && containerMain < 0 {
+		return 0, false
+	}
+	return resolveTextIndent(raw, fontSize, containerMain, viewportWidth), true
+}
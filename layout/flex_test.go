@@ -0,0 +1,242 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlexLayoutRow(t *testing.T) {
+	// body has an 8px margin, so the container's content starts at x=8
+	tests := []struct {
+		name     string
+		css      string
+		expected map[string]Rect // X and Width of each item
+	}{
+		{
+			name: "items sit side by side with a gap",
+			css:  `div { display: flex; width: 600px; gap: 10px; } section { width: 100px; } article { width: 200px; }`,
+			expected: map[string]Rect{
+				"section": {X: 8, Width: 100},
+				"article": {X: 118, Width: 200},
+			},
+		},
+		{
+			name: "flex-grow shares free space by factor",
+			css:  `div { display: flex; width: 600px; } section { width: 100px; flex-grow: 1; } article { width: 100px; flex-grow: 3; }`,
+			expected: map[string]Rect{
+				"section": {X: 8, Width: 200},
+				"article": {X: 208, Width: 400},
+			},
+		},
+		{
+			name: "flex shorthand grows from a zero basis",
+			css:  `div { display: flex; width: 600px; } section { flex: 1; } article { flex: 2; }`,
+			expected: map[string]Rect{
+				"section": {X: 8, Width: 200},
+				"article": {X: 208, Width: 400},
+			},
+		},
+		{
+			name: "flex-shrink scales overflow by basis",
+			css:  `div { display: flex; width: 600px; } section { flex-basis: 400px; } article { flex-basis: 800px; }`,
+			expected: map[string]Rect{
+				"section": {X: 8, Width: 200},
+				"article": {X: 208, Width: 400},
+			},
+		},
+		{
+			name: "flex-shrink 0 keeps the basis",
+			css:  `div { display: flex; width: 600px; } section { flex: 0 0 400px; } article { flex-basis: 400px; }`,
+			expected: map[string]Rect{
+				"section": {X: 8, Width: 400},
+				"article": {X: 408, Width: 200},
+			},
+		},
+		{
+			name: "min-width stops shrinking",
+			css:  `div { display: flex; width: 600px; } section { flex-basis: 400px; min-width: 350px; } article { flex-basis: 400px; }`,
+			expected: map[string]Rect{
+				"section": {X: 8, Width: 350},
+				"article": {X: 358, Width: 250},
+			},
+		},
+		{
+			name: "justify-content center",
+			css:  `div { display: flex; width: 600px; justify-content: center; } section, article { width: 100px; }`,
+			expected: map[string]Rect{
+				"section": {X: 208, Width: 100},
+				"article": {X: 308, Width: 100},
+			},
+		},
+		{
+			name: "justify-content flex-end",
+			css:  `div { display: flex; width: 600px; justify-content: flex-end; } section, article { width: 100px; }`,
+			expected: map[string]Rect{
+				"section": {X: 408, Width: 100},
+				"article": {X: 508, Width: 100},
+			},
+		},
+		{
+			name: "justify-content space-between",
+			css:  `div { display: flex; width: 600px; justify-content: space-between; } section, article { width: 100px; }`,
+			expected: map[string]Rect{
+				"section": {X: 8, Width: 100},
+				"article": {X: 508, Width: 100},
+			},
+		},
+		{
+			name: "justify-content space-evenly",
+			css:  `div { display: flex; width: 600px; justify-content: space-evenly; } section, article { width: 100px; }`,
+			expected: map[string]Rect{
+				"section": {X: 8 + 400.0/3, Width: 100},
+				"article": {X: 8 + 400.0/3*2 + 100, Width: 100},
+			},
+		},
+		{
+			name: "row-reverse starts from the right",
+			css:  `div { display: flex; width: 600px; flex-direction: row-reverse; } section, article { width: 100px; }`,
+			expected: map[string]Rect{
+				"section": {X: 508, Width: 100},
+				"article": {X: 408, Width: 100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(`<div><section>A</section><article>B</article></div>`, tt.css)
+			ComputeLayout(tree, 800)
+
+			for tag, want := range tt.expected {
+				box := findBoxByTag(tree, tag)
+				assert.NotNil(t, box)
+				assert.InDelta(t, want.X, box.Rect.X, 0.01, "%s x", tag)
+				assert.InDelta(t, want.Width, box.Rect.Width, 0.01, "%s width", tag)
+			}
+		})
+	}
+}
+
+func TestFlexLayoutCrossAxis(t *testing.T) {
+	tests := []struct {
+		name       string
+		css        string
+		sectionY   float64 // relative to the container's top
+		sectionH   float64
+		containerH float64
+	}{
+		{
+			name:       "stretch fills the line",
+			css:        `div { display: flex; } article { height: 60px; }`,
+			sectionY:   0,
+			sectionH:   60,
+			containerH: 60,
+		},
+		{
+			name:       "center within a fixed height",
+			css:        `div { display: flex; height: 100px; align-items: center; } section { height: 20px; }`,
+			sectionY:   40,
+			sectionH:   20,
+			containerH: 100,
+		},
+		{
+			name:       "flex-end within the tallest item",
+			css:        `div { display: flex; align-items: flex-end; } section { height: 20px; } article { height: 50px; }`,
+			sectionY:   30,
+			sectionH:   20,
+			containerH: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(`<div><section>A</section><article>B</article></div>`, tt.css)
+			ComputeLayout(tree, 800)
+
+			container := findBoxByTag(tree, "div")
+			section := findBoxByTag(tree, "section")
+			assert.InDelta(t, tt.sectionY, section.Rect.Y-container.Rect.Y, 0.01)
+			assert.InDelta(t, tt.sectionH, section.Rect.Height, 0.01)
+			assert.InDelta(t, tt.containerH, container.Rect.Height, 0.01)
+		})
+	}
+}
+
+func TestFlexLayoutWrap(t *testing.T) {
+	tree := buildTreeWithCSS(
+		`<div><section>A</section><article>B</article><aside>C</aside></div>`,
+		`div { display: flex; flex-wrap: wrap; width: 600px; row-gap: 10px; }
+		 section, article, aside { width: 250px; height: 40px; }`,
+	)
+	ComputeLayout(tree, 800)
+
+	container := findBoxByTag(tree, "div")
+	section := findBoxByTag(tree, "section")
+	article := findBoxByTag(tree, "article")
+	aside := findBoxByTag(tree, "aside")
+
+	assert.Equal(t, section.Rect.Y, article.Rect.Y, "first two items share a line")
+	assert.InDelta(t, 258, article.Rect.X, 0.01)
+	assert.InDelta(t, 8, aside.Rect.X, 0.01, "third item wraps to the start")
+	assert.InDelta(t, section.Rect.Y+50, aside.Rect.Y, 0.01, "second line follows the row gap")
+	assert.InDelta(t, 90, container.Rect.Height, 0.01)
+}
+
+func TestFlexLayoutColumn(t *testing.T) {
+	t.Run("items stack and stretch across", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>A</section><article>B</article></div>`,
+			`div { display: flex; flex-direction: column; width: 300px; gap: 5px; }
+			 section { height: 30px; } article { height: 40px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		container := findBoxByTag(tree, "div")
+		section := findBoxByTag(tree, "section")
+		article := findBoxByTag(tree, "article")
+		assert.InDelta(t, 300, section.Rect.Width, 0.01)
+		assert.InDelta(t, section.Rect.Y+35, article.Rect.Y, 0.01)
+		assert.InDelta(t, 75, container.Rect.Height, 0.01)
+	})
+
+	t.Run("flex-grow fills a fixed height", func(t *testing.T) {
+		tree := buildTreeWithCSS(
+			`<div><section>A</section><article>B</article></div>`,
+			`div { display: flex; flex-direction: column; height: 200px; }
+			 section { height: 50px; } article { flex-grow: 1; height: 50px; }`,
+		)
+		ComputeLayout(tree, 800)
+
+		article := findBoxByTag(tree, "article")
+		assert.InDelta(t, 150, article.Rect.Height, 0.01)
+	})
+}
+
+func TestBlockifyFlexItems(t *testing.T) {
+	tree := buildTreeWithCSS(`<div> <span>A</span> text <img src="x.png"> </div>`, `div { display: flex; }`)
+
+	container := findBoxByTag(tree, "div")
+	assert.Len(t, container.Children, 3, "whitespace between items is dropped")
+
+	span := container.Children[0]
+	assert.Equal(t, BlockBox, span.Type, "inline elements are blockified")
+	assert.Equal(t, "span", span.Node.TagName)
+
+	for _, anon := range container.Children[1:] {
+		assert.Equal(t, BlockBox, anon.Type)
+		assert.Nil(t, anon.Node, "text and atomic inlines get anonymous items")
+		assert.Len(t, anon.Children, 1)
+		assert.Same(t, anon, anon.Children[0].Parent)
+	}
+}
+
+func TestFlexLayoutContentSizedItems(t *testing.T) {
+	tree := buildTreeWithCSS(`<div><span>Home</span><span>About us</span></div>`, `div { display: flex; }`)
+	ComputeLayout(tree, 800)
+
+	container := findBoxByTag(tree, "div")
+	first, second := container.Children[0], container.Children[1]
+	assert.InDelta(t, MeasureText("Home", 16), first.Rect.Width, 0.01)
+	assert.InDelta(t, first.Rect.X+first.Rect.Width, second.Rect.X, 0.01)
+}
@@ -0,0 +1,149 @@
+package layout
+
+import "math"
+
+// floatContext holds the floats placed so far in one block formatting
+// context (CSS 2.1 §9.4.1). Blocks in the context share it, so line boxes
+// of nested paragraphs flow around floats of their ancestors. Rects are in
+// page coordinates.
+type floatContext struct {
+	floats []placedFloat
+}
+
+// placedFloat is a positioned float and the side it floats to.
+type placedFloat struct {
+	rect Rect
+	side string
+}
+
+// isFloated reports whether box is taken out of the flow by float.
+func isFloated(box *LayoutBox) bool {
+	return box.Float == "left" || box.Float == "right"
+}
+
+// layoutFloat lays out a floated box and places it in c at or below y,
+// within the content box [left, left+width] of its container.
+func layoutFloat(box *LayoutBox, c *floatContext, y, left, width, viewportWidth float64) {
+	floatWidth := resolveWidth(box.Style, width)
+	if floatWidth <= 0 {
+		floatWidth = shrinkToFitWidth(box, width)
+	}
+	computeBlockLayout(box, blockLayoutParams{
+		containerWidth: floatWidth,
+		viewportWidth:  viewportWidth,
+	})
+	c.place(box, box.Float, y, left, left+width)
+}
+
+// establishesFormattingContext reports whether box lays out its content in
+// a block formatting context of its own, keeping floats inside it from
+// affecting the outside and the other way around.
+func establishesFormattingContext(box *LayoutBox) bool {
+	if box.Parent == nil || isFloated(box) {
+		return true
+	}
+	if box.Position == "absolute" || box.Position == "fixed" {
+		return true
+	}
+	switch box.Style.Display {
+	case "inline-block", "flow-root", "flex", "inline-flex", "table-cell":
+		return true
+	}
+	if isMulticolContainer(box) {
+		return true
+	}
+	switch box.Type {
+	case TableCellBox, TableCaptionBox, FieldsetBox:
+		return true
+	}
+	x, y := box.Style.EffectiveOverflowX(), box.Style.EffectiveOverflowY()
+	return (x != "" && x != "visible") || (y != "" && y != "visible")
+}
+
+// lineBand returns the horizontal extent a line box spanning [y, y+height)
+// may use within [left, right] once floats beside it are excluded.
+func (c *floatContext) lineBand(y, height, left, right float64) (float64, float64) {
+	for _, f := range c.floats {
+		if !f.overlaps(y, height) {
+			continue
+		}
+		if f.side == "left" {
+			left = math.Max(left, f.rect.X+f.rect.Width)
+		} else {
+			right = math.Min(right, f.rect.X)
+		}
+	}
+	return left, right
+}
+
+// nextBottom returns the nearest bottom edge of a float beside [y,
+// y+height), where the space beside floats next widens.
+func (c *floatContext) nextBottom(y, height float64) (float64, bool) {
+	next, found := math.Inf(1), false
+	for _, f := range c.floats {
+		if f.overlaps(y, height) {
+			next, found = math.Min(next, f.rect.Y+f.rect.Height), true
+		}
+	}
+	return next, found
+}
+
+// fitLine returns the top of the first line box at or below y that is
+// at least width wide, or has no float beside it to move past.
+func (c *floatContext) fitLine(y, height, width, left, right float64) float64 {
+	for {
+		l, r := c.lineBand(y, height, left, right)
+		if r-l >= width {
+			return y
+		}
+		next, ok := c.nextBottom(y, height)
+		if !ok {
+			return y
+		}
+		y = next
+	}
+}
+
+// overlaps reports whether the float is beside the band [y, y+height).
+// Empty bands count as one pixel tall.
+func (f placedFloat) overlaps(y, height float64) bool {
+	height = math.Max(height, 1)
+	return f.rect.Y < y+height && y < f.rect.Y+f.rect.Height
+}
+
+// place moves a laid-out float to the highest spot at or below y where it
+// fits beside the floats already placed, against the left or right edge
+// (CSS 2.1 §9.5.1). A float is never placed above an earlier one, and one
+// wider than the container goes below all floats in its way.
+func (c *floatContext) place(box *LayoutBox, side string, y, left, right float64) {
+	width, height := box.Rect.Width, box.Rect.Height
+	if n := len(c.floats); n > 0 {
+		y = math.Max(y, c.floats[n-1].rect.Y)
+	}
+	y = c.fitLine(y, height, width, left, right)
+
+	l, r := c.lineBand(y, height, left, right)
+	x := l
+	if side == "right" {
+		x = r - width
+	}
+	offsetBox(box, x-box.Rect.X, y-box.Rect.Y)
+	c.floats = append(c.floats, placedFloat{rect: box.Rect, side: side})
+}
+
+// clearance returns where a box with the given clear value may start: below
+// the bottom of every float on the cleared sides, and never above y.
+func (c *floatContext) clearance(clear string, y float64) float64 {
+	for _, f := range c.floats {
+		if clear == "both" || clear == f.side {
+			y = math.Max(y, f.rect.Y+f.rect.Height)
+		}
+	}
+	return y
+}
+
+// extendsBelow reports whether any float reaches below y, so content from
+// y on may have to flow around one.
+func (c *floatContext) extendsBelow(y float64) bool {
+	return c.clearance("both", y) > y
+}
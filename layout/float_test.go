@@ -0,0 +1,60 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloatContextPlace(t *testing.T) {
+	c := &floatContext{}
+	a := createBoxWithRect(0, 0, 100, 50)
+	b := createBoxWithRect(0, 0, 100, 30)
+	wide := createBoxWithRect(0, 0, 250, 20)
+
+	c.place(a, "left", 10, 0, 300)
+	c.place(b, "right", 10, 0, 300)
+	assert.Equal(t, Rect{X: 0, Y: 10, Width: 100, Height: 50}, a.Rect)
+	assert.Equal(t, Rect{X: 200, Y: 10, Width: 100, Height: 30}, b.Rect)
+
+	// Too wide beside both floats: moves down until the right one ends,
+	// then the left one
+	c.place(wide, "left", 10, 0, 300)
+	assert.Equal(t, 60.0, wide.Rect.Y)
+	assert.Equal(t, 0.0, wide.Rect.X)
+}
+
+func TestFloatContextLineBand(t *testing.T) {
+	c := &floatContext{}
+	c.place(createBoxWithRect(0, 0, 100, 50), "left", 0, 0, 400)
+	c.place(createBoxWithRect(0, 0, 80, 20), "right", 0, 0, 400)
+
+	l, r := c.lineBand(0, 18, 0, 400)
+	assert.Equal(t, 100.0, l)
+	assert.Equal(t, 320.0, r)
+
+	l, r = c.lineBand(30, 18, 0, 400)
+	assert.Equal(t, 100.0, l)
+	assert.Equal(t, 400.0, r)
+
+	l, r = c.lineBand(50, 18, 0, 400)
+	assert.Equal(t, 0.0, l)
+	assert.Equal(t, 400.0, r)
+
+	assert.Equal(t, 50.0, c.fitLine(0, 18, 350, 0, 400))
+	assert.Equal(t, 0.0, c.fitLine(0, 18, 200, 0, 400))
+}
+
+func TestFloatContextClearance(t *testing.T) {
+	c := &floatContext{}
+	c.place(createBoxWithRect(0, 0, 100, 50), "left", 0, 0, 400)
+	c.place(createBoxWithRect(0, 0, 100, 80), "right", 0, 0, 400)
+
+	assert.Equal(t, 50.0, c.clearance("left", 0))
+	assert.Equal(t, 80.0, c.clearance("right", 0))
+	assert.Equal(t, 80.0, c.clearance("both", 0))
+	assert.Equal(t, 0.0, c.clearance("", 0))
+	assert.Equal(t, 90.0, c.clearance("both", 90))
+	assert.True(t, c.extendsBelow(60))
+	assert.False(t, c.extendsBelow(80))
+}
@@ -0,0 +1,162 @@
+package layout
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// softHyphen (&shy;) marks where a word may break. It is invisible unless
+// a line breaks there, when it shows as a hyphen.
+const softHyphen = '\u00ad'
+
+// Limits on automatic hyphenation, those CSS Text 4 suggests for
+// `hyphenate-limit-chars: auto`: words shorter than minHyphenatedWord are
+// not hyphenated, and no line keeps fewer than minHyphenFragment letters
+// of a word on either side of a hyphen.
+const (
+	minHyphenatedWord = 5
+	minHyphenFragment = 2
+)
+
+// hyphenationText returns text with the break opportunities the hyphens
+// property allows (CSS Text §5.3): none drops its soft hyphens, manual
+// keeps them and auto adds ones of its own.
+func hyphenationText(text, hyphens string) string {
+	switch hyphens {
+	case "none":
+		return removeSoftHyphens(text)
+	case "auto":
+		return hyphenate(text)
+	}
+	return text
+}
+
+// removeSoftHyphens drops the soft hyphens from text.
+func removeSoftHyphens(text string) string {
+	if !strings.ContainsRune(text, softHyphen) {
+		return text
+	}
+	return strings.ReplaceAll(text, string(softHyphen), "")
+}
+
+// endLine returns line as drawn when a line breaks after it: a soft hyphen
+// it ends with shows as a hyphen, the others vanish.
+func endLine(line string) string {
+	if hyphenated, ok := strings.CutSuffix(line, string(softHyphen)); ok {
+		return removeSoftHyphens(hyphenated) + "-"
+	}
+	return removeSoftHyphens(line)
+}
+
+// hyphenate adds soft hyphens between the syllables of the longer Latin
+// words of text. It knows no dictionary: words break before a consonant
+// between two vowels and between two consonants, keeping digraphs such as
+// "th" together, which is right more often than not in English and most
+// European languages. Words the author already hyphenated are left alone.
+func hyphenate(text string) string {
+	runes := []rune(text)
+	var sb strings.Builder
+	for start := 0; start < len(runes); {
+		end := start
+		for end < len(runes) && !isBreakableSpace(runes[end]) {
+			end++
+		}
+		if end == start {
+			sb.WriteRune(runes[start])
+			start++
+			continue
+		}
+		word := runes[start:end]
+		if slices.Contains(word, softHyphen) {
+			sb.WriteString(string(word))
+		} else {
+			hyphenateLetters(&sb, word)
+		}
+		start = end
+	}
+	return sb.String()
+}
+
+// hyphenateLetters writes word to sb with soft hyphens added to each of
+// its runs of Latin letters, so punctuation and digits never split.
+func hyphenateLetters(sb *strings.Builder, word []rune) {
+	for start := 0; start < len(word); {
+		end := start
+		for end < len(word) && unicode.Is(unicode.Latin, word[end]) {
+			end++
+		}
+		if end == start {
+			sb.WriteRune(word[start])
+			start++
+			continue
+		}
+		letters := word[start:end]
+		breaks := syllableBreaks(letters)
+		for i, r := range letters {
+			if len(breaks) > 0 && breaks[0] == i {
+				sb.WriteRune(softHyphen)
+				breaks = breaks[1:]
+			}
+			sb.WriteRune(r)
+		}
+		start = end
+	}
+}
+
+// syllableBreaks returns the indexes of word a hyphen may go before.
+// Between two vowels a lone consonant starts the next syllable and a pair
+// splits unless it is a digraph; of three or more, the first stays behind.
+// Acronyms are not hyphenated.
+func syllableBreaks(word []rune) []int {
+	if len(word) < minHyphenatedWord || !slices.ContainsFunc(word, unicode.IsLower) {
+		return nil
+	}
+	lower := make([]rune, len(word))
+	for i, r := range word {
+		lower[i] = unicode.ToLower(r)
+	}
+	var breaks []int
+	prev := -1 // the last vowel seen
+	for i := range lower {
+		if !isVowel(lower, i) {
+			continue
+		}
+		if prev >= 0 && i-prev > 1 {
+			at := prev + 1 // a lone consonant starts the syllable
+			switch consonants := i - prev - 1; {
+			case consonants == 2 && isDigraph(lower[prev+1], lower[prev+2]):
+			case consonants >= 2:
+				at = prev + 2
+			}
+			if at >= minHyphenFragment && len(word)-at >= minHyphenFragment {
+				breaks = append(breaks, at)
+			}
+		}
+		prev = i
+	}
+	return breaks
+}
+
+// isVowel reports whether word[i] is a vowel. A "y" is one unless it
+// starts the word, and a final "e" is taken as silent.
+func isVowel(word []rune, i int) bool {
+	switch r := word[i]; {
+	case r == 'y':
+		return i > 0
+	case r == 'e' && i == len(word)-1:
+		return false
+	default:
+		return strings.ContainsRune("aeiouàáâãäåæèéêëìíîïòóôõöøùúûüœ", r)
+	}
+}
+
+// isDigraph reports whether the consonants a and b spell one sound, so a
+// hyphen goes before them rather than between.
+func isDigraph(a, b rune) bool {
+	switch string([]rune{a, b}) {
+	case "ch", "gh", "ph", "sh", "th", "wh":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHyphenate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"hyphenation", "hy-phe-na-tion"},
+		{"butterfly", "but-ter-fly"},
+		{"teacher", "tea-cher"},
+		{"became", "be-came"},
+		{"short word", "short word"},
+		{"NASA and UNICEF", "NASA and UNICEF"},
+		{"(internationalization)", "(in-ter-na-tio-na-li-za-tion)"},
+		{"manu\u00adscript", "manu\u00adscript"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := hyphenate(tt.text)
+			if !strings.Contains(tt.text, "\u00ad") {
+				got = strings.ReplaceAll(got, "\u00ad", "-")
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWrapSoftHyphens(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	tests := []struct {
+		name    string
+		text    string
+		hyphens string
+		width   float64
+		want    []string
+	}{
+		{"unbroken soft hyphen vanishes", "extra\u00adordinary", "manual", 400, []string{"extraordinary"}},
+		{"broken soft hyphen shows", "extra\u00adordinary", "manual", 80, []string{"extra-", "ordinary"}},
+		{"none ignores soft hyphens", "extra\u00adordinary", "none", 80, []string{"extraordinary"}},
+		{"manual leaves words whole", "hyphenation", "manual", 64, []string{"hyphenation"}},
+		{"auto hyphenates", "hyphenation", "auto", 64, []string{"hyphena-", "tion"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, WrapText(hyphenationText(tt.text, tt.hyphens), 16, tt.width))
+		})
+	}
+}
+
+func TestHyphensLayout(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	tree := buildTreeWithCSS(`<div>internationalization</div>`, `div { width: 100px; hyphens: auto; }`)
+	ComputeLayout(tree, 800)
+	text := findBoxByType(findBoxByTag(tree, "div"), TextBox)
+
+	assert.Equal(t, []string{"internatio-", "nalization"}, text.WrappedLines)
+	assert.LessOrEqual(t, text.Rect.Width, 100.0, "the word no longer overflows")
+	assert.Equal(t, 4*8.0, flowContentWidth(findBoxByTag(tree, "div"), "div", true), "min-content is the widest fragment")
+}
@@ -0,0 +1,141 @@
+package layout
+
+import (
+	"browser/dom"
+	"strings"
+)
+
+// shrinkToFitWidth returns the outer width of a box sized to its content
+// within available (CSS 2.1 §10.3.5): its preferred width, but no wider
+// than available and never narrower than its widest unbreakable content.
+// Floats and absolutely positioned boxes without a width use it.
+func shrinkToFitWidth(box *LayoutBox, available float64) float64 {
+	tag := ""
+	if box.Node != nil {
+		tag = box.Node.TagName
+	}
+	return min(max(minContentWidth(box, tag), available), maxContentWidth(box, tag))
+}
+
+// maxContentWidth estimates the outer width of box laid out without any
+// line wrapping, its preferred width. It is the flex base size of items
+// without a width or flex-basis and bounds shrink-to-fit widths. parentTag
+// sizes text the way computeBlockLayout does.
+func maxContentWidth(box *LayoutBox, parentTag string) float64 {
+	return contentWidth(box, parentTag, false)
+}
+
+// minContentWidth estimates the outer width of box with every line broken
+// wherever it may be: the widest word, image or form control in it.
+func minContentWidth(box *LayoutBox, parentTag string) float64 {
+	return contentWidth(box, parentTag, true)
+}
+
+// contentWidth measures box for maxContentWidth, or for minContentWidth
+// when wrap is set.
+func contentWidth(box *LayoutBox, parentTag string, wrap bool) float64 {
+	s := box.Style
+	margins := s.MarginLeft + s.MarginRight
+	edges := s.PaddingLeft + s.PaddingRight + s.BorderLeftWidth + s.BorderRightWidth
+	if s.Width > 0 {
+		if s.BoxSizing == "border-box" {
+			return s.Width + margins
+		}
+		return s.Width + edges + margins
+	}
+
+	switch box.Type {
+	case TextBox:
+		letterSpacing, wordSpacing := 0.0, 0.0
+		var font Font
+		nowrap, hyphens := false, ""
+		if box.Parent != nil {
+			letterSpacing, wordSpacing = box.Parent.Style.LetterSpacing, box.Parent.Style.WordSpacing
+			font = StyleFont(box.Parent.Style)
+			nowrap = !wrapsLines(box.Parent.Style.WhiteSpace) || isInsidePre(box)
+			hyphens = box.Parent.Style.Hyphens
+		}
+		fontSize := textFontSize(box)
+		if !wrap || nowrap {
+			return MeasureStyledText(strings.TrimSpace(box.Text), fontSize, font, letterSpacing, wordSpacing)
+		}
+		widest := 0.0
+		for _, segment := range lineSegments(hyphenationText(box.Text, hyphens)) {
+			widest = max(widest, MeasureStyledText(endLine(segment.text), fontSize, font, letterSpacing, wordSpacing))
+		}
+		return widest
+	case InlineBox:
+		if !wrap {
+			w, _ := computeInlineSize(box, parentTag, 0)
+			return w
+		}
+	case ImageBox:
+		w, _ := imageSize(box, 0)
+		return w + 4
+	case MediaBox:
+		w, _ := imageSize(box, 0)
+		return w
+	case InputBox, SelectBox, ButtonBox, TextareaBox, FileInputBox:
+		w, _ := formControlSize(box, parentTag, 0)
+		return w
+	case RadioBox, CheckboxBox:
+		return 20
+	case HRBox, BRBox:
+		return 0
+	case TableBox:
+		return tableContentWidth(box, wrap) + margins
+	}
+
+	tag := parentTag
+	if box.Node != nil && box.Node.Type == dom.Element {
+		tag = box.Node.TagName
+	}
+	switch tag {
+	case dom.TagUL, dom.TagOL, dom.TagMenu:
+		edges += 20
+	case dom.TagBlockquote:
+		edges += 30
+	case dom.TagDD, dom.TagFigure:
+		edges += 40
+	}
+
+	content := flowContentWidth(box, tag, wrap)
+	if box.Type == InlineBox {
+		return content
+	}
+	return content + edges + margins
+}
+
+// flowContentWidth measures the children of box for contentWidth: inline
+// runs add up, block children and flex columns stack. Lines break between
+// inline boxes when wrapping, so runs never form.
+func flowContentWidth(box *LayoutBox, tag string, wrap bool) float64 {
+	content, run := 0.0, 0.0
+	rowFlex := isFlexContainer(box) && !strings.HasPrefix(box.Style.FlexDirection, "column")
+	for i, child := range box.Children {
+		w := contentWidth(child, tag, wrap)
+		switch {
+		case rowFlex && !wrap:
+			if i > 0 {
+				run += box.Style.ColumnGap
+			}
+			run += w
+		case isInlineLevel(child) && !wrap:
+			run += w
+		default:
+			content = max(content, run, w)
+			run = 0
+		}
+	}
+	return max(content, run)
+}
+
+// isInlineLevel reports whether box sits on a line with its siblings: inline
+// boxes and form controls.
+func isInlineLevel(box *LayoutBox) bool {
+	switch box.Type {
+	case InputBox, ButtonBox, SelectBox, TextareaBox, RadioBox, CheckboxBox, FileInputBox:
+		return true
+	}
+	return box.IsInline()
+}
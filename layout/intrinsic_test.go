@@ -0,0 +1,68 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentWidths(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	tree := buildTreeWithCSS(
+		`<section>Hello wide world <span>again</span></section>`,
+		`section { padding-left: 5px; margin-right: 3px; }`,
+	)
+	section := findBoxByTag(tree, "section")
+
+	// "Hello wide world" runs on with "again": 21 characters at 8px each
+	assert.Equal(t, 21*8.0+5+3, maxContentWidth(section, "section"))
+	// "Hello" and "again" are the widest words
+	assert.Equal(t, 5*8.0+5+3, minContentWidth(section, "section"))
+}
+
+func TestShrinkToFitFloats(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	tests := []struct {
+		name  string
+		html  string
+		width float64
+	}{
+		{"short text uses its preferred width", `<div><section>Hello</section></div>`, 40},
+		{"long text fills the container", `<div style="width: 200px"><section>Lorem ipsum dolor sit amet consectetur adipiscing</section></div>`, 200},
+		{"long word overflows a narrow container", `<div style="width: 50px"><section>Supercalifragilistic</section></div>`, 160},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(tt.html, `section { float: left; }`)
+			ComputeLayout(tree, 800)
+			assert.Equal(t, tt.width, findBoxByTag(tree, "section").Rect.Width)
+		})
+	}
+}
+
+func TestShrinkToFitAbsolutes(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	html := `<div><aside>Hi there</aside></div>`
+	t.Run("one offset shrinks to fit", func(t *testing.T) {
+		tree := buildTreeWithCSS(html, `div { position: relative; } aside { position: absolute; left: 10px; }`)
+		ComputeLayout(tree, 800)
+		assert.Equal(t, 8*8.0, findBoxByTag(tree, "aside").Rect.Width)
+	})
+
+	t.Run("left and right stretch between them", func(t *testing.T) {
+		tree := buildTreeWithCSS(html, `div { position: relative; } aside { position: absolute; left: 10px; right: 30px; }`)
+		ComputeLayout(tree, 800)
+		div := findBoxByTag(tree, "div")
+		assert.Equal(t, div.Rect.Width-40, findBoxByTag(tree, "aside").Rect.Width)
+	})
+}
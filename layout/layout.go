@@ -3,6 +3,8 @@ package layout
 import (
 	"browser/css"
 	"browser/dom"
+	"browser/utils"
+	"slices"
 	"strings"
 )
 
@@ -11,6 +13,12 @@ type Viewport struct {
 	Height float64
 }
 
+// PruneWhitespace controls whether white space that collapses away is left
+// out of the layout tree: whitespace-only text between block-level boxes,
+// and spaces at line edges or following another space. Such text only
+// formats the markup; disable it to inspect raw trees.
+var PruneWhitespace = true
+
 var blockElements = map[string]bool{
 	"html":       true,
 	"body":       true,
@@ -45,6 +53,9 @@ var blockElements = map[string]bool{
 	"figure":     true,
 	"figcaption": true,
 	"search":     true,
+	"details":    true,
+	"dialog":     true,
+	"summary":    true,
 }
 
 // transparentElements have a transparent content model per the HTML spec.
@@ -72,10 +83,22 @@ var imageElements = map[string]bool{
 }
 
 func BuildLayoutTree(root *dom.Node, stylesheet css.Stylesheet, viewport Viewport, ctx css.MatchContext) *LayoutBox {
-	return BuildBox(root, nil, stylesheet, viewport, ctx)
+	start := utils.Profile.Start()
+	tree := BuildBox(root, nil, stylesheet, viewport, ctx)
+	recordBoxes(utils.PhaseStyle, tree, start)
+	if tree != nil {
+		tree.viewportHeight = viewport.Height
+	}
+	return tree
 }
 
 func BuildBox(node *dom.Node, parent *LayoutBox, stylesheet css.Stylesheet, viewport Viewport, ctx css.MatchContext) *LayoutBox {
+	return buildBox(node, parent, stylesheet, viewport, ctx, &counterState{})
+}
+
+// buildBox builds the box for node and its subtree in document order,
+// threading CSS counter state through the traversal.
+func buildBox(node *dom.Node, parent *LayoutBox, stylesheet css.Stylesheet, viewport Viewport, ctx css.MatchContext, counters *counterState) *LayoutBox {
 	if node.Type == dom.Element && skipElements[node.TagName] {
 		return nil
 	}
@@ -83,72 +106,19 @@ func BuildBox(node *dom.Node, parent *LayoutBox, stylesheet css.Stylesheet, view
 	box := &LayoutBox{Node: node, Parent: parent}
 
 	if node.Type == dom.Element {
-		// Get parent's font-size for em unit resolution
-		parentFontSize := 16.0 // Default browser font-size
-		if parent != nil && parent.Style.FontSize > 0 {
-			parentFontSize = parent.Style.FontSize
-		}
-
-		box.Style = css.ApplyStylesheetWithContext(stylesheet, node, parentFontSize, viewport.Width, viewport.Height, ctx)
-
-		if align, ok := node.Attributes["align"]; ok {
-			switch strings.ToLower(align) {
-			case "left":
-				box.Style.TextAlign = "left"
-			case "right":
-				box.Style.TextAlign = "right"
-			case "center":
-				box.Style.TextAlign = "center"
-			case "justify":
-				box.Style.TextAlign = "justify"
-			}
-		}
-
-		if valign, ok := node.Attributes["valign"]; ok {
-			switch strings.ToLower(valign) {
-			case "top", "middle", "bottom", "baseline":
-				box.Style.VerticalAlign = strings.ToLower(valign)
-			}
-		}
-
-		if bgcolor, ok := node.Attributes["bgcolor"]; ok {
-			if c := css.ParseColor(bgcolor); c != nil {
-				box.Style.BackgroundColor = c
-			}
-		}
-
-		// Then apply inline styles (override stylesheet)
-		if styleAttr, ok := node.Attributes["style"]; ok {
-			inlineStyle := css.ParseInlineStyleWithContext(styleAttr, parentFontSize, viewport.Width, viewport.Height)
-			mergeStyles(&box.Style, &inlineStyle)
-		}
-		if parent != nil && box.Style.TextAlign == "" {
-			box.Style.TextAlign = parent.Style.TextAlign
-		}
-		if parent != nil && box.Style.WhiteSpace == "" {
-			box.Style.WhiteSpace = parent.Style.WhiteSpace
-		}
-
-		if parent != nil && box.Style.TextOverflow == "" {
-			box.Style.TextOverflow = parent.Style.TextOverflow
-		}
-		// Note: overflow, overflow-x, overflow-y are NOT CSS inherited properties.
-		// Clipping propagation is handled through TextStyle in paintLayoutBox.
-
-		if parent != nil && !box.Style.LetterSpacingSet {
-			box.Style.LetterSpacing = parent.Style.LetterSpacing
-		}
-		if parent != nil && !box.Style.WordSpacingSet {
-			box.Style.WordSpacing = parent.Style.WordSpacing
+		// Parent's computed style resolves em units and inherit keywords
+		var parentStyle *css.Style
+		if parent != nil && parent.Node != nil && parent.Node.Type == dom.Element {
+			parentStyle = &parent.Style
 		}
 
-		if parent != nil && box.Style.LineHeight == 0 {
-			box.Style.LineHeight = parent.Style.LineHeight
-		}
+		box.Style = css.ApplyStylesheetWithParent(stylesheet, node, parentStyle, viewport.Width, viewport.Height, ctx)
+		inheritParentStyle(&box.Style, parent)
 
 		if box.Style.Display == "none" {
 			return nil
 		}
+		counters.apply(&box.Style)
 
 		box.Position = box.Style.Position
 		box.Top = box.Style.Top
@@ -169,6 +139,10 @@ func BuildBox(node *dom.Node, parent *LayoutBox, stylesheet css.Stylesheet, view
 			box.Type = BRBox
 		} else if imageElements[node.TagName] {
 			box.Type = ImageBox
+			box.naturalWidth, box.naturalHeight = node.NaturalWidth, node.NaturalHeight
+			box.brokenImage = imageBroken(node)
+		} else if isMediaElement(node) {
+			box.Type = MediaBox
 		} else if node.TagName == dom.TagInput {
 			inputType := node.Attributes["type"]
 			switch strings.ToLower(inputType) {
@@ -180,6 +154,8 @@ func BuildBox(node *dom.Node, parent *LayoutBox, stylesheet css.Stylesheet, view
 				box.Type = CheckboxBox
 			case "file":
 				box.Type = FileInputBox
+			case "submit", "reset", "button":
+				box.Type = ButtonBox
 			default:
 				box.Type = InputBox
 			}
@@ -209,19 +185,44 @@ func BuildBox(node *dom.Node, parent *LayoutBox, stylesheet css.Stylesheet, view
 	case dom.Text:
 		box.Type = TextBox
 		box.Text = wrapInlineQuotes(node)
+		if parent != nil {
+			box.Text = collapseWhiteSpace(box.Text, parent.Style.WhiteSpace)
+		}
 	}
 
 	// CSS display property overrides the default box type
-	if box.Type == InlineBox && (box.Style.Display == "block" || box.Style.Display == "list-item") {
+	if box.Type == InlineBox && (box.Style.Display == "block" || box.Style.Display == "list-item" || isFlexContainer(box)) {
 		box.Type = BlockBox
 	}
 
-	for _, child := range node.Children {
-		childBox := BuildBox(child, box, stylesheet, viewport, ctx)
+	// Media elements draw their own content; their children are sources,
+	// tracks and fallback content
+	if box.Type == MediaBox {
+		return box
+	}
+
+	// Counters instantiated inside this element go out of scope at its end
+	scope := counters.mark()
+	if marker := buildDisclosureMarker(node, box); marker != nil {
+		box.Children = append(box.Children, marker)
+	}
+	if before := buildPseudoBox(node, box, "before", box.Style.BeforeStyle, counters); before != nil {
+		box.Children = append(box.Children, before)
+	}
+	for _, child := range renderedChildren(node) {
+		childBox := buildBox(child, box, stylesheet, viewport, ctx, counters)
 		if childBox != nil {
 			box.Children = append(box.Children, childBox)
 		}
 	}
+	if after := buildPseudoBox(node, box, "after", box.Style.AfterStyle, counters); after != nil {
+		box.Children = append(box.Children, after)
+	}
+	counters.popTo(scope)
+
+	if isFlexContainer(box) {
+		blockifyFlexItems(box)
+	}
 
 	// Promote transparent elements to block if they contain block children
 	if box.Type == InlineBox && box.Node != nil && transparentElements[box.Node.TagName] {
@@ -233,255 +234,236 @@ func BuildBox(node *dom.Node, parent *LayoutBox, stylesheet css.Stylesheet, view
 		}
 	}
 
+	if PruneWhitespace {
+		if collapsesSpaces(box.Style.WhiteSpace) {
+			box.Children = pruneWhitespaceRuns(box.Children)
+		}
+		if box.Type != InlineBox && box.Type != TextBox {
+			collapseInlineSpaces(box)
+		}
+	}
+	if box.Type == BlockBox || box.Type == TableCellBox || box.Type == TableCaptionBox || box.Type == FieldsetBox {
+		wrapInlineRuns(box)
+	}
+
 	return box
 }
 
-// mergeStyles merges inline style into base style (inline wins)
-func mergeStyles(base *css.Style, inline *css.Style) {
-	if inline.Color != nil {
-		base.Color = inline.Color
-	}
-	if inline.BackgroundColor != nil {
-		base.BackgroundColor = inline.BackgroundColor
-	}
-	if inline.FontSize > 0 {
-		base.FontSize = inline.FontSize
-	}
-	if inline.FontVariant != "" {
-		base.FontVariant = inline.FontVariant
-	}
-	if inline.LineHeight > 0 {
-		base.LineHeight = inline.LineHeight
-	}
-	if inline.Bold {
-		base.Bold = true
-	}
-	if inline.Italic {
-		base.Italic = true
-	}
-	if inline.MarginTop > 0 {
-		base.MarginTop = inline.MarginTop
-	}
-	if inline.MarginBottom > 0 {
-		base.MarginBottom = inline.MarginBottom
-	}
-	if inline.MarginLeft > 0 || inline.MarginLeftAuto {
-		base.MarginLeft = inline.MarginLeft
-		base.MarginLeftAuto = inline.MarginLeftAuto
-	}
-	if inline.MarginRight > 0 || inline.MarginRightAuto {
-		base.MarginRight = inline.MarginRight
-		base.MarginRightAuto = inline.MarginRightAuto
-	}
-	if inline.PaddingTop > 0 {
-		base.PaddingTop = inline.PaddingTop
-	}
-	if inline.PaddingBottom > 0 {
-		base.PaddingBottom = inline.PaddingBottom
-	}
-	if inline.PaddingLeft > 0 {
-		base.PaddingLeft = inline.PaddingLeft
-	}
-	if inline.PaddingRight > 0 {
-		base.PaddingRight = inline.PaddingRight
-	}
-	if inline.TextAlign != "" {
-		base.TextAlign = inline.TextAlign
-	}
-	if inline.TextIndent != "" {
-		base.TextIndent = inline.TextIndent
-	}
-	if inline.WhiteSpace != "" {
-		base.WhiteSpace = inline.WhiteSpace
-	}
-	if inline.TextOverflow != "" {
-		base.TextOverflow = inline.TextOverflow
-	}
-	if inline.OverflowX != "" {
-		base.OverflowX = inline.OverflowX
-	}
-	if inline.OverflowY != "" {
-		base.OverflowY = inline.OverflowY
+// pruneWhitespaceRuns drops runs of whitespace-only text that sit between
+// block-level boxes or at the edges of a block with block children, the
+// text CSS would put in empty anonymous blocks (CSS 2.1 §9.2.1.1). Text
+// in a purely inline context is kept; line layout collapses it.
+func pruneWhitespaceRuns(children []*LayoutBox) []*LayoutBox {
+	hasBlock := false
+	for _, child := range children {
+		if isBlockLevel(child) {
+			hasBlock = true
+			break
+		}
 	}
-	if inline.Overflow != "" {
-		base.Overflow = inline.Overflow
+	if !hasBlock {
+		return children
 	}
 
-	if inline.VerticalAlign != "" {
-		base.VerticalAlign = inline.VerticalAlign
-	}
-	if inline.Display != "" {
-		base.Display = inline.Display
-	}
-	if inline.TextDecoration != "" {
-		base.TextDecoration = inline.TextDecoration
-	}
-	if inline.TextTransform != "" {
-		base.TextTransform = inline.TextTransform
-	}
-	if inline.LetterSpacingSet {
-		base.LetterSpacing = inline.LetterSpacing
-		base.LetterSpacingSet = true
-	}
-	if inline.WordSpacingSet {
-		base.WordSpacing = inline.WordSpacing
-		base.WordSpacingSet = true
-	}
-	if inline.Opacity != 1.0 {
-		base.Opacity = inline.Opacity
-	}
-	if inline.Visibility != "" {
-		base.Visibility = inline.Visibility
-	}
-	if inline.Cursor != "" {
-		base.Cursor = inline.Cursor
-	}
-	if inline.ListStyleType != "" {
-		base.ListStyleType = inline.ListStyleType
-	}
-	// Border properties
-	if inline.BorderTopWidth > 0 {
-		base.BorderTopWidth = inline.BorderTopWidth
-	}
-	if inline.BorderRightWidth > 0 {
-		base.BorderRightWidth = inline.BorderRightWidth
-	}
-	if inline.BorderBottomWidth > 0 {
-		base.BorderBottomWidth = inline.BorderBottomWidth
-	}
-	if inline.BorderLeftWidth > 0 {
-		base.BorderLeftWidth = inline.BorderLeftWidth
-	}
-	if inline.BorderTopColor != nil {
-		base.BorderTopColor = inline.BorderTopColor
-	}
-	if inline.BorderRightColor != nil {
-		base.BorderRightColor = inline.BorderRightColor
-	}
-	if inline.BorderBottomColor != nil {
-		base.BorderBottomColor = inline.BorderBottomColor
-	}
-	if inline.BorderLeftColor != nil {
-		base.BorderLeftColor = inline.BorderLeftColor
-	}
-	if inline.BorderTopStyle != "" {
-		base.BorderTopStyle = inline.BorderTopStyle
+	kept := children[:0]
+	start := 0 // first index of the current inline run
+	flush := func(end int) {
+		run := children[start:end]
+		for _, child := range run {
+			if !isWhitespaceText(child) {
+				kept = append(kept, run...)
+				return
+			}
+		}
 	}
-	if inline.BorderRightStyle != "" {
-		base.BorderRightStyle = inline.BorderRightStyle
+	for i, child := range children {
+		if isBlockLevel(child) {
+			flush(i)
+			kept = append(kept, child)
+			start = i + 1
+		}
 	}
-	if inline.BorderBottomStyle != "" {
-		base.BorderBottomStyle = inline.BorderBottomStyle
+	flush(len(children))
+	return kept
+}
+
+// wrapInlineRuns wraps each run of inline-level children of a block
+// container that also has block-level children in an anonymous block box
+// (CSS 2.1 §9.2.1.1), so the run's lines stack between the blocks like a
+// paragraph of their own. Runs of only floats, positioned boxes and
+// collapsible white space are left unwrapped.
+func wrapInlineRuns(box *LayoutBox) {
+	breaksRun := func(child *LayoutBox) bool {
+		return isBlockLevel(child) || child.Type == LegendBox
+	}
+	if !slices.ContainsFunc(box.Children, breaksRun) {
+		return
+	}
+
+	children := make([]*LayoutBox, 0, len(box.Children))
+	var run []*LayoutBox
+	flush := func() {
+		inFlow := slices.ContainsFunc(run, func(child *LayoutBox) bool {
+			return !isFloated(child) && child.Position != "absolute" && child.Position != "fixed" && !isWhitespaceText(child)
+		})
+		if inFlow {
+			children = append(children, anonymousBlock(box, run))
+		} else {
+			children = append(children, run...)
+		}
+		run = nil
 	}
-	if inline.BorderLeftStyle != "" {
-		base.BorderLeftStyle = inline.BorderLeftStyle
+	for _, child := range box.Children {
+		if breaksRun(child) {
+			flush()
+			children = append(children, child)
+			continue
+		}
+		run = append(run, child)
 	}
+	flush()
+	box.Children = children
+}
 
-	if inline.BorderTopLeftRadius > 0 {
-		base.BorderTopLeftRadius = inline.BorderTopLeftRadius
+// anonymousBlock wraps children in a block box with no node that inherits
+// container's text properties.
+func anonymousBlock(container *LayoutBox, children []*LayoutBox) *LayoutBox {
+	anon := &LayoutBox{Type: BlockBox, Parent: container, Style: css.InheritedStyle(&container.Style), Children: children}
+	for _, child := range children {
+		child.Parent = anon
 	}
+	return anon
+}
 
-	if inline.BorderTopRightRadius > 0 {
-		base.BorderTopRightRadius = inline.BorderTopRightRadius
+// isBlockLevel reports whether box takes part in block layout rather than
+// a line. Floats and positioned boxes are out of flow and count as neither.
+func isBlockLevel(box *LayoutBox) bool {
+	if box.Float == "left" || box.Float == "right" || box.Position == "absolute" || box.Position == "fixed" {
+		return false
 	}
-
-	if inline.BorderBottomLeftRadius > 0 {
-		base.BorderBottomLeftRadius = inline.BorderBottomLeftRadius
+	switch box.Type {
+	case BlockBox, TableBox, FieldsetBox, HRBox, TableRowBox, TableCellBox, TableCaptionBox:
+		return true
 	}
+	return false
+}
 
-	if inline.BorderBottomRightRadius > 0 {
-		base.BorderBottomRightRadius = inline.BorderBottomRightRadius
-	}
+// isWhitespaceText reports whether box is a text box of collapsible spaces.
+func isWhitespaceText(box *LayoutBox) bool {
+	return box.Type == TextBox && strings.TrimFunc(box.Text, isBreakableSpace) == ""
+}
 
-	// Sizing properties
-	if inline.Width > 0 {
-		base.Width = inline.Width
+// inheritParentStyle copies inherited text properties the cascade left unset.
+func inheritParentStyle(style *css.Style, parent *LayoutBox) {
+	if parent == nil {
+		return
 	}
-	if inline.WidthPercent > 0 {
-		base.WidthPercent = inline.WidthPercent
+	if style.TextAlign == "" {
+		style.TextAlign = parent.Style.TextAlign
 	}
-
-	if inline.MinWidth > 0 {
-		base.MinWidth = inline.MinWidth
+	if style.WhiteSpace == "" {
+		style.WhiteSpace = parent.Style.WhiteSpace
 	}
-
-	if inline.MaxWidth > 0 {
-		base.MaxWidth = inline.MaxWidth
+	if style.Hyphens == "" {
+		style.Hyphens = parent.Style.Hyphens
 	}
 
-	if inline.Height > 0 {
-		base.Height = inline.Height
+	if style.TextOverflow == "" {
+		style.TextOverflow = parent.Style.TextOverflow
 	}
+	// Note: overflow, overflow-x, overflow-y are NOT CSS inherited properties.
+	// Clipping propagation is handled through TextStyle in paintLayoutBox.
 
-	if inline.MinHeight > 0 {
-		base.MinHeight = inline.MinHeight
+	if !style.LetterSpacingSet {
+		style.LetterSpacing = parent.Style.LetterSpacing
 	}
-
-	if inline.MaxHeight > 0 {
-		base.MaxHeight = inline.MaxHeight
+	if !style.WordSpacingSet {
+		style.WordSpacing = parent.Style.WordSpacing
 	}
 
-	// Position properties
-	if inline.Position != "" {
-		base.Position = inline.Position
-	}
-	if inline.Top > 0 {
-		base.Top = inline.Top
-	}
-	if inline.Left > 0 {
-		base.Left = inline.Left
-	}
-	if inline.Right > 0 {
-		base.Right = inline.Right
-	}
-	if inline.Bottom > 0 {
-		base.Bottom = inline.Bottom
+	if style.LineHeight == 0 {
+		style.LineHeight = parent.Style.LineHeight
 	}
 
-	if inline.Float != "" {
-		base.Float = inline.Float
+	// Font selection, so text is measured in the face it is painted in
+	if len(style.FontFamily) == 0 {
+		style.FontFamily = parent.Style.FontFamily
 	}
-
-	if inline.Clear != "" {
-		base.Clear = inline.Clear
+	if !style.Italic {
+		style.Italic = parent.Style.Italic
 	}
 
-	if inline.BoxSizing != "" {
-		base.BoxSizing = inline.BoxSizing
+	if style.BorderCollapse == "" {
+		style.BorderCollapse = parent.Style.BorderCollapse
 	}
-
-	if len(inline.FontFamily) > 0 {
-		base.FontFamily = inline.FontFamily
+	if style.CaptionSide == "" {
+		style.CaptionSide = parent.Style.CaptionSide
 	}
-
-	if inline.BorderRadius > 0 {
-		base.BorderRadius = inline.BorderRadius
+	if !style.BorderSpacingSet {
+		style.BorderSpacingX, style.BorderSpacingY = parent.Style.BorderSpacingX, parent.Style.BorderSpacingY
+		style.BorderSpacingSet = parent.Style.BorderSpacingSet
 	}
+}
 
-	if inline.BackgroundImage != "" {
-		base.BackgroundImage = inline.BackgroundImage
+// buildPseudoBox creates the ::before or ::after box of an element from its
+// cascaded pseudo-element style. The box gets a synthetic node (not attached
+// to the DOM) whose parent is the originating element, so hit testing and
+// link lookup resolve to the element itself.
+func buildPseudoBox(node *dom.Node, parent *LayoutBox, pseudo string, style *css.Style, counters *counterState) *LayoutBox {
+	if style == nil || style.Display == "none" {
+		return nil
 	}
+	counters.apply(style)
 
-	if inline.BackgroundSize != "" {
-		base.BackgroundSize = inline.BackgroundSize
+	pseudoNode := &dom.Node{Type: dom.Element, TagName: "::" + pseudo, Parent: node}
+	box := &LayoutBox{Node: pseudoNode, Parent: parent, Style: *style, Type: InlineBox}
+	inheritParentStyle(&box.Style, parent)
+	if style.Display == "block" || style.Display == "list-item" || isFlexContainer(box) {
+		box.Type = BlockBox
 	}
+	box.Position = box.Style.Position
+	box.Top = box.Style.Top
+	box.Left = box.Style.Left
+	box.Right = box.Style.Right
+	box.Bottom = box.Style.Bottom
+	box.Float = box.Style.Float
+	box.Clear = box.Style.Clear
 
-	if inline.TopSet {
-		base.Top = inline.Top
-		base.TopSet = true
-	}
-	if inline.LeftSet {
-		base.Left = inline.Left
-		base.LeftSet = true
+	if text := generatedContentText(node, css.ParseContent(style.Content), counters); text != "" {
+		textNode := &dom.Node{Type: dom.Text, Text: text, Parent: pseudoNode}
+		pseudoNode.Children = []*dom.Node{textNode}
+		box.Children = []*LayoutBox{{Node: textNode, Parent: box, Type: TextBox, Text: text}}
 	}
-	if inline.RightSet {
-		base.Right = inline.Right
-		base.RightSet = true
-	}
-	if inline.BottomSet {
-		base.Bottom = inline.Bottom
-		base.BottomSet = true
+	return box
+}
+
+// generatedContentText resolves parsed `content` items against the
+// originating element and the counters in scope.
+func generatedContentText(node *dom.Node, items []css.ContentItem, counters *counterState) string {
+	var sb strings.Builder
+	for _, item := range items {
+		switch item.Kind {
+		case css.ContentString:
+			sb.WriteString(item.Value)
+		case css.ContentAttr:
+			sb.WriteString(node.Attributes[item.Value])
+		case css.ContentCounter:
+			sb.WriteString(css.FormatCounter(counters.value(item.Value), item.ListStyle))
+		case css.ContentCounters:
+			values := counters.values(item.Value)
+			for i, v := range values {
+				if i > 0 {
+					sb.WriteString(item.Separator)
+				}
+				sb.WriteString(css.FormatCounter(v, item.ListStyle))
+			}
+		case css.ContentOpenQuote:
+			sb.WriteString("\u201C")
+		case css.ContentCloseQuote:
+			sb.WriteString("\u201D")
+		}
 	}
+	return sb.String()
 }
 
 // wrapInlineQuotes adds quotation marks for <q> elements
@@ -1,8 +1,6 @@
 package layout
 
 import (
-	"browser/css"
-	"image/color"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -295,188 +293,212 @@ func TestBuildLayoutTreeOverflowYNotInherited(t *testing.T) {
 	assert.Equal(t, "", spanBox.Style.OverflowY)
 }
 
-func TestMergeStyles(t *testing.T) {
+func TestBuildLayoutTreePseudoElements(t *testing.T) {
 	tests := []struct {
-		name   string
-		base   css.Style
-		inline css.Style
-		verify func(t *testing.T, result *css.Style)
+		name       string
+		html       string
+		css        string
+		wantBefore string // text of the ::before box, "-" = no box
+		wantAfter  string
+		beforeType BoxType
+	}{
+		{"string content", `<p>body</p>`, `p::before { content: "> "; } p::after { content: " <"; }`, "> ", " <", InlineBox},
+		{"attr content", `<p title="hi">body</p>`, `p::after { content: " (" attr(title) ")"; }`, "-", " (hi)", InlineBox},
+		{"missing attr is empty", `<p>body</p>`, `p::before { content: attr(title); }`, "", "-", InlineBox},
+		{"quotes", `<p>body</p>`, `p::before { content: open-quote; } p::after { content: close-quote; }`, "\u201C", "\u201D", InlineBox},
+		{"display block", `<p>body</p>`, `p::before { content: "x"; display: block; }`, "x", "-", BlockBox},
+		{"display none", `<p>body</p>`, `p::before { content: "x"; display: none; }`, "-", "-", InlineBox},
+		{"content none", `<p>body</p>`, `p::before { content: none; }`, "-", "-", InlineBox},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(tt.html, tt.css)
+			p := findBoxByTag(tree, "p")
+			assert.NotNil(t, p)
+
+			before := findBoxByTag(p, "::before")
+			if tt.wantBefore == "-" {
+				assert.Nil(t, before)
+			} else if assert.NotNil(t, before) {
+				assert.Same(t, before, p.Children[0])
+				assert.Equal(t, tt.beforeType, before.Type)
+				assert.Equal(t, tt.wantBefore, collectText(before))
+			}
+
+			after := findBoxByTag(p, "::after")
+			if tt.wantAfter == "-" {
+				assert.Nil(t, after)
+			} else if assert.NotNil(t, after) {
+				assert.Same(t, after, p.Children[len(p.Children)-1])
+				assert.Equal(t, tt.wantAfter, collectText(after))
+			}
+		})
+	}
+}
+
+func TestBuildLayoutTreeCounters(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		css      string
+		expected []string // ::before texts in document order
 	}{
 		{
-			name:   "color merged",
-			base:   css.Style{},
-			inline: css.Style{Color: color.RGBA{255, 0, 0, 255}},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, color.RGBA{255, 0, 0, 255}, r.Color) },
-		},
-		{
-			name:   "background color merged",
-			base:   css.Style{},
-			inline: css.Style{BackgroundColor: color.RGBA{0, 255, 0, 255}},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, color.RGBA{0, 255, 0, 255}, r.BackgroundColor) },
-		},
-		{
-			name:   "font size merged when positive",
-			base:   css.Style{FontSize: 16},
-			inline: css.Style{FontSize: 24},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, 24.0, r.FontSize) },
-		},
-		{
-			name:   "font size zero does not override",
-			base:   css.Style{FontSize: 16},
-			inline: css.Style{FontSize: 0},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, 16.0, r.FontSize) },
-		},
-		{
-			name:   "bold merged",
-			base:   css.Style{Bold: false},
-			inline: css.Style{Bold: true},
-			verify: func(t *testing.T, r *css.Style) { assert.True(t, r.Bold) },
-		},
-		{
-			name:   "italic merged",
-			base:   css.Style{Italic: false},
-			inline: css.Style{Italic: true},
-			verify: func(t *testing.T, r *css.Style) { assert.True(t, r.Italic) },
-		},
-		{
-			name:   "margins merged",
-			base:   css.Style{},
-			inline: css.Style{MarginTop: 10, MarginRight: 20, MarginBottom: 30, MarginLeft: 40},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 10.0, r.MarginTop)
-				assert.Equal(t, 20.0, r.MarginRight)
-				assert.Equal(t, 30.0, r.MarginBottom)
-				assert.Equal(t, 40.0, r.MarginLeft)
-			},
-		},
-		{
-			name:   "paddings merged",
-			base:   css.Style{},
-			inline: css.Style{PaddingTop: 5, PaddingRight: 10, PaddingBottom: 15, PaddingLeft: 20},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 5.0, r.PaddingTop)
-				assert.Equal(t, 10.0, r.PaddingRight)
-				assert.Equal(t, 15.0, r.PaddingBottom)
-				assert.Equal(t, 20.0, r.PaddingLeft)
-			},
-		},
-		{
-			name:   "text align merged",
-			base:   css.Style{TextAlign: "left"},
-			inline: css.Style{TextAlign: "center"},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, "center", r.TextAlign) },
-		},
-		{
-			name:   "empty text align does not override",
-			base:   css.Style{TextAlign: "left"},
-			inline: css.Style{TextAlign: ""},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, "left", r.TextAlign) },
-		},
-		{
-			name:   "display merged",
-			base:   css.Style{Display: "block"},
-			inline: css.Style{Display: "none"},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, "none", r.Display) },
-		},
-		{
-			name:   "overflow-x merged",
-			base:   css.Style{OverflowX: "visible"},
-			inline: css.Style{OverflowX: "hidden"},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, "hidden", r.OverflowX) },
-		},
-		{
-			name:   "overflow-y merged",
-			base:   css.Style{OverflowY: "visible"},
-			inline: css.Style{OverflowY: "hidden"},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, "hidden", r.OverflowY) },
-		},
-		{
-			name:   "opacity merged when not 1.0",
-			base:   css.Style{Opacity: 1.0},
-			inline: css.Style{Opacity: 0.5},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, 0.5, r.Opacity) },
-		},
-		{
-			name:   "opacity 1.0 does not override",
-			base:   css.Style{Opacity: 0.5},
-			inline: css.Style{Opacity: 1.0},
-			verify: func(t *testing.T, r *css.Style) { assert.Equal(t, 0.5, r.Opacity) },
-		},
-		{
-			name:   "letter-spacing merged when explicitly set",
-			base:   css.Style{LetterSpacing: 2, LetterSpacingSet: true},
-			inline: css.Style{LetterSpacing: 0, LetterSpacingSet: true},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 0.0, r.LetterSpacing)
-				assert.True(t, r.LetterSpacingSet)
-			},
-		},
-		{
-			name:   "unset letter-spacing does not override",
-			base:   css.Style{LetterSpacing: 2, LetterSpacingSet: true},
-			inline: css.Style{},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 2.0, r.LetterSpacing)
-				assert.True(t, r.LetterSpacingSet)
-			},
+			"numbered headings",
+			`<body><h2>A</h2><p>x</p><h2>B</h2><h2>C</h2></body>`,
+			`body { counter-reset: section; } h2::before { counter-increment: section; content: counter(section) ". "; }`,
+			[]string{"1. ", "2. ", "3. "},
 		},
 		{
-			name:   "word-spacing merged when explicitly set",
-			base:   css.Style{WordSpacing: 2, WordSpacingSet: true},
-			inline: css.Style{WordSpacing: 0, WordSpacingSet: true},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 0.0, r.WordSpacing)
-				assert.True(t, r.WordSpacingSet)
-			},
+			"heading levels reset nested counters",
+			`<body><h2>A</h2><h3>a</h3><h3>b</h3><h2>B</h2><h3>a</h3></body>`,
+			`body { counter-reset: h2; } h2 { counter-reset: h3; }
+			 h2::before { counter-increment: h2; content: counter(h2) " "; }
+			 h3::before { counter-increment: h3; content: counter(h2) "." counter(h3) " "; }`,
+			[]string{"1 ", "1.1 ", "1.2 ", "2 ", "2.1 "},
 		},
 		{
-			name:   "unset word-spacing does not override",
-			base:   css.Style{WordSpacing: 2, WordSpacingSet: true},
-			inline: css.Style{},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 2.0, r.WordSpacing)
-				assert.True(t, r.WordSpacingSet)
-			},
+			"nested counters() in lists",
+			`<ol><li>a<ol><li>b</li><li>c</li></ol></li><li>d</li></ol>`,
+			`ol { counter-reset: item; } li::before { counter-increment: item; content: counters(item, ".") " "; }`,
+			[]string{"1 ", "1.1 ", "1.2 ", "2 "},
 		},
 		{
-			name:   "border widths merged",
-			base:   css.Style{},
-			inline: css.Style{BorderTopWidth: 1, BorderRightWidth: 2, BorderBottomWidth: 3, BorderLeftWidth: 4},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 1.0, r.BorderTopWidth)
-				assert.Equal(t, 2.0, r.BorderRightWidth)
-				assert.Equal(t, 3.0, r.BorderBottomWidth)
-				assert.Equal(t, 4.0, r.BorderLeftWidth)
-			},
+			"counter style",
+			`<div><p>a</p><p>b</p></div>`,
+			`p { counter-increment: para; } p::before { content: counter(para, upper-roman) ") "; }`,
+			[]string{"I) ", "II) "},
 		},
 		{
-			name:   "sizing properties merged",
-			base:   css.Style{},
-			inline: css.Style{Width: 100, Height: 200, MinWidth: 50},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 100.0, r.Width)
-				assert.Equal(t, 200.0, r.Height)
-				assert.Equal(t, 50.0, r.MinWidth)
-			},
+			"reset value and inline style",
+			`<div style="counter-reset: n 9"><p>a</p><p>b</p></div>`,
+			`p::before { counter-increment: n; content: counter(n); }`,
+			[]string{"10", "11"},
 		},
 		{
-			name:   "zero sizing does not override",
-			base:   css.Style{Width: 100, Height: 200, MinWidth: 50},
-			inline: css.Style{Width: 0, Height: 0, MinWidth: 0},
-			verify: func(t *testing.T, r *css.Style) {
-				assert.Equal(t, 100.0, r.Width)
-				assert.Equal(t, 200.0, r.Height)
-				assert.Equal(t, 50.0, r.MinWidth)
-			},
+			"display none does not increment",
+			`<div><p>a</p><p style="display: none">b</p><p>c</p></div>`,
+			`div { counter-reset: n; } p { counter-increment: n; } p::before { content: counter(n); }`,
+			[]string{"1", "2"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			base := tt.base
-			inline := tt.inline
-			mergeStyles(&base, &inline)
-			tt.verify(t, &base)
+			tree := buildTreeWithCSS(tt.html, tt.css)
+			var got []string
+			var walk func(box *LayoutBox)
+			walk = func(box *LayoutBox) {
+				if box.Node != nil && box.Node.TagName == "::before" {
+					got = append(got, collectText(box))
+				}
+				for _, child := range box.Children {
+					walk(child)
+				}
+			}
+			walk(tree)
+			assert.Equal(t, tt.expected, got)
 		})
 	}
 }
+
+func TestBuildLayoutTreeCSSWideKeywords(t *testing.T) {
+	tree := buildTreeWithCSS(
+		`<div><p>a</p><span style="padding-left: inherit; margin-left: initial">b</span></div>`,
+		`div { font-size: 20px; padding-left: 7px; border-top: 2px solid black; }
+		 p { font-size: 10px; font-size: inherit; border-top: inherit; margin-top: unset; }
+		 span { margin-left: 4px; }`)
+
+	p := findBoxByTag(tree, "p")
+	assert.NotNil(t, p)
+	assert.Equal(t, 20.0, p.Style.FontSize)
+	assert.Equal(t, 2.0, p.Style.BorderTopWidth)
+	assert.Equal(t, 0.0, p.Style.MarginTop)
+
+	span := findBoxByTag(tree, "span")
+	assert.NotNil(t, span)
+	assert.Equal(t, 7.0, span.Style.PaddingLeft)
+	assert.Equal(t, 0.0, span.Style.MarginLeft)
+}
+
+func TestBuildLayoutTreePrunesWhitespace(t *testing.T) {
+	// Markup as it is usually written: indented, with spaces between tags
+	indented := `<html><body>
+  <div class="card">  <h2>Title</h2>
+    <p>Some <b>bold</b> <i>text</i></p>  <hr>
+  </div>  <ul>  <li>One</li>  <li>Two</li>  </ul>
+  <pre>  keep
+    this  </pre>
+</body></html>`
+	compact := `<html><body><div class="card"><h2>Title</h2><p>Some <b>bold</b> <i>text</i></p><hr></div><ul><li>One</li><li>Two</li></ul><pre>  keep
+    this  </pre></body></html>`
+
+	t.Run("no text boxes between blocks", func(t *testing.T) {
+		tree := buildTree(indented)
+		for _, tag := range []string{"body", "div", "ul"} {
+			for _, child := range findBoxByTag(tree, tag).Children {
+				assert.NotEqual(t, TextBox, child.Type, "whitespace text kept in <%s>", tag)
+			}
+		}
+	})
+
+	t.Run("inline whitespace is kept", func(t *testing.T) {
+		p := findBoxByTag(buildTree(indented), "p")
+		assert.Len(t, p.Children, 4, "Some, <b>, space, <i>")
+		assert.Equal(t, " ", p.Children[2].Text)
+	})
+
+	t.Run("pre keeps its whitespace", func(t *testing.T) {
+		pre := findBoxByTag(buildTree(indented), "pre")
+		assert.Equal(t, "  keep\n    this  ", pre.Children[0].Text)
+	})
+
+	t.Run("same height as compact markup", func(t *testing.T) {
+		a, b := buildTree(indented), buildTree(compact)
+		ComputeLayout(a, 800)
+		ComputeLayout(b, 800)
+		assert.Equal(t, b.Rect.Height, a.Rect.Height)
+		assert.Equal(t, findBoxByTag(b, "ul").Rect, findBoxByTag(a, "ul").Rect)
+	})
+
+	t.Run("pruning can be turned off", func(t *testing.T) {
+		PruneWhitespace = false
+		defer func() { PruneWhitespace = true }()
+
+		ul := findBoxByTag(buildTree(indented), "ul")
+		assert.Equal(t, TextBox, ul.Children[0].Type)
+	})
+}
+
+func TestBuildLayoutTreeAnonymousBlocks(t *testing.T) {
+	tree := buildTreeWithCSS(`<div>Some text <b>bold</b><p>Para</p>more text</div>`, `div { text-align: center; font-size: 20px; }`)
+	div := findBoxByTag(tree, "div")
+
+	if assert.Len(t, div.Children, 3, "anonymous block, <p>, anonymous block") {
+		first, p, last := div.Children[0], div.Children[1], div.Children[2]
+		assert.Equal(t, BlockBox, first.Type)
+		assert.Nil(t, first.Node)
+		assert.Len(t, first.Children, 2, "text and <b> share one block")
+		assert.Same(t, first, first.Children[0].Parent)
+		assert.Equal(t, "p", p.Node.TagName)
+		assert.Nil(t, last.Node)
+		assert.Equal(t, "more text", last.Children[0].Text)
+
+		// Inherited properties reach the anonymous block, others do not
+		assert.Equal(t, "center", first.Style.TextAlign)
+		assert.Equal(t, 20.0, first.Style.FontSize)
+		assert.Zero(t, first.Style.MarginTop)
+
+		ComputeLayout(tree, 800)
+		assert.LessOrEqual(t, first.Rect.Y+first.Rect.Height, p.Rect.Y)
+		assert.LessOrEqual(t, p.Rect.Y+p.Rect.Height, last.Rect.Y)
+	}
+
+	t.Run("only inline content is not wrapped", func(t *testing.T) {
+		p := findBoxByTag(buildTree(`<p>Some <b>bold</b> text</p>`), "p")
+		for _, child := range p.Children {
+			assert.NotNil(t, child.Node)
+		}
+	})
+}
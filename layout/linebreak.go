@@ -0,0 +1,83 @@
+package layout
+
+import (
+	"strings"
+	"unicode"
+)
+
+// textSegment is a piece of text lines may not break inside.
+type textSegment struct {
+	text  string
+	space bool // collapsible space separates it from the previous segment
+}
+
+// lineSegments splits text at its line break opportunities, a subset of
+// UAX #14: runs of collapsible spaces, which are dropped; after hyphens and
+// slashes inside words; and around CJK characters, which break anywhere
+// except before closing and after opening punctuation. No-break spaces and
+// word joiners never allow a break.
+func lineSegments(text string) []textSegment {
+	var segments []textSegment
+	for _, word := range strings.FieldsFunc(text, isBreakableSpace) {
+		runes := []rune(word)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			if breaksBefore(runes, i) {
+				segments = append(segments, textSegment{text: string(runes[start:i]), space: start == 0})
+				start = i
+			}
+		}
+		segments = append(segments, textSegment{text: string(runes[start:]), space: start == 0})
+	}
+	if len(segments) > 0 {
+		segments[0].space = false
+	}
+	return segments
+}
+
+// breaksBefore reports whether a line may break between runes[i-1] and
+// runes[i], which are not spaces.
+func breaksBefore(runes []rune, i int) bool {
+	before, after := runes[i-1], runes[i]
+	if isGlue(before) || isGlue(after) || noBreakBefore(after) || noBreakAfter(before) {
+		return false
+	}
+	switch before {
+	case '-', '\u2010', '\u2013', '\u00ad':
+		// "well-known" breaks after the hyphen, "-5" and "3-4" do not
+		return i >= 2 && (unicode.IsLetter(runes[i-2]) || unicode.IsDigit(runes[i-2])) && unicode.IsLetter(after)
+	case '/':
+		// Paths break after a slash, "//" stays together
+		return i >= 2 && runes[i-2] != '/' && after != '/' && (unicode.IsLetter(after) || unicode.IsDigit(after))
+	}
+	return isCJK(before) || isCJK(after)
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character, which
+// lines may break before and after without a space.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r >= '\u3000' && r <= '\u303f' || r >= '\uff00' && r <= '\uffef'
+}
+
+// isGlue reports whether r joins its neighbours: no-break spaces and word
+// joiners.
+func isGlue(r rune) bool {
+	switch r {
+	case '\u00a0', '\u2007', '\u202f', '\u2060', '\ufeff':
+		return true
+	}
+	return false
+}
+
+// noBreakBefore reports whether r may not start a line: closing brackets,
+// sentence punctuation, small kana and iteration marks.
+func noBreakBefore(r rune) bool {
+	return strings.ContainsRune(`,.:;!?)]}'"%`+"、。，．：；！？）」』】〕〉》〙〗｝］｠〟’”ーゝゞヽヾ々〻ぁぃぅぇぉっゃゅょゎゕゖァィゥェォッャュョヮヵヶ", r)
+}
+
+// noBreakAfter reports whether r may not end a line: opening brackets and
+// quotes.
+func noBreakAfter(r rune) bool {
+	return strings.ContainsRune(`([{`+"（「『【〔〈《〘〖｛［｟〝‘“", r)
+}
@@ -0,0 +1,33 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineSegments(t *testing.T) {
+	segment := func(text string, space bool) textSegment { return textSegment{text: text, space: space} }
+
+	tests := []struct {
+		name string
+		text string
+		want []textSegment
+	}{
+		{"spaces", " hello  big\tworld ", []textSegment{segment("hello", false), segment("big", true), segment("world", true)}},
+		{"no-break space", "10\u00a0km away", []textSegment{segment("10\u00a0km", false), segment("away", true)}},
+		{"after hyphens", "well-known", []textSegment{segment("well-", false), segment("known", false)}},
+		{"not before numbers", "-5 and 3-4", []textSegment{segment("-5", false), segment("and", true), segment("3-4", true)}},
+		{"after slashes", "a/b http://x.org", []textSegment{segment("a/", false), segment("b", false), segment("http://x.org", true)}},
+		{"CJK anywhere", "日本語", []textSegment{segment("日", false), segment("本", false), segment("語", false)}},
+		{"CJK punctuation", "「東京」です。", []textSegment{segment("「東", false), segment("京」", false), segment("で", false), segment("す。", false)}},
+		{"CJK beside Latin", "Go言語", []textSegment{segment("Go", false), segment("言", false), segment("語", false)}},
+		{"word joiner", "日\u2060本", []textSegment{segment("日\u2060本", false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, lineSegments(tt.text))
+		})
+	}
+}
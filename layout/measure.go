@@ -2,42 +2,104 @@ package layout
 
 import (
 	"strings"
-	"unicode/utf8"
+
+	"browser/css"
 )
 
-// MeasureTextFunc is a function that measures text width given text, fontSize, bold, italic
-type MeasureTextFunc func(text string, fontSize float64, bold bool, italic bool) float64
+// Font selects the face text is measured in.
+type Font struct {
+	Families  []string // CSS font-family list; empty means the default font
+	Weight    int      // 1–1000; 0 means normal
+	Italic    bool
+	Monospace bool // fixed-width face, as for <pre> and monospace families
+}
+
+// StyleFont returns the font of text in an element with computed style s.
+func StyleFont(s css.Style) Font {
+	return Font{Families: s.FontFamily, Weight: s.FontWeight, Italic: s.Italic, Monospace: css.IsMonospaceFamily(s.FontFamily)}
+}
+
+// MeasureTextFunc is a function that measures the width of text in font
+type MeasureTextFunc func(text string, fontSize float64, font Font) float64
 
 // TextMeasurer is the function used to measure text width.
 // Set this to use accurate font measurements (e.g., from Fyne).
 // If nil, falls back to estimation.
 var TextMeasurer MeasureTextFunc
 
-// MeasureText returns the width of text.
-// Uses TextMeasurer if set, otherwise estimates.
+// FontMetrics are a font's vertical metrics in em units (OpenType hhea).
+type FontMetrics struct {
+	Ascent  float64 // height above the baseline
+	Descent float64 // depth below the baseline, positive
+	LineGap float64 // recommended extra space between lines
+}
+
+// BaseFontMetrics are the metrics of the font used for layout. The default
+// matches Noto Sans, Fyne's bundled theme font; the browser replaces it with
+// the metrics read from the active theme font.
+var BaseFontMetrics = FontMetrics{Ascent: 1.069, Descent: 0.293}
+
+// NormalLineHeight returns the used value of `line-height: normal` for
+// fontSize: the font's ascent + descent + line gap (CSS2 §10.8.1).
+func NormalLineHeight(fontSize float64) float64 {
+	m := BaseFontMetrics
+	return (m.Ascent + m.Descent + m.LineGap) * fontSize
+}
+
+// HalfLeading returns the space above the glyph content area of a line box
+// of lineHeight, so leading is split evenly above and below the text
+// (CSS2 §10.8.1). It is negative when lineHeight is smaller than the content.
+func HalfLeading(fontSize, lineHeight float64) float64 {
+	m := BaseFontMetrics
+	return (lineHeight - (m.Ascent+m.Descent)*fontSize) / 2
+}
+
+// MeasureText returns the width of text in the default font.
 func MeasureText(text string, fontSize float64) float64 {
-	if TextMeasurer != nil {
-		return TextMeasurer(text, fontSize, false, false)
+	return MeasureFontText(text, fontSize, Font{})
+}
+
+// MeasureFontText returns the width of text in font.
+// Uses TextMeasurer if set, caching its results, otherwise estimates.
+// Soft hyphens take no room.
+func MeasureFontText(text string, fontSize float64, font Font) float64 {
+	text = removeSoftHyphens(text)
+	if measure := TextMeasurer; measure != nil {
+		return cachedMeasure(text, fontSize, font, func() float64 {
+			return measure(text, fontSize, font)
+		})
 	}
-	// Fallback: rough estimation
+	// Fallback: rough estimation; monospace glyphs all advance 0.6em,
+	// proportional ones average 0.5em and heavier weights set wider
 	if len(text) == 0 {
 		return 0
 	}
+	if font.Monospace {
+		return float64(ClusterCount(text)) * fontSize * 0.6
+	}
 	avgCharWidth := fontSize * 0.5
-	return float64(len(text)) * avgCharWidth
+	if font.Weight != 0 {
+		avgCharWidth *= 1 + float64(font.Weight-css.FontWeightNormal)/5000
+	}
+	return float64(ClusterCount(text)) * avgCharWidth
 }
 
 // MeasureTextWithSpacing returns text width including CSS letter-spacing.
 func MeasureTextWithSpacing(text string, fontSize, letterSpacing float64) float64 {
-	width := MeasureText(text, fontSize)
+	return addLetterSpacing(MeasureText(text, fontSize), text, letterSpacing)
+}
+
+// addLetterSpacing adds letter-spacing between the characters (grapheme
+// clusters) of text to its measured width.
+func addLetterSpacing(width float64, text string, letterSpacing float64) float64 {
 	if letterSpacing == 0 {
 		return width
 	}
-	runeCount := utf8.RuneCountInString(text)
-	if runeCount <= 1 {
+	clusters := ClusterCount(text)
+	if clusters <= 1 {
 		return width
 	}
-	width += letterSpacing * float64(runeCount-1)
+	width += letterSpacing * float64(clusters-1)
 	if width < 0 {
 		return 0
 	}
@@ -63,28 +125,47 @@ func MeasureTextWithWordSpacing(text string, fontSize, wordSpacing float64) floa
 }
 
 // WrapText breaks text into lines that fit within maxWidth.
-// Returns slice of lines. Words are not broken mid-word.
+// Returns slice of lines. Words only break after hyphens and slashes, and
+// CJK text between characters.
 func WrapText(text string, fontSize float64, maxWidth float64) []string {
 	return WrapTextWithSpacing(text, fontSize, maxWidth, 0, 0)
 }
 
 // WrapTextWithSpacing breaks text into lines that fit maxWidth using letter-spacing and word-spacing.
 func WrapTextWithSpacing(text string, fontSize, maxWidth, letterSpacing, wordSpacing float64) []string {
-	return WrapTextWithIndent(text, fontSize, maxWidth, maxWidth, letterSpacing, wordSpacing)
+	return WrapTextWithIndent(text, fontSize, Font{}, maxWidth, maxWidth, letterSpacing, wordSpacing)
 }
 
-// WrapTextWithIndent wraps text like WrapTextWithSpacing, but uses firstLineMaxWidth
-// for the first line (to support text-indent) and maxWidth for subsequent lines.
-func WrapTextWithIndent(text string, fontSize, maxWidth, firstLineMaxWidth, letterSpacing, wordSpacing float64) []string {
+// WrapTextWithIndent wraps text in font like WrapTextWithSpacing, but uses
+// firstLineMaxWidth for the first line (to support text-indent) and maxWidth
+// for subsequent lines.
+func WrapTextWithIndent(text string, fontSize float64, font Font, maxWidth, firstLineMaxWidth, letterSpacing, wordSpacing float64) []string {
 	if maxWidth <= 0 {
 		return []string{text}
 	}
+	key := newTextKey(text, fontSize, font)
+	key.lineWidth, key.firstLineWidth = maxWidth, firstLineMaxWidth
+	key.letterSpacing, key.wordSpacing = letterSpacing, wordSpacing
+	return cachedWrap(key, func() []string {
+		return WrapTextToWidths(text, fontSize, font, func(line int) float64 {
+			if line == 0 {
+				return firstLineMaxWidth
+			}
+			return maxWidth
+		}, letterSpacing, wordSpacing)
+	})
+}
 
+// WrapTextToWidths wraps text in font with the width available to each
+// line given by lineWidth, e.g. for lines shortened by floats. Lines break
+// at the opportunities lineSegments finds; a line broken at a soft hyphen
+// ends in a hyphen, and the other soft hyphens are dropped.
+func WrapTextToWidths(text string, fontSize float64, font Font, lineWidth func(line int) float64, letterSpacing, wordSpacing float64) []string {
 	startsWithSpace := strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")
 	endsWithSpace := strings.HasSuffix(text, " ") || strings.HasSuffix(text, "\t")
 
-	words := strings.Fields(text)
-	if len(words) == 0 {
+	segments := lineSegments(text)
+	if len(segments) == 0 {
 		// Whitespace-only text nodes should still render a space.
 		if strings.TrimSpace(text) == "" && strings.ContainsAny(text, " \t") {
 			return []string{" "}
@@ -95,33 +176,29 @@ func WrapTextWithIndent(text string, fontSize, maxWidth, firstLineMaxWidth, lett
 	var lines []string
 	var currentLine strings.Builder
 
-	for _, word := range words {
-		// First line uses reduced width for text-indent
-		effectiveMax := maxWidth
-		if len(lines) == 0 {
-			effectiveMax = firstLineMaxWidth
-		}
+	for _, segment := range segments {
+		effectiveMax := lineWidth(len(lines))
 
-		// Try adding word to current line
+		// Try adding the segment to current line
 		testLine := currentLine.String()
-		if testLine != "" {
+		if testLine != "" && segment.space {
 			testLine += " "
 		}
-		testLine += word
+		testLine += segment.text
 
-		lineWidth := MeasureTextWithSpacingAndWordSpacing(testLine, fontSize, letterSpacing, wordSpacing)
+		testWidth := MeasureStyledText(endLine(testLine), fontSize, font, letterSpacing, wordSpacing)
 
-		if lineWidth <= effectiveMax || currentLine.Len() == 0 {
-			// Word fits, or it's the first word (must include even if too long)
-			if currentLine.Len() > 0 {
+		if testWidth <= effectiveMax || currentLine.Len() == 0 {
+			// Segment fits, or it's the first one (must include even if too long)
+			if currentLine.Len() > 0 && segment.space {
 				currentLine.WriteString(" ")
 			}
-			currentLine.WriteString(word)
+			currentLine.WriteString(segment.text)
 		} else {
-			// Word doesn't fit, start new line
+			// Segment doesn't fit, start new line
 			lines = append(lines, currentLine.String())
 			currentLine.Reset()
-			currentLine.WriteString(word)
+			currentLine.WriteString(segment.text)
 		}
 	}
 
@@ -130,6 +207,14 @@ func WrapTextWithIndent(text string, fontSize, maxWidth, firstLineMaxWidth, lett
 		lines = append(lines, currentLine.String())
 	}
 
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			lines[i] = endLine(line)
+		} else {
+			lines[i] = removeSoftHyphens(line)
+		}
+	}
+
 	if len(lines) > 0 {
 		if startsWithSpace {
 			lines[0] = " " + lines[0]
@@ -142,8 +227,49 @@ func WrapTextWithIndent(text string, fontSize, maxWidth, firstLineMaxWidth, lett
 	return lines
 }
 
+// isBreakableSpace reports whether text may wrap at r. U+00A0 is a
+// non-breaking space, so it is not one.
+func isBreakableSpace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\f':
+		return true
+	}
+	return false
+}
+
+// collapseTextStart drops the leading space of text placed after lineBoxes
+// when it starts a line or follows content that already ends in a space
+// (CSS Text §4.1.2).
+func collapseTextStart(text string, lineBoxes []*LayoutBox) string {
+	if len(lineBoxes) == 0 || endsWithSpace(lineBoxes[len(lineBoxes)-1]) {
+		return strings.TrimLeft(text, " ")
+	}
+	return text
+}
+
+// endsWithSpace reports whether the last text inside box ends in a
+// collapsible space.
+func endsWithSpace(box *LayoutBox) bool {
+	switch box.Type {
+	case TextBox:
+		return strings.HasSuffix(box.Text, " ")
+	case InlineBox:
+		if len(box.Children) > 0 {
+			return endsWithSpace(box.Children[len(box.Children)-1])
+		}
+	}
+	return false
+}
+
 func MeasureTextWithSpacingAndWordSpacing(text string, fontSize, letterSpacing, wordSpacing float64) float64 {
-	width := MeasureTextWithSpacing(text, fontSize, letterSpacing)
+	return MeasureStyledText(text, fontSize, Font{}, letterSpacing, wordSpacing)
+}
+
+// MeasureStyledText returns the width of text in font including CSS
+// letter-spacing and word-spacing.
+func MeasureStyledText(text string, fontSize float64, font Font, letterSpacing, wordSpacing float64) float64 {
+	text = removeSoftHyphens(text)
+	width := addLetterSpacing(MeasureFontText(text, fontSize, font), text, letterSpacing)
 	if wordSpacing == 0 {
 		return width
 	}
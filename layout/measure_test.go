@@ -27,6 +27,7 @@ func TestMeasureText(t *testing.T) {
 			{"space counts as character", " ", 16, 8},
 			{"text with spaces", "a b", 16, 24},    // 3 * 16 * 0.5 = 24
 			{"longer text", "Hello World", 16, 88}, // 11 * 16 * 0.5 = 88
+			{"non-breaking space is one character", "a\u00a0b", 16, 24},
 			{"zero font size", "hello", 0, 0},
 			{"small font size", "ab", 10, 10},   // 2 * 10 * 0.5 = 10
 			{"large font size", "ab", 100, 100}, // 2 * 100 * 0.5 = 100
@@ -45,15 +46,13 @@ func TestMeasureText(t *testing.T) {
 		var calledWith struct {
 			text     string
 			fontSize float64
-			bold     bool
-			italic   bool
+			font     Font
 		}
 
-		TextMeasurer = func(text string, fontSize float64, bold, italic bool) float64 {
+		TextMeasurer = func(text string, fontSize float64, font Font) float64 {
 			calledWith.text = text
 			calledWith.fontSize = fontSize
-			calledWith.bold = bold
-			calledWith.italic = italic
+			calledWith.font = font
 			return 999.0 // Return a distinctive value
 		}
 
@@ -62,13 +61,12 @@ func TestMeasureText(t *testing.T) {
 		assert.Equal(t, 999.0, result, "should return custom measurer result")
 		assert.Equal(t, "test", calledWith.text, "should pass text to measurer")
 		assert.Equal(t, 20.0, calledWith.fontSize, "should pass fontSize to measurer")
-		assert.False(t, calledWith.bold, "should pass bold=false")
-		assert.False(t, calledWith.italic, "should pass italic=false")
+		assert.Equal(t, Font{}, calledWith.font, "should pass the default font")
 	})
 
 	t.Run("custom measurer receives empty string", func(t *testing.T) {
 		var receivedText string
-		TextMeasurer = func(text string, fontSize float64, bold, italic bool) float64 {
+		TextMeasurer = func(text string, fontSize float64, font Font) float64 {
 			receivedText = text
 			return 0
 		}
@@ -78,7 +76,7 @@ func TestMeasureText(t *testing.T) {
 	})
 
 	t.Run("custom measurer overrides default behavior", func(t *testing.T) {
-		TextMeasurer = func(text string, fontSize float64, bold, italic bool) float64 {
+		TextMeasurer = func(text string, fontSize float64, font Font) float64 {
 			// Custom measurer that returns different value than default
 			return float64(len(text)) * 10.0 // Different multiplier
 		}
@@ -160,6 +158,7 @@ func TestWrapText(t *testing.T) {
 		{"negative maxWidth no wrap", "hello world", 16, -10, 1, "hello world"},
 		{"exact fit one line", "ab", 16, 16, 1, "ab"},
 		{"just over wraps", "abc de", 16, 24, 2, "abc"},
+		{"non-breaking space does not wrap", "abc\u00a0de", 16, 24, 1, "abc\u00a0de"},
 	}
 
 	for _, tt := range tests {
@@ -185,3 +184,161 @@ func TestWrapTextWithSpacing(t *testing.T) {
 		assert.Equal(t, []string{"ab", "cd"}, lines)
 	})
 }
+
+func TestWrapTextToWidths(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	// Each word is 2 * 16 * 0.5 = 16px, "ab cd" is 40px: the first two
+	// lines are too narrow for a pair of words, later ones are not
+	widths := func(line int) float64 {
+		if line < 2 {
+			return 30
+		}
+		return 100
+	}
+	lines := WrapTextToWidths("ab cd ef gh ij", 16, Font{}, widths, 0, 0)
+	assert.Equal(t, []string{"ab", "cd", "ef gh ij"}, lines)
+}
+
+func TestWrapTextBreakOpportunities(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	// 8px per character at 16px
+	tests := []struct {
+		name     string
+		text     string
+		width    float64
+		expected []string
+	}{
+		{"CJK breaks between characters", "日本語のテキスト", 32, []string{"日本語の", "テキスト"}},
+		{"CJK keeps closing punctuation", "東京です。", 32, []string{"東京で", "す。"}},
+		{"hyphenated words", "state-of-the-art", 64, []string{"state-", "of-the-", "art"}},
+		{"hyphen joins the next word without a space", "a well-known fact", 80, []string{"a well-", "known fact"}},
+		{"no-break space", "x 10\u00a0km", 40, []string{"x", "10\u00a0km"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, WrapText(tt.text, 16, tt.width))
+		})
+	}
+}
+
+func TestNormalLineHeight(t *testing.T) {
+	defer func(m FontMetrics) { BaseFontMetrics = m }(BaseFontMetrics)
+
+	tests := []struct {
+		name     string
+		metrics  FontMetrics
+		fontSize float64
+		expected float64
+	}{
+		{"ascent plus descent", FontMetrics{Ascent: 0.8, Descent: 0.2}, 16, 16},
+		{"line gap is included", FontMetrics{Ascent: 0.8, Descent: 0.2, LineGap: 0.15}, 20, 23},
+		{"scales with font size", FontMetrics{Ascent: 0.9, Descent: 0.3}, 32, 38.4},
+		{"zero font size", FontMetrics{Ascent: 0.9, Descent: 0.3}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			BaseFontMetrics = tt.metrics
+			assert.InDelta(t, tt.expected, NormalLineHeight(tt.fontSize), 1e-9)
+		})
+	}
+}
+
+func TestHalfLeading(t *testing.T) {
+	defer func(m FontMetrics) { BaseFontMetrics = m }(BaseFontMetrics)
+	BaseFontMetrics = FontMetrics{Ascent: 0.8, Descent: 0.2, LineGap: 0.25}
+
+	tests := []struct {
+		name       string
+		fontSize   float64
+		lineHeight float64
+		expected   float64
+	}{
+		{"normal line height splits the line gap", 16, NormalLineHeight(16), 2},
+		{"larger line height", 16, 24, 4},
+		{"line height equal to content area", 16, 16, 0},
+		{"line height smaller than content area", 20, 10, -5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, HalfLeading(tt.fontSize, tt.lineHeight), 1e-9)
+		})
+	}
+}
+
+func TestMeasureFontText(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	t.Run("estimate widens with weight", func(t *testing.T) {
+		TextMeasurer = nil
+		regular := MeasureFontText("weight", 16, Font{Weight: 400})
+		assert.Equal(t, MeasureText("weight", 16), regular)
+		assert.Less(t, MeasureFontText("weight", 16, Font{Weight: 300}), regular)
+		assert.Greater(t, MeasureFontText("weight", 16, Font{Weight: 500}), regular)
+		assert.Greater(t, MeasureFontText("weight", 16, Font{Weight: 600}), MeasureFontText("weight", 16, Font{Weight: 500}))
+	})
+
+	t.Run("monospace estimate uses a fixed advance", func(t *testing.T) {
+		TextMeasurer = nil
+		mono := Font{Monospace: true}
+		assert.InDelta(t, 6*16*0.6, MeasureFontText("iiiiii", 16, mono), 1e-9)
+		assert.Equal(t, MeasureFontText("mmmmmm", 16, mono), MeasureFontText("iiiiii", 16, Font{Monospace: true, Weight: 700}))
+	})
+
+	t.Run("measurer receives the font", func(t *testing.T) {
+		var received Font
+		TextMeasurer = func(text string, fontSize float64, font Font) float64 {
+			received = font
+			return float64(font.Weight)
+		}
+		font := Font{Families: []string{"Inter", "sans-serif"}, Weight: 600, Italic: true}
+		assert.Equal(t, 604.0, MeasureStyledText("ab", 16, font, 4, 0))
+		assert.Equal(t, font, received)
+	})
+}
+
+func TestTextMeasuredInElementFont(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	defer func() { TextMeasurer = originalMeasurer }()
+	TextMeasurer = func(text string, fontSize float64, font Font) float64 {
+		perChar := 8.0
+		if font.Weight >= 600 {
+			perChar = 10
+		}
+		return float64(len(text)) * perChar
+	}
+
+	tree := buildTreeWithCSS(`<p><span class="semi">abcd</span> <b>abcd</b> <i class="light">abcd</i></p>`, `p { font-family: Inter } .semi { font-weight: 600 } .light { font-weight: 300 }`)
+	ComputeLayout(tree, 600)
+
+	assert.Equal(t, 40.0, findBoxByTag(tree, "span").Rect.Width, "semibold text measures in the heavier face")
+	assert.Equal(t, 40.0, findBoxByTag(tree, "b").Rect.Width)
+	light := findBoxByTag(tree, "i")
+	assert.Equal(t, 32.0, light.Rect.Width)
+	assert.Equal(t, []string{"Inter"}, light.Style.FontFamily, "font-family is inherited for measuring")
+}
+
+func TestPreformattedTextMeasuredMonospace(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	defer func() { TextMeasurer = originalMeasurer }()
+	TextMeasurer = func(text string, fontSize float64, font Font) float64 {
+		if font.Monospace {
+			return float64(len(text)) * 10
+		}
+		return float64(len(text)) * 8
+	}
+
+	tree := buildTreeWithCSS(`<pre>abc</pre><p><code>abcd</code></p>`, `code { font-family: Menlo, monospace }`)
+	ComputeLayout(tree, 600)
+
+	assert.Equal(t, 30.0, findBoxByType(findBoxByTag(tree, "pre"), TextBox).Rect.Width)
+	assert.Equal(t, 40.0, findBoxByTag(tree, "code").Rect.Width, "monospace families measure in the monospace face")
+}
@@ -0,0 +1,68 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"browser/dom"
+)
+
+// Sizes of media elements without width and height (HTML §15.4.1): video,
+// canvas and embedded content is 300x150, audio a bar of controls.
+const (
+	DefaultMediaWidth  = 300.0
+	DefaultMediaHeight = 150.0
+	DefaultAudioHeight = 54.0
+)
+
+// isMediaElement reports whether node is drawn as a media box: <video>,
+// <audio>, <canvas>, <embed>, <object> with data, and inline <svg>. An
+// <object> without data shows its fallback content instead.
+func isMediaElement(node *dom.Node) bool {
+	switch node.TagName {
+	case dom.TagVideo, dom.TagAudio, dom.TagCanvas, dom.TagEmbed, dom.TagSvg:
+		return true
+	case dom.TagObject:
+		return node.Attributes["data"] != ""
+	}
+	return false
+}
+
+// defaultReplacedSize returns the size of an image or media box neither
+// its attributes nor CSS size, and whether that size is also the aspect
+// ratio a single given dimension scales by. Images take their natural
+// size once loaded and have no ratio until then; audio controls keep
+// their height whatever their width, and an inline SVG takes the ratio of
+// its viewBox.
+func defaultReplacedSize(box *LayoutBox) (width, height float64, ratio bool) {
+	if box.naturalWidth > 0 && box.naturalHeight > 0 {
+		return float64(box.naturalWidth), float64(box.naturalHeight), true
+	}
+	if box.Type != MediaBox || box.Node == nil {
+		return DefaultImageWidth, DefaultImageHeight, false
+	}
+	switch box.Node.TagName {
+	case dom.TagAudio:
+		return DefaultMediaWidth, DefaultAudioHeight, false
+	case dom.TagSvg:
+		if w, h, ok := viewBoxSize(box.Node.Attributes["viewBox"]); ok {
+			return DefaultMediaWidth, DefaultMediaWidth * h / w, true
+		}
+	}
+	return DefaultMediaWidth, DefaultMediaHeight, true
+}
+
+// viewBoxSize returns the width and height of an SVG viewBox attribute,
+// "min-x min-y width height", when both are positive.
+func viewBoxSize(viewBox string) (width, height float64, ok bool) {
+	fields := strings.Fields(strings.ReplaceAll(viewBox, ",", " "))
+	if len(fields) != 4 {
+		return 0, 0, false
+	}
+	width, errW := strconv.ParseFloat(fields[2], 64)
+	height, errH := strconv.ParseFloat(fields[3], 64)
+	if errW != nil || errH != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
@@ -0,0 +1,70 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaBoxes(t *testing.T) {
+	tests := []struct {
+		name          string
+		html          string
+		tag           string
+		width, height float64
+	}{
+		{"video defaults to 300x150", `<p><video src="a.mp4"></video></p>`, "video", 300, 150},
+		{"video keeps its default ratio", `<p><video width="400"></video></p>`, "video", 400, 200},
+		{"video attributes", `<p><video width="640" height="360"></video></p>`, "video", 640, 360},
+		{"video css size", `<p><video style="width: 320px" width="640" height="360"></video></p>`, "video", 320, 180},
+		{"audio controls", `<p><audio controls src="a.mp3"></audio></p>`, "audio", 300, 54},
+		{"audio keeps its height", `<p><audio controls style="width: 500px"></audio></p>`, "audio", 500, 54},
+		{"embed", `<p><embed src="a.swf" width="200" height="100"></p>`, "embed", 200, 100},
+		{"object with data", `<p><object data="a.pdf"></object></p>`, "object", 300, 150},
+		{"svg attributes", `<p><svg width="24" height="24" viewBox="0 0 48 48"></svg></p>`, "svg", 24, 24},
+		{"svg takes its viewBox ratio", `<p><svg viewBox="0 0 30 60"></svg></p>`, "svg", 300, 600},
+		{"svg css width", `<p><svg style="width: 48px" viewBox="0 0 24 12"></svg></p>`, "svg", 48, 24},
+		{"canvas defaults to 300x150", `<p><canvas></canvas></p>`, "canvas", 300, 150},
+		{"canvas attributes", `<p><canvas width="64" height="32">Fallback</canvas></p>`, "canvas", 64, 32},
+		{"svg without viewBox", `<p><svg><circle r="5"/></svg></p>`, "svg", 300, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTree(tt.html)
+			ComputeLayout(tree, 800)
+			box := findBoxByTag(tree, tt.tag)
+			if assert.NotNil(t, box) {
+				assert.Equal(t, MediaBox, box.Type)
+				assert.Equal(t, tt.width, box.Rect.Width)
+				assert.Equal(t, tt.height, box.Rect.Height)
+				assert.Empty(t, box.Children)
+			}
+		})
+	}
+
+	t.Run("fallback content is not rendered", func(t *testing.T) {
+		tree := buildTree(`<video><source src="a.webm"><p>Your browser does not support video</p></video>`)
+		assert.Nil(t, findBoxByTag(tree, "p"))
+	})
+
+	t.Run("object without data shows its fallback", func(t *testing.T) {
+		tree := buildTree(`<object><p>Fallback</p></object>`)
+		assert.NotNil(t, findBoxByTag(tree, "p"))
+		assert.NotEqual(t, MediaBox, findBoxByTag(tree, "object").Type)
+	})
+
+	t.Run("audio without controls is not rendered", func(t *testing.T) {
+		assert.Nil(t, findBoxByTag(buildTree(`<p><audio src="a.mp3"></audio></p>`), "audio"))
+	})
+
+	t.Run("media sits on the line", func(t *testing.T) {
+		tree := buildTree(`<p>Watch <video width="100" height="50"></video> now</p>`)
+		ComputeLayout(tree, 800)
+		video := findBoxByTag(tree, "video")
+		before := findTextBoxInSubtree(findBoxByTag(tree, "p"), "Watch ")
+		if assert.NotNil(t, before) {
+			assert.Equal(t, before.Rect.X+before.Rect.Width, video.Rect.X)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package layout
+
+import (
+	"math"
+
+	"browser/css"
+)
+
+// isMulticolContainer reports whether box lays its content out in columns
+// (CSS Multi-column Layout §2): a block with a column count or width.
+func isMulticolContainer(box *LayoutBox) bool {
+	return box.Type == BlockBox && !isFlexContainer(box) && (box.Style.ColumnCount > 0 || box.Style.ColumnWidth > 0)
+}
+
+// columnLayout returns how many columns of what width fit in width, and the
+// gap between them (CSS Multi-column Layout §3.4). column-width is a
+// minimum and column-count a maximum when both are given.
+func columnLayout(s css.Style, width float64) (count int, columnWidth, gap float64) {
+	gap = s.ColumnGap
+	if !s.ColumnGapSet {
+		gap = s.FontSize
+		if gap <= 0 {
+			gap = css.DefaultFontSize
+		}
+	}
+	count = s.ColumnCount
+	if s.ColumnWidth > 0 {
+		fit := max(int(math.Floor((width+gap)/(s.ColumnWidth+gap))), 1)
+		if count == 0 || fit < count {
+			count = fit
+		}
+	}
+	columnWidth = max((width-float64(count-1)*gap)/float64(count), 0)
+	return count, columnWidth, gap
+}
+
+// contentStyle is the style the content of a multicol container or table
+// cell is laid out with in a block of its own: the container's text
+// properties, without its size, spacing, borders and overflow.
+func contentStyle(s css.Style) css.Style {
+	s.Display, s.Position, s.Float, s.Clear = "block", "", "", ""
+	s.Width, s.WidthPercent, s.WidthFitContent, s.MinWidth, s.MaxWidth = 0, 0, false, 0, 0
+	s.WidthMath, s.MinWidthMath, s.MaxWidthMath = nil, nil, nil
+	s.Height, s.MinHeight, s.MaxHeight, s.AspectRatio = 0, 0, 0, 0
+	s.MarginTop, s.MarginRight, s.MarginBottom, s.MarginLeft = 0, 0, 0, 0
+	s.MarginTopAuto, s.MarginRightAuto, s.MarginBottomAuto, s.MarginLeftAuto = false, false, false, false
+	s.PaddingTop, s.PaddingRight, s.PaddingBottom, s.PaddingLeft = 0, 0, 0, 0
+	s.BorderTopWidth, s.BorderRightWidth, s.BorderBottomWidth, s.BorderLeftWidth = 0, 0, 0, 0
+	s.Overflow, s.OverflowX, s.OverflowY = "", "", ""
+	s.ColumnCount, s.ColumnWidth = 0, 0
+	return s
+}
+
+// computeMulticolLayout lays children out in balanced columns filling
+// width from (x, y) and returns the height of the tallest column. The
+// content is laid out as one column first, then split between boxes no
+// other box straddles, so blocks and runs of inline content each stay
+// whole in one column. Margins at the top of a column are dropped.
+func computeMulticolLayout(box *LayoutBox, children []*LayoutBox, x, y, width float64, parentTag string, viewportWidth float64) float64 {
+	count, columnWidth, gap := columnLayout(box.Style, width)
+
+	column := &LayoutBox{Type: BlockBox, Parent: box, Style: contentStyle(box.Style), Children: children}
+	computeBlockLayout(column, blockLayoutParams{
+		containerWidth: columnWidth,
+		startX:         x,
+		startY:         y,
+		parentTag:      parentTag,
+		viewportWidth:  viewportWidth,
+	})
+
+	var flow, floated []*LayoutBox
+	for _, child := range column.Children {
+		if isFloated(child) {
+			floated = append(floated, child)
+		} else {
+			flow = append(flow, child)
+		}
+	}
+	if count == 1 || len(flow) == 0 {
+		return column.Rect.Height
+	}
+
+	// Group the boxes into runs a column may not break inside
+	type run struct {
+		boxes       []*LayoutBox
+		top, bottom float64 // border edges of the run's boxes
+	}
+	var runs []run
+	for _, child := range flow {
+		top := child.Rect.Y + child.Margin.Top
+		bottom := max(child.Rect.Y+child.Rect.Height-child.Margin.Bottom, top)
+		if n := len(runs); n > 0 && top < runs[n-1].bottom {
+			runs[n-1].boxes = append(runs[n-1].boxes, child)
+			runs[n-1].bottom = max(runs[n-1].bottom, bottom)
+			continue
+		}
+		runs = append(runs, run{boxes: []*LayoutBox{child}, top: top, bottom: bottom})
+	}
+
+	// columnStarts fills columns no taller than height, unless a run is
+	// taller on its own, and returns the index of each column's first run
+	columnStarts := func(height float64) []int {
+		starts := []int{0}
+		for i := 1; i < len(runs); i++ {
+			if runs[i].bottom-runs[starts[len(starts)-1]].top > height {
+				starts = append(starts, i)
+			}
+		}
+		return starts
+	}
+
+	// Balance: find the shortest height that fits in count columns
+	total := runs[len(runs)-1].bottom - runs[0].top
+	lo, hi := total/float64(count), total
+	for range 32 {
+		mid := (lo + hi) / 2
+		if len(columnStarts(mid)) <= count {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	starts := columnStarts(hi)
+
+	// Move each run into its column, and floats along with the run they
+	// were placed beside
+	tallest := 0.0
+	columnOf := make([]int, len(runs))
+	for c, first := range starts {
+		last := len(runs)
+		if c+1 < len(starts) {
+			last = starts[c+1]
+		}
+		dx := float64(c) * (columnWidth + gap)
+		dy := y - runs[first].top
+		bottom := runs[first].top
+		for i := first; i < last; i++ {
+			columnOf[i] = c
+			for _, child := range runs[i].boxes {
+				offsetBox(child, dx, dy)
+			}
+			bottom = max(bottom, runs[i].bottom)
+		}
+		tallest = max(tallest, bottom-runs[first].top)
+	}
+	for _, f := range floated {
+		i := 0
+		for i+1 < len(runs) && runs[i+1].top <= f.Rect.Y {
+			i++
+		}
+		c := columnOf[i]
+		offsetBox(f, float64(c)*(columnWidth+gap), y-runs[starts[c]].top)
+	}
+	return tallest
+}
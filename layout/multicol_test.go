@@ -0,0 +1,66 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulticolLayout(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	t.Run("blocks are balanced into columns", func(t *testing.T) {
+		tree := buildTreeWithCSS(`<div><section>1</section><section>2</section><section>3</section><section>4</section></div><p>After</p>`,
+			`div { columns: 2; column-gap: 20px; width: 420px; } section { height: 50px; }`)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		var xs, ys []float64
+		for _, child := range div.Children {
+			xs = append(xs, child.Rect.X-div.Rect.X)
+			ys = append(ys, child.Rect.Y-div.Rect.Y)
+			assert.Equal(t, 200.0, child.Rect.Width)
+		}
+		assert.Equal(t, []float64{0, 0, 220, 220}, xs)
+		assert.Equal(t, []float64{0, 50, 0, 50}, ys)
+		assert.Equal(t, 100.0, div.Rect.Height)
+		assert.Equal(t, div.Rect.Y+100, findBoxByTag(tree, "p").Rect.Y, "content after the columns follows them")
+	})
+
+	t.Run("uneven blocks keep the tallest column short", func(t *testing.T) {
+		tree := buildTreeWithCSS(`<div><section style="height: 90px">1</section><section>2</section><section>3</section></div>`,
+			`div { column-count: 2; } section { height: 40px; }`)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		assert.Equal(t, div.Children[1].Rect.Y, div.Children[2].Rect.Y-40, "second and third share a column")
+		assert.Greater(t, div.Children[1].Rect.X, div.Children[0].Rect.X)
+		assert.Equal(t, 90.0, div.Rect.Height)
+	})
+
+	t.Run("column-width sets how many columns fit", func(t *testing.T) {
+		tree := buildTreeWithCSS(`<div><section>1</section><section>2</section><section>3</section><section>4</section></div>`,
+			`div { column-width: 150px; column-gap: 10px; width: 640px; } section { height: 10px; }`)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		for i, child := range div.Children {
+			assert.Equal(t, 152.5, child.Rect.Width)
+			assert.Equal(t, float64(i)*162.5, child.Rect.X-div.Rect.X)
+			assert.Equal(t, div.Rect.Y, child.Rect.Y)
+		}
+	})
+
+	t.Run("inline content stays in one column", func(t *testing.T) {
+		tree := buildTreeWithCSS(`<div>Some <b>bold</b> text</div>`, `div { columns: 3; }`)
+		ComputeLayout(tree, 800)
+
+		div := findBoxByTag(tree, "div")
+		for _, child := range div.Children {
+			assert.Equal(t, div.Rect.Y, child.Rect.Y)
+			assert.Less(t, child.Rect.X+child.Rect.Width, div.Rect.X+div.Rect.Width/3)
+		}
+	})
+}
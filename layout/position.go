@@ -0,0 +1,171 @@
+package layout
+
+// absoluteBox is an absolutely positioned box waiting for its containing
+// block to finish layout, together with the box it sits in for the static
+// position.
+type absoluteBox struct {
+	box    *LayoutBox
+	parent *LayoutBox
+}
+
+// isPositioned reports whether a box is positioned (CSS 2.1 §9.3.1).
+func isPositioned(box *LayoutBox) bool {
+	switch box.Position {
+	case "relative", "absolute", "fixed", "sticky":
+		return true
+	}
+	return false
+}
+
+// establishesContainingBlock reports whether absolute descendants of box
+// resolve against it: positioned block-level boxes laid out by
+// computeBlockLayout, and the root, which stands in for the initial
+// containing block.
+func establishesContainingBlock(box *LayoutBox) bool {
+	if box.Parent == nil {
+		return true
+	}
+	if !isPositioned(box) || box.IsInline() {
+		return false
+	}
+	switch box.Type {
+	case TableBox, TableRowBox, TableCellBox, TableCaptionBox:
+		return false
+	}
+	return true
+}
+
+// containingBlockFor returns the box an absolutely positioned child of
+// parent is placed against (CSS 2.1 §10.1): the nearest positioned
+// ancestor, or the root for fixed boxes and when there is none.
+func containingBlockFor(parent, child *LayoutBox) *LayoutBox {
+	cb := parent
+	for cb.Parent != nil && (child.Position == "fixed" || !establishesContainingBlock(cb)) {
+		cb = cb.Parent
+	}
+	return cb
+}
+
+// deferAbsolute queues child for layout when cb finishes. A box laid out
+// more than once (floats and flex items are measured first) is queued once.
+func deferAbsolute(cb, parent, child *LayoutBox) {
+	for i := range cb.absolutes {
+		if cb.absolutes[i].box == child {
+			cb.absolutes[i].parent = parent
+			return
+		}
+	}
+	cb.absolutes = append(cb.absolutes, absoluteBox{box: child, parent: parent})
+}
+
+// layoutAbsolutes lays out the absolutely positioned boxes whose containing
+// block is cb, now that its size is known. Boxes without offsets stay at
+// their static position, the top-left of the box they were declared in.
+// Fixed boxes are placed in viewport coordinates, which match the page's
+// only while it is scrolled to the top; painting and hit testing keep
+// them there as it scrolls.
+func layoutAbsolutes(cb *LayoutBox, viewportWidth float64) {
+	// Laying out one box can queue fixed descendants on the root
+	for i := 0; i < len(cb.absolutes); i++ {
+		child, parent := cb.absolutes[i].box, cb.absolutes[i].parent
+
+		containingX := cb.Rect.X
+		containingY := cb.Rect.Y
+		containingWidth := cb.Rect.Width
+		containingHeight := cb.Rect.Height
+		staticX, staticY := parent.Rect.X, parent.Rect.Y
+		if child.Position == "fixed" {
+			containingX = 0
+			containingY = 0
+			containingWidth = viewportWidth
+			if cb.viewportHeight > 0 {
+				containingHeight = cb.viewportHeight
+			}
+		}
+
+		// Without a width the box fills the space between left and right
+		// when both are set, and otherwise, or with width: fit-content,
+		// shrinks to fit its content
+		childWidth := resolveWidth(child.Style, containingWidth)
+		if childWidth <= 0 {
+			available := containingWidth
+			if child.Style.LeftSet {
+				available -= child.Left
+			}
+			if child.Style.RightSet {
+				available -= child.Right
+			}
+			childWidth = max(available, 0)
+			if !child.Style.LeftSet || !child.Style.RightSet || child.Style.WidthFitContent {
+				childWidth = shrinkToFitWidth(child, childWidth)
+			}
+		}
+
+		// First, compute layout to determine child dimensions
+		computeBlockLayout(child, blockLayoutParams{
+			containerWidth: childWidth,
+			startX:         0,
+			startY:         0,
+			parentTag:      "",
+			viewportWidth:  viewportWidth,
+		})
+
+		childX := staticX
+		if child.Style.LeftSet {
+			childX = containingX + child.Left
+		} else if child.Style.RightSet {
+			childX = containingX + containingWidth - child.Right - child.Rect.Width
+		}
+
+		childY := staticY
+		if child.Style.TopSet {
+			childY = containingY + child.Top
+		} else if child.Style.BottomSet {
+			childY = containingY + containingHeight - child.Bottom - child.Rect.Height
+		}
+
+		// Auto margins share the space left between both offsets, centring
+		// the box (CSS 2.1 §10.3.7, §10.6.4)
+		s := child.Style
+		if s.LeftSet && s.RightSet && s.MarginLeftAuto && s.MarginRightAuto {
+			childX += max(containingWidth-child.Left-child.Right-child.Rect.Width, 0) / 2
+		}
+		if s.TopSet && s.BottomSet && s.MarginTopAuto && s.MarginBottomAuto {
+			childY += max(containingHeight-child.Top-child.Bottom-child.Rect.Height, 0) / 2
+		}
+
+		// Apply final position by offsetting the entire subtree
+		offsetBox(child, childX, childY)
+	}
+	cb.absolutes = nil
+}
+
+// relativeOffset returns how far position: relative shifts a box from its
+// place in normal flow (CSS 2.1 §9.4.3). left wins over right and top over
+// bottom; a lone right or bottom shifts the other way.
+func relativeOffset(box *LayoutBox) (dx, dy float64) {
+	if box.Position != "relative" {
+		return 0, 0
+	}
+	if box.Style.LeftSet {
+		dx = box.Left
+	} else if box.Style.RightSet {
+		dx = -box.Right
+	}
+	if box.Style.TopSet {
+		dy = box.Top
+	} else if box.Style.BottomSet {
+		dy = -box.Bottom
+	}
+	return dx, dy
+}
+
+// applyRelativeOffsets shifts relatively positioned boxes after the flow
+// around them is final, so their neighbours keep the space they left.
+func applyRelativeOffsets(children []*LayoutBox) {
+	for _, child := range children {
+		if dx, dy := relativeOffset(child); dx != 0 || dy != 0 {
+			offsetBox(child, dx, dy)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package layout
+
+import (
+	"testing"
+
+	"browser/css"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelativePositioning(t *testing.T) {
+	tests := []struct {
+		name   string
+		css    string
+		dx, dy float64 // shift of section from its static place
+	}{
+		{"top and left", `section { position: relative; top: 10px; left: 20px; }`, 20, 10},
+		{"bottom and right shift the other way", `section { position: relative; bottom: 10px; right: 20px; }`, -20, -10},
+		{"left wins over right", `section { position: relative; left: 5px; right: 50px; }`, 5, 0},
+		{"static ignores offsets", `section { top: 10px; left: 20px; }`, 0, 0},
+	}
+
+	html := `<div><p>before</p><section>moved</section><article>after</article></div>`
+	static := buildTreeWithCSS(html, "")
+	ComputeLayout(static, 800)
+	staticSection := findBoxByTag(static, "section").Rect
+	staticArticle := findBoxByTag(static, "article").Rect
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(html, tt.css)
+			ComputeLayout(tree, 800)
+
+			section := findBoxByTag(tree, "section")
+			assert.Equal(t, staticSection.X+tt.dx, section.Rect.X)
+			assert.Equal(t, staticSection.Y+tt.dy, section.Rect.Y)
+			assert.Equal(t, staticSection.Width, section.Rect.Width)
+
+			// Following content keeps its place
+			assert.Equal(t, staticArticle, findBoxByTag(tree, "article").Rect)
+
+			// Descendants move with the box
+			text := section.Children[0]
+			assert.Equal(t, section.Rect.Y, text.Rect.Y)
+		})
+	}
+}
+
+func TestRelativeInlinePositioning(t *testing.T) {
+	html := `<p>a <span>b</span> c</p>`
+	static := buildTreeWithCSS(html, "")
+	ComputeLayout(static, 800)
+	tree := buildTreeWithCSS(html, `span { position: relative; top: 4px; left: 3px; }`)
+	ComputeLayout(tree, 800)
+
+	want := findBoxByTag(static, "span").Rect
+	want.X += 3
+	want.Y += 4
+	assert.Equal(t, want, findBoxByTag(tree, "span").Rect)
+}
+
+func TestAbsoluteContainingBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		x, y float64
+	}{
+		{
+			name: "nearest relative ancestor",
+			css:  `div { position: relative; padding-top: 100px; } aside { position: absolute; top: 10px; left: 20px; width: 50px; }`,
+			x:    8 + 20, y: 8 + 10,
+		},
+		{
+			name: "initial containing block without a positioned ancestor",
+			css:  `div { padding-top: 100px; } aside { position: absolute; top: 10px; left: 20px; width: 50px; }`,
+			x:    20, y: 10,
+		},
+		{
+			name: "right and bottom against the relative ancestor",
+			css:  `div { position: relative; width: 300px; height: 200px; } aside { position: absolute; right: 10px; bottom: 20px; width: 50px; height: 30px; }`,
+			x:    8 + 300 - 10 - 50, y: 8 + 200 - 20 - 30,
+		},
+		{
+			name: "shifted with a relative ancestor",
+			css:  `div { position: relative; top: 40px; left: 30px; } aside { position: absolute; top: 0; left: 0; width: 50px; }`,
+			x:    8 + 30, y: 8 + 40,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := buildTreeWithCSS(`<div><section><aside>x</aside></section></div>`, tt.css)
+			ComputeLayout(tree, 800)
+
+			aside := findBoxByTag(tree, "aside")
+			assert.Equal(t, tt.x, aside.Rect.X)
+			assert.Equal(t, tt.y, aside.Rect.Y)
+			assert.Equal(t, 50.0, aside.Rect.Width)
+
+			// The box stays in its parent for painting and hit testing
+			assert.Equal(t, "section", aside.Parent.Node.TagName)
+			assert.Contains(t, aside.Parent.Children, aside)
+		})
+	}
+}
+
+func TestAbsoluteStaticPosition(t *testing.T) {
+	tree := buildTreeWithCSS(`<div><p>text</p><section><aside>x</aside></section></div>`,
+		`div { position: relative; } aside { position: absolute; width: 50px; }`)
+	ComputeLayout(tree, 800)
+
+	section := findBoxByTag(tree, "section")
+	aside := findBoxByTag(tree, "aside")
+	assert.Equal(t, section.Rect.X, aside.Rect.X)
+	assert.Equal(t, section.Rect.Y, aside.Rect.Y)
+}
+
+func TestFixedPosition(t *testing.T) {
+	layoutPage := func(html, cssText string) *LayoutBox {
+		tree := BuildLayoutTree(parseHTML(html), createStylesheet(cssText), Viewport{Width: 800, Height: 600}, css.MatchContext{})
+		ComputeLayout(tree, 800)
+		return tree
+	}
+
+	t.Run("placed against the viewport", func(t *testing.T) {
+		tree := layoutPage(`<div style="height: 2000px"></div><nav>menu</nav>`, `nav { position: fixed; bottom: 0; right: 0; width: 100px; height: 40px; }`)
+		nav := findBoxByTag(tree, "nav")
+		assert.Equal(t, Rect{X: 700, Y: 560, Width: 100, Height: 40}, nav.Rect)
+	})
+
+	t.Run("reserves no space", func(t *testing.T) {
+		with := layoutPage(`<p>before</p><nav>menu</nav><article>after</article>`, `nav { position: fixed; top: 0; height: 40px; }`)
+		without := layoutPage(`<p>before</p><article>after</article>`, "")
+		assert.Equal(t, findBoxByTag(without, "article").Rect, findBoxByTag(with, "article").Rect)
+	})
+
+	t.Run("static position", func(t *testing.T) {
+		tree := layoutPage(`<p>text</p><section><nav>menu</nav></section>`, `nav { position: fixed; width: 50px; }`)
+		section := findBoxByTag(tree, "section")
+		nav := findBoxByTag(tree, "nav")
+		assert.Equal(t, section.Rect.X, nav.Rect.X)
+		assert.Equal(t, section.Rect.Y, nav.Rect.Y)
+	})
+
+	t.Run("ancestors moving later do not move it", func(t *testing.T) {
+		tree := layoutPage(`<main><section><nav>menu</nav></section></main>`,
+			`main { display: flex; justify-content: center; } section { position: relative; top: 30px; width: 200px; } nav { position: fixed; top: 10px; left: 10px; }`)
+		nav := findBoxByTag(tree, "nav")
+		assert.Equal(t, 10.0, nav.Rect.X)
+		assert.Equal(t, 10.0, nav.Rect.Y)
+	})
+}
@@ -0,0 +1,89 @@
+package layout
+
+import (
+	"time"
+
+	"browser/utils"
+)
+
+var boxTypeNames = [...]string{
+	BlockBox:        "block",
+	InlineBox:       "inline",
+	TextBox:         "text",
+	ImageBox:        "image",
+	HRBox:           "hr",
+	BRBox:           "br",
+	TableBox:        "table",
+	TableRowBox:     "table-row",
+	TableCellBox:    "table-cell",
+	TableCaptionBox: "table-caption",
+	InputBox:        "input",
+	ButtonBox:       "button",
+	TextareaBox:     "textarea",
+	SelectBox:       "select",
+	RadioBox:        "radio",
+	CheckboxBox:     "checkbox",
+	FileInputBox:    "file-input",
+	FieldsetBox:     "fieldset",
+	LegendBox:       "legend",
+	MediaBox:        "media",
+}
+
+func (t BoxType) String() string {
+	if t < 0 || int(t) >= len(boxTypeNames) {
+		return "unknown"
+	}
+	return boxTypeNames[t]
+}
+
+// CountBoxes returns how many boxes of each type are in the tree under
+// root, root included.
+func CountBoxes(root *LayoutBox) map[string]int {
+	counts := make(map[string]int)
+	var walk func(box *LayoutBox)
+	walk = func(box *LayoutBox) {
+		counts[box.Type.String()]++
+		for _, child := range box.Children {
+			walk(child)
+		}
+	}
+	if root != nil {
+		walk(root)
+	}
+	return counts
+}
+
+// boxCount returns the number of boxes in the tree under root.
+func boxCount(root *LayoutBox) int {
+	if root == nil {
+		return 0
+	}
+	n := 1
+	for _, child := range root.Children {
+		n += boxCount(child)
+	}
+	return n
+}
+
+// recordBoxes records a run of phase over the tree under root that began
+// at start.
+func recordBoxes(phase utils.Phase, root *LayoutBox, start time.Time) {
+	if !start.IsZero() {
+		utils.Profile.Record(phase, start, boxCount(root))
+	}
+}
+
+// recordLayout records a layout of the tree under root that began at
+// start, and the boxes it placed.
+func recordLayout(root *LayoutBox, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	counts := CountBoxes(root)
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	utils.Profile.Record(utils.PhaseLayout, start, total)
+	utils.Profile.SetBoxCounts(counts)
+}
@@ -0,0 +1,43 @@
+package layout
+
+import (
+	"testing"
+
+	"browser/css"
+	"browser/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountBoxes(t *testing.T) {
+	tree := buildTree(`<div><p>Hello <b>world</b></p><hr><img src="a.png"></div>`)
+	counts := CountBoxes(tree)
+	assert.Equal(t, 1, counts["hr"])
+	assert.Equal(t, 1, counts["image"])
+	assert.Equal(t, 2, counts["text"])
+	assert.Equal(t, 1, counts["inline"])
+	assert.Empty(t, CountBoxes(nil))
+	assert.Equal(t, "table-cell", TableCellBox.String())
+}
+
+func TestProfileLayout(t *testing.T) {
+	utils.Profile.Reset()
+	utils.Profile.SetEnabled(true)
+	t.Cleanup(func() {
+		utils.Profile.SetEnabled(false)
+		utils.Profile.Reset()
+	})
+
+	root := parseHTML(`<div><p>Hello</p><p>world</p></div>`)
+	tree := BuildLayoutTree(root, emptyStylesheet(), Viewport{Width: 800, Height: 600}, css.MatchContext{})
+	ComputeLayout(tree, 800)
+
+	report := utils.Profile.Report()
+	boxes := boxCount(tree)
+	for _, phase := range []utils.Phase{utils.PhaseStyle, utils.PhaseLayout} {
+		assert.Equal(t, 1, report.Phase(phase).Runs, phase.String())
+		assert.Equal(t, boxes, report.Phase(phase).Count, phase.String())
+	}
+	assert.Equal(t, CountBoxes(tree), report.Boxes)
+	assert.Equal(t, 2, report.Boxes["text"])
+}
@@ -0,0 +1,200 @@
+package layout
+
+import (
+	"strings"
+
+	"browser/css"
+	"browser/dom"
+	"browser/utils"
+)
+
+// blockLayout is what computeBlockLayout laid a box out with and the rect
+// it produced, before the box's parent shifted it (e.g. position: relative).
+type blockLayout struct {
+	params blockLayoutParams
+	rect   Rect
+}
+
+// Relayout updates tree, laid out by ComputeLayout, after the DOM under
+// node changed. It rebuilds and lays out again only the nearest enclosing
+// block whose size and margins come out the same, so every box outside it
+// keeps its place. It reports false, leaving tree untouched, when the
+// change may move boxes elsewhere and the whole tree must be laid out again.
+func Relayout(tree *LayoutBox, node *dom.Node, stylesheet css.Stylesheet, viewport Viewport, ctx css.MatchContext) bool {
+	if node == nil || node.Parent == nil {
+		return false
+	}
+
+	// Sibling combinators restyle the changed node's siblings too, so
+	// restyling starts at its parent
+	var box *LayoutBox
+	for n := node.Parent; n != nil && box == nil; n = n.Parent {
+		box = findBoxForNode(tree, n)
+	}
+	if box == nil {
+		return false
+	}
+
+	// Only blocks whose own size ignores their content can be relaid alone:
+	// every box from the candidate up must be a block in normal flow
+	var candidates []*LayoutBox
+	for b := box; b.Parent != nil; b = b.Parent {
+		if !inNormalBlockFlow(b) || b.laidOut == nil {
+			candidates = nil
+			continue
+		}
+		// Anonymous blocks have no node to be rebuilt from
+		if b.Node != nil {
+			candidates = append(candidates, b)
+		}
+	}
+
+	for _, b := range candidates {
+		fresh, ok := relayoutBlock(b, stylesheet, viewport, ctx)
+		if !ok {
+			return false
+		}
+		if fresh == nil {
+			continue
+		}
+		replaceChild(b.Parent, b, fresh)
+		for a := fresh.Parent; a != nil; a = a.Parent {
+			computeScrollSize(a)
+		}
+		return true
+	}
+	return false
+}
+
+// ImageNeedsReflow reports whether box is an image whose natural size
+// changed since the box was built, usually because the image has loaded
+// or failed to since, and would be sized differently now: neither CSS nor
+// its width and height attributes fix both of its dimensions.
+func ImageNeedsReflow(box *LayoutBox) bool {
+	if box.Type != ImageBox || box.Node == nil {
+		return false
+	}
+	if box.naturalWidth == box.Node.NaturalWidth && box.naturalHeight == box.Node.NaturalHeight && box.brokenImage == imageBroken(box.Node) {
+		return false
+	}
+	_, attrW := box.Node.Attributes["width"]
+	_, attrH := box.Node.Attributes["height"]
+	width := box.Style.Width > 0 || box.Style.WidthPercent > 0 || attrW
+	height := box.Style.Height > 0 || attrH
+	ratio := box.Style.AspectRatio > 0 || attrW && attrH
+	return !(width && height) && !((width || height) && ratio)
+}
+
+// relayoutBlock rebuilds box from its DOM node and lays the new box out
+// where box was laid out. It returns nil when the new box differs in size
+// or margins, so its parent must be laid out again instead, and reports
+// false when nothing short of a full layout is correct.
+func relayoutBlock(box *LayoutBox, stylesheet css.Stylesheet, viewport Viewport, ctx css.MatchContext) (*LayoutBox, bool) {
+	p := box.laidOut.params
+	if p.floats == nil {
+		return nil, false
+	}
+	// Floats beside the box shape its lines; the context does not record
+	// which of them are
+	formattingContext := establishesFormattingContext(box)
+	if len(p.floats.floats) > 0 && !formattingContext {
+		return nil, false
+	}
+
+	start := utils.Profile.Start()
+	fresh := buildBox(box.Node, box.Parent, stylesheet, viewport, ctx, &counterState{})
+	recordBoxes(utils.PhaseStyle, fresh, start)
+	if fresh == nil || !inNormalBlockFlow(fresh) || establishesFormattingContext(fresh) != formattingContext {
+		return nil, true
+	}
+	// Counters carry on past the subtree, and absolute boxes are laid out
+	// by containing blocks outside it
+	if usesCounters(box) || usesCounters(fresh) || hasOutOfFlow(box) || hasOutOfFlow(fresh) {
+		return nil, false
+	}
+
+	floats := &floatContext{}
+	p.floats = floats
+	start = utils.Profile.Start()
+	computeBlockLayout(fresh, p)
+	recordBoxes(utils.PhaseLayout, fresh, start)
+	if len(floats.floats) > 0 && !formattingContext {
+		return nil, false
+	}
+
+	if fresh.Rect != box.laidOut.rect || fresh.Margin != box.Margin || fresh.Clear != box.Clear ||
+		fresh.collapsedMarginBottom != box.collapsedMarginBottom ||
+		estimatedBlockTopMargin(fresh) != estimatedBlockTopMargin(box) {
+		return nil, true
+	}
+	dx, dy := relativeOffset(box)
+	if fdx, fdy := relativeOffset(fresh); fdx != dx || fdy != dy {
+		return nil, true
+	}
+	offsetBox(fresh, box.Rect.X-box.laidOut.rect.X, box.Rect.Y-box.laidOut.rect.Y)
+	return fresh, true
+}
+
+// inNormalBlockFlow reports whether box is a block laid out by its parent's
+// block flow, sized by its container rather than by its content.
+func inNormalBlockFlow(box *LayoutBox) bool {
+	if box.Type != BlockBox || isFloated(box) || box.Position == "absolute" || box.Position == "fixed" || isFlexContainer(box) {
+		return false
+	}
+	if box.Parent != nil && isFlexContainer(box.Parent) {
+		return false
+	}
+	return box.Style.Display != "inline-block"
+}
+
+// findBoxForNode returns the box generated for node in tree, or nil.
+func findBoxForNode(tree *LayoutBox, node *dom.Node) *LayoutBox {
+	if tree.Node == node {
+		return tree
+	}
+	for _, child := range tree.Children {
+		if box := findBoxForNode(child, node); box != nil {
+			return box
+		}
+	}
+	return nil
+}
+
+// replaceChild puts fresh in old's place among parent's children.
+func replaceChild(parent, old, fresh *LayoutBox) {
+	for i, child := range parent.Children {
+		if child == old {
+			parent.Children[i] = fresh
+			return
+		}
+	}
+}
+
+// usesCounters reports whether any box in the subtree changes or shows a
+// CSS counter.
+func usesCounters(box *LayoutBox) bool {
+	s := box.Style
+	if s.CounterReset != "" || s.CounterIncrement != "" || s.CounterSet != "" || strings.Contains(s.Content, "counter") {
+		return true
+	}
+	for _, child := range box.Children {
+		if usesCounters(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOutOfFlow reports whether the subtree holds absolutely positioned or
+// fixed boxes.
+func hasOutOfFlow(box *LayoutBox) bool {
+	if box.Position == "absolute" || box.Position == "fixed" {
+		return true
+	}
+	for _, child := range box.Children {
+		if hasOutOfFlow(child) {
+			return true
+		}
+	}
+	return false
+}
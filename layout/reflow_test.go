@@ -0,0 +1,157 @@
+package layout
+
+import (
+	"testing"
+
+	"browser/css"
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// laidOutBox is what a box looks like to painting.
+type laidOutBox struct {
+	rect  Rect
+	lines []string
+}
+
+func flattenLayout(box *LayoutBox) []laidOutBox {
+	boxes := []laidOutBox{{box.Rect, box.WrappedLines}}
+	for _, child := range box.Children {
+		boxes = append(boxes, flattenLayout(child)...)
+	}
+	return boxes
+}
+
+func TestRelayout(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	setText := func(tag, text string) func(doc *dom.Node) *dom.Node {
+		return func(doc *dom.Node) *dom.Node {
+			el := dom.FindElementsByTagName(doc, tag)
+			el.Children = nil
+			el.AppendChild(dom.NewText(text))
+			return el
+		}
+	}
+
+	tests := []struct {
+		name   string
+		html   string
+		css    string
+		change func(doc *dom.Node) *dom.Node
+		ok     bool
+	}{
+		{
+			name:   "fixed height block",
+			html:   `<div><section><p>Hello</p></section><p>After</p></div>`,
+			css:    `section { height: 100px; }`,
+			change: setText("p", "Goodbye cruel world, this text wraps onto more lines than before"),
+			ok:     true,
+		},
+		{
+			name:   "text of the same height",
+			html:   `<p>Hello</p><h2>After</h2>`,
+			change: setText("p", "Howdy"),
+			ok:     true,
+		},
+		{
+			name:   "relatively positioned block",
+			html:   `<article><span>Hi</span></article><p>After</p>`,
+			css:    `article { position: relative; top: 10px; left: 5px; height: 40px; }`,
+			change: setText("span", "Hello there"),
+			ok:     true,
+		},
+		{
+			name:   "block that grows",
+			html:   `<p>Hello</p><p>After</p>`,
+			change: setText("p", "Goodbye cruel world, this text wraps onto more lines than before and then some more, and more again"),
+		},
+		{
+			name:   "beside a float",
+			html:   `<aside>Side</aside><p>Hello</p>`,
+			css:    `aside { float: left; }`,
+			change: setText("p", "Howdy"),
+		},
+		{
+			name:   "counters",
+			html:   `<p>Hello</p><p>After</p>`,
+			css:    `p { counter-increment: para; }`,
+			change: setText("p", "Howdy"),
+		},
+		{
+			name:   "absolute descendant",
+			html:   `<section><p>Hello</p><aside>Abs</aside></section>`,
+			css:    `section { position: relative; height: 50px; } aside { position: absolute; top: 0; }`,
+			change: setText("p", "Howdy"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTML(tt.html)
+			sheet := createStylesheet(tt.css)
+			tree := BuildLayoutTree(doc, sheet, Viewport{}, css.MatchContext{})
+			ComputeLayout(tree, 800)
+			before := flattenLayout(tree)
+
+			changed := tt.change(doc)
+			ok := Relayout(tree, changed, sheet, Viewport{}, css.MatchContext{})
+			assert.Equal(t, tt.ok, ok)
+			if !ok {
+				assert.Equal(t, before, flattenLayout(tree), "tree is left untouched")
+				return
+			}
+
+			full := BuildLayoutTree(doc, sheet, Viewport{}, css.MatchContext{})
+			ComputeLayout(full, 800)
+			assert.Equal(t, flattenLayout(full), flattenLayout(tree))
+		})
+	}
+}
+
+func TestImageNeedsReflow(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"no size given", `<p><img src="a.png"></p>`, true},
+		{"width only", `<p><img src="a.png" width="100"></p>`, true},
+		{"both attributes", `<p><img src="a.png" width="100" height="50"></p>`, false},
+		{"css size", `<p><img src="a.png" style="width: 100px; height: 50px"></p>`, false},
+		{"css width and attribute ratio", `<p><img src="a.png" width="100" height="50" style="width: 20px"></p>`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTML(tt.html)
+			tree := BuildLayoutTree(doc, css.Stylesheet{}, Viewport{}, css.MatchContext{})
+			img := findBoxByType(tree, ImageBox)
+			assert.False(t, ImageNeedsReflow(img), "nothing has loaded")
+
+			img.Node.NaturalWidth, img.Node.NaturalHeight = 640, 480
+			assert.Equal(t, tt.want, ImageNeedsReflow(img))
+
+			rebuilt := findBoxByType(BuildLayoutTree(doc, css.Stylesheet{}, Viewport{}, css.MatchContext{}), ImageBox)
+			assert.False(t, ImageNeedsReflow(rebuilt), "a rebuilt box has the loaded size")
+		})
+	}
+
+	t.Run("relayout sizes the loaded image", func(t *testing.T) {
+		doc := parseHTML(`<div><p><img src="a.png"></p></div><p>After</p>`)
+		tree := BuildLayoutTree(doc, css.Stylesheet{}, Viewport{}, css.MatchContext{})
+		ComputeLayout(tree, 800)
+
+		img := findBoxByType(tree, ImageBox)
+		img.Node.NaturalWidth, img.Node.NaturalHeight = 64, 48
+		if !Relayout(tree, img.Node, css.Stylesheet{}, Viewport{}, css.MatchContext{}) {
+			tree = BuildLayoutTree(doc, css.Stylesheet{}, Viewport{}, css.MatchContext{})
+			ComputeLayout(tree, 800)
+		}
+		img = findBoxByType(tree, ImageBox)
+		assert.Equal(t, 48.0, img.Rect.Height)
+	})
+}
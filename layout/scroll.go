@@ -0,0 +1,188 @@
+package layout
+
+import "browser/dom"
+
+// ScrollOffsets holds how far each scroll container has been scrolled on
+// each axis, keyed by its DOM node so offsets survive relayout, and how
+// far the page itself has been scrolled down.
+type ScrollOffsets struct {
+	X     map[*dom.Node]float64
+	Y     map[*dom.Node]float64
+	PageY float64
+}
+
+// of returns box's current scroll offset.
+func (o ScrollOffsets) of(box *LayoutBox) (x, y float64) {
+	if box.Node == nil {
+		return 0, 0
+	}
+	return o.X[box.Node], o.Y[box.Node]
+}
+
+// scrolls reports whether an overflow value lets the user scroll that axis.
+func scrolls(overflow string) bool {
+	return overflow == "auto" || overflow == "scroll"
+}
+
+// IsScrollContainer reports whether box scrolls its overflowing content,
+// that is, it has overflow auto or scroll on either axis.
+func (box *LayoutBox) IsScrollContainer() bool {
+	switch box.Type {
+	case BlockBox, TableCellBox, TableBox, FieldsetBox:
+	default:
+		return false
+	}
+	return scrolls(box.Style.EffectiveOverflowX()) || scrolls(box.Style.EffectiveOverflowY())
+}
+
+// clientSize returns the visible size of box's padding box, less the room
+// reserved for its scrollbars.
+func (box *LayoutBox) clientSize() (width, height float64) {
+	width = box.Rect.Width - box.Style.BorderLeftWidth - box.Style.BorderRightWidth
+	height = box.Rect.Height - box.Style.BorderTopWidth - box.Style.BorderBottomWidth
+	if scrolls(box.Style.EffectiveOverflowY()) {
+		width -= ScrollbarWidth
+	}
+	if scrolls(box.Style.EffectiveOverflowX()) {
+		height -= ScrollbarHeight
+	}
+	return width, height
+}
+
+// computeScrollSize records the size of a scroll container's content in
+// ScrollWidth and ScrollHeight: its padding box grown to enclose every
+// descendant, except what nested scroll containers clip away themselves.
+func computeScrollSize(box *LayoutBox) {
+	if !box.IsScrollContainer() {
+		box.ScrollWidth, box.ScrollHeight = 0, 0
+		return
+	}
+	left := box.Rect.X + box.Style.BorderLeftWidth
+	top := box.Rect.Y + box.Style.BorderTopWidth
+	width, height := box.clientSize()
+	right, bottom := left+width, top+height
+
+	var extend func(b *LayoutBox)
+	extend = func(b *LayoutBox) {
+		for _, child := range b.Children {
+			if child.Position == "fixed" {
+				continue // placed against the viewport, not box
+			}
+			right = max(right, child.Rect.X+child.Rect.Width+box.Padding.Right)
+			bottom = max(bottom, child.Rect.Y+child.Rect.Height+box.Padding.Bottom)
+			if !child.IsScrollContainer() {
+				extend(child)
+			}
+		}
+	}
+	extend(box)
+
+	box.ScrollWidth = right - left
+	box.ScrollHeight = bottom - top
+}
+
+// MaxScroll returns how far box's content can be scrolled on each axis.
+// Axes that do not scroll report 0.
+func (box *LayoutBox) MaxScroll() (x, y float64) {
+	if !box.IsScrollContainer() {
+		return 0, 0
+	}
+	width, height := box.clientSize()
+	if scrolls(box.Style.EffectiveOverflowX()) {
+		x = max(0, box.ScrollWidth-width)
+	}
+	if scrolls(box.Style.EffectiveOverflowY()) {
+		y = max(0, box.ScrollHeight-height)
+	}
+	return x, y
+}
+
+// canScroll reports whether box can move any further towards (dx, dy).
+func (box *LayoutBox) canScroll(dx, dy float64, offsets ScrollOffsets) bool {
+	maxX, maxY := box.MaxScroll()
+	x, y := offsets.of(box)
+	return (dx > 0 && x < maxX) || (dx < 0 && x > 0) ||
+		(dy > 0 && y < maxY) || (dy < 0 && y > 0)
+}
+
+// ScrollBy scrolls box by (dx, dy), clamped to its scroll range, and reports
+// whether either offset changed.
+func (o ScrollOffsets) ScrollBy(box *LayoutBox, dx, dy float64) bool {
+	if box.Node == nil {
+		return false
+	}
+	maxX, maxY := box.MaxScroll()
+	x, y := o.of(box)
+	newX := min(max(x+dx, 0), maxX)
+	newY := min(max(y+dy, 0), maxY)
+	if newX == x && newY == y {
+		return false
+	}
+	o.X[box.Node] = newX
+	o.Y[box.Node] = newY
+	return true
+}
+
+// HitTestScrolled is HitTest for a page whose scroll containers have been
+// scrolled: points inside a container are shifted by its offsets before
+// its children are tested. Called on the root, it tests fixed boxes first,
+// which stay put in the viewport however far the page has scrolled.
+func (box *LayoutBox) HitTestScrolled(x, y float64, offsets ScrollOffsets) *LayoutBox {
+	if box.Parent == nil {
+		if hit := box.hitTestFixed(x, y-offsets.PageY, offsets); hit != nil {
+			return hit
+		}
+	}
+	return box.hitTestFlow(x, y, offsets)
+}
+
+// hitTestFixed returns the box at viewport point (x, y) inside the fixed
+// boxes in box's subtree, the last in tree order on top. Their ancestors
+// need not contain the point, nor do their scroll offsets apply.
+func (box *LayoutBox) hitTestFixed(x, y float64, offsets ScrollOffsets) *LayoutBox {
+	for i := len(box.Children) - 1; i >= 0; i-- {
+		if hit := box.Children[i].hitTestFixed(x, y, offsets); hit != nil {
+			return hit
+		}
+	}
+	if box.Position == "fixed" {
+		return box.hitTestFlow(x, y, offsets)
+	}
+	return nil
+}
+
+// hitTestFlow is HitTestScrolled within box, skipping the fixed boxes
+// hitTestFixed has already tested.
+func (box *LayoutBox) hitTestFlow(x, y float64, offsets ScrollOffsets) *LayoutBox {
+	if !box.Contains(x, y) {
+		return nil
+	}
+
+	childX, childY := x, y
+	if box.IsScrollContainer() {
+		dx, dy := offsets.of(box)
+		childX += dx
+		childY += dy
+	}
+	for i := len(box.Children) - 1; i >= 0; i-- {
+		if box.Children[i].Position == "fixed" {
+			continue
+		}
+		if hit := box.Children[i].hitTestFlow(childX, childY, offsets); hit != nil {
+			return hit
+		}
+	}
+
+	return box
+}
+
+// ScrollTarget returns the innermost scroll container under (x, y) that can
+// still scroll towards (dx, dy), or nil when the page itself should scroll.
+func (box *LayoutBox) ScrollTarget(x, y, dx, dy float64, offsets ScrollOffsets) *LayoutBox {
+	for b := box.HitTestScrolled(x, y, offsets); b != nil; b = b.Parent {
+		if b.IsScrollContainer() && b.canScroll(dx, dy, offsets) {
+			return b
+		}
+	}
+	return nil
+}
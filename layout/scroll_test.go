@@ -0,0 +1,121 @@
+package layout
+
+import (
+	"browser/css"
+	"browser/dom"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scrollPage lays out a 200x100 scroller holding a 400px tall article and a
+// nested scroller, 62px tall with its horizontal scrollbar.
+func scrollPage(t *testing.T, extraCSS string) *LayoutBox {
+	t.Helper()
+	tree := buildTreeWithCSS(
+		`<div><section><article></article><aside><p></p></aside></section></div>`,
+		`section { width: 200px; height: 100px; overflow-y: auto; }
+		article { height: 400px; }
+		aside { height: 50px; overflow: scroll; }
+		p { height: 300px; }`+extraCSS)
+	ComputeLayout(tree, 800)
+	return tree
+}
+
+func newScrollOffsets() ScrollOffsets {
+	return ScrollOffsets{X: map[*dom.Node]float64{}, Y: map[*dom.Node]float64{}}
+}
+
+func TestScrollSize(t *testing.T) {
+	tree := scrollPage(t, "")
+	section := findBoxByTag(tree, "section")
+
+	assert.True(t, section.IsScrollContainer())
+	assert.False(t, findBoxByTag(tree, "article").IsScrollContainer())
+	assert.False(t, findBoxByTag(tree, "div").IsScrollContainer())
+
+	// Content is the article plus the nested scroller, not the nested
+	// scroller's own overflow
+	assert.Equal(t, 462.0, section.ScrollHeight)
+
+	maxX, maxY := section.MaxScroll()
+	assert.Equal(t, 0.0, maxX, "overflow-x is visible")
+	assert.Equal(t, 362.0, maxY)
+}
+
+func TestScrollBy(t *testing.T) {
+	tree := scrollPage(t, "")
+	section := findBoxByTag(tree, "section")
+	offsets := newScrollOffsets()
+
+	assert.True(t, offsets.ScrollBy(section, 0, 100))
+	assert.Equal(t, 100.0, offsets.Y[section.Node])
+
+	assert.True(t, offsets.ScrollBy(section, 0, 1000))
+	assert.Equal(t, 362.0, offsets.Y[section.Node], "clamped to the end")
+	assert.False(t, offsets.ScrollBy(section, 0, 10))
+
+	assert.True(t, offsets.ScrollBy(section, 0, -1000))
+	assert.Equal(t, 0.0, offsets.Y[section.Node], "clamped to the start")
+}
+
+func TestHitTestScrolled(t *testing.T) {
+	tree := scrollPage(t, "")
+	section := findBoxByTag(tree, "section")
+	aside := findBoxByTag(tree, "aside")
+	x := section.Rect.X + 10
+	y := section.Rect.Y + 10
+
+	offsets := newScrollOffsets()
+	assert.Equal(t, "article", tree.HitTestScrolled(x, y, offsets).Node.TagName)
+
+	// Scrolling the aside to the top of the viewport puts it under the point
+	offsets.Y[section.Node] = aside.Rect.Y - section.Rect.Y
+	hit := tree.HitTestScrolled(x, y, offsets)
+	assert.Equal(t, "p", hit.Node.TagName)
+}
+
+func TestScrollTarget(t *testing.T) {
+	tree := scrollPage(t, "")
+	section := findBoxByTag(tree, "section")
+	aside := findBoxByTag(tree, "aside")
+	x := section.Rect.X + 10
+	y := section.Rect.Y + 10
+
+	offsets := newScrollOffsets()
+	assert.Equal(t, section, tree.ScrollTarget(x, y, 0, 10, offsets))
+	assert.Nil(t, tree.ScrollTarget(x, y, 0, -10, offsets), "at the top the page scrolls")
+	assert.Nil(t, tree.ScrollTarget(x, section.Rect.Y+section.Rect.Height+20, 0, 10, offsets), "outside any scroller")
+
+	// With the section scrolled to its end the aside sits under the point,
+	// and the innermost scroller wins while it can still move
+	_, sectionMax := section.MaxScroll()
+	offsets.Y[section.Node] = sectionMax
+	y = aside.Rect.Y - sectionMax + 10
+	assert.Equal(t, aside, tree.ScrollTarget(x, y, 0, 10, offsets))
+
+	// At its top it cannot move up, so the wheel chains to the outer scroller
+	assert.Equal(t, section, tree.ScrollTarget(x, y, 0, -10, offsets))
+
+	// At the end of both, the page scrolls
+	_, asideMax := aside.MaxScroll()
+	offsets.Y[aside.Node] = asideMax
+	assert.Nil(t, tree.ScrollTarget(x, y, 0, 10, offsets))
+}
+
+func TestHitTestFixed(t *testing.T) {
+	doc := parseHTML(`<header><h1>Title</h1><nav><a href="#">Home</a></nav></header><main>content</main>`)
+	tree := BuildLayoutTree(doc, createStylesheet(`main { height: 2000px; } nav { position: fixed; bottom: 0; left: 0; width: 800px; height: 40px; }`),
+		Viewport{Width: 800, Height: 600}, css.MatchContext{})
+	ComputeLayout(tree, 800)
+	main := findBoxByTag(tree, "main")
+
+	// The header ends far above the bar, which still takes the point
+	offsets := newScrollOffsets()
+	assert.Equal(t, "#", tree.HitTestScrolled(10, 580, offsets).FindLink())
+
+	// Scrolled down, the bar is found where it shows in the viewport
+	offsets.PageY = 1000
+	assert.Equal(t, "#", tree.HitTestScrolled(10, 1580, offsets).FindLink())
+	assert.Equal(t, main, tree.HitTestScrolled(10, 580, offsets), "the page beneath the bar's laid out place")
+}
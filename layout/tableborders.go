@@ -0,0 +1,280 @@
+package layout
+
+import (
+	"image/color"
+
+	"browser/css"
+)
+
+// Collapsing border model (CSS 2.1 §17.6.2). Adjacent cells share one
+// border centred on the grid line between them; each shared edge is won by
+// one of the cell, row, row group and table borders that meet there. The
+// winner is written into the cell's border style and CollapsedBorders tells
+// the painter the borders straddle the cell's rect.
+
+// Sides of a box, in the order CSS lists them.
+const (
+	sideTop = iota
+	sideRight
+	sideBottom
+	sideLeft
+)
+
+// borderOrigin ranks where a border comes from when two edges tie on width
+// and style: cell beats row beats row group beats table.
+type borderOrigin int
+
+const (
+	originTable borderOrigin = iota
+	originRowGroup
+	originRow
+	originCell
+)
+
+// collapsedEdge is one candidate for a shared border.
+type collapsedEdge struct {
+	width  float64
+	style  string
+	color  color.Color
+	origin borderOrigin
+}
+
+// borderStyleRank orders border styles for conflict resolution; more
+// prominent styles win (CSS 2.1 §17.6.2.1 rule 3).
+var borderStyleRank = map[string]int{
+	"inset": 1, "groove": 2, "outset": 3, "ridge": 4,
+	"dotted": 5, "dashed": 6, "solid": 7, "double": 8,
+}
+
+// styleEdge returns the side of s as an edge candidate.
+func styleEdge(s css.Style, side int, origin borderOrigin) collapsedEdge {
+	switch side {
+	case sideTop:
+		return collapsedEdge{s.BorderTopWidth, s.BorderTopStyle, s.BorderTopColor, origin}
+	case sideRight:
+		return collapsedEdge{s.BorderRightWidth, s.BorderRightStyle, s.BorderRightColor, origin}
+	case sideBottom:
+		return collapsedEdge{s.BorderBottomWidth, s.BorderBottomStyle, s.BorderBottomColor, origin}
+	default:
+		return collapsedEdge{s.BorderLeftWidth, s.BorderLeftStyle, s.BorderLeftColor, origin}
+	}
+}
+
+// setStyleEdge writes edge into the side of s.
+func setStyleEdge(s *css.Style, side int, edge collapsedEdge) {
+	switch side {
+	case sideTop:
+		s.BorderTopWidth, s.BorderTopStyle, s.BorderTopColor = edge.width, edge.style, edge.color
+	case sideRight:
+		s.BorderRightWidth, s.BorderRightStyle, s.BorderRightColor = edge.width, edge.style, edge.color
+	case sideBottom:
+		s.BorderBottomWidth, s.BorderBottomStyle, s.BorderBottomColor = edge.width, edge.style, edge.color
+	default:
+		s.BorderLeftWidth, s.BorderLeftStyle, s.BorderLeftColor = edge.width, edge.style, edge.color
+	}
+}
+
+// winningEdge resolves the border conflict between candidates
+// (CSS 2.1 §17.6.2.1): hidden suppresses the border, none loses, then the
+// wider border, the more prominent style and the closer origin win. On a
+// full tie the earlier candidate wins, so callers list the edge further
+// left or further up first.
+func winningEdge(candidates ...collapsedEdge) collapsedEdge {
+	var best collapsedEdge
+	found := false
+	for _, c := range candidates {
+		if c.style == "hidden" {
+			return collapsedEdge{style: "hidden", origin: c.origin}
+		}
+		if c.style == "" || c.style == "none" || c.width <= 0 {
+			continue
+		}
+		if !found || beats(c, best) {
+			best, found = c, true
+		}
+	}
+	if !found {
+		return collapsedEdge{}
+	}
+	return best
+}
+
+// beats reports whether a strictly wins over b.
+func beats(a, b collapsedEdge) bool {
+	if a.width != b.width {
+		return a.width > b.width
+	}
+	if ra, rb := borderStyleRank[a.style], borderStyleRank[b.style]; ra != rb {
+		return ra > rb
+	}
+	return a.origin > b.origin
+}
+
+// collapseTableBorders resolves the shared borders of every cell in rows
+// and moves them onto the cells. Row, row group and table borders are
+// cleared, since the cells now paint them. A non-zero border attribute
+// gives cells without a border style of their own the legacy grey frame.
+func collapseTableBorders(table *LayoutBox, rows []*LayoutBox, tableBorder int) {
+	type slot struct {
+		cell   *LayoutBox
+		row    int // first row and column the cell occupies
+		col    int
+		endRow int // last row and column the cell occupies
+		endCol int
+	}
+
+	// Map every grid position to the cell covering it
+	var slots []slot
+	grid := make(map[int]map[int]*LayoutBox)
+	numCols := 0
+	for rowIdx, row := range rows {
+		colIdx := 0
+		for _, cell := range row.Children {
+			if cell.Type != TableCellBox {
+				continue
+			}
+			for grid[rowIdx] != nil && grid[rowIdx][colIdx] != nil {
+				colIdx++
+			}
+			cs := getCellColSpan(cell)
+			rs := getCellRowSpan(cell)
+			endRow := min(rowIdx+rs, len(rows)) - 1
+			for r := rowIdx; r <= endRow; r++ {
+				if grid[r] == nil {
+					grid[r] = make(map[int]*LayoutBox)
+				}
+				for c := colIdx; c < colIdx+cs; c++ {
+					grid[r][c] = cell
+				}
+			}
+			slots = append(slots, slot{cell, rowIdx, colIdx, endRow, colIdx + cs - 1})
+			colIdx += cs
+		}
+		numCols = max(numCols, colIdx)
+	}
+
+	cellEdge := func(cell *LayoutBox, side int) collapsedEdge {
+		edge := styleEdge(cell.Style, side, originCell)
+		if edge.style == "" && tableBorder > 0 {
+			edge = collapsedEdge{float64(tableBorder), "solid", color.Gray{Y: 180}, originCell}
+		}
+		return edge
+	}
+	rowEdge := func(row *LayoutBox, side int) collapsedEdge {
+		return styleEdge(row.Style, side, originRow)
+	}
+	// groupEdge is the side of the row group around rows[r], if the row is
+	// in an explicit tbody/thead/tfoot
+	groupEdge := func(r, side int) collapsedEdge {
+		if group := rows[r].Parent; group != nil && group != table && group.Type == TableBox {
+			return styleEdge(group.Style, side, originRowGroup)
+		}
+		return collapsedEdge{}
+	}
+	// groupBoundary reports whether rows r-1 and r are in different groups
+	groupBoundary := func(r int) bool {
+		return r == 0 || r == len(rows) || rows[r-1].Parent != rows[r].Parent
+	}
+	tableEdge := func(side int) collapsedEdge {
+		return styleEdge(table.Style, side, originTable)
+	}
+
+	resolved := make(map[*LayoutBox][4]collapsedEdge, len(slots))
+	for _, s := range slots {
+		var edges [4]collapsedEdge
+
+		// Top: the cell above and its row come first, being further up
+		var top []collapsedEdge
+		if s.row > 0 {
+			if above := grid[s.row-1][s.col]; above != nil {
+				top = append(top, cellEdge(above, sideBottom))
+			}
+		}
+		top = append(top, cellEdge(s.cell, sideTop))
+		if s.row > 0 {
+			top = append(top, rowEdge(rows[s.row-1], sideBottom))
+		}
+		top = append(top, rowEdge(rows[s.row], sideTop))
+		if groupBoundary(s.row) {
+			if s.row > 0 {
+				top = append(top, groupEdge(s.row-1, sideBottom))
+			}
+			top = append(top, groupEdge(s.row, sideTop))
+		}
+		if s.row == 0 {
+			top = append(top, tableEdge(sideTop))
+		}
+		edges[sideTop] = winningEdge(top...)
+
+		// Bottom
+		bottom := []collapsedEdge{cellEdge(s.cell, sideBottom)}
+		last := s.endRow == len(rows)-1
+		if !last {
+			if below := grid[s.endRow+1][s.col]; below != nil {
+				bottom = append(bottom, cellEdge(below, sideTop))
+			}
+		}
+		bottom = append(bottom, rowEdge(rows[s.endRow], sideBottom))
+		if !last {
+			bottom = append(bottom, rowEdge(rows[s.endRow+1], sideTop))
+		}
+		if groupBoundary(s.endRow + 1) {
+			bottom = append(bottom, groupEdge(s.endRow, sideBottom))
+			if !last {
+				bottom = append(bottom, groupEdge(s.endRow+1, sideTop))
+			}
+		}
+		if last {
+			bottom = append(bottom, tableEdge(sideBottom))
+		}
+		edges[sideBottom] = winningEdge(bottom...)
+
+		// Left: rows and groups only border the first and last columns
+		var left []collapsedEdge
+		if s.col > 0 {
+			if prev := grid[s.row][s.col-1]; prev != nil {
+				left = append(left, cellEdge(prev, sideRight))
+			}
+		}
+		left = append(left, cellEdge(s.cell, sideLeft))
+		if s.col == 0 {
+			left = append(left, rowEdge(rows[s.row], sideLeft), groupEdge(s.row, sideLeft), tableEdge(sideLeft))
+		}
+		edges[sideLeft] = winningEdge(left...)
+
+		// Right
+		right := []collapsedEdge{cellEdge(s.cell, sideRight)}
+		if next := grid[s.row][s.endCol+1]; next != nil {
+			right = append(right, cellEdge(next, sideLeft))
+		}
+		if s.endCol == numCols-1 {
+			right = append(right, rowEdge(rows[s.row], sideRight), groupEdge(s.row, sideRight), tableEdge(sideRight))
+		}
+		edges[sideRight] = winningEdge(right...)
+
+		resolved[s.cell] = edges
+	}
+
+	// Write back only once every edge is resolved, since neighbours read
+	// each other's specified borders
+	for cell, edges := range resolved {
+		for side, edge := range edges {
+			setStyleEdge(&cell.Style, side, edge)
+		}
+		cell.CollapsedBorders = true
+		cell.TableBorder = 0
+	}
+	for _, box := range append([]*LayoutBox{table}, rows...) {
+		clearBorders(&box.Style)
+		if group := box.Parent; box != table && group != nil && group != table && group.Type == TableBox {
+			clearBorders(&group.Style)
+		}
+	}
+}
+
+// clearBorders removes every border of s.
+func clearBorders(s *css.Style) {
+	for side := sideTop; side <= sideLeft; side++ {
+		setStyleEdge(s, side, collapsedEdge{})
+	}
+}
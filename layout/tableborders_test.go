@@ -0,0 +1,123 @@
+package layout
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWinningEdge(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	tests := []struct {
+		name       string
+		candidates []collapsedEdge
+		want       collapsedEdge
+	}{
+		{
+			name:       "hidden suppresses every other border",
+			candidates: []collapsedEdge{{5, "solid", red, originCell}, {1, "hidden", nil, originTable}},
+			want:       collapsedEdge{style: "hidden", origin: originTable},
+		},
+		{
+			name:       "none loses",
+			candidates: []collapsedEdge{{5, "none", red, originCell}, {1, "dotted", blue, originTable}},
+			want:       collapsedEdge{1, "dotted", blue, originTable},
+		},
+		{
+			name:       "wider wins",
+			candidates: []collapsedEdge{{1, "double", red, originCell}, {3, "inset", blue, originTable}},
+			want:       collapsedEdge{3, "inset", blue, originTable},
+		},
+		{
+			name:       "more prominent style wins at equal width",
+			candidates: []collapsedEdge{{2, "dashed", red, originCell}, {2, "solid", blue, originRow}},
+			want:       collapsedEdge{2, "solid", blue, originRow},
+		},
+		{
+			name:       "cell beats row beats table",
+			candidates: []collapsedEdge{{2, "solid", red, originTable}, {2, "solid", blue, originCell}, {2, "solid", red, originRow}},
+			want:       collapsedEdge{2, "solid", blue, originCell},
+		},
+		{
+			name:       "the earlier of two cells wins a full tie",
+			candidates: []collapsedEdge{{2, "solid", red, originCell}, {2, "solid", blue, originCell}},
+			want:       collapsedEdge{2, "solid", red, originCell},
+		},
+		{
+			name:       "no border at all",
+			candidates: []collapsedEdge{{}, {0, "solid", red, originCell}},
+			want:       collapsedEdge{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, winningEdge(tt.candidates...))
+		})
+	}
+}
+
+func TestCollapsedTableBorders(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	html := `<table><tbody><tr><td>A</td><td class="wide">B</td></tr><tr><td>C</td><td>D</td></tr></tbody></table>`
+	css := `table { border-collapse: collapse; border: 4px solid blue }
+		tbody { border-bottom: 6px double red }
+		td { border: 1px solid red }
+		.wide { border-left: 2px dashed blue; border-bottom-style: hidden }`
+
+	tree := buildTreeWithCSS(html, css)
+	ComputeLayout(tree, 600)
+
+	table := findBoxByTag(tree, "table")
+	tbody := findBoxByTag(tree, "tbody")
+	a, b, c, d := findCellByText(tree, "A"), findCellByText(tree, "B"), findCellByText(tree, "C"), findCellByText(tree, "D")
+
+	for _, cell := range []*LayoutBox{a, b, c, d} {
+		assert.True(t, cell.CollapsedBorders)
+	}
+	assert.Equal(t, a.Rect.X+a.Rect.Width, b.Rect.X, "no spacing between collapsed cells")
+	assert.Equal(t, a.Rect.Y+a.Rect.Height, c.Rect.Y)
+
+	// Outer edges take the wider table border
+	assert.Equal(t, 4.0, a.Style.BorderTopWidth)
+	assert.Equal(t, blue, a.Style.BorderTopColor)
+	assert.Equal(t, 4.0, a.Style.BorderLeftWidth)
+	assert.Equal(t, 4.0, b.Style.BorderRightWidth)
+
+	// The shared edge between A and B is B's wider left border, on both sides
+	assert.Equal(t, 2.0, a.Style.BorderRightWidth)
+	assert.Equal(t, "dashed", a.Style.BorderRightStyle)
+	assert.Equal(t, 2.0, b.Style.BorderLeftWidth)
+
+	// Equal borders between rows resolve to the same red line
+	assert.Equal(t, 1.0, a.Style.BorderBottomWidth)
+	assert.Equal(t, red, c.Style.BorderTopColor)
+
+	// hidden on B's bottom removes the line between B and D
+	assert.Equal(t, "hidden", b.Style.BorderBottomStyle)
+	assert.Equal(t, "hidden", d.Style.BorderTopStyle)
+	assert.Equal(t, 0.0, d.Style.BorderTopWidth)
+
+	// The row group's double border wins the table's bottom edge
+	assert.Equal(t, 6.0, c.Style.BorderBottomWidth)
+	assert.Equal(t, "double", d.Style.BorderBottomStyle)
+
+	// Table and row group borders moved onto the cells
+	assert.Equal(t, 0.0, table.Style.BorderTopWidth)
+	assert.Equal(t, 0.0, tbody.Style.BorderBottomWidth)
+}
+
+func TestCollapsedBorderAttribute(t *testing.T) {
+	tree := buildTreeWithCSS(`<table border="1"><tr><td>A</td><td style="border-left: 3px solid red">B</td></tr></table>`, "table { border-collapse: collapse }")
+	ComputeLayout(tree, 600)
+
+	a, b := findCellByText(tree, "A"), findCellByText(tree, "B")
+	assert.Equal(t, 0, a.TableBorder, "collapsed cells do not paint the legacy frame")
+	assert.Equal(t, 1.0, a.Style.BorderTopWidth)
+	assert.Equal(t, "solid", a.Style.BorderTopStyle)
+	assert.Equal(t, 3.0, a.Style.BorderRightWidth, "author borders beat the attribute frame")
+	assert.Equal(t, 3.0, b.Style.BorderLeftWidth)
+}
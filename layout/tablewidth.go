@@ -0,0 +1,155 @@
+package layout
+
+import "strconv"
+
+// Automatic table layout (CSS 2.1 §17.5.2.2). Every column has a
+// min-content width, the widest unbreakable content of its cells, and a
+// max-content width, its cells laid out without wrapping. A table gets
+// the width it asks for or its max-content width, clamped to the
+// container, and never less than its columns' min-content widths; the
+// columns share that width between their two bounds.
+
+// spanningCell is a cell spanning several columns, waiting to widen them
+// once the widths of the cells in single columns are known.
+type spanningCell struct {
+	col, span int
+	min, max  float64 // widths the spanned columns need between them
+}
+
+// tableCellPadding returns the padding of table's cells: the cellpadding
+// attribute, or 8px.
+func tableCellPadding(table *LayoutBox) float64 {
+	if table.Node != nil {
+		if p, ok := table.Node.Attributes["cellpadding"]; ok {
+			if parsed, err := strconv.Atoi(p); err == nil && parsed >= 0 {
+				return float64(parsed)
+			}
+		}
+	}
+	return 8.0
+}
+
+// cellContentWidths returns the min-content and max-content widths of the
+// content of a table cell, without its padding.
+func cellContentWidths(cell *LayoutBox) (minWidth, maxWidth float64) {
+	return flowContentWidth(cell, "td", true), flowContentWidth(cell, "td", false)
+}
+
+// spreadSpanningCell widens the columns cell spans until they hold its
+// min-content and max-content widths. The extra goes to columns without a
+// specified width (those with colWidths[i] == 0) in proportion to their
+// max-content widths, or to all of them when every column has one.
+func spreadSpanningCell(colMin, colMax, colWidths []float64, cell spanningCell) {
+	cols := make([]int, 0, cell.span)
+	for i := cell.col; i < cell.col+cell.span; i++ {
+		if colWidths[i] == 0 {
+			cols = append(cols, i)
+		}
+	}
+	if len(cols) == 0 {
+		for i := cell.col; i < cell.col+cell.span; i++ {
+			cols = append(cols, i)
+		}
+	}
+
+	spread := func(widths []float64, need float64) {
+		have, weight := 0.0, 0.0
+		for i := cell.col; i < cell.col+cell.span; i++ {
+			have += widths[i]
+		}
+		for _, i := range cols {
+			weight += colMax[i]
+		}
+		if need <= have {
+			return
+		}
+		for _, i := range cols {
+			share := 1 / float64(len(cols))
+			if weight > 0 {
+				share = colMax[i] / weight
+			}
+			widths[i] += (need - have) * share
+		}
+	}
+	spread(colMin, cell.min)
+	spread(colMax, max(cell.max, cell.min))
+	for _, i := range cols {
+		colMax[i] = max(colMax[i], colMin[i])
+	}
+}
+
+// distributeColumnWidths shares width between columns with the given
+// min-content and max-content widths. Below the sum of their max-content
+// widths, each column gets its min-content width plus the same fraction
+// of the room it would use beyond it; any width beyond that goes to the
+// columns without a specified width (colWidths[i] == 0) in proportion to
+// their max-content widths.
+func distributeColumnWidths(colMin, colMax, colWidths []float64, width float64) []float64 {
+	sumMin, sumMax := 0.0, 0.0
+	for i := range colMin {
+		sumMin += colMin[i]
+		sumMax += colMax[i]
+	}
+
+	widths := make([]float64, len(colMin))
+	switch {
+	case width <= sumMin:
+		copy(widths, colMin)
+	case width <= sumMax:
+		f := (width - sumMin) / (sumMax - sumMin)
+		for i := range widths {
+			widths[i] = colMin[i] + (colMax[i]-colMin[i])*f
+		}
+	default:
+		copy(widths, colMax)
+		var grow []int
+		weight := 0.0
+		for i, w := range colWidths {
+			if w == 0 {
+				grow = append(grow, i)
+				weight += colMax[i]
+			}
+		}
+		if len(grow) == 0 {
+			for i := range colMax {
+				grow = append(grow, i)
+				weight += colMax[i]
+			}
+		}
+		for _, i := range grow {
+			share := 1 / float64(len(grow))
+			if weight > 0 {
+				share = colMax[i] / weight
+			}
+			widths[i] += (width - sumMax) * share
+		}
+	}
+	return widths
+}
+
+// tableContentWidth estimates the min-content or max-content width of a
+// table, when wrap is set or not: its widest row of cells side by side.
+// Column spans are not taken into account.
+func tableContentWidth(table *LayoutBox, wrap bool) float64 {
+	padding := tableCellPadding(table)
+	widest := 0.0
+	var measureRows func(box *LayoutBox)
+	measureRows = func(box *LayoutBox) {
+		for _, child := range box.Children {
+			switch child.Type {
+			case TableBox:
+				measureRows(child)
+			case TableRowBox:
+				row := 0.0
+				for _, cell := range child.Children {
+					if cell.Type == TableCellBox {
+						row += max(flowContentWidth(cell, "td", wrap), cell.Style.Width) + padding*2
+					}
+				}
+				widest = max(widest, row)
+			}
+		}
+	}
+	measureRows(table)
+	return widest
+}
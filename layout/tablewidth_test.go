@@ -0,0 +1,70 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistributeColumnWidths(t *testing.T) {
+	tests := []struct {
+		name      string
+		colWidths []float64
+		width     float64
+		want      []float64
+	}{
+		{"narrower than min-content", []float64{0, 0}, 50, []float64{20, 40}},
+		{"between the bounds", []float64{0, 0}, 110, []float64{30, 80}},
+		{"max-content", []float64{0, 0}, 160, []float64{40, 120}},
+		{"wider than max-content", []float64{0, 0}, 320, []float64{80, 240}},
+		{"extra width skips specified columns", []float64{40, 0}, 200, []float64{40, 160}},
+	}
+
+	colMin, colMax := []float64{20, 40}, []float64{40, 120}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, distributeColumnWidths(colMin, colMax, tt.colWidths, tt.width))
+		})
+	}
+}
+
+func TestAutoTableLayout(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	TextMeasurer = nil
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	layoutTable := func(html string, width float64) []*LayoutBox {
+		tree := buildTree(html)
+		ComputeLayout(tree, width)
+		var cells []*LayoutBox
+		var collect func(box *LayoutBox)
+		collect = func(box *LayoutBox) {
+			if box.Type == TableCellBox {
+				cells = append(cells, box)
+			}
+			for _, child := range box.Children {
+				collect(child)
+			}
+		}
+		collect(findBoxByTag(tree, "table"))
+		return cells
+	}
+
+	t.Run("short columns keep their max-content width", func(t *testing.T) {
+		cells := layoutTable(`<table><tr><td>Name</td><td>Lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod</td></tr></table>`, 400)
+		assert.Equal(t, 4*8.0+16, cells[0].Rect.Width)
+		assert.Equal(t, 400.0-16, cells[0].Rect.Width+cells[1].Rect.Width, "wrapping table fills the body")
+	})
+
+	t.Run("narrow container keeps min-content widths", func(t *testing.T) {
+		cells := layoutTable(`<table><tr><td>Supercalifragilistic</td><td>word</td></tr></table>`, 100)
+		assert.Equal(t, 20*8.0+16, cells[0].Rect.Width)
+		assert.Equal(t, 4*8.0+16, cells[1].Rect.Width)
+	})
+
+	t.Run("spanning cell widens its columns", func(t *testing.T) {
+		cells := layoutTable(`<table><tr><td colspan="2">A much longer heading</td></tr><tr><td>a</td><td>b</td></tr></table>`, 800)
+		assert.Equal(t, 21*8.0+16, cells[0].Rect.Width)
+		assert.Equal(t, cells[0].Rect.Width, cells[1].Rect.Width+cells[2].Rect.Width)
+	})
+}
@@ -133,6 +133,9 @@ func findTableRows(table *LayoutBox) []*LayoutBox {
 			}
 		}
 	}
+	if table.Node != nil {
+		rows = rowsInTableOrder(table.Node, rows)
+	}
 	return rows
 }
 
@@ -182,3 +185,15 @@ func cellContainsText(box *LayoutBox, text string) bool {
 	}
 	return false
 }
+
+// collectText concatenates the text of all text boxes under root
+func collectText(root *LayoutBox) string {
+	var sb strings.Builder
+	if root.Type == TextBox {
+		sb.WriteString(root.Text)
+	}
+	for _, child := range root.Children {
+		sb.WriteString(collectText(child))
+	}
+	return sb.String()
+}
@@ -0,0 +1,131 @@
+package layout
+
+import (
+	"container/list"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// textCacheSize bounds the number of measurements and wrappings kept.
+const textCacheSize = 4096
+
+// textKey identifies a measurement (lineWidth and firstLineWidth zero) or a
+// wrapping of text.
+type textKey struct {
+	wrap                       bool
+	text                       string
+	fontSize                   float64
+	families                   string
+	weight                     int
+	italic, monospace          bool
+	lineWidth, firstLineWidth  float64
+	letterSpacing, wordSpacing float64
+}
+
+type textEntry struct {
+	key   textKey
+	width float64
+	lines []string
+}
+
+// textCache is an LRU cache of text widths and wrapped lines, so a reflow
+// does not measure the same strings again. Results depend on the measurer,
+// so the cache empties itself when TextMeasurer is replaced.
+type textCache struct {
+	mu       sync.Mutex
+	measurer uintptr
+	entries  map[textKey]*list.Element
+	order    *list.List // front is most recently used
+}
+
+var texts = &textCache{entries: make(map[textKey]*list.Element), order: list.New()}
+
+// InvalidateTextCache drops every cached measurement and wrapping. Call it
+// when fonts change the widths TextMeasurer returns, e.g. when a web font
+// loads or the text scale changes.
+func InvalidateTextCache() {
+	texts.mu.Lock()
+	defer texts.mu.Unlock()
+	texts.clear()
+}
+
+func (c *textCache) clear() {
+	clear(c.entries)
+	c.order.Init()
+}
+
+func newTextKey(text string, fontSize float64, font Font) textKey {
+	return textKey{
+		text:      text,
+		fontSize:  fontSize,
+		families:  strings.Join(font.Families, ","),
+		weight:    font.Weight,
+		italic:    font.Italic,
+		monospace: font.Monospace,
+	}
+}
+
+// get returns the entry for key, or nil.
+func (c *textCache) get(key textKey) *textEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m := measurerID(); m != c.measurer {
+		c.clear()
+		c.measurer = m
+	}
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*textEntry)
+}
+
+// put stores e, evicting the least recently used entry when full.
+func (c *textCache) put(e *textEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[e.key] = c.order.PushFront(e)
+	if c.order.Len() > textCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*textEntry).key)
+	}
+}
+
+// measurerID identifies the current TextMeasurer; 0 means estimation.
+func measurerID() uintptr {
+	if TextMeasurer == nil {
+		return 0
+	}
+	return reflect.ValueOf(TextMeasurer).Pointer()
+}
+
+// cachedMeasure returns the width of text in font, measuring it on a miss.
+func cachedMeasure(text string, fontSize float64, font Font, measure func() float64) float64 {
+	key := newTextKey(text, fontSize, font)
+	if e := texts.get(key); e != nil {
+		return e.width
+	}
+	width := measure()
+	texts.put(&textEntry{key: key, width: width})
+	return width
+}
+
+// cachedWrap returns the lines text wraps to, wrapping it on a miss.
+func cachedWrap(key textKey, wrap func() []string) []string {
+	key.wrap = true
+	if e := texts.get(key); e != nil {
+		return slices.Clone(e.lines)
+	}
+	lines := wrap()
+	texts.put(&textEntry{key: key, lines: slices.Clone(lines)})
+	return lines
+}
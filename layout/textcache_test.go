@@ -0,0 +1,73 @@
+package layout
+
+import (
+	"container/list"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextCache(t *testing.T) {
+	originalMeasurer := TextMeasurer
+	defer func() { TextMeasurer = originalMeasurer }()
+
+	calls := 0
+	TextMeasurer = func(text string, fontSize float64, font Font) float64 {
+		calls++
+		return float64(len(text)) * fontSize / 2
+	}
+
+	t.Run("measurements are reused", func(t *testing.T) {
+		InvalidateTextCache()
+		calls = 0
+		assert.Equal(t, 40.0, MeasureFontText("hello", 16, Font{}))
+		assert.Equal(t, 40.0, MeasureFontText("hello", 16, Font{}))
+		assert.Equal(t, 1, calls)
+
+		// A different size or face is measured separately
+		MeasureFontText("hello", 20, Font{})
+		MeasureFontText("hello", 16, Font{Families: []string{"serif"}})
+		MeasureFontText("hello", 16, Font{Weight: 700})
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("invalidation measures again", func(t *testing.T) {
+		calls = 0
+		MeasureFontText("again", 16, Font{})
+		InvalidateTextCache()
+		MeasureFontText("again", 16, Font{})
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("wrapped lines are reused per width", func(t *testing.T) {
+		InvalidateTextCache()
+		first := WrapTextWithIndent("one two three four", 16, Font{}, 80, 80, 0, 0)
+		calls = 0
+		assert.Equal(t, first, WrapTextWithIndent("one two three four", 16, Font{}, 80, 80, 0, 0))
+		assert.Equal(t, 0, calls)
+
+		assert.NotEqual(t, first, WrapTextWithIndent("one two three four", 16, Font{}, 200, 200, 0, 0))
+	})
+
+	t.Run("cached lines are not shared", func(t *testing.T) {
+		lines := WrapTextWithIndent("one two three four", 16, Font{}, 80, 80, 0, 0)
+		lines[0] = "changed"
+		assert.Equal(t, "one two", WrapTextWithIndent("one two three four", 16, Font{}, 80, 80, 0, 0)[0])
+	})
+
+	t.Run("replacing the measurer empties the cache", func(t *testing.T) {
+		MeasureFontText("swap", 16, Font{})
+		TextMeasurer = func(text string, fontSize float64, font Font) float64 { return 1 }
+		assert.Equal(t, 1.0, MeasureFontText("swap", 16, Font{}))
+	})
+}
+
+func TestTextCacheEviction(t *testing.T) {
+	c := &textCache{entries: make(map[textKey]*list.Element), order: list.New()}
+	for i := 0; i <= textCacheSize; i++ {
+		c.put(&textEntry{key: textKey{fontSize: float64(i)}})
+	}
+	assert.Equal(t, textCacheSize, c.order.Len())
+	assert.Nil(t, c.get(textKey{fontSize: 0}), "least recently used entry is evicted")
+	assert.NotNil(t, c.get(textKey{fontSize: textCacheSize}))
+}
@@ -0,0 +1,116 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"browser/dom"
+)
+
+// Bounds on the scale and layout width a page may ask for (CSS Viewport
+// Module §2.2).
+const (
+	minViewportScale = 0.1
+	maxViewportScale = 10.0
+	maxViewportWidth = 10000
+)
+
+// ViewportMeta is what a page asks of the viewport with
+// <meta name="viewport" content="...">. Zero fields were not given.
+type ViewportMeta struct {
+	Width        float64 // layout width in CSS pixels; 0 for device-width
+	InitialScale float64
+	MinimumScale float64
+	MaximumScale float64
+}
+
+// ParseViewportMeta parses the content of a viewport <meta> element:
+// key=value pairs separated by commas or semicolons. Unknown keys and
+// malformed values are ignored.
+func ParseViewportMeta(content string) ViewportMeta {
+	var m ViewportMeta
+	fields := strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == ';' })
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		if key == "width" {
+			if value == "device-width" {
+				m.Width = 0
+			} else if n, ok := parseViewportNumber(strings.TrimSuffix(value, "px")); ok {
+				m.Width = min(max(n, 1), maxViewportWidth)
+			}
+			continue
+		}
+		n, ok := parseViewportNumber(value)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "initial-scale":
+			m.InitialScale = n
+		case "minimum-scale":
+			m.MinimumScale = n
+		case "maximum-scale":
+			m.MaximumScale = n
+		}
+	}
+	return m
+}
+
+// parseViewportNumber parses a positive number.
+func parseViewportNumber(value string) (float64, bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	return n, err == nil && n > 0
+}
+
+// FindViewportMeta returns the settings of the last viewport <meta> element
+// in doc, and false when it has none.
+func FindViewportMeta(doc *dom.Node) (ViewportMeta, bool) {
+	var content string
+	found := false
+	for meta := range dom.Elements(doc, "meta") {
+		if strings.EqualFold(meta.Attributes["name"], "viewport") {
+			content, found = meta.Attributes["content"], true
+		}
+	}
+	if !found {
+		return ViewportMeta{}, false
+	}
+	return ParseViewportMeta(content), true
+}
+
+// Resolve returns the width of the initial containing block the page is
+// laid out in, and the scale it is drawn at, in a window windowWidth wide.
+// A page asking for a fixed width is scaled to fit the window unless it
+// also gives an initial scale; device-width lays out at the window width
+// divided by the scale.
+func (m ViewportMeta) Resolve(windowWidth float64) (width, scale float64) {
+	lo := minViewportScale
+	if m.MinimumScale > 0 {
+		lo = min(max(m.MinimumScale, minViewportScale), maxViewportScale)
+	}
+	hi := maxViewportScale
+	if m.MaximumScale > 0 {
+		hi = max(min(m.MaximumScale, maxViewportScale), lo)
+	}
+
+	scale = 1.0
+	switch {
+	case m.InitialScale > 0:
+		scale = m.InitialScale
+	case m.Width > 0:
+		scale = windowWidth / m.Width
+	}
+	scale = min(max(scale, lo), hi)
+
+	// The page never gets less room than the window holds at its scale
+	width = windowWidth / scale
+	if m.Width > 0 {
+		width = max(width, m.Width)
+	}
+	return width, scale
+}
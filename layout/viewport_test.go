@@ -0,0 +1,48 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseViewportMeta(t *testing.T) {
+	assert.Equal(t, ViewportMeta{InitialScale: 1}, ParseViewportMeta("width=device-width, initial-scale=1"))
+	assert.Equal(t, ViewportMeta{Width: 600, MaximumScale: 2}, ParseViewportMeta("width=600px; maximum-scale=2.0"))
+	assert.Equal(t, ViewportMeta{Width: 10000}, ParseViewportMeta(" WIDTH = 50000 , user-scalable=no, initial-scale=zero"))
+	assert.Equal(t, ViewportMeta{}, ParseViewportMeta("nonsense"))
+}
+
+func TestFindViewportMeta(t *testing.T) {
+	_, ok := FindViewportMeta(parseHTML(`<head><meta charset="utf-8"></head>`))
+	assert.False(t, ok)
+
+	meta, ok := FindViewportMeta(parseHTML(`<head><meta name="viewport" content="width=320"><meta name="Viewport" content="width=480"></head>`))
+	assert.True(t, ok)
+	assert.Equal(t, 480.0, meta.Width, "the last viewport meta wins")
+}
+
+func TestViewportResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		width   float64
+		scale   float64
+	}{
+		{"device width", "width=device-width, initial-scale=1", 1000, 1},
+		{"device width zoomed", "width=device-width, initial-scale=2", 500, 2},
+		{"fixed width fits the window", "width=500", 500, 2},
+		{"fixed width narrower than the scaled window", "width=400, initial-scale=1", 1000, 1},
+		{"fixed width wider than the window", "width=2000", 2000, 0.5},
+		{"maximum scale", "width=250, maximum-scale=2", 500, 2},
+		{"minimum scale", "initial-scale=0.2, minimum-scale=0.5", 2000, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, scale := ParseViewportMeta(tt.content).Resolve(1000)
+			assert.Equal(t, tt.width, width)
+			assert.Equal(t, tt.scale, scale)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package layout
+
+import "strings"
+
+// collapsesSpaces reports whether the white-space value ws collapses runs
+// of spaces and tabs (CSS Text §3).
+func collapsesSpaces(ws string) bool {
+	return ws != "pre" && ws != "pre-wrap"
+}
+
+// preservesNewlines reports whether ws keeps line feeds as forced breaks.
+func preservesNewlines(ws string) bool {
+	return ws == "pre" || ws == "pre-wrap" || ws == "pre-line"
+}
+
+// wrapsLines reports whether ws lets text wrap at soft break opportunities.
+func wrapsLines(ws string) bool {
+	return ws != "nowrap" && ws != "pre"
+}
+
+// collapseWhiteSpace applies the first phase of white space processing
+// (CSS Text §4.1.1) to text of an element with white-space ws: unless
+// white-space preserves them, line feeds become spaces and every run of
+// spaces and tabs becomes a single space. pre-line keeps line feeds and
+// drops the spaces around them. Non-breaking spaces never collapse.
+func collapseWhiteSpace(text, ws string) string {
+	if !collapsesSpaces(ws) {
+		return text
+	}
+	if !preservesNewlines(ws) {
+		return collapseSpaceRuns(text)
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = collapseSpaceRuns(line)
+		if i > 0 {
+			line = strings.TrimPrefix(line, " ")
+		}
+		if i < len(lines)-1 {
+			line = strings.TrimSuffix(line, " ")
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collapseSpaceRuns replaces every run of collapsible space in text with a
+// single space.
+func collapseSpaceRuns(text string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range text {
+		if isBreakableSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+// collapseInlineSpaces collapses the white space of box's inline content
+// across element boundaries (CSS Text §4.1.1-4.1.2): a collapsible space is
+// removed after another one, and at the start and end of the block and of
+// each line forced by a <br>, a preserved line feed or a block child. Text
+// boxes left empty are dropped. Floats and positioned boxes do not take
+// part; images, form controls and inline-blocks separate like letters.
+func collapseInlineSpaces(box *LayoutBox) {
+	var last *LayoutBox // text whose trailing space ends a line if nothing follows
+	atLineStart := true // at a line start or after a collapsible space
+	trimEnd := func() {
+		if last != nil {
+			last.Text = strings.TrimRight(last.Text, " ")
+			last = nil
+		}
+	}
+
+	var walk func(parent *LayoutBox)
+	walk = func(parent *LayoutBox) {
+		for _, child := range parent.Children {
+			switch {
+			case isFloated(child) || child.Position == "absolute" || child.Position == "fixed":
+			case child.Type == TextBox:
+				if !collapsesSpaces(parent.Style.WhiteSpace) {
+					last, atLineStart = nil, strings.HasSuffix(child.Text, "\n")
+					continue
+				}
+				if strings.HasPrefix(child.Text, "\n") {
+					trimEnd()
+				}
+				if atLineStart {
+					child.Text = strings.TrimLeft(child.Text, " ")
+				}
+				if child.Text == "" {
+					continue
+				}
+				last = child
+				atLineStart = strings.HasSuffix(child.Text, " ") || strings.HasSuffix(child.Text, "\n")
+			case child.Type == InlineBox && child.Style.Display != "inline-block":
+				walk(child)
+			case child.Type == BRBox || isBlockLevel(child):
+				trimEnd()
+				atLineStart = true
+			default:
+				last, atLineStart = nil, false
+			}
+		}
+	}
+	walk(box)
+	trimEnd()
+	dropEmptyText(box)
+}
+
+// dropEmptyText removes the text boxes white space collapsing emptied from
+// box's inline content.
+func dropEmptyText(box *LayoutBox) {
+	kept := box.Children[:0]
+	for _, child := range box.Children {
+		if child.Type == TextBox && child.Text == "" {
+			continue
+		}
+		if child.Type == InlineBox && child.Style.Display != "inline-block" {
+			dropEmptyText(child)
+		}
+		kept = append(kept, child)
+	}
+	box.Children = kept
+}
@@ -0,0 +1,38 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapseWhiteSpace(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		ws       string
+		expected string
+	}{
+		{"empty string", "", "normal", ""},
+		{"whitespace only", "\n    \n", "normal", " "},
+		{"tabs only", "\t\t", "normal", " "},
+		{"boundary spaces kept", " hello ", "normal", " hello "},
+		{"leading tab", "\thello", "normal", " hello"},
+		{"internal spaces", "hello    world", "normal", "hello world"},
+		{"internal newline", "hello  \n  world", "normal", "hello world"},
+		{"form feed", "a\f\fb", "normal", "a b"},
+		{"nbsp kept", "a    b", "normal", "a    b"},
+		{"nowrap collapses", "a  \n b", "nowrap", "a b"},
+		{"inherited from nothing", "a  b", "", "a b"},
+		{"pre keeps everything", " a\t b\n c ", "pre", " a\t b\n c "},
+		{"pre-wrap keeps everything", " a  b\n", "pre-wrap", " a  b\n"},
+		{"pre-line keeps line feeds", " a  b \n\t c ", "pre-line", " a b\nc "},
+		{"pre-line normalizes CRLF", "a\r\nb", "pre-line", "a\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, collapseWhiteSpace(tt.input, tt.ws))
+		})
+	}
+}
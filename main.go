@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -25,6 +27,20 @@ func main() {
 
 	startURL := os.Args[1]
 
+	// Cookies, cached responses and proxy settings persist in the profile
+	// between runs
+	if dir, err := utils.DataDir(); err == nil {
+		if err := utils.Cookies.Persist(filepath.Join(dir, "cookies.json")); err != nil {
+			fmt.Println("Error loading cookies:", err)
+		}
+		if err := utils.DiskCache.Open(filepath.Join(dir, "cache"), utils.DefaultDiskCacheLimit); err != nil {
+			fmt.Println("Error opening the HTTP cache:", err)
+		}
+		if err := utils.Proxies.Persist(filepath.Join(dir, "proxy.json")); err != nil {
+			fmt.Println("Error loading proxy settings:", err)
+		}
+	}
+
 	// Create browser window
 	browser := render.NewBrowser(900, 600)
 
@@ -40,9 +56,16 @@ func main() {
 
 	// Run the GUI
 	browser.Run()
+	if err := utils.Cookies.Save(); err != nil {
+		fmt.Println("Error saving cookies:", err)
+	}
 }
 
 func loadPage(browser *render.Browser, req render.NavigationRequest) {
+	// A path to a local file or folder opens as a file: URL
+	if fileURL, ok := utils.LocalFileURL(req.URL); ok {
+		req.URL = fileURL
+	}
 	pageURL := req.URL
 	method := req.Method
 	if method == "" {
@@ -54,38 +77,61 @@ func loadPage(browser *render.Browser, req render.NavigationRequest) {
 
 	// Run fetch in background so UI stays responsive
 	go func() {
-		resp, err := utils.DoRequest(utils.HTTPRequest{
-			Method:         method,
-			URL:            pageURL,
-			Body:           req.Body,
-			ContentType:    req.ContentType,
-			FormData:       req.Data,
-			ReferrerPolicy: req.ReferrerPolicy,
-			FromURL:        browser.GetCurrentURL(),
-		})
+		// The page being left stops loading its stylesheets, scripts,
+		// fonts and images
+		utils.Subresources.BeginDocument()
+		browser.CancelRefresh()
 
+		page, err := fetchPage(browser, req)
 		if err != nil {
 			fmt.Println("Error:", err)
-			browser.ShowError("Error 404")
+			var certErr *utils.CertificateError
+			if errors.As(err, &certErr) {
+				browser.ShowCertificateError(req, certErr)
+			} else if errors.Is(err, utils.ErrTooManyRedirects) {
+				browser.ShowError("Too many redirects")
+			} else {
+				browser.ShowError("Error 404")
+			}
 			return
 		}
-		defer resp.Body.Close()
+		// The page is at the URL the request was redirected to
+		if page.url != pageURL {
+			fmt.Println("Redirected to:", page.url)
+			pageURL = page.url
+			browser.UpdateURLBar(pageURL)
+		}
 
 		fmt.Println("Parsing HTML...")
-		document := dom.Parse(resp.Body)
+		text, encoding := utils.DecodeHTML(page.body, page.contentType, req.Encoding)
+		document := dom.Parse(strings.NewReader(text))
+		// A <meta> past where the encoding was sniffed can still change it
+		if document != nil {
+			declared := dom.FindMetaCharset(document)
+			if redecoded, name, ok := utils.RedecodeHTML(page.body, page.contentType, req.Encoding, encoding, declared); ok {
+				text, encoding = redecoded, name
+				document = dom.Parse(strings.NewReader(text))
+			}
+		}
+		browser.SetDocumentEncoding(encoding, req.Encoding != "")
 		if document == nil {
 			browser.ShowError("Error 404")
 			fmt.Println("Error: failed to parse HTML")
 			return
 		}
 
+		// The page's <meta name="referrer"> overrides its Referrer-Policy
+		if policy := dom.FindMetaReferrer(document); utils.ParseReferrerPolicy(policy) != "" {
+			utils.SetDocumentReferrerPolicy(pageURL, policy)
+		}
+
 		title := dom.FindTitle(document)
 		browser.SetTitle(title)
 		browser.SetDocument(document)
 
-		fmt.Println("Fetching CSS...")
+		fmt.Println("Fetching CSS and scripts...")
 
-		// 1. Fetch external stylesheets in parallel
+		// 1. Fetch external stylesheets and scripts in parallel
 		links := dom.FindStylesheetLinks(document)
 		cssResults := make([]string, len(links))
 		var wg sync.WaitGroup
@@ -96,19 +142,36 @@ func loadPage(browser *render.Browser, req render.NavigationRequest) {
 				defer wg.Done()
 				absURL := resolveURL(pageURL, href)
 				fmt.Println("Fetching CSS:", absURL)
-				cssResp, err := http.Get(absURL)
+				cssResp, err := fetchSubresource(absURL, pageURL, "")
 				if err == nil {
 					data, _ := io.ReadAll(cssResp.Body)
 					cssResp.Body.Close()
+					text, sheetEncoding := utils.DecodeCSS(data, cssResp.Header.Get("Content-Type"), encoding)
 					// Resolve @import directives in fetched stylesheet
 					seen := map[string]bool{absURL: true}
-					cssResults[idx] = resolveCSSimports(string(data), absURL, 0, seen)
+					cssResults[idx] = resolveCSSimports(text, absURL, sheetEncoding, 0, seen)
 				} else {
 					fmt.Println("Failed to fetch CSS:", err)
 				}
 			}(i, link)
 		}
 
+		scripts := js.FindScripts(document)
+		scriptCode := make([]string, len(scripts))
+		for i, script := range scripts {
+			if script.Src == "" {
+				scriptCode[i] = script.Code
+				continue
+			}
+			wg.Add(1)
+			go func(idx int, script js.Script) {
+				defer wg.Done()
+				absURL := resolveURL(pageURL, script.Src)
+				fmt.Println("Fetching script:", absURL)
+				scriptCode[idx] = fetchScript(absURL, pageURL, script.ReferrerPolicy, encoding)
+			}(i, script)
+		}
+
 		wg.Wait()
 
 		// Combine external CSS in order
@@ -121,42 +184,56 @@ func loadPage(browser *render.Browser, req render.NavigationRequest) {
 		browser.SetExternalCSS(externalCSS.String())
 
 		// Combine external + internal <style> content (resolve @imports in inline styles)
-		fullCSS := combineCSS(externalCSS.String(), document, pageURL)
+		fullCSS := combineCSS(externalCSS.String(), document, pageURL, encoding)
 
 		fmt.Println("Building layout...")
 		stylesheet := css.Parse(fullCSS)
 		browser.SetDocument(document)
 		matchCtx := css.MatchContext{
-			IsVisited:  func(url string) bool { return browser.IsVisited(url) },
-			ResolveURL: func(href string) string { return resolveURL(pageURL, href) },
+			IsVisited:   func(url string) bool { return browser.IsVisited(url) },
+			ResolveURL:  func(href string) string { return resolveURL(pageURL, href) },
+			ColorScheme: browser.ColorScheme(),
 		}
-		layoutTree := layout.BuildLayoutTree(document, stylesheet, layout.Viewport{
-			Width:  float64(browser.Width),
-			Height: float64(browser.Height),
-		}, matchCtx)
-		layout.ComputeLayout(layoutTree, float64(browser.Width))
+		viewport := browser.LayoutViewport(browser.Width, browser.Height)
+		layoutTree := layout.BuildLayoutTree(document, stylesheet, viewport, matchCtx)
+		layout.ComputeLayout(layoutTree, viewport.Width)
 
 		// Execute JavaScript
 		fmt.Println("Executing JavaScript...")
-		jsRuntime := js.NewJSRuntime(document, func() {
-			browser.Reflow(browser.Width)
-		})
+		jsRuntime := js.NewJSRuntime(document, browser.ReflowChanged)
 
 		jsRuntime.SetAlertHandler(browser.ShowAlert)
 		jsRuntime.SetConfirmHandler(browser.ShowConfirm)
 		jsRuntime.SetPromptHandler(browser.ShowPrompt)
+		jsRuntime.SetLeaveConfirmHandler(browser.ConfirmLeave)
 		browser.SetJSClickHandler(jsRuntime.DispatchClick)
+		browser.SetJSToggleHandler(jsRuntime.DispatchToggle)
+		browser.SetJSDialogCancelHandler(jsRuntime.CancelDialog)
+		browser.SetJSActivationHandler(jsRuntime.NotifyUserActivation)
+		jsRuntime.SetOpenWindowHandler(browser.OpenPopup)
+		jsRuntime.SetPopupBlockedHandler(browser.NotifyPopupBlocked)
+		jsRuntime.SetPopupPermission(browser.PopupsAllowed)
 		browser.SetBeforeNavigateHandler(jsRuntime.CheckBeforeUnload)
+		jsRuntime.SetFrameRequestHandler(browser.RequestFrame)
+		browser.SetAnimationFrameHandler(jsRuntime.RunAnimationFrames)
+		browser.SetJSScrollHandler(jsRuntime.DispatchScroll)
+		jsRuntime.SetScrollPositionHandler(browser.ScrollPosition)
+		jsRuntime.SetScrollHandler(browser.ScrollTo)
+		jsRuntime.SetPrintHandler(browser.Print)
+		browser.SetJSPrintHandler(jsRuntime.Print)
 
 		jsRuntime.SetCurrentURL(pageURL)
 
-		scripts := js.FindScripts(document)
-		for i, script := range scripts {
+		for i, code := range scriptCode {
+			if code == "" {
+				continue
+			}
 			fmt.Printf("Running script %d...\n", i+1)
-			jsRuntime.Execute(script)
+			jsRuntime.Execute(code)
 		}
 
 		browser.SetCurrentURL(pageURL)
+		browser.LoadFavicon()
 		jsRuntime.SetReloadHandler(func() {
 			browser.Refresh()
 		})
@@ -164,41 +241,128 @@ func loadPage(browser *render.Browser, req render.NavigationRequest) {
 		jsRuntime.SetTitleChangeHandler(browser.SetTitle)
 
 		// Re-parse CSS after JavaScript (respects disabled styles)
-		fullCSS = combineCSS(externalCSS.String(), document, pageURL)
+		fullCSS = combineCSS(externalCSS.String(), document, pageURL, encoding)
 		stylesheet = css.Parse(fullCSS)
 
 		// Rebuild layout tree AFTER JavaScript has modified the DOM
-		layoutTree = layout.BuildLayoutTree(document, stylesheet, layout.Viewport{
-			Width:  float64(browser.Width),
-			Height: float64(browser.Height),
-		}, matchCtx)
-		layout.ComputeLayout(layoutTree, float64(browser.Width))
+		viewport = browser.LayoutViewport(browser.Width, browser.Height)
+		layoutTree = layout.BuildLayoutTree(document, stylesheet, viewport, matchCtx)
+		layout.ComputeLayout(layoutTree, viewport.Width)
 		browser.SetContent(layoutTree)
+		browser.LoadWebFonts(stylesheet.FontFaces)
 
 		fmt.Println("Firing load event...")
 		jsRuntime.FireLoad()
 
-		browser.AddToHistory(pageURL)
+		if req.Replace {
+			browser.ReplaceHistory(pageURL)
+		} else {
+			browser.AddToHistory(pageURL)
+		}
 		browser.MarkVisited(pageURL)
+		// Links to the URL that redirected here are visited too
+		browser.MarkVisited(req.URL)
+
+		// A Refresh header goes before any <meta http-equiv="refresh">
+		if refresh, ok := dom.ParseRefresh(page.refresh); ok {
+			browser.ScheduleRefresh(pageURL, refresh)
+		} else if refresh, ok := dom.FindMetaRefresh(document); ok {
+			browser.ScheduleRefresh(pageURL, refresh)
+		}
 
 		fmt.Println("Page loaded!")
 	}()
 }
 
+// fetchedPage is the response to a navigation.
+type fetchedPage struct {
+	url         string // after any redirects
+	body        []byte
+	contentType string
+	refresh     string // the Refresh header
+}
+
+// lastResponse keeps the current page's response so an encoding override
+// can re-decode it without fetching again.
+var lastResponse struct {
+	sync.Mutex
+	page fetchedPage
+}
+
+// fetchPage returns the response to the requested page, reusing the last
+// one for re-decode requests.
+func fetchPage(browser *render.Browser, req render.NavigationRequest) (fetchedPage, error) {
+	if req.Redecode {
+		lastResponse.Lock()
+		page := lastResponse.page
+		lastResponse.Unlock()
+		if req.URL == page.url {
+			return page, nil
+		}
+	}
+	if !render.InternalPageAllowed(req) {
+		return fetchedPage{}, fmt.Errorf("%s cannot be opened from %s", req.URL, req.FromURL)
+	}
+	if page, ok := browser.InternalPage(req.URL); ok {
+		return fetchedPage{url: req.URL, body: []byte(page), contentType: "text/html; charset=utf-8"}, nil
+	}
+
+	resp, err := utils.DoRequest(utils.HTTPRequest{
+		Method:         req.Method,
+		URL:            req.URL,
+		Body:           req.Body,
+		ContentType:    req.ContentType,
+		FormData:       req.Data,
+		ReferrerPolicy: req.ReferrerPolicy,
+		FromURL:        req.FromURL,
+		Navigation:     true,
+	})
+	if err != nil {
+		return fetchedPage{}, err
+	}
+	defer resp.Body.Close()
+	utils.SetDocumentReferrerPolicy(resp.Request.URL.String(), resp.Header.Get("Referrer-Policy"))
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fetchedPage{}, err
+	}
+	page := fetchedPage{
+		url:         resp.Request.URL.String(),
+		body:        body,
+		contentType: resp.Header.Get("Content-Type"),
+		refresh:     resp.Header.Get("Refresh"),
+	}
+	lastResponse.Lock()
+	lastResponse.page = page
+	lastResponse.Unlock()
+	return page, nil
+}
+
 // combineCSS merges external CSS with inline <style> content, resolving @imports in inline styles.
-func combineCSS(externalCSS string, document *dom.Node, pageURL string) string {
-	inlineCSS := resolveCSSimports(dom.FindActiveStyleContent(document), pageURL, 0, map[string]bool{})
+// Imported stylesheets default to the document's encoding.
+func combineCSS(externalCSS string, document *dom.Node, pageURL, encoding string) string {
+	baseURL := pageURL
+	if href := dom.FindBaseHref(document); href != "" {
+		baseURL = resolveURL(pageURL, href)
+	}
+	inlineCSS := resolveCSSimports(dom.FindActiveStyleContent(document), baseURL, encoding, 0, map[string]bool{})
 	return externalCSS + inlineCSS
 }
 
-func resolveCSSimports(cssContent, baseURL string, depth int, seen map[string]bool) string {
+// resolveCSSimports prepends the stylesheets cssContent imports, decoded
+// with encoding, that of cssContent, unless they say otherwise.
+func resolveCSSimports(cssContent, baseURL, encoding string, depth int, seen map[string]bool) string {
+	// Each sheet is marked with its URL, so its @font-face sources resolve
+	// against it once the sheets are joined
+	own := css.SheetBase(baseURL) + cssContent
 	if depth >= 5 {
-		return cssContent
+		return own
 	}
 
 	sheet := css.Parse(cssContent)
 	if len(sheet.Imports) == 0 {
-		return cssContent
+		return own
 	}
 
 	var imported strings.Builder
@@ -211,22 +375,57 @@ func resolveCSSimports(cssContent, baseURL string, depth int, seen map[string]bo
 		seen[absURL] = true
 
 		fmt.Printf("Fetching @import: %s\n", absURL)
-		resp, err := http.Get(absURL)
+		resp, err := fetchSubresource(absURL, baseURL, "")
 		if err != nil {
 			fmt.Printf("Failed to fetch @import %s: %v\n", absURL, err)
 			continue
 		}
 		data, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
+		text, importEncoding := utils.DecodeCSS(data, resp.Header.Get("Content-Type"), encoding)
 
 		// Recursively resolve nested imports
-		resolved := resolveCSSimports(string(data), absURL, depth+1, seen)
+		resolved := resolveCSSimports(text, absURL, importEncoding, depth+1, seen)
 		imported.WriteString(resolved)
 		imported.WriteString("\n")
 	}
 
 	// Imported rules prepended = lower cascade priority
-	return imported.String() + cssContent
+	return imported.String() + own
+}
+
+// fetchSubresource fetches a stylesheet or script of the page at pageURL,
+// ahead of its fonts and images, under the element's referrerPolicy.
+func fetchSubresource(absURL, pageURL, referrerPolicy string) (*http.Response, error) {
+	return utils.Subresources.Load(utils.PriorityHigh, utils.HTTPRequest{
+		Method:         "GET",
+		URL:            absURL,
+		FromURL:        pageURL,
+		ReferrerPolicy: referrerPolicy,
+	})
+}
+
+// fetchScript returns the code of the external script at absURL, decoded
+// with the document's encoding unless it says otherwise, or "" if it fails
+// to load.
+func fetchScript(absURL, pageURL, referrerPolicy, encoding string) string {
+	resp, err := fetchSubresource(absURL, pageURL, referrerPolicy)
+	if err != nil {
+		fmt.Println("Failed to fetch script:", err)
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		fmt.Println("Failed to fetch script:", resp.Status)
+		return ""
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Println("Failed to fetch script:", err)
+		return ""
+	}
+	code, _ := utils.DecodeScript(data, resp.Header.Get("Content-Type"), encoding)
+	return code
 }
 
 func resolveURL(baseURL, href string) string {
@@ -0,0 +1,194 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"browser/utils"
+)
+
+// Internal about: pages, generated by the browser rather than fetched.
+// about:blank is an empty document, the page for new windows and frames
+// with nothing to show yet; the others describe the browser itself.
+
+const (
+	BlankURL   = "about:blank"
+	VersionURL = "about:version"
+	HistoryURL = "about:history"
+	CacheURL   = "about:cache"
+)
+
+// historyLimit is how many visits about:history remembers.
+const historyLimit = 1000
+
+// cacheURLLength is how much of a URL about:cache shows.
+const cacheURLLength = 120
+
+// aboutPages returns each internal page's HTML, by name, for its URL's
+// query.
+var aboutPages = map[string]func(b *Browser, query url.Values) string{
+	"blank":   func(*Browser, url.Values) string { return blankPage },
+	"version": func(*Browser, url.Values) string { return versionPage() },
+	"history": func(b *Browser, _ url.Values) string { return b.historyPage() },
+	"cache":   func(*Browser, url.Values) string { return cachePage() },
+	"privacy": func(b *Browser, query url.Values) string {
+		message := ""
+		if query.Get("action") == "clear" {
+			if query.Get("token") == b.privacyNonce() {
+				message = clearFromQuery(query)
+			} else {
+				message = "Data can only be cleared with the controls on this page."
+			}
+		}
+		return privacyPage(message, b.privacyNonce())
+	},
+}
+
+const blankPage = "<html><head></head><body></body></html>"
+
+// InternalPage returns the HTML of a browser-provided about: page, and
+// false for any other URL. Loading about:privacy with action=clear in the
+// query clears the selected data first, but only when the query carries
+// the token the page's own controls were generated with; see privacyNonce.
+func (b *Browser) InternalPage(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "about" {
+		return "", false
+	}
+	page, ok := aboutPages[strings.ToLower(u.Opaque)]
+	if !ok {
+		return "", false
+	}
+	return page(b, u.Query()), true
+}
+
+// InternalPageAllowed reports whether req may load an about: page. Web
+// pages can only open about:blank; the others are reached from the
+// address bar, the browser's menus or another internal page.
+func InternalPageAllowed(req NavigationRequest) bool {
+	target, err := url.Parse(req.URL)
+	if err != nil || target.Scheme != "about" || strings.EqualFold(target.Opaque, "blank") {
+		return true
+	}
+	from, err := url.Parse(req.FromURL)
+	return req.FromURL == "" || (err == nil && from.Scheme == "about")
+}
+
+// privacyNonce returns this session's about:privacy token, made on first
+// use. A web page cannot read it, so it cannot forge a clearing request.
+func (b *Browser) privacyNonce() string {
+	b.privacyOnce.Do(func() { b.privacyToken = randomToken(16) })
+	return b.privacyToken
+}
+
+// visit is a page loaded this session.
+type visit struct {
+	URL   string
+	Title string
+	Time  time.Time
+}
+
+// recordVisit adds pageURL to the pages about:history lists. Internal pages
+// are left out.
+func (b *Browser) recordVisit(pageURL string) {
+	if strings.HasPrefix(pageURL, "about:") {
+		return
+	}
+	b.visits = append(b.visits, visit{URL: pageURL, Title: b.title, Time: time.Now()})
+	if len(b.visits) > historyLimit {
+		b.visits = b.visits[len(b.visits)-historyLimit:]
+	}
+}
+
+// historyPage lists the pages loaded this session, latest first.
+func (b *Browser) historyPage() string {
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>History</title></head><body>\n<h1>History</h1>\n")
+	if len(b.visits) == 0 {
+		sb.WriteString("<p>No pages have been visited yet.</p>\n")
+	} else {
+		sb.WriteString("<table cellpadding=\"4\">\n<tr><th align=\"left\">Time</th><th align=\"left\">Page</th></tr>\n")
+		for i := len(b.visits) - 1; i >= 0; i-- {
+			v := b.visits[i]
+			title := v.Title
+			if title == "" {
+				title = v.URL
+			}
+			fmt.Fprintf(&sb, "<tr><td>%s</td><td><a href=\"%s\">%s</a></td></tr>\n",
+				v.Time.Format("2006-01-02 15:04:05"), html.EscapeString(v.URL), html.EscapeString(title))
+		}
+		sb.WriteString("</table>\n")
+	}
+	sb.WriteString("</body></html>\n")
+	return sb.String()
+}
+
+// versionPage describes the build: its version, the Go toolchain and
+// platform, the modules it was built from and where the profile is kept.
+func versionPage() string {
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Version</title></head><body>\n<h1>Go Browser</h1>\n<table cellpadding=\"4\">\n")
+	row := func(name, value string) {
+		fmt.Fprintf(&sb, "<tr><th align=\"left\">%s</th><td>%s</td></tr>\n", name, html.EscapeString(value))
+	}
+	info, ok := debug.ReadBuildInfo()
+	if ok {
+		row("Version", info.Main.Version)
+	}
+	row("Go", runtime.Version())
+	row("Platform", runtime.GOOS+"/"+runtime.GOARCH)
+	if dir, err := utils.DataDir(); err == nil {
+		row("Profile", dir)
+	}
+	sb.WriteString("</table>\n")
+
+	if ok && len(info.Deps) > 0 {
+		sb.WriteString("<h2>Modules</h2>\n<table cellpadding=\"4\">\n")
+		for _, dep := range info.Deps {
+			row(html.EscapeString(dep.Path), dep.Version)
+		}
+		sb.WriteString("</table>\n")
+	}
+	sb.WriteString("</body></html>\n")
+	return sb.String()
+}
+
+// cachePage lists the responses in the disk cache and the resources in the
+// memory cache, with a link clearing both.
+func cachePage() string {
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Cache</title></head><body>\n<h1>Cache</h1>\n")
+	clear := url.Values{"action": {"clear"}, "cache": {"on"}, "range": {"all"}}
+	fmt.Fprintf(&sb, "<p><a href=\"%s?%s\">Clear cached images and files</a></p>\n", PrivacyURL, html.EscapeString(clear.Encode()))
+
+	disk := utils.DiskCache.Entries()
+	fmt.Fprintf(&sb, "<h2>Disk</h2>\n<p>%d responses, %s.</p>\n", len(disk), utils.FormatSize(utils.DiskCache.Size()))
+	writeCacheEntries(&sb, disk)
+
+	memory := utils.HTTPCache.Entries()
+	fmt.Fprintf(&sb, "<h2>Memory</h2>\n<p>%d resources.</p>\n", len(memory))
+	writeCacheEntries(&sb, memory)
+
+	sb.WriteString("</body></html>\n")
+	return sb.String()
+}
+
+func writeCacheEntries(sb *strings.Builder, entries []utils.CacheEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	sb.WriteString("<table cellpadding=\"4\">\n<tr><th align=\"left\">URL</th><th align=\"left\">Stored</th></tr>\n")
+	for _, e := range entries {
+		shown := e.URL
+		if len(shown) > cacheURLLength {
+			shown = shown[:cacheURLLength] + "…" // long data: URLs
+		}
+		fmt.Fprintf(sb, "<tr><td>%s</td><td>%s</td></tr>\n", html.EscapeString(shown), e.Stored.Format("2006-01-02 15:04"))
+	}
+	sb.WriteString("</table>\n")
+}
@@ -0,0 +1,68 @@
+package render
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"browser/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlankPage(t *testing.T) {
+	page, ok := (&Browser{}).InternalPage(BlankURL)
+	assert.True(t, ok)
+	assert.Equal(t, blankPage, page)
+}
+
+func TestHistoryPage(t *testing.T) {
+	b := &Browser{}
+	page, _ := b.InternalPage(HistoryURL)
+	assert.Contains(t, page, "No pages have been visited yet.")
+
+	b.title = "First & best"
+	b.AddToHistory("https://example.com/one")
+	b.title = ""
+	b.AddToHistory("https://example.com/two?a=1&b=2")
+	b.AddToHistory(HistoryURL)
+
+	page, _ = b.InternalPage(HistoryURL)
+	assert.Contains(t, page, `<a href="https://example.com/one">First &amp; best</a>`)
+	assert.Contains(t, page, `<a href="https://example.com/two?a=1&amp;b=2">https://example.com/two?a=1&amp;b=2</a>`)
+	assert.NotContains(t, page, `href="about:history"`, "internal pages are left out")
+	assert.Less(t, strings.Index(page, "/two"), strings.Index(page, "/one"), "latest first")
+}
+
+func TestHistoryLimit(t *testing.T) {
+	b := &Browser{}
+	for range historyLimit + 5 {
+		b.AddToHistory("https://example.com/")
+	}
+	assert.Len(t, b.visits, historyLimit)
+}
+
+func TestVersionPage(t *testing.T) {
+	profile := t.TempDir()
+	t.Setenv("BROWSER_PROFILE", profile)
+	page, ok := (&Browser{}).InternalPage(VersionURL)
+	assert.True(t, ok)
+	assert.Contains(t, page, runtime.Version())
+	assert.Contains(t, page, runtime.GOOS+"/"+runtime.GOARCH)
+	assert.Contains(t, page, profile)
+}
+
+func TestCachePage(t *testing.T) {
+	url := "https://example.com/about-cache-test.png"
+	utils.HTTPCache.Put(url, "cached")
+	long := "data:text/plain," + strings.Repeat("x", 2*cacheURLLength)
+	utils.HTTPCache.Put(long, "cached")
+	defer utils.HTTPCache.Clear("", time.Time{})
+
+	page, ok := (&Browser{}).InternalPage(CacheURL)
+	assert.True(t, ok)
+	assert.Contains(t, page, "<td>"+url+"</td>")
+	assert.Contains(t, page, "<td>"+long[:cacheURLLength]+"…</td>")
+	assert.Contains(t, page, `href="about:privacy?action=clear&amp;cache=on&amp;range=all"`)
+}
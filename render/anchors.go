@@ -0,0 +1,111 @@
+package render
+
+import (
+	"browser/dom"
+	"net/url"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Size of the copy-link button shown beside hovered headings
+const anchorButtonSize = 24
+
+// GoToSection scrolls to the element with id and reflects the section in
+// the URL bar and the history, like following an in-page #fragment link.
+func (b *Browser) GoToSection(id string) bool {
+	if !b.scrollToID(id) {
+		return false
+	}
+	if b.currentURL != nil {
+		u := sectionURL(b.currentURL, id)
+		if u != b.currentURL.String() {
+			b.AddToHistory(u)
+		}
+		b.currentURL.Fragment = id
+		b.currentURL.RawFragment = ""
+		b.UpdateURLBar(u)
+	}
+	return true
+}
+
+// CopySectionLink copies the URL of heading's section to the clipboard.
+func (b *Browser) CopySectionLink(heading *dom.Node) {
+	id := heading.Attributes["id"]
+	if id == "" || b.currentURL == nil {
+		return
+	}
+	b.Window.Clipboard().SetContent(sectionURL(b.currentURL, id))
+	b.showToast("Link to section copied")
+}
+
+// sectionURL returns page with its fragment replaced by id.
+func sectionURL(page *url.URL, id string) string {
+	u := *page
+	u.Fragment = id
+	u.RawFragment = ""
+	return u.String()
+}
+
+// isCurrentDocument reports whether u points into the loaded page, so a
+// fragment can be followed without navigating.
+func (b *Browser) isCurrentDocument(u *url.URL) bool {
+	if b.currentURL == nil {
+		return true
+	}
+	return u.Scheme == b.currentURL.Scheme && u.Host == b.currentURL.Host &&
+		u.Path == b.currentURL.Path && u.RawQuery == b.currentURL.RawQuery
+}
+
+// anchorHeadingFor returns the heading whose copy-link button should show
+// while node is hovered. The button sits in the heading's left margin, so it
+// stays up while the pointer crosses the parent's box on its way there.
+func anchorHeadingFor(node, current *dom.Node) *dom.Node {
+	if heading := dom.FindHeading(node); heading != nil && heading.Attributes["id"] != "" {
+		return heading
+	}
+	if current != nil && node != nil && node == current.Parent {
+		return current
+	}
+	return nil
+}
+
+// updateHeadingAnchor shows the copy-link button next to the hovered
+// heading, or hides it when the pointer leaves.
+func (b *Browser) updateHeadingAnchor(hovered *dom.Node) {
+	heading := anchorHeadingFor(hovered, b.anchorHeading)
+	if heading == b.anchorHeading {
+		return
+	}
+	b.hideHeadingAnchor()
+	if heading == nil {
+		return
+	}
+	box := findLayoutBoxByNode(b.layoutTree, heading)
+	if box == nil {
+		return
+	}
+
+	btn := widget.NewButton("#", func() {
+		b.CopySectionLink(heading)
+	})
+	btn.Importance = widget.LowImportance
+	pos := b.fromPage(box.Rect.X, box.Rect.Y)
+	pos.X = max(pos.X-anchorButtonSize-2, 0)
+	btn.Resize(fyne.NewSize(anchorButtonSize, anchorButtonSize))
+	btn.Move(b.contentToWindow(pos))
+
+	b.anchorHeading = heading
+	b.anchorOverlay = btn
+	b.toastContainer.Add(btn)
+	b.toastContainer.Refresh()
+}
+
+func (b *Browser) hideHeadingAnchor() {
+	if b.anchorOverlay != nil {
+		b.toastContainer.Remove(b.anchorOverlay)
+		b.toastContainer.Refresh()
+	}
+	b.anchorHeading = nil
+	b.anchorOverlay = nil
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"browser/dom"
+	"browser/layout"
+	"net/url"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSectionURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		id       string
+		expected string
+	}{
+		{"adds fragment", "https://example.com/docs/guide", "install", "https://example.com/docs/guide#install"},
+		{"replaces fragment", "https://example.com/guide#old", "usage", "https://example.com/guide#usage"},
+		{"keeps query", "https://example.com/guide?v=2", "api", "https://example.com/guide?v=2#api"},
+		{"escapes id", "https://example.com/", "a b", "https://example.com/#a%20b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := url.Parse(tt.page)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, sectionURL(page, tt.id))
+			// The page URL itself is left untouched
+			assert.Equal(t, tt.page, page.String())
+		})
+	}
+}
+
+func TestIsCurrentDocument(t *testing.T) {
+	current, _ := url.Parse("https://example.com/guide?v=2#intro")
+	b := &Browser{currentURL: current}
+
+	tests := []struct {
+		target   string
+		expected bool
+	}{
+		{"https://example.com/guide?v=2#usage", true},
+		{"https://example.com/guide?v=2", true},
+		{"https://example.com/guide?v=3#usage", false},
+		{"https://example.com/other#usage", false},
+		{"https://other.com/guide?v=2#usage", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			u, _ := url.Parse(tt.target)
+			assert.Equal(t, tt.expected, b.isCurrentDocument(u))
+		})
+	}
+}
+
+func TestAnchorHeadingFor(t *testing.T) {
+	section := dom.NewElement("section", map[string]string{})
+	withID := dom.NewElement("h2", map[string]string{"id": "install"})
+	withoutID := dom.NewElement("h3", map[string]string{})
+	code := dom.NewElement("code", map[string]string{})
+	para := dom.NewElement("p", map[string]string{})
+	section.AppendChild(withID)
+	section.AppendChild(withoutID)
+	section.AppendChild(para)
+	withID.AppendChild(code)
+
+	tests := []struct {
+		name     string
+		hovered  *dom.Node
+		current  *dom.Node
+		expected *dom.Node
+	}{
+		{"heading with id", withID, nil, withID},
+		{"descendant of heading", code, nil, withID},
+		{"heading without id", withoutID, nil, nil},
+		{"paragraph", para, nil, nil},
+		{"nothing hovered", nil, withID, nil},
+		{"parent keeps the current button", section, withID, withID},
+		{"parent alone shows nothing", section, nil, nil},
+		{"sibling hides the button", para, withID, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, anchorHeadingFor(tt.hovered, tt.current))
+		})
+	}
+}
+
+func TestGoToSection(t *testing.T) {
+	test.NewTempApp(t)
+	doc := dom.Parse(strings.NewReader(`<h2 id="install">Install</h2><h2 id="usage">Usage</h2>`))
+	tree := &layout.LayoutBox{}
+	for _, id := range []string{"install", "usage"} {
+		tree.Children = append(tree.Children, &layout.LayoutBox{Node: dom.FindByID(doc, id)})
+	}
+	current, _ := url.Parse("https://example.com/guide")
+	b := &Browser{document: doc, layoutTree: tree, currentURL: current, urlEntry: widget.NewEntry(), historyPos: -1}
+	b.AddToHistory("https://example.com/guide")
+
+	assert.True(t, b.GoToSection("install"))
+	assert.True(t, b.GoToSection("install"), "the same section again")
+	assert.True(t, b.GoToSection("usage"))
+	assert.False(t, b.GoToSection("missing"))
+	assert.Equal(t, []string{"https://example.com/guide", "https://example.com/guide#install", "https://example.com/guide#usage"}, b.history)
+	assert.Equal(t, "https://example.com/guide#usage", b.urlEntry.Text)
+}
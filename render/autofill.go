@@ -0,0 +1,109 @@
+package render
+
+import (
+	"browser/dom"
+	"strings"
+)
+
+const (
+	maxFormHistory         = 20 // remembered values per field
+	maxAutofillSuggestions = 6  // entries shown below a focused field
+	suggestionHeight       = 28.0
+)
+
+// Input types whose submitted values are remembered for suggestions.
+// Passwords are never stored.
+var autofillInputTypes = map[string]bool{
+	"": true, "text": true, "email": true, "search": true, "tel": true, "url": true,
+}
+
+// AutofillSuggestions returns previously submitted values for field that
+// extend what has been typed so far. Fields that opt out with
+// autocomplete=off (on the field or its form) or new-password get none.
+func (b *Browser) AutofillSuggestions(field *dom.Node) []string {
+	if !isAutofillable(field) {
+		return nil
+	}
+	typed := strings.ToLower(b.inputValues[field])
+	var suggestions []string
+	for _, v := range b.formHistory[autofillKey(field)] {
+		lower := strings.ToLower(v)
+		if lower != typed && strings.HasPrefix(lower, typed) {
+			suggestions = append(suggestions, v)
+			if len(suggestions) == maxAutofillSuggestions {
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+// rememberFormValues records the values of form's autofillable fields on
+// submission, most recent first.
+func (b *Browser) rememberFormValues(form *dom.Node) {
+	var walk func(n *dom.Node)
+	walk = func(n *dom.Node) {
+		if isAutofillable(n) {
+			if key, value := autofillKey(n), b.inputValues[n]; key != "" && strings.TrimSpace(value) != "" {
+				b.formHistory[key] = prependUnique(b.formHistory[key], value, maxFormHistory)
+			}
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(form)
+}
+
+// acceptSuggestionAt fills the focused field with the suggestion under
+// (x, y), if any, and reports whether one was picked.
+func (b *Browser) acceptSuggestionAt(x, y float64) bool {
+	field := b.focusedInputNode
+	suggestions := b.AutofillSuggestions(field)
+	if len(suggestions) == 0 {
+		return false
+	}
+	box := findLayoutBoxByNode(b.layoutTree, field)
+	if box == nil {
+		return false
+	}
+	top := box.Rect.Y + box.Rect.Height
+	if x < box.Rect.X || x >= box.Rect.X+box.Rect.Width || y < top || y >= top+float64(len(suggestions))*suggestionHeight {
+		return false
+	}
+	b.inputValues[field] = suggestions[int((y-top)/suggestionHeight)]
+	b.repaint()
+	return true
+}
+
+// isAutofillable reports whether field takes part in form history.
+func isAutofillable(field *dom.Node) bool {
+	if field == nil || field.Type != dom.Element || field.TagName != dom.TagInput {
+		return false
+	}
+	if !autofillInputTypes[strings.ToLower(field.Attributes["type"])] || isNodeDisabled(field) || isNodeReadonly(field) {
+		return false
+	}
+	return !dom.FieldAutofill(field).Off
+}
+
+// autofillKey groups remembered values: by autofill hint when the page
+// gives one (so "email" fields share entries across sites), else by name.
+func autofillKey(field *dom.Node) string {
+	if hint := dom.FieldAutofill(field).Hint; hint != "" {
+		return hint
+	}
+	return strings.ToLower(field.Attributes["name"])
+}
+
+// prependUnique puts value first in list, dropping an older copy and
+// anything past limit.
+func prependUnique(list []string, value string, limit int) []string {
+	out := []string{value}
+	for _, v := range list {
+		if v != value && len(out) < limit {
+			out = append(out, v)
+		}
+	}
+	return out
+}
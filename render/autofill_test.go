@@ -0,0 +1,65 @@
+package render
+
+import (
+	"browser/dom"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutofillSuggestions(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`
+		<form><input id="email" name="email"><input id="pw" type="password" name="pw"><input id="off" name="email" autocomplete="off"></form>
+		<form autocomplete="off"><input id="formoff" name="email"></form>
+		<input id="hinted" name="contact" autocomplete="email">`))
+	field := func(id string) *dom.Node { return dom.FindByID(doc, id) }
+
+	b := &Browser{inputValues: map[*dom.Node]string{}, formHistory: map[string][]string{}}
+	b.formHistory["email"] = []string{"ada@example.com", "alan@example.com", "grace@example.com"}
+
+	tests := []struct {
+		name     string
+		field    string
+		typed    string
+		expected []string
+	}{
+		{"all entries when empty", "email", "", []string{"ada@example.com", "alan@example.com", "grace@example.com"}},
+		{"prefix match ignores case", "email", "A", []string{"ada@example.com", "alan@example.com"}},
+		{"exact match not repeated", "email", "grace@example.com", nil},
+		{"password fields never suggest", "pw", "", nil},
+		{"autocomplete=off on the field", "off", "", nil},
+		{"autocomplete=off on the form", "formoff", "", nil},
+		{"hint shares entries across names", "hinted", "g", []string{"grace@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.inputValues[field(tt.field)] = tt.typed
+			assert.Equal(t, tt.expected, b.AutofillSuggestions(field(tt.field)))
+		})
+	}
+}
+
+func TestRememberFormValues(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<form id="f">
+		<input id="user" name="user"><input id="pw" type="password" name="pw">
+		<input id="new" type="password" name="new" autocomplete="new-password">
+		<input id="secret" name="secret" autocomplete="off"><input id="empty" name="empty">
+	</form>`))
+	b := &Browser{inputValues: map[*dom.Node]string{}, formHistory: map[string][]string{"user": {"bob", "ada"}}}
+	b.inputValues[dom.FindByID(doc, "user")] = "ada"
+	b.inputValues[dom.FindByID(doc, "pw")] = "hunter2"
+	b.inputValues[dom.FindByID(doc, "new")] = "hunter3"
+	b.inputValues[dom.FindByID(doc, "secret")] = "1234"
+
+	b.rememberFormValues(dom.FindByID(doc, "f"))
+
+	assert.Equal(t, map[string][]string{"user": {"ada", "bob"}}, b.formHistory)
+}
+
+func TestPrependUnique(t *testing.T) {
+	assert.Equal(t, []string{"c", "a", "b"}, prependUnique([]string{"a", "b"}, "c", 5))
+	assert.Equal(t, []string{"b", "a"}, prependUnique([]string{"a", "b"}, "b", 5))
+	assert.Equal(t, []string{"c", "a"}, prependUnique([]string{"a", "b"}, "c", 2))
+}
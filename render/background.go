@@ -0,0 +1,194 @@
+package render
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"browser/css"
+	"browser/layout"
+)
+
+// maxBackgroundTiles caps the images a single background may produce; tiles
+// past the cap are dropped rather than flooding the canvas.
+const maxBackgroundTiles = 4096
+
+// backgroundArea returns where a background layer is positioned when that
+// is not its box: the viewport, of size view, for a fixed layer (CSS
+// Backgrounds §3.5), in the viewport coordinates the fixed layer is drawn
+// in. A fixed layer with no viewport, as when printing, scrolls with its
+// box.
+func backgroundArea(layer css.BackgroundLayer, view layout.Rect) layout.Rect {
+	if layer.Attachment != "fixed" || view.Width <= 0 || view.Height <= 0 {
+		return layout.Rect{}
+	}
+	return layout.Rect{Width: view.Width, Height: view.Height}
+}
+
+// backgroundTileSize resolves background-size for an image of imgW x imgH
+// painted into area (CSS Backgrounds §3.9). Percentages are relative to the
+// area; an auto dimension keeps the image's aspect ratio.
+func backgroundTileSize(size string, area layout.Rect, imgW, imgH float64) (w, h float64) {
+	switch size {
+	case "contain", "cover":
+		scale := min(area.Width/imgW, area.Height/imgH)
+		if size == "cover" {
+			scale = max(area.Width/imgW, area.Height/imgH)
+		}
+		return imgW * scale, imgH * scale
+	}
+
+	parts := strings.Fields(size)
+	if len(parts) == 1 {
+		parts = append(parts, "auto")
+	}
+	if len(parts) != 2 {
+		return imgW, imgH
+	}
+	w = backgroundLength(parts[0], area.Width)
+	h = backgroundLength(parts[1], area.Height)
+	switch {
+	case w <= 0 && h <= 0:
+		return imgW, imgH
+	case w <= 0:
+		return h * imgW / imgH, h
+	case h <= 0:
+		return w, w * imgH / imgW
+	}
+	return w, h
+}
+
+// backgroundLength resolves a length or percentage of extent; auto and
+// unparsable values resolve to 0.
+func backgroundLength(value string, extent float64) float64 {
+	if pct, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return 0
+		}
+		return extent * p / 100
+	}
+	if value == "auto" {
+		return 0
+	}
+	return css.ParseSize(value)
+}
+
+// backgroundOffset resolves background-position to the tile's offset from
+// the area's top-left corner (CSS Backgrounds §3.6). Percentages align that
+// point of the tile with the same point of the area; a missing value is center.
+func backgroundOffset(position string, area layout.Rect, tileW, tileH float64) (x, y float64) {
+	parts := strings.Fields(position)
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	horizontal, vertical := "0%", "0%"
+	switch len(parts) {
+	case 1:
+		horizontal, vertical = parts[0], "center"
+		if parts[0] == "top" || parts[0] == "bottom" {
+			horizontal, vertical = "center", parts[0]
+		}
+	case 2:
+		horizontal, vertical = parts[0], parts[1]
+		// "top left" names the vertical edge first
+		if horizontal == "top" || horizontal == "bottom" || vertical == "left" || vertical == "right" {
+			horizontal, vertical = vertical, horizontal
+		}
+	}
+	return positionValue(horizontal, "left", "right", area.Width-tileW),
+		positionValue(vertical, "top", "bottom", area.Height-tileH)
+}
+
+// positionValue resolves one background-position component against the
+// room left over by the tile.
+func positionValue(value, start, end string, room float64) float64 {
+	switch value {
+	case start:
+		return 0
+	case "center":
+		return room / 2
+	case end:
+		return room
+	}
+	if pct, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return 0
+		}
+		return room * p / 100
+	}
+	return css.ParseSize(value)
+}
+
+// backgroundRepeats resolves background-repeat into whether the image tiles
+// horizontally and vertically. space and round are treated as repeat.
+func backgroundRepeats(repeat string) (x, y bool) {
+	parts := strings.Fields(repeat)
+	switch {
+	case len(parts) == 0:
+		return true, true
+	case len(parts) == 1 && parts[0] == "repeat-x":
+		return true, false
+	case len(parts) == 1 && parts[0] == "repeat-y":
+		return false, true
+	case len(parts) == 1:
+		parts = append(parts, parts[0])
+	}
+	return parts[0] != "no-repeat", parts[1] != "no-repeat"
+}
+
+// backgroundTiles returns where copies of an imgW x imgH background image
+// are drawn to paint area, given the box's background-size,
+// background-position and background-repeat. Tiles may extend past the area;
+// the painter clips them.
+func backgroundTiles(area layout.Rect, imgW, imgH float64, size, position, repeat string) []layout.Rect {
+	if imgW <= 0 || imgH <= 0 || area.Width <= 0 || area.Height <= 0 {
+		return nil
+	}
+	tileW, tileH := backgroundTileSize(size, area, imgW, imgH)
+	if tileW <= 0 || tileH <= 0 {
+		return nil
+	}
+	offsetX, offsetY := backgroundOffset(position, area, tileW, tileH)
+	repeatX, repeatY := backgroundRepeats(repeat)
+
+	xs := tileStarts(area.X, area.Width, area.X+offsetX, tileW, repeatX)
+	ys := tileStarts(area.Y, area.Height, area.Y+offsetY, tileH, repeatY)
+	var tiles []layout.Rect
+	for _, y := range ys {
+		for _, x := range xs {
+			if len(tiles) == maxBackgroundTiles {
+				return tiles
+			}
+			tiles = append(tiles, layout.Rect{X: x, Y: y, Width: tileW, Height: tileH})
+		}
+	}
+	return tiles
+}
+
+// tileStarts returns the coordinates of the tiles along one axis. Without
+// repeat there is a single tile at pos; with it, tiles run from before start
+// until they cover start+extent.
+func tileStarts(start, extent, pos, tile float64, repeat bool) []float64 {
+	if !repeat {
+		if pos+tile <= start || pos >= start+extent {
+			return nil
+		}
+		return []float64{pos}
+	}
+	first := pos
+	if first > start {
+		first -= math.Ceil((first-start)/tile) * tile
+	} else {
+		first += math.Floor((start-first)/tile) * tile
+	}
+	var out []float64
+	for p := first; p < start+extent; p += tile {
+		out = append(out, p)
+		if len(out) > maxBackgroundTiles {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,264 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackgroundTileSize(t *testing.T) {
+	area := layout.Rect{Width: 200, Height: 100}
+
+	tests := []struct {
+		name  string
+		size  string
+		wantW float64
+		wantH float64
+	}{
+		{"initial value", "", 40, 20},
+		{"auto", "auto", 40, 20},
+		{"contain", "contain", 200, 100},
+		{"cover", "cover", 200, 100},
+		{"explicit size", "30px 10px", 30, 10},
+		{"width only keeps ratio", "80px", 80, 40},
+		{"auto width keeps ratio", "auto 50px", 100, 50},
+		{"percentages of the area", "50% 50%", 100, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := backgroundTileSize(tt.size, area, 40, 20)
+			assert.Equal(t, tt.wantW, w)
+			assert.Equal(t, tt.wantH, h)
+		})
+	}
+
+	w, h := backgroundTileSize("contain", area, 50, 50)
+	assert.Equal(t, []float64{100, 100}, []float64{w, h}, "contain fits the shorter side")
+	w, h = backgroundTileSize("cover", area, 50, 50)
+	assert.Equal(t, []float64{200, 200}, []float64{w, h}, "cover fills the longer side")
+}
+
+func TestBackgroundOffset(t *testing.T) {
+	area := layout.Rect{Width: 200, Height: 100}
+
+	tests := []struct {
+		name     string
+		position string
+		wantX    float64
+		wantY    float64
+	}{
+		{"initial value", "", 0, 0},
+		{"center", "center", 80, 40},
+		{"single horizontal keyword", "right", 160, 40},
+		{"single vertical keyword", "bottom", 80, 80},
+		{"two keywords", "right bottom", 160, 80},
+		{"vertical keyword first", "top right", 160, 0},
+		{"percentages", "25% 100%", 40, 80},
+		{"lengths", "10px 5px", 10, 5},
+		{"length and keyword", "10px center", 10, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := backgroundOffset(tt.position, area, 40, 20)
+			assert.Equal(t, tt.wantX, x)
+			assert.Equal(t, tt.wantY, y)
+		})
+	}
+}
+
+func TestBackgroundRepeats(t *testing.T) {
+	tests := []struct {
+		repeat string
+		wantX  bool
+		wantY  bool
+	}{
+		{"", true, true},
+		{"repeat", true, true},
+		{"repeat-x", true, false},
+		{"repeat-y", false, true},
+		{"no-repeat", false, false},
+		{"repeat no-repeat", true, false},
+		{"space", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat, func(t *testing.T) {
+			x, y := backgroundRepeats(tt.repeat)
+			assert.Equal(t, tt.wantX, x)
+			assert.Equal(t, tt.wantY, y)
+		})
+	}
+}
+
+func TestBackgroundTiles(t *testing.T) {
+	area := layout.Rect{X: 10, Y: 20, Width: 100, Height: 50}
+	tile := func(x, y, w, h float64) layout.Rect {
+		return layout.Rect{X: x, Y: y, Width: w, Height: h}
+	}
+
+	tests := []struct {
+		name     string
+		size     string
+		position string
+		repeat   string
+		want     []layout.Rect
+	}{
+		{
+			name:   "no-repeat at the origin",
+			repeat: "no-repeat",
+			want:   []layout.Rect{tile(10, 20, 40, 30)},
+		},
+		{
+			name:     "no-repeat centered",
+			position: "center",
+			repeat:   "no-repeat",
+			want:     []layout.Rect{tile(40, 30, 40, 30)},
+		},
+		{
+			name:   "repeat-x covers the row",
+			repeat: "repeat-x",
+			want:   []layout.Rect{tile(10, 20, 40, 30), tile(50, 20, 40, 30), tile(90, 20, 40, 30)},
+		},
+		{
+			name:     "repeat-x centered starts before the area",
+			position: "center top",
+			repeat:   "repeat-x",
+			want:     []layout.Rect{tile(0, 20, 40, 30), tile(40, 20, 40, 30), tile(80, 20, 40, 30)},
+		},
+		{
+			name: "repeat fills both axes",
+			size: "50px 25px",
+			want: []layout.Rect{
+				tile(10, 20, 50, 25), tile(60, 20, 50, 25),
+				tile(10, 45, 50, 25), tile(60, 45, 50, 25),
+			},
+		},
+		{
+			name: "cover is a single tile",
+			size: "cover",
+			want: []layout.Rect{tile(10, 20, 100, 75)},
+		},
+		{
+			name:     "no-repeat outside the area",
+			position: "200px 0px",
+			repeat:   "no-repeat",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, backgroundTiles(area, 40, 30, tt.size, tt.position, tt.repeat))
+		})
+	}
+}
+
+func TestBackgroundTilesCapped(t *testing.T) {
+	area := layout.Rect{Width: 10000, Height: 10000}
+	tiles := backgroundTiles(area, 1, 1, "", "", "")
+	assert.Len(t, tiles, maxBackgroundTiles)
+}
+
+func TestBackgroundLayerPaintOrder(t *testing.T) {
+	root := buildLayout(`<div id="hero"></div>`,
+		`#hero { height: 50px; background: url(top.png) no-repeat center, url(middle.png) repeat-x, url(bottom.png) navy; }`, 800)
+
+	var events []string
+	for _, cmd := range BuildDisplayList(root, InputState{}, LinkStyler{}) {
+		switch c := cmd.(type) {
+		case PushClip:
+			events = append(events, "push")
+		case PopClip:
+			events = append(events, "pop")
+		case DrawImage:
+			events = append(events, c.URL+" "+c.Repeat)
+		}
+	}
+
+	// One clip around every layer, painted bottom to top
+	assert.Equal(t, []string{"push", "bottom.png repeat", "middle.png repeat-x", "top.png no-repeat", "pop"}, events)
+}
+
+func TestFixedBackground(t *testing.T) {
+	root := buildLayout(`<div id="hero"></div><div id="bar"></div>`,
+		`#hero { height: 300px; margin-top: 500px; background: red url(hero.jpg) center / cover fixed; }
+		#bar { position: fixed; top: 0; height: 40px; background: url(bar.png) fixed; }`, 800)
+	view := layout.Rect{Width: 800, Height: 600}
+
+	areas := func(commands []DisplayCommand) []layout.Rect {
+		var areas []layout.Rect
+		for _, cmd := range commands {
+			if img, ok := cmd.(DrawImage); ok && img.Background {
+				areas = append(areas, img.Area)
+			}
+		}
+		return areas
+	}
+	normal, fixed := BuildDisplayLists(root, InputState{Viewport: view}, LinkStyler{})
+	assert.Empty(t, areas(normal.Commands), "the page leaves the hero's background out")
+	assert.Equal(t, []layout.Rect{view}, areas(fixed.Commands), "fixed boxes are drawn in the viewport")
+
+	var beneath []DrawFixedBackground
+	for _, cmd := range fixed.Commands {
+		if c, ok := cmd.(DrawFixedBackground); ok {
+			beneath = append(beneath, c)
+		}
+	}
+	if assert.Len(t, beneath, 1) {
+		hero := beneath[0]
+		assert.Equal(t, 300.0, hero.Height)
+		assert.Equal(t, color.RGBA{255, 0, 0, 255}, hero.Color)
+		assert.Equal(t, []DrawImage{{Rect: view, URL: "hero.jpg", SizeMode: "cover", Background: true, Position: "center", Area: view}}, hero.Layers)
+
+		var holes []layout.Rect
+		for _, cmd := range normal.Commands {
+			if c, ok := cmd.(PushClip); ok && c.Exclude {
+				holes = append(holes, c.Rect)
+			}
+		}
+		assert.Contains(t, holes, hero.Rect, "the page is cut where the hero shows")
+	}
+
+	normal, fixed = BuildDisplayLists(root, InputState{}, LinkStyler{})
+	assert.Equal(t, []layout.Rect{{}}, areas(normal.Commands), "without a viewport the background scrolls")
+	assert.NotContains(t, fmt.Sprint(fixed.Commands), "hero.jpg")
+}
+
+func TestPlaceFixedBackground(t *testing.T) {
+	test.NewApp()
+	layer := container.NewWithoutLayout()
+	bg := &fixedBackground{
+		rect:  layout.Rect{Y: 500, Width: 800, Height: 300},
+		clip:  container.NewScroll(container.NewWithoutLayout(layer)),
+		fixed: []fyne.CanvasObject{layer},
+	}
+
+	bg.place(fyne.NewPos(0, 400))
+	assert.Equal(t, fyne.NewPos(0, 100), bg.clip.Position(), "the hole scrolls with the page")
+	assert.Equal(t, fyne.NewPos(0, -100), layer.Position(), "the layer stays at the viewport's origin")
+}
+
+func TestRenderFixedBackground(t *testing.T) {
+	test.NewApp()
+	src := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	c := DrawImage{
+		Rect:       layout.Rect{X: 0, Y: 100, Width: 800, Height: 100},
+		Background: true,
+		Area:       layout.Rect{Width: 800, Height: 600},
+	}
+	objects := renderBackground(src, c)
+	assert.Len(t, objects, 8, "only the row of tiles over the box")
+	for _, obj := range objects {
+		assert.Equal(t, float32(100), obj.Position().Y, "tiled from the top of the viewport")
+	}
+}
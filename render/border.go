@@ -0,0 +1,235 @@
+package render
+
+import (
+	"image/color"
+	"math"
+	"slices"
+
+	"browser/layout"
+)
+
+// borderEdge is one side of a box's border.
+type borderEdge struct {
+	width float64
+	style string
+	color color.Color
+	// horizontal edges (top, bottom) run along x; vertical ones along y
+	horizontal bool
+	// far edges (right, bottom) have their outside at the larger coordinate
+	far bool
+	// outer is the strip the edge covers
+	outer layout.Rect
+}
+
+// borderEdges returns the top, right, bottom and left border edges of box,
+// each spanning the full side of rect. Collapsed table borders are centred
+// on the grid lines, so they extend half their width beyond rect.
+func borderEdges(box *layout.LayoutBox, rect layout.Rect) []borderEdge {
+	s := box.Style
+	if box.CollapsedBorders {
+		rect = layout.Rect{
+			X:      rect.X - s.BorderLeftWidth/2,
+			Y:      rect.Y - s.BorderTopWidth/2,
+			Width:  rect.Width + (s.BorderLeftWidth+s.BorderRightWidth)/2,
+			Height: rect.Height + (s.BorderTopWidth+s.BorderBottomWidth)/2,
+		}
+	}
+	return edgesAround(rect,
+		[4]float64{s.BorderTopWidth, s.BorderRightWidth, s.BorderBottomWidth, s.BorderLeftWidth},
+		[4]string{s.BorderTopStyle, s.BorderRightStyle, s.BorderBottomStyle, s.BorderLeftStyle},
+		[4]color.Color{s.BorderTopColor, s.BorderRightColor, s.BorderBottomColor, s.BorderLeftColor})
+}
+
+// outlineEdges returns the edges of box's outline: a ring of uniform style
+// drawn outline-offset outside rect, the border box (CSS Basic UI §5).
+// currentColor resolves to textColor. Returns nil if there is no outline.
+func outlineEdges(box *layout.LayoutBox, rect layout.Rect, textColor color.Color) []borderEdge {
+	s := box.Style
+	style := s.OutlineStyle
+	if style == "auto" {
+		style = "solid"
+	}
+	if style == "" || style == "none" || s.OutlineWidth <= 0 {
+		return nil
+	}
+	outlineColor := s.OutlineColor
+	if outlineColor == nil {
+		outlineColor = textColor
+	}
+	grow := s.OutlineOffset + s.OutlineWidth
+	ring := layout.Rect{X: rect.X - grow, Y: rect.Y - grow, Width: rect.Width + 2*grow, Height: rect.Height + 2*grow}
+	if ring.Width <= 0 || ring.Height <= 0 {
+		return nil
+	}
+	w := s.OutlineWidth
+	return edgesAround(ring, [4]float64{w, w, w, w}, [4]string{style, style, style, style},
+		[4]color.Color{outlineColor, outlineColor, outlineColor, outlineColor})
+}
+
+// edgesAround returns the edges lining the inside of rect with the given
+// top, right, bottom and left widths, styles and colors.
+func edgesAround(rect layout.Rect, widths [4]float64, styles [4]string, colors [4]color.Color) []borderEdge {
+	top, right, bottom, left := widths[0], widths[1], widths[2], widths[3]
+	return []borderEdge{
+		{
+			width: top, style: styles[0], color: colors[0], horizontal: true,
+			outer: layout.Rect{X: rect.X, Y: rect.Y, Width: rect.Width, Height: top},
+		},
+		{
+			width: right, style: styles[1], color: colors[1],
+			outer: layout.Rect{X: rect.X + rect.Width - right, Y: rect.Y, Width: right, Height: rect.Height}, far: true,
+		},
+		{
+			width: bottom, style: styles[2], color: colors[2], horizontal: true,
+			outer: layout.Rect{X: rect.X, Y: rect.Y + rect.Height - bottom, Width: rect.Width, Height: bottom}, far: true,
+		},
+		{
+			width: left, style: styles[3], color: colors[3],
+			outer: layout.Rect{X: rect.X, Y: rect.Y, Width: left, Height: rect.Height},
+		},
+	}
+}
+
+// paintEdges appends the rects painting the visible edges.
+func paintEdges(edges []borderEdge, commands *[]DisplayCommand) {
+	for _, edge := range edges {
+		if !edge.visible() {
+			continue
+		}
+		for _, rect := range edge.rects(edge.color) {
+			*commands = append(*commands, rect)
+		}
+	}
+}
+
+// roundedEdges grows the solid edges inward over the rounded corners at
+// their ends, radii listing top-left, top-right, bottom-right and
+// bottom-left, so that once clipped to the border's curves (see borderClips)
+// they fill the corners. rect is the border box the edges line.
+func roundedEdges(edges []borderEdge, rect layout.Rect, radii [4]float64) []borderEdge {
+	out := slices.Clone(edges)
+	for i, e := range out {
+		if !e.solid() {
+			continue
+		}
+		depth := math.Max(e.width, math.Max(radii[i], radii[(i+1)%4]))
+		if e.horizontal {
+			depth = math.Min(depth, rect.Height)
+			if e.far {
+				e.outer.Y -= depth - e.outer.Height
+			}
+			e.outer.Height = depth
+		} else {
+			depth = math.Min(depth, rect.Width)
+			if e.far {
+				e.outer.X -= depth - e.outer.Width
+			}
+			e.outer.Width = depth
+		}
+		out[i] = e
+	}
+	return out
+}
+
+// solid reports whether the edge paints as the single rect it covers.
+func (e borderEdge) solid() bool {
+	switch e.style {
+	case "dashed", "dotted":
+		return false
+	case "double":
+		return e.width < 3
+	}
+	return true
+}
+
+// visible reports whether the edge paints anything.
+func (e borderEdge) visible() bool {
+	return e.width > 0 && e.color != nil && e.style != "none" && e.style != "hidden"
+}
+
+// rects returns the rects painting the edge in its border style
+// (CSS Backgrounds §4.2). Dashes and dots are spread so the pattern starts
+// and ends at the corners; dots are round. Styles without a dedicated
+// rendering (groove, ridge, inset, outset) paint solid.
+func (e borderEdge) rects(col color.Color) []DrawRect {
+	switch e.style {
+	case "dashed":
+		return e.segments(col, 2*e.width, e.width, 0)
+	case "dotted":
+		return e.segments(col, e.width, e.width, e.width/2)
+	case "double":
+		if e.width >= 3 {
+			return e.doubleLines(col)
+		}
+	}
+	return []DrawRect{{Rect: e.outer, Color: col}}
+}
+
+// segments splits the edge into dashes of length dash separated by gaps of
+// roughly gap, with corner radius r.
+func (e borderEdge) segments(col color.Color, dash, gap, r float64) []DrawRect {
+	start, length := e.outer.Y, e.outer.Height
+	if e.horizontal {
+		start, length = e.outer.X, e.outer.Width
+	}
+	var out []DrawRect
+	for _, seg := range dashSegments(start, length, dash, gap) {
+		rect := e.outer
+		if e.horizontal {
+			rect.X, rect.Width = seg[0], seg[1]
+		} else {
+			rect.Y, rect.Height = seg[0], seg[1]
+		}
+		out = append(out, DrawRect{
+			Rect:              rect,
+			Color:             col,
+			TopLeftRadius:     r,
+			TopRightRadius:    r,
+			BottomRightRadius: r,
+			BottomLeftRadius:  r,
+		})
+	}
+	return out
+}
+
+// doubleLines paints two parallel lines, each a third of the border width,
+// at the outside and the inside of the edge.
+func (e borderEdge) doubleLines(col color.Color) []DrawRect {
+	line := e.width / 3
+	outer, inner := e.outer, e.outer
+	shifted := &inner
+	if e.far {
+		shifted = &outer
+	}
+	if e.horizontal {
+		outer.Height, inner.Height = line, line
+		shifted.Y += e.width - line
+	} else {
+		outer.Width, inner.Width = line, line
+		shifted.X += e.width - line
+	}
+	return []DrawRect{{Rect: outer, Color: col}, {Rect: inner, Color: col}}
+}
+
+// dashSegments returns the (start, length) of dashes covering length from
+// start. The dash count is chosen so the first and last dash touch the
+// ends; the gaps stretch or shrink to fit.
+func dashSegments(start, length, dash, gap float64) [][2]float64 {
+	if length <= 0 || dash <= 0 {
+		return nil
+	}
+	if length <= dash {
+		return [][2]float64{{start, length}}
+	}
+	n := math.Max(2, math.Round((length+gap)/(dash+gap)))
+	// Very short edges cannot hold two dashes with any gap between them
+	if n*dash >= length {
+		return [][2]float64{{start, length}}
+	}
+	spacing := (length - n*dash) / (n - 1)
+	segments := make([][2]float64, 0, int(n))
+	for i := 0.0; i < n; i++ {
+		segments = append(segments, [2]float64{start + i*(dash+spacing), dash})
+	}
+	return segments
+}
@@ -0,0 +1,163 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"browser/layout"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashSegments(t *testing.T) {
+	tests := []struct {
+		name   string
+		length float64
+		dash   float64
+		gap    float64
+		want   [][2]float64
+	}{
+		{"exact fit", 20, 4, 4, [][2]float64{{0, 4}, {8, 4}, {16, 4}}},
+		{"gaps stretch to reach the end", 22, 4, 4, [][2]float64{{0, 4}, {9, 4}, {18, 4}}},
+		{"edge shorter than a dash", 3, 4, 2, [][2]float64{{0, 3}}},
+		{"no room for a gap", 7, 4, 2, [][2]float64{{0, 7}}},
+		{"empty edge", 0, 4, 2, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, dashSegments(0, tt.length, tt.dash, tt.gap))
+		})
+	}
+}
+
+func TestBorderEdgeRects(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	box := &layout.LayoutBox{}
+	box.Style.BorderTopWidth, box.Style.BorderTopStyle, box.Style.BorderTopColor = 3, "double", black
+	box.Style.BorderRightWidth, box.Style.BorderRightStyle, box.Style.BorderRightColor = 6, "double", black
+	box.Style.BorderBottomWidth, box.Style.BorderBottomStyle, box.Style.BorderBottomColor = 2, "dotted", black
+	box.Style.BorderLeftWidth, box.Style.BorderLeftStyle, box.Style.BorderLeftColor = 2, "dashed", black
+	edges := borderEdges(box, layout.Rect{X: 10, Y: 10, Width: 100, Height: 22})
+	top, right, bottom, left := edges[0], edges[1], edges[2], edges[3]
+
+	rects := func(e borderEdge) []layout.Rect {
+		var out []layout.Rect
+		for _, r := range e.rects(black) {
+			out = append(out, r.Rect)
+		}
+		return out
+	}
+
+	t.Run("double lines hug both sides of the edge", func(t *testing.T) {
+		assert.Equal(t, []layout.Rect{{X: 10, Y: 10, Width: 100, Height: 1}, {X: 10, Y: 12, Width: 100, Height: 1}}, rects(top))
+		assert.Equal(t, []layout.Rect{{X: 108, Y: 10, Width: 2, Height: 22}, {X: 104, Y: 10, Width: 2, Height: 22}}, rects(right))
+	})
+
+	t.Run("dots are round and span the edge", func(t *testing.T) {
+		dots := bottom.rects(black)
+		assert.Len(t, dots, 26)
+		assert.Equal(t, 1.0, dots[0].TopLeftRadius)
+		assert.Equal(t, layout.Rect{X: 10, Y: 30, Width: 2, Height: 2}, dots[0].Rect)
+		assert.Equal(t, layout.Rect{X: 108, Y: 30, Width: 2, Height: 2}, dots[len(dots)-1].Rect)
+	})
+
+	t.Run("dashes run along vertical edges", func(t *testing.T) {
+		assert.Equal(t, []layout.Rect{
+			{X: 10, Y: 10, Width: 2, Height: 4}, {X: 10, Y: 16, Width: 2, Height: 4},
+			{X: 10, Y: 22, Width: 2, Height: 4}, {X: 10, Y: 28, Width: 2, Height: 4},
+		}, rects(left))
+	})
+
+	t.Run("thin double and unsupported styles paint solid", func(t *testing.T) {
+		for _, style := range []string{"solid", "", "groove", "inset"} {
+			edge := borderEdge{width: 2, style: style, color: black, horizontal: true, outer: layout.Rect{Width: 50, Height: 2}}
+			assert.Equal(t, []layout.Rect{{Width: 50, Height: 2}}, rects(edge), style)
+		}
+		thin := borderEdge{width: 2, style: "double", color: black, horizontal: true, outer: layout.Rect{Width: 50, Height: 2}}
+		assert.Len(t, thin.rects(black), 1)
+	})
+
+	t.Run("none and hidden are not painted", func(t *testing.T) {
+		assert.False(t, borderEdge{width: 2, style: "none", color: black}.visible())
+		assert.False(t, borderEdge{width: 2, style: "hidden", color: black}.visible())
+		assert.True(t, left.visible())
+	})
+}
+
+func TestCollapsedBorderEdges(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	box := &layout.LayoutBox{CollapsedBorders: true}
+	box.Style.BorderTopWidth, box.Style.BorderTopStyle, box.Style.BorderTopColor = 2, "solid", black
+	box.Style.BorderRightWidth, box.Style.BorderRightStyle, box.Style.BorderRightColor = 4, "solid", black
+	box.Style.BorderBottomWidth, box.Style.BorderBottomStyle, box.Style.BorderBottomColor = 2, "solid", black
+	box.Style.BorderLeftWidth, box.Style.BorderLeftStyle, box.Style.BorderLeftColor = 4, "solid", black
+
+	// Each border is centred on the cell's grid line
+	edges := borderEdges(box, layout.Rect{X: 10, Y: 10, Width: 100, Height: 40})
+	assert.Equal(t, layout.Rect{X: 8, Y: 9, Width: 104, Height: 2}, edges[0].outer)
+	assert.Equal(t, layout.Rect{X: 108, Y: 9, Width: 4, Height: 42}, edges[1].outer)
+	assert.Equal(t, layout.Rect{X: 8, Y: 49, Width: 104, Height: 2}, edges[2].outer)
+	assert.Equal(t, layout.Rect{X: 8, Y: 9, Width: 4, Height: 42}, edges[3].outer)
+}
+
+func TestRoundedEdges(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	box := &layout.LayoutBox{}
+	box.Style.BorderTopWidth, box.Style.BorderTopStyle, box.Style.BorderTopColor = 2, "solid", black
+	box.Style.BorderRightWidth, box.Style.BorderRightStyle, box.Style.BorderRightColor = 2, "solid", black
+	box.Style.BorderBottomWidth, box.Style.BorderBottomStyle, box.Style.BorderBottomColor = 2, "dashed", black
+	box.Style.BorderLeftWidth, box.Style.BorderLeftStyle, box.Style.BorderLeftColor = 2, "solid", black
+	rect := layout.Rect{X: 10, Y: 10, Width: 100, Height: 30}
+	edges := roundedEdges(borderEdges(box, rect), rect, [4]float64{10, 0, 50, 0})
+
+	// Solid edges reach over the larger radius at their ends, at most the box
+	assert.Equal(t, layout.Rect{X: 10, Y: 10, Width: 100, Height: 10}, edges[0].outer)
+	assert.Equal(t, layout.Rect{X: 60, Y: 10, Width: 50, Height: 30}, edges[1].outer)
+	assert.Equal(t, layout.Rect{X: 10, Y: 38, Width: 100, Height: 2}, edges[2].outer, "dashes keep their width")
+	assert.Equal(t, layout.Rect{X: 10, Y: 10, Width: 10, Height: 30}, edges[3].outer)
+}
+
+func TestOutlineEdges(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	rect := layout.Rect{X: 10, Y: 10, Width: 100, Height: 20}
+
+	t.Run("ring grows by offset and width", func(t *testing.T) {
+		box := &layout.LayoutBox{}
+		box.Style.OutlineWidth, box.Style.OutlineStyle, box.Style.OutlineOffset = 2, "dashed", 3
+		edges := outlineEdges(box, rect, red)
+		assert.Len(t, edges, 4)
+		assert.Equal(t, layout.Rect{X: 5, Y: 5, Width: 110, Height: 2}, edges[0].outer)
+		assert.Equal(t, layout.Rect{X: 113, Y: 5, Width: 2, Height: 30}, edges[1].outer)
+		for _, e := range edges {
+			assert.Equal(t, "dashed", e.style)
+			assert.Equal(t, color.Color(red), e.color, "currentColor resolves to the text color")
+		}
+	})
+
+	t.Run("auto paints solid", func(t *testing.T) {
+		box := &layout.LayoutBox{}
+		box.Style.OutlineWidth, box.Style.OutlineStyle, box.Style.OutlineColor = 2, "auto", color.Black
+		edges := outlineEdges(box, rect, red)
+		assert.Equal(t, "solid", edges[0].style)
+		assert.Equal(t, color.Color(color.Black), edges[0].color)
+	})
+
+	t.Run("negative offset draws inside the box", func(t *testing.T) {
+		box := &layout.LayoutBox{}
+		box.Style.OutlineWidth, box.Style.OutlineStyle, box.Style.OutlineOffset = 1, "solid", -3
+		edges := outlineEdges(box, rect, red)
+		assert.Equal(t, layout.Rect{X: 12, Y: 12, Width: 96, Height: 1}, edges[0].outer)
+	})
+
+	t.Run("no outline", func(t *testing.T) {
+		for _, style := range []string{"", "none"} {
+			box := &layout.LayoutBox{}
+			box.Style.OutlineWidth, box.Style.OutlineStyle = 3, style
+			assert.Nil(t, outlineEdges(box, rect, red), style)
+		}
+		box := &layout.LayoutBox{}
+		box.Style.OutlineWidth, box.Style.OutlineStyle = 0, "solid"
+		assert.Nil(t, outlineEdges(box, rect, red))
+	})
+}
@@ -2,6 +2,7 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"image"
@@ -10,24 +11,24 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
-	"net/http"
+	"math"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
 	"browser/css"
 	"browser/dom"
+	"browser/layout"
 	"browser/utils"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
-	"github.com/fyne-io/oksvg"
-	"github.com/srwiley/rasterx"
 )
 
 var (
-	imageCache      = make(map[string]image.Image)
-	imageCacheMu    sync.Mutex
 	pendingFeteches = make(map[string]bool)
 	pendingMu       sync.Mutex
 	failedImages    = make(map[string]bool)
@@ -81,8 +82,47 @@ func splitWordSpacingSegments(text string) []wordSegment {
 	return segments
 }
 
+// renderDropdownList draws a list of 28px rows below a form control, used
+// for open <select> options and autofill suggestions. selected is
+// highlighted.
+func renderDropdownList(x, y, width float64, items []string, selected string) []fyne.CanvasObject {
+	optionHeight := float64(28)
+	dropdownHeight := optionHeight * float64(len(items))
+	var objects []fyne.CanvasObject
+
+	// Dropdown border
+	dropBorder := canvas.NewRectangle(ColorBorder)
+	dropBorder.Resize(fyne.NewSize(float32(width), float32(dropdownHeight+2)))
+	dropBorder.Move(fyne.NewPos(float32(x), float32(y)))
+	objects = append(objects, dropBorder)
+
+	// Dropdown background
+	dropBg := canvas.NewRectangle(ColorWhite)
+	dropBg.Resize(fyne.NewSize(float32(width-2), float32(dropdownHeight)))
+	dropBg.Move(fyne.NewPos(float32(x+1), float32(y+1)))
+	objects = append(objects, dropBg)
+
+	for i, item := range items {
+		itemY := y + float64(i)*optionHeight
+
+		if item == selected {
+			highlight := canvas.NewRectangle(ColorSelectHighlight)
+			highlight.Resize(fyne.NewSize(float32(width-2), float32(optionHeight)))
+			highlight.Move(fyne.NewPos(float32(x+1), float32(itemY+1)))
+			objects = append(objects, highlight)
+		}
+
+		text := canvas.NewText(item, ColorBlack)
+		text.TextSize = 14
+		text.Move(fyne.NewPos(float32(x+6), float32(itemY+6)))
+		objects = append(objects, text)
+	}
+	return objects
+}
+
 // renderTextFieldObjects creates canvas objects for input/textarea fields
-func renderTextFieldObjects(x, y, width, height float64, value, placeholder string, isFocused, isDisabled, isValid bool) []fyne.CanvasObject {
+// Text is scrolled left by edit.ScrollX and clipped to the field.
+func renderTextFieldObjects(x, y, width, height float64, value, placeholder string, edit FieldEdit, showCaret, isFocused, isDisabled, isValid bool) []fyne.CanvasObject {
 	var objects []fyne.CanvasObject
 
 	// Border color based on state
@@ -116,27 +156,49 @@ func renderTextFieldObjects(x, y, width, height float64, value, placeholder stri
 	if isDisabled {
 		textColor = ColorTextDisabled
 	}
+	textX := x + fieldPadding
+	textWidth := width - 2*fieldPadding
+	showCaret = showCaret && !isDisabled
 
 	// Show typed value or placeholder
 	if value != "" {
-		lines := strings.Split(value, "\n")
-		lineHeight := float32(18)
-		var lastLineWidth float32
+		edit = edit.clamp(len(value))
+		selStart, selEnd := edit.Selection()
+
+		lineStart := 0
+		for i, line := range strings.Split(value, "\n") {
+			lineEnd := lineStart + len(line)
+			lineY := y + fieldPadding + float64(i)*fieldLineHeight
+			left := edit.ScrollX
+			visStart, visEnd := visibleRange(line, left, left+textWidth)
+			// xAt returns where the caret at offset into the line is drawn
+			xAt := func(offset int) float64 {
+				return textX + fieldTextWidth(line[:offset]) - left
+			}
 
-		for i, line := range lines {
-			text := canvas.NewText(line, textColor)
-			text.TextSize = 14
-			text.Move(fyne.NewPos(float32(x+6), float32(y+6)+float32(i)*lineHeight))
-			objects = append(objects, text)
-			lastLineWidth = fyne.MeasureText(line, 14, fyne.TextStyle{}).Width
-		}
+			// Selection, covering the line break when it runs past it
+			if start, end := max(selStart, lineStart), min(selEnd, lineEnd); start < end || start == end && selStart <= lineEnd && selEnd > lineEnd {
+				x0, x1 := xAt(max(start-lineStart, visStart)), xAt(min(end-lineStart, visEnd))
+				if selEnd > lineEnd {
+					x1 += fieldTextWidth(" ")
+				}
+				if x1 > x0 {
+					highlight := canvas.NewRectangle(ColorSelection)
+					highlight.Resize(fyne.NewSize(float32(x1-x0), fieldLineHeight-2))
+					highlight.Move(fyne.NewPos(float32(x0), float32(lineY-1)))
+					objects = append(objects, highlight)
+				}
+			}
 
-		if isFocused && !isDisabled {
-			cursorY := float32(y+5) + float32(len(lines)-1)*lineHeight
-			cursor := canvas.NewRectangle(ColorBlack)
-			cursor.Resize(fyne.NewSize(1, 16))
-			cursor.Move(fyne.NewPos(float32(x+6)+lastLineWidth, cursorY))
-			objects = append(objects, cursor)
+			// The visible text
+			if visStart < visEnd {
+				objects = append(objects, newFallbackTextObjects(line[visStart:visEnd], xAt(visStart), lineY, fieldTextSize, textColor, fyne.TextStyle{}, nil)...)
+			}
+
+			if caret := edit.Caret; showCaret && caret >= lineStart && caret <= lineEnd {
+				objects = append(objects, newCaret(xAt(caret-lineStart), lineY-1))
+			}
+			lineStart = lineEnd + 1
 		}
 	} else if placeholder != "" {
 		placeholderColor := ColorPlaceholder
@@ -149,22 +211,49 @@ func renderTextFieldObjects(x, y, width, height float64, value, placeholder stri
 		text.Move(fyne.NewPos(float32(x+6), float32(y+6)))
 		objects = append(objects, text)
 
-		if isFocused && !isDisabled {
-			cursor := canvas.NewRectangle(ColorBlack)
-			cursor.Resize(fyne.NewSize(1, 16))
-			cursor.Move(fyne.NewPos(float32(x+6), float32(y+5)))
-			objects = append(objects, cursor)
+		if showCaret {
+			objects = append(objects, newCaret(textX, y+5))
 		}
-	} else if isFocused && !isDisabled {
-		cursor := canvas.NewRectangle(ColorBlack)
-		cursor.Resize(fyne.NewSize(1, 16))
-		cursor.Move(fyne.NewPos(float32(x+6), float32(y+5)))
-		objects = append(objects, cursor)
+	} else if showCaret {
+		objects = append(objects, newCaret(textX, y+5))
 	}
 
 	return objects
 }
 
+// newCaret creates the caret of a text field at (x, y).
+func newCaret(x, y float64) fyne.CanvasObject {
+	cursor := canvas.NewRectangle(ColorBlack)
+	cursor.Resize(fyne.NewSize(1, 16))
+	cursor.Move(fyne.NewPos(float32(x), float32(y)))
+	return cursor
+}
+
+// visibleRange returns the bytes of line, start to end, whose clusters fit
+// between left and right measured from the start of the line.
+func visibleRange(line string, left, right float64) (start, end int) {
+	if left <= 0 && fieldTextWidth(line) <= right {
+		return 0, len(line)
+	}
+	start, end = -1, 0
+	offset := 0
+	for _, cluster := range layout.GraphemeClusters(line) {
+		clusterLeft := fieldTextWidth(line[:offset])
+		offset += len(cluster)
+		if fieldTextWidth(line[:offset]) > right {
+			break
+		}
+		if start < 0 && clusterLeft >= left {
+			start = offset - len(cluster)
+		}
+		end = offset
+	}
+	if start < 0 || end < start {
+		return end, end
+	}
+	return start, end
+}
+
 // renderNumberInput creates canvas objects for number input with spin buttons
 func renderNumberInput(x, y, width, height float64, value, placeholder string, isFocused, isDisabled bool) []fyne.CanvasObject {
 	var objects []fyne.CanvasObject
@@ -273,11 +362,53 @@ func renderNumberInput(x, y, width, height float64, value, placeholder string, i
 }
 
 func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, useCache bool, onImageLoad func()) []fyne.CanvasObject {
+	return renderToCanvas(commands, baseURL, pageURL, useCache, onImageLoad, nil)
+}
+
+// renderToCanvas is RenderToCanvas drawing a frame of a layer: commands the
+// previous frame drew are given the objects it made for them, when frame is
+// not nil.
+func renderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, useCache bool, onImageLoad func(), frame *frameObjects) []fyne.CanvasObject {
 	var objects []fyne.CanvasObject
 	var dropdownOverlays []fyne.CanvasObject // Collect dropdowns to render LAST (on top)
+	var clips clipStack
+	var filters []filterGroup
 
 	for _, cmd := range commands {
+		start := len(objects)
+		overlayStart := len(dropdownOverlays)
+		// Filters draw their group into one image, so what is inside is
+		// drawn afresh
+		key := ""
+		if frame != nil && len(filters) == 0 {
+			key = drawKey(cmd, clips)
+			if drawn, ok := frame.reused(key); ok {
+				objects = append(objects, drawn.objects...)
+				dropdownOverlays = append(dropdownOverlays, drawn.overlays...)
+				continue
+			}
+		}
 		switch c := cmd.(type) {
+		case PushFilter:
+			filters = append(filters, filterGroup{filters: c.Filters, start: len(objects)})
+		case PopFilter:
+			if len(filters) > 0 {
+				group := filters[len(filters)-1]
+				filters = filters[:len(filters)-1]
+				objects = append(objects[:group.start], filterObjects(objects[group.start:], group.filters)...)
+				start = len(objects)
+			}
+		case PushClip:
+			clips = append(clips, c)
+		case PopClip:
+			if len(clips) > 0 {
+				clips = clips[:len(clips)-1]
+			}
+		case DrawFixedBackground:
+			// Drawn beneath the page rather than with the layer's objects
+			if frame != nil {
+				frame.backgrounds = append(frame.backgrounds, newFixedBackground(c, baseURL, pageURL, useCache, onImageLoad, frame))
+			}
 		case DrawRect:
 			allSame := c.TopLeftRadius == c.TopRightRadius &&
 				c.TopRightRadius == c.BottomRightRadius &&
@@ -289,15 +420,17 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 				rect.CornerRadius = float32(c.TopLeftRadius)
 				objects = append(objects, rect)
 			} else {
+				// Drawn at device resolution, so the corners stay smooth
+				scale := TextRaster.DeviceScale()
 				img := drawRoundedRectImage(
-					int(c.Width)+1, int(c.Height)+1, c.Color,
-					c.TopLeftRadius, c.TopRightRadius,
-					c.BottomRightRadius, c.BottomLeftRadius,
+					int(math.Ceil(c.Width*scale)), int(math.Ceil(c.Height*scale)), c.Color,
+					c.TopLeftRadius*scale, c.TopRightRadius*scale,
+					c.BottomRightRadius*scale, c.BottomLeftRadius*scale,
 				)
 				ci := canvas.NewImageFromImage(img)
 				ci.Resize(fyne.NewSize(float32(c.Width), float32(c.Height)))
 				ci.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
-				ci.FillMode = canvas.ImageFillOriginal
+				ci.FillMode = canvas.ImageFillStretch
 				objects = append(objects, ci)
 			}
 
@@ -305,7 +438,7 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 			displayText := c.Text
 
 			if c.ClipLeftOffset > 0 {
-				displayText = truncateTextClipLeft(displayText, c.ClipLeftOffset, c.Size, c.LetterSpacing, c.WordSpacing)
+				displayText = truncateTextClipLeft(displayText, c.ClipLeftOffset, c)
 			}
 
 			effectiveOverflowX := c.OverflowX
@@ -314,65 +447,24 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 			}
 
 			if c.Width > 0 && effectiveOverflowX != "visible" {
-				textWidth := measureTextWidth(displayText, c.Size, c.LetterSpacing, c.WordSpacing)
+				textWidth := measureTextWidth(displayText, c)
 				if textWidth > c.Width {
 					switch c.TextOverflow {
 					case "ellipsis":
-						displayText = truncateTextWithEllipsis(displayText, c.Width, c.Size, c.LetterSpacing, c.WordSpacing)
+						displayText = truncateTextWithEllipsis(displayText, c.Width, c)
 					case "clip", "":
-						displayText = truncateTextClip(displayText, c.Width, c.Size, c.LetterSpacing, c.WordSpacing)
+						displayText = truncateTextClip(displayText, c.Width, c)
 					}
 				}
 			}
 
-			textStyle := fyne.TextStyle{
-				Bold:      c.Bold,
-				Italic:    c.Italic,
-				Monospace: c.Monospace,
-			}
-			switch {
-			case c.LetterSpacing == 0 && c.WordSpacing == 0:
-				text := canvas.NewText(displayText, c.Color)
-				text.TextSize = c.Size
-				text.TextStyle = textStyle
-				text.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
-				objects = append(objects, text)
-			case c.LetterSpacing == 0 && c.WordSpacing != 0:
-				x := c.X
-				for _, segment := range splitWordSpacingSegments(displayText) {
-					text := canvas.NewText(segment.text, c.Color)
-					text.TextSize = c.Size
-					text.TextStyle = textStyle
-					text.Move(fyne.NewPos(float32(x), float32(c.Y)))
-					objects = append(objects, text)
-
-					segWidth := float64(text.MinSize().Width)
-					x += segWidth
-					if segment.isGap {
-						x += c.WordSpacing
-					}
-				}
-			default:
-				x := c.X
-				runes := []rune(displayText)
-				for i, r := range runes {
-					ch := string(r)
-					text := canvas.NewText(ch, c.Color)
-					text.TextSize = c.Size
-					text.TextStyle = textStyle
-					text.Move(fyne.NewPos(float32(x), float32(c.Y)))
-					objects = append(objects, text)
-
-					advance := float64(c.Size) * 0.5
-					x += advance
-					if i < len(runes)-1 {
-						x += c.LetterSpacing
-						if r == ' ' || r == '\t' {
-							x += c.WordSpacing
-						}
-					}
+			// Shadows sit beneath the text, the first one on top
+			for _, shadow := range slices.Backward(c.Shadows) {
+				for _, cp := range textShadowCopies(shadow) {
+					objects = append(objects, textGlyphObjects(c, displayText, c.X+cp.dx, c.Y+cp.dy, cp.color)...)
 				}
 			}
+			objects = append(objects, textGlyphObjects(c, displayText, c.X, c.Y, c.Color)...)
 
 			// Draw text decoration lines
 			if c.Underline || c.DottedUnderline || c.Strikethrough || c.Overline {
@@ -425,34 +517,64 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 				OnLoad:         onImageLoad,
 			})
 
-			if err != nil {
-				// Broken image icon (top-left corner)
-				icon := canvas.NewText("🖼", color.RGBA{150, 150, 150, 255})
-				icon.TextSize = 12
-				icon.Move(fyne.NewPos(float32(c.X)+4, float32(c.Y)+4))
-				objects = append(objects, icon)
-
-				altText := canvas.NewText(c.AltText, color.RGBA{100, 100, 100, 255})
-				altText.TextSize = 12
-				altText.Move(fyne.NewPos(float32(c.X)+22, float32(c.Y)+4))
-				objects = append(objects, altText)
+			var deferred deferredImageError
+			if errors.As(err, &deferred) {
+				// Click-to-load placeholder; backgrounds are simply left out
+				if c.Node != nil {
+					objects = append(objects, renderDeferredImage(c.Rect, deferred.size)...)
+				}
+			} else if err != nil {
+				// Broken backgrounds are simply left out
+				if !c.Background {
+					objects = append(objects, renderBrokenImage(c)...)
+				}
 			} else if img != nil {
-				if c.SizeMode != "" && img.Image != nil {
-					handled := renderBackgroundSized(img, c, &objects)
-					if handled {
-						continue
-					}
+				if c.Background && img.Image != nil {
+					objects = append(objects, renderBackground(img.Image, c)...)
+				} else {
+					img.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
+					objects = append(objects, img)
 				}
-				img.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
-				objects = append(objects, img)
 			} else {
 				// Not cached yet - show gray placeholder
+				if frame != nil {
+					frame.pending = append(frame.pending, pendingImage{url: resolveImageURL(c.URL, baseURL), rect: c.Rect})
+				}
 				placeholder := canvas.NewRectangle(color.RGBA{220, 220, 220, 255})
 				placeholder.Resize(fyne.NewSize(float32(c.Width), float32(c.Height)))
 				placeholder.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
 				objects = append(objects, placeholder)
 			}
 
+		case DrawSVG:
+			// Rasterized at device resolution, so it stays sharp at any size
+			scale := TextRaster.DeviceScale()
+			w, h := int(math.Ceil(c.Width*scale)), int(math.Ceil(c.Height*scale))
+			if w <= 0 || h <= 0 {
+				break
+			}
+			raster, err := rasterizeSVG([]byte(c.Markup), w, h, c.Color)
+			if err != nil {
+				break
+			}
+			svg := canvas.NewImageFromImage(raster)
+			svg.FillMode = canvas.ImageFillStretch
+			svg.Resize(fyne.NewSize(float32(c.Width), float32(c.Height)))
+			svg.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
+			objects = append(objects, svg)
+
+		case DrawCanvas:
+			if c.Bitmap == nil || c.Bitmap.Rect.Empty() {
+				break
+			}
+			// A copy, as scripts keep drawing into the bitmap
+			bitmap := &image.NRGBA{Pix: slices.Clone(c.Bitmap.Pix), Stride: c.Bitmap.Stride, Rect: c.Bitmap.Rect}
+			img := canvas.NewImageFromImage(bitmap)
+			img.FillMode = canvas.ImageFillStretch
+			img.Resize(fyne.NewSize(float32(c.Width), float32(c.Height)))
+			img.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
+			objects = append(objects, img)
+
 		case DrawHR:
 			hr := canvas.NewRectangle(ColorHR)
 			hr.Resize(fyne.NewSize(float32(c.Width), float32(c.Height)))
@@ -460,14 +582,17 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 			objects = append(objects, hr)
 
 		case DrawInput:
-			displayValue := c.Value
+			displayValue, edit := c.Value, c.Edit
 			if c.InputType == "password" && displayValue != "" {
-				displayValue = strings.Repeat("•", len([]rune(displayValue)))
+				displayValue, edit = maskPassword(displayValue, edit)
 			}
 			if c.InputType == "number" {
 				objects = append(objects, renderNumberInput(c.X, c.Y, c.Width, c.Height, displayValue, c.Placeholder, c.IsFocused, c.IsDisabled)...)
 			} else {
-				objects = append(objects, renderTextFieldObjects(c.X, c.Y, c.Width, c.Height, displayValue, c.Placeholder, c.IsFocused, c.IsDisabled, c.IsValid)...)
+				objects = append(objects, renderTextFieldObjects(c.X, c.Y, c.Width, c.Height, displayValue, c.Placeholder, edit, c.ShowCaret, c.IsFocused, c.IsDisabled, c.IsValid)...)
+			}
+			if len(c.Suggestions) > 0 {
+				dropdownOverlays = append(dropdownOverlays, renderDropdownList(c.X, c.Y+c.Height, c.Width, c.Suggestions, "")...)
 			}
 
 		case DrawButton:
@@ -514,7 +639,7 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 			objects = append(objects, text)
 
 		case DrawTextarea:
-			objects = append(objects, renderTextFieldObjects(c.X, c.Y, c.Width, c.Height, c.Value, c.Placeholder, c.IsFocused, c.IsDisabled, true)...)
+			objects = append(objects, renderTextFieldObjects(c.X, c.Y, c.Width, c.Height, c.Value, c.Placeholder, c.Edit, c.ShowCaret, c.IsFocused, c.IsDisabled, true)...)
 
 		case DrawSelect:
 			// Border - blue when open
@@ -572,38 +697,7 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 			// Dropdown list when open
 			if c.IsOpen && len(c.Options) > 0 {
 				fmt.Printf("Canvas: Rendering dropdown with %d options at Y=%.0f\n", len(c.Options), c.Y+c.Height)
-				optionHeight := float64(28)
-				dropdownHeight := optionHeight * float64(len(c.Options))
-
-				// Dropdown border
-				dropBorder := canvas.NewRectangle(ColorBorder)
-				dropBorder.Resize(fyne.NewSize(float32(c.Width), float32(dropdownHeight+2)))
-				dropBorder.Move(fyne.NewPos(float32(c.X), float32(c.Y+c.Height)))
-				dropdownOverlays = append(dropdownOverlays, dropBorder)
-
-				// Dropdown background
-				dropBg := canvas.NewRectangle(ColorWhite)
-				dropBg.Resize(fyne.NewSize(float32(c.Width-2), float32(dropdownHeight)))
-				dropBg.Move(fyne.NewPos(float32(c.X+1), float32(c.Y+c.Height+1)))
-				dropdownOverlays = append(dropdownOverlays, dropBg)
-
-				// Options
-				for i, opt := range c.Options {
-					optY := c.Y + c.Height + float64(i)*optionHeight
-
-					// Highlight selected option
-					if opt == c.SelectedValue {
-						highlight := canvas.NewRectangle(ColorSelectHighlight)
-						highlight.Resize(fyne.NewSize(float32(c.Width-2), float32(optionHeight)))
-						highlight.Move(fyne.NewPos(float32(c.X+1), float32(optY+1)))
-						dropdownOverlays = append(dropdownOverlays, highlight)
-					}
-
-					optText := canvas.NewText(opt, ColorBlack)
-					optText.TextSize = 14
-					optText.Move(fyne.NewPos(float32(c.X+6), float32(optY+6)))
-					dropdownOverlays = append(dropdownOverlays, optText)
-				}
+				dropdownOverlays = append(dropdownOverlays, renderDropdownList(c.X, c.Y+c.Height, c.Width, c.Options, c.SelectedValue)...)
 			}
 
 		case DrawRadio:
@@ -746,6 +840,12 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 			bottom.Move(fyne.NewPos(float32(c.X), float32(c.Y+c.Height-1)))
 			objects = append(objects, bottom)
 		}
+		if len(clips) > 0 {
+			objects = append(objects[:start], clipObjects(objects[start:], clips)...)
+		}
+		if key != "" {
+			frame.keep(key, objects[start:], dropdownOverlays[overlayStart:])
+		}
 	}
 
 	// Append dropdown overlays at the end so they render on top of everything
@@ -754,47 +854,134 @@ func RenderToCanvas(commands []DisplayCommand, baseURL string, pageURL string, u
 	return objects
 }
 
-func fetchAndCreateImage(src, baseURL string, width, height float64) *canvas.Image {
-	fullURL := resolveImageURL(src, baseURL)
-	fmt.Println("Fetching image:", fullURL)
+// renderDeferredImage draws the placeholder for an image data saver mode
+// left unloaded.
+func renderDeferredImage(r layout.Rect, size int64) []fyne.CanvasObject {
+	bg := canvas.NewRectangle(color.RGBA{235, 235, 235, 255})
+	bg.StrokeColor = color.RGBA{200, 200, 200, 255}
+	bg.StrokeWidth = 1
+	bg.Resize(fyne.NewSize(float32(r.Width), float32(r.Height)))
+	bg.Move(fyne.NewPos(float32(r.X), float32(r.Y)))
+
+	label := canvas.NewText("⬇ "+deferredImageLabel(size), color.RGBA{90, 90, 90, 255})
+	label.TextSize = 12
+	label.Move(fyne.NewPos(float32(r.X)+6, float32(r.Y)+6))
+	return []fyne.CanvasObject{bg, label}
+}
 
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		fmt.Println("Error fetching image:", err)
-		return nil
+// renderBrokenImage draws an image that failed to load: a bordered box
+// with an icon and the alt text, cut to fit (see layout/brokenimage.go).
+// An image with an empty alt is decorative and draws nothing, and one too
+// small for the icon draws just its border.
+func renderBrokenImage(c DrawImage) []fyne.CanvasObject {
+	if c.Node != nil {
+		if alt, ok := c.Node.Attributes["alt"]; ok && alt == "" {
+			return nil
+		}
 	}
-	defer resp.Body.Close()
-
-	img, _, err := image.Decode(resp.Body)
-	if err != nil {
-		fmt.Println("Error decoding image:", err)
-		return nil
+	border := canvas.NewRectangle(color.Transparent)
+	border.StrokeColor = color.RGBA{192, 192, 192, 255}
+	border.StrokeWidth = 1
+	border.Resize(fyne.NewSize(float32(c.Width), float32(c.Height)))
+	border.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
+	objects := []fyne.CanvasObject{border}
+
+	size := c.AltSize
+	if size <= 0 {
+		size = SizeNormal
 	}
-
-	// Cache the image
-	imageCache[fullURL] = img
-
-	fyneImg := canvas.NewImageFromImage(img)
-	fyneImg.FillMode = canvas.ImageFillContain
-	fyneImg.Resize(fyne.NewSize(float32(width), float32(height)))
-
-	return fyneImg
+	pad := layout.BrokenImagePadding
+	if c.Width < layout.BrokenImageIconWidth+2*pad || c.Height < float64(size)+2*pad {
+		return objects
+	}
+	icon := canvas.NewText("🖼", color.RGBA{150, 150, 150, 255})
+	icon.TextSize = min(size, 12)
+	icon.Move(fyne.NewPos(float32(c.X+pad), float32(c.Y+pad)))
+	objects = append(objects, icon)
+
+	room := c.Width - layout.BrokenImageIconWidth - 2*pad
+	if alt := truncateTextClip(c.AltText, room, DrawText{Size: size}); alt != "" {
+		altText := canvas.NewText(alt, color.RGBA{100, 100, 100, 255})
+		altText.TextSize = size
+		altText.Move(fyne.NewPos(float32(c.X+pad+layout.BrokenImageIconWidth), float32(c.Y+pad)))
+		objects = append(objects, altText)
+	}
+	return objects
 }
 
-// createImageFromCache uses cached image data
-func createImageFromCache(src, baseURL string, width, height float64) *canvas.Image {
-	fullURL := resolveImageURL(src, baseURL)
+// clipObjects trims the objects drawn for one display command to the active
+// overflow clips. Rectangles and images are cut to the visible area (masked
+// where a rounded corner or a hole crosses them, though plain rectangles
+// are split around rectangular holes); text is already trimmed by the
+// painter, so other objects are only dropped when entirely outside.
+func clipObjects(objects []fyne.CanvasObject, clips clipStack) []fyne.CanvasObject {
+	var kept []fyne.CanvasObject
+	for _, obj := range objects {
+		pos, size := obj.Position(), obj.Size()
+		if size.Width <= 0 || size.Height <= 0 {
+			kept = append(kept, obj)
+			continue
+		}
+		r := layout.Rect{X: float64(pos.X), Y: float64(pos.Y), Width: float64(size.Width), Height: float64(size.Height)}
+		visible, ok := clips.clipRect(r)
+		if !ok {
+			continue
+		}
+		corner := clips.cutsCorner(visible)
+		if visible == r && !corner {
+			kept = append(kept, obj)
+			continue
+		}
 
-	// Check cache first
-	if cached, ok := imageCache[fullURL]; ok {
-		fyneImg := canvas.NewImageFromImage(cached)
-		fyneImg.FillMode = canvas.ImageFillContain
-		fyneImg.Resize(fyne.NewSize(float32(width), float32(height)))
-		return fyneImg
+		var img image.Image
+		switch o := obj.(type) {
+		case *canvas.Rectangle:
+			if !corner && o.CornerRadius == 0 {
+				o.Resize(fyne.NewSize(float32(visible.Width), float32(visible.Height)))
+				o.Move(fyne.NewPos(float32(visible.X), float32(visible.Y)))
+				kept = append(kept, o)
+				continue
+			}
+			if pieces, ok := clips.aroundHoles(visible); ok && o.CornerRadius == 0 {
+				for _, piece := range pieces {
+					fill := canvas.NewRectangle(o.FillColor)
+					fill.Resize(fyne.NewSize(float32(piece.Width), float32(piece.Height)))
+					fill.Move(fyne.NewPos(float32(piece.X), float32(piece.Y)))
+					kept = append(kept, fill)
+				}
+				continue
+			}
+			img, visible, ok = clips.clipFill(o.FillColor, r, float64(o.CornerRadius))
+		case *canvas.Image:
+			if o.Image == nil {
+				// Resource- and file-backed images cannot be cropped
+				kept = append(kept, o)
+				continue
+			}
+			img, visible, ok = clips.clipImage(o.Image, r)
+		default:
+			kept = append(kept, obj)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		ci := canvas.NewImageFromImage(img)
+		ci.FillMode = canvas.ImageFillStretch
+		ci.Resize(fyne.NewSize(float32(visible.Width), float32(visible.Height)))
+		ci.Move(fyne.NewPos(float32(visible.X), float32(visible.Y)))
+		kept = append(kept, ci)
 	}
+	return kept
+}
 
-	// Not cached, fetch it
-	return fetchAndCreateImage(src, baseURL, width, height)
+// cachedImage returns the decoded image cached for fullURL.
+func cachedImage(fullURL string) (image.Image, bool) {
+	if value, ok := utils.HTTPCache.Get(fullURL); ok {
+		img, ok := value.(image.Image)
+		return img, ok
+	}
+	return nil, false
 }
 
 func resolveImageURL(src, baseURL string) string {
@@ -839,112 +1026,98 @@ func isLocalFile(path string) bool {
 	return false
 }
 
-// renderBackgroundSized handles background-size modes (contain, cover, auto, explicit).
-// Creates a fresh canvas.Image with correct dimensions and appends to objects.
-// Returns true if handled (caller should continue), false to use default rendering.
-func renderBackgroundSized(img *canvas.Image, c DrawImage, objects *[]fyne.CanvasObject) bool {
-	bounds := img.Image.Bounds()
-	imgW := float64(bounds.Dx())
-	imgH := float64(bounds.Dy())
-	if imgW <= 0 || imgH <= 0 || c.Width <= 0 || c.Height <= 0 {
-		return false
+// face returns the Fyne text style and resolved primary font c is drawn in
+// (nil for the theme font). Layout measures text in the same faces.
+func (c DrawText) face() (fyne.TextStyle, fyne.Resource) {
+	style := fyne.TextStyle{
+		Bold:      css.IsBold(c.Weight),
+		Italic:    c.Italic,
+		Monospace: c.Monospace,
 	}
+	return style, resolveFontFamily(c.FontFamily, c.Weight, c.Italic)
+}
 
-	sizeMode := strings.ToLower(strings.TrimSpace(c.SizeMode))
-
-	switch sizeMode {
-	case "contain":
-		scaleX := c.Width / imgW
-		scaleY := c.Height / imgH
-		scale := scaleX
-		if scaleY < scaleX {
-			scale = scaleY
-		}
-		newW := float32(imgW * scale)
-		newH := float32(imgH * scale)
-		offsetX := float32(c.X) + (float32(c.Width)-newW)/2
-		offsetY := float32(c.Y) + (float32(c.Height)-newH)/2
-
-		sized := canvas.NewImageFromImage(img.Image)
-		sized.FillMode = canvas.ImageFillStretch
-		sized.SetMinSize(fyne.NewSize(newW, newH))
-		sized.Resize(fyne.NewSize(newW, newH))
-		sized.Move(fyne.NewPos(offsetX, offsetY))
-		*objects = append(*objects, sized)
-		return true
-
-	case "cover":
-		boxRatio := c.Width / c.Height
-		imgRatio := imgW / imgH
-		var cropRect image.Rectangle
-		if imgRatio > boxRatio {
-			newW := int(imgH * boxRatio)
-			offset := (int(imgW) - newW) / 2
-			cropRect = image.Rect(offset, 0, offset+newW, int(imgH))
-		} else {
-			newH := int(imgW / boxRatio)
-			offset := (int(imgH) - newH) / 2
-			cropRect = image.Rect(0, offset, int(imgW), offset+newH)
-		}
-		type subImager interface {
-			SubImage(r image.Rectangle) image.Image
-		}
-		srcImg := img.Image
-		if si, ok := srcImg.(subImager); ok {
-			srcImg = si.SubImage(cropRect)
+// textGlyphObjects lays out the glyphs of a text run at (x0, y0) in col,
+// applying the run's letter and word spacing.
+func textGlyphObjects(c DrawText, displayText string, x0, y0 float64, col color.Color) []fyne.CanvasObject {
+	var objects []fyne.CanvasObject
+	textStyle, primaryFont := c.face()
+	textSize := TextRaster.TextSize(c.Size)
+	switch {
+	case c.LetterSpacing == 0 && c.WordSpacing == 0:
+		originX, originY := TextRaster.GlyphOrigin(x0, y0)
+		objects = append(objects, newFallbackTextObjects(displayText, originX, originY, textSize, col, textStyle, primaryFont)...)
+	case c.LetterSpacing == 0 && c.WordSpacing != 0:
+		x := x0
+		for _, segment := range splitWordSpacingSegments(displayText) {
+			originX, originY := TextRaster.GlyphOrigin(x, y0)
+			objects = append(objects, newFallbackTextObjects(segment.text, originX, originY, textSize, col, textStyle, primaryFont)...)
+
+			segWidth := float64(measureTextWithFallback(segment.text, textSize, textStyle, primaryFont))
+			x += segWidth
+			if segment.isGap {
+				x += c.WordSpacing
+			}
 		}
-		sized := canvas.NewImageFromImage(srcImg)
-		sized.FillMode = canvas.ImageFillStretch
-		w := float32(c.Width)
-		h := float32(c.Height)
-		sized.SetMinSize(fyne.NewSize(w, h))
-		sized.Resize(fyne.NewSize(w, h))
-		sized.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
-		*objects = append(*objects, sized)
-		return true
-
-	case "auto":
-		sized := canvas.NewImageFromImage(img.Image)
-		sized.FillMode = canvas.ImageFillOriginal
-		sized.SetMinSize(fyne.NewSize(float32(imgW), float32(imgH)))
-		sized.Resize(fyne.NewSize(float32(imgW), float32(imgH)))
-		sized.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
-		*objects = append(*objects, sized)
-		return true
-
 	default:
-		// Explicit sizes like "200px 100px" or "200px"
-		var w, h float64
-		parts := strings.Fields(sizeMode)
-		if len(parts) == 2 {
-			w = css.ParseSize(parts[0])
-			h = css.ParseSize(parts[1])
-		} else if len(parts) == 1 {
-			w = css.ParseSize(parts[0])
-			if w > 0 && imgW > 0 {
-				h = w * (imgH / imgW)
+		// Letter spacing goes between grapheme clusters, so an accented
+		// letter or an emoji sequence is drawn whole
+		x := x0
+		clusters := layout.GraphemeClusters(displayText)
+		for i, ch := range clusters {
+			text := canvas.NewText(ch, col)
+			text.TextSize = textSize
+			text.TextStyle = textStyle
+			text.FontSource = clusterFont(ch, classifyCluster(ch), primaryFont)
+			originX, originY := TextRaster.GlyphOrigin(x, y0)
+			text.Move(fyne.NewPos(float32(originX), float32(originY)))
+			objects = append(objects, text)
+
+			x += float64(measureTextWithFallback(ch, textSize, textStyle, primaryFont))
+			if i < len(clusters)-1 {
+				x += c.LetterSpacing
+				if ch == " " || ch == "\t" {
+					x += c.WordSpacing
+				}
 			}
 		}
-		if w > 0 && h > 0 {
-			sized := canvas.NewImageFromImage(img.Image)
-			sized.FillMode = canvas.ImageFillStretch
-			sized.SetMinSize(fyne.NewSize(float32(w), float32(h)))
-			sized.Resize(fyne.NewSize(float32(w), float32(h)))
-			sized.Move(fyne.NewPos(float32(c.X), float32(c.Y)))
-			*objects = append(*objects, sized)
-			return true
-		}
 	}
+	return objects
+}
 
-	return false
+// renderBackground draws one copy of a background image per tile; the
+// enclosing PushClip trims tiles that run past the box.
+func renderBackground(src image.Image, c DrawImage) []fyne.CanvasObject {
+	bounds := src.Bounds()
+	area := c.Rect
+	if c.Area != (layout.Rect{}) {
+		area = c.Area
+	}
+	tiles := backgroundTiles(area, float64(bounds.Dx()), float64(bounds.Dy()), c.SizeMode, c.Position, c.Repeat)
+	objects := make([]fyne.CanvasObject, 0, len(tiles))
+	for _, tile := range tiles {
+		if !overlaps(tile, c.Rect) {
+			continue // positioned elsewhere, away from the box
+		}
+		img := canvas.NewImageFromImage(src)
+		img.FillMode = canvas.ImageFillStretch
+		img.SetMinSize(fyne.NewSize(float32(tile.Width), float32(tile.Height)))
+		img.Resize(fyne.NewSize(float32(tile.Width), float32(tile.Height)))
+		img.Move(fyne.NewPos(float32(tile.X), float32(tile.Y)))
+		objects = append(objects, img)
+	}
+	return objects
 }
 
 // toLocalPath converts a file:// URL to a filesystem path
-func toLocalPath(url string) string {
-	if strings.HasPrefix(url, "file://") {
-		return url[7:] // Remove "file://"
+func toLocalPath(rawURL string) string {
+	if strings.HasPrefix(rawURL, "file://") {
+		if u, err := url.Parse(rawURL); err == nil {
+			return filepath.FromSlash(u.Path) // unescaped, e.g. %20
+		}
+		return rawURL[7:] // Remove "file://"
 	}
-	return url
+	return rawURL
 }
 
 // loadLocalImage loads an image from the local filesystem
@@ -1012,32 +1185,57 @@ func fetchimageToCache(fullURL, referrerPolicy, pageURL string) (image.Image, er
 	var img image.Image
 	var err error
 
-	// Check if it's a local file
+	// Check if it's a local file, which only local pages may show
 	if isLocalFile(fullURL) {
+		if pageURL != "" && !strings.HasPrefix(pageURL, "file:") {
+			return nil, errors.New("Web pages cannot load local images")
+		}
 		img, err = loadLocalImage(fullURL)
 		if err != nil {
 			fmt.Println("Error loading local image:", err)
 			return nil, errors.New("Error loading local image")
 		}
 	} else {
-		// Remote URL - fetch via HTTP
-		resp, err := utils.DoRequest(utils.HTTPRequest{
+		// Remote URL - fetch via HTTP, after the page's stylesheets,
+		// scripts and fonts. The load is held while the image decodes, so
+		// a page of images decodes a few at a time.
+		resp, err := utils.Subresources.Load(utils.PriorityLow, utils.HTTPRequest{
 			Method:         "GET",
 			URL:            fullURL,
 			ReferrerPolicy: referrerPolicy,
 			FromURL:        pageURL,
+			Accept:         imageAccept,
 		})
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
 		if err != nil {
 			fmt.Println("Error fetching image:", err)
 			return nil, errors.New("Error fetching image")
 		}
 		defer resp.Body.Close()
 
-		data, err := io.ReadAll(resp.Body)
+		// Data saver mode defers large images by their declared size or,
+		// without one, once the download passes the limit
+		if resp.ContentLength >= 0 && dataSaver.shouldDefer(fullURL, resp.ContentLength) {
+			return nil, errImageDeferred
+		}
+		body := io.Reader(resp.Body)
+		if resp.ContentLength < 0 && dataSaver.applies(fullURL) {
+			body = io.LimitReader(resp.Body, DataSaverImageLimit+1)
+		}
+
+		data, err := io.ReadAll(body)
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
 		if err != nil {
 			fmt.Println("Error reading image data:", err)
 			return nil, errors.New("Error reading image data")
 		}
+		if resp.ContentLength < 0 && int64(len(data)) > DataSaverImageLimit && dataSaver.shouldDefer(fullURL, -1) {
+			return nil, errImageDeferred
+		}
 
 		contentType := resp.Header.Get("Content-Type")
 		if isSVG(fullURL, contentType) {
@@ -1053,13 +1251,21 @@ func fetchimageToCache(fullURL, referrerPolicy, pageURL string) (image.Image, er
 
 	}
 
-	imageCacheMu.Lock()
-	imageCache[fullURL] = img
-	imageCacheMu.Unlock()
+	utils.HTTPCache.Put(fullURL, img)
 	return img, nil
 }
 func getImageOrPlaceholder(req ImageRequest) (*canvas.Image, error) {
 	fullURL := resolveImageURL(req.Src, req.BaseURL)
+	// A page opened from disk finds its images relative to its folder
+	if strings.HasPrefix(req.PageURL, "file:") {
+		if page, err := url.Parse(req.PageURL); err == nil {
+			if ref, err := url.Parse(req.Src); err == nil {
+				fullURL = page.ResolveReference(ref).String()
+			}
+		}
+	}
+	// Whether the image had already failed when the page was last drawn
+	wasBroken := req.Node != nil && req.Node.ImageComplete && req.Node.NaturalWidth == 0
 	if req.Node != nil {
 		req.Node.NaturalWidth = 0
 		req.Node.NaturalHeight = 0
@@ -1073,9 +1279,11 @@ func getImageOrPlaceholder(req ImageRequest) (*canvas.Image, error) {
 		return nil, errors.New("Image src is empty")
 	}
 
-	imageCacheMu.Lock()
-	cached, found := imageCache[fullURL]
-	imageCacheMu.Unlock()
+	if size, deferred := dataSaver.deferredSize(fullURL); deferred {
+		return nil, deferredImageError{size: size}
+	}
+
+	cached, found := cachedImage(fullURL)
 
 	if found {
 		setImageNaturalSize(req.Node, cached)
@@ -1095,6 +1303,11 @@ func getImageOrPlaceholder(req ImageRequest) (*canvas.Image, error) {
 		if req.Node != nil {
 			req.Node.ImageComplete = true
 			req.Node.CurrentSrc = fullURL
+			// An image that failed on another page is laid out as loading
+			// until told it is broken, once
+			if !wasBroken && req.OnLoad != nil {
+				fyne.Do(req.OnLoad)
+			}
 		}
 		return nil, errors.New("Previously failed to load image")
 	}
@@ -1110,8 +1323,17 @@ func getImageOrPlaceholder(req ImageRequest) (*canvas.Image, error) {
 	if !alreadyFetching {
 		go func() {
 			img, err := fetchimageToCache(fullURL, req.ReferrerPolicy, req.PageURL)
+			if errors.Is(err, context.Canceled) {
+				// The page was left; the next to show it fetches it again
+				pendingMu.Lock()
+				delete(pendingFeteches, fullURL)
+				pendingMu.Unlock()
+				return
+			}
 
-			if err != nil {
+			// A deferred image is not a failure: its placeholder shows
+			// until the user asks for it
+			if err != nil && !errors.Is(err, errImageDeferred) {
 				failedMu.Lock()
 				failedImages[fullURL] = true
 				failedMu.Unlock()
@@ -1153,51 +1375,28 @@ func setImageNaturalSize(node *dom.Node, img image.Image) {
 	node.NaturalHeight = bounds.Dy()
 }
 
-func decodeSVG(data []byte) (image.Image, error) {
-	icon, err := oksvg.ReadIconStream(bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-
-	w := int(icon.ViewBox.W)
-	h := int(icon.ViewBox.H)
-	if w <= 0 {
-		w = 64
-	}
-	if h <= 0 {
-		h = 64
-	}
-
-	icon.SetTarget(0, 0, float64(w), float64(h))
-
-	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
-	scanner := rasterx.NewScannerGV(w, h, rgba, rgba.Bounds())
-	raster := rasterx.NewDasher(w, h, scanner)
-	icon.Draw(raster, 1.0)
-
-	return rgba, nil
-}
-
-// measureTextWidth returns the pixel width of text accounting for letter-spacing and word-spacing.
-func measureTextWidth(text string, fontSize float32, letterSpacing, wordSpacing float64) float64 {
+// measureTextWidth returns the pixel width of text drawn as c, in its face
+// and size, accounting for letter-spacing and word-spacing.
+func measureTextWidth(text string, c DrawText) float64 {
 	if text == "" {
 		return 0
 	}
-	baseWidth := float64(fyne.MeasureText(text, fontSize, fyne.TextStyle{}).Width)
-	if letterSpacing != 0 {
+	style, primary := c.face()
+	baseWidth := float64(measureTextWithFallback(text, TextRaster.TextSize(c.Size), style, primary))
+	if c.LetterSpacing != 0 {
 		// letter-spacing adds extra space after each character except the last
-		baseWidth += letterSpacing * float64(len([]rune(text))-1)
+		baseWidth += c.LetterSpacing * float64(layout.ClusterCount(text)-1)
 	}
-	if wordSpacing != 0 {
+	if c.WordSpacing != 0 {
 		spaces := strings.Count(text, " ")
-		baseWidth += wordSpacing * float64(spaces)
+		baseWidth += c.WordSpacing * float64(spaces)
 	}
 	return baseWidth
 }
 
-func truncateTextWithEllipsis(text string, maxWidth float64, fontSize float32, letterSpacing, wordSpacing float64) string {
+func truncateTextWithEllipsis(text string, maxWidth float64, c DrawText) string {
 	ellipsis := "..."
-	ellipsisWidth := measureTextWidth(ellipsis, fontSize, letterSpacing, wordSpacing)
+	ellipsisWidth := measureTextWidth(ellipsis, c)
 
 	if maxWidth <= ellipsisWidth {
 		return ellipsis
@@ -1208,7 +1407,7 @@ func truncateTextWithEllipsis(text string, maxWidth float64, fontSize float32, l
 
 	for len(result) > 0 {
 		candidate := string(result)
-		w := measureTextWidth(candidate, fontSize, letterSpacing, wordSpacing)
+		w := measureTextWidth(candidate, c)
 		if w <= availableWidth {
 			return candidate + ellipsis
 		}
@@ -1219,11 +1418,11 @@ func truncateTextWithEllipsis(text string, maxWidth float64, fontSize float32, l
 }
 
 // truncateTextClipLeft removes leading characters until the removed portion is at least clipOffset wide.
-func truncateTextClipLeft(text string, clipOffset float64, fontSize float32, letterSpacing, wordSpacing float64) string {
+func truncateTextClipLeft(text string, clipOffset float64, c DrawText) string {
 	runes := []rune(text)
 	for i := 1; i <= len(runes); i++ {
 		removed := string(runes[:i])
-		w := measureTextWidth(removed, fontSize, letterSpacing, wordSpacing)
+		w := measureTextWidth(removed, c)
 		if w >= clipOffset {
 			return string(runes[i:])
 		}
@@ -1231,12 +1430,12 @@ func truncateTextClipLeft(text string, clipOffset float64, fontSize float32, let
 	return ""
 }
 
-func truncateTextClip(text string, maxWidth float64, fontSize float32, letterSpacing, wordSpacing float64) string {
+func truncateTextClip(text string, maxWidth float64, c DrawText) string {
 	result := []rune(text)
 
 	for len(result) > 0 {
 		candidate := string(result)
-		w := measureTextWidth(candidate, fontSize, letterSpacing, wordSpacing)
+		w := measureTextWidth(candidate, c)
 		if w <= maxWidth {
 			return candidate
 		}
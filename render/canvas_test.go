@@ -2,11 +2,13 @@ package render
 
 import (
 	"browser/css"
+	"browser/dom"
 	"browser/layout"
 	"image/color"
 	"strings"
 	"testing"
 
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/test"
 	"github.com/stretchr/testify/assert"
@@ -51,6 +53,7 @@ func TestToLocalPath(t *testing.T) {
 	}{
 		{"file protocol", "file:///home/user/image.png", "/home/user/image.png"},
 		{"file protocol root", "file:///image.png", "/image.png"},
+		{"file protocol escaped", "file:///home/user/my%20site/image.png", "/home/user/my site/image.png"},
 		{"already absolute path", "/home/user/image.png", "/home/user/image.png"},
 		{"relative path unchanged", "images/bg.png", "images/bg.png"},
 		{"http url unchanged", "http://example.com/img.png", "http://example.com/img.png"},
@@ -124,6 +127,51 @@ func TestIsSVG(t *testing.T) {
 	}
 }
 
+func TestRenderBrokenImage(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+
+	texts := func(objects []fyne.CanvasObject) []string {
+		var texts []string
+		for _, obj := range objects {
+			if text, ok := obj.(*canvas.Text); ok {
+				texts = append(texts, text.Text)
+			}
+		}
+		return texts
+	}
+	node := dom.NewElement("img", map[string]string{"alt": "A long description"})
+	broken := DrawImage{Rect: layout.Rect{X: 10, Y: 20, Width: 300, Height: 40}, AltText: node.Attributes["alt"], AltSize: 16, Node: node}
+
+	objects := renderBrokenImage(broken)
+	border := objects[0].(*canvas.Rectangle)
+	assert.Equal(t, fyne.NewPos(10, 20), border.Position())
+	assert.Equal(t, fyne.NewSize(300, 40), border.Size())
+	assert.NotZero(t, border.StrokeWidth)
+	assert.Equal(t, []string{"🖼", "A long description"}, texts(objects))
+
+	t.Run("alt text cut to the box", func(t *testing.T) {
+		narrow := broken
+		narrow.Width = 100
+		got := texts(renderBrokenImage(narrow))
+		assert.Len(t, got, 2)
+		assert.True(t, strings.HasPrefix("A long description", got[1]))
+		assert.Less(t, len(got[1]), len("A long description"))
+	})
+
+	t.Run("too small for the icon", func(t *testing.T) {
+		tiny := broken
+		tiny.Width, tiny.Height = 1, 1
+		assert.Len(t, renderBrokenImage(tiny), 1)
+	})
+
+	t.Run("empty alt draws nothing", func(t *testing.T) {
+		decorative := broken
+		decorative.Node = dom.NewElement("img", map[string]string{"alt": ""})
+		assert.Empty(t, renderBrokenImage(decorative))
+	})
+}
+
 func TestRenderToCanvasDrawTextLetterSpacing(t *testing.T) {
 	app := test.NewApp()
 	defer app.Quit()
@@ -446,7 +494,7 @@ func TestTruncateTextClipLeft(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := truncateTextClipLeft(tt.text, tt.clipOffset, 14, 0, 0)
+			result := truncateTextClipLeft(tt.text, tt.clipOffset, DrawText{Size: 14})
 			if tt.clipOffset > 99000 {
 				assert.Equal(t, "", result)
 			} else {
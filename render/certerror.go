@@ -0,0 +1,106 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"browser/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Certificate errors. A page whose server's certificate fails validation is
+// not shown; an interstitial explains why, and offers a way back and a way
+// on. Its buttons are the browser's own widgets rather than page content,
+// so no page can press them. The certificate of the page shown is kept for
+// the embedding UI's padlock.
+
+// ShowCertificateError shows, in place of the page req asked for, why its
+// server's certificate was not trusted. Proceeding trusts that certificate
+// for the rest of the session and loads the page again.
+func (b *Browser) ShowCertificateError(req NavigationRequest, certErr *utils.CertificateError) {
+	fyne.Do(func() {
+		bg := canvas.NewRectangle(ColorWhite)
+		bg.Resize(fyne.NewSize(b.Width, b.Height))
+
+		title := canvas.NewText("Your connection is not private", color.RGBA{200, 0, 0, 255})
+		title.TextSize = 24
+		title.TextStyle = fyne.TextStyle{Bold: true}
+
+		summary := widget.NewLabel(fmt.Sprintf("%s\nSomeone may be trying to read or change what you exchange with %s.",
+			certErr.Reason(), certErr.Host))
+		summary.Wrapping = fyne.TextWrapWord
+
+		details := widget.NewLabel(strings.Join(certificateDetails(certErr.Certificate), "\n"))
+		details.TextStyle = fyne.TextStyle{Monospace: true}
+
+		back := widget.NewButton("Back to safety", func() {
+			if b.currentURL != nil {
+				b.Refresh()
+			} else if b.OnNavigate != nil {
+				go b.OnNavigate(NavigationRequest{URL: BlankURL, Method: "GET"})
+			}
+		})
+		back.Importance = widget.HighImportance
+		proceed := widget.NewButton(fmt.Sprintf("Proceed to %s (unsafe)", certErr.Host), func() {
+			utils.AllowCertificate(certErr.Host, certErr.Certificate)
+			if b.OnNavigate != nil {
+				go b.OnNavigate(req)
+			}
+		})
+		proceed.Importance = widget.DangerImportance
+
+		content := container.NewVBox(title, summary, details, container.NewHBox(back, proceed))
+		width := min(b.Width-48, 640)
+		centered := container.NewCenter(container.NewGridWrap(fyne.NewSize(width, content.MinSize().Height), content))
+		stack := container.NewStack(bg, centered)
+
+		b.content.Objects = []fyne.CanvasObject{stack}
+		b.content.Refresh()
+	})
+}
+
+// certificateDetails describes a certificate line by line: who it was
+// issued to and by, when it is valid, the names it covers and its
+// fingerprint.
+func certificateDetails(info utils.CertificateInfo) []string {
+	lines := []string{
+		"Issued to:   " + info.Subject,
+		"Issued by:   " + info.Issuer,
+		"Valid from:  " + info.NotBefore.Format("2006-01-02"),
+		"Valid until: " + info.NotAfter.Format("2006-01-02"),
+	}
+	if len(info.Names) > 0 {
+		lines = append(lines, "Names:       "+strings.Join(info.Names, ", "))
+	}
+	lines = append(lines, "SHA-256:     "+info.Fingerprint)
+	if info.Error != "" {
+		lines = append(lines, "Warning:     "+info.Error)
+	}
+	return lines
+}
+
+// Certificate returns the certificate of the page's server, nil when the
+// page was not loaded over https.
+func (b *Browser) Certificate() *utils.CertificateInfo {
+	return b.certificate
+}
+
+// SetCertificateHandler sets the callback told of each page's certificate,
+// nil when it was not loaded over https, for the embedding UI's padlock.
+// CertificateInfo.Trusted tells a valid certificate from one the user
+// proceeded past.
+func (b *Browser) SetCertificateHandler(handler func(*utils.CertificateInfo)) {
+	b.onCertificate = handler
+}
+
+func (b *Browser) setCertificate(info *utils.CertificateInfo) {
+	b.certificate = info
+	if b.onCertificate != nil {
+		b.onCertificate(info)
+	}
+}
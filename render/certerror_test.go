@@ -0,0 +1,45 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"browser/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateDetails(t *testing.T) {
+	info := utils.CertificateInfo{
+		Subject:     "example.com",
+		Issuer:      "Example CA",
+		NotBefore:   time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		NotAfter:    time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		Names:       []string{"example.com", "www.example.com"},
+		Fingerprint: "ab12",
+	}
+	assert.Equal(t, []string{
+		"Issued to:   example.com",
+		"Issued by:   Example CA",
+		"Valid from:  2025-01-02",
+		"Valid until: 2026-01-02",
+		"Names:       example.com, www.example.com",
+		"SHA-256:     ab12",
+	}, certificateDetails(info))
+
+	info.Names = nil
+	info.Error = "Trusted by you despite failing validation."
+	lines := certificateDetails(info)
+	assert.Len(t, lines, 6)
+	assert.Equal(t, "Warning:     Trusted by you despite failing validation.", lines[5])
+}
+
+func TestCertificateHandler(t *testing.T) {
+	b := &Browser{}
+	var got []*utils.CertificateInfo
+	b.SetCertificateHandler(func(info *utils.CertificateInfo) { got = append(got, info) })
+
+	b.SetCurrentURL("http://example.com/")
+	assert.Nil(t, b.Certificate(), "not loaded over https")
+	assert.Equal(t, []*utils.CertificateInfo{nil}, got)
+}
@@ -21,6 +21,7 @@ type ClickableContainer struct {
 	onMouseUp   func(x, y float32)
 	onDrag      func(x, y float32)
 	onDragEnd   func()
+	onScroll    func(ev *fyne.ScrollEvent)
 
 	browser *Browser // Reference to browser for tooltip support
 }
@@ -71,7 +72,7 @@ func (c *ClickableContainer) MouseMoved(event *desktop.MouseEvent) {
 
 	var hit *layout.LayoutBox
 	if c.browser != nil {
-		hit = c.browser.hitTestWithFixedPriority(float64(event.Position.X), float64(event.Position.Y))
+		hit = c.browser.hitTestWithFixedPriority(c.browser.toPage(event.Position.X, event.Position.Y))
 	} else {
 		hit = c.layoutTree.HitTest(float64(event.Position.X), float64(event.Position.Y))
 	}
@@ -86,6 +87,8 @@ func (c *ClickableContainer) MouseMoved(event *desktop.MouseEvent) {
 		// Hovered element changed
 		c.browser.hideTooltip()
 		c.browser.hoveredNode = hoveredNode
+		c.browser.onHoverChanged()
+		c.browser.updateHeadingAnchor(hoveredNode)
 
 		// Check for title attribute on this node or ancestors
 		if hoveredNode != nil {
@@ -156,6 +159,14 @@ func (c *ClickableContainer) DragEnd() {
 	}
 }
 
+// Scrolled routes wheel events through onScroll, which scrolls inner scroll
+// containers before the page.
+func (c *ClickableContainer) Scrolled(event *fyne.ScrollEvent) {
+	if c.onScroll != nil {
+		c.onScroll(event)
+	}
+}
+
 // clickableRenderer handles drawing
 type clickableRenderer struct {
 	container *ClickableContainer
@@ -0,0 +1,291 @@
+package render
+
+import (
+	"browser/layout"
+	"image"
+	"image/color"
+	"math"
+)
+
+// PushClip restricts every following command to its rect, rounded by the
+// corner radii, until the matching PopClip. An Exclude clip keeps what lies
+// outside the rounded rect instead, cutting a hole into what follows.
+type PushClip struct {
+	layout.Rect
+	TopLeftRadius     float64
+	TopRightRadius    float64
+	BottomRightRadius float64
+	BottomLeftRadius  float64
+	Exclude           bool
+}
+
+// PopClip ends the most recent PushClip.
+type PopClip struct{}
+
+// clipsOverflow reports whether a box hides content that overflows its padding
+// box. overflow: auto and scroll clip as well; scrolling only moves the
+// content underneath the clip.
+func clipsOverflow(box *layout.LayoutBox) bool {
+	switch box.Type {
+	case layout.BlockBox, layout.TableCellBox, layout.TableBox, layout.FieldsetBox:
+	default:
+		return false
+	}
+	x, y := box.Style.EffectiveOverflowX(), box.Style.EffectiveOverflowY()
+	return (x != "" && x != "visible") || (y != "" && y != "visible")
+}
+
+// borderRadii returns the box's outer corner radii (top-left, top-right,
+// bottom-right, bottom-left), falling back to the border-radius shorthand.
+func borderRadii(box *layout.LayoutBox) (tl, tr, br, bl float64) {
+	tl = box.Style.BorderTopLeftRadius
+	tr = box.Style.BorderTopRightRadius
+	br = box.Style.BorderBottomRightRadius
+	bl = box.Style.BorderBottomLeftRadius
+	if tl == 0 && tr == 0 && br == 0 && bl == 0 {
+		r := box.Style.BorderRadius
+		return r, r, r, r
+	}
+	return tl, tr, br, bl
+}
+
+// backgroundClip clips a box's background to its border box.
+func backgroundClip(box *layout.LayoutBox, rect layout.Rect) PushClip {
+	tl, tr, br, bl := borderRadii(box)
+	return PushClip{Rect: rect, TopLeftRadius: tl, TopRightRadius: tr, BottomRightRadius: br, BottomLeftRadius: bl}
+}
+
+// backgroundRect fills a box's border box with col, rounded by its radii.
+func backgroundRect(box *layout.LayoutBox, rect layout.Rect, col color.Color) DrawRect {
+	tl, tr, br, bl := borderRadii(box)
+	return DrawRect{
+		Rect:              rect,
+		Color:             col,
+		CornerRadius:      box.Style.BorderRadius,
+		TopLeftRadius:     tl,
+		TopRightRadius:    tr,
+		BottomRightRadius: br,
+		BottomLeftRadius:  bl,
+	}
+}
+
+// paddingClip clips to a box's padding box, whose corners are the border
+// radii shrunk by the adjacent border widths (CSS Backgrounds §5.3).
+func paddingClip(box *layout.LayoutBox, rect layout.Rect) PushClip {
+	s := box.Style
+	tl, tr, br, bl := borderRadii(box)
+	return PushClip{
+		Rect: layout.Rect{
+			X:      rect.X + s.BorderLeftWidth,
+			Y:      rect.Y + s.BorderTopWidth,
+			Width:  rect.Width - s.BorderLeftWidth - s.BorderRightWidth,
+			Height: rect.Height - s.BorderTopWidth - s.BorderBottomWidth,
+		},
+		TopLeftRadius:     math.Max(0, tl-math.Max(s.BorderTopWidth, s.BorderLeftWidth)),
+		TopRightRadius:    math.Max(0, tr-math.Max(s.BorderTopWidth, s.BorderRightWidth)),
+		BottomRightRadius: math.Max(0, br-math.Max(s.BorderBottomWidth, s.BorderRightWidth)),
+		BottomLeftRadius:  math.Max(0, bl-math.Max(s.BorderBottomWidth, s.BorderLeftWidth)),
+	}
+}
+
+// overflowClip clips a box's content to its padding box, rounded corners
+// included. Room taken by the box's scrollbars is excluded.
+func overflowClip(box *layout.LayoutBox, rect layout.Rect, style TextStyle) PushClip {
+	clip := paddingClip(box, rect)
+	if needsVerticalScrollbar(box, style) {
+		clip.Width -= ScrollbarWidth
+	}
+	if needsHorizontalScrollbar(box, style) {
+		clip.Height -= ScrollbarHeight
+	}
+	return clip
+}
+
+// borderClips returns the clips that shape a box's border to its rounded
+// corners: the border box with the padding box cut out, so the edges follow
+// both the outer and the inner curve. Boxes without rounded corners, and
+// collapsed table borders, which border-radius does not apply to, get none.
+func borderClips(box *layout.LayoutBox, rect layout.Rect) []DisplayCommand {
+	outer := backgroundClip(box, rect)
+	if !outer.rounded() || box.CollapsedBorders {
+		return nil
+	}
+	hole := paddingClip(box, rect)
+	hole.Exclude = true
+	return []DisplayCommand{outer, hole}
+}
+
+// clipStack holds the clips in effect while rendering; content stays visible
+// only where it lies inside all of them.
+type clipStack []PushClip
+
+// rounded reports whether the clip has any rounded corner.
+func (c PushClip) rounded() bool {
+	return c.TopLeftRadius > 0 || c.TopRightRadius > 0 || c.BottomRightRadius > 0 || c.BottomLeftRadius > 0
+}
+
+// contains reports whether the canvas point (x, y) is kept by the clip.
+func (c PushClip) contains(x, y float64) bool {
+	inside := x >= c.X && y >= c.Y && x < c.X+c.Width && y < c.Y+c.Height &&
+		insideRoundedRectAt(x-c.X, y-c.Y, c.Width, c.Height,
+			c.TopLeftRadius, c.TopRightRadius, c.BottomRightRadius, c.BottomLeftRadius)
+	return inside != c.Exclude
+}
+
+// contains reports whether the canvas point (x, y) is inside every clip.
+func (s clipStack) contains(x, y float64) bool {
+	for _, c := range s {
+		if !c.contains(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// clipRect intersects r with every clip's rectangle. ok is false when
+// nothing of r remains visible. Exclude clips are left to the masking.
+func (s clipStack) clipRect(r layout.Rect) (visible layout.Rect, ok bool) {
+	left, top := r.X, r.Y
+	right, bottom := r.X+r.Width, r.Y+r.Height
+	for _, c := range s {
+		if c.Exclude {
+			continue
+		}
+		left = math.Max(left, c.X)
+		top = math.Max(top, c.Y)
+		right = math.Min(right, c.X+c.Width)
+		bottom = math.Min(bottom, c.Y+c.Height)
+	}
+	if right <= left || bottom <= top {
+		return layout.Rect{}, false
+	}
+	return layout.Rect{X: left, Y: top, Width: right - left, Height: bottom - top}, true
+}
+
+// cutsCorner reports whether r reaches into a rounded corner of any clip,
+// or into the hole of an Exclude clip, where trimming the rectangle alone is
+// not enough.
+func (s clipStack) cutsCorner(r layout.Rect) bool {
+	for _, c := range s {
+		if c.Exclude {
+			if overlaps(r, c.Rect) {
+				return true
+			}
+			continue
+		}
+		if !c.rounded() {
+			continue
+		}
+		corners := []layout.Rect{
+			{X: c.X, Y: c.Y, Width: c.TopLeftRadius, Height: c.TopLeftRadius},
+			{X: c.X + c.Width - c.TopRightRadius, Y: c.Y, Width: c.TopRightRadius, Height: c.TopRightRadius},
+			{X: c.X + c.Width - c.BottomRightRadius, Y: c.Y + c.Height - c.BottomRightRadius, Width: c.BottomRightRadius, Height: c.BottomRightRadius},
+			{X: c.X, Y: c.Y + c.Height - c.BottomLeftRadius, Width: c.BottomLeftRadius, Height: c.BottomLeftRadius},
+		}
+		for _, corner := range corners {
+			if corner.Width > 0 && overlaps(r, corner) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// aroundHoles returns the parts of r outside the holes of the Exclude
+// clips, so a plain fill can be split around them rather than masked. ok is
+// false when a hole or another clip's corner is rounded where it crosses r.
+func (s clipStack) aroundHoles(r layout.Rect) (pieces []layout.Rect, ok bool) {
+	pieces = []layout.Rect{r}
+	for _, c := range s {
+		if !c.Exclude {
+			if (clipStack{c}).cutsCorner(r) {
+				return nil, false
+			}
+			continue
+		}
+		if c.rounded() && overlaps(r, c.Rect) {
+			return nil, false
+		}
+		var rest []layout.Rect
+		for _, piece := range pieces {
+			rest = append(rest, subtractRect(piece, c.Rect)...)
+		}
+		pieces = rest
+	}
+	return pieces, true
+}
+
+// subtractRect returns what is left of r outside hole, as up to four
+// rectangles: above, below, left and right of it.
+func subtractRect(r, hole layout.Rect) []layout.Rect {
+	if !overlaps(r, hole) {
+		return []layout.Rect{r}
+	}
+	var parts []layout.Rect
+	top, bottom := max(r.Y, hole.Y), min(r.Y+r.Height, hole.Y+hole.Height)
+	left, right := hole.X, hole.X+hole.Width
+	if top > r.Y {
+		parts = append(parts, layout.Rect{X: r.X, Y: r.Y, Width: r.Width, Height: top - r.Y})
+	}
+	if bottom < r.Y+r.Height {
+		parts = append(parts, layout.Rect{X: r.X, Y: bottom, Width: r.Width, Height: r.Y + r.Height - bottom})
+	}
+	if left > r.X {
+		parts = append(parts, layout.Rect{X: r.X, Y: top, Width: left - r.X, Height: bottom - top})
+	}
+	if right < r.X+r.Width {
+		parts = append(parts, layout.Rect{X: right, Y: top, Width: r.X + r.Width - right, Height: bottom - top})
+	}
+	return parts
+}
+
+// overlaps reports whether two rectangles share any area.
+func overlaps(a, b layout.Rect) bool {
+	return a.X < b.X+b.Width && b.X < a.X+a.Width && a.Y < b.Y+b.Height && b.Y < a.Y+a.Height
+}
+
+// rasterize paints the visible part of dst into an image at device
+// resolution, sampling each pixel's center from at with coordinates relative
+// to dst. Pixels outside the clips are left transparent. It returns the image
+// and the canvas rect it covers.
+func (s clipStack) rasterize(dst layout.Rect, at func(x, y float64) color.Color) (image.Image, layout.Rect, bool) {
+	visible, ok := s.clipRect(dst)
+	if !ok {
+		return nil, layout.Rect{}, false
+	}
+	scale := TextRaster.DeviceScale()
+	w, h := int(math.Ceil(visible.Width*scale)), int(math.Ceil(visible.Height*scale))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for py := 0; py < h; py++ {
+		for px := 0; px < w; px++ {
+			x, y := visible.X+(float64(px)+0.5)/scale, visible.Y+(float64(py)+0.5)/scale
+			if s.contains(x, y) {
+				img.Set(px, py, at(x-dst.X, y-dst.Y))
+			}
+		}
+	}
+	return img, visible, true
+}
+
+// clipImage crops src, stretched over dst, to the clips.
+func (s clipStack) clipImage(src image.Image, dst layout.Rect) (image.Image, layout.Rect, bool) {
+	b := src.Bounds()
+	if b.Empty() || dst.Width <= 0 || dst.Height <= 0 {
+		return nil, layout.Rect{}, false
+	}
+	scaleX := float64(b.Dx()) / dst.Width
+	scaleY := float64(b.Dy()) / dst.Height
+	return s.rasterize(dst, func(x, y float64) color.Color {
+		return src.At(b.Min.X+int(x*scaleX), b.Min.Y+int(y*scaleY))
+	})
+}
+
+// clipFill crops a rectangle of color col with uniform corner radius to the clips.
+func (s clipStack) clipFill(col color.Color, dst layout.Rect, radius float64) (image.Image, layout.Rect, bool) {
+	return s.rasterize(dst, func(x, y float64) color.Color {
+		if radius > 0 && !insideRoundedRectAt(x, y, dst.Width, dst.Height, radius, radius, radius, radius) {
+			return color.Transparent
+		}
+		return col
+	})
+}
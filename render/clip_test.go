@@ -0,0 +1,238 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"browser/layout"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clipEvents lists the clip commands and the background rects named by
+// their width, in paint order.
+func clipEvents(commands []DisplayCommand, names map[float64]string) []string {
+	var events []string
+	for _, cmd := range commands {
+		switch c := cmd.(type) {
+		case PushClip:
+			events = append(events, "push")
+		case PopClip:
+			events = append(events, "pop")
+		case DrawRect:
+			if name, ok := names[c.Width]; ok {
+				events = append(events, name)
+			}
+		case DrawImage:
+			events = append(events, "image")
+		}
+	}
+	return events
+}
+
+func TestOverflowClipCommands(t *testing.T) {
+	names := map[float64]string{101: "box", 113: "box", 102: "child", 103: "after"}
+	base := `div { height: 20px; background: gray; } #box { width: 101px; } #child { width: 102px; height: 50px; } #after { width: 103px; } `
+
+	tests := []struct {
+		name string
+		css  string
+		want []string
+	}{
+		{
+			name: "visible overflow paints without clips",
+			want: []string{"box", "child", "after"},
+		},
+		{
+			name: "hidden overflow clips children but not the box itself",
+			css:  `#box { overflow: hidden; }`,
+			want: []string{"box", "push", "child", "pop", "after"},
+		},
+		{
+			// The box grows by the width of its scrollbar
+			name: "auto overflow clips too",
+			css:  `#box { overflow-y: auto; }`,
+			want: []string{"box", "push", "child", "pop", "after"},
+		},
+		{
+			name: "background images are clipped to the box",
+			css:  `#box { background-image: url(a.png); }`,
+			want: []string{"box", "push", "image", "pop", "child", "after"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := `<div id="box"><div id="child"></div></div><div id="after"></div>`
+			root := buildLayout(html, base+tt.css, 800)
+			commands := BuildDisplayList(root, InputState{}, LinkStyler{})
+			assert.Equal(t, tt.want, clipEvents(commands, names))
+		})
+	}
+}
+
+func TestOverflowClipRect(t *testing.T) {
+	root := buildLayout(`<div id="box"><p>text</p></div>`,
+		`#box { width: 200px; height: 100px; overflow: hidden; background: gray; border: 4px solid black; border-radius: 10px; }`, 800)
+	var border layout.Rect
+	var clip PushClip
+	for _, cmd := range BuildDisplayList(root, InputState{}, LinkStyler{}) {
+		switch c := cmd.(type) {
+		case DrawRect:
+			if c.TopLeftRadius == 10 {
+				border = c.Rect
+			}
+		case PushClip:
+			clip = c
+		}
+	}
+
+	// The clip is the padding box, its radii shrunk by the border width
+	assert.Equal(t, layout.Rect{X: border.X + 4, Y: border.Y + 4, Width: border.Width - 8, Height: border.Height - 8}, clip.Rect)
+	assert.Equal(t, 6.0, clip.TopLeftRadius)
+	assert.Equal(t, 6.0, clip.BottomRightRadius)
+}
+
+func TestClipStack(t *testing.T) {
+	clips := clipStack{
+		{Rect: layout.Rect{X: 0, Y: 0, Width: 100, Height: 100}},
+		{Rect: layout.Rect{X: 50, Y: 50, Width: 100, Height: 100}},
+	}
+
+	t.Run("rects are intersected with every clip", func(t *testing.T) {
+		visible, ok := clips.clipRect(layout.Rect{X: 20, Y: 20, Width: 60, Height: 60})
+		assert.True(t, ok)
+		assert.Equal(t, layout.Rect{X: 50, Y: 50, Width: 30, Height: 30}, visible)
+	})
+
+	t.Run("rects outside a clip are invisible", func(t *testing.T) {
+		_, ok := clips.clipRect(layout.Rect{X: 0, Y: 0, Width: 40, Height: 40})
+		assert.False(t, ok)
+	})
+
+	t.Run("points must be inside every clip", func(t *testing.T) {
+		assert.True(t, clips.contains(60, 60))
+		assert.False(t, clips.contains(20, 20))
+		assert.False(t, clips.contains(120, 120))
+	})
+}
+
+func TestRoundedClip(t *testing.T) {
+	clips := clipStack{{
+		Rect:          layout.Rect{X: 10, Y: 10, Width: 40, Height: 40},
+		TopLeftRadius: 20,
+	}}
+
+	t.Run("corner pixels are outside the clip", func(t *testing.T) {
+		assert.False(t, clips.contains(11, 11))
+		assert.True(t, clips.contains(30, 30))
+		assert.True(t, clips.contains(48, 11))
+	})
+
+	t.Run("only rects reaching a rounded corner need masking", func(t *testing.T) {
+		assert.True(t, clips.cutsCorner(layout.Rect{X: 0, Y: 0, Width: 20, Height: 20}))
+		assert.False(t, clips.cutsCorner(layout.Rect{X: 35, Y: 10, Width: 10, Height: 40}))
+	})
+
+	t.Run("fills are masked by the corner", func(t *testing.T) {
+		img, visible, ok := clips.clipFill(color.RGBA{255, 0, 0, 255}, layout.Rect{X: 0, Y: 0, Width: 60, Height: 60}, 0)
+		assert.True(t, ok)
+		assert.Equal(t, layout.Rect{X: 10, Y: 10, Width: 40, Height: 40}, visible)
+		_, _, _, a := img.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0), a)
+		_, _, _, a = img.At(20, 20).RGBA()
+		assert.Equal(t, uint32(0xffff), a)
+	})
+}
+
+func TestExcludeClip(t *testing.T) {
+	clips := clipStack{
+		{Rect: layout.Rect{X: 0, Y: 0, Width: 100, Height: 100}},
+		{Rect: layout.Rect{X: 10, Y: 10, Width: 80, Height: 80}, TopLeftRadius: 20, Exclude: true},
+	}
+
+	t.Run("points inside the hole are cut out", func(t *testing.T) {
+		assert.True(t, clips.contains(5, 50))
+		assert.False(t, clips.contains(50, 50))
+		assert.True(t, clips.contains(12, 12), "outside the hole's rounded corner")
+		assert.False(t, clips.contains(120, 5))
+	})
+
+	t.Run("the hole does not shrink rects but needs masking", func(t *testing.T) {
+		visible, ok := clips.clipRect(layout.Rect{X: 0, Y: 0, Width: 100, Height: 5})
+		assert.True(t, ok)
+		assert.Equal(t, layout.Rect{X: 0, Y: 0, Width: 100, Height: 5}, visible)
+		assert.False(t, clips.cutsCorner(visible))
+		assert.True(t, clips.cutsCorner(layout.Rect{X: 0, Y: 0, Width: 100, Height: 20}))
+	})
+
+	t.Run("plain fills are split around square holes", func(t *testing.T) {
+		square := clipStack{{Rect: layout.Rect{X: 10, Y: 10, Width: 80, Height: 30}, Exclude: true}}
+		pieces, ok := square.aroundHoles(layout.Rect{X: 0, Y: 0, Width: 100, Height: 100})
+		assert.True(t, ok)
+		assert.Equal(t, []layout.Rect{
+			{X: 0, Y: 0, Width: 100, Height: 10},
+			{X: 0, Y: 40, Width: 100, Height: 60},
+			{X: 0, Y: 10, Width: 10, Height: 30},
+			{X: 90, Y: 10, Width: 10, Height: 30},
+		}, pieces)
+
+		_, ok = clips.aroundHoles(layout.Rect{X: 0, Y: 0, Width: 100, Height: 100})
+		assert.False(t, ok, "a rounded hole is masked")
+	})
+}
+
+func TestRoundedBorderCommands(t *testing.T) {
+	root := buildLayout(`<div id="box"><img src="a.png" style="width: 40px; height: 40px; border-radius: 8px"></div>`,
+		`#box { width: 200px; height: 100px; border: 4px solid black; border-radius: 10px; }`, 800)
+	commands := BuildDisplayList(root, InputState{}, LinkStyler{})
+
+	var clips []PushClip
+	var edges []layout.Rect
+	for _, cmd := range commands {
+		switch c := cmd.(type) {
+		case PushClip:
+			clips = append(clips, c)
+		case DrawRect:
+			// Skip the page background
+			if c.Width != 3000 {
+				edges = append(edges, c.Rect)
+			}
+		}
+	}
+	if !assert.Len(t, clips, 3) || !assert.Len(t, edges, 4) {
+		return
+	}
+	box := edges[0]
+	box.Height = edges[1].Height
+
+	// The border is clipped to the border box with the padding box cut out
+	assert.Equal(t, PushClip{Rect: box, TopLeftRadius: 10, TopRightRadius: 10, BottomRightRadius: 10, BottomLeftRadius: 10}, clips[0])
+	assert.Equal(t, PushClip{
+		Rect:          layout.Rect{X: box.X + 4, Y: box.Y + 4, Width: box.Width - 8, Height: box.Height - 8},
+		TopLeftRadius: 6, TopRightRadius: 6, BottomRightRadius: 6, BottomLeftRadius: 6, Exclude: true,
+	}, clips[1])
+	assert.Equal(t, 10.0, edges[0].Height, "edges reach over the corners")
+	assert.Equal(t, []string{"push", "push", "pop", "pop", "push", "image", "pop"}, clipEvents(commands, nil))
+
+	// The image is trimmed to its own rounded corners
+	assert.Equal(t, 8.0, clips[2].TopLeftRadius)
+	assert.Equal(t, edges[0].X+4, clips[2].X)
+}
+
+func TestClipImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{0, 255, 0, 255})
+	src.Set(0, 1, color.RGBA{0, 0, 255, 255})
+	src.Set(1, 1, color.RGBA{255, 255, 255, 255})
+
+	// The image is stretched over 100x100 and only its right half is visible
+	clips := clipStack{{Rect: layout.Rect{X: 50, Y: 0, Width: 100, Height: 100}}}
+	img, visible, ok := clips.clipImage(src, layout.Rect{X: 0, Y: 0, Width: 100, Height: 100})
+	assert.True(t, ok)
+	assert.Equal(t, layout.Rect{X: 50, Y: 0, Width: 50, Height: 100}, visible)
+	assert.Equal(t, color.RGBA{0, 255, 0, 255}, img.At(10, 10))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, img.At(10, 90))
+}
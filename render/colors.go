@@ -81,3 +81,8 @@ var (
 	ColorScrollbarTrack = color.RGBA{240, 240, 240, 255} // Light gray track
 	ColorScrollbarThumb = color.RGBA{190, 190, 190, 255} // Darker gray thumb
 )
+
+// Text selection color
+var (
+	ColorSelection = color.RGBA{0, 120, 215, 128} // Translucent blue behind selected text
+)
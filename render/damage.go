@@ -0,0 +1,288 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"browser/layout"
+	"browser/utils"
+
+	"fyne.io/fyne/v2"
+)
+
+// Incremental repaint. Each frame's display list is compared with the one
+// drawn before it (see displaydiff.go): the commands found in only one of
+// the two are the damage, the regions of the page that look different. A
+// frame without damage draws nothing. Otherwise only the damaged commands
+// are drawn again; every other command gets back the canvas objects the
+// previous frame made for it, so a caret moving or a link changing color
+// on hover costs a few objects rather than the whole page.
+
+// commandKey identifies a display command by value: two commands with the
+// same key draw the same thing.
+func commandKey(cmd DisplayCommand) string {
+	return fmt.Sprintf("%T%+v", cmd, cmd)
+}
+
+// reusable reports whether what cmd drew can be handed to a later frame.
+// Images are left out, as one drawn while loading shows a placeholder, as
+// are canvases, whose bitmaps change without their commands changing, the
+// clip and filter markers, which draw nothing, and fixed backgrounds, drawn
+// beneath the page rather than with the layer's objects.
+func reusable(cmd DisplayCommand) bool {
+	switch cmd.(type) {
+	case DrawImage, DrawCanvas, PushClip, PopClip, PushFilter, PopFilter, DrawFixedBackground:
+		return false
+	}
+	return true
+}
+
+// commandBounds returns the page area cmd draws over, or false for the
+// commands whose effect has no bounds of its own.
+func commandBounds(cmd DisplayCommand) (layout.Rect, bool) {
+	switch c := cmd.(type) {
+	case DrawRect:
+		return c.Rect, true
+	case DrawText:
+		r := layout.Rect{X: c.X, Y: c.Y, Height: float64(c.Size) * 1.5}
+		r.Width = max(c.Width, layout.MeasureText(c.Text, float64(c.Size)))
+		for _, s := range c.Shadows {
+			r = unionRect(r, layout.Rect{X: r.X + s.OffsetX - s.Blur, Y: r.Y + s.OffsetY - s.Blur, Width: r.Width + 2*s.Blur, Height: r.Height + 2*s.Blur})
+		}
+		return r, true
+	case DrawInput:
+		// Autofill suggestions drop down below the field
+		return dropdownBounds(c.Rect, len(c.Suggestions)), true
+	case DrawSelect:
+		if c.IsOpen {
+			return dropdownBounds(c.Rect, len(c.Options)), true
+		}
+		return c.Rect, true
+	case DrawImage:
+		return c.Rect, true
+	case DrawSVG:
+		return c.Rect, true
+	case DrawCanvas:
+		return c.Rect, true
+	case DrawHR:
+		return c.Rect, true
+	case DrawButton:
+		return c.Rect, true
+	case DrawTextarea:
+		return c.Rect, true
+	case DrawRadio:
+		return c.Rect, true
+	case DrawCheckbox:
+		return c.Rect, true
+	case DrawFileInput:
+		return c.Rect, true
+	case DrawFieldset:
+		return c.Rect, true
+	case PushClip:
+		return c.Rect, true
+	}
+	return layout.Rect{}, false
+}
+
+// dropdownBounds returns r extended by the list of n options renderDropdownList
+// draws below it.
+func dropdownBounds(r layout.Rect, n int) layout.Rect {
+	if n > 0 {
+		r.Height += 28*float64(n) + 2
+	}
+	return r
+}
+
+// unionRect returns the smallest rect holding a and b.
+func unionRect(a, b layout.Rect) layout.Rect {
+	x, y := min(a.X, b.X), min(a.Y, b.Y)
+	right := max(a.X+a.Width, b.X+b.Width)
+	bottom := max(a.Y+a.Height, b.Y+b.Height)
+	return layout.Rect{X: x, Y: y, Width: right - x, Height: bottom - y}
+}
+
+// allBounds returns the bounds of every command in the given lists.
+func allBounds(lists ...[]DisplayCommand) []layout.Rect {
+	var rects []layout.Rect
+	for _, list := range lists {
+		for _, cmd := range list {
+			if r, ok := commandBounds(cmd); ok {
+				rects = append(rects, r)
+			}
+		}
+	}
+	return rects
+}
+
+// mergeRects unions overlapping rects until none overlap.
+func mergeRects(rects []layout.Rect) []layout.Rect {
+	var merged []layout.Rect
+	for _, r := range rects {
+		for i := 0; i < len(merged); {
+			if overlaps(merged[i], r) {
+				r = unionRect(merged[i], r)
+				merged = slices.Delete(merged, i, i+1)
+				i = 0
+				continue
+			}
+			i++
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// drawnCommand is what drawing one display command made: its canvas
+// objects, and the dropdown lists floated above the rest of the page.
+type drawnCommand struct {
+	objects  []fyne.CanvasObject
+	overlays []fyne.CanvasObject
+}
+
+// frameObjects is what one frame of a layer reuses from the previous frame
+// and keeps for the next, drawn commands by key.
+type frameObjects struct {
+	reuse, kept map[string][]drawnCommand
+	pending     []pendingImage     // images drawn as placeholders until they load
+	backgrounds []*fixedBackground // drawn beneath the page (see fixedbackground.go)
+}
+
+// pendingImage is an image drawn as a placeholder, with where it goes.
+type pendingImage struct {
+	url  string
+	rect layout.Rect
+}
+
+// settled reports whether the image has loaded, failed or been deferred by
+// data saver since, so drawing it again shows something new.
+func (p pendingImage) settled() bool {
+	if _, ok := cachedImage(p.url); ok {
+		return true
+	}
+	if _, deferred := dataSaver.deferredSize(p.url); deferred {
+		return true
+	}
+	failedMu.Lock()
+	defer failedMu.Unlock()
+	return failedImages[p.url]
+}
+
+// drawKey returns the key what cmd draws under the active clips is kept
+// under, or "" when it is not kept.
+func drawKey(cmd DisplayCommand, clips clipStack) string {
+	if !reusable(cmd) {
+		return ""
+	}
+	if len(clips) == 0 {
+		return commandKey(cmd)
+	}
+	return fmt.Sprintf("%s%+v", commandKey(cmd), clips)
+}
+
+// reused hands out what the previous frame drew under key. Each drawing is
+// handed out once, as a canvas object cannot be shown twice.
+func (f *frameObjects) reused(key string) (drawnCommand, bool) {
+	drawn := f.reuse[key]
+	if len(drawn) == 0 {
+		return drawnCommand{}, false
+	}
+	f.reuse[key] = drawn[1:]
+	f.keep(key, drawn[0].objects, drawn[0].overlays)
+	return drawn[0], true
+}
+
+// keep records what was drawn under key for the next frame.
+func (f *frameObjects) keep(key string, objects, overlays []fyne.CanvasObject) {
+	f.kept[key] = append(f.kept[key], drawnCommand{objects: slices.Clone(objects), overlays: slices.Clone(overlays)})
+}
+
+// layerFrame is a display layer as last drawn: its display list and the
+// canvas objects it was drawn with.
+type layerFrame struct {
+	mu       sync.Mutex
+	commands []DisplayCommand
+	keys     []string // matching keys of commands (see displayKeys)
+	objects  []fyne.CanvasObject
+	drawn    map[string][]drawnCommand
+	pending  []pendingImage
+	raster   TextRasterOptions
+
+	backgrounds []*fixedBackground
+}
+
+// render draws list as RenderToCanvas does, reusing what the previous
+// frame drew, and returns the objects and the damaged regions. Without
+// damage the previous frame's objects are returned as they are.
+func (f *layerFrame) render(list DisplayList, baseURL, pageURL string, useCache bool, onImageLoad func()) ([]fyne.CanvasObject, []layout.Rect) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.raster != TextRaster {
+		// Text drawn with other raster options looks different
+		f.drawn = nil
+	}
+
+	commands, keys := list.Commands, displayKeys(list)
+	damaged := diffDisplayCommands(f.commands, f.keys, commands, keys).Damage
+	// Of the images drawn as placeholders, only those that arrived since
+	// are drawn again
+	for _, img := range f.pending {
+		if img.settled() {
+			damaged = append(damaged, img.rect)
+		}
+	}
+	damaged = mergeRects(damaged)
+	if f.drawn != nil && len(damaged) == 0 {
+		return f.objects, nil
+	}
+
+	frame := &frameObjects{reuse: f.drawn, kept: make(map[string][]drawnCommand)}
+	objects := renderToCanvas(commands, baseURL, pageURL, useCache, onImageLoad, frame)
+	f.commands, f.keys, f.objects = commands, keys, objects
+	f.drawn, f.pending, f.raster = frame.kept, frame.pending, TextRaster
+	f.backgrounds = frame.backgrounds
+	return objects, damaged
+}
+
+// fixedBackgrounds returns the fixed backgrounds the last frame drew.
+func (f *layerFrame) fixedBackgrounds() []*fixedBackground {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.backgrounds
+}
+
+// renderLayers draws the page and fixed layers' display lists as frames
+// of normalFrame and fixedFrame.
+func (b *Browser) renderLayers(normal, fixed DisplayList, baseURL, pageURL string, useCache bool, onImageLoad func()) (normalObjects []fyne.CanvasObject, normalDamage []layout.Rect, fixedObjects []fyne.CanvasObject, fixedDamage []layout.Rect) {
+	start := utils.Profile.Start()
+	normalObjects, normalDamage = b.normalFrame.render(normal, baseURL, pageURL, useCache, onImageLoad)
+	fixedObjects, fixedDamage = b.fixedFrame.render(fixed, baseURL, pageURL, useCache, onImageLoad)
+	utils.Profile.Record(utils.PhaseRaster, start, len(normal.Commands)+len(fixed.Commands))
+	return normalObjects, normalDamage, fixedObjects, fixedDamage
+}
+
+// reset forgets the last frame, so the next one is drawn from scratch.
+func (f *layerFrame) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands, f.keys, f.objects, f.drawn = nil, nil, nil, nil
+	f.pending, f.backgrounds = nil, nil
+}
+
+// setDeviceScale draws the page for a display of scale device pixels per
+// canvas unit: text is measured and hinted for it again, and everything
+// rasterized in software is drawn anew at its resolution. It runs on the
+// UI thread, like the reflow that follows it.
+func (b *Browser) setDeviceScale(scale float32) {
+	TextRaster = DefaultTextRasterOptions(scale)
+	layout.InvalidateTextCache()
+	b.resetFrames()
+	b.tiles.reset()
+}
+
+// resetFrames makes the next frame draw the whole page afresh, for a new
+// page or when what commands draw has changed, as when a web font loads.
+func (b *Browser) resetFrames() {
+	b.normalFrame.reset()
+	b.fixedFrame.reset()
+}
@@ -0,0 +1,140 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"browser/layout"
+	"browser/utils"
+
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDamage(t *testing.T) {
+	keysOf := func(cmds []DisplayCommand) []string {
+		keys := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			keys[i] = commandKey(cmd)
+		}
+		return keys
+	}
+	box := func(x, y float64, c color.RGBA) DrawRect {
+		return DrawRect{Rect: layout.Rect{X: x, Y: y, Width: 10, Height: 10}, Color: c}
+	}
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+
+	tests := []struct {
+		name       string
+		prev, next []DisplayCommand
+		want       []layout.Rect
+	}{
+		{
+			"same commands",
+			[]DisplayCommand{box(0, 0, red), box(50, 0, red)},
+			[]DisplayCommand{box(0, 0, red), box(50, 0, red)},
+			nil,
+		},
+		{
+			"changed color",
+			[]DisplayCommand{box(0, 0, red), box(50, 0, red)},
+			[]DisplayCommand{box(0, 0, red), box(50, 0, blue)},
+			[]layout.Rect{{X: 50, Width: 10, Height: 10}},
+		},
+		{
+			"moved box damages where it was and is",
+			[]DisplayCommand{box(0, 0, red)},
+			[]DisplayCommand{box(5, 5, red)},
+			[]layout.Rect{{Width: 15, Height: 15}},
+		},
+		{
+			"added command",
+			[]DisplayCommand{box(0, 0, red)},
+			[]DisplayCommand{box(0, 0, red), box(50, 50, blue)},
+			[]layout.Rect{{X: 50, Y: 50, Width: 10, Height: 10}},
+		},
+		{
+			"changed filter damages everything",
+			[]DisplayCommand{PushFilter{}, box(0, 0, red), PopFilter{}, box(50, 0, red)},
+			[]DisplayCommand{box(0, 0, red), box(50, 0, red)},
+			[]layout.Rect{{X: 0, Width: 10, Height: 10}, {X: 50, Width: 10, Height: 10}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diffDisplayCommands(tt.prev, keysOf(tt.prev), tt.next, keysOf(tt.next)).Damage)
+		})
+	}
+}
+
+func TestLayerFrameReusesObjects(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+
+	cmds := []DisplayCommand{
+		DrawRect{Rect: layout.Rect{Width: 100, Height: 20}, Color: ColorWhite},
+		DrawText{Text: "Home", X: 4, Y: 2, Color: ColorLink, Size: 14},
+		DrawText{Text: "About", X: 60, Y: 2, Color: ColorLink, Size: 14},
+	}
+	var frame layerFrame
+	first, damaged := frame.render(DisplayList{Commands: cmds}, "", "", false, nil)
+	assert.NotEmpty(t, damaged, "the first frame draws everything")
+
+	again, damaged := frame.render(DisplayList{Commands: cmds}, "", "", false, nil)
+	assert.Empty(t, damaged)
+	assert.Equal(t, first, again)
+
+	// A link changing color only redraws the link
+	restyled := append([]DisplayCommand(nil), cmds...)
+	restyled[2] = DrawText{Text: "About", X: 60, Y: 2, Color: ColorLinkVisited, Size: 14, Underline: true}
+	next, damaged := frame.render(DisplayList{Commands: restyled}, "", "", false, nil)
+	assert.Len(t, damaged, 1)
+	assert.Same(t, first[0], next[0], "the background is reused")
+	assert.Same(t, first[1], next[1], "the unchanged link is reused")
+	assert.NotSame(t, first[2], next[2], "the restyled link is drawn again")
+
+	frame.reset()
+	fresh, _ := frame.render(DisplayList{Commands: restyled}, "", "", false, nil)
+	assert.NotSame(t, first[0], fresh[0], "a reset frame draws from scratch")
+}
+
+func TestLayerFramePendingImages(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+
+	const url = "https://example.com/pending.png"
+	// Pretend the image is on its way, so no fetch starts
+	pendingMu.Lock()
+	pendingFeteches[url] = true
+	pendingMu.Unlock()
+	defer func() {
+		pendingMu.Lock()
+		delete(pendingFeteches, url)
+		pendingMu.Unlock()
+	}()
+
+	imageRect := layout.Rect{X: 10, Y: 40, Width: 32, Height: 32}
+	list := DisplayList{Commands: []DisplayCommand{
+		DrawText{Text: "Logo", X: 10, Y: 10, Color: ColorBlack, Size: 14},
+		DrawImage{Rect: imageRect, URL: url},
+	}}
+	var frame layerFrame
+	first, _ := frame.render(list, "", "", true, nil)
+
+	_, damaged := frame.render(list, "", "", true, nil)
+	assert.Empty(t, damaged, "nothing to draw until the image arrives")
+
+	utils.HTTPCache.Put(url, image.NewRGBA(image.Rect(0, 0, 32, 32)))
+	defer utils.HTTPCache.Clear(utils.SiteOf(url), time.Time{})
+	next, damaged := frame.render(list, "", "", true, nil)
+	assert.Equal(t, []layout.Rect{imageRect}, damaged, "only the image is damaged")
+	assert.Same(t, first[0], next[0])
+	assert.IsType(t, &canvas.Image{}, next[1])
+
+	_, damaged = frame.render(list, "", "", true, nil)
+	assert.Empty(t, damaged)
+}
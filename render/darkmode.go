@@ -0,0 +1,180 @@
+package render
+
+import (
+	"image/color"
+	"math"
+
+	"browser/css"
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+)
+
+// Forced dark maps page lightness into this range, so white backgrounds
+// turn a soft black and black text an off-white rather than the extremes.
+const (
+	forcedDarkMinLightness = 0.07
+	forcedDarkMaxLightness = 0.93
+	// forcedDarkTextLightness keeps darkened text readable on the darkened
+	// backgrounds; mid-lightness link colors would otherwise fade
+	forcedDarkTextLightness = 0.65
+)
+
+// SetColorScheme sets the color scheme pages see through
+// prefers-color-scheme ("light" or "dark") and restyles the current page.
+func (b *Browser) SetColorScheme(scheme string) {
+	b.colorScheme = scheme
+	b.refreshMainMenu()
+	b.scheduleReflow()
+}
+
+// ColorScheme returns the color scheme pages see through
+// prefers-color-scheme.
+func (b *Browser) ColorScheme() string {
+	if b.colorScheme == css.ColorSchemeDark {
+		return css.ColorSchemeDark
+	}
+	return css.ColorSchemeLight
+}
+
+// SetForceDark turns forced dark mode on or off. While the dark scheme is
+// in use, pages without dark styles of their own are painted darkened.
+func (b *Browser) SetForceDark(on bool) {
+	b.forceDark = on
+	b.refreshMainMenu()
+	b.repaint()
+}
+
+// ForceDarkEnabled reports whether forced dark mode is on.
+func (b *Browser) ForceDarkEnabled() bool {
+	return b.forceDark
+}
+
+// forcingDark reports whether the current page is painted darkened: the
+// user wants dark pages and the page has no dark styles to offer.
+func (b *Browser) forcingDark() bool {
+	return b.forceDark && b.ColorScheme() == css.ColorSchemeDark && !b.hasDarkStyles
+}
+
+// appearanceMenuItem builds the Appearance submenu of the View menu.
+func (b *Browser) appearanceMenuItem() *fyne.MenuItem {
+	light := fyne.NewMenuItem("Light", func() { b.SetColorScheme(css.ColorSchemeLight) })
+	light.Checked = b.ColorScheme() == css.ColorSchemeLight
+	dark := fyne.NewMenuItem("Dark", func() { b.SetColorScheme(css.ColorSchemeDark) })
+	dark.Checked = b.ColorScheme() == css.ColorSchemeDark
+	force := fyne.NewMenuItem("Force Dark Pages", func() { b.SetForceDark(!b.forceDark) })
+	force.Checked = b.forceDark
+	force.Disabled = b.ColorScheme() != css.ColorSchemeDark
+
+	appearance := fyne.NewMenuItem("Appearance", nil)
+	appearance.ChildMenu = fyne.NewMenu("", light, dark, fyne.NewMenuItemSeparator(), force)
+	return appearance
+}
+
+// displayLayers builds the page's display layers, darkened when forced
+// dark mode applies to the page.
+func (b *Browser) displayLayers(root *layout.LayoutBox, state InputState, linkStyler LinkStyler) (DisplayList, DisplayList) {
+	state.Viewport = b.viewport()
+	normal, fixed := BuildDisplayLists(root, state, linkStyler)
+	if z := b.zoom(); z != 1 {
+		scaleCommands(normal.Commands, z)
+		scaleCommands(fixed.Commands, z)
+	}
+	if b.forcingDark() {
+		forceDarkColors(normal.Commands)
+		forceDarkColors(fixed.Commands)
+	}
+	return normal, fixed
+}
+
+// forceDarkColors darkens the colors of commands in place. Backgrounds and
+// borders have their lightness inverted, keeping hue and saturation; text
+// is inverted too and then lifted to stay readable. Images and form
+// controls keep their own colors.
+func forceDarkColors(commands []DisplayCommand) {
+	for i, cmd := range commands {
+		switch c := cmd.(type) {
+		case DrawRect:
+			c.Color = darkModeColor(c.Color, 0)
+			commands[i] = c
+		case DrawText:
+			c.Color = darkModeColor(c.Color, forcedDarkTextLightness)
+			if len(c.Shadows) > 0 {
+				shadows := make([]css.TextShadow, len(c.Shadows))
+				for j, shadow := range c.Shadows {
+					shadow.Color = darkModeColor(shadow.Color, 0)
+					shadows[j] = shadow
+				}
+				c.Shadows = shadows
+			}
+			commands[i] = c
+		case DrawSVG:
+			// Only currentColor, as images keep their colors
+			c.Color = darkModeColor(c.Color, forcedDarkTextLightness)
+			commands[i] = c
+		case DrawFixedBackground:
+			c.Color = darkModeColor(c.Color, 0)
+			commands[i] = c
+		}
+	}
+}
+
+// darkModeColor inverts the lightness of c into the forced dark range,
+// raising it to at least minLightness. Alpha is kept.
+func darkModeColor(c color.Color, minLightness float64) color.Color {
+	if c == nil {
+		return nil
+	}
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return c
+	}
+	// RGBA is alpha-premultiplied
+	h, s, l := rgbToHSL(float64(r)/float64(a), float64(g)/float64(a), float64(b)/float64(a))
+	l = forcedDarkMinLightness + (forcedDarkMaxLightness-forcedDarkMinLightness)*(1-l)
+	l = math.Max(l, minLightness)
+	rf, gf, bf := hslToRGB(h, s, l)
+	return color.NRGBA{
+		R: uint8(math.Round(rf * 255)),
+		G: uint8(math.Round(gf * 255)),
+		B: uint8(math.Round(bf * 255)),
+		A: uint8(a >> 8),
+	}
+}
+
+// rgbToHSL converts RGB components in [0, 1] to hue in degrees and
+// saturation and lightness in [0, 1].
+func rgbToHSL(r, g, b float64) (h, s, l float64) {
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	l = (maxC + minC) / 2
+	d := maxC - minC
+	if d == 0 {
+		return 0, 0, l
+	}
+	s = d / (1 - math.Abs(2*l-1))
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
+
+// hslToRGB converts hue in degrees and saturation and lightness in [0, 1]
+// to RGB components in [0, 1] (CSS Color 4 §7.1).
+func hslToRGB(h, s, l float64) (r, g, b float64) {
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(k-3, math.Min(9-k, 1)))
+	}
+	return f(0), f(8), f(4)
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"browser/css"
+	"browser/layout"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDarkModeColor(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           color.Color
+		minLightness float64
+		want         color.Color
+	}{
+		{"white turns soft black", color.White, 0, color.NRGBA{18, 18, 18, 255}},
+		{"black turns off-white", color.Black, 0, color.NRGBA{237, 237, 237, 255}},
+		{"mid gray stays put", color.NRGBA{128, 128, 128, 255}, 0, color.NRGBA{127, 127, 127, 255}},
+		{"hue survives", color.NRGBA{255, 230, 230, 255}, 0, color.NRGBA{57, 0, 0, 255}},
+		{"text is lifted to stay readable", color.NRGBA{0, 0, 238, 255}, forcedDarkTextLightness, color.NRGBA{77, 77, 255, 255}},
+		{"alpha is kept", color.NRGBA{255, 255, 255, 128}, 0, color.NRGBA{18, 18, 18, 128}},
+		{"transparent is untouched", color.Transparent, 0, color.Transparent},
+		{"nil stays nil", nil, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, darkModeColor(tt.in, tt.minLightness))
+		})
+	}
+}
+
+func TestForceDarkColors(t *testing.T) {
+	shadows := []css.TextShadow{{OffsetX: 1, Color: color.Black}}
+	commands := []DisplayCommand{
+		DrawRect{Rect: layout.Rect{Width: 10, Height: 10}, Color: color.White},
+		DrawText{Text: "hi", Color: color.Black, Shadows: shadows},
+		DrawImage{URL: "photo.png"},
+	}
+
+	forceDarkColors(commands)
+
+	assert.Equal(t, color.NRGBA{18, 18, 18, 255}, commands[0].(DrawRect).Color)
+	text := commands[1].(DrawText)
+	assert.Equal(t, color.NRGBA{237, 237, 237, 255}, text.Color)
+	assert.Equal(t, color.NRGBA{237, 237, 237, 255}, text.Shadows[0].Color)
+	assert.Equal(t, color.Black, shadows[0].Color, "the box's shadows are not modified")
+	assert.Equal(t, DrawImage{URL: "photo.png"}, commands[2], "images keep their colors")
+}
+
+func TestForcingDark(t *testing.T) {
+	tests := []struct {
+		name          string
+		scheme        string
+		forceDark     bool
+		hasDarkStyles bool
+		want          bool
+	}{
+		{"dark page without dark styles", css.ColorSchemeDark, true, false, true},
+		{"page has its own dark styles", css.ColorSchemeDark, true, true, false},
+		{"force dark off", css.ColorSchemeDark, false, false, false},
+		{"light scheme", css.ColorSchemeLight, true, false, false},
+		{"unset scheme is light", "", true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Browser{colorScheme: tt.scheme, forceDark: tt.forceDark, hasDarkStyles: tt.hasDarkStyles}
+			assert.Equal(t, tt.want, b.forcingDark())
+		})
+	}
+}
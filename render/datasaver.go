@@ -0,0 +1,170 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"browser/layout"
+)
+
+// DataSaverImageLimit is the largest image, in bytes, that data saver mode
+// loads before the user clicks its placeholder.
+var DataSaverImageLimit int64 = 100 * 1024
+
+// errImageDeferred is returned for images data saver mode left unloaded.
+var errImageDeferred = errors.New("image deferred by data saver")
+
+// deferredImageError is returned for an image data saver mode already
+// deferred, carrying its size for the placeholder.
+type deferredImageError struct {
+	size int64
+}
+
+func (e deferredImageError) Error() string        { return errImageDeferred.Error() }
+func (e deferredImageError) Is(target error) bool { return target == errImageDeferred }
+
+// dataSaverState tracks which images data saver mode deferred and how many
+// bytes that saved. Images are fetched by a package-wide loader, so the
+// state is shared by every window.
+type dataSaverState struct {
+	mu       sync.Mutex
+	enabled  bool
+	deferred map[string]int64 // image URL -> size, -1 when the server did not say
+	allowed  map[string]bool  // deferred images the user chose to load
+	saved    int64
+}
+
+var dataSaver = newDataSaverState()
+
+func newDataSaverState() *dataSaverState {
+	return &dataSaverState{
+		deferred: make(map[string]int64),
+		allowed:  make(map[string]bool),
+	}
+}
+
+// setEnabled turns data saver mode on or off. Turning it off releases every
+// deferred image.
+func (d *dataSaverState) setEnabled(on bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.enabled = on
+	if !on {
+		clear(d.deferred)
+	}
+}
+
+func (d *dataSaverState) isEnabled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.enabled
+}
+
+// applies reports whether the image at url is subject to data saver mode.
+func (d *dataSaverState) applies(url string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.enabled && !d.allowed[url]
+}
+
+// shouldDefer decides whether the image at url, size bytes long, is too
+// large to load, and records it if so. A size of -1 means the server did not
+// say but the download already passed the limit.
+func (d *dataSaverState) shouldDefer(url string, size int64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.enabled || d.allowed[url] || (size >= 0 && size <= DataSaverImageLimit) {
+		return false
+	}
+	if _, ok := d.deferred[url]; !ok && size > 0 {
+		d.saved += size
+	}
+	d.deferred[url] = size
+	return true
+}
+
+// deferredSize reports whether url was deferred, and its size.
+func (d *dataSaverState) deferredSize(url string) (int64, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	size, ok := d.deferred[url]
+	return size, ok
+}
+
+// allow loads a deferred image after all; its bytes no longer count as saved.
+func (d *dataSaverState) allow(url string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	size, ok := d.deferred[url]
+	if !ok {
+		return false
+	}
+	delete(d.deferred, url)
+	d.allowed[url] = true
+	if size > 0 {
+		d.saved -= size
+	}
+	return true
+}
+
+func (d *dataSaverState) bytesSaved() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.saved
+}
+
+// formatBytes renders a byte count for the user, e.g. "340 KB".
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%d KB", n/1024)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// deferredImageLabel is the text on a deferred image's placeholder.
+func deferredImageLabel(size int64) string {
+	if size > 0 {
+		return "Load image (" + formatBytes(size) + ")"
+	}
+	return "Load image"
+}
+
+// SetDataSaver turns data saver mode on or off. In data saver mode images
+// larger than DataSaverImageLimit show a placeholder until clicked.
+func (b *Browser) SetDataSaver(on bool) {
+	dataSaver.setEnabled(on)
+	b.refreshMainMenu()
+	if !on {
+		b.repaint()
+	}
+}
+
+// DataSaverEnabled reports whether data saver mode is on.
+func (b *Browser) DataSaverEnabled() bool {
+	return dataSaver.isEnabled()
+}
+
+// DataSaverBytesSaved returns how many bytes data saver mode has avoided
+// downloading, counting only images whose size the server reported.
+func (b *Browser) DataSaverBytesSaved() int64 {
+	return dataSaver.bytesSaved()
+}
+
+// loadDeferredImage loads the image under a click when data saver mode
+// deferred it, and reports whether it did.
+func (b *Browser) loadDeferredImage(hit *layout.LayoutBox) bool {
+	if hit.Type != layout.ImageBox || hit.Node == nil || b.currentURL == nil {
+		return false
+	}
+	baseURL := b.currentURL.Scheme + "://" + b.currentURL.Host
+	if !dataSaver.allow(resolveImageURL(hit.Node.Attributes["src"], baseURL)) {
+		return false
+	}
+	b.repaint()
+	return true
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataSaverDefersLargeImages(t *testing.T) {
+	const big = "http://example.com/big.jpg"
+	limit := DataSaverImageLimit
+
+	tests := []struct {
+		name    string
+		enabled bool
+		size    int64
+		want    bool
+	}{
+		{"off", false, limit * 10, false},
+		{"small image", true, limit, false},
+		{"large image", true, limit + 1, true},
+		{"unknown size past the limit", true, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDataSaverState()
+			d.setEnabled(tt.enabled)
+			assert.Equal(t, tt.want, d.shouldDefer(big, tt.size))
+			_, deferred := d.deferredSize(big)
+			assert.Equal(t, tt.want, deferred)
+		})
+	}
+}
+
+func TestDataSaverBytesSaved(t *testing.T) {
+	d := newDataSaverState()
+	d.setEnabled(true)
+	size := DataSaverImageLimit + 100
+
+	d.shouldDefer("http://example.com/a.jpg", size)
+	d.shouldDefer("http://example.com/a.jpg", size) // counted once
+	d.shouldDefer("http://example.com/b.jpg", -1)   // size unknown
+	assert.Equal(t, size, d.bytesSaved())
+
+	// Loading an image on request gives its bytes back and stops deferring it
+	assert.True(t, d.allow("http://example.com/a.jpg"))
+	assert.Equal(t, int64(0), d.bytesSaved())
+	assert.False(t, d.applies("http://example.com/a.jpg"))
+	assert.False(t, d.shouldDefer("http://example.com/a.jpg", size))
+	assert.False(t, d.allow("http://example.com/c.jpg"), "never deferred")
+}
+
+func TestDataSaverDisableReleasesImages(t *testing.T) {
+	d := newDataSaverState()
+	d.setEnabled(true)
+	d.shouldDefer("http://example.com/a.jpg", DataSaverImageLimit*2)
+
+	d.setEnabled(false)
+	_, deferred := d.deferredSize("http://example.com/a.jpg")
+	assert.False(t, deferred)
+	assert.False(t, d.applies("http://example.com/a.jpg"))
+}
+
+func TestDeferredImageError(t *testing.T) {
+	var err error = deferredImageError{size: 2048}
+	assert.True(t, errors.Is(err, errImageDeferred))
+
+	var deferred deferredImageError
+	assert.True(t, errors.As(err, &deferred))
+	assert.Equal(t, "Load image (2 KB)", deferredImageLabel(deferred.size))
+	assert.Equal(t, "Load image", deferredImageLabel(-1))
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "340 KB", formatBytes(340*1024))
+	assert.Equal(t, "1.5 MB", formatBytes(3*1024*1024/2))
+}
@@ -0,0 +1,24 @@
+package render
+
+import "browser/dom"
+
+// SetJSToggleHandler sets the callback that fires toggle events at
+// <details> elements the user opens or closes.
+func (b *Browser) SetJSToggleHandler(handler func(node *dom.Node)) {
+	b.onJSToggle = handler
+}
+
+// clickSummary runs the page's click handlers for a click on target in
+// summary, then opens or closes summary's <details> element unless one of
+// them called preventDefault.
+func (b *Browser) clickSummary(target, summary *dom.Node) {
+	if b.onJSClick != nil && b.onJSClick(target) {
+		return
+	}
+	details := summary.Parent
+	dom.ToggleDetails(details)
+	b.ReflowChanged(details)
+	if b.onJSToggle != nil {
+		b.onJSToggle(details)
+	}
+}
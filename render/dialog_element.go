@@ -0,0 +1,25 @@
+package render
+
+import "browser/dom"
+
+// SetJSDialogCancelHandler sets the callback that fires cancel at a modal
+// <dialog> dismissed with Escape and closes it unless the page objects.
+func (b *Browser) SetJSDialogCancelHandler(handler func(node *dom.Node)) {
+	b.onJSDialogCancel = handler
+}
+
+// cancelModalDialog dismisses the topmost modal dialog, as Escape does. It
+// reports false when no modal dialog is open.
+func (b *Browser) cancelModalDialog() bool {
+	dialog := dom.TopModalDialog(b.document)
+	if dialog == nil {
+		return false
+	}
+	if b.onJSDialogCancel != nil {
+		go b.onJSDialogCancel(dialog)
+		return true
+	}
+	dom.CloseDialog(dialog)
+	b.ReflowChanged(dialog)
+	return true
+}
@@ -0,0 +1,186 @@
+package render
+
+import (
+	"browser/dom"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// offerSuppressAfter is the dialog count on one page from which the
+// "prevent additional dialogs" checkbox is shown.
+const offerSuppressAfter = 2
+
+// dialogQueue shows script dialogs one at a time. Each show function gets a
+// done callback that it must call exactly once when its dialog closes.
+type dialogQueue struct {
+	mu      sync.Mutex
+	showing bool
+	pending []func(done func())
+}
+
+func (q *dialogQueue) enqueue(show func(done func())) {
+	q.mu.Lock()
+	if q.showing {
+		q.pending = append(q.pending, show)
+		q.mu.Unlock()
+		return
+	}
+	q.showing = true
+	q.mu.Unlock()
+	show(q.next)
+}
+
+func (q *dialogQueue) next() {
+	q.mu.Lock()
+	if len(q.pending) == 0 {
+		q.showing = false
+		q.mu.Unlock()
+		return
+	}
+	show := q.pending[0]
+	q.pending = q.pending[1:]
+	q.mu.Unlock()
+	show(q.next)
+}
+
+// pageDialogs counts the dialogs the current page has shown and records
+// whether the user asked to suppress further ones. It resets whenever the
+// document changes.
+type pageDialogs struct {
+	mu         sync.Mutex
+	page       *dom.Node
+	count      int
+	suppressed bool
+}
+
+// begin registers a dialog from page. It reports whether the dialog may be
+// shown and whether it should offer to suppress further dialogs.
+func (p *pageDialogs) begin(page *dom.Node) (allowed, offerSuppress bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if page != p.page {
+		p.page, p.count, p.suppressed = page, 0, false
+	}
+	if p.suppressed {
+		return false, false
+	}
+	p.count++
+	return true, p.count >= offerSuppressAfter
+}
+
+// suppress stops page from showing more dialogs.
+func (p *pageDialogs) suppress(page *dom.Node) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if page == p.page {
+		p.suppressed = true
+	}
+}
+
+// isCurrent reports whether page is still the page being tracked, so
+// dialogs queued by a page that was navigated away from can be dropped.
+func (p *pageDialogs) isCurrent(page *dom.Node) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return page == p.page
+}
+
+// isSuppressed reports whether the user suppressed page's dialogs.
+func (p *pageDialogs) isSuppressed(page *dom.Node) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return page == p.page && p.suppressed
+}
+
+// runScriptDialog shows a dialog for the current page and blocks the
+// calling script until it is answered, leaving the window responsive.
+// Dialogs queue behind each other. shown is false when the page's dialogs
+// are suppressed or the page went away while the dialog was queued. An
+// empty dismiss label makes a single-button dialog.
+func (b *Browser) runScriptDialog(title string, body fyne.CanvasObject, confirm, dismiss string) (ok, shown bool) {
+	page := b.document
+	allowed, offer := b.pageDialogs.begin(page)
+	if !allowed {
+		return false, false
+	}
+
+	type answer struct{ ok, shown bool }
+	result := make(chan answer, 1)
+	b.dialogs.enqueue(func(done func()) {
+		if !b.pageDialogs.isCurrent(page) {
+			result <- answer{}
+			done()
+			return
+		}
+		fyne.Do(func() {
+			content := body
+			var suppress *widget.Check
+			if offer {
+				suppress = widget.NewCheck("Prevent this page from creating additional dialogs", nil)
+				content = container.NewVBox(body, suppress)
+			}
+			finish := func(ok bool) {
+				if suppress != nil && suppress.Checked {
+					b.pageDialogs.suppress(page)
+				}
+				result <- answer{ok, true}
+				done()
+			}
+
+			if dismiss == "" {
+				d := dialog.NewCustom(title, confirm, content, b.Window)
+				d.SetOnClosed(func() { finish(true) })
+				d.Show()
+				return
+			}
+			dialog.NewCustomConfirm(title, confirm, dismiss, content, finish, b.Window).Show()
+		})
+	})
+
+	a := <-result
+	return a.ok, a.shown
+}
+
+// ShowAlert implements window.alert. It returns once the user dismisses
+// the dialog.
+func (b *Browser) ShowAlert(message string) {
+	b.runScriptDialog("Alert", widget.NewLabel(message), "OK", "")
+}
+
+// ShowConfirm implements window.confirm. Suppressed dialogs answer false.
+func (b *Browser) ShowConfirm(message string) bool {
+	ok, _ := b.runScriptDialog("Confirm", widget.NewLabel(message), "OK", "Cancel")
+	return ok
+}
+
+// ShowPrompt implements window.prompt. It returns nil when cancelled or
+// suppressed.
+func (b *Browser) ShowPrompt(message, defaultValue string) *string {
+	entry := widget.NewEntry()
+	entry.Text = defaultValue // not yet shown, so no refresh is needed
+
+	// Create a wider container for the entry
+	label := widget.NewLabel(message)
+	entryContainer := container.NewGridWrap(fyne.NewSize(300, 36), entry)
+	content := container.NewVBox(label, entryContainer)
+
+	if ok, _ := b.runScriptDialog("Prompt", content, "OK", "Cancel"); !ok {
+		return nil
+	}
+	text := entry.Text
+	return &text
+}
+
+// ConfirmLeave asks whether to leave a page whose beforeunload handler
+// objected. Pages whose dialogs are suppressed may always be left.
+func (b *Browser) ConfirmLeave(message string) bool {
+	if b.pageDialogs.isSuppressed(b.document) {
+		return true
+	}
+	ok, shown := b.runScriptDialog("Leave page?", widget.NewLabel(message), "Leave", "Stay")
+	return ok || !shown
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"browser/dom"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialogQueueShowsOneAtATime(t *testing.T) {
+	var q dialogQueue
+	var shown []string
+	var closers []func()
+	show := func(name string) func(done func()) {
+		return func(done func()) {
+			shown = append(shown, name)
+			closers = append(closers, done)
+		}
+	}
+
+	q.enqueue(show("first"))
+	q.enqueue(show("second"))
+	q.enqueue(show("third"))
+	assert.Equal(t, []string{"first"}, shown, "later dialogs wait")
+
+	closers[0]()
+	assert.Equal(t, []string{"first", "second"}, shown)
+	closers[1]()
+	closers[2]()
+	assert.Equal(t, []string{"first", "second", "third"}, shown)
+
+	q.enqueue(show("fourth"))
+	assert.Equal(t, "fourth", shown[3], "an idle queue shows at once")
+}
+
+func TestPageDialogsSuppression(t *testing.T) {
+	var p pageDialogs
+	page := &dom.Node{}
+
+	allowed, offer := p.begin(page)
+	assert.True(t, allowed)
+	assert.False(t, offer, "the first dialog has no checkbox")
+
+	allowed, offer = p.begin(page)
+	assert.True(t, allowed)
+	assert.True(t, offer, "repeated dialogs offer suppression")
+
+	p.suppress(page)
+	assert.True(t, p.isSuppressed(page))
+	allowed, _ = p.begin(page)
+	assert.False(t, allowed)
+
+	// A new document starts over
+	next := &dom.Node{}
+	assert.False(t, p.isCurrent(next))
+	allowed, offer = p.begin(next)
+	assert.True(t, allowed)
+	assert.False(t, offer)
+	assert.False(t, p.isCurrent(page))
+
+	// Suppressing a page that is gone has no effect
+	p.suppress(page)
+	assert.False(t, p.isSuppressed(next))
+}
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"strconv"
+
+	"browser/layout"
+)
+
+// DisplayList is a display list with the layout box each command paints,
+// so the lists built for two frames of a page can be matched command by
+// command.
+type DisplayList struct {
+	Commands []DisplayCommand
+	// BoxIDs holds the ID of the box that painted each command, "" for the
+	// page background and other commands no box paints
+	BoxIDs []string
+}
+
+// DisplayListDiff is how a display list differs from the one drawn before
+// it.
+type DisplayListDiff struct {
+	// Previous holds, for each command of the new list, the index of the
+	// same command in the previous list, or -1 if it has to be drawn
+	Previous []int
+	// Removed holds the indexes of the previous list's commands the new
+	// list no longer draws
+	Removed []int
+	// Damage is the regions of the page that look different, overlapping
+	// ones merged
+	Damage []layout.Rect
+}
+
+// Unchanged reports whether the new list draws exactly what the previous
+// one did.
+func (d DisplayListDiff) Unchanged() bool {
+	return len(d.Damage) == 0
+}
+
+// DiffDisplayLists compares the display list drawn last with the next one.
+// A command is unchanged when the previous list has the same command
+// painted by the box with the same ID, so a backend can keep what it drew
+// for it and draw only the rest. Box IDs are the box's path in the layout
+// tree, which stays the same when a page is laid out again.
+func DiffDisplayLists(prev, next DisplayList) DisplayListDiff {
+	return diffDisplayCommands(prev.Commands, displayKeys(prev), next.Commands, displayKeys(next))
+}
+
+// displayKeys returns the key matching each command of list: its box ID
+// and what it draws.
+func displayKeys(list DisplayList) []string {
+	keys := make([]string, len(list.Commands))
+	for i, cmd := range list.Commands {
+		keys[i] = commandKey(cmd)
+		if i < len(list.BoxIDs) {
+			keys[i] = list.BoxIDs[i] + " " + keys[i]
+		}
+	}
+	return keys
+}
+
+// diffDisplayCommands diffs two display lists by the keys of their
+// commands. Commands with equal keys pair up in order; the bounds of every
+// command left over in either list are the damage, or the bounds of all
+// commands when one has no bounds of its own.
+func diffDisplayCommands(prev []DisplayCommand, prevKeys []string, next []DisplayCommand, nextKeys []string) DisplayListDiff {
+	unmatched := make(map[string][]int, len(prevKeys))
+	for i, key := range prevKeys {
+		unmatched[key] = append(unmatched[key], i)
+	}
+	diff := DisplayListDiff{Previous: make([]int, len(nextKeys))}
+	var changed []DisplayCommand
+	for i, key := range nextKeys {
+		if indexes := unmatched[key]; len(indexes) > 0 {
+			diff.Previous[i] = indexes[0]
+			unmatched[key] = indexes[1:]
+			continue
+		}
+		diff.Previous[i] = -1
+		changed = append(changed, next[i])
+	}
+	for i, key := range prevKeys {
+		if indexes := unmatched[key]; len(indexes) > 0 && indexes[0] == i {
+			unmatched[key] = indexes[1:]
+			diff.Removed = append(diff.Removed, i)
+			changed = append(changed, prev[i])
+		}
+	}
+
+	var rects []layout.Rect
+	for _, cmd := range changed {
+		r, ok := commandBounds(cmd)
+		if !ok {
+			diff.Damage = mergeRects(allBounds(prev, next))
+			return diff
+		}
+		rects = append(rects, r)
+	}
+	diff.Damage = mergeRects(rects)
+	return diff
+}
+
+// boxStart and boxEnd bracket the commands paintLayoutBox paints for a box,
+// nested like the boxes. newDisplayList takes them out of the list.
+type boxStart struct {
+	box *layout.LayoutBox
+}
+
+type boxEnd struct{}
+
+// newDisplayList returns the display list of commands, with the box
+// markers taken out and each command given the ID of the innermost box
+// around it.
+func newDisplayList(commands []DisplayCommand) DisplayList {
+	list := DisplayList{Commands: make([]DisplayCommand, 0, len(commands))}
+	ids := make(map[*layout.LayoutBox]string)
+	var open []string
+	for _, cmd := range commands {
+		switch c := cmd.(type) {
+		case boxStart:
+			open = append(open, boxID(c.box, ids))
+		case boxEnd:
+			open = open[:len(open)-1]
+		default:
+			id := ""
+			if len(open) > 0 {
+				id = open[len(open)-1]
+			}
+			list.Commands = append(list.Commands, cmd)
+			list.BoxIDs = append(list.BoxIDs, id)
+		}
+	}
+	return list
+}
+
+// boxID returns the path of child indexes from the root of the layout tree
+// to box, such as "0/2/1", remembering the IDs of box and its siblings in
+// ids.
+func boxID(box *layout.LayoutBox, ids map[*layout.LayoutBox]string) string {
+	if id, ok := ids[box]; ok {
+		return id
+	}
+	if box.Parent == nil {
+		ids[box] = "0"
+		return "0"
+	}
+	parent := boxID(box.Parent, ids)
+	for i, sibling := range box.Parent.Children {
+		ids[sibling] = parent + "/" + strconv.Itoa(i)
+	}
+	if id, ok := ids[box]; ok {
+		return id
+	}
+	// A box painted on its parent's behalf, like a list marker
+	id := parent + "/-"
+	ids[box] = id
+	return id
+}
@@ -0,0 +1,75 @@
+package render
+
+import (
+	"testing"
+
+	"browser/layout"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDisplayListsBoxIDs(t *testing.T) {
+	root := buildLayout(`<div id="a">One</div><div id="b">Two</div>`, `#a { background: red } #b { background: blue }`, 400)
+	normal, fixed := BuildDisplayLists(root, InputState{}, LinkStyler{})
+
+	assert.Empty(t, fixed.Commands)
+	assert.Len(t, normal.BoxIDs, len(normal.Commands))
+	for _, cmd := range normal.Commands {
+		switch cmd.(type) {
+		case boxStart, boxEnd:
+			t.Fatalf("box marker %T left in the display list", cmd)
+		}
+	}
+	assert.Equal(t, "", normal.BoxIDs[0], "the page background belongs to no box")
+
+	ids := make(map[string]bool)
+	for i, cmd := range normal.Commands {
+		if _, ok := cmd.(DrawRect); ok && i > 0 {
+			ids[normal.BoxIDs[i]] = true
+		}
+	}
+	assert.Len(t, ids, 2, "each div paints its background under its own ID")
+
+	again, _ := BuildDisplayLists(buildLayout(`<div id="a">One</div><div id="b">Two</div>`, `#a { background: red } #b { background: blue }`, 400), InputState{}, LinkStyler{})
+	assert.Equal(t, normal.BoxIDs, again.BoxIDs, "IDs are stable across layouts")
+}
+
+func TestDiffDisplayLists(t *testing.T) {
+	const page = `<div id="a">One</div><div id="b">Two</div>`
+	before, _ := BuildDisplayLists(buildLayout(page, `#a { background: red }`, 400), InputState{}, LinkStyler{})
+
+	t.Run("same layout", func(t *testing.T) {
+		after, _ := BuildDisplayLists(buildLayout(page, `#a { background: red }`, 400), InputState{}, LinkStyler{})
+		diff := DiffDisplayLists(before, after)
+		assert.True(t, diff.Unchanged())
+		assert.Empty(t, diff.Removed)
+		for i, prev := range diff.Previous {
+			assert.Equal(t, i, prev)
+		}
+	})
+
+	t.Run("one box restyled", func(t *testing.T) {
+		after, _ := BuildDisplayLists(buildLayout(page, `#a { background: blue }`, 400), InputState{}, LinkStyler{})
+		diff := DiffDisplayLists(before, after)
+		assert.False(t, diff.Unchanged())
+		var redrawn []DisplayCommand
+		for i, prev := range diff.Previous {
+			if prev < 0 {
+				redrawn = append(redrawn, after.Commands[i])
+			}
+		}
+		assert.Len(t, redrawn, 1, "only the background of #a is drawn again")
+		assert.Len(t, diff.Removed, 1)
+		assert.Equal(t, []layout.Rect{redrawn[0].(DrawRect).Rect}, diff.Damage)
+	})
+
+	t.Run("same command from another box", func(t *testing.T) {
+		rect := DrawRect{Rect: layout.Rect{Width: 10, Height: 10}, Color: ColorBlack}
+		prev := DisplayList{Commands: []DisplayCommand{rect}, BoxIDs: []string{"0/1"}}
+		next := DisplayList{Commands: []DisplayCommand{rect}, BoxIDs: []string{"0/2"}}
+		diff := DiffDisplayLists(prev, next)
+		assert.Equal(t, []int{-1}, diff.Previous)
+		assert.Equal(t, []int{0}, diff.Removed)
+		assert.Equal(t, []layout.Rect{rect.Rect}, diff.Damage)
+	})
+}
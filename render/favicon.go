@@ -0,0 +1,353 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"browser/dom"
+
+	"fyne.io/fyne/v2"
+)
+
+// Favicons (HTML §4.6.7.8). A page names its icons with <link rel="icon">;
+// the one whose sizes best fit a window icon is tried first, scalable ones
+// before all, and a site's /favicon.ico last. Icons are fetched like images,
+// so decoded ones stay in the HTTP cache; icons that fail to load are
+// remembered so every page of a site without one does not ask again.
+
+// faviconSize is the size in pixels icons are chosen for.
+const faviconSize = 32
+
+// favicons remembers the icon URLs that failed to load.
+var favicons = &faviconCache{failed: make(map[string]bool)}
+
+type faviconCache struct {
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+func (c *faviconCache) hasFailed(iconURL string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failed[iconURL]
+}
+
+func (c *faviconCache) fail(iconURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failed[iconURL] = true
+}
+
+// faviconCandidates returns the URLs to try for a page's icon in order:
+// links resolved with resolve, best fitting first, then the /favicon.ico
+// of pageURL's origin for pages served over HTTP.
+func faviconCandidates(links []dom.IconLink, resolve func(string) string, pageURL *url.URL) []string {
+	sorted := slices.Clone(links)
+	slices.SortStableFunc(sorted, func(a, b dom.IconLink) int {
+		return iconFit(a) - iconFit(b)
+	})
+	var candidates []string
+	for _, link := range sorted {
+		if href := strings.TrimSpace(link.Href); href != "" {
+			candidates = append(candidates, resolve(href))
+		}
+	}
+	if pageURL != nil && (pageURL.Scheme == "http" || pageURL.Scheme == "https") {
+		fallback := pageURL.Scheme + "://" + pageURL.Host + "/favicon.ico"
+		if !slices.Contains(candidates, fallback) {
+			candidates = append(candidates, fallback)
+		}
+	}
+	return candidates
+}
+
+// iconFit rates how well link fits a faviconSize icon, lower being better:
+// scalable icons fit exactly, smaller ones cost twice what larger ones do
+// as they blur when scaled up, and icons of unknown size come last.
+func iconFit(link dom.IconLink) int {
+	if link.Type == "image/svg+xml" || strings.HasSuffix(strings.ToLower(link.Href), ".svg") {
+		return 0
+	}
+	best := math.MaxInt32
+	for _, size := range strings.Fields(strings.ToLower(link.Sizes)) {
+		if size == "any" {
+			return 0
+		}
+		w, _, ok := strings.Cut(size, "x")
+		width, err := strconv.Atoi(w)
+		if !ok || err != nil || width <= 0 {
+			continue
+		}
+		cost := width - faviconSize
+		if width < faviconSize {
+			cost = 2 * (faviconSize - width)
+		}
+		best = min(best, 1+cost)
+	}
+	return best
+}
+
+// fetchFavicon returns the first of candidates that loads as an image, or
+// nil if none does.
+func fetchFavicon(candidates []string, pageURL string) image.Image {
+	for _, iconURL := range candidates {
+		if img, ok := cachedImage(iconURL); ok && img != nil {
+			return img
+		}
+		if favicons.hasFailed(iconURL) {
+			continue
+		}
+		img, err := fetchimageToCache(iconURL, "", pageURL)
+		if errors.Is(err, context.Canceled) {
+			return nil // navigated away meanwhile
+		}
+		if err != nil {
+			if err != errImageDeferred {
+				favicons.fail(iconURL)
+			}
+			continue
+		}
+		return img
+	}
+	return nil
+}
+
+// LoadFavicon fetches the current page's icon in the background and shows
+// it once loaded; see SetFaviconHandler.
+func (b *Browser) LoadFavicon() {
+	doc := b.document
+	var links []dom.IconLink
+	if doc != nil {
+		links = dom.FindIconLinks(doc)
+	}
+	candidates := faviconCandidates(links, b.resolveURL, b.currentURL)
+	pageURL := b.GetCurrentURL()
+	go func() {
+		icon := fetchFavicon(candidates, pageURL)
+		if b.document != doc {
+			return // navigated away meanwhile
+		}
+		b.setFavicon(icon)
+	}()
+}
+
+// Favicon returns the current page's icon, nil if it has none or it has
+// not loaded yet.
+func (b *Browser) Favicon() image.Image {
+	return b.favicon
+}
+
+// SetFaviconHandler sets the callback told of each page's icon, nil when
+// it has none, for the embedding UI to show. Without one the icon becomes
+// the window's.
+func (b *Browser) SetFaviconHandler(handler func(image.Image)) {
+	b.onFavicon = handler
+}
+
+func (b *Browser) setFavicon(icon image.Image) {
+	b.favicon = icon
+	if b.onFavicon != nil {
+		b.onFavicon(icon)
+		return
+	}
+	var resource fyne.Resource
+	if icon != nil {
+		var buf bytes.Buffer
+		if png.Encode(&buf, icon) == nil {
+			resource = fyne.NewStaticResource("favicon.png", buf.Bytes())
+		}
+	}
+	// A nil icon goes back to the application's
+	fyne.Do(func() { b.Window.SetIcon(resource) })
+}
+
+// ICO files hold one or more icons, each a PNG or a headerless BMP whose
+// height counts both its color bitmap and the 1 bit AND mask after it.
+// The largest is decoded.
+
+var errInvalidICO = errors.New("invalid ICO file")
+
+func init() {
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", decodeICO, decodeICOConfig)
+}
+
+// icoEntry is an icon's entry in an ICO file's directory.
+type icoEntry struct {
+	width, height int
+	bitCount      int
+	size, offset  uint32
+}
+
+// readICODirectory returns the entry of the largest icon in an ICO file,
+// the deepest of those largest.
+func readICODirectory(data []byte) (icoEntry, error) {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[2:]) != 1 {
+		return icoEntry{}, errInvalidICO
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:]))
+	var best icoEntry
+	found := false
+	for i := range count {
+		at := 6 + 16*i
+		if at+16 > len(data) {
+			break
+		}
+		d := data[at : at+16]
+		e := icoEntry{
+			width:    int(d[0]),
+			height:   int(d[1]),
+			bitCount: int(binary.LittleEndian.Uint16(d[6:])),
+			size:     binary.LittleEndian.Uint32(d[8:]),
+			offset:   binary.LittleEndian.Uint32(d[12:]),
+		}
+		// A zero means 256 pixels
+		if e.width == 0 {
+			e.width = 256
+		}
+		if e.height == 0 {
+			e.height = 256
+		}
+		area, bestArea := e.width*e.height, best.width*best.height
+		if !found || area > bestArea || area == bestArea && e.bitCount > best.bitCount {
+			best, found = e, true
+		}
+	}
+	if !found || uint64(best.offset)+uint64(best.size) > uint64(len(data)) {
+		return icoEntry{}, errInvalidICO
+	}
+	return best, nil
+}
+
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	e, err := readICODirectory(data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: e.width, Height: e.height}, nil
+}
+
+func decodeICO(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	e, err := readICODirectory(data)
+	if err != nil {
+		return nil, err
+	}
+	icon := data[e.offset : e.offset+e.size]
+	if bytes.HasPrefix(icon, []byte("\x89PNG\r\n\x1a\n")) {
+		return png.Decode(bytes.NewReader(icon))
+	}
+	return decodeICOBitmap(icon)
+}
+
+// decodeICOBitmap decodes an ICO file's BMP icon: a BITMAPINFOHEADER, a
+// palette for 8 bits per pixel or fewer, then bottom-up rows of pixels and
+// of the AND mask, each padded to 4 bytes. 32 bit pixels carry their own
+// alpha; the mask makes the others transparent where its bits are set.
+func decodeICOBitmap(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errInvalidICO
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+	bitCount := int(binary.LittleEndian.Uint16(data[14:]))
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:]))
+	if headerSize < 40 || headerSize > len(data) || width <= 0 || height <= 0 || width > 256 || height > 256 {
+		return nil, errInvalidICO
+	}
+	switch bitCount {
+	case 1, 4, 8, 24, 32:
+	default:
+		return nil, errInvalidICO
+	}
+
+	var palette []color.NRGBA
+	at := headerSize
+	if bitCount <= 8 {
+		n := colorsUsed
+		if n == 0 {
+			n = 1 << bitCount
+		}
+		if n > 1<<bitCount || at+4*n > len(data) {
+			return nil, errInvalidICO
+		}
+		for i := range n {
+			p := data[at+4*i : at+4*i+4]
+			palette = append(palette, color.NRGBA{p[2], p[1], p[0], 255})
+		}
+		at += 4 * n
+	}
+
+	// Every row is read whole below, so the pixels must all be there; the
+	// mask is optional but used only when complete
+	stride := (width*bitCount + 31) / 32 * 4
+	maskStride := (width + 31) / 32 * 4
+	if at+stride*height > len(data) {
+		return nil, errInvalidICO
+	}
+	pixels := data[at : at+stride*height]
+	mask := []byte(nil)
+	if rest := data[at+stride*height:]; len(rest) >= maskStride*height {
+		mask = rest[:maskStride*height]
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := range height {
+		row := pixels[(height-1-y)*stride:]
+		for x := range width {
+			var c color.NRGBA
+			switch bitCount {
+			case 32:
+				p := row[4*x:]
+				c = color.NRGBA{p[2], p[1], p[0], p[3]}
+				hasAlpha = hasAlpha || p[3] != 0
+			case 24:
+				p := row[3*x:]
+				c = color.NRGBA{p[2], p[1], p[0], 255}
+			default:
+				bit := x * bitCount
+				index := int(row[bit/8]>>(8-bitCount-bit%8)) & (1<<bitCount - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32 bit icons without any alpha rely on the mask like the others
+	if mask != nil && (bitCount != 32 || !hasAlpha) {
+		for y := range height {
+			row := mask[(height-1-y)*maskStride:]
+			for x := range width {
+				c := img.NRGBAAt(x, y)
+				c.A = 255
+				if row[x/8]&(0x80>>(x%8)) != 0 {
+					c.A = 0
+				}
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	return img, nil
+}
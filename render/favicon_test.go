@@ -0,0 +1,205 @@
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"browser/dom"
+	"browser/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// icoFile returns an ICO file holding icons, each given with its size.
+func icoFile(sizes []int, icons ...[]byte) []byte {
+	var dir, body bytes.Buffer
+	le := binary.LittleEndian
+	dir.Write([]byte{0, 0, 1, 0})
+	binary.Write(&dir, le, uint16(len(icons)))
+	offset := 6 + 16*len(icons)
+	for i, icon := range icons {
+		dir.Write([]byte{byte(sizes[i]), byte(sizes[i]), 0, 0, 1, 0, 32, 0})
+		binary.Write(&dir, le, uint32(len(icon)))
+		binary.Write(&dir, le, uint32(offset+body.Len()))
+		body.Write(icon)
+	}
+	return append(dir.Bytes(), body.Bytes()...)
+}
+
+// icoBitmap returns a 2x2 BMP icon of bitCount bits per pixel with the
+// given palette, bottom-up pixel rows and AND mask rows.
+func icoBitmap(bitCount int, palette []byte, rows, mask [][]byte) []byte {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	for _, v := range []uint32{40, 2, 4} {
+		binary.Write(&b, le, v)
+	}
+	binary.Write(&b, le, uint16(1))
+	binary.Write(&b, le, uint16(bitCount))
+	b.Write(make([]byte, 16))
+	binary.Write(&b, le, uint32(len(palette)/4))
+	b.Write(make([]byte, 4))
+	b.Write(palette)
+	for _, row := range append(rows, mask...) {
+		b.Write(row)
+	}
+	return b.Bytes()
+}
+
+func TestDecodeICO(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+
+	t.Run("the largest icon, stored as PNG", func(t *testing.T) {
+		big := image.NewNRGBA(image.Rect(0, 0, 3, 3))
+		big.SetNRGBA(1, 1, red)
+		var pngData bytes.Buffer
+		require.NoError(t, png.Encode(&pngData, big))
+		small := icoBitmap(32, nil, [][]byte{make([]byte, 8), make([]byte, 8)}, nil)
+
+		data := icoFile([]int{2, 3}, small, pngData.Bytes())
+		img, format, err := image.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, "ico", format)
+		assert.Equal(t, image.Rect(0, 0, 3, 3), img.Bounds())
+		assert.Equal(t, red, color.NRGBAModel.Convert(img.At(1, 1)))
+
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, 3, config.Width)
+	})
+
+	t.Run("32 bit pixels keep their alpha", func(t *testing.T) {
+		rows := [][]byte{
+			{0, 0, 255, 255, 255, 0, 0, 128}, // bottom: red, half blue
+			{0, 0, 0, 0, 0, 0, 0, 0},
+		}
+		img, err := decodeICO(bytes.NewReader(icoFile([]int{2}, icoBitmap(32, nil, rows, nil))))
+		require.NoError(t, err)
+		assert.Equal(t, red, img.At(0, 1))
+		assert.Equal(t, color.NRGBA{0, 0, 255, 128}, img.At(1, 1))
+		assert.Equal(t, color.NRGBA{}, img.At(0, 0))
+	})
+
+	t.Run("paletted pixels take the mask", func(t *testing.T) {
+		palette := []byte{255, 0, 0, 0, 0, 0, 255, 0} // blue, red
+		rows := [][]byte{{0b01000000, 0, 0, 0}, {0b10000000, 0, 0, 0}}
+		mask := [][]byte{{0, 0, 0, 0}, {0b01000000, 0, 0, 0}}
+		img, err := decodeICO(bytes.NewReader(icoFile([]int{2}, icoBitmap(1, palette, rows, mask))))
+		require.NoError(t, err)
+		assert.Equal(t, red, img.At(0, 0))
+		assert.Equal(t, uint8(0), img.(*image.NRGBA).NRGBAAt(1, 0).A, "masked out")
+		assert.Equal(t, blue, img.At(0, 1))
+		assert.Equal(t, red, img.At(1, 1))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := icoFile([]int{2}, icoBitmap(32, nil, [][]byte{make([]byte, 8), make([]byte, 8)}, nil))
+		_, err := decodeICO(bytes.NewReader(data[:len(data)-4]))
+		assert.ErrorIs(t, err, errInvalidICO)
+	})
+
+	t.Run("malformed bitmaps", func(t *testing.T) {
+		palette := []byte{255, 0, 0, 0, 0, 0, 255, 0}
+		rows := [][]byte{{0, 0, 0, 0}, {0, 0, 0, 0}}
+		le := binary.LittleEndian
+		tests := []struct {
+			name  string
+			patch func(bmp []byte) []byte
+		}{
+			{"header larger than the icon", func(bmp []byte) []byte {
+				le.PutUint32(bmp, 4000)
+				le.PutUint16(bmp[14:], 24)
+				return bmp
+			}},
+			{"header size overflowing", func(bmp []byte) []byte { le.PutUint32(bmp, 0xffffffff); return bmp }},
+			{"more colors than the bit count allows", func(bmp []byte) []byte { le.PutUint32(bmp[32:], 3); return bmp }},
+			{"palette past the end", func(bmp []byte) []byte { le.PutUint32(bmp[32:], 2); return bmp[:44] }},
+			{"pixels past the end", func(bmp []byte) []byte { return bmp[:len(bmp)-1] }},
+			{"unsupported bit count", func(bmp []byte) []byte { le.PutUint16(bmp[14:], 2); return bmp }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				bmp := tt.patch(icoBitmap(1, palette, rows, nil))
+				assert.NotPanics(t, func() {
+					_, err := decodeICO(bytes.NewReader(icoFile([]int{2}, bmp)))
+					assert.ErrorIs(t, err, errInvalidICO)
+				})
+			})
+		}
+	})
+}
+
+func TestFaviconCandidates(t *testing.T) {
+	page, _ := url.Parse("https://example.com/docs/page.html")
+	resolve := func(href string) string { return page.ResolveReference(&url.URL{Path: href}).String() }
+	links := []dom.IconLink{
+		{Href: "plain.ico"},
+		{Href: "20.png", Sizes: "20x20"},
+		{Href: "44.png", Sizes: "44X44"},
+		{Href: "both.png", Sizes: "16x16 48x48"},
+		{Href: "/logo.svg", Type: "image/svg+xml"},
+	}
+	assert.Equal(t, []string{
+		"https://example.com/logo.svg",
+		"https://example.com/docs/44.png",
+		"https://example.com/docs/both.png",
+		"https://example.com/docs/20.png",
+		"https://example.com/docs/plain.ico",
+		"https://example.com/favicon.ico",
+	}, faviconCandidates(links, resolve, page))
+
+	local, _ := url.Parse("file:///tmp/page.html")
+	assert.Empty(t, faviconCandidates(nil, resolve, local), "only sites have a /favicon.ico")
+}
+
+func TestLoadFavicon(t *testing.T) {
+	icon := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	var iconPNG bytes.Buffer
+	require.NoError(t, png.Encode(&iconPNG, icon))
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		if r.URL.Path != "/favicon.ico" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(iconPNG.Bytes())
+	}))
+	defer server.Close()
+	defer utils.HTTPCache.Clear(utils.SiteOf(server.URL), time.Time{})
+
+	load := func() image.Image {
+		doc := dom.Parse(strings.NewReader(`<link rel="icon" href="/missing.png"><p>page</p>`))
+		b := &Browser{document: doc}
+		b.SetCurrentURL(server.URL + "/page.html")
+		got := make(chan image.Image, 1)
+		b.SetFaviconHandler(func(img image.Image) { got <- img })
+		b.LoadFavicon()
+		select {
+		case img := <-got:
+			assert.Equal(t, img, b.Favicon())
+			return img
+		case <-time.After(5 * time.Second):
+			t.Fatal("no favicon")
+			return nil
+		}
+	}
+
+	img := load()
+	require.NotNil(t, img, "falls back to /favicon.ico")
+	assert.Equal(t, icon.Bounds(), img.Bounds())
+	load()
+	assert.Equal(t, map[string]int{"/missing.png": 1, "/favicon.ico": 1}, requests, "loaded and failed icons are cached")
+}
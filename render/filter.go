@@ -0,0 +1,257 @@
+package render
+
+import (
+	"image"
+	"math"
+	"slices"
+
+	"browser/css"
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/software"
+)
+
+// PushFilter starts a group of commands whose painted output is filtered
+// as a whole (Filter Effects 1 §5), until the matching PopFilter.
+type PushFilter struct {
+	Filters []css.Filter
+}
+
+// PopFilter ends the most recent PushFilter.
+type PopFilter struct{}
+
+// groupFilters returns the filters applied to all that a box with style
+// paints: its filter list, then its opacity. Opacity composites the group
+// as one layer (CSS Color 4 §3.2), so overlapping descendants of a
+// translucent box do not show through each other.
+func groupFilters(style css.Style) []css.Filter {
+	filters := style.Filters
+	if style.Opacity > 0 && style.Opacity < 1 {
+		filters = append(slices.Clip(filters), css.Filter{Name: "opacity", Amount: style.Opacity})
+	}
+	return filters
+}
+
+// filterGroup is an open PushFilter with the index of its first object.
+type filterGroup struct {
+	filters []css.Filter
+	start   int
+}
+
+// blurExtent is how many standard deviations a blurred group grows by on
+// each side; the gaussian is negligible beyond it.
+const blurExtent = 3
+
+// filterObjects renders the objects of a filter group offscreen, applies
+// the filters to the pixels and returns the result as a single image.
+func filterObjects(objects []fyne.CanvasObject, filters []css.Filter) []fyne.CanvasObject {
+	bounds, ok := filterBounds(objects)
+	if !ok {
+		return nil
+	}
+	for _, f := range filters {
+		if f.Name == "blur" {
+			pad := math.Ceil(f.Amount * blurExtent)
+			bounds = layout.Rect{X: bounds.X - pad, Y: bounds.Y - pad, Width: bounds.Width + 2*pad, Height: bounds.Height + 2*pad}
+		}
+	}
+
+	// Draw the group relative to its bounds at device resolution
+	for _, obj := range objects {
+		pos := obj.Position()
+		obj.Move(fyne.NewPos(pos.X-float32(bounds.X), pos.Y-float32(bounds.Y)))
+	}
+	scale := float32(TextRaster.DeviceScale())
+	offscreen := software.NewTransparentCanvas()
+	offscreen.SetPadded(false)
+	offscreen.SetScale(scale)
+	offscreen.SetContent(container.NewWithoutLayout(objects...))
+	offscreen.Resize(fyne.NewSize(float32(bounds.Width), float32(bounds.Height)))
+
+	captured := offscreen.Capture()
+	img, ok := captured.(*image.NRGBA)
+	if !ok {
+		img = image.NewNRGBA(captured.Bounds())
+		for y := captured.Bounds().Min.Y; y < captured.Bounds().Max.Y; y++ {
+			for x := captured.Bounds().Min.X; x < captured.Bounds().Max.X; x++ {
+				img.Set(x, y, captured.At(x, y))
+			}
+		}
+	}
+	applyFilters(img, filters, float64(scale))
+
+	out := canvas.NewImageFromImage(img)
+	out.FillMode = canvas.ImageFillStretch
+	out.Resize(fyne.NewSize(float32(bounds.Width), float32(bounds.Height)))
+	out.Move(fyne.NewPos(float32(bounds.X), float32(bounds.Y)))
+	return []fyne.CanvasObject{out}
+}
+
+// filterBounds returns the whole-pixel rect covering the objects. Objects
+// without a size, like text, are measured by their minimum size.
+func filterBounds(objects []fyne.CanvasObject) (layout.Rect, bool) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, obj := range objects {
+		pos, size := obj.Position(), obj.Size()
+		if size.Width <= 0 || size.Height <= 0 {
+			size = obj.MinSize()
+		}
+		if size.Width <= 0 || size.Height <= 0 {
+			continue
+		}
+		minX = math.Min(minX, float64(pos.X))
+		minY = math.Min(minY, float64(pos.Y))
+		maxX = math.Max(maxX, float64(pos.X+size.Width))
+		maxY = math.Max(maxY, float64(pos.Y+size.Height))
+	}
+	if minX >= maxX || minY >= maxY {
+		return layout.Rect{}, false
+	}
+	minX, minY = math.Floor(minX), math.Floor(minY)
+	return layout.Rect{X: minX, Y: minY, Width: math.Ceil(maxX) - minX, Height: math.Ceil(maxY) - minY}, true
+}
+
+// applyFilters runs the filters over img in order. scale converts blur
+// radii from CSS pixels to image pixels.
+func applyFilters(img *image.NRGBA, filters []css.Filter, scale float64) {
+	for _, f := range filters {
+		if f.Name == "blur" {
+			gaussianBlur(img, f.Amount*scale)
+			continue
+		}
+		for i := 0; i+3 < len(img.Pix); i += 4 {
+			px := img.Pix[i : i+4 : i+4]
+			r, g, b, a := filterPixel(f, float64(px[0])/255, float64(px[1])/255, float64(px[2])/255, float64(px[3])/255)
+			px[0], px[1], px[2], px[3] = toByte(r), toByte(g), toByte(b), toByte(a)
+		}
+	}
+}
+
+// filterPixel applies one color filter to unpremultiplied components in
+// [0, 1], using the equivalent matrices of Filter Effects 1 §12.
+func filterPixel(f css.Filter, r, g, b, a float64) (float64, float64, float64, float64) {
+	amount := f.Amount
+	switch f.Name {
+	case "grayscale":
+		s := 1 - amount
+		return (0.2126+0.7874*s)*r + (0.7152-0.7152*s)*g + (0.0722-0.0722*s)*b,
+			(0.2126-0.2126*s)*r + (0.7152+0.2848*s)*g + (0.0722-0.0722*s)*b,
+			(0.2126-0.2126*s)*r + (0.7152-0.7152*s)*g + (0.0722+0.9278*s)*b,
+			a
+	case "sepia":
+		s := 1 - amount
+		return (0.393+0.607*s)*r + (0.769-0.769*s)*g + (0.189-0.189*s)*b,
+			(0.349-0.349*s)*r + (0.686+0.314*s)*g + (0.168-0.168*s)*b,
+			(0.272-0.272*s)*r + (0.534-0.534*s)*g + (0.131+0.869*s)*b,
+			a
+	case "brightness":
+		return r * amount, g * amount, b * amount, a
+	case "contrast":
+		intercept := 0.5 - 0.5*amount
+		return r*amount + intercept, g*amount + intercept, b*amount + intercept, a
+	case "invert":
+		return amount + r*(1-2*amount), amount + g*(1-2*amount), amount + b*(1-2*amount), a
+	case "opacity":
+		return r, g, b, a * amount
+	}
+	return r, g, b, a
+}
+
+// toByte converts a component in [0, 1] to 8 bits, clamping overflow.
+func toByte(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
+
+// gaussianBlur blurs img with standard deviation sigma in pixels,
+// approximated by three successive box blurs. Pixels beyond the image are
+// transparent.
+func gaussianBlur(img *image.NRGBA, sigma float64) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if sigma <= 0 || w == 0 || h == 0 {
+		return
+	}
+
+	// Blur premultiplied values so transparent pixels do not bleed color
+	buf := make([]float64, w*h*4)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := img.PixOffset(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+			a := float64(img.Pix[i+3]) / 255
+			j := (y*w + x) * 4
+			buf[j] = float64(img.Pix[i]) / 255 * a
+			buf[j+1] = float64(img.Pix[i+1]) / 255 * a
+			buf[j+2] = float64(img.Pix[i+2]) / 255 * a
+			buf[j+3] = a
+		}
+	}
+	tmp := make([]float64, len(buf))
+	for _, size := range gaussianBoxSizes(sigma, 3) {
+		radius := (size - 1) / 2
+		boxBlur(buf, tmp, h, w, radius, 4, w*4)
+		boxBlur(tmp, buf, w, h, radius, w*4, 4)
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := img.PixOffset(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+			j := (y*w + x) * 4
+			a := buf[j+3]
+			if a <= 0 {
+				img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 0, 0, 0, 0
+				continue
+			}
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = toByte(buf[j]/a), toByte(buf[j+1]/a), toByte(buf[j+2]/a), toByte(a)
+		}
+	}
+}
+
+// gaussianBoxSizes returns the widths of n box blurs that together
+// approximate a gaussian of standard deviation sigma.
+func gaussianBoxSizes(sigma float64, n int) []int {
+	ideal := math.Sqrt(12*sigma*sigma/float64(n) + 1)
+	lower := int(math.Floor(ideal))
+	if lower%2 == 0 {
+		lower--
+	}
+	upper := lower + 2
+	l := float64(lower)
+	m := int(math.Round((12*sigma*sigma - float64(n)*l*l - 4*float64(n)*l - 3*float64(n)) / (-4*l - 4)))
+	sizes := make([]int, n)
+	for i := range sizes {
+		if i < m {
+			sizes[i] = lower
+		} else {
+			sizes[i] = upper
+		}
+	}
+	return sizes
+}
+
+// boxBlur averages each pixel of src with its radius neighbours along one
+// axis into dst. The buffer holds lines of length pixels; step is the
+// distance between neighbours and stride between lines, both in values.
+func boxBlur(src, dst []float64, lines, length, radius, step, stride int) {
+	width := float64(2*radius + 1)
+	for line := 0; line < lines; line++ {
+		base := line * stride
+		for c := 0; c < 4; c++ {
+			var sum float64
+			for k := 0; k <= radius && k < length; k++ {
+				sum += src[base+k*step+c]
+			}
+			for k := 0; k < length; k++ {
+				dst[base+k*step+c] = sum / width
+				if in := k + radius + 1; in < length {
+					sum += src[base+in*step+c]
+				}
+				if out := k - radius; out >= 0 {
+					sum -= src[base+out*step+c]
+				}
+			}
+		}
+	}
+}
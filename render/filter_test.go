@@ -0,0 +1,128 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"browser/css"
+	"browser/layout"
+
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []css.Filter
+		in      color.NRGBA
+		want    color.NRGBA
+	}{
+		{"grayscale keeps luminance", []css.Filter{{Name: "grayscale", Amount: 1}}, color.NRGBA{255, 0, 0, 255}, color.NRGBA{54, 54, 54, 255}},
+		{"partial grayscale", []css.Filter{{Name: "grayscale", Amount: 0.5}}, color.NRGBA{255, 0, 0, 255}, color.NRGBA{155, 27, 27, 255}},
+		{"brightness scales", []css.Filter{{Name: "brightness", Amount: 0.5}}, color.NRGBA{200, 100, 50, 255}, color.NRGBA{100, 50, 25, 255}},
+		{"brightness clamps", []css.Filter{{Name: "brightness", Amount: 2}}, color.NRGBA{200, 100, 50, 255}, color.NRGBA{255, 200, 100, 255}},
+		{"contrast zero is mid gray", []css.Filter{{Name: "contrast", Amount: 0}}, color.NRGBA{200, 100, 50, 255}, color.NRGBA{128, 128, 128, 255}},
+		{"invert", []css.Filter{{Name: "invert", Amount: 1}}, color.NRGBA{200, 100, 50, 255}, color.NRGBA{55, 155, 205, 255}},
+		{"sepia", []css.Filter{{Name: "sepia", Amount: 1}}, color.NRGBA{255, 255, 255, 255}, color.NRGBA{255, 255, 239, 255}},
+		{"opacity lowers alpha", []css.Filter{{Name: "opacity", Amount: 0.5}}, color.NRGBA{200, 100, 50, 200}, color.NRGBA{200, 100, 50, 100}},
+		{"filters apply in order", []css.Filter{{Name: "grayscale", Amount: 1}, {Name: "invert", Amount: 1}}, color.NRGBA{255, 0, 0, 255}, color.NRGBA{201, 201, 201, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.SetNRGBA(0, 0, tt.in)
+			applyFilters(img, tt.filters, 1)
+			assert.Equal(t, tt.want, img.NRGBAAt(0, 0))
+		})
+	}
+}
+
+func TestGaussianBlur(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 21, 21))
+	img.SetNRGBA(10, 10, color.NRGBA{255, 0, 0, 255})
+
+	applyFilters(img, []css.Filter{{Name: "blur", Amount: 2}}, 1)
+
+	center := img.NRGBAAt(10, 10)
+	assert.Less(t, center.A, uint8(255), "the dot spreads out")
+	assert.Greater(t, img.NRGBAAt(12, 10).A, uint8(0))
+	assert.Equal(t, img.NRGBAAt(8, 10), img.NRGBAAt(12, 10), "the blur is symmetric")
+	assert.Equal(t, img.NRGBAAt(10, 8), img.NRGBAAt(10, 12))
+	assert.Equal(t, color.NRGBA{255, 0, 0, center.A}, center, "transparent neighbours do not darken the color")
+	assert.Equal(t, uint8(0), img.NRGBAAt(0, 0).A, "pixels beyond the blur stay transparent")
+}
+
+func TestGaussianBoxSizes(t *testing.T) {
+	assert.Equal(t, []int{1, 1, 1}, gaussianBoxSizes(0.1, 3))
+	assert.Equal(t, []int{3, 3, 5}, gaussianBoxSizes(2, 3))
+	assert.Equal(t, []int{11, 11, 11}, gaussianBoxSizes(5.5, 3))
+}
+
+func TestFilterCommands(t *testing.T) {
+	root := buildLayout(`<div id="box"><p>text</p></div><div id="after"></div>`,
+		`div { height: 20px; background: gray } #box { width: 101px; filter: grayscale(1) } #after { width: 103px }`, 800)
+
+	var events []string
+	for _, cmd := range BuildDisplayList(root, InputState{}, LinkStyler{}) {
+		switch c := cmd.(type) {
+		case PushFilter:
+			assert.Equal(t, []css.Filter{{Name: "grayscale", Amount: 1}}, c.Filters)
+			events = append(events, "push")
+		case PopFilter:
+			events = append(events, "pop")
+		case DrawRect:
+			if name, ok := map[float64]string{101: "box", 103: "after"}[c.Width]; ok {
+				events = append(events, name)
+			}
+		case DrawText:
+			events = append(events, "text")
+		}
+	}
+	// Like opacity, a filter makes the box a stacking context, painted over
+	// the in-flow blocks that follow it
+	assert.Equal(t, []string{"after", "push", "box", "text", "pop"}, events)
+}
+
+func TestOpacityGroupCommands(t *testing.T) {
+	root := buildLayout(`<div id="box"><p id="a"></p><p id="b"></p></div>`,
+		`#box { opacity: 0.5; filter: grayscale(1) } p { height: 20px; margin: 0; background: red }`, 800)
+
+	var pushes [][]css.Filter
+	var fills []color.Color
+	for _, cmd := range BuildDisplayList(root, InputState{}, LinkStyler{}) {
+		switch c := cmd.(type) {
+		case PushFilter:
+			pushes = append(pushes, c.Filters)
+		case DrawRect:
+			fills = append(fills, c.Color)
+		}
+	}
+	// Opacity composites after the filter, once for the whole group
+	assert.Equal(t, [][]css.Filter{{{Name: "grayscale", Amount: 1}, {Name: "opacity", Amount: 0.5}}}, pushes)
+	for _, fill := range fills {
+		_, _, _, a := fill.RGBA()
+		assert.Equal(t, uint32(0xffff), a, "descendants paint opaque inside the group")
+	}
+}
+
+func TestOpacityGroupComposite(t *testing.T) {
+	test.NewApp()
+	red := color.RGBA{255, 0, 0, 255}
+	objects := renderToCanvas([]DisplayCommand{
+		PushFilter{Filters: groupFilters(css.Style{Opacity: 0.5})},
+		DrawRect{Rect: layout.Rect{Width: 20, Height: 10}, Color: red},
+		DrawRect{Rect: layout.Rect{X: 10, Width: 20, Height: 10}, Color: red},
+		PopFilter{},
+	}, "", "", false, nil, nil)
+
+	if assert.Len(t, objects, 1) {
+		img := objects[0].(*canvas.Image).Image.(*image.NRGBA)
+		single, overlap := img.NRGBAAt(5, 5), img.NRGBAAt(15, 5)
+		assert.InDelta(t, 128, int(single.A), 1)
+		assert.Equal(t, single, overlap, "the overlap is not darker")
+	}
+}
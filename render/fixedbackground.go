@@ -0,0 +1,216 @@
+package render
+
+import (
+	"image/color"
+	"slices"
+
+	"browser/css"
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+)
+
+// Fixed backgrounds (CSS Backgrounds §3.5). A fixed background layer stays
+// put in the viewport while its box scrolls over it. Boxes on the fixed
+// layer are drawn in viewport coordinates already, so their fixed layers
+// are simply positioned in the viewport. A box on the page has its whole
+// background drawn on the fixed layer instead, beneath the page, through
+// a hole the page leaves where the box is: the boxes around it and the
+// page canvas have their backgrounds cut there. Scrolling moves only the
+// clip the background is seen through, so the page is not drawn again.
+// The hole is the box's border box, without its rounded corners.
+
+// DrawFixedBackground draws, beneath the page, the background of a box on
+// it with a fixed layer. Rect is the part of the box in view of the
+// scroll containers around it, in page coordinates; Color fills it, and
+// Layers are drawn over it from the bottom up, the fixed ones in viewport
+// coordinates and the others relative to Rect.
+type DrawFixedBackground struct {
+	layout.Rect
+	Color  color.Color
+	Layers []DrawImage
+}
+
+// fixedBackgroundBoxes is what BuildDisplayLists finds of the boxes on the
+// page with a fixed background layer.
+type fixedBackgroundBoxes struct {
+	commands []DisplayCommand                    // one DrawFixedBackground per box, in tree order
+	holes    map[*layout.LayoutBox][]layout.Rect // what each box around them leaves of its background
+	canvas   []layout.Rect                       // every hole, cut from the page canvas
+	beneath  map[*layout.LayoutBox]bool          // the boxes whose background is drawn beneath the page
+}
+
+// findFixedBackgrounds finds the boxes on the page under root with a fixed
+// background layer, positioned in the viewport of state. It returns nil
+// without a viewport, as when printing, where fixed layers scroll with
+// their boxes.
+func findFixedBackgrounds(root *layout.LayoutBox, state InputState) *fixedBackgroundBoxes {
+	if state.Viewport.Width <= 0 || state.Viewport.Height <= 0 {
+		return nil
+	}
+	f := &fixedBackgroundBoxes{holes: make(map[*layout.LayoutBox][]layout.Rect), beneath: make(map[*layout.LayoutBox]bool)}
+	f.find(root, nil, layout.Rect{}, 0, 0, false, state)
+	return f
+}
+
+// find looks for fixed backgrounds in box and its descendants. clip is
+// where the overflow clips of the boxes around it leave it visible, empty
+// when none clips; dx and dy are how far the scroll containers around it
+// have scrolled.
+func (f *fixedBackgroundBoxes) find(box *layout.LayoutBox, ancestors []*layout.LayoutBox, clip layout.Rect, dx, dy float64, hidden bool, state InputState) {
+	if box.Position == "fixed" {
+		return // on the fixed layer, with its descendants
+	}
+	if box.Style.Visibility != "" {
+		hidden = box.Style.Visibility == "hidden"
+	}
+	rect := box.Rect
+	rect.X, rect.Y = rect.X-dx, rect.Y-dy
+
+	layers := box.Style.BackgroundLayers()
+	fixed := slices.ContainsFunc(layers, func(layer css.BackgroundLayer) bool { return layer.Attachment == "fixed" })
+	if visible, ok := clipTo(rect, clip); fixed && !hidden && ok {
+		f.beneath[box] = true
+		f.canvas = append(f.canvas, visible)
+		for _, ancestor := range ancestors {
+			f.holes[ancestor] = append(f.holes[ancestor], visible)
+		}
+		draw := DrawFixedBackground{Rect: visible, Color: box.Style.BackgroundColor}
+		view := layout.Rect{Width: state.Viewport.Width, Height: state.Viewport.Height}
+		for _, layer := range slices.Backward(layers) {
+			img := DrawImage{
+				Rect:       layout.Rect{X: rect.X - visible.X, Y: rect.Y - visible.Y, Width: rect.Width, Height: rect.Height},
+				URL:        layer.Image,
+				SizeMode:   layer.Size,
+				Background: true,
+				Position:   layer.Position,
+				Repeat:     layer.Repeat,
+			}
+			if layer.Attachment == "fixed" {
+				img.Rect, img.Area = view, view
+			}
+			draw.Layers = append(draw.Layers, img)
+		}
+		f.commands = append(f.commands, draw)
+	}
+
+	if clipsOverflow(box) {
+		padding := paddingClip(box, rect).Rect
+		if clip == (layout.Rect{}) {
+			clip = padding
+		} else if clip, _ = clipTo(padding, clip); clip == (layout.Rect{}) {
+			return // clipped away, descendants included
+		}
+	}
+	if box.Node != nil {
+		dx += state.ScrollOffsets[box.Node]
+		dy += state.ScrollOffsetsY[box.Node]
+	}
+	ancestors = append(ancestors, box)
+	for _, child := range box.Children {
+		f.find(child, ancestors, clip, dx, dy, hidden, state)
+	}
+}
+
+// clipTo returns the part of r inside clip, r itself for an empty clip.
+func clipTo(r, clip layout.Rect) (layout.Rect, bool) {
+	if clip == (layout.Rect{}) {
+		return r, r.Width > 0 && r.Height > 0
+	}
+	return clipStack{{Rect: clip}}.clipRect(r)
+}
+
+// holesIn returns the holes box leaves in its background; f may be nil.
+func (f *fixedBackgroundBoxes) holesIn(box *layout.LayoutBox) []layout.Rect {
+	if f == nil {
+		return nil
+	}
+	return f.holes[box]
+}
+
+// isBeneath reports whether box has its background drawn beneath the page;
+// f may be nil.
+func (f *fixedBackgroundBoxes) isBeneath(box *layout.LayoutBox) bool {
+	return f != nil && f.beneath[box]
+}
+
+// cutHoles wraps commands, painting backgrounds, in clips that leave holes
+// out of them.
+func cutHoles(holes []layout.Rect, commands []DisplayCommand) []DisplayCommand {
+	if len(holes) == 0 || len(commands) == 0 {
+		return commands
+	}
+	cut := make([]DisplayCommand, 0, len(commands)+2*len(holes))
+	for _, hole := range holes {
+		cut = append(cut, PushClip{Rect: hole, Exclude: true})
+	}
+	cut = append(cut, commands...)
+	for range holes {
+		cut = append(cut, PopClip{})
+	}
+	return cut
+}
+
+// fixedBackground is what a DrawFixedBackground draws: its layers, seen
+// through a clip at the part of the box in view.
+type fixedBackground struct {
+	rect  layout.Rect         // the part of the box in view, on the page
+	clip  *container.Scroll   // a scroll container that does not scroll, clipping what it holds
+	fixed []fyne.CanvasObject // the layers drawn in viewport coordinates
+}
+
+// newFixedBackground draws c, as renderToCanvas draws its layers.
+func newFixedBackground(c DrawFixedBackground, baseURL, pageURL string, useCache bool, onImageLoad func(), frame *frameObjects) *fixedBackground {
+	bg := &fixedBackground{rect: c.Rect}
+	var layers []fyne.CanvasObject
+	if c.Color != nil {
+		fill := renderToCanvas([]DisplayCommand{DrawRect{Rect: layout.Rect{Width: c.Width, Height: c.Height}, Color: c.Color}}, baseURL, pageURL, useCache, onImageLoad, frame)
+		layers = append(layers, fill...)
+	}
+	for _, img := range c.Layers {
+		layer := container.NewWithoutLayout(renderToCanvas([]DisplayCommand{img}, baseURL, pageURL, useCache, onImageLoad, frame)...)
+		if img.Area != (layout.Rect{}) {
+			bg.fixed = append(bg.fixed, layer)
+		}
+		layers = append(layers, layer)
+	}
+	bg.clip = container.NewScroll(container.NewWithoutLayout(layers...))
+	bg.clip.Direction = container.ScrollNone
+	bg.clip.Resize(fyne.NewSize(float32(c.Width), float32(c.Height)))
+	return bg
+}
+
+// place shows bg where its box is with the page scrolled to offset, its
+// fixed layers staying where they are in the viewport.
+func (bg *fixedBackground) place(offset fyne.Position) {
+	at := fyne.NewPos(float32(bg.rect.X)-offset.X, float32(bg.rect.Y)-offset.Y)
+	bg.clip.Move(at)
+	for _, layer := range bg.fixed {
+		layer.Move(fyne.NewPos(-at.X, -at.Y))
+	}
+}
+
+// fixedBackdrop returns the layer beneath the page showing backgrounds,
+// drawn by the fixed layer's last frame, in place for the page's scroll
+// offset.
+func (b *Browser) fixedBackdrop(backgrounds []*fixedBackground) *fyne.Container {
+	b.fixedBackgrounds = backgrounds
+	backdrop := container.NewWithoutLayout()
+	for _, bg := range backgrounds {
+		backdrop.Add(bg.clip)
+	}
+	b.placeFixedBackgrounds()
+	return backdrop
+}
+
+// placeFixedBackgrounds moves the fixed backgrounds to where their boxes
+// are as the page scrolls.
+func (b *Browser) placeFixedBackgrounds() {
+	if b.contentScroll == nil {
+		return
+	}
+	for _, bg := range b.fixedBackgrounds {
+		bg.place(b.contentScroll.Offset)
+	}
+}
@@ -0,0 +1,357 @@
+package render
+
+import (
+	"encoding/binary"
+	"image/color"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+)
+
+// fallbackClass groups Unicode ranges that share a font fallback chain.
+type fallbackClass int
+
+const (
+	fallbackDefault fallbackClass = iota // covered by the theme font
+	fallbackEmoji
+	fallbackCJK
+	fallbackCyrillic
+	fallbackGreek
+	fallbackArabic
+	fallbackHebrew
+	fallbackDevanagari
+	fallbackThai
+	fallbackSymbol
+)
+
+// fallbackChains lists font files to try, in order, for each class. Names
+// cover Linux (Noto/DejaVu), macOS and Windows system fonts. Color emoji fonts
+// come first so CBDT (Noto), sbix (Apple) and COLR (Segoe) glyphs are picked
+// over monochrome outlines.
+var fallbackChains = map[fallbackClass][]string{
+	fallbackEmoji: {
+		"NotoColorEmoji.ttf", "Apple Color Emoji.ttc", "seguiemj.ttf",
+		"TwemojiMozilla.ttf", "NotoEmoji-Regular.ttf", "Symbola.ttf",
+	},
+	fallbackCJK: {
+		"NotoSansCJK-Regular.ttc", "NotoSansCJKsc-Regular.otf", "NotoSansCJKjp-Regular.otf",
+		"wqy-microhei.ttc", "wqy-zenhei.ttc", "PingFang.ttc", "Hiragino Sans GB.ttc",
+		"msyh.ttc", "YuGothR.ttc", "malgun.ttf",
+	},
+	fallbackCyrillic: {"DejaVuSans.ttf", "NotoSans-Regular.ttf", "LiberationSans-Regular.ttf", "Arial.ttf", "arial.ttf"},
+	fallbackGreek:    {"DejaVuSans.ttf", "NotoSans-Regular.ttf", "LiberationSans-Regular.ttf", "Arial.ttf", "arial.ttf"},
+	fallbackArabic:   {"NotoSansArabic-Regular.ttf", "NotoNaskhArabic-Regular.ttf", "GeezaPro.ttc", "tahoma.ttf"},
+	fallbackHebrew:   {"NotoSansHebrew-Regular.ttf", "ArialHB.ttc", "david.ttf", "tahoma.ttf"},
+	fallbackDevanagari: {
+		"NotoSansDevanagari-Regular.ttf", "Lohit-Devanagari.ttf", "DevanagariMT.ttc", "mangal.ttf",
+	},
+	fallbackThai:   {"NotoSansThai-Regular.ttf", "Thonburi.ttc", "tahoma.ttf"},
+	fallbackSymbol: {"DejaVuSans.ttf", "NotoSansSymbols2-Regular.ttf", "Apple Symbols.ttf", "seguisym.ttf"},
+}
+
+// isEmojiRune reports whether r is rendered with emoji presentation.
+func isEmojiRune(r rune) bool {
+	switch {
+	case r >= 0x1F300 && r <= 0x1FAFF: // pictographs, emoticons, transport, supplemental
+		return true
+	case r >= 0x1F1E6 && r <= 0x1F1FF: // regional indicators (flags)
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // misc symbols and dingbats
+		return true
+	case r == 0x2B50 || r == 0x2B55 || r == 0x231A || r == 0x231B || r == 0x23F0 || r == 0x23F3:
+		return true
+	}
+	return false
+}
+
+// classifyRune returns the fallback class for a single rune.
+func classifyRune(r rune) fallbackClass {
+	switch {
+	case r < 0x0370:
+		return fallbackDefault
+	case isEmojiRune(r):
+		return fallbackEmoji
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r),
+		unicode.Is(unicode.Hangul, r), r >= 0x3000 && r <= 0x303F, r >= 0xFF00 && r <= 0xFFEF:
+		return fallbackCJK
+	case unicode.Is(unicode.Cyrillic, r):
+		return fallbackCyrillic
+	case unicode.Is(unicode.Greek, r):
+		return fallbackGreek
+	case unicode.Is(unicode.Arabic, r):
+		return fallbackArabic
+	case unicode.Is(unicode.Hebrew, r):
+		return fallbackHebrew
+	case unicode.Is(unicode.Devanagari, r):
+		return fallbackDevanagari
+	case unicode.Is(unicode.Thai, r):
+		return fallbackThai
+	case r >= 0x2190 && r <= 0x2BFF:
+		return fallbackSymbol
+	}
+	return fallbackDefault
+}
+
+// classifyCluster returns the fallback class of a grapheme cluster: that
+// of its first character, or emoji when a variation selector or keycap
+// asks for emoji presentation, as in "1️⃣".
+func classifyCluster(cluster string) fallbackClass {
+	if strings.ContainsAny(cluster, "\uFE0F\u20E3") {
+		return fallbackEmoji
+	}
+	r, _ := utf8.DecodeRuneInString(cluster)
+	return classifyRune(r)
+}
+
+// fallbackRun is a stretch of text that resolves to one fallback class.
+type fallbackRun struct {
+	text  string
+	class fallbackClass
+}
+
+// splitFallbackRuns segments text into runs by fallback class, a grapheme
+// cluster at a time so combining marks and ZWJ sequences stay with the
+// character they belong to. Whitespace stays with the current run so words
+// are not split.
+func splitFallbackRuns(text string) []fallbackRun {
+	var runs []fallbackRun
+	start, end := 0, 0
+	for _, cluster := range layout.GraphemeClusters(text) {
+		class := classifyCluster(cluster)
+		if len(runs) > 0 && strings.TrimSpace(cluster) == "" {
+			class = runs[len(runs)-1].class
+		}
+		end += len(cluster)
+		if n := len(runs); n > 0 && runs[n-1].class == class {
+			runs[n-1].text = text[start:end]
+			continue
+		}
+		start = end - len(cluster)
+		runs = append(runs, fallbackRun{text: text[start:end], class: class})
+	}
+	return runs
+}
+
+var (
+	fallbackPaths   = make(map[fallbackClass][]string)
+	fallbackFound   = make(map[fallbackClass]bool)
+	fallbackPathsMu sync.Mutex
+)
+
+// fallbackFontPaths returns the installed fonts of the class's chain, in
+// order.
+func fallbackFontPaths(class fallbackClass) []string {
+	fallbackPathsMu.Lock()
+	defer fallbackPathsMu.Unlock()
+	if fallbackFound[class] {
+		return fallbackPaths[class]
+	}
+	fallbackFound[class] = true
+
+	fonts := systemFonts()
+	for _, name := range fallbackChains[class] {
+		if path, ok := fonts.byFile[strings.ToLower(name)]; ok {
+			fallbackPaths[class] = append(fallbackPaths[class], path)
+		}
+	}
+	return fallbackPaths[class]
+}
+
+// clusterFont returns the font to draw a grapheme cluster of text in
+// primary (nil for the theme font) with: the first of primary and the
+// class's fallback chain that has glyphs for the whole cluster, the chain
+// first for emoji so color glyphs win over monochrome ones. It returns nil
+// when the theme font has them, or none does, so Fyne draws the cluster
+// and looks through the installed fonts itself.
+func clusterFont(cluster string, class fallbackClass, primary fyne.Resource) fyne.Resource {
+	if class == fallbackDefault && primary == nil {
+		return nil
+	}
+	own := primary
+	if own == nil {
+		own = theme.TextFont()
+	}
+	if class != fallbackEmoji && fontCovers(own, cluster) {
+		return primary
+	}
+	for _, path := range fallbackFontPaths(class) {
+		if font := loadFontResource(path); font != nil && fontCovers(font, cluster) {
+			return font
+		}
+	}
+	if class == fallbackEmoji && fontCovers(own, cluster) {
+		return primary
+	}
+	return nil
+}
+
+// fontRun is a stretch of text drawn in one font, nil for the theme font.
+type fontRun struct {
+	text string
+	font fyne.Resource
+}
+
+// splitFontRuns segments text drawn in primary into runs of the font
+// each grapheme cluster resolves to. Whitespace stays with the current run.
+func splitFontRuns(text string, primary fyne.Resource) []fontRun {
+	var runs []fontRun
+	for _, run := range splitFallbackRuns(text) {
+		if run.class == fallbackDefault && primary == nil {
+			runs = appendFontRun(runs, run.text, nil)
+			continue
+		}
+		for _, cluster := range layout.GraphemeClusters(run.text) {
+			if len(runs) > 0 && strings.TrimSpace(cluster) == "" {
+				runs[len(runs)-1].text += cluster
+				continue
+			}
+			runs = appendFontRun(runs, cluster, clusterFont(cluster, run.class, primary))
+		}
+	}
+	return runs
+}
+
+// appendFontRun adds text in font to the end of runs.
+func appendFontRun(runs []fontRun, text string, font fyne.Resource) []fontRun {
+	if n := len(runs); n > 0 && runs[n-1].font == font {
+		runs[n-1].text += text
+		return runs
+	}
+	return append(runs, fontRun{text: text, font: font})
+}
+
+// measureFallbackRun returns the advance width of a run in its resolved font.
+func measureFallbackRun(text string, size float32, style fyne.TextStyle, source fyne.Resource) float32 {
+	if source == nil || fyne.CurrentApp() == nil {
+		return fyne.MeasureText(text, size, style).Width
+	}
+	s, _ := fyne.CurrentApp().Driver().RenderedTextSize(text, size, style, source)
+	return s.Width
+}
+
+// measureTextWithFallback measures text the way newFallbackTextObjects
+// draws it. Layout measures text with it too, so lines fit what is drawn.
+func measureTextWithFallback(text string, size float32, style fyne.TextStyle, primary fyne.Resource) float32 {
+	var width float32
+	for _, run := range splitFontRuns(text, primary) {
+		width += measureFallbackRun(run.text, size, style, run.font)
+	}
+	return width
+}
+
+// newFallbackTextObjects creates one canvas.Text per font run, each in
+// the font chosen for its grapheme clusters, laid out left to right from
+// (x, y). primary is the font resolved from the CSS font-family list (nil
+// = theme).
+func newFallbackTextObjects(text string, x, y float64, size float32, col color.Color, style fyne.TextStyle, primary fyne.Resource) []fyne.CanvasObject {
+	var objects []fyne.CanvasObject
+	for _, run := range splitFontRuns(text, primary) {
+		t := canvas.NewText(run.text, col)
+		t.TextSize = size
+		t.TextStyle = style
+		t.FontSource = run.font
+		t.Move(fyne.NewPos(float32(x), float32(y)))
+		objects = append(objects, t)
+		x += float64(measureFallbackRun(run.text, size, style, run.font))
+	}
+	return objects
+}
+
+// cmapRange is a range of characters a font has glyphs for.
+type cmapRange struct {
+	lo, hi rune
+}
+
+// fontCoverage holds the cmap ranges of each font clusters were looked up
+// in, nil for a font whose cmap could not be read.
+var fontCoverage sync.Map // fyne.Resource -> []cmapRange
+
+// fontCovers reports whether font has glyphs for the characters of cluster
+// that draw one: all but joiners and variation selectors. A font whose
+// cmap cannot be read is trusted to.
+func fontCovers(font fyne.Resource, cluster string) bool {
+	v, ok := fontCoverage.Load(font)
+	if !ok {
+		ranges, _ := readCmap(font.Content())
+		v, _ = fontCoverage.LoadOrStore(font, ranges)
+	}
+	ranges := v.([]cmapRange)
+	if ranges == nil {
+		return true
+	}
+	for _, r := range cluster {
+		if r == '\u200d' || r >= 0xFE00 && r <= 0xFE0F || r >= 0xE0020 && r <= 0xE007F {
+			continue
+		}
+		i := sort.Search(len(ranges), func(i int) bool { return ranges[i].hi >= r })
+		if i == len(ranges) || ranges[i].lo > r {
+			return false
+		}
+	}
+	return true
+}
+
+// readCmap returns the characters an OpenType or TrueType font (the first
+// face of a collection) maps to glyphs, read from the format 12 or format
+// 4 Unicode subtable of its cmap, or false when it has neither.
+func readCmap(data []byte) ([]cmapRange, bool) {
+	table := fontTable(data, "cmap")
+	if len(table) < 4 {
+		return nil, false
+	}
+	var format4, format12 []byte
+	numTables := int(binary.BigEndian.Uint16(table[2:4]))
+	for i := 0; i < numTables; i++ {
+		rec := 4 + i*8
+		if rec+8 > len(table) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(table[rec : rec+2])
+		encoding := binary.BigEndian.Uint16(table[rec+2 : rec+4])
+		off := int(binary.BigEndian.Uint32(table[rec+4 : rec+8]))
+		if off+2 > len(table) || !(platform == 0 || platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(table[off : off+2]) {
+		case 4:
+			format4 = table[off:]
+		case 12:
+			format12 = table[off:]
+		}
+	}
+
+	var ranges []cmapRange
+	switch {
+	case len(format12) >= 16:
+		groups := int(binary.BigEndian.Uint32(format12[12:16]))
+		for i := 0; i < groups && 16+i*12+12 <= len(format12); i++ {
+			g := format12[16+i*12:]
+			ranges = append(ranges, cmapRange{rune(binary.BigEndian.Uint32(g[0:4])), rune(binary.BigEndian.Uint32(g[4:8]))})
+		}
+	case len(format4) >= 14:
+		segments := int(binary.BigEndian.Uint16(format4[6:8])) / 2
+		ends, starts := 14, 14+2*segments+2
+		if starts+2*segments > len(format4) {
+			return nil, false
+		}
+		for i := 0; i < segments; i++ {
+			lo := rune(binary.BigEndian.Uint16(format4[starts+2*i:]))
+			hi := rune(binary.BigEndian.Uint16(format4[ends+2*i:]))
+			if lo != 0xFFFF {
+				ranges = append(ranges, cmapRange{lo, hi})
+			}
+		}
+	default:
+		return nil, false
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].lo < ranges[j].lo })
+	return ranges, true
+}
@@ -0,0 +1,149 @@
+package render
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyRune(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        rune
+		expected fallbackClass
+	}{
+		{"latin letter", 'a', fallbackDefault},
+		{"latin-1 accent", 'é', fallbackDefault},
+		{"grinning face", '😀', fallbackEmoji},
+		{"heart symbol", '❤', fallbackEmoji},
+		{"regional indicator", '🇦', fallbackEmoji},
+		{"han ideograph", '世', fallbackCJK},
+		{"hiragana", 'あ', fallbackCJK},
+		{"hangul", '한', fallbackCJK},
+		{"cyrillic", 'Ж', fallbackCyrillic},
+		{"greek", 'λ', fallbackGreek},
+		{"arabic", 'ع', fallbackArabic},
+		{"hebrew", 'ש', fallbackHebrew},
+		{"arrow", '→', fallbackSymbol},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyRune(tt.r))
+		})
+	}
+}
+
+func TestSplitFallbackRuns(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []fallbackRun
+	}{
+		{"empty", "", nil},
+		{"plain latin", "hello world", []fallbackRun{{"hello world", fallbackDefault}}},
+		{"latin then cjk", "Hello 世界", []fallbackRun{{"Hello ", fallbackDefault}, {"世界", fallbackCJK}}},
+		{"emoji in sentence", "hi 😀 there", []fallbackRun{{"hi ", fallbackDefault}, {"😀 ", fallbackEmoji}, {"there", fallbackDefault}}},
+		{"zwj sequence stays together", "👩‍💻", []fallbackRun{{"👩‍💻", fallbackEmoji}}},
+		{"variation selector stays with emoji", "❤️!", []fallbackRun{{"❤️", fallbackEmoji}, {"!", fallbackDefault}}},
+		{"cyrillic word", "Привет", []fallbackRun{{"Привет", fallbackCyrillic}}},
+		{"combining mark stays with its letter", "и\u0306!", []fallbackRun{{"и\u0306", fallbackCyrillic}, {"!", fallbackDefault}}},
+		{"keycap is emoji", "1\uFE0F\u20E3", []fallbackRun{{"1\uFE0F\u20E3", fallbackEmoji}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, splitFallbackRuns(tt.input))
+		})
+	}
+}
+
+// cmapFont returns a font file holding just a format 12 cmap of ranges.
+func cmapFont(ranges ...cmapRange) []byte {
+	sub := binary.BigEndian.AppendUint16(nil, 12)
+	sub = binary.BigEndian.AppendUint16(sub, 0)
+	sub = binary.BigEndian.AppendUint32(sub, uint32(16+12*len(ranges)))
+	sub = binary.BigEndian.AppendUint32(sub, 0)
+	sub = binary.BigEndian.AppendUint32(sub, uint32(len(ranges)))
+	for i, r := range ranges {
+		sub = binary.BigEndian.AppendUint32(sub, uint32(r.lo))
+		sub = binary.BigEndian.AppendUint32(sub, uint32(r.hi))
+		sub = binary.BigEndian.AppendUint32(sub, uint32(i+1))
+	}
+	cmap := []byte{0, 0, 0, 1, 0, 3, 0, 10, 0, 0, 0, 12}
+	cmap = append(cmap, sub...)
+
+	font := []byte{0, 1, 0, 0, 0, 1, 0, 16, 0, 0, 0, 0}
+	font = append(font, "cmap"...)
+	font = binary.BigEndian.AppendUint32(font, 0)
+	font = binary.BigEndian.AppendUint32(font, 28)
+	font = binary.BigEndian.AppendUint32(font, uint32(len(cmap)))
+	return append(font, cmap...)
+}
+
+// useFallbackFonts makes fonts the fallback chain of class for the test.
+func useFallbackFonts(t *testing.T, class fallbackClass, fonts ...[]byte) {
+	var paths []string
+	for i, font := range fonts {
+		path := filepath.Join(t.TempDir(), fmt.Sprintf("fallback%d.ttf", i))
+		require.NoError(t, os.WriteFile(path, font, 0o644))
+		paths = append(paths, path)
+	}
+	fallbackPathsMu.Lock()
+	saved, found := fallbackPaths[class], fallbackFound[class]
+	fallbackPaths[class], fallbackFound[class] = paths, true
+	fallbackPathsMu.Unlock()
+	t.Cleanup(func() {
+		fallbackPathsMu.Lock()
+		fallbackPaths[class], fallbackFound[class] = saved, found
+		fallbackPathsMu.Unlock()
+	})
+}
+
+func TestReadCmap(t *testing.T) {
+	ranges, ok := readCmap(cmapFont(cmapRange{0x4E00, 0x9FFF}, cmapRange{0x20, 0x7E}))
+	require.True(t, ok)
+	assert.Equal(t, []cmapRange{{0x20, 0x7E}, {0x4E00, 0x9FFF}}, ranges)
+
+	_, ok = readCmap([]byte("not a font"))
+	assert.False(t, ok)
+
+	themeFont := theme.TextFont()
+	assert.True(t, fontCovers(themeFont, "Aé"))
+	assert.True(t, fontCovers(themeFont, "Ж"))
+	assert.False(t, fontCovers(themeFont, "世"))
+}
+
+func TestSplitFontRuns(t *testing.T) {
+	ascii := fyne.NewStaticResource("ascii.ttf", cmapFont(cmapRange{0x20, 0x7E}))
+	asciiAndEmoji := fyne.NewStaticResource("ascii-emoji.ttf", cmapFont(cmapRange{0x20, 0x7E}, cmapRange{0x1F600, 0x1F64F}))
+	useFallbackFonts(t, fallbackCJK, cmapFont(cmapRange{0x20, 0x20}), cmapFont(cmapRange{0x4E00, 0x9FFF}))
+	useFallbackFonts(t, fallbackEmoji, cmapFont(cmapRange{0x1F300, 0x1FAFF}))
+	cjk := loadFontResource(fallbackFontPaths(fallbackCJK)[1])
+	emoji := loadFontResource(fallbackFontPaths(fallbackEmoji)[0])
+
+	tests := []struct {
+		name    string
+		text    string
+		primary fyne.Resource
+		want    []fontRun
+	}{
+		{"theme font", "Hello", nil, []fontRun{{"Hello", nil}}},
+		{"the first font of the chain with the glyphs", "Hi 世界 ok", ascii, []fontRun{{"Hi ", ascii}, {"世界 ", cjk}, {"ok", ascii}}},
+		{"color emoji over the page font's", "Hi 😀", asciiAndEmoji, []fontRun{{"Hi ", asciiAndEmoji}, {"😀", emoji}}},
+		{"a cluster no font covers is left to Fyne", "aé", ascii, []fontRun{{"a", ascii}, {"é", nil}}},
+		{"the theme font covers cyrillic", "Жук", nil, []fontRun{{"Жук", nil}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitFontRuns(tt.text, tt.primary))
+		})
+	}
+}
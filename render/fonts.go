@@ -0,0 +1,423 @@
+package render
+
+import (
+	"browser/css"
+	"browser/layout"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"fyne.io/fyne/v2"
+)
+
+// FontFace describes one installed font file.
+type FontFace struct {
+	Family string
+	Style  string // subfamily, e.g. "Regular", "Bold Italic"
+	Path   string
+	Weight int // 100–900, from the style name
+	Italic bool
+}
+
+// fontCatalog indexes installed fonts by family and by file name.
+type fontCatalog struct {
+	byFamily map[string][]FontFace // lowercased family -> faces
+	byFile   map[string]string     // lowercased file name -> path
+}
+
+func newFontCatalog(faces []FontFace) *fontCatalog {
+	cat := &fontCatalog{
+		byFamily: make(map[string][]FontFace),
+		byFile:   make(map[string]string),
+	}
+	for _, face := range faces {
+		key := strings.ToLower(face.Family)
+		cat.byFamily[key] = append(cat.byFamily[key], face)
+		name := strings.ToLower(filepath.Base(face.Path))
+		if _, exists := cat.byFile[name]; !exists {
+			cat.byFile[name] = face.Path
+		}
+	}
+	return cat
+}
+
+// lookup returns the face of family closest to the requested weight/slant,
+// or nil if the family is not installed.
+func (cat *fontCatalog) lookup(family string, weight int, italic bool) *FontFace {
+	faces := cat.byFamily[strings.ToLower(strings.TrimSpace(family))]
+	if len(faces) == 0 {
+		return nil
+	}
+	best := 0
+	for i, face := range faces {
+		if faceDistance(face.Weight, face.Italic, weight, italic) < faceDistance(faces[best].Weight, faces[best].Italic, weight, italic) {
+			best = i
+		}
+	}
+	return &faces[best]
+}
+
+// faceDistance ranks a face of the given weight and slant against the
+// requested ones; lower is a better match. Slant is matched first, then
+// weight as in CSS Fonts §5.2: for 400–500 prefer heavier faces up to 500,
+// then lighter, then heavier; below 400 prefer lighter faces; above 500
+// prefer heavier ones.
+func faceDistance(faceWeight int, faceItalic bool, weight int, italic bool) int {
+	if weight == 0 {
+		weight = css.FontWeightNormal
+	}
+	distance := faceWeight - weight
+	if distance < 0 {
+		distance = -distance
+	}
+	switch {
+	case weight >= 400 && weight <= 500:
+		if faceWeight < weight {
+			distance += 1000
+		} else if faceWeight > 500 {
+			distance += 2000
+		}
+	case weight < 400 && faceWeight > weight, weight > 500 && faceWeight < weight:
+		distance += 1000
+	}
+	if faceItalic != italic {
+		distance += 10000
+	}
+	return distance
+}
+
+var (
+	systemFontsOnce sync.Once
+	systemFontsCat  *fontCatalog
+)
+
+// systemFonts enumerates installed fonts once per process.
+func systemFonts() *fontCatalog {
+	systemFontsOnce.Do(func() {
+		systemFontsCat = newFontCatalog(enumerateSystemFonts())
+	})
+	return systemFontsCat
+}
+
+// enumerateSystemFonts lists installed fonts. Linux asks fontconfig; other
+// platforms (or Linux without fc-list) read family names straight from the
+// OpenType name table of each file in the system font directories.
+func enumerateSystemFonts() []FontFace {
+	if runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		if faces := fontconfigFonts(); len(faces) > 0 {
+			return faces
+		}
+	}
+	return scanFontDirs(systemFontDirs())
+}
+
+// fontconfigFonts runs fc-list and parses "family|style|file" lines.
+func fontconfigFonts() []FontFace {
+	out, err := exec.Command("fc-list", "--format", "%{family[0]}|%{style[0]}|%{file}\n").Output()
+	if err != nil {
+		return nil
+	}
+	return parseFcList(out)
+}
+
+func parseFcList(out []byte) []FontFace {
+	var faces []FontFace
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "|", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			continue
+		}
+		faces = append(faces, newFontFace(parts[0], parts[1], parts[2]))
+	}
+	return faces
+}
+
+func newFontFace(family, style, path string) FontFace {
+	lower := strings.ToLower(style)
+	return FontFace{
+		Family: family,
+		Style:  style,
+		Path:   path,
+		Weight: styleNameWeight(lower),
+		Italic: strings.Contains(lower, "italic") || strings.Contains(lower, "oblique"),
+	}
+}
+
+// styleWeightNames maps weight words of font style names to their numeric
+// weight (OpenType usWeightClass), longest names first so "semibold" is
+// not read as "bold".
+var styleWeightNames = []struct {
+	name   string
+	weight int
+}{
+	{"extralight", 200}, {"ultralight", 200}, {"extrabold", 800}, {"ultrabold", 800},
+	{"semibold", 600}, {"demibold", 600}, {"hairline", 100}, {"medium", 500},
+	{"black", 900}, {"heavy", 900}, {"light", 300}, {"thin", 100}, {"bold", 700},
+}
+
+// styleNameWeight returns the weight named by a lowercased style name such
+// as "semibold italic"; styles without a weight word are normal.
+func styleNameWeight(style string) int {
+	compact := strings.NewReplacer(" ", "", "-", "").Replace(style)
+	for _, w := range styleWeightNames {
+		if strings.Contains(compact, w.name) {
+			return w.weight
+		}
+	}
+	return css.FontWeightNormal
+}
+
+// systemFontDirs returns the platform font directories.
+func systemFontDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"/System/Library/Fonts", "/Library/Fonts", filepath.Join(home, "Library/Fonts")}
+	case "windows":
+		return []string{filepath.Join(os.Getenv("WINDIR"), "Fonts")}
+	default:
+		return []string{"/usr/share/fonts", "/usr/local/share/fonts", filepath.Join(home, ".local/share/fonts"), filepath.Join(home, ".fonts")}
+	}
+}
+
+// scanFontDirs walks dirs and reads the family/style of every font file.
+func scanFontDirs(dirs []string) []FontFace {
+	var faces []FontFace
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".ttf", ".otf", ".ttc", ".otc":
+			default:
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			family, style, ok := readFontNames(data)
+			if !ok {
+				family = strings.TrimSuffix(d.Name(), filepath.Ext(d.Name()))
+			}
+			faces = append(faces, newFontFace(family, style, path))
+			return nil
+		})
+	}
+	return faces
+}
+
+// OpenType name IDs (OpenType spec, 'name' table).
+const (
+	nameIDFamily    = 1
+	nameIDSubfamily = 2
+)
+
+// fontTable returns the named sfnt table of an OpenType or TrueType font
+// (the first face of a collection), or nil if it is missing.
+func fontTable(data []byte, tag string) []byte {
+	if len(data) < 12 {
+		return nil
+	}
+	offset := uint32(0)
+	if string(data[:4]) == "ttcf" {
+		if len(data) < 16 {
+			return nil
+		}
+		offset = binary.BigEndian.Uint32(data[12:16])
+	}
+	if int(offset)+12 > len(data) {
+		return nil
+	}
+	numTables := int(binary.BigEndian.Uint16(data[offset+4 : offset+6]))
+	for i := 0; i < numTables; i++ {
+		rec := int(offset) + 12 + i*16
+		if rec+16 > len(data) {
+			return nil
+		}
+		if string(data[rec:rec+4]) == tag {
+			off := binary.BigEndian.Uint32(data[rec+8 : rec+12])
+			length := binary.BigEndian.Uint32(data[rec+12 : rec+16])
+			if off == 0 || uint64(off)+uint64(length) > uint64(len(data)) {
+				return nil
+			}
+			return data[off : off+length]
+		}
+	}
+	return nil
+}
+
+// readFontNames extracts the family and subfamily names from an OpenType or
+// TrueType font (the first face of a collection).
+func readFontNames(data []byte) (family, style string, ok bool) {
+	table := fontTable(data, "name")
+	if len(table) < 6 {
+		return "", "", false
+	}
+	count := int(binary.BigEndian.Uint16(table[2:4]))
+	strOff := int(binary.BigEndian.Uint16(table[4:6]))
+
+	for i := 0; i < count; i++ {
+		rec := 6 + i*12
+		if rec+12 > len(table) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(table[rec : rec+2])
+		nameID := binary.BigEndian.Uint16(table[rec+6 : rec+8])
+		length := int(binary.BigEndian.Uint16(table[rec+8 : rec+10]))
+		start := strOff + int(binary.BigEndian.Uint16(table[rec+10:rec+12]))
+		if start+length > len(table) || (nameID != nameIDFamily && nameID != nameIDSubfamily) {
+			continue
+		}
+		value := decodeFontName(table[start:start+length], platform)
+		if value == "" {
+			continue
+		}
+		// Prefer Windows/Unicode (UTF-16) records over Mac Roman ones
+		if nameID == nameIDFamily && (family == "" || platform != 1) {
+			family = value
+		}
+		if nameID == nameIDSubfamily && (style == "" || platform != 1) {
+			style = value
+		}
+	}
+	return family, style, family != ""
+}
+
+// readFontMetrics returns the hhea ascender, descender and line gap of a
+// font scaled to em units by head.unitsPerEm.
+func readFontMetrics(data []byte) (layout.FontMetrics, bool) {
+	head := fontTable(data, "head")
+	hhea := fontTable(data, "hhea")
+	if len(head) < 20 || len(hhea) < 10 {
+		return layout.FontMetrics{}, false
+	}
+	unitsPerEm := float64(binary.BigEndian.Uint16(head[18:20]))
+	if unitsPerEm == 0 {
+		return layout.FontMetrics{}, false
+	}
+	ascender := float64(int16(binary.BigEndian.Uint16(hhea[4:6])))
+	descender := float64(int16(binary.BigEndian.Uint16(hhea[6:8])))
+	lineGap := float64(int16(binary.BigEndian.Uint16(hhea[8:10])))
+	if ascender <= 0 {
+		return layout.FontMetrics{}, false
+	}
+	if lineGap < 0 {
+		lineGap = 0
+	}
+	return layout.FontMetrics{
+		Ascent:  ascender / unitsPerEm,
+		Descent: -descender / unitsPerEm,
+		LineGap: lineGap / unitsPerEm,
+	}, true
+}
+
+func decodeFontName(raw []byte, platform uint16) string {
+	if platform == 1 { // Macintosh, single-byte Roman
+		return string(raw)
+	}
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// genericFontFamilies maps CSS generic families (CSS Fonts §4.2) to installed
+// system families, in preference order, per platform.
+func genericFontFamilies(generic string) []string {
+	var table map[string][]string
+	switch runtime.GOOS {
+	case "darwin":
+		table = map[string][]string{
+			"serif":      {"Times", "Times New Roman", "Georgia"},
+			"sans-serif": {"Helvetica", "Helvetica Neue", "Arial"},
+			"monospace":  {"Menlo", "Monaco", "Courier New", "Courier"},
+			"cursive":    {"Apple Chancery", "Snell Roundhand", "Brush Script MT"},
+			"fantasy":    {"Papyrus", "Chalkduster", "Herculanum"},
+			"system-ui":  {"SF Pro", ".AppleSystemUIFont", "Helvetica Neue"},
+		}
+	case "windows":
+		table = map[string][]string{
+			"serif":      {"Times New Roman", "Georgia", "Cambria"},
+			"sans-serif": {"Arial", "Segoe UI", "Verdana"},
+			"monospace":  {"Consolas", "Courier New", "Lucida Console"},
+			"cursive":    {"Comic Sans MS", "Segoe Script", "Brush Script MT"},
+			"fantasy":    {"Impact", "Papyrus"},
+			"system-ui":  {"Segoe UI"},
+		}
+	default:
+		table = map[string][]string{
+			"serif":      {"DejaVu Serif", "Liberation Serif", "Noto Serif", "FreeSerif", "Times New Roman"},
+			"sans-serif": {"DejaVu Sans", "Liberation Sans", "Noto Sans", "FreeSans", "Arial"},
+			"monospace":  {"DejaVu Sans Mono", "Liberation Mono", "Noto Sans Mono", "FreeMono", "Courier New"},
+			"cursive":    {"URW Chancery L", "Z003", "Comic Neue", "Comic Sans MS"},
+			"fantasy":    {"Impact", "Cantarell"},
+			"system-ui":  {"Cantarell", "Ubuntu", "Noto Sans", "DejaVu Sans"},
+		}
+	}
+	return table[strings.ToLower(generic)]
+}
+
+// resolveFontFace walks the author's ordered font-family list, expanding
+// generic keywords in place, and returns the first installed face.
+func (cat *fontCatalog) resolveFontFace(families []string, weight int, italic bool) *FontFace {
+	for _, family := range families {
+		candidates := genericFontFamilies(family)
+		if candidates == nil {
+			candidates = []string{family}
+		}
+		for _, name := range candidates {
+			if face := cat.lookup(name, weight, italic); face != nil {
+				return face
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	fontResources   = make(map[string]fyne.Resource) // path -> loaded font
+	fontResourcesMu sync.Mutex
+)
+
+// loadFontResource reads a font file into a cached fyne.Resource.
+func loadFontResource(path string) fyne.Resource {
+	fontResourcesMu.Lock()
+	defer fontResourcesMu.Unlock()
+	if res, ok := fontResources[path]; ok {
+		return res
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fontResources[path] = nil
+		return nil
+	}
+	res := fyne.NewStaticResource(filepath.Base(path), data)
+	fontResources[path] = res
+	return res
+}
+
+// resolveFontFamily returns the font for a CSS font-family list, or nil to
+// use the theme font when no listed family is available. Each family is
+// looked up among the page's loaded web fonts before installed fonts.
+func resolveFontFamily(families []string, weight int, italic bool) fyne.Resource {
+	for i, family := range families {
+		if font := webFonts.lookup(family, weight, italic); font != nil {
+			return font
+		}
+		if face := systemFonts().resolveFontFace(families[i:i+1], weight, italic); face != nil {
+			return loadFontResource(face.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package render
+
+import (
+	"browser/layout"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildNameTableFont returns a minimal sfnt containing only a 'name' table
+// with Windows (UTF-16BE) family and subfamily records.
+func buildNameTableFont(family, style string) []byte {
+	encode := func(s string) []byte {
+		units := utf16.Encode([]rune(s))
+		out := make([]byte, len(units)*2)
+		for i, u := range units {
+			binary.BigEndian.PutUint16(out[i*2:], u)
+		}
+		return out
+	}
+	familyBytes, styleBytes := encode(family), encode(style)
+
+	// name table: header (6) + 2 records (24) + strings
+	name := make([]byte, 6+24)
+	binary.BigEndian.PutUint16(name[2:], 2)
+	binary.BigEndian.PutUint16(name[4:], 30)
+	records := []struct {
+		id     uint16
+		length int
+		offset int
+	}{
+		{nameIDFamily, len(familyBytes), 0},
+		{nameIDSubfamily, len(styleBytes), len(familyBytes)},
+	}
+	for i, r := range records {
+		rec := name[6+i*12:]
+		binary.BigEndian.PutUint16(rec[0:], 3) // Windows platform
+		binary.BigEndian.PutUint16(rec[2:], 1)
+		binary.BigEndian.PutUint16(rec[6:], r.id)
+		binary.BigEndian.PutUint16(rec[8:], uint16(r.length))
+		binary.BigEndian.PutUint16(rec[10:], uint16(r.offset))
+	}
+	name = append(name, familyBytes...)
+	name = append(name, styleBytes...)
+
+	return buildSfnt(map[string][]byte{"name": name})
+}
+
+// buildSfnt assembles an sfnt file from raw tables.
+func buildSfnt(tables map[string][]byte) []byte {
+	var tags []string
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	// sfnt header (12) + one record (16) per table
+	font := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(font[0:], 0x00010000)
+	binary.BigEndian.PutUint16(font[4:], uint16(len(tags)))
+	for i, tag := range tags {
+		rec := font[12+i*16:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(font)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(tables[tag])))
+		font = append(font, tables[tag]...)
+	}
+	return font
+}
+
+func TestReadFontNames(t *testing.T) {
+	family, style, ok := readFontNames(buildNameTableFont("DejaVu Serif", "Bold Italic"))
+	assert.True(t, ok)
+	assert.Equal(t, "DejaVu Serif", family)
+	assert.Equal(t, "Bold Italic", style)
+
+	_, _, ok = readFontNames([]byte("not a font"))
+	assert.False(t, ok)
+}
+
+func TestReadFontNamesMalformed(t *testing.T) {
+	font := buildNameTableFont("DejaVu Serif", "Bold Italic")
+	// setTable points the name table's directory record at off and length
+	setTable := func(off, length uint32) []byte {
+		data := slices.Clone(font)
+		binary.BigEndian.PutUint32(data[12+8:], off)
+		binary.BigEndian.PutUint32(data[12+12:], length)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated file", font[:len(font)-4]},
+		{"table past the end", setTable(28, uint32(len(font)))},
+		{"offset and length overflow 32 bits", setTable(0xFFFFFFF0, 0x20)},
+		{"table shorter than its header", setTable(28, 4)},
+		{"records past the table", setTable(28, 6+12)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, ok := readFontNames(tt.data)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestReadFontMetrics(t *testing.T) {
+	metricsFont := func(unitsPerEm uint16, ascender, descender, lineGap int16) []byte {
+		head := make([]byte, 54)
+		binary.BigEndian.PutUint16(head[18:], unitsPerEm)
+		hhea := make([]byte, 36)
+		binary.BigEndian.PutUint16(hhea[4:], uint16(ascender))
+		binary.BigEndian.PutUint16(hhea[6:], uint16(descender))
+		binary.BigEndian.PutUint16(hhea[8:], uint16(lineGap))
+		return buildSfnt(map[string][]byte{"head": head, "hhea": hhea})
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected layout.FontMetrics
+		ok       bool
+	}{
+		{"noto sans", metricsFont(1000, 1069, -293, 0), layout.FontMetrics{Ascent: 1.069, Descent: 0.293}, true},
+		{"line gap", metricsFont(2048, 1638, -410, 67), layout.FontMetrics{Ascent: 1638.0 / 2048, Descent: 410.0 / 2048, LineGap: 67.0 / 2048}, true},
+		{"negative line gap clamps to zero", metricsFont(1000, 800, -200, -10), layout.FontMetrics{Ascent: 0.8, Descent: 0.2}, true},
+		{"zero units per em", metricsFont(0, 800, -200, 0), layout.FontMetrics{}, false},
+		{"missing tables", buildNameTableFont("Font", "Regular"), layout.FontMetrics{}, false},
+		{"not a font", []byte("not a font"), layout.FontMetrics{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := readFontMetrics(tt.data)
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.expected.Ascent, m.Ascent, 1e-9)
+			assert.InDelta(t, tt.expected.Descent, m.Descent, 1e-9)
+			assert.InDelta(t, tt.expected.LineGap, m.LineGap, 1e-9)
+		})
+	}
+}
+
+func TestParseFcList(t *testing.T) {
+	out := []byte("DejaVu Sans|Book|/usr/share/fonts/DejaVuSans.ttf\n" +
+		"DejaVu Sans|Bold Oblique|/usr/share/fonts/DejaVuSans-BoldOblique.ttf\n" +
+		"broken line\n")
+
+	faces := parseFcList(out)
+	assert.Len(t, faces, 2)
+	assert.Equal(t, "DejaVu Sans", faces[0].Family)
+	assert.Equal(t, 400, faces[0].Weight)
+	assert.Equal(t, 700, faces[1].Weight)
+	assert.True(t, faces[1].Italic)
+}
+
+func TestFontCatalogLookupPrefersMatchingStyle(t *testing.T) {
+	cat := newFontCatalog([]FontFace{
+		newFontFace("Georgia", "Regular", "/fonts/georgia.ttf"),
+		newFontFace("Georgia", "Bold", "/fonts/georgiab.ttf"),
+		newFontFace("Georgia", "Italic", "/fonts/georgiai.ttf"),
+	})
+
+	assert.Equal(t, "/fonts/georgia.ttf", cat.lookup("georgia", 400, false).Path)
+	assert.Equal(t, "/fonts/georgiab.ttf", cat.lookup("Georgia", 700, false).Path)
+	assert.Equal(t, "/fonts/georgiai.ttf", cat.lookup("Georgia", 400, true).Path)
+	assert.Nil(t, cat.lookup("Missing", 400, false))
+	assert.Equal(t, "/fonts/georgia.ttf", cat.byFile["georgia.ttf"])
+}
+
+func TestStyleNameWeight(t *testing.T) {
+	tests := map[string]int{
+		"regular":         400,
+		"book":            400,
+		"thin":            100,
+		"extralight":      200,
+		"ultra light":     200,
+		"light italic":    300,
+		"medium":          500,
+		"semibold":        600,
+		"demi bold":       600,
+		"bold oblique":    700,
+		"extra-bold":      800,
+		"black":           900,
+		"heavy italic":    900,
+		"semibold italic": 600,
+	}
+	for style, expected := range tests {
+		assert.Equal(t, expected, styleNameWeight(style), style)
+	}
+}
+
+func TestFontCatalogLookupNearestWeight(t *testing.T) {
+	cat := newFontCatalog([]FontFace{
+		newFontFace("Inter", "Light", "/fonts/inter-300.ttf"),
+		newFontFace("Inter", "Regular", "/fonts/inter-400.ttf"),
+		newFontFace("Inter", "Medium", "/fonts/inter-500.ttf"),
+		newFontFace("Inter", "SemiBold", "/fonts/inter-600.ttf"),
+		newFontFace("Inter", "Bold", "/fonts/inter-700.ttf"),
+		newFontFace("Inter", "Italic", "/fonts/inter-400i.ttf"),
+		newFontFace("Sparse", "Regular", "/fonts/sparse-400.ttf"),
+		newFontFace("Sparse", "Bold", "/fonts/sparse-700.ttf"),
+		newFontFace("Sparse", "Black", "/fonts/sparse-900.ttf"),
+	})
+
+	tests := []struct {
+		family   string
+		weight   int
+		italic   bool
+		expected string
+	}{
+		{"Inter", 300, false, "/fonts/inter-300.ttf"},
+		{"Inter", 500, false, "/fonts/inter-500.ttf"},
+		{"Inter", 600, false, "/fonts/inter-600.ttf"},
+		{"Inter", 800, false, "/fonts/inter-700.ttf"},
+		{"Inter", 0, false, "/fonts/inter-400.ttf"},
+		{"Inter", 500, true, "/fonts/inter-400i.ttf"},
+		{"Inter", 200, false, "/fonts/inter-300.ttf"},
+		// 400–500 try up to 500, then lighter, then heavier
+		{"Sparse", 500, false, "/fonts/sparse-400.ttf"},
+		// Below 400 prefer lighter, then the lightest heavier face
+		{"Sparse", 300, false, "/fonts/sparse-400.ttf"},
+		// Above 500 prefer heavier faces
+		{"Sparse", 600, false, "/fonts/sparse-700.ttf"},
+		{"Sparse", 800, false, "/fonts/sparse-900.ttf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d italic=%t", tt.family, tt.weight, tt.italic), func(t *testing.T) {
+			assert.Equal(t, tt.expected, cat.lookup(tt.family, tt.weight, tt.italic).Path)
+		})
+	}
+}
+
+func TestResolveFontFace(t *testing.T) {
+	serif := genericFontFamilies("serif")
+	mono := genericFontFamilies("monospace")
+	assert.NotEmpty(t, serif)
+	assert.NotEmpty(t, mono)
+
+	cat := newFontCatalog([]FontFace{
+		newFontFace("Fira Code", "Regular", "/fonts/fira.ttf"),
+		newFontFace(serif[len(serif)-1], "Regular", "/fonts/serif.ttf"),
+		newFontFace(mono[0], "Regular", "/fonts/mono.ttf"),
+	})
+
+	tests := []struct {
+		name     string
+		families []string
+		expected string
+	}{
+		{"first installed author family wins", []string{"Fira Code", "monospace"}, "/fonts/fira.ttf"},
+		{"missing family falls through to generic", []string{"Nonexistent", "serif"}, "/fonts/serif.ttf"},
+		{"generic keyword is case-insensitive", []string{"MONOSPACE"}, "/fonts/mono.ttf"},
+		{"author order beats generic order", []string{"monospace", "Fira Code"}, "/fonts/mono.ttf"},
+		{"nothing installed", []string{"Nonexistent", "cursive-ish"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			face := cat.resolveFontFace(tt.families, 400, false)
+			if tt.expected == "" {
+				assert.Nil(t, face)
+				return
+			}
+			assert.NotNil(t, face)
+			assert.Equal(t, tt.expected, face.Path)
+		})
+	}
+}
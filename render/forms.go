@@ -0,0 +1,468 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"browser/dom"
+)
+
+// Form submission (HTML §4.10.21). Activating a submit button, or
+// pressing Enter in a form's text field, builds the form's entry list from
+// the controls' current values and sends it to the form's action: in the
+// query of a GET, or as a POST body encoded as its enctype asks. The
+// button that submits can override the form's action, method, enctype and
+// target with its formaction, formmethod, formenctype and formtarget.
+
+const (
+	enctypeURLEncoded = "application/x-www-form-urlencoded"
+	enctypeMultipart  = "multipart/form-data"
+	enctypeTextPlain  = "text/plain"
+)
+
+// formEntry is a name and value of a form's entry list. The entries of
+// file inputs carry the path of the chosen file instead, "" for none.
+type formEntry struct {
+	name   string
+	value  string
+	isFile bool
+	file   string
+}
+
+// formSubmission is where and how a form is sent.
+type formSubmission struct {
+	action  string // absolute URL
+	method  string // "get", "post" or "dialog"
+	enctype string
+	target  string
+}
+
+// Input types whose fields stop a form without a submit button from
+// being submitted by Enter when it has more than one of them.
+var implicitSubmissionBlockers = map[string]bool{
+	"": true, "text": true, "search": true, "url": true, "tel": true, "email": true,
+	"password": true, "date": true, "month": true, "week": true, "time": true,
+	"datetime-local": true, "number": true,
+}
+
+// activateButton runs the page's click handlers for a click on target in
+// button, then submits or resets the button's form unless one of them
+// called preventDefault.
+func (b *Browser) activateButton(target, button *dom.Node) {
+	if b.onJSClick != nil && b.onJSClick(target) {
+		return
+	}
+	form := dom.FormOwner(button)
+	if form == nil {
+		return
+	}
+	switch dom.ButtonType(button) {
+	case "submit":
+		b.submitForm(form, button)
+	case "reset":
+		b.resetForm(form)
+	}
+}
+
+// submitImplicitly submits the form of field, as pressing Enter in it
+// does: by clicking the form's default button, or without a button when
+// the form has no other field that would take Enter.
+func (b *Browser) submitImplicitly(field *dom.Node) {
+	form := dom.FormOwner(field)
+	if form == nil {
+		return
+	}
+	if button := dom.DefaultButton(form); button != nil {
+		if !dom.IsControlDisabled(button) {
+			go b.activateButton(button, button)
+		}
+		return
+	}
+	blockers := 0
+	for _, control := range dom.FormControls(form) {
+		if control.TagName == dom.TagInput && implicitSubmissionBlockers[strings.ToLower(control.Attributes["type"])] {
+			blockers++
+		}
+	}
+	if blockers <= 1 {
+		go b.submitForm(form, nil)
+	}
+}
+
+// submitForm submits form, on behalf of submitter unless it is nil. Forms
+// with invalid fields are not sent; the fields are marked instead.
+func (b *Browser) submitForm(form, submitter *dom.Node) {
+	if !noValidate(form, submitter) {
+		if invalid := b.validateForm(form); len(invalid) > 0 {
+			b.invalidNodes = make(map[*dom.Node]bool)
+			for _, node := range invalid {
+				b.invalidNodes[node] = true
+			}
+			b.repaint()
+			return
+		}
+	}
+	b.invalidNodes = make(map[*dom.Node]bool)
+	b.rememberFormValues(form)
+
+	s := b.submission(form, submitter)
+	if s.method == "dialog" {
+		if dialog := form.ClosestAncestor(dom.TagDialog); dialog != nil && dom.CloseDialog(dialog) {
+			b.ReflowChanged(dialog)
+		}
+		return
+	}
+
+	entries := b.formEntries(form, submitter)
+	req := NavigationRequest{URL: s.action, Method: "GET", FromURL: b.GetCurrentURL()}
+	if s.method == "get" {
+		// The entries replace the action's query
+		target, err := url.Parse(s.action)
+		if err != nil {
+			fmt.Println("Invalid form action:", err)
+			return
+		}
+		target.RawQuery = urlencodeEntries(entries)
+		target.ForceQuery = true
+		req.URL = target.String()
+	} else {
+		body, contentType, err := encodeEntries(entries, s.enctype)
+		if err != nil {
+			fmt.Println("Error encoding form data:", err)
+			return
+		}
+		req.Method, req.Body, req.ContentType = "POST", body, contentType
+	}
+	b.navigateTo(req, s.target)
+}
+
+// navigateTo loads req in the browsing context target names.
+func (b *Browser) navigateTo(req NavigationRequest, target string) {
+	switch strings.ToLower(target) {
+	case "", "_self", "_parent", "_top":
+	default:
+		// Without frames, any other name is a new window
+		// TODO: new windows open a URL only, so a POST's method and body are lost
+		b.openNewWindow(req.URL)
+		return
+	}
+	if b.OnNavigate == nil {
+		return
+	}
+	go func() {
+		if b.onBeforeNavigate != nil && !b.onBeforeNavigate() {
+			return
+		}
+		b.OnNavigate(req)
+	}()
+}
+
+// noValidate reports whether form is submitted without checking its
+// fields: it has novalidate, or submitter has formnovalidate.
+func noValidate(form, submitter *dom.Node) bool {
+	if submitter != nil {
+		if _, ok := submitter.Attributes["formnovalidate"]; ok {
+			return true
+		}
+	}
+	_, ok := form.Attributes["novalidate"]
+	return ok
+}
+
+// submission returns how form is sent when submitter, which may be nil,
+// submits it. An unknown method is GET and an unknown enctype is
+// urlencoded; an empty action is the page itself.
+func (b *Browser) submission(form, submitter *dom.Node) formSubmission {
+	attr := func(formAttr, buttonAttr string) string {
+		if submitter != nil {
+			if value, ok := submitter.Attributes[buttonAttr]; ok {
+				return strings.TrimSpace(value)
+			}
+		}
+		return strings.TrimSpace(form.Attributes[formAttr])
+	}
+	s := formSubmission{
+		method:  strings.ToLower(attr("method", "formmethod")),
+		enctype: strings.ToLower(attr("enctype", "formenctype")),
+		target:  attr("target", "formtarget"),
+	}
+	if s.method != "post" && s.method != "dialog" {
+		s.method = "get"
+	}
+	if s.enctype != enctypeMultipart && s.enctype != enctypeTextPlain {
+		s.enctype = enctypeURLEncoded
+	}
+	if action := attr("action", "formaction"); action != "" {
+		s.action = b.resolveURL(action)
+	} else {
+		s.action = b.GetCurrentURL()
+	}
+	return s
+}
+
+// formEntries returns the entry list of form submitted by submitter: the
+// names and values of its enabled, named controls in tree order. Of its
+// buttons only submitter is included, and of its checkboxes and radio
+// buttons only those checked.
+func (b *Browser) formEntries(form, submitter *dom.Node) []formEntry {
+	var entries []formEntry
+	for _, control := range dom.FormControls(form) {
+		if dom.IsControlDisabled(control) {
+			continue
+		}
+		name := control.Attributes["name"]
+		inputType := strings.ToLower(control.Attributes["type"])
+		if dom.ButtonType(control) != "" {
+			if control != submitter {
+				continue
+			}
+			// An image button sends where it was clicked
+			if control.TagName == dom.TagInput && inputType == "image" {
+				prefix := ""
+				if name != "" {
+					prefix = name + "."
+				}
+				entries = append(entries, formEntry{name: prefix + "x", value: "0"}, formEntry{name: prefix + "y", value: "0"})
+				continue
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		switch control.TagName {
+		case dom.TagSelect:
+			for _, value := range b.selectedValues(control) {
+				entries = append(entries, formEntry{name: name, value: value})
+			}
+		case dom.TagTextarea:
+			entries = append(entries, formEntry{name: name, value: normalizeNewlines(b.fieldValue(control))})
+		case dom.TagButton:
+			entries = append(entries, formEntry{name: name, value: control.Attributes["value"]})
+		case dom.TagInput:
+			switch inputType {
+			case "checkbox", "radio":
+				if !b.isChecked(control) {
+					continue
+				}
+				value, ok := control.Attributes["value"]
+				if !ok {
+					value = "on"
+				}
+				entries = append(entries, formEntry{name: name, value: value})
+			case "file":
+				entries = append(entries, formEntry{name: name, isFile: true, file: b.fileInputValues[control]})
+			case "hidden":
+				value := control.Attributes["value"]
+				if name == "_charset_" {
+					value = "UTF-8"
+				}
+				entries = append(entries, formEntry{name: name, value: value})
+			default:
+				entries = append(entries, formEntry{name: name, value: b.fieldValue(control)})
+			}
+		}
+	}
+	return entries
+}
+
+// fieldValue returns the value of a text input or textarea: what the user
+// left in it, else its initial value.
+func (b *Browser) fieldValue(field *dom.Node) string {
+	if value, ok := b.inputValues[field]; ok {
+		return value
+	}
+	if field.TagName != dom.TagTextarea {
+		return field.Attributes["value"]
+	}
+	var sb strings.Builder
+	for _, child := range field.Children {
+		if child.Type == dom.Text {
+			sb.WriteString(child.Text)
+		}
+	}
+	return sb.String()
+}
+
+// normalizeNewlines turns every line break in s into CRLF, as forms send
+// them.
+func normalizeNewlines(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// resetForm returns form's controls to their initial values.
+func (b *Browser) resetForm(form *dom.Node) {
+	for _, control := range dom.FormControls(form) {
+		delete(b.inputValues, control)
+		delete(b.checkboxValue, control)
+		delete(b.fileInputValues, control)
+		delete(b.invalidNodes, control)
+		if strings.EqualFold(control.Attributes["type"], "radio") {
+			delete(b.radioValues, control.Attributes["name"])
+		}
+	}
+	b.repaint()
+}
+
+// isChecked reports whether a checkbox or radio button is checked: as the
+// user left it, else as its checked attribute has it.
+func (b *Browser) isChecked(node *dom.Node) bool {
+	switch strings.ToLower(node.Attributes["type"]) {
+	case "checkbox":
+		if checked, ok := b.checkboxValue[node]; ok {
+			return checked
+		}
+	case "radio":
+		if selected, ok := b.radioValues[node.Attributes["name"]]; ok {
+			return selected == node
+		}
+	default:
+		return false
+	}
+	_, checked := node.Attributes["checked"]
+	return checked
+}
+
+// getSelectedValue returns the value of a select's selected option: the
+// one the user picked, else the first with the selected attribute, else
+// the first option.
+func (b *Browser) getSelectedValue(selectNode *dom.Node) string {
+	var options []*dom.Node
+	for _, child := range selectNode.Children {
+		if child.TagName == dom.TagOption {
+			options = append(options, child)
+		}
+	}
+	// The dropdown remembers the label picked
+	if label, ok := b.inputValues[selectNode]; ok {
+		for _, option := range options {
+			if optionLabel(option) == label {
+				return optionValue(option)
+			}
+		}
+		return label
+	}
+	for _, option := range options {
+		if _, selected := option.Attributes["selected"]; selected {
+			return optionValue(option)
+		}
+	}
+	if len(options) > 0 {
+		return optionValue(options[0])
+	}
+	return ""
+}
+
+// selectedValues returns the values a select submits: that of its
+// selected option, or of each option selected in a multiple select.
+func (b *Browser) selectedValues(selectNode *dom.Node) []string {
+	if _, multiple := selectNode.Attributes["multiple"]; !multiple {
+		if dom.FindElementsByTagName(selectNode, dom.TagOption) == nil {
+			return nil
+		}
+		return []string{b.getSelectedValue(selectNode)}
+	}
+	var values []string
+	for option := range dom.Elements(selectNode, dom.TagOption) {
+		if _, selected := option.Attributes["selected"]; selected && !isNodeDisabled(option) {
+			values = append(values, optionValue(option))
+		}
+	}
+	return values
+}
+
+// optionLabel returns the text an option shows.
+func optionLabel(option *dom.Node) string {
+	for _, child := range option.Children {
+		if child.Type == dom.Text {
+			return dom.CollapseWhitespace(child.Text)
+		}
+	}
+	return ""
+}
+
+// optionValue returns the value an option submits: its value attribute,
+// else its label.
+func optionValue(option *dom.Node) string {
+	if value, ok := option.Attributes["value"]; ok {
+		return value
+	}
+	return optionLabel(option)
+}
+
+// encodeEntries returns entries as a request body in enctype, and the
+// body's Content-Type.
+func encodeEntries(entries []formEntry, enctype string) ([]byte, string, error) {
+	switch enctype {
+	case enctypeMultipart:
+		return multipartEntries(entries)
+	case enctypeTextPlain:
+		var sb strings.Builder
+		for _, e := range entries {
+			sb.WriteString(e.name + "=" + entryValue(e) + "\r\n")
+		}
+		return []byte(sb.String()), "text/plain;charset=UTF-8", nil
+	}
+	return []byte(urlencodeEntries(entries)), enctypeURLEncoded, nil
+}
+
+// urlencodeEntries serializes entries as application/x-www-form-urlencoded,
+// in order.
+func urlencodeEntries(entries []formEntry) string {
+	pairs := make([]string, len(entries))
+	for i, e := range entries {
+		pairs[i] = url.QueryEscape(e.name) + "=" + url.QueryEscape(entryValue(e))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// multipartEntries serializes entries as multipart/form-data, uploading
+// the chosen files.
+func multipartEntries(entries []formEntry) ([]byte, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, e := range entries {
+		if !e.isFile {
+			if err := writer.WriteField(e.name, e.value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		// A file input with no file chosen sends an empty part
+		var data []byte
+		if e.file != "" {
+			var err error
+			if data, err = os.ReadFile(e.file); err != nil {
+				return nil, "", err
+			}
+		}
+		part, err := writer.CreateFormFile(e.name, entryValue(e))
+		if err != nil {
+			return nil, "", err
+		}
+		part.Write(data)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// entryValue returns the value of e as text: a file's name, for file
+// entries.
+func entryValue(e formEntry) string {
+	if !e.isFile {
+		return e.value
+	}
+	if e.file == "" {
+		return ""
+	}
+	return filepath.Base(e.file)
+}
@@ -0,0 +1,274 @@
+package render
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"browser/dom"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formBrowser returns a browser showing html at pageURL, and the channel
+// its navigations are sent to.
+func formBrowser(t *testing.T, pageURL, html string) (*Browser, chan NavigationRequest) {
+	current, err := url.Parse(pageURL)
+	require.NoError(t, err)
+	navigations := make(chan NavigationRequest, 1)
+	b := &Browser{
+		currentURL:      current,
+		document:        dom.Parse(strings.NewReader(html)),
+		inputValues:     map[*dom.Node]string{},
+		radioValues:     map[string]*dom.Node{},
+		checkboxValue:   map[*dom.Node]bool{},
+		fileInputValues: map[*dom.Node]string{},
+		formHistory:     map[string][]string{},
+		OnNavigate:      func(req NavigationRequest) { navigations <- req },
+	}
+	return b, navigations
+}
+
+func nextNavigation(t *testing.T, navigations chan NavigationRequest) NavigationRequest {
+	select {
+	case req := <-navigations:
+		return req
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no navigation")
+	}
+	return NavigationRequest{}
+}
+
+func TestFormEntries(t *testing.T) {
+	b, _ := formBrowser(t, "https://example.com/", `<form id="f">
+		<input name="user" value="initial"><input name="typed" value="old">
+		<input type="password" name="pw"><input name="off" disabled value="x"><input value="unnamed">
+		<input type="checkbox" name="remember" checked><input type="checkbox" name="news" value="yes">
+		<input type="radio" name="plan" value="free" checked><input type="radio" name="plan" value="pro">
+		<select name="country"><option value="ar">Argentina</option><option value="uy" selected>Uruguay</option></select>
+		<select name="tags" multiple><option selected>a</option><option>b</option><option selected>c</option></select>
+		<textarea name="note">line one
+line two</textarea>
+		<fieldset disabled><input name="fenced" value="x"></fieldset>
+		<input type="hidden" name="_charset_"><input type="hidden" name="token" value="t0k">
+		<button name="action" value="save">Save</button><input type="submit" name="other" value="Other">
+	</form><input name="outside" form="f" value="o">`)
+	field := func(name string) *dom.Node { return namedElement(b.document, name) }
+	b.inputValues[field("typed")] = ""
+	b.inputValues[field("pw")] = "s3cret"
+	b.checkboxValue[field("news")] = true
+	for radio := range dom.Elements(b.document, dom.TagInput) {
+		if radio.Attributes["value"] == "pro" {
+			b.radioValues["plan"] = radio
+		}
+	}
+
+	form := dom.FindByID(b.document, "f")
+	var pairs []string
+	for _, e := range b.formEntries(form, field("action")) {
+		pairs = append(pairs, e.name+"="+e.value)
+	}
+	assert.Equal(t, []string{
+		"user=initial", "typed=", "pw=s3cret", "remember=on", "news=yes", "plan=pro",
+		"country=uy", "tags=a", "tags=c", "note=line one\r\nline two",
+		"_charset_=UTF-8", "token=t0k", "action=save", "outside=o",
+	}, pairs)
+}
+
+// namedElement returns the first element under root named name.
+func namedElement(root *dom.Node, name string) *dom.Node {
+	for n := range dom.Elements(root, "") {
+		if n.Attributes["name"] == name {
+			return n
+		}
+	}
+	return nil
+}
+
+func TestSubmitFormGet(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/search/page", `<form id="f" action="results?old=1#top">
+		<input name="q"><input name="lang" value="en"><button id="go">Search</button>
+		<button id="alt" formaction="/elsewhere" name="mode" value="alt">Alt</button></form>`)
+	form := dom.FindByID(b.document, "f")
+	b.inputValues[namedElement(b.document, "q")] = "go & rust"
+
+	b.submitForm(form, dom.FindByID(b.document, "go"))
+	req := nextNavigation(t, navigations)
+	assert.Equal(t, NavigationRequest{
+		URL:     "https://example.com/search/results?q=go+%26+rust&lang=en#top",
+		Method:  "GET",
+		FromURL: "https://example.com/search/page",
+	}, req, "the entries replace the action's query")
+
+	b.submitForm(form, dom.FindByID(b.document, "alt"))
+	assert.Equal(t, "https://example.com/elsewhere?q=go+%26+rust&lang=en&mode=alt", nextNavigation(t, navigations).URL)
+}
+
+func TestSubmitFormPost(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/login", `<form id="f" method="POST" action="/session">
+		<input name="user"><input type="password" name="pw"><input id="go" type="submit" value="Log in">
+		<input id="upload" type="submit" formenctype="multipart/form-data" formmethod="post">
+		<input id="plain" type="submit" formenctype="text/plain">
+		<input id="get" type="submit" formmethod="get"></form>`)
+	form := dom.FindByID(b.document, "f")
+	b.inputValues[namedElement(b.document, "user")] = "ada"
+	b.inputValues[namedElement(b.document, "pw")] = "a=b&c"
+
+	b.submitForm(form, dom.FindByID(b.document, "go"))
+	req := nextNavigation(t, navigations)
+	assert.Equal(t, "https://example.com/session", req.URL)
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "application/x-www-form-urlencoded", req.ContentType)
+	assert.Equal(t, "user=ada&pw=a%3Db%26c", string(req.Body))
+
+	b.submitForm(form, dom.FindByID(b.document, "plain"))
+	req = nextNavigation(t, navigations)
+	assert.Equal(t, "text/plain;charset=UTF-8", req.ContentType)
+	assert.Equal(t, "user=ada\r\npw=a=b&c\r\n", string(req.Body))
+
+	b.submitForm(form, dom.FindByID(b.document, "get"))
+	req = nextNavigation(t, navigations)
+	assert.Equal(t, "GET", req.Method)
+	assert.Equal(t, "https://example.com/session?user=ada&pw=a%3Db%26c", req.URL)
+}
+
+func TestSubmitFormTarget(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/", `<form id="f" action="/search" target="results">
+		<input name="q"><input id="go" type="submit"><input id="here" type="submit" formtarget="_self"></form>`)
+	b.App = test.NewTempApp(t)
+	form := dom.FindByID(b.document, "f")
+	windows := len(b.App.Driver().AllWindows())
+
+	b.submitForm(form, dom.FindByID(b.document, "go"))
+	assert.Len(t, b.App.Driver().AllWindows(), windows+1, "a named target opens a new window")
+	assert.Empty(t, navigations)
+
+	b.submitForm(form, dom.FindByID(b.document, "here"))
+	assert.Equal(t, "https://example.com/search?q=", nextNavigation(t, navigations).URL)
+	assert.Len(t, b.App.Driver().AllWindows(), windows+1)
+}
+
+func TestSubmitFormMultipart(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/", `<form id="f" method="post" enctype="multipart/form-data" action="/upload">
+		<input name="title"><input type="file" name="doc"><input type="file" name="none"><textarea name="body"></textarea></form>`)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("file contents"), 0o644))
+	b.inputValues[namedElement(b.document, "title")] = "Report"
+	b.fileInputValues[namedElement(b.document, "doc")] = path
+	b.inputValues[namedElement(b.document, "body")] = "a\nb"
+
+	b.submitForm(dom.FindByID(b.document, "f"), nil)
+	req := nextNavigation(t, navigations)
+	assert.Equal(t, "POST", req.Method)
+	mediaType, params, err := mime.ParseMediaType(req.ContentType)
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/form-data", mediaType)
+
+	reader := multipart.NewReader(strings.NewReader(string(req.Body)), params["boundary"])
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, _ := io.ReadAll(part)
+		parts = append(parts, part.FormName()+" "+part.FileName()+" "+string(data))
+	}
+	assert.Equal(t, []string{"title  Report", "doc notes.txt file contents", "none  ", "body  a\r\nb"}, parts, "in tree order")
+}
+
+func TestSubmitFormInvalid(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/", `<form id="f"><input name="q" required></form>`)
+	form := dom.FindByID(b.document, "f")
+
+	b.submitForm(form, nil)
+	assert.True(t, b.invalidNodes[namedElement(b.document, "q")])
+	select {
+	case req := <-navigations:
+		assert.Fail(t, "an invalid form was sent", req.URL)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	form.Attributes["novalidate"] = ""
+	b.submitForm(form, nil)
+	assert.Equal(t, "https://example.com/?q=", nextNavigation(t, navigations).URL)
+}
+
+func TestSubmitFormDialog(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/", `<dialog open><form id="f" method="dialog"><button>OK</button></form></dialog>`)
+
+	form, dialog := dom.FindByID(b.document, "f"), dom.FindElementsByTagName(b.document, dom.TagDialog)
+	b.document = nil // nothing to reflow without a window
+
+	b.submitForm(form, nil)
+	assert.False(t, dom.IsDialogOpen(dialog))
+	assert.Empty(t, navigations, "dialog forms do not navigate")
+}
+
+func TestSubmitImplicitly(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/", `<form id="one" action="/a"><input name="q"></form>
+		<form id="two" action="/b"><input name="user"><input name="pw" type="password"></form>
+		<form id="button" action="/c"><input name="x"><input name="y"><button name="via" value="enter">Go</button></form>`)
+	var clicked []*dom.Node
+	b.onJSClick = func(node *dom.Node) bool {
+		clicked = append(clicked, node)
+		return false
+	}
+
+	b.submitImplicitly(namedElement(b.document, "q"))
+	assert.Equal(t, "https://example.com/a?q=", nextNavigation(t, navigations).URL, "a lone field submits its form")
+
+	b.submitImplicitly(namedElement(b.document, "user"))
+	select {
+	case req := <-navigations:
+		assert.Fail(t, "two fields and no button submitted", req.URL)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.submitImplicitly(namedElement(b.document, "x"))
+	assert.Equal(t, "https://example.com/c?x=&y=&via=enter", nextNavigation(t, navigations).URL, "the default button is clicked")
+	assert.Equal(t, []*dom.Node{namedElement(b.document, "via")}, clicked)
+}
+
+func TestActivateButton(t *testing.T) {
+	b, navigations := formBrowser(t, "https://example.com/", `<form id="f"><input name="q" value="start" id="q">
+		<input type="checkbox" name="c" checked><button id="go">Go</button><button id="reset" type="reset">Reset</button></form>`)
+	prevent := true
+	b.onJSClick = func(*dom.Node) bool { return prevent }
+	q := dom.FindByID(b.document, "q")
+	b.inputValues[q] = "changed"
+
+	b.activateButton(dom.FindByID(b.document, "go"), dom.FindByID(b.document, "go"))
+	assert.Empty(t, navigations, "preventDefault stops the submission")
+
+	prevent = false
+	b.checkboxValue[namedElement(b.document, "c")] = false
+	b.activateButton(dom.FindByID(b.document, "reset"), dom.FindByID(b.document, "reset"))
+	assert.Equal(t, "start", b.fieldValue(q))
+	assert.True(t, b.isChecked(namedElement(b.document, "c")), "reset restores the checked attribute")
+
+	b.activateButton(dom.FindByID(b.document, "go"), dom.FindByID(b.document, "go"))
+	assert.Equal(t, "https://example.com/?q=start&c=on", nextNavigation(t, navigations).URL)
+}
+
+func TestGetSelectedValue(t *testing.T) {
+	b, _ := formBrowser(t, "https://example.com/", `<select id="s"><option value="ar">Argentina</option>
+		<option value="uy" selected>Uruguay</option><option>Chile</option></select><select id="empty"></select>`)
+	s := dom.FindByID(b.document, "s")
+
+	assert.Equal(t, "uy", b.getSelectedValue(s), "the selected attribute, even without a value")
+	b.inputValues[s] = "Argentina"
+	assert.Equal(t, "ar", b.getSelectedValue(s), "the dropdown stores the label picked")
+	b.inputValues[s] = "Chile"
+	assert.Equal(t, "Chile", b.getSelectedValue(s))
+	assert.Nil(t, b.selectedValues(dom.FindByID(b.document, "empty")))
+}
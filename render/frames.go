@@ -0,0 +1,108 @@
+package render
+
+import (
+	"sync"
+	"time"
+)
+
+// MaxFPS caps how many frames per second the page repaints and runs
+// animation frame callbacks. Zero or less removes the cap.
+var MaxFPS = 60
+
+// frameInterval is the shortest time allowed between two frames.
+func frameInterval() time.Duration {
+	if MaxFPS <= 0 {
+		return 0
+	}
+	return time.Second / time.Duration(MaxFPS)
+}
+
+// frameScheduler coalesces repaint and animation frame requests into frames,
+// at most MaxFPS a second. It holds no timer while nothing is pending, so a
+// page that is not animating or changing does no rendering work at all.
+type frameScheduler struct {
+	mu        sync.Mutex
+	scheduled bool // a frame is waiting for its timer
+	repaint   bool // the next frame must repaint
+	lastFrame time.Time
+
+	now   func() time.Time
+	after func(d time.Duration, f func())
+	run   func(frameTime time.Time, repaint bool)
+}
+
+func newFrameScheduler(run func(frameTime time.Time, repaint bool)) *frameScheduler {
+	return &frameScheduler{
+		now:   time.Now,
+		after: func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+		run:   run,
+	}
+}
+
+// request asks for a frame, which also repaints when repaint is set.
+// Requests made before the frame runs share it.
+func (s *frameScheduler) request(repaint bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repaint = s.repaint || repaint
+	if s.scheduled {
+		return
+	}
+	s.scheduled = true
+	delay := max(0, frameInterval()-s.now().Sub(s.lastFrame))
+	s.after(delay, s.fire)
+}
+
+// fire runs the scheduled frame. Work requested while it runs gets a new
+// frame; otherwise the scheduler goes idle.
+func (s *frameScheduler) fire() {
+	s.mu.Lock()
+	s.scheduled = false
+	repaint := s.repaint
+	s.repaint = false
+	s.lastFrame = s.now()
+	frameTime := s.lastFrame
+	s.mu.Unlock()
+
+	s.run(frameTime, repaint)
+}
+
+// idle reports whether no frame is pending.
+func (s *frameScheduler) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.scheduled
+}
+
+// SetAnimationFrameHandler sets the callback that runs the page's animation
+// frame callbacks at frame time. It reports whether any ran, in which case
+// it has already reflowed and repainted the page.
+func (b *Browser) SetAnimationFrameHandler(handler func(frameTime time.Time) bool) {
+	b.onAnimationFrame = handler
+}
+
+// RequestFrame asks for an animation frame, as requestAnimationFrame does.
+func (b *Browser) RequestFrame() {
+	b.frames.request(false)
+}
+
+// scheduleRepaint repaints the page at the next frame, so bursts of changes
+// such as images arriving or wheel scrolling cost one repaint per frame.
+func (b *Browser) scheduleRepaint() {
+	b.frames.request(true)
+}
+
+// runFrame runs a frame: the scroll event if the page scrolled, animation
+// callbacks, then a repaint if one was asked for and the callbacks did not
+// already cause it.
+func (b *Browser) runFrame(frameTime time.Time, repaint bool) {
+	if b.scrollPending.Swap(false) && b.onJSScroll != nil {
+		b.onJSScroll()
+	}
+	if b.onAnimationFrame != nil && b.onAnimationFrame(frameTime) {
+		return
+	}
+	if repaint {
+		b.repaint()
+	}
+}
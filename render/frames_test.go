@@ -0,0 +1,83 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeFrameClock drives a frameScheduler without real timers.
+type fakeFrameClock struct {
+	now     time.Time
+	pending func()
+	delay   time.Duration
+	frames  []bool // repaint flag of each frame run
+}
+
+func newTestScheduler(clock *fakeFrameClock) *frameScheduler {
+	s := newFrameScheduler(func(_ time.Time, repaint bool) {
+		clock.frames = append(clock.frames, repaint)
+	})
+	s.now = func() time.Time { return clock.now }
+	s.after = func(d time.Duration, f func()) {
+		clock.delay = d
+		clock.pending = f
+	}
+	return s
+}
+
+// tick advances the clock to the scheduled frame and runs it.
+func (c *fakeFrameClock) tick() {
+	f := c.pending
+	c.pending = nil
+	c.now = c.now.Add(c.delay)
+	f()
+}
+
+func TestFrameSchedulerCoalesces(t *testing.T) {
+	clock := &fakeFrameClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(clock)
+	assert.True(t, s.idle())
+
+	s.request(false)
+	s.request(true)
+	s.request(false)
+	assert.False(t, s.idle())
+	assert.Equal(t, time.Duration(0), clock.delay, "the first frame runs at once")
+
+	clock.tick()
+	assert.Equal(t, []bool{true}, clock.frames, "requests share one frame")
+	assert.True(t, s.idle(), "no timer runs while nothing is pending")
+	assert.Nil(t, clock.pending)
+}
+
+func TestFrameSchedulerCapsRate(t *testing.T) {
+	defer func(fps int) { MaxFPS = fps }(MaxFPS)
+	MaxFPS = 50
+
+	clock := &fakeFrameClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(clock)
+
+	s.request(true)
+	clock.tick()
+
+	clock.now = clock.now.Add(5 * time.Millisecond)
+	s.request(false)
+	assert.Equal(t, 15*time.Millisecond, clock.delay, "waits out the rest of the 20ms frame")
+	clock.tick()
+
+	clock.now = clock.now.Add(time.Second)
+	s.request(false)
+	assert.Equal(t, time.Duration(0), clock.delay, "an idle page repaints at once")
+	assert.Equal(t, []bool{true, false}, clock.frames)
+}
+
+func TestFrameInterval(t *testing.T) {
+	defer func(fps int) { MaxFPS = fps }(MaxFPS)
+
+	MaxFPS = 60
+	assert.Equal(t, time.Second/60, frameInterval())
+	MaxFPS = 0
+	assert.Equal(t, time.Duration(0), frameInterval(), "uncapped")
+}
@@ -0,0 +1,35 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"io"
+
+	_ "golang.org/x/image/webp" // registers WebP with image.Decode
+)
+
+// Image formats beyond the standard library's GIF, JPEG and PNG. WebP
+// decodes in pure Go. AVIF has no pure Go decoder to register, so it is
+// recognized only to fail with a clear error, and image requests leave it
+// out of their Accept header so servers that negotiate the format send
+// WebP or JPEG instead.
+
+// imageAccept is the Accept header of image requests: every format
+// image.Decode and decodeSVG take.
+const imageAccept = "image/webp,image/svg+xml,image/png,image/jpeg,image/gif,*/*;q=0.8"
+
+// errAVIFUnsupported is the error decoding an AVIF image gives.
+//
+// TODO: decode AVIF. It needs an AV1 decoder, which only exists as cgo
+// bindings to libdav1d or libaom; once one is vendored, register it here
+// in place of the stub and add image/avif to imageAccept.
+var errAVIFUnsupported = errors.New("AVIF images are not supported")
+
+func init() {
+	// An ISOBMFF file whose ftyp box names the AVIF brand, as an image
+	// (avif) or an image sequence (avis)
+	decode := func(io.Reader) (image.Image, error) { return nil, errAVIFUnsupported }
+	decodeConfig := func(io.Reader) (image.Config, error) { return image.Config{}, errAVIFUnsupported }
+	image.RegisterFormat("avif", "????ftypavif", decode, decodeConfig)
+	image.RegisterFormat("avif", "????ftypavis", decode, decodeConfig)
+}
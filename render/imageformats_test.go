@@ -0,0 +1,60 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"browser/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A lossless 1x1 WebP of an orange pixel
+var tinyWebP = []byte{
+	0x52, 0x49, 0x46, 0x46, 0x1a, 0x00, 0x00, 0x00, 0x57, 0x45, 0x42, 0x50,
+	0x56, 0x50, 0x38, 0x4c, 0x0d, 0x00, 0x00, 0x00, 0x2f, 0x00, 0x00, 0x00,
+	0x10, 0x28, 0x60, 0xff, 0x0b, 0xd2, 0xff, 0x02, 0x00, 0x00,
+}
+
+// The start of an AVIF file: its ftyp box
+var avifHeader = []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf")
+
+func TestImageFormats(t *testing.T) {
+	t.Run("webp", func(t *testing.T) {
+		img, format, err := image.Decode(bytes.NewReader(tinyWebP))
+		require.NoError(t, err)
+		assert.Equal(t, "webp", format)
+		assert.Equal(t, image.Rect(0, 0, 1, 1), img.Bounds())
+		assert.Equal(t, color.NRGBA{255, 128, 32, 255}, color.NRGBAModel.Convert(img.At(0, 0)))
+	})
+
+	t.Run("avif is recognized but not decoded", func(t *testing.T) {
+		_, format, err := image.Decode(bytes.NewReader(avifHeader))
+		assert.Equal(t, "avif", format)
+		assert.ErrorIs(t, err, errAVIFUnsupported)
+	})
+
+	t.Run("fetched with an Accept header", func(t *testing.T) {
+		var accept string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept = r.Header.Get("Accept")
+			w.Header().Set("Content-Type", "image/webp")
+			w.Write(tinyWebP)
+		}))
+		defer server.Close()
+		url := server.URL + "/tiny.webp"
+		defer utils.HTTPCache.Clear(utils.SiteOf(url), time.Time{})
+
+		img, err := fetchimageToCache(url, "", "")
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 1, 1), img.Bounds())
+		assert.Contains(t, accept, "image/webp")
+		assert.NotContains(t, accept, "image/avif")
+	})
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"browser/dom"
+	"browser/layout"
+)
+
+// Height of the control bar drawn along the bottom of media with controls
+const mediaControlsHeight = 32.0
+
+var (
+	mediaBackground    = color.RGBA{0, 0, 0, 255}
+	mediaControlsShade = color.RGBA{0, 0, 0, 160}
+	audioBackground    = color.RGBA{241, 243, 244, 255}
+	embedBackground    = color.RGBA{240, 240, 240, 255}
+	embedLabelColor    = color.RGBA{110, 110, 110, 255}
+)
+
+// paintMedia draws a media element nothing plays yet: a video as its
+// poster image on black, audio as a bar of controls, a canvas as its
+// bitmap, and embedded content as a grey box naming its type. Videos with
+// the controls attribute get a control bar along their bottom edge.
+func paintMedia(box *layout.LayoutBox, rect layout.Rect, commands *[]DisplayCommand) {
+	node := box.Node
+	switch node.TagName {
+	case dom.TagVideo:
+		*commands = append(*commands, DrawRect{Rect: rect, Color: mediaBackground})
+		if poster := node.Attributes["poster"]; poster != "" {
+			// The poster is letterboxed like the video would be
+			*commands = append(*commands, DrawImage{
+				Rect:           rect,
+				URL:            poster,
+				ReferrerPolicy: node.Attributes["referrerpolicy"],
+				SizeMode:       "contain",
+				Background:     true,
+				Position:       "center",
+				Repeat:         "no-repeat",
+			})
+		}
+		if _, ok := node.Attributes["controls"]; ok {
+			bar := rect
+			bar.Y = rect.Y + max(rect.Height-mediaControlsHeight, 0)
+			bar.Height = min(mediaControlsHeight, rect.Height)
+			*commands = append(*commands, DrawRect{Rect: bar, Color: mediaControlsShade})
+			paintMediaControls(bar, ColorWhite, commands)
+		}
+	case dom.TagAudio:
+		*commands = append(*commands, DrawRect{Rect: rect, Color: audioBackground, CornerRadius: min(rect.Height/2, 16)})
+		paintMediaControls(rect, ColorBlack, commands)
+	case dom.TagCanvas:
+		*commands = append(*commands, DrawCanvas{Rect: rect, Bitmap: node.CanvasBitmap()})
+	default:
+		*commands = append(*commands, DrawRect{Rect: rect, Color: embedBackground})
+		label := node.Attributes["type"]
+		if label == "" {
+			label = "<" + node.TagName + ">"
+		}
+		*commands = append(*commands, DrawText{
+			Text:  label,
+			X:     rect.X + 8,
+			Y:     rect.Y + 8,
+			Width: max(rect.Width-16, 0),
+			Color: embedLabelColor,
+			Size:  12,
+		})
+	}
+}
+
+// DrawCanvas draws a <canvas> element's bitmap stretched over Rect.
+// Scripts draw into Bitmap in place, so what it shows is read when it is
+// rendered.
+type DrawCanvas struct {
+	layout.Rect
+	Bitmap *image.NRGBA
+}
+
+// paintMediaControls draws the stub of a media control bar in bar: a play
+// button and the elapsed time, vertically centred.
+func paintMediaControls(bar layout.Rect, c color.Color, commands *[]DisplayCommand) {
+	y := bar.Y + (bar.Height-14)/2
+	*commands = append(*commands,
+		DrawText{Text: "▶", X: bar.X + 12, Y: y, Color: c, Size: 14},
+		DrawText{Text: "0:00", X: bar.X + 36, Y: y, Color: c, Size: 12},
+	)
+}
@@ -0,0 +1,60 @@
+package render
+
+import (
+	"image"
+	"testing"
+
+	"browser/dom"
+	"browser/layout"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaintMedia(t *testing.T) {
+	rect := layout.Rect{X: 10, Y: 20, Width: 300, Height: 150}
+	paint := func(tag string, attrs map[string]string) []DisplayCommand {
+		var commands []DisplayCommand
+		paintMedia(&layout.LayoutBox{Type: layout.MediaBox, Node: dom.NewElement(tag, attrs)}, rect, &commands)
+		return commands
+	}
+
+	t.Run("video poster", func(t *testing.T) {
+		commands := paint("video", map[string]string{"poster": "poster.jpg"})
+		if assert.Len(t, commands, 2) {
+			img, ok := commands[1].(DrawImage)
+			assert.True(t, ok)
+			assert.Equal(t, "poster.jpg", img.URL)
+			assert.Equal(t, rect, img.Rect)
+			assert.Equal(t, "contain", img.SizeMode)
+		}
+	})
+
+	t.Run("video controls along the bottom", func(t *testing.T) {
+		commands := paint("video", map[string]string{"controls": ""})
+		if assert.Len(t, commands, 4) {
+			bar, ok := commands[1].(DrawRect)
+			assert.True(t, ok)
+			assert.Equal(t, rect.Y+rect.Height-mediaControlsHeight, bar.Y)
+			assert.Equal(t, rect.Width, bar.Width)
+		}
+	})
+
+	t.Run("canvas draws its bitmap", func(t *testing.T) {
+		commands := paint("canvas", map[string]string{"width": "40", "height": "20"})
+		if assert.Len(t, commands, 1) {
+			c, ok := commands[0].(DrawCanvas)
+			assert.True(t, ok)
+			assert.Equal(t, rect, c.Rect)
+			assert.Equal(t, image.Rect(0, 0, 40, 20), c.Bitmap.Rect, "at the size of its attributes")
+		}
+	})
+
+	t.Run("embed names its type", func(t *testing.T) {
+		commands := paint("embed", map[string]string{"type": "application/pdf"})
+		if assert.Len(t, commands, 2) {
+			label, ok := commands[1].(DrawText)
+			assert.True(t, ok)
+			assert.Equal(t, "application/pdf", label.Text)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// TextEncodings lists the Text Encoding menu's override choices.
+var TextEncodings = []string{
+	"UTF-8", "windows-1252", "ISO-8859-2", "windows-1251", "KOI8-R",
+	"Shift_JIS", "EUC-JP", "ISO-2022-JP", "GBK", "gb18030", "Big5",
+	"EUC-KR", "UTF-16LE", "UTF-16BE",
+}
+
+// SetDocumentEncoding records the encoding the current page was decoded
+// with, as shown in the Text Encoding menu.
+func (b *Browser) SetDocumentEncoding(name string, overridden bool) {
+	b.encoding = name
+	b.encodingOverridden = overridden
+	b.refreshMainMenu()
+}
+
+// DocumentEncoding returns the canonical name of the current page's
+// character encoding.
+func (b *Browser) DocumentEncoding() string {
+	return b.encoding
+}
+
+// SetEncodingOverride re-decodes the current page with the encoding named
+// by label, or re-detects it when label is empty. The response is not
+// fetched again, and the page keeps its history entry.
+func (b *Browser) SetEncodingOverride(label string) {
+	if b.currentURL == nil || b.OnNavigate == nil {
+		return
+	}
+	req := NavigationRequest{URL: b.currentURL.String(), Method: "GET", Encoding: label, Redecode: true, Replace: true}
+	go b.OnNavigate(req)
+}
+
+// refreshMainMenu rebuilds the window menu so check marks and enabled
+// items follow the page state.
+func (b *Browser) refreshMainMenu() {
+	if b.Window == nil {
+		return
+	}
+	menu := b.buildMainMenu()
+	fyne.Do(func() {
+		b.Window.SetMainMenu(menu)
+	})
+}
+
+func (b *Browser) buildMainMenu() *fyne.MainMenu {
+	contents := fyne.NewMenuItem("Contents", b.ToggleOutlinePanel)
+
+	autoDetect := fyne.NewMenuItem("Auto-Detect", func() { b.SetEncodingOverride("") })
+	autoDetect.Checked = !b.encodingOverridden
+	encodingItems := []*fyne.MenuItem{autoDetect, fyne.NewMenuItemSeparator()}
+	for _, label := range TextEncodings {
+		label := label
+		item := fyne.NewMenuItem(label, func() { b.SetEncodingOverride(label) })
+		item.Checked = strings.EqualFold(label, b.encoding)
+		encodingItems = append(encodingItems, item)
+	}
+	encoding := fyne.NewMenuItem("Text Encoding", nil)
+	encoding.ChildMenu = fyne.NewMenu("", encodingItems...)
+
+	original := fyne.NewMenuItem("Show Original", func() { go b.ShowOriginal() })
+	original.Checked = b.translatedTo == ""
+	translateItems := []*fyne.MenuItem{original, fyne.NewMenuItemSeparator()}
+	for _, lang := range TranslationLanguages {
+		lang := lang
+		item := fyne.NewMenuItem(lang.Name, func() {
+			go func() {
+				if err := b.TranslatePage(lang.Code); err != nil {
+					b.showToast("Translation failed: " + err.Error())
+				}
+			}()
+		})
+		item.Checked = b.translatedTo == lang.Code
+		translateItems = append(translateItems, item)
+	}
+	translate := fyne.NewMenuItem("Translate", nil)
+	translate.ChildMenu = fyne.NewMenu("", translateItems...)
+	translate.Disabled = b.translator == nil
+
+	dataSaverLabel := "Data Saver"
+	if saved := b.DataSaverBytesSaved(); saved > 0 {
+		dataSaverLabel += " (" + formatBytes(saved) + " saved)"
+	}
+	dataSaverItem := fyne.NewMenuItem(dataSaverLabel, func() { b.SetDataSaver(!b.DataSaverEnabled()) })
+	dataSaverItem.Checked = b.DataSaverEnabled()
+
+	profiling := fyne.NewMenuItem("Show Rendering Timings", func() { b.SetProfiling(!b.Profiling()) })
+	profiling.Checked = b.Profiling()
+
+	browsingData := fyne.NewMenuItem("Browsing Data", func() {
+		if b.OnNavigate != nil {
+			go b.OnNavigate(NavigationRequest{URL: PrivacyURL, Method: "GET"})
+		}
+	})
+
+	items := []*fyne.MenuItem{contents, fyne.NewMenuItemSeparator(), encoding, translate,
+		b.appearanceMenuItem(), b.zoomMenuItem(), fyne.NewMenuItemSeparator()}
+	items = append(items, b.popupMenuItems()...)
+	items = append(items, fyne.NewMenuItemSeparator(), dataSaverItem, browsingData,
+		fyne.NewMenuItemSeparator(), profiling)
+	view := fyne.NewMenu("View", items...)
+	return fyne.NewMainMenu(view)
+}
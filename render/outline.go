@@ -0,0 +1,143 @@
+package render
+
+import (
+	"browser/dom"
+	"browser/layout"
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Width of the table of contents sidebar
+const outlinePanelWidth = 240
+
+// Section is a heading of the document outline resolved against the
+// laid-out page.
+type Section struct {
+	Level    int    // 1–6 for h1–h6
+	Text     string // heading text with whitespace collapsed
+	ID       string // id attribute, empty if the heading has none
+	Node     *dom.Node
+	Offset   float64 // scroll offset of the heading, -1 if it isn't rendered
+	Children []*Section
+}
+
+// Outline returns the current document's h1–h6 hierarchy with the scroll
+// offset of each heading, e.g. for a table of contents. Pass a section to
+// ScrollToSection to navigate to it.
+func (b *Browser) Outline() []*Section {
+	return resolveSections(dom.Outline(b.document), b.layoutTree)
+}
+
+// ScrollToSection scrolls to a section of the outline. Sections with an ID
+// are followed like an in-page #fragment link so the URL bar reflects them.
+func (b *Browser) ScrollToSection(s *Section) bool {
+	if s.ID != "" && b.GoToSection(s.ID) {
+		return true
+	}
+	return b.scrollToNode(s.Node)
+}
+
+// resolveSections converts outline entries to sections, looking up each
+// heading's box in tree for its offset.
+func resolveSections(entries []*dom.OutlineEntry, tree *layout.LayoutBox) []*Section {
+	var sections []*Section
+	for _, e := range entries {
+		s := &Section{Level: e.Level, Text: e.Text, ID: e.ID, Node: e.Node, Offset: -1}
+		if box := findLayoutBoxByNode(tree, e.Node); box != nil {
+			s.Offset = box.Rect.Y
+		}
+		s.Children = resolveSections(e.Children, tree)
+		sections = append(sections, s)
+	}
+	return sections
+}
+
+// flattenSections lists sections in document order with their nesting depth.
+func flattenSections(sections []*Section) ([]*Section, []int) {
+	var flat []*Section
+	var depths []int
+	var walk func(list []*Section, depth int)
+	walk = func(list []*Section, depth int) {
+		for _, s := range list {
+			flat = append(flat, s)
+			depths = append(depths, depth)
+			walk(s.Children, depth+1)
+		}
+	}
+	walk(sections, 0)
+	return flat, depths
+}
+
+// ToggleOutlinePanel shows or hides the table of contents sidebar. The page
+// reflows to the remaining width on the next resize check.
+func (b *Browser) ToggleOutlinePanel() {
+	if b.outlinePanel.Visible() {
+		b.outlinePanel.Hide()
+	} else {
+		b.refreshOutlinePanel()
+		b.outlinePanel.Show()
+	}
+	b.outlinePanel.Refresh()
+}
+
+// sidebarWidth is the horizontal space the sidebar takes from the page.
+func (b *Browser) sidebarWidth() float32 {
+	if b.outlinePanel == nil || !b.outlinePanel.Visible() {
+		return 0
+	}
+	return b.outlinePanel.MinSize().Width + theme.Padding()
+}
+
+// newOutlinePanel builds the (initially hidden) table of contents sidebar.
+func (b *Browser) newOutlinePanel() *fyne.Container {
+	b.outlineList = widget.NewList(
+		func() int { return len(b.outlineItems) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(strings.Repeat("    ", b.outlineDepths[id]) + b.outlineItems[id].Text)
+		},
+	)
+	b.outlineList.OnSelected = func(id widget.ListItemID) {
+		if id < len(b.outlineItems) {
+			b.ScrollToSection(b.outlineItems[id])
+		}
+		b.outlineList.UnselectAll()
+	}
+	b.outlineEmpty = widget.NewLabel("No headings")
+
+	title := widget.NewLabelWithStyle("Contents", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	spacer := canvas.NewRectangle(color.Transparent)
+	spacer.SetMinSize(fyne.NewSize(outlinePanelWidth, 0))
+	panel := container.NewStack(spacer, container.NewBorder(title, nil, nil, nil,
+		container.NewStack(b.outlineList, b.outlineEmpty)))
+	panel.Hide()
+	return panel
+}
+
+// refreshOutlinePanel reloads the sidebar from the current document.
+func (b *Browser) refreshOutlinePanel() {
+	if b.outlineList == nil {
+		return
+	}
+	items, depths := flattenSections(b.Outline())
+	// The list reads the items as it draws, on the UI thread
+	fyne.Do(func() {
+		b.outlineItems, b.outlineDepths = items, depths
+		if len(items) == 0 {
+			b.outlineEmpty.Show()
+		} else {
+			b.outlineEmpty.Hide()
+		}
+		b.outlineList.Refresh()
+	})
+}
@@ -0,0 +1,59 @@
+package render
+
+import (
+	"browser/dom"
+	"browser/layout"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSections(t *testing.T) {
+	h1 := dom.NewElement("h1", map[string]string{"id": "top"})
+	h1.AppendChild(dom.NewText("Guide"))
+	h2 := dom.NewElement("h2", map[string]string{})
+	h2.AppendChild(dom.NewText("Install"))
+	hidden := dom.NewElement("h2", map[string]string{})
+	hidden.AppendChild(dom.NewText("Hidden"))
+	body := dom.NewElement("body", map[string]string{})
+	body.AppendChild(h1)
+	body.AppendChild(h2)
+	body.AppendChild(hidden)
+
+	tree := &layout.LayoutBox{Node: body, Children: []*layout.LayoutBox{
+		{Node: h1, Rect: layout.Rect{Y: 10}},
+		{Node: h2, Rect: layout.Rect{Y: 120}},
+	}}
+
+	sections := resolveSections(dom.Outline(body), tree)
+	assert.Len(t, sections, 1)
+	assert.Equal(t, "Guide", sections[0].Text)
+	assert.Equal(t, "top", sections[0].ID)
+	assert.Equal(t, 10.0, sections[0].Offset)
+	assert.Len(t, sections[0].Children, 2)
+	assert.Equal(t, h2, sections[0].Children[0].Node)
+	assert.Equal(t, 120.0, sections[0].Children[0].Offset)
+	assert.Equal(t, -1.0, sections[0].Children[1].Offset, "heading without a box")
+}
+
+func TestFlattenSections(t *testing.T) {
+	sections := []*Section{
+		{Text: "A", Children: []*Section{
+			{Text: "A.1", Children: []*Section{{Text: "A.1.a"}}},
+			{Text: "A.2"},
+		}},
+		{Text: "B"},
+	}
+
+	flat, depths := flattenSections(sections)
+	var texts []string
+	for _, s := range flat {
+		texts = append(texts, s.Text)
+	}
+	assert.Equal(t, []string{"A", "A.1", "A.1.a", "A.2", "B"}, texts)
+	assert.Equal(t, []int{0, 1, 2, 1, 0}, depths)
+
+	flat, depths = flattenSections(nil)
+	assert.Empty(t, flat)
+	assert.Empty(t, depths)
+}
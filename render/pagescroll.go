@@ -0,0 +1,165 @@
+package render
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// Page scrolling. Wheel and keyboard scrolls glide to where they lead
+// instead of jumping there. A scroll made while the page is still gliding
+// continues from where the last one was heading, so spinning the wheel
+// builds up momentum. Each frame in which the page scrolled fires scroll
+// at the page's window (see runFrame).
+
+// SmoothScrolling makes wheel and keyboard scrolls glide. When false the
+// page jumps straight to the new position.
+var SmoothScrolling = true
+
+const (
+	smoothScrollDuration = 150 * time.Millisecond
+	keyScrollStep        = 40    // arrow keys, in canvas pixels
+	pageScrollFraction   = 0.875 // of the viewport that Space and Page Up/Down scroll
+)
+
+// smoothScroll is the glide the page is scrolling in, if any.
+type smoothScroll struct {
+	anim   *fyne.Animation
+	target fyne.Position // where anim ends
+}
+
+// SetJSScrollHandler sets the callback that fires scroll at the page's
+// window after the page scrolled.
+func (b *Browser) SetJSScrollHandler(handler func()) {
+	b.onJSScroll = handler
+}
+
+// ScrollPosition returns the page's scroll position in CSS pixels.
+func (b *Browser) ScrollPosition() (x, y float64) {
+	if b.contentScroll == nil {
+		return 0, 0
+	}
+	return b.toPage(b.contentScroll.Offset.X, b.contentScroll.Offset.Y)
+}
+
+// ScrollTo scrolls the page to (x, y) in CSS pixels, gliding there when
+// smooth is set, as window.scrollTo does.
+func (b *Browser) ScrollTo(x, y float64, smooth bool) {
+	fyne.Do(func() {
+		b.scrollPageTo(b.fromPage(x, y), smooth)
+	})
+}
+
+// maxScroll returns the largest offset the page scrolls to.
+func (b *Browser) maxScroll() fyne.Position {
+	page, view := b.contentScroll.Content.MinSize(), b.contentScroll.Size()
+	return fyne.NewPos(max(page.Width-view.Width, 0), max(page.Height-view.Height, 0))
+}
+
+// scrollTarget returns where the page is scrolling to: the end of the
+// running glide, or its offset when still.
+func (b *Browser) scrollTarget() fyne.Position {
+	if b.smoothScroll.anim != nil {
+		return b.smoothScroll.target
+	}
+	return b.contentScroll.Offset
+}
+
+// scrollPageTo scrolls the page to offset, clamped to the page, gliding
+// there when smooth is set and SmoothScrolling allows.
+func (b *Browser) scrollPageTo(offset fyne.Position, smooth bool) {
+	if b.contentScroll == nil {
+		return
+	}
+	limit := b.maxScroll()
+	offset = fyne.NewPos(min(max(offset.X, 0), limit.X), min(max(offset.Y, 0), limit.Y))
+	if b.smoothScroll.anim != nil {
+		b.smoothScroll.anim.Stop()
+		b.smoothScroll.anim = nil
+	}
+	from := b.contentScroll.Offset
+	if !smooth || !SmoothScrolling || from == offset {
+		b.setScrollOffset(offset)
+		return
+	}
+
+	// Repaints replace the scroll container, so each step moves the
+	// current one
+	var anim *fyne.Animation
+	anim = fyne.NewAnimation(smoothScrollDuration, func(done float32) {
+		b.setScrollOffset(fyne.NewPos(from.X+(offset.X-from.X)*done, from.Y+(offset.Y-from.Y)*done))
+		if done == 1 && b.smoothScroll.anim == anim {
+			b.smoothScroll.anim = nil
+		}
+	})
+	anim.Curve = fyne.AnimationEaseOut
+	b.smoothScroll = smoothScroll{anim: anim, target: offset}
+	anim.Start()
+}
+
+// scrollPageBy scrolls the page by (dx, dy) from where it is heading.
+func (b *Browser) scrollPageBy(dx, dy float32, smooth bool) {
+	if b.contentScroll == nil {
+		return
+	}
+	target := b.scrollTarget()
+	b.scrollPageTo(fyne.NewPos(target.X+dx, target.Y+dy), smooth)
+}
+
+// setScrollOffset moves the page to offset at once.
+func (b *Browser) setScrollOffset(offset fyne.Position) {
+	if b.contentScroll == nil || b.contentScroll.Offset == offset {
+		return
+	}
+	b.contentScroll.ScrollToOffset(offset)
+	b.pageScrolled()
+}
+
+// pageScrolled shows the page as scrolled: the tiles now in view, the
+// scrollbar's thumb, the holes fixed backgrounds show through, and a
+// scroll event at the next frame.
+func (b *Browser) pageScrolled() {
+	b.showTiles()
+	if b.scrollbar != nil {
+		b.scrollbar.Refresh()
+	}
+	b.placeFixedBackgrounds()
+	if b.onJSScroll != nil {
+		b.scrollPending.Store(true)
+		b.frames.request(false)
+	}
+}
+
+// scrollPageByKey scrolls the page for a key pressed while no control has
+// focus.
+func (b *Browser) scrollPageByKey(key fyne.KeyName) {
+	if b.contentScroll == nil {
+		return
+	}
+	target := b.scrollTarget()
+	view := b.contentScroll.Size().Height
+	if y, ok := keyScrollTarget(key, target.Y, view, b.contentScroll.Content.MinSize().Height); ok {
+		b.scrollPageTo(fyne.NewPos(target.X, y), true)
+	}
+}
+
+// keyScrollTarget returns the offset key scrolls to from offset y, on a
+// page of height page seen through a viewport of height view. ok is false
+// for keys that do not scroll.
+func keyScrollTarget(key fyne.KeyName, y, view, page float32) (target float32, ok bool) {
+	switch key {
+	case fyne.KeyDown:
+		return y + keyScrollStep, true
+	case fyne.KeyUp:
+		return y - keyScrollStep, true
+	case fyne.KeySpace, fyne.KeyPageDown:
+		return y + view*pageScrollFraction, true
+	case fyne.KeyPageUp:
+		return y - view*pageScrollFraction, true
+	case fyne.KeyHome:
+		return 0, true
+	case fyne.KeyEnd:
+		return max(page-view, 0), true
+	}
+	return y, false
+}
@@ -0,0 +1,93 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyScrollTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    fyne.KeyName
+		want   float32
+		scroll bool
+	}{
+		{"space scrolls most of a page", fyne.KeySpace, 100 + 350, true},
+		{"page down", fyne.KeyPageDown, 100 + 350, true},
+		{"page up", fyne.KeyPageUp, 100 - 350, true},
+		{"arrow down", fyne.KeyDown, 140, true},
+		{"arrow up", fyne.KeyUp, 60, true},
+		{"home", fyne.KeyHome, 0, true},
+		{"end", fyne.KeyEnd, 2000 - 400, true},
+		{"letters do not scroll", fyne.KeyA, 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := keyScrollTarget(tt.key, 100, 400, 2000)
+			assert.Equal(t, tt.scroll, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScrollbarThumb(t *testing.T) {
+	pos, length := scrollbarThumb(200, 400, 1600, 0)
+	assert.Equal(t, float32(0), pos)
+	assert.Equal(t, float32(50), length, "a quarter of the page is in view")
+
+	pos, _ = scrollbarThumb(200, 400, 1600, 1200)
+	assert.Equal(t, float32(150), pos, "at the bottom")
+	assert.Equal(t, float32(600), scrollbarOffset(200, 400, 1600, 75))
+
+	_, length = scrollbarThumb(200, 400, 400000, 0)
+	assert.Equal(t, float32(ScrollbarThumbMinHeight), length, "long pages keep a thumb to grab")
+
+	pos, length = scrollbarThumb(200, 400, 300, 0)
+	assert.Equal(t, float32(0), pos)
+	assert.Equal(t, float32(200), length, "a page that fits fills the track")
+	assert.Equal(t, float32(0), scrollbarOffset(200, 400, 300, 50))
+}
+
+func TestScrollPage(t *testing.T) {
+	test.NewApp()
+	saved := SmoothScrolling
+	SmoothScrolling = false
+	t.Cleanup(func() { SmoothScrolling = saved })
+
+	page := canvas.NewRectangle(color.White)
+	page.SetMinSize(fyne.NewSize(100, 1000))
+	b := &Browser{contentScroll: container.NewScroll(page)}
+	b.contentScroll.Resize(fyne.NewSize(100, 200))
+	bar := newPageScrollbar(b)
+	b.scrollbar = bar
+	bar.Resize(fyne.NewSize(ScrollbarWidth, 200))
+
+	b.scrollPageBy(0, 300, true)
+	assert.Equal(t, float32(300), b.contentScroll.Offset.Y)
+	b.scrollPageByKey(fyne.KeyEnd)
+	assert.Equal(t, float32(800), b.contentScroll.Offset.Y)
+	b.scrollPageBy(0, 500, true)
+	assert.Equal(t, float32(800), b.contentScroll.Offset.Y, "clamped to the page")
+
+	x, y := b.ScrollPosition()
+	assert.Equal(t, 0.0, x)
+	assert.Equal(t, 800.0, y)
+
+	// Dragging the thumb by its middle to the middle of the track
+	// scrolls halfway
+	track := 200 - 2*float32(scrollbarThumbPadding)
+	pos, length := scrollbarThumb(track, 200, 1000, 800)
+	drag := func(y float32) {
+		bar.Dragged(&fyne.DragEvent{PointEvent: fyne.PointEvent{Position: fyne.NewPos(6, scrollbarThumbPadding+y)}})
+	}
+	drag(pos + length/2)
+	drag(track / 2)
+	bar.DragEnd()
+	assert.InDelta(t, 400, b.contentScroll.Offset.Y, 1)
+}
@@ -4,8 +4,10 @@ import (
 	"browser/css"
 	"browser/dom"
 	"browser/layout"
+	"browser/utils"
 	"fmt"
 	"image/color"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -18,17 +20,9 @@ type LinkStyler struct {
 	ResolveURL func(href string) string
 }
 
-// Font sizes
-var (
-	SizeH1     float32 = 32
-	SizeH2     float32 = 24
-	SizeH3     float32 = 18
-	SizeH4     float32 = 16
-	SizeH5     float32 = 14
-	SizeH6     float32 = 12
-	SizeNormal float32 = 16
-	SizeSmall  float32 = 12
-)
+// SizeNormal is the font size of text outside any styled element; headings
+// and <small> get theirs from the user-agent styles (css.applyUserAgentDefaults).
+var SizeNormal float32 = 16
 
 // Text decoration constants
 const (
@@ -43,7 +37,7 @@ const (
 type TextStyle struct {
 	Color          color.Color
 	Size           float32
-	Bold           bool
+	Weight         int // numeric font-weight; 0 is normal
 	Italic         bool
 	Monospace      bool
 	FontFamily     []string
@@ -51,10 +45,10 @@ type TextStyle struct {
 	TextTransform  string
 	FontVariant    string
 
-	Opacity       float64
 	Visibility    string
 	LetterSpacing float64
 	WordSpacing   float64
+	TextShadows   []css.TextShadow
 	TextOverflow  string
 	OverflowX     string
 	OverflowY     string
@@ -66,21 +60,38 @@ type TextStyle struct {
 	LineHeight     float64
 	ScrollOffsetX  float64 // Horizontal scroll offset applied to children
 	ScrollOffsetY  float64 // Vertical scroll offset applied to children
+
+	// Stacking (see stacking.go): the enclosing stacking context, and the
+	// list collecting positioned descendants of the nearest positioned box
+	stacking   *stackingContext
+	positioned *[]stackedBox
+}
+
+// textTop returns where glyphs start in a line box at lineTop: the half
+// leading is added above the font's content area (CSS2 §10.8.1).
+func (ts TextStyle) textTop(lineTop, lineHeight float64) float64 {
+	if lineHeight <= 0 {
+		return lineTop
+	}
+	return lineTop + layout.HalfLeading(float64(ts.Size), lineHeight)
 }
 
 func (ts TextStyle) newDrawText(text string, x, y, width float64) DrawText {
 	return DrawText{
-		Text:            text,
+		// Layout turned the soft hyphens lines break at into hyphens; the
+		// rest stay invisible
+		Text:            strings.ReplaceAll(text, "\u00ad", ""),
 		X:               x,
 		Y:               y,
 		Width:           width,
 		LetterSpacing:   ts.LetterSpacing,
 		WordSpacing:     ts.WordSpacing,
 		Size:            ts.Size,
-		Color:           applyOpacity(ts.Color, ts.Opacity),
-		Bold:            ts.Bold,
+		Color:           ts.Color,
+		Weight:          ts.Weight,
 		Italic:          ts.Italic,
-		Monospace:       ts.Monospace || fontStackHasMonospace(ts.FontFamily),
+		Monospace:       ts.Monospace || css.IsMonospaceFamily(ts.FontFamily),
+		FontFamily:      ts.FontFamily,
 		Underline:       ts.TextDecoration == TextDecorationUnderline,
 		DottedUnderline: ts.TextDecoration == TextDecorationDottedUnderline,
 		Strikethrough:   ts.TextDecoration == TextDecorationLineThrough,
@@ -89,14 +100,34 @@ func (ts TextStyle) newDrawText(text string, x, y, width float64) DrawText {
 		TextOverflow:    ts.TextOverflow,
 		OverflowX:       ts.OverflowX,
 		OverflowY:       ts.OverflowY,
+		Shadows:         ts.resolvedShadows(),
+	}
+}
+
+// resolvedShadows returns the text shadows with currentColor replaced by
+// the text color.
+func (ts TextStyle) resolvedShadows() []css.TextShadow {
+	if len(ts.TextShadows) == 0 {
+		return nil
+	}
+	shadows := make([]css.TextShadow, len(ts.TextShadows))
+	for i, shadow := range ts.TextShadows {
+		if shadow.Color == nil {
+			shadow.Color = ts.Color
+		}
+		shadows[i] = shadow
 	}
+	return shadows
 }
 
 type DrawInput struct {
 	layout.Rect
 	Placeholder string
 	Value       string
-	InputType   string // text, password, email, number, etc.
+	Suggestions []string // autofill entries listed below the field
+	InputType   string   // text, password, email, number, etc.
+	Edit        FieldEdit
+	ShowCaret   bool
 	IsFocused   bool
 	IsDisabled  bool
 	IsReadonly  bool
@@ -113,6 +144,8 @@ type DrawTextarea struct {
 	layout.Rect
 	Placeholder string
 	Value       string
+	Edit        FieldEdit
+	ShowCaret   bool
 	IsFocused   bool
 	IsDisabled  bool
 	IsReadonly  bool
@@ -149,50 +182,36 @@ type InputState struct {
 	CheckboxValues  map[*dom.Node]bool    // Checked state per check
 	FileInputValues map[*dom.Node]string  // Selected filename per file input
 	InvalidNodes    map[*dom.Node]bool    // Nodes with invalid input
+	Suggestions     []string              // Autofill suggestions for FocusedNode
 	ScrollOffsets   map[*dom.Node]float64 // Horizontal scroll offset per overflow container
 	ScrollOffsetsY  map[*dom.Node]float64 // Vertical scroll offset per overflow container
 
-	SelectionStart *SelectionPoint
-	SelectionEnd   *SelectionPoint
-}
-
-// isTextSelected checks if a text box is within the current selection range
-func isTextSelected(box *layout.LayoutBox, state InputState) bool {
-	if state.SelectionStart == nil || state.SelectionEnd == nil {
-		return false
-	}
-
-	// Calculate selection bounds (handle reverse selection - dragging up)
-	minY := state.SelectionStart.Y
-	maxY := state.SelectionEnd.Y
-	if minY > maxY {
-		minY, maxY = maxY, minY
-	}
+	Selection map[*dom.Node][]TextRange // Selected part of each text box's lines (see selection.go)
 
-	minX := state.SelectionStart.X
-	maxX := state.SelectionEnd.X
-	if minX > maxX {
-		minX, maxX = maxX, minX
-	}
+	Edit        FieldEdit // Caret and selection in FocusedNode (see textedit.go)
+	CaretHidden bool      // The caret is between blinks
 
-	boxTop := box.Rect.Y
-	boxBottom := box.Rect.Y + box.Rect.Height
-	boxLeft := box.Rect.X
-	boxRight := box.Rect.X + box.Rect.Width
+	Viewport layout.Rect // The viewport's size, where fixed backgrounds are positioned
 
-	// Check if box overlaps selection area
-	verticalOverlap := boxBottom >= minY && boxTop <= maxY
-	horizontalOverlap := boxRight >= minX && boxLeft <= maxX
+	fixedBackgrounds *fixedBackgroundBoxes // found by BuildDisplayLists (see fixedbackground.go)
+}
 
-	// For single-line selection, require both overlaps
-	// For multi-line, just vertical overlap is enough for middle lines
-	if maxY-minY < box.Rect.Height {
-		// Single line selection - need both overlaps
-		return verticalOverlap && horizontalOverlap
+// editFor returns the caret and selection of node, the caret after the
+// value when node is not being edited.
+func (state InputState) editFor(node *dom.Node) FieldEdit {
+	if node == nil || node != state.FocusedNode {
+		n := len(state.InputValues[node])
+		return FieldEdit{Caret: n, Anchor: n}
 	}
+	return state.Edit
+}
 
-	// Multi-line selection
-	return verticalOverlap
+// suggestionsFor returns the autofill suggestions to list below node.
+func (state InputState) suggestionsFor(node *dom.Node) []string {
+	if node == nil || node != state.FocusedNode {
+		return nil
+	}
+	return state.Suggestions
 }
 
 // DefaultStyle returns the default text style
@@ -200,21 +219,10 @@ func DefaultStyle() TextStyle {
 	return TextStyle{
 		Color:      ColorBlack,
 		Size:       SizeNormal,
-		Bold:       false,
+		Weight:     css.FontWeightNormal,
 		Italic:     false,
-		Opacity:    1.0,
-		LineHeight: float64(SizeNormal) * 1.2,
-	}
-}
-
-// applyOpacity returns a color with opacity applied to alpha channel
-func applyOpacity(c color.Color, opacity float64) color.Color {
-	if opacity >= 1.0 {
-		return c
+		LineHeight: layout.NormalLineHeight(float64(SizeNormal)),
 	}
-	r, g, b, a := c.RGBA()
-	newAlpha := uint8(float64(a>>8) * opacity)
-	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), newAlpha}
 }
 
 type DisplayCommand any
@@ -237,9 +245,10 @@ type DrawText struct {
 	WordSpacing     float64
 	Color           color.Color
 	Size            float32
-	Bold            bool
+	Weight          int // numeric font-weight, matched to the nearest face
 	Italic          bool
 	Monospace       bool
+	FontFamily      []string // CSS font-family list, resolved to a system font at render time
 	Underline       bool
 	DottedUnderline bool
 	Strikethrough   bool
@@ -248,16 +257,25 @@ type DrawText struct {
 	TextOverflow    string
 	OverflowX       string
 	OverflowY       string
-	ClipLeftOffset  float64 // Amount to trim from the left side of text
+	ClipLeftOffset  float64          // Amount to trim from the left side of text
+	Shadows         []css.TextShadow // text-shadow, topmost first, colors resolved
 }
 
 type DrawImage struct {
 	layout.Rect
 	URL            string
 	AltText        string
+	AltSize        float32 // the font size of AltText, shown if the image is broken
 	ReferrerPolicy string
 	Node           *dom.Node
 	SizeMode       string
+
+	// Background images tile across Rect (CSS Backgrounds §3.4-3.9), or
+	// across Area for those positioned elsewhere, showing only within Rect
+	Background bool
+	Position   string
+	Repeat     string
+	Area       layout.Rect
 }
 
 type DrawHR struct {
@@ -294,6 +312,14 @@ func BuildDisplayList(root *layout.LayoutBox, state InputState, linkStyler LinkS
 }
 
 func BuildDisplayLayers(root *layout.LayoutBox, state InputState, linkStyler LinkStyler) ([]DisplayCommand, []DisplayCommand) {
+	normal, fixed := BuildDisplayLists(root, state, linkStyler)
+	return normal.Commands, fixed.Commands
+}
+
+// BuildDisplayLists builds the display layers of BuildDisplayLayers with
+// the ID of the box each command paints, for DiffDisplayLists.
+func BuildDisplayLists(root *layout.LayoutBox, state InputState, linkStyler LinkStyler) (DisplayList, DisplayList) {
+	start := utils.Profile.Start()
 	var normalCommands []DisplayCommand
 	var fixedCommands []DisplayCommand
 	var commands []DisplayCommand
@@ -304,16 +330,62 @@ func BuildDisplayLayers(root *layout.LayoutBox, state InputState, linkStyler Lin
 		contentHeight = 600 // Minimum height
 	}
 
-	commands = append(commands, DrawRect{
+	state.fixedBackgrounds = findFixedBackgrounds(root, state)
+	var canvasHoles []layout.Rect
+	if state.fixedBackgrounds != nil {
+		canvasHoles = state.fixedBackgrounds.canvas
+		fixedCommands = append(fixedCommands, state.fixedBackgrounds.commands...)
+	}
+	commands = append(commands, cutHoles(canvasHoles, []DisplayCommand{DrawRect{
 		Rect:  layout.Rect{X: 0, Y: 0, Width: 3000, Height: contentHeight}, // Wide enough for most screens
 		Color: color.White,
-	})
+	}})...)
 
 	normalCommands = append(normalCommands, commands...)
 	paintLayoutBox(root, &normalCommands, DefaultStyle(), state, linkStyler, paintNormalOnly, false)
 	paintLayoutBox(root, &fixedCommands, DefaultStyle(), state, linkStyler, paintFixedOnly, false)
 
-	return normalCommands, fixedCommands
+	// Modal dialogs sit in the top layer, above every fixed box, each over
+	// a backdrop dimming the page beneath. The fixed layer is drawn in
+	// viewport coordinates; printed pages have no viewport and are covered
+	// whole.
+	backdrop := layout.Rect{Width: state.Viewport.Width, Height: state.Viewport.Height}
+	if backdrop.Width == 0 || backdrop.Height == 0 {
+		backdrop = layout.Rect{Width: root.Rect.X + root.Rect.Width, Height: contentHeight}
+	}
+	for _, dialog := range modalDialogBoxes(root) {
+		fixedCommands = append(fixedCommands, DrawRect{
+			Rect:  backdrop,
+			Color: dialogBackdropColor,
+		})
+		paintLayoutBox(dialog, &fixedCommands, DefaultStyle(), state, linkStyler, paintAll, true)
+	}
+
+	normal, fixed := newDisplayList(normalCommands), newDisplayList(fixedCommands)
+	utils.Profile.Record(utils.PhaseDisplayList, start, len(normal.Commands)+len(fixed.Commands))
+	return normal, fixed
+}
+
+// dialogBackdropColor dims the page behind a modal dialog.
+var dialogBackdropColor = color.RGBA{A: 0x1a}
+
+// isModalDialog reports whether box is an open <dialog> shown with
+// showModal, painted in the top layer rather than in tree order.
+func isModalDialog(box *layout.LayoutBox) bool {
+	return box.Node != nil && box.Node.TagName == dom.TagDialog && box.Node.Modal && box.Position == "fixed"
+}
+
+// modalDialogBoxes returns the modal dialog boxes under box in tree order,
+// the last one on top.
+func modalDialogBoxes(box *layout.LayoutBox) []*layout.LayoutBox {
+	if isModalDialog(box) {
+		return []*layout.LayoutBox{box}
+	}
+	var dialogs []*layout.LayoutBox
+	for _, child := range box.Children {
+		dialogs = append(dialogs, modalDialogBoxes(child)...)
+	}
+	return dialogs
 }
 
 // scrolledRect returns a copy of the box rect with ScrollOffsetX applied.
@@ -343,6 +415,10 @@ func isFirstTextChild(box *layout.LayoutBox) bool {
 	if box.Parent == nil {
 		return false
 	}
+	// An anonymous block after the first line's block starts a later line
+	if anon := box.Parent; anon.Node == nil && anon.Parent != nil && anon.Parent.Children[0] != anon {
+		return false
+	}
 	for _, child := range box.Parent.Children {
 		if child.Type == layout.TextBox || child.Type == layout.InlineBox {
 			return child == box
@@ -363,8 +439,8 @@ func applyFirstLineStyle(base TextStyle, fls *css.Style) TextStyle {
 	if fls.FontSize > 0 {
 		s.Size = float32(fls.FontSize)
 	}
-	if fls.Bold {
-		s.Bold = true
+	if fls.FontWeight > 0 {
+		s.Weight = fls.FontWeight
 	}
 	if fls.Italic {
 		s.Italic = true
@@ -387,6 +463,9 @@ func applyFirstLineStyle(base TextStyle, fls *css.Style) TextStyle {
 	if fls.WordSpacingSet {
 		s.WordSpacing = fls.WordSpacing
 	}
+	if fls.TextShadowSet {
+		s.TextShadows = fls.TextShadows
+	}
 	if fls.LineHeight > 0 {
 		s.LineHeight = fls.LineHeight
 	}
@@ -400,6 +479,9 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	if layer == paintNormalOnly && isFixed {
 		return
 	}
+	if layer == paintFixedOnly && isModalDialog(box) {
+		return // painted last by BuildDisplayLists
+	}
 	if layer == paintFixedOnly && !isFixed {
 		// Skip drawing this non-fixed box, but still traverse children to find fixed descendants.
 		if box.Type != layout.ButtonBox && box.Type != layout.SelectBox {
@@ -413,6 +495,15 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 		return
 	}
 
+	*commands = append(*commands, boxStart{box})
+	defer func() { *commands = append(*commands, boxEnd{}) }()
+
+	// The filter applies to everything the box paints, descendants included
+	if filters := groupFilters(box.Style); len(filters) > 0 {
+		*commands = append(*commands, PushFilter{Filters: filters})
+		defer func() { *commands = append(*commands, PopFilter{}) }()
+	}
+
 	// Apply inline styles from CSS
 	if box.Style.Color != nil {
 		currentStyle.Color = box.Style.Color
@@ -420,11 +511,11 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	if box.Style.FontSize > 0 {
 		currentStyle.Size = float32(box.Style.FontSize)
 		if box.Style.LineHeight == 0 {
-			currentStyle.LineHeight = box.Style.FontSize * 1.2
+			currentStyle.LineHeight = layout.NormalLineHeight(box.Style.FontSize)
 		}
 	}
-	if box.Style.Bold {
-		currentStyle.Bold = true
+	if box.Style.FontWeight > 0 {
+		currentStyle.Weight = box.Style.FontWeight
 	}
 	if box.Style.Italic {
 		currentStyle.Italic = true
@@ -449,14 +540,14 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	if box.Style.WordSpacingSet {
 		currentStyle.WordSpacing = box.Style.WordSpacing
 	}
+	if box.Style.TextShadowSet {
+		currentStyle.TextShadows = box.Style.TextShadows
+	}
 
 	if box.Style.LineHeight > 0 {
 		currentStyle.LineHeight = box.Style.LineHeight
 	}
 
-	if box.Style.Opacity > 0 {
-		currentStyle.Opacity = box.Style.Opacity
-	}
 	if box.Style.Visibility != "" {
 		currentStyle.Visibility = box.Style.Visibility
 	}
@@ -508,121 +599,64 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	boxRect = scrolledRectY(boxRect, currentStyle.ScrollOffsetY)
 
 
-	// Draw background if set
-	if box.Style.BackgroundColor != nil && !isHidden {
-		tl := box.Style.BorderTopLeftRadius
-		tr := box.Style.BorderTopRightRadius
-		br := box.Style.BorderBottomRightRadius
-		bl := box.Style.BorderBottomLeftRadius
-		if tl == 0 && tr == 0 && br == 0 && bl == 0 {
-			tl = box.Style.BorderRadius
-			tr = box.Style.BorderRadius
-			br = box.Style.BorderRadius
-			bl = box.Style.BorderRadius
+	// Draw background if set. One with a fixed layer on the page is drawn
+	// beneath it, and the boxes around it leave a hole for it to show
+	// through (see fixedbackground.go).
+	if !isHidden && !state.fixedBackgrounds.isBeneath(box) {
+		var background []DisplayCommand
+		if box.Style.BackgroundColor != nil {
+			background = append(background, backgroundRect(box, boxRect, box.Style.BackgroundColor))
 		}
-		*commands = append(*commands, DrawRect{
-			Rect:              boxRect,
-			Color:             applyOpacity(box.Style.BackgroundColor, currentStyle.Opacity),
-			CornerRadius:      box.Style.BorderRadius,
-			TopLeftRadius:     tl,
-			TopRightRadius:    tr,
-			BottomRightRadius: br,
-			BottomLeftRadius:  bl,
-		})
-	}
-
-	if box.Style.BackgroundImage != "" && !isHidden {
-		*commands = append(*commands, DrawImage{
-			Rect:     boxRect,
-			URL:      box.Style.BackgroundImage,
-			SizeMode: box.Style.BackgroundSize,
-		})
+		if layers := box.Style.BackgroundLayers(); len(layers) > 0 {
+			background = append(background, backgroundClip(box, boxRect))
+			// Layers are listed topmost first; paint from the bottom up
+			for _, layer := range slices.Backward(layers) {
+				background = append(background, DrawImage{
+					Rect:       boxRect,
+					URL:        layer.Image,
+					SizeMode:   layer.Size,
+					Background: true,
+					Position:   layer.Position,
+					Repeat:     layer.Repeat,
+					Area:       backgroundArea(layer, state.Viewport),
+				})
+			}
+			background = append(background, PopClip{})
+		}
+		*commands = append(*commands, cutHoles(state.fixedBackgrounds.holesIn(box), background)...)
 	}
 
 	// Draw borders if set
 	if !isHidden {
-		if box.Style.BorderTopWidth > 0 && box.Style.BorderTopStyle != "none" && box.Style.BorderTopColor != nil {
-			*commands = append(*commands, DrawRect{
-				Rect:  layout.Rect{X: boxRect.X, Y: boxRect.Y, Width: boxRect.Width, Height: box.Style.BorderTopWidth},
-				Color: applyOpacity(box.Style.BorderTopColor, currentStyle.Opacity),
-			})
+		// Rounded borders follow the corner curves on both sides
+		edges := borderEdges(box, boxRect)
+		var clips []DisplayCommand
+		if slices.ContainsFunc(edges, borderEdge.visible) {
+			clips = borderClips(box, boxRect)
 		}
-		if box.Style.BorderBottomWidth > 0 && box.Style.BorderBottomStyle != "none" && box.Style.BorderBottomColor != nil {
-			*commands = append(*commands, DrawRect{
-				Rect:  layout.Rect{X: boxRect.X, Y: boxRect.Y + boxRect.Height - box.Style.BorderBottomWidth, Width: boxRect.Width, Height: box.Style.BorderBottomWidth},
-				Color: applyOpacity(box.Style.BorderBottomColor, currentStyle.Opacity),
-			})
+		if len(clips) > 0 {
+			tl, tr, br, bl := borderRadii(box)
+			edges = roundedEdges(edges, boxRect, [4]float64{tl, tr, br, bl})
 		}
-		if box.Style.BorderLeftWidth > 0 && box.Style.BorderLeftStyle != "none" && box.Style.BorderLeftColor != nil {
-			*commands = append(*commands, DrawRect{
-				Rect:  layout.Rect{X: boxRect.X, Y: boxRect.Y, Width: box.Style.BorderLeftWidth, Height: boxRect.Height},
-				Color: applyOpacity(box.Style.BorderLeftColor, currentStyle.Opacity),
-			})
-		}
-		if box.Style.BorderRightWidth > 0 && box.Style.BorderRightStyle != "none" && box.Style.BorderRightColor != nil {
-			*commands = append(*commands, DrawRect{
-				Rect:  layout.Rect{X: boxRect.X + boxRect.Width - box.Style.BorderRightWidth, Y: boxRect.Y, Width: box.Style.BorderRightWidth, Height: boxRect.Height},
-				Color: applyOpacity(box.Style.BorderRightColor, currentStyle.Opacity),
-			})
+		*commands = append(*commands, clips...)
+		paintEdges(edges, commands)
+		for range clips {
+			*commands = append(*commands, PopClip{})
 		}
+		// The outline takes no space; it is drawn around the border box
+		paintEdges(outlineEdges(box, boxRect, currentStyle.Color), commands)
 	}
 
 	// Apply tag-based styles
 	if box.Node != nil {
 		switch box.Node.TagName {
-		case dom.TagH1:
-			if box.Style.FontSize == 0 {
-				currentStyle.Size = SizeH1
-			}
-			if !box.Style.Bold {
-				currentStyle.Bold = true
-			}
-		case dom.TagH2:
-			if box.Style.FontSize == 0 {
-				currentStyle.Size = SizeH2
-			}
-			if !box.Style.Bold {
-				currentStyle.Bold = true
-			}
-		case dom.TagH3:
-			if box.Style.FontSize == 0 {
-				currentStyle.Size = SizeH3
-			}
-			if !box.Style.Bold {
-				currentStyle.Bold = true
-			}
-		case dom.TagH4:
-			if box.Style.FontSize == 0 {
-				currentStyle.Size = SizeH4
-			}
-			if !box.Style.Bold {
-				currentStyle.Bold = true
-			}
-		case dom.TagH5:
-			if box.Style.FontSize == 0 {
-				currentStyle.Size = SizeH5
-			}
-			if !box.Style.Bold {
-				currentStyle.Bold = true
-			}
-		case dom.TagH6:
-			if box.Style.FontSize == 0 {
-				currentStyle.Size = SizeH6
-			}
-			if !box.Style.Bold {
-				currentStyle.Bold = true
-			}
 		case dom.TagA:
 			// Link color and text-decoration are now handled via CSS cascade
 			// (UA defaults in applyUserAgentDefaults, overridable by user CSS rules)
-		case dom.TagStrong, dom.TagB:
-			currentStyle.Bold = true
 		case dom.TagEm, dom.TagI, dom.TagCite, dom.TagDnf:
 			currentStyle.Italic = true
 		case dom.TagAbbr:
 			currentStyle.TextDecoration = TextDecorationDottedUnderline
-		case dom.TagSmall:
-			currentStyle.Size = SizeSmall
 		case dom.TagU:
 			currentStyle.TextDecoration = TextDecorationUnderline
 		case dom.TagDel:
@@ -634,20 +668,12 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 		case dom.TagPre:
 			currentStyle.Monospace = true
 			if box.Style.BackgroundColor == nil && !isHidden {
-				*commands = append(*commands, DrawRect{
-					Rect:  boxRect,
-					Color: color.RGBA{245, 245, 245, 255},
-				})
+				*commands = append(*commands, backgroundRect(box, boxRect, color.RGBA{245, 245, 245, 255}))
 			}
-		case dom.TagTH:
-			currentStyle.Bold = true
 		case dom.TagMark:
 			currentStyle.Color = color.RGBA{0, 0, 0, 255}
 			if !isHidden {
-				*commands = append(*commands, DrawRect{
-					Rect:  boxRect,
-					Color: color.RGBA{255, 255, 0, 255},
-				})
+				*commands = append(*commands, backgroundRect(box, boxRect, color.RGBA{255, 255, 0, 255}))
 			}
 
 		}
@@ -655,13 +681,7 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 
 	// Draw text
 	if box.Type == layout.TextBox && box.Text != "" && !isHidden {
-		if isTextSelected(box, state) {
-			*commands = append(*commands, DrawRect{
-				Rect:  boxRect,
-				Color: color.RGBA{0, 120, 215, 128}, // Selection blue
-			})
-
-		}
+		*commands = append(*commands, selectionHighlights(box, boxRect, state.Selection[box.Node])...)
 
 		text := css.ApplyTextTransform(box.Text, currentStyle.TextTransform, currentStyle.FontVariant)
 
@@ -669,9 +689,12 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 			text = formatListMarker(index, listType) + " " + text
 		}
 
-		if currentStyle.Monospace && strings.Contains(text, "\n") {
+		if currentStyle.Monospace {
 			// Expand tabs to spaces for proper alignment
 			text = dom.ExpandTabs(text, 8)
+		}
+		if strings.Contains(text, "\n") {
+			// Line feeds white-space preserved break the text into lines
 			lines := strings.Split(text, "\n")
 			lineHeight := float64(currentStyle.Size) * 1.5
 			y := boxRect.Y
@@ -683,7 +706,7 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 				if currentStyle.ClipBottom > 0 && y >= currentStyle.ClipBottom {
 					break
 				}
-				*commands = append(*commands, currentStyle.newDrawText(line, boxRect.X, y, boxRect.Width))
+				*commands = append(*commands, currentStyle.newDrawText(line, boxRect.X, currentStyle.textTop(y, lineHeight), boxRect.Width))
 				y += lineHeight
 			}
 		} else if len(box.WrappedLines) > 1 {
@@ -715,7 +738,10 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 				if i == 0 {
 					x += box.TextIndentPx // offset first line for text-indent
 				}
-				dt := lineStyle.newDrawText(transformedLine, x, y, boxRect.Width)
+				if i < len(box.LineOffsets) {
+					x += box.LineOffsets[i] // lines beside a float start past it
+				}
+				dt := lineStyle.newDrawText(transformedLine, x, lineStyle.textTop(y, lineHeight), boxRect.Width)
 				if i < len(box.JustifyWordSpacings) {
 					dt.WordSpacing += box.JustifyWordSpacings[i]
 				}
@@ -760,7 +786,7 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 					})
 				}
 			}
-			dt := drawStyle.newDrawText(text, boxRect.X, boxRect.Y, drawWidth)
+			dt := drawStyle.newDrawText(text, boxRect.X, drawStyle.textTop(boxRect.Y, currentStyle.LineHeight), drawWidth)
 			if currentStyle.ClipLeft > 0 && boxRect.X < currentStyle.ClipLeft {
 				dt.ClipLeftOffset = currentStyle.ClipLeft - boxRect.X
 				dt.X = currentStyle.ClipLeft
@@ -776,13 +802,30 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	// Draw image
 	if box.Type == layout.ImageBox && box.Node != nil && !isHidden {
 		if src := box.Node.Attributes["src"]; src != "" {
+			// Replaced content is trimmed to the inner corner curves
+			clip := paddingClip(box, boxRect)
+			if clip.rounded() {
+				*commands = append(*commands, clip)
+			}
 			*commands = append(*commands, DrawImage{
 				Rect:           boxRect,
 				URL:            src,
 				AltText:        box.Node.Attributes["alt"],
+				AltSize:        currentStyle.Size,
 				ReferrerPolicy: box.Node.Attributes["referrerpolicy"],
 				Node:           box.Node,
 			})
+			if clip.rounded() {
+				*commands = append(*commands, PopClip{})
+			}
+		}
+	}
+
+	if box.Type == layout.MediaBox && box.Node != nil && !isHidden {
+		if box.Node.TagName == dom.TagSvg {
+			*commands = append(*commands, DrawSVG{Rect: boxRect, Markup: svgMarkup(box.Node), Color: currentStyle.Color})
+		} else {
+			paintMedia(box, boxRect, commands)
 		}
 	}
 
@@ -828,7 +871,10 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 			Rect:        boxRect,
 			Placeholder: placeholder,
 			Value:       value,
+			Suggestions: state.suggestionsFor(box.Node),
 			InputType:   inputType,
+			Edit:        state.editFor(box.Node),
+			ShowCaret:   isFocused && !state.CaretHidden,
 			IsFocused:   isFocused,
 			IsDisabled:  isDisabled,
 			IsReadonly:  isReadonly,
@@ -858,6 +904,8 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 			Rect:        boxRect,
 			Placeholder: box.Node.Attributes["placeholder"],
 			Value:       value,
+			Edit:        state.editFor(box.Node),
+			ShowCaret:   isFocused && !state.CaretHidden,
 			IsFocused:   isFocused,
 			IsDisabled:  isDisabled,
 			IsReadonly:  isReadonly,
@@ -874,7 +922,7 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 				for _, textNode := range child.Children {
 					fmt.Printf("    TextNode: Type=%d, Text=%q\n", textNode.Type, textNode.Text)
 					if textNode.Type == dom.Text {
-						options = append(options, textNode.Text)
+						options = append(options, dom.CollapseWhitespace(textNode.Text))
 						break
 					}
 				}
@@ -900,12 +948,10 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	// Radio button
 	if box.Type == layout.RadioBox && box.Node != nil && !isHidden {
 		name := box.Node.Attributes["name"]
-		isChecked := false
-		if name != "" && state.RadioValues != nil {
-			isChecked = (state.RadioValues[name] == box.Node)
-		}
+		selected, picked := state.RadioValues[name]
+		isChecked := selected == box.Node
 		// Fallback to HTML checked attribute if no runtime state
-		if !isChecked {
+		if !picked {
 			_, isChecked = box.Node.Attributes["checked"]
 		}
 
@@ -919,9 +965,10 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	}
 
 	if box.Type == layout.CheckboxBox && box.Node != nil && !isHidden {
-		isChecked := false
-		if state.CheckboxValues != nil {
-			isChecked = state.CheckboxValues[box.Node]
+		isChecked, toggled := state.CheckboxValues[box.Node]
+		// The HTML checked attribute until the user toggles it
+		if !toggled {
+			_, isChecked = box.Node.Attributes["checked"]
 		}
 
 		_, isDisabled := box.Node.Attributes["disabled"]
@@ -1000,9 +1047,34 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 	// Paint children with input state
 	// Skip children for elements that render their own content
 	if box.Type != layout.ButtonBox && box.Type != layout.SelectBox {
+		// Content overflowing a box with overflow other than visible is
+		// clipped to its padding box, rounded corners included
+		clips := clipsOverflow(box)
+		if clips {
+			*commands = append(*commands, overflowClip(box, boxRect, currentStyle))
+		}
+
 		childStyle := currentStyle
 		childStyle.ScrollOffsetX += scrollOffsetX
 		childStyle.ScrollOffsetY += scrollOffsetY
+
+		// The root and stacking context roots collect descendants with a
+		// z-index; they and positioned boxes paint positioned descendants
+		// after their normal flow (CSS 2.1 Appendix E)
+		isContext := currentStyle.stacking == nil || createsStackingContext(box)
+		if isContext {
+			childStyle.stacking = &stackingContext{}
+		}
+		var positioned []stackedBox
+		if isContext || isPositionedBox(box) {
+			childStyle.positioned = &positioned
+		}
+		flow := commands
+		var flowCommands []DisplayCommand
+		if isContext {
+			flow = &flowCommands
+		}
+
 		for _, child := range box.Children {
 			// Skip LegendBox - DrawFieldset already renders the legend text
 			if child.Type == layout.LegendBox {
@@ -1019,7 +1091,31 @@ func paintLayoutBox(box *layout.LayoutBox, commands *[]DisplayCommand, style Tex
 			if scrollOffsetY > 0 && child.Rect.Y+child.Rect.Height < box.Rect.Y+box.Style.BorderTopWidth+scrollOffsetY {
 				continue
 			}
-			paintLayoutBox(child, commands, childStyle, state, linkStyler, layer, isFixed)
+			if paintsAboveFlow(child) {
+				deferred := stackedBox{box: child, style: childStyle, isFixed: isFixed}
+				switch z := stackLevel(child); {
+				case z < 0:
+					childStyle.stacking.negative = append(childStyle.stacking.negative, deferred)
+				case z > 0:
+					childStyle.stacking.positive = append(childStyle.stacking.positive, deferred)
+				default:
+					*childStyle.positioned = append(*childStyle.positioned, deferred)
+				}
+				continue
+			}
+			paintLayoutBox(child, flow, childStyle, state, linkStyler, layer, isFixed)
+		}
+
+		for _, s := range positioned {
+			paintLayoutBox(s.box, flow, s.style, state, linkStyler, layer, s.isFixed)
+		}
+		if isContext {
+			paintStacked(childStyle.stacking.negative, commands, state, linkStyler, layer)
+			*commands = append(*commands, flowCommands...)
+			paintStacked(childStyle.stacking.positive, commands, state, linkStyler, layer)
+		}
+		if clips {
+			*commands = append(*commands, PopClip{})
 		}
 	}
 
@@ -1044,15 +1140,16 @@ func isListItemBox(box *layout.LayoutBox) bool {
 
 // getListInfo returns (isListItem, isOrdered, itemIndex, listType)
 func getListInfo(box *layout.LayoutBox) (bool, bool, int, string) {
-	if box.Parent == nil || box.Parent.Node == nil {
-		return false, false, 0, ""
+	li := box.Parent
+	// Text beside a nested list sits in an anonymous block; the marker goes
+	// on the first one
+	if li != nil && li.Node == nil && li.Parent != nil && len(li.Parent.Children) > 0 && li.Parent.Children[0] == li {
+		li = li.Parent
 	}
-	if !isListItemBox(box.Parent) {
+	if li == nil || !isListItemBox(li) {
 		return false, false, 0, ""
 	}
 
-	li := box.Parent
-
 	// Find list container (ul/ol/menu) — may be direct parent or grandparent
 	var listContainer *layout.LayoutBox
 	if li.Parent != nil && li.Parent.Node != nil {
@@ -1208,6 +1305,9 @@ func getButtonTextFromBox(box *layout.LayoutBox) string {
 		if val, ok := box.Node.Attributes["value"]; ok {
 			return val
 		}
+		if label := layout.DefaultButtonLabels[dom.ButtonType(box.Node)]; label != "" && box.Node.TagName == dom.TagInput {
+			return label
+		}
 	}
 	return "Button"
 }
@@ -1270,18 +1370,6 @@ func computeClip(overflow string, boxType layout.BoxType, pos, size, padding, bo
 	return currentClip
 }
 
-// fontStackHasMonospace checks if any font in the stack is a monospace font
-func fontStackHasMonospace(fonts []string) bool {
-	for _, font := range fonts {
-		f := strings.ToLower(font)
-		if f == "monospace" || f == "courier" || f == "courier new" ||
-			f == "consolas" || f == "monaco" || f == "menlo" {
-			return true
-		}
-	}
-	return false
-}
-
 // Scrollbar constants
 const (
 	ScrollbarHeight         = layout.ScrollbarHeight
@@ -1381,7 +1469,7 @@ func drawHorizontalScrollbar(box *layout.LayoutBox, commands *[]DisplayCommand,
 		if scrollOffset, ok := state.ScrollOffsets[box.Node]; ok && scrollOffset > 0 {
 			maxScroll := contentWidth - visibleWidth
 			if maxScroll > 0 {
-				scrollRatio := scrollOffset / maxScroll
+				scrollRatio := min(scrollOffset/maxScroll, 1)
 				scrollableTrack := trackW - 2*scrollbarThumbPadding - thumbWidth
 				thumbX += scrollRatio * scrollableTrack
 			}
@@ -1500,7 +1588,7 @@ func drawVerticalScrollbar(box *layout.LayoutBox, commands *[]DisplayCommand, st
 		if scrollOffset, ok := state.ScrollOffsetsY[box.Node]; ok && scrollOffset > 0 {
 			maxScroll := contentHeight - visibleHeight
 			if maxScroll > 0 {
-				scrollRatio := scrollOffset / maxScroll
+				scrollRatio := min(scrollOffset/maxScroll, 1)
 				scrollableTrack := trackH - 2*scrollbarThumbPadding - thumbHeight
 				thumbY += scrollRatio * scrollableTrack
 			}
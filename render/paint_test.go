@@ -1172,3 +1172,60 @@ func TestFirstLineSingleLineText(t *testing.T) {
 	}
 	assert.True(t, found, "expected DrawText for 'Short text'")
 }
+
+func TestBuildDisplayListSuggestions(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<div><input type="email" name="a"><input type="text" name="b"></div>`))
+	var inputs []*dom.Node
+	var walk func(n *dom.Node)
+	walk = func(n *dom.Node) {
+		if n.TagName == "input" {
+			inputs = append(inputs, n)
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(doc)
+	layoutRoot := layout.BuildLayoutTree(doc, css.Stylesheet{}, layout.Viewport{Width: 800, Height: 600}, css.MatchContext{})
+	layout.ComputeLayout(layoutRoot, 800)
+
+	tests := []struct {
+		name     string
+		state    InputState
+		expected [][]string // suggestions per input, in document order
+	}{
+		{
+			name:     "listed under the focused field only",
+			state:    InputState{FocusedNode: inputs[0], Suggestions: []string{"ada@example.com"}},
+			expected: [][]string{{"ada@example.com"}, nil},
+		},
+		{
+			name:     "follow focus to the next field",
+			state:    InputState{FocusedNode: inputs[1], Suggestions: []string{"Ada"}},
+			expected: [][]string{nil, {"Ada"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, cmd := range BuildDisplayList(layoutRoot, tt.state, LinkStyler{}) {
+				if in, ok := cmd.(DrawInput); ok {
+					got = append(got, in.Suggestions)
+				}
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestModalDialogBackdropCoversViewport(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<p style="height: 3000px">Page</p><dialog>Hello</dialog>`))
+	dom.ShowDialog(dom.FindElementsByTagName(doc, dom.TagDialog), true)
+	root := layout.BuildLayoutTree(doc, css.Stylesheet{}, layout.Viewport{Width: 1920, Height: 1080}, css.MatchContext{})
+	layout.ComputeLayout(root, 1920)
+
+	_, fixed := BuildDisplayLists(root, InputState{Viewport: layout.Rect{Y: 2500, Width: 1920, Height: 1080}}, LinkStyler{})
+	assert.Equal(t, []DrawRect{{Rect: layout.Rect{Width: 1920, Height: 1080}, Color: dialogBackdropColor}},
+		findRectsByColor(fixed.Commands, dialogBackdropColor), "the fixed layer is in viewport coordinates")
+}
@@ -0,0 +1,436 @@
+// Package pdf writes PDF 1.4 documents (ISO 32000-1) made of filled
+// shapes, text in the standard 14 fonts and raster images. It is the
+// backend print output is drawn with.
+//
+// Coordinates are PostScript points with the origin at the top-left corner
+// of the page and y growing downward, as on screen; the page's content
+// stream flips them into PDF's bottom-up space.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Font is one of the standard 14 fonts every PDF reader provides, so
+// nothing has to be embedded. Each family lists its regular, bold, italic
+// and bold italic faces in that order, so Bold and Italic can be added to
+// a family's regular face.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+	HelveticaOblique
+	HelveticaBoldOblique
+	TimesRoman
+	TimesBold
+	TimesItalic
+	TimesBoldItalic
+	Courier
+	CourierBold
+	CourierOblique
+	CourierBoldOblique
+)
+
+// Offsets from a family's regular face to its other faces.
+const (
+	Bold   Font = 1
+	Italic Font = 2
+)
+
+var fontNames = [...]string{
+	"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique",
+	"Times-Roman", "Times-Bold", "Times-Italic", "Times-BoldItalic",
+	"Courier", "Courier-Bold", "Courier-Oblique", "Courier-BoldOblique",
+}
+
+// Radii are the corner radii of a rounded rectangle: top-left, top-right,
+// bottom-right and bottom-left.
+type Radii [4]float64
+
+// kappa places the control points of a cubic Bézier approximating a
+// quarter circle.
+const kappa = 0.5523
+
+// Document is a PDF being built page by page.
+type Document struct {
+	pages  []*Page
+	images []image.Image
+	alphas []float64 // opacities, each an ExtGState
+}
+
+// New returns an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// Page is one page of a Document. Drawing appends to its content stream.
+type Page struct {
+	doc           *Document
+	Width, Height float64
+	content       bytes.Buffer
+	fonts         map[Font]bool
+	images        map[int]bool
+	alphas        map[int]bool
+	clips         int
+}
+
+// AddPage appends a page of the given size in points.
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{doc: d, Width: width, Height: height, fonts: map[Font]bool{}, images: map[int]bool{}, alphas: map[int]bool{}}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages returns the number of pages added so far.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// FillRect fills a rectangle, rounded by radii, with c.
+func (p *Page) FillRect(x, y, w, h float64, radii Radii, c color.Color) {
+	if w <= 0 || h <= 0 || !p.setFill(c) {
+		return
+	}
+	p.path(x, y, w, h, radii)
+	p.content.WriteString("f\nQ\n")
+}
+
+// StrokeRect outlines a rectangle, rounded by radii, with a line width
+// wide centred on its edges.
+func (p *Page) StrokeRect(x, y, w, h float64, radii Radii, width float64, c color.Color) {
+	if w <= 0 || h <= 0 || width <= 0 || !p.setColor(c, "RG") {
+		return
+	}
+	fmt.Fprintf(&p.content, "%s w ", num(width))
+	p.path(x, y, w, h, radii)
+	p.content.WriteString("S\nQ\n")
+}
+
+// Line draws a straight line width wide from (x1, y1) to (x2, y2).
+func (p *Page) Line(x1, y1, x2, y2, width float64, c color.Color) {
+	if width <= 0 || !p.setColor(c, "RG") {
+		return
+	}
+	fmt.Fprintf(&p.content, "%s w ", num(width))
+	p.moveTo(x1, y1)
+	p.lineTo(x2, y2)
+	p.content.WriteString("S\nQ\n")
+}
+
+// Text draws s with its baseline starting at (x, y). Characters outside
+// the fonts' WinAnsi encoding show as '?'.
+func (p *Page) Text(x, y float64, s string, font Font, size float64, c color.Color) {
+	if s == "" || !p.setFill(c) {
+		return
+	}
+	p.fonts[font] = true
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\nQ\n",
+		font, num(size), num(x), num(p.Height-y), escape(s))
+}
+
+// Image draws img stretched over the rectangle. An image drawn more than
+// once is stored once.
+func (p *Page) Image(img image.Image, x, y, w, h float64) {
+	if w <= 0 || h <= 0 || img.Bounds().Empty() {
+		return
+	}
+	id := -1
+	// Comparing interfaces panics on images of uncomparable types
+	if reflect.TypeOf(img).Comparable() {
+		for i, stored := range p.doc.images {
+			if stored == img {
+				id = i
+			}
+		}
+	}
+	if id < 0 {
+		id = len(p.doc.images)
+		p.doc.images = append(p.doc.images, img)
+	}
+	p.images[id] = true
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(w), num(h), num(x), num(p.Height-y-h), id)
+}
+
+// PushClip restricts what is drawn next to a rectangle rounded by radii,
+// until the matching PopClip.
+func (p *Page) PushClip(x, y, w, h float64, radii Radii) {
+	p.content.WriteString("q\n")
+	p.path(x, y, w, h, radii)
+	p.content.WriteString("W n\n")
+	p.clips++
+}
+
+// PopClip ends the most recent PushClip.
+func (p *Page) PopClip() {
+	if p.clips > 0 {
+		p.content.WriteString("Q\n")
+		p.clips--
+	}
+}
+
+// setFill saves the graphics state and sets the fill color and opacity of
+// the next operator, which restores the state after it. It reports false
+// for colors that paint nothing.
+func (p *Page) setFill(c color.Color) bool {
+	return p.setColor(c, "rg")
+}
+
+// setColor is setFill for the fill ("rg") or stroke ("RG") color.
+func (p *Page) setColor(c color.Color, operator string) bool {
+	if c == nil {
+		return false
+	}
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0 {
+		return false
+	}
+	p.content.WriteString("q ")
+	if n.A < 255 {
+		alpha := float64(n.A) / 255
+		id := -1
+		for i, a := range p.doc.alphas {
+			if a == alpha {
+				id = i
+			}
+		}
+		if id < 0 {
+			id = len(p.doc.alphas)
+			p.doc.alphas = append(p.doc.alphas, alpha)
+		}
+		p.alphas[id] = true
+		fmt.Fprintf(&p.content, "/GS%d gs ", id)
+	}
+	fmt.Fprintf(&p.content, "%s %s %s %s\n", num(float64(n.R)/255), num(float64(n.G)/255), num(float64(n.B)/255), operator)
+	return true
+}
+
+// path appends a rectangle rounded by radii to the current path.
+func (p *Page) path(x, y, w, h float64, radii Radii) {
+	if radii == (Radii{}) {
+		fmt.Fprintf(&p.content, "%s %s %s %s re\n", num(x), num(p.Height-y-h), num(w), num(h))
+		return
+	}
+	limit := min(w, h) / 2
+	tl, tr, br, bl := min(radii[0], limit), min(radii[1], limit), min(radii[2], limit), min(radii[3], limit)
+	// Walk clockwise on screen from the end of the top-left corner
+	p.moveTo(x+tl, y)
+	p.lineTo(x+w-tr, y)
+	p.curveTo(x+w-tr+tr*kappa, y, x+w, y+tr-tr*kappa, x+w, y+tr)
+	p.lineTo(x+w, y+h-br)
+	p.curveTo(x+w, y+h-br+br*kappa, x+w-br+br*kappa, y+h, x+w-br, y+h)
+	p.lineTo(x+bl, y+h)
+	p.curveTo(x+bl-bl*kappa, y+h, x, y+h-bl+bl*kappa, x, y+h-bl)
+	p.lineTo(x, y+tl)
+	p.curveTo(x, y+tl-tl*kappa, x+tl-tl*kappa, y, x+tl, y)
+	p.content.WriteString("h\n")
+}
+
+func (p *Page) moveTo(x, y float64) {
+	fmt.Fprintf(&p.content, "%s %s m ", num(x), num(p.Height-y))
+}
+
+func (p *Page) lineTo(x, y float64) {
+	fmt.Fprintf(&p.content, "%s %s l ", num(x), num(p.Height-y))
+}
+
+func (p *Page) curveTo(x1, y1, x2, y2, x3, y3 float64) {
+	fmt.Fprintf(&p.content, "%s %s %s %s %s %s c ",
+		num(x1), num(p.Height-y1), num(x2), num(p.Height-y2), num(x3), num(p.Height-y3))
+}
+
+// num formats a number the shortest way PDF accepts: no exponent, at most
+// three decimals.
+func num(v float64) string {
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", v), "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// winAnsi maps the characters WinAnsiEncoding places in 0x80-0x9F; the
+// rest of Latin-1 keeps its code.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// escape encodes s in WinAnsi as the body of a PDF literal string.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		var c byte
+		switch code, ok := winAnsi[r]; {
+		case ok:
+			c = code
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			c = byte(r)
+		default:
+			c = '?'
+		}
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// writer numbers the objects of a document as they are written and
+// remembers where each starts for the cross-reference table.
+type writer struct {
+	w       io.Writer
+	n       int64
+	err     error
+	offsets []int64
+}
+
+func (w *writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+// object starts object id, which must be the next in order.
+func (w *writer) object(id int) {
+	w.offsets = append(w.offsets, w.n)
+	w.printf("%d 0 obj\n", id)
+}
+
+// stream writes object id as a Flate-compressed stream with the extra
+// dictionary entries.
+func (w *writer) stream(id int, dict string, data []byte) {
+	var packed bytes.Buffer
+	zw := zlib.NewWriter(&packed)
+	zw.Write(data)
+	zw.Close()
+	w.object(id)
+	w.printf("<< %s/Filter /FlateDecode /Length %d >>\nstream\n", dict, packed.Len())
+	if w.err == nil {
+		n, err := w.w.Write(packed.Bytes())
+		w.n += int64(n)
+		w.err = err
+	}
+	w.printf("\nendstream\nendobj\n")
+}
+
+// WriteTo writes the document to w.
+//
+// Objects are numbered: 1 the catalog, 2 the page tree, then the fonts,
+// the opacity states, the images with their alpha masks, and a page
+// object followed by its content stream for each page.
+func (d *Document) WriteTo(out io.Writer) (int64, error) {
+	w := &writer{w: out}
+	w.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	fontBase := 3
+	alphaBase := fontBase + len(fontNames)
+	imageBase := alphaBase + len(d.alphas)
+	// Each image takes two numbers, its alpha mask the second
+	pageBase := imageBase + 2*len(d.images)
+
+	w.object(1)
+	w.printf("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	w.object(2)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageBase+2*i)
+	}
+	w.printf("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(d.pages))
+
+	for i, name := range fontNames {
+		w.object(fontBase + i)
+		w.printf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\nendobj\n", name)
+	}
+	for i, alpha := range d.alphas {
+		w.object(alphaBase + i)
+		w.printf("<< /Type /ExtGState /ca %s /CA %s >>\nendobj\n", num(alpha), num(alpha))
+	}
+	for i, img := range d.images {
+		rgb, alpha, opaque := pixels(img)
+		b := img.Bounds()
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 ", b.Dx(), b.Dy())
+		id := imageBase + 2*i
+		if !opaque {
+			dict += fmt.Sprintf("/SMask %d 0 R ", id+1)
+		}
+		w.stream(id, dict, rgb)
+		// The mask is written even for opaque images to keep the numbering
+		w.stream(id+1, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 ", b.Dx(), b.Dy()), alpha)
+	}
+
+	for i, p := range d.pages {
+		id := pageBase + 2*i
+		var resources strings.Builder
+		resources.WriteString("/Font <<")
+		for f := range fontNames {
+			if p.fonts[Font(f)] {
+				fmt.Fprintf(&resources, " /F%d %d 0 R", f, fontBase+f)
+			}
+		}
+		resources.WriteString(" >> /ExtGState <<")
+		for a := range d.alphas {
+			if p.alphas[a] {
+				fmt.Fprintf(&resources, " /GS%d %d 0 R", a, alphaBase+a)
+			}
+		}
+		resources.WriteString(" >> /XObject <<")
+		for img := range d.images {
+			if p.images[img] {
+				fmt.Fprintf(&resources, " /Im%d %d 0 R", img, imageBase+2*img)
+			}
+		}
+		resources.WriteString(" >>")
+		w.object(id)
+		w.printf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>\nendobj\n",
+			num(p.Width), num(p.Height), resources.String(), id+1)
+		content := p.content.Bytes()
+		// Close clips left open
+		content = append(content, strings.Repeat("Q\n", p.clips)...)
+		w.stream(id+1, "", content)
+	}
+
+	xref := w.n
+	w.printf("xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		w.printf("%010d 00000 n \n", offset)
+	}
+	w.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.n, w.err
+}
+
+// pixels returns the 8-bit RGB samples and alpha of img, unpremultiplied,
+// and whether every pixel is opaque.
+func pixels(img image.Image) (rgb, alpha []byte, opaque bool) {
+	b := img.Bounds()
+	rgb = make([]byte, 0, 3*b.Dx()*b.Dy())
+	alpha = make([]byte, 0, b.Dx()*b.Dy())
+	opaque = true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			opaque = opaque && c.A == 255
+		}
+	}
+	return rgb, alpha, opaque
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streams returns the decompressed streams of a written document, in order.
+func streams(t *testing.T, data []byte) []string {
+	var out []string
+	for _, m := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(data, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if !assert.NoError(t, err) {
+			continue
+		}
+		raw, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		out = append(out, string(raw))
+	}
+	return out
+}
+
+func TestWriteTo(t *testing.T) {
+	doc := New()
+	page := doc.AddPage(200, 100)
+	page.FillRect(10, 20, 30, 40, Radii{}, color.RGBA{255, 0, 0, 255})
+	page.Text(10, 50, "(a) é€ 中", HelveticaBold, 12, color.Black)
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	page.Image(img, 0, 0, 20, 10)
+	page.Image(img, 50, 0, 20, 10)
+	doc.AddPage(200, 100)
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+	assert.Equal(t, int64(len(data)), n)
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+
+	t.Run("the cross-reference table points at every object", func(t *testing.T) {
+		m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+		if !assert.NotNil(t, m) {
+			return
+		}
+		xref, _ := strconv.Atoi(string(m[1]))
+		assert.True(t, bytes.HasPrefix(data[xref:], []byte("xref\n")))
+		entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(data[xref:], -1)
+		assert.Len(t, entries, 2+12+2+4, "catalog, pages, fonts, an image with its mask, two pages")
+		for i, entry := range entries {
+			offset, _ := strconv.Atoi(string(entry[1]))
+			assert.True(t, bytes.HasPrefix(data[offset:], fmt.Appendf(nil, "%d 0 obj", i+1)), "object %d", i+1)
+		}
+	})
+
+	t.Run("pages draw in flipped coordinates", func(t *testing.T) {
+		s := streams(t, data)
+		if !assert.Len(t, s, 4) {
+			return
+		}
+		content := s[2]
+		assert.Contains(t, content, "1 0 0 rg\n10 40 30 40 re\nf")
+		assert.Contains(t, content, "BT /F1 12 Tf 10 50 Td (\\(a\\) \xe9\x80 ?) Tj ET")
+		assert.Equal(t, 2, strings.Count(content, "/Im0 Do"), "the image is stored once")
+		assert.Contains(t, content, "q 20 0 0 10 50 90 cm /Im0 Do Q")
+		assert.Equal(t, "\x00\x00", s[1], "transparent pixels are masked")
+	})
+}
+
+func TestRoundedPath(t *testing.T) {
+	doc := New()
+	page := doc.AddPage(100, 100)
+	page.PushClip(0, 0, 20, 10, Radii{5, 0, 0, 0})
+	page.FillRect(0, 0, 10, 10, Radii{}, color.RGBA{0, 0, 0, 128})
+	page.PopClip()
+	page.PopClip()
+	page.StrokeRect(0, 0, 10, 10, Radii{}, 2, color.RGBA{0, 0, 255, 255})
+
+	content := page.content.String()
+	assert.True(t, strings.HasPrefix(content, "q\n5 100 m 20 100 l "), content)
+	assert.Contains(t, content, "W n\n")
+	assert.Contains(t, content, "/GS0 gs 0 0 0 rg")
+	assert.Contains(t, content, "0 0 1 RG\n2 w 0 90 10 10 re\nS")
+	assert.Equal(t, 3, strings.Count(content, "Q\n"), "the extra PopClip is ignored")
+	assert.Equal(t, []float64{128.0 / 255}, doc.alphas)
+}
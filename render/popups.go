@@ -0,0 +1,93 @@
+package render
+
+import (
+	"browser/utils"
+	"net/url"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+// AllowPopups sets whether pages on site, a host as returned by utils.SiteOf,
+// or its subdomains may open popups without a click or key press.
+func (b *Browser) AllowPopups(site string, allow bool) {
+	if b.popupSites == nil {
+		b.popupSites = make(map[string]bool)
+	}
+	if allow {
+		b.popupSites[site] = true
+	} else {
+		delete(b.popupSites, site)
+	}
+	b.refreshMainMenu()
+}
+
+// PopupsAllowed reports whether the page at pageURL is exempt from popup
+// blocking.
+func (b *Browser) PopupsAllowed(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for site := range b.popupSites {
+		if host == site || strings.HasSuffix(host, "."+site) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenPopup opens a window requested by script.
+func (b *Browser) OpenPopup(targetURL string) {
+	fyne.Do(func() {
+		b.openNewWindow(b.resolveURL(targetURL))
+	})
+}
+
+// NotifyPopupBlocked tells the user a popup was blocked. An empty URL means
+// the page tried to show too many dialogs without user activation.
+func (b *Browser) NotifyPopupBlocked(targetURL string) {
+	if targetURL == "" {
+		b.showToast("Blocked repeated dialogs from this page")
+		return
+	}
+	// Scripts call this from their own goroutine, while SetCurrentURL
+	// clears the list on the UI thread.
+	fyne.Do(func() {
+		b.blockedPopups = append(b.blockedPopups, targetURL)
+		b.refreshMainMenu()
+	})
+	b.showToast("Popup blocked: " + targetURL)
+}
+
+// popupMenuItems returns the View menu entries for the current site's popup
+// permission and for reopening the most recently blocked popup.
+func (b *Browser) popupMenuItems() []*fyne.MenuItem {
+	site := ""
+	if b.currentURL != nil {
+		site = utils.SiteOf(b.currentURL.String())
+	}
+	allow := fyne.NewMenuItem("Always Allow Popups", func() {
+		b.AllowPopups(site, !b.popupSites[site])
+	})
+	allow.Checked = site != "" && b.popupSites[site]
+	allow.Disabled = site == ""
+
+	reopen := fyne.NewMenuItem("Open Blocked Popup", func() {
+		if n := len(b.blockedPopups); n > 0 {
+			last := b.blockedPopups[n-1]
+			b.blockedPopups = b.blockedPopups[:n-1]
+			b.OpenPopup(last)
+			b.refreshMainMenu()
+		}
+	})
+	reopen.Disabled = len(b.blockedPopups) == 0
+	return []*fyne.MenuItem{allow, reopen}
+}
+
+// SetJSActivationHandler sets the callback run when the user presses a key
+// in the page, so scripts gain user activation from keyboard input too.
+func (b *Browser) SetJSActivationHandler(handler func()) {
+	b.onJSActivation = handler
+}
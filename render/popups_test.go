@@ -0,0 +1,29 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPopupsAllowed(t *testing.T) {
+	b := &Browser{}
+	assert.False(t, b.PopupsAllowed("https://example.com/"))
+
+	b.AllowPopups("example.com", true)
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com/page", true},
+		{"https://www.example.com/", true},
+		{"https://other.org/", false},
+		{"about:blank", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, b.PopupsAllowed(tt.url), tt.url)
+	}
+
+	b.AllowPopups("example.com", false)
+	assert.False(t, b.PopupsAllowed("https://example.com/page"))
+}
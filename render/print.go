@@ -0,0 +1,325 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"os"
+	"strings"
+
+	"browser/css"
+	"browser/dom"
+	"browser/layout"
+	"browser/render/pdf"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+)
+
+// Printing (CSS Paged Media §3). The document is laid out again for a
+// window as wide as the printable part of a sheet, with @media print rules
+// applied instead of screen ones, and drawn as one tall page. That page is
+// cut into sheets: a cut moves up above any line of text or replaced
+// element it would go through, so they never straddle two sheets. Each
+// sheet becomes a page of a PDF, with the fixed boxes repeated on every
+// one (CSS 2.1 §9.6.1).
+
+// PrintOptions describes the sheets printed on, in CSS pixels.
+type PrintOptions struct {
+	PageWidth, PageHeight float64
+	Margin                float64 // on every side of the sheet
+}
+
+// DefaultPrintOptions prints on A4 with half-inch margins.
+var DefaultPrintOptions = PrintOptions{PageWidth: 794, PageHeight: 1123, Margin: 48}
+
+// pointsPerPixel converts CSS pixels, 96 to the inch, to PDF points, 72 to
+// the inch.
+const pointsPerPixel = 0.75
+
+// contentSize returns the size of the printable part of a sheet.
+func (o PrintOptions) contentSize() (width, height float64) {
+	return o.PageWidth - 2*o.Margin, o.PageHeight - 2*o.Margin
+}
+
+// layoutForPrint lays document out for printing: at the width of a sheet's
+// printable area, matching @media print rules.
+func layoutForPrint(document *dom.Node, stylesheet css.Stylesheet, ctx css.MatchContext, opts PrintOptions) *layout.LayoutBox {
+	ctx.Media = css.MediaPrint
+	// Paper cannot be hovered
+	ctx.Hovered = nil
+	width, height := opts.contentSize()
+	root := layout.BuildLayoutTree(document, stylesheet, layout.Viewport{Width: width, Height: height}, ctx)
+	layout.ComputeLayout(root, width)
+	return root
+}
+
+// span is a vertical extent that a page break must not cut.
+type span struct {
+	top, bottom float64
+}
+
+// unbreakableSpans returns the lines of text and replaced elements and form
+// controls laid out under root.
+func unbreakableSpans(root *layout.LayoutBox) []span {
+	var spans []span
+	var walk func(box *layout.LayoutBox)
+	walk = func(box *layout.LayoutBox) {
+		switch box.Type {
+		case layout.TextBox:
+			for _, line := range textLines(box) {
+				spans = append(spans, span{line.y, line.y + line.height})
+			}
+		case layout.ImageBox, layout.MediaBox, layout.HRBox, layout.InputBox, layout.ButtonBox,
+			layout.TextareaBox, layout.SelectBox, layout.RadioBox, layout.CheckboxBox, layout.FileInputBox:
+			spans = append(spans, span{box.Rect.Y, box.Rect.Y + box.Rect.Height})
+		}
+		for _, child := range box.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return spans
+}
+
+// pageBreaks returns where each page of a document height tall starts,
+// pages being pageHeight tall. A page ends early rather than cut through
+// one of spans; only a span taller than a page is cut.
+func pageBreaks(height, pageHeight float64, spans []span) []float64 {
+	breaks := []float64{0}
+	if pageHeight <= 0 {
+		return breaks
+	}
+	for top := 0.0; top+pageHeight < height; {
+		end := top + pageHeight
+		// Moving the break above one span can put it inside another
+		for moved := true; moved; {
+			moved = false
+			for _, s := range spans {
+				if s.top > top && s.top < end && s.bottom > end {
+					end, moved = s.top, true
+				}
+			}
+		}
+		breaks = append(breaks, end)
+		top = end
+	}
+	return breaks
+}
+
+// PrintToPDF prints the current page to a PDF on sheets described by opts.
+func (b *Browser) PrintToPDF(opts PrintOptions) *pdf.Document {
+	if b.document == nil {
+		doc := pdf.New()
+		doc.AddPage(opts.PageWidth*pointsPerPixel, opts.PageHeight*pointsPerPixel)
+		return doc
+	}
+	root := layoutForPrint(b.document, b.pageStylesheet(), b.matchContext(), opts)
+	// The focused field is printed without a caret or selection
+	normal, fixed := BuildDisplayLists(root, InputState{
+		InputValues:     b.inputValues,
+		RadioValues:     b.radioValues,
+		CheckboxValues:  b.checkboxValue,
+		FileInputValues: b.fileInputValues,
+	}, LinkStyler{
+		IsVisited:  b.IsVisited,
+		ResolveURL: b.resolveURL,
+	})
+	baseURL, pageURL := "", ""
+	if b.currentURL != nil {
+		baseURL = b.currentURL.Scheme + "://" + b.currentURL.Host
+		pageURL = b.currentURL.String()
+	}
+	_, pageHeight := opts.contentSize()
+	height := root.Rect.Y + root.Rect.Height
+	return printPages(
+		renderToCanvas(normal.Commands, baseURL, pageURL, true, nil, nil),
+		renderToCanvas(fixed.Commands, baseURL, pageURL, true, nil, nil),
+		pageBreaks(height, pageHeight, unbreakableSpans(root)), height, opts)
+}
+
+// printPages draws the page's objects on one PDF page per break, each
+// showing the part of the page from its break to the next, and the fixed
+// objects on all of them.
+func printPages(objects, fixed []fyne.CanvasObject, breaks []float64, height float64, opts PrintOptions) *pdf.Document {
+	doc := pdf.New()
+	width, pageHeight := opts.contentSize()
+	for i, top := range breaks {
+		bottom := height
+		if i+1 < len(breaks) {
+			bottom = breaks[i+1]
+		}
+		page := doc.AddPage(opts.PageWidth*pointsPerPixel, opts.PageHeight*pointsPerPixel)
+		m := opts.Margin * pointsPerPixel
+		page.PushClip(m, m, width*pointsPerPixel, (bottom-top)*pointsPerPixel, pdf.Radii{})
+		printer{page: page, top: opts.Margin, bottom: opts.Margin + bottom - top}.draw(objects, opts.Margin, opts.Margin-top)
+		page.PopClip()
+		page.PushClip(m, m, width*pointsPerPixel, pageHeight*pointsPerPixel, pdf.Radii{})
+		printer{page: page, top: math.Inf(-1), bottom: math.Inf(1)}.draw(fixed, opts.Margin, opts.Margin)
+		page.PopClip()
+	}
+	return doc
+}
+
+// printer draws canvas objects onto a PDF page. Objects lying wholly
+// outside top to bottom, in CSS pixels down the sheet, are left out.
+type printer struct {
+	page        *pdf.Page
+	top, bottom float64
+}
+
+// draw draws objects moved by (dx, dy) CSS pixels.
+func (p printer) draw(objects []fyne.CanvasObject, dx, dy float64) {
+	pt := func(v float64) float64 { return v * pointsPerPixel }
+	for _, obj := range objects {
+		if obj == nil || !obj.Visible() {
+			continue
+		}
+		pos, size := obj.Position(), obj.Size()
+		x, y := float64(pos.X)+dx, float64(pos.Y)+dy
+		w, h := float64(size.Width), float64(size.Height)
+		extent := h
+		if text, ok := obj.(*canvas.Text); ok {
+			extent = float64(text.TextSize) * 1.5
+		}
+		if y+extent < p.top || y > p.bottom {
+			continue
+		}
+		switch o := obj.(type) {
+		case *fyne.Container:
+			p.draw(o.Objects, x, y)
+		case *canvas.Rectangle:
+			r := pt(float64(o.CornerRadius))
+			radii := pdf.Radii{r, r, r, r}
+			p.page.FillRect(pt(x), pt(y), pt(w), pt(h), radii, o.FillColor)
+			if o.StrokeWidth > 0 {
+				p.page.StrokeRect(pt(x), pt(y), pt(w), pt(h), radii, pt(float64(o.StrokeWidth)), o.StrokeColor)
+			}
+		case *canvas.Circle:
+			r := pt(min(w, h) / 2)
+			radii := pdf.Radii{r, r, r, r}
+			p.page.FillRect(pt(x), pt(y), pt(w), pt(h), radii, o.FillColor)
+			if o.StrokeWidth > 0 {
+				p.page.StrokeRect(pt(x), pt(y), pt(w), pt(h), radii, pt(float64(o.StrokeWidth)), o.StrokeColor)
+			}
+		case *canvas.Line:
+			p.page.Line(pt(float64(o.Position1.X)+dx), pt(float64(o.Position1.Y)+dy),
+				pt(float64(o.Position2.X)+dx), pt(float64(o.Position2.Y)+dy), pt(float64(o.StrokeWidth)), o.StrokeColor)
+		case *canvas.Text:
+			_, baseline := fyne.CurrentApp().Driver().RenderedTextSize(o.Text, o.TextSize, o.TextStyle, o.FontSource)
+			p.page.Text(pt(x), pt(y+float64(baseline)), o.Text, printFont(o.TextStyle), pt(float64(o.TextSize)), o.Color)
+		case *canvas.Image:
+			if img := canvasImage(o); img != nil {
+				x, y, w, h = fitImage(img, o.FillMode, x, y, w, h)
+				p.page.Image(img, pt(x), pt(y), pt(w), pt(h))
+			}
+		}
+	}
+}
+
+// printFont returns the standard PDF font standing in for style: Courier
+// for monospace text, Helvetica otherwise.
+func printFont(style fyne.TextStyle) pdf.Font {
+	font := pdf.Helvetica
+	if style.Monospace {
+		font = pdf.Courier
+	}
+	if style.Bold {
+		font += pdf.Bold
+	}
+	if style.Italic {
+		font += pdf.Italic
+	}
+	return font
+}
+
+// canvasImage returns the pixels an image object shows, decoding the file
+// or resource it was loaded from if needed. It returns nil if they cannot
+// be had.
+func canvasImage(o *canvas.Image) image.Image {
+	if o.Image != nil {
+		return o.Image
+	}
+	var data []byte
+	switch {
+	case o.Resource != nil:
+		data = o.Resource.Content()
+	case o.File != "":
+		data, _ = os.ReadFile(o.File)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// fitImage returns where img is drawn in the rect (x, y, w, h): centred at
+// its aspect ratio when the fill mode keeps it, over all of it otherwise.
+func fitImage(img image.Image, mode canvas.ImageFill, x, y, w, h float64) (float64, float64, float64, float64) {
+	b := img.Bounds()
+	if mode != canvas.ImageFillContain || b.Dx() == 0 || b.Dy() == 0 || w <= 0 || h <= 0 {
+		return x, y, w, h
+	}
+	scale := min(w/float64(b.Dx()), h/float64(b.Dy()))
+	fw, fh := float64(b.Dx())*scale, float64(b.Dy())*scale
+	return x + (w-fw)/2, y + (h-fh)/2, fw, fh
+}
+
+// printFileName returns the file name a page titled title is saved as.
+func printFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "page"
+	}
+	return name + ".pdf"
+}
+
+// SetJSPrintHandler sets the callback that prints through the page's
+// scripts, firing beforeprint and afterprint around Print.
+func (b *Browser) SetJSPrintHandler(handler func()) {
+	b.onJSPrint = handler
+}
+
+// printPage is the print command: it prints through the page's scripts
+// when there are any.
+func (b *Browser) printPage() {
+	if b.onJSPrint != nil {
+		b.onJSPrint()
+		return
+	}
+	b.Print()
+}
+
+// Print implements window.print: it prints the page to a PDF and asks
+// where to save it. The document is captured before Print returns.
+func (b *Browser) Print() {
+	doc := b.PrintToPDF(DefaultPrintOptions)
+	name := printFileName("")
+	if b.document != nil {
+		name = printFileName(dom.FindTitle(b.document))
+	}
+	fyne.Do(func() {
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return // cancelled
+			}
+			defer writer.Close()
+			if _, err := doc.WriteTo(writer); err != nil {
+				b.showToast("Printing failed: " + err.Error())
+				return
+			}
+			b.showToast("Saved " + writer.URI().Name())
+		}, b.Window)
+		save.SetFileName(name)
+		save.SetFilter(storage.NewExtensionFileFilter([]string{".pdf"}))
+		save.Show()
+	})
+}
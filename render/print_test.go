@@ -0,0 +1,93 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"browser/css"
+	"browser/dom"
+	"browser/layout"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageBreaks(t *testing.T) {
+	tests := []struct {
+		name   string
+		height float64
+		spans  []span
+		want   []float64
+	}{
+		{"fits on one page", 80, nil, []float64{0}},
+		{"even cuts", 250, nil, []float64{0, 100, 200}},
+		{"a line is moved to the next page", 250, []span{{90, 110}}, []float64{0, 90, 190}},
+		{"a line ending at the cut stays", 150, []span{{80, 100}}, []float64{0, 100}},
+		{"moving above one line can land in another", 150, []span{{60, 92}, {90, 100}, {95, 105}}, []float64{0, 60}},
+		{"a line taller than a page is cut", 250, []span{{50, 220}}, []float64{0, 50, 150}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pageBreaks(tt.height, 100, tt.spans))
+		})
+	}
+}
+
+func TestLayoutForPrint(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<div id="nav">menu</div><p id="body">text</p>`))
+	sheet := css.Parse(`#body { color: red } @media print { #nav { display: none } #body { color: blue } } @media screen { #body { font-size: 30px } }`)
+	opts := PrintOptions{PageWidth: 300, PageHeight: 400, Margin: 20}
+	root := layoutForPrint(doc, sheet, css.MatchContext{}, opts)
+
+	var ids []string
+	var body *layout.LayoutBox
+	var walk func(box *layout.LayoutBox)
+	walk = func(box *layout.LayoutBox) {
+		if box.Node != nil && box.Node.Attributes["id"] != "" {
+			ids = append(ids, box.Node.Attributes["id"])
+			body = box
+		}
+		for _, child := range box.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	assert.Equal(t, []string{"body"}, ids, "print rules hide the menu")
+	assert.Equal(t, 260.0, root.Rect.Width, "laid out at the printable width")
+	r, g, b, _ := body.Style.Color.RGBA()
+	assert.Equal(t, [3]uint32{0, 0, 0xffff}, [3]uint32{r, g, b})
+	assert.NotEqual(t, 30.0, body.Style.FontSize, "screen rules are left out")
+}
+
+func TestPrintToPDF(t *testing.T) {
+	test.NewApp()
+	var html strings.Builder
+	html.WriteString(`<title>A/B report</title>`)
+	for range 40 {
+		html.WriteString(`<p>A paragraph of printed text</p>`)
+	}
+	b := &Browser{document: dom.Parse(strings.NewReader(html.String()))}
+	opts := PrintOptions{PageWidth: 400, PageHeight: 300, Margin: 20}
+
+	doc := b.PrintToPDF(opts)
+	root := layoutForPrint(b.document, b.pageStylesheet(), b.matchContext(), opts)
+	spans := unbreakableSpans(root)
+	breaks := pageBreaks(root.Rect.Y+root.Rect.Height, 260, spans)
+	assert.Greater(t, len(breaks), 1)
+	assert.Equal(t, len(breaks), doc.Pages())
+	for _, cut := range breaks {
+		for _, line := range spans {
+			assert.False(t, line.top < cut && cut < line.bottom, "no line is cut at %v", cut)
+		}
+	}
+
+	var out bytes.Buffer
+	_, err := doc.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "/MediaBox [0 0 300 225]", "sheets are sized in points")
+
+	assert.Equal(t, "A_B report.pdf", printFileName(dom.FindTitle(b.document)))
+	assert.Equal(t, "page.pdf", printFileName("  "))
+}
@@ -0,0 +1,112 @@
+package render
+
+import (
+	"browser/utils"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PrivacyURL is the internal page for inspecting and clearing browsing data.
+const PrivacyURL = "about:privacy"
+
+// clearRanges are the time ranges offered by the about:privacy form.
+var clearRanges = []struct {
+	Value string
+	Label string
+	Age   time.Duration // 0 for all time
+}{
+	{"hour", "Last hour", time.Hour},
+	{"day", "Last 24 hours", 24 * time.Hour},
+	{"week", "Last 7 days", 7 * 24 * time.Hour},
+	{"all", "All time", 0},
+}
+
+// clearFromQuery clears the data a privacy form submission selects: the
+// cookies, cache and storage checkboxes, an optional site, and a range.
+func clearFromQuery(query url.Values) string {
+	var kinds utils.BrowsingData
+	if query.Get("cookies") != "" {
+		kinds |= utils.BrowsingCookies
+	}
+	if query.Get("cache") != "" {
+		kinds |= utils.BrowsingCache
+	}
+	if query.Get("storage") != "" {
+		kinds |= utils.BrowsingLocalStorage
+	}
+	if kinds == 0 {
+		return "Nothing selected to clear."
+	}
+
+	var since time.Time
+	for _, r := range clearRanges {
+		if r.Value == query.Get("range") && r.Age > 0 {
+			since = time.Now().Add(-r.Age)
+		}
+	}
+	site := strings.TrimSpace(query.Get("site"))
+	removed := utils.ClearBrowsingData(kinds, site, since)
+	if site == "" {
+		return fmt.Sprintf("Cleared %d items.", removed)
+	}
+	return fmt.Sprintf("Cleared %d items for %s.", removed, site)
+}
+
+// privacyPage lists the stored data, with controls that clear it carrying
+// token.
+func privacyPage(message, token string) string {
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Privacy</title></head><body>\n<h1>Browsing data</h1>\n")
+	if message != "" {
+		fmt.Fprintf(&sb, "<p><strong>%s</strong></p>\n", html.EscapeString(message))
+	}
+
+	fmt.Fprintf(&sb, `<form action="about:privacy" method="get">
+<input type="hidden" name="action" value="clear">
+<input type="hidden" name="token" value="%s">
+<p><input type="checkbox" name="cookies" checked> Cookies
+<input type="checkbox" name="cache" checked> Cached images and files
+<input type="checkbox" name="storage" checked> Site storage</p>
+<p>Site <input type="text" name="site" placeholder="every site"> Time range <select name="range">
+`, html.EscapeString(token))
+	for _, r := range clearRanges {
+		fmt.Fprintf(&sb, "<option value=%q>%s</option>\n", r.Value, r.Label)
+	}
+	sb.WriteString("</select> <button type=\"submit\">Clear data</button></p>\n</form>\n")
+
+	sites := utils.StoredSiteData()
+	sb.WriteString("<h2>Sites</h2>\n")
+	if len(sites) == 0 {
+		sb.WriteString("<p>No site data is stored.</p>\n")
+	} else {
+		sb.WriteString("<table border=\"1\" cellpadding=\"4\">\n<tr><th>Site</th><th>Cookies</th><th>Cache</th><th>Storage</th><th></th></tr>\n")
+		for _, s := range sites {
+			clear := url.Values{"action": {"clear"}, "token": {token}, "site": {s.Site}, "range": {"all"},
+				"cookies": {"on"}, "cache": {"on"}, "storage": {"on"}}
+			fmt.Fprintf(&sb, "<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td><a href=\"%s?%s\">Clear</a></td></tr>\n",
+				html.EscapeString(s.Site), s.Cookies, s.CacheEntries, s.StorageItems,
+				PrivacyURL, html.EscapeString(clear.Encode()))
+		}
+		sb.WriteString("</table>\n")
+	}
+
+	cookies := utils.Cookies.All()
+	if len(cookies) > 0 {
+		sb.WriteString("<h2>Cookies</h2>\n<table border=\"1\" cellpadding=\"4\">\n<tr><th>Domain</th><th>Name</th><th>Path</th><th>Expires</th></tr>\n")
+		for _, c := range cookies {
+			expires := "Session"
+			if !c.Expires.IsZero() {
+				expires = c.Expires.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(&sb, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+				html.EscapeString(c.Domain), html.EscapeString(c.Name), html.EscapeString(c.Path), expires)
+		}
+		sb.WriteString("</table>\n")
+	}
+
+	sb.WriteString("</body></html>\n")
+	return sb.String()
+}
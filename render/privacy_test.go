@@ -0,0 +1,81 @@
+package render
+
+import (
+	"browser/utils"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInternalPage(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"about:privacy", true},
+		{"about:privacy?action=clear", true},
+		{"about:blank", true},
+		{"about:Version", true},
+		{"about:unknown", false},
+		{"https://example.com/privacy", false},
+	}
+	b := &Browser{}
+	for _, tt := range tests {
+		_, ok := b.InternalPage(tt.url)
+		assert.Equal(t, tt.ok, ok, tt.url)
+	}
+}
+
+func TestPrivacyPageClearsSelectedData(t *testing.T) {
+	defer func() { utils.Cookies, utils.LocalStorage = utils.NewCookieJar(), utils.NewWebStorage() }()
+	utils.Cookies, utils.LocalStorage = utils.NewCookieJar(), utils.NewWebStorage()
+
+	u, _ := url.Parse("https://example.com/")
+	utils.Cookies.SetCookies(u, []*http.Cookie{{Name: "session", Value: "1"}})
+	utils.LocalStorage.SetItem("https://example.com", "theme", "dark")
+
+	b := &Browser{}
+	page, _ := b.InternalPage(PrivacyURL)
+	assert.Contains(t, page, "<td>example.com</td>")
+	assert.Contains(t, page, "<td>session</td>")
+	assert.Contains(t, page, `name="token" value="`+b.privacyNonce()+`"`)
+
+	page, _ = b.InternalPage(PrivacyURL + "?action=clear&cookies=on&cache=on&storage=on&range=all")
+	assert.Contains(t, page, "Data can only be cleared with the controls on this page.")
+	page, _ = b.InternalPage(PrivacyURL + "?action=clear&cookies=on&range=all&token=guess")
+	assert.Contains(t, page, "Data can only be cleared with the controls on this page.")
+	assert.Len(t, utils.Cookies.All(), 1, "a forged request cleared nothing")
+
+	token := "&token=" + b.privacyNonce()
+	page, _ = b.InternalPage(PrivacyURL + "?action=clear&storage=on&site=example.com&range=all" + token)
+	assert.Contains(t, page, "Cleared 1 items for example.com.")
+	assert.Len(t, utils.Cookies.All(), 1, "cookies were not selected")
+	assert.Empty(t, utils.LocalStorage.Origins())
+
+	page, _ = b.InternalPage(PrivacyURL + "?action=clear&range=hour" + token)
+	assert.Contains(t, page, "Nothing selected to clear.")
+
+	page, _ = b.InternalPage(PrivacyURL + "?action=clear&cookies=on&range=hour" + token)
+	assert.Contains(t, page, "Cleared 1 items.")
+	assert.Contains(t, page, "No site data is stored.")
+}
+
+func TestInternalPageAllowed(t *testing.T) {
+	tests := []struct {
+		url, from string
+		allowed   bool
+	}{
+		{PrivacyURL, "", true},
+		{PrivacyURL + "?action=clear", PrivacyURL, true},
+		{HistoryURL, "https://example.com/", false},
+		{PrivacyURL + "?action=clear", "http://example.com/", false},
+		{"ABOUT:cache", "file:///tmp/page.html", false},
+		{BlankURL, "https://example.com/", true},
+		{"https://example.com/next", "https://example.com/", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, InternalPageAllowed(NavigationRequest{URL: tt.url, FromURL: tt.from}), tt.url+" from "+tt.from)
+	}
+}
@@ -0,0 +1,163 @@
+package render
+
+import (
+	"cmp"
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+	"time"
+
+	"browser/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+)
+
+// Profiling. While on, every style, layout, paint and raster run is timed
+// (see utils.Profile), and an overlay in the corner of the page shows the
+// latest timings, refreshed twice a second.
+
+// profileRefresh is how often the overlay shows new timings.
+const profileRefresh = 500 * time.Millisecond
+
+// profileBoxesPerLine is how many box types the overlay lists per line.
+const profileBoxesPerLine = 4
+
+// profileUnits names what each phase's Count counts.
+var profileUnits = map[utils.Phase]string{
+	utils.PhaseStyle:       "boxes",
+	utils.PhaseLayout:      "boxes",
+	utils.PhaseDisplayList: "commands",
+	utils.PhaseRaster:      "items",
+}
+
+// SetProfiling turns profiling and its overlay on or off. Turning it on
+// starts from fresh timings.
+func (b *Browser) SetProfiling(on bool) {
+	if on == utils.Profile.Enabled() {
+		return
+	}
+	if on {
+		utils.Profile.Reset()
+	}
+	utils.Profile.SetEnabled(on)
+	b.refreshMainMenu()
+	if b.profileOverlay == nil {
+		return
+	}
+	if b.profileStop != nil {
+		close(b.profileStop)
+		b.profileStop = nil
+	}
+	if !on {
+		fyne.Do(b.profileOverlay.Hide)
+		return
+	}
+	stop := make(chan struct{})
+	b.profileStop = stop
+	go func() {
+		ticker := time.NewTicker(profileRefresh)
+		defer ticker.Stop()
+		for {
+			fyne.Do(b.showProfile)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Profiling reports whether profiling is on.
+func (b *Browser) Profiling() bool {
+	return utils.Profile.Enabled()
+}
+
+// ProfileReport returns the timings recorded since profiling was turned on.
+func (b *Browser) ProfileReport() utils.ProfileReport {
+	return utils.Profile.Report()
+}
+
+// newProfileOverlay returns the hidden overlay showing profiling timings.
+func (b *Browser) newProfileOverlay() *fyne.Container {
+	bg := canvas.NewRectangle(color.NRGBA{A: 200})
+	overlay := container.NewWithoutLayout(bg)
+	overlay.Hide()
+	return overlay
+}
+
+// showProfile shows the latest timings in the overlay, at the top right
+// corner of the page.
+func (b *Browser) showProfile() {
+	if !utils.Profile.Enabled() {
+		return
+	}
+	lines := formatProfile(utils.Profile.Report())
+	const textSize, padding = 12, 8
+	style := fyne.TextStyle{Monospace: true}
+
+	pos := fyne.NewPos(padding, padding)
+	var width float32
+	for _, line := range lines {
+		width = max(width, fyne.MeasureText(line, textSize, style).Width)
+	}
+	if b.content != nil {
+		pos = b.content.Position().AddXY(b.content.Size().Width-width-3*padding, padding)
+	}
+
+	bg := b.profileOverlay.Objects[0]
+	bg.Move(pos)
+	objects := []fyne.CanvasObject{bg}
+	y := pos.Y + padding
+	for _, line := range lines {
+		text := canvas.NewText(line, color.White)
+		text.TextSize = textSize
+		text.TextStyle = style
+		text.Move(fyne.NewPos(pos.X+padding, y))
+		objects = append(objects, text)
+		y += text.MinSize().Height
+	}
+	bg.Resize(fyne.NewSize(width+2*padding, y+padding-pos.Y))
+	b.profileOverlay.Objects = objects
+	b.profileOverlay.Show()
+	b.profileOverlay.Refresh()
+}
+
+// formatProfile returns the lines the overlay shows for report: each
+// phase's last and average run, then the boxes of the last layout by type,
+// most numerous first.
+func formatProfile(report utils.ProfileReport) []string {
+	var lines []string
+	for phase := utils.PhaseStyle; phase <= utils.PhaseRaster; phase++ {
+		t := report.Phase(phase)
+		lines = append(lines, fmt.Sprintf("%-12s %8s  avg %8s  %d %s",
+			phase, formatMillis(t.Last), formatMillis(t.Average()), t.Count, profileUnits[phase]))
+	}
+
+	types := make([]string, 0, len(report.Boxes))
+	total := 0
+	for name, n := range report.Boxes {
+		types = append(types, name)
+		total += n
+	}
+	slices.SortFunc(types, func(a, b string) int {
+		return cmp.Or(report.Boxes[b]-report.Boxes[a], strings.Compare(a, b))
+	})
+	lines = append(lines, fmt.Sprintf("%d boxes", total))
+	for i := 0; i < len(types); i += profileBoxesPerLine {
+		var line []string
+		for _, name := range types[i:min(i+profileBoxesPerLine, len(types))] {
+			line = append(line, fmt.Sprintf("%s %d", name, report.Boxes[name]))
+		}
+		lines = append(lines, "  "+strings.Join(line, ", "))
+	}
+	return lines
+}
+
+// formatMillis formats d in milliseconds.
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"browser/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatProfile(t *testing.T) {
+	var report utils.ProfileReport
+	report.Phases[utils.PhaseLayout] = utils.PhaseTiming{Runs: 2, Last: 1500 * time.Microsecond, Total: 5 * time.Millisecond, Count: 12}
+	report.Boxes = map[string]int{"block": 4, "text": 6, "inline": 1, "image": 1, "hr": 1}
+
+	lines := formatProfile(report)
+	assert.Equal(t, []string{
+		"style          0.00ms  avg   0.00ms  0 boxes",
+		"layout         1.50ms  avg   2.50ms  12 boxes",
+		"display list   0.00ms  avg   0.00ms  0 commands",
+		"raster         0.00ms  avg   0.00ms  0 items",
+		"13 boxes",
+		"  text 6, block 4, hr 1, image 1",
+		"  inline 1",
+	}, lines)
+}
+
+func TestProfileDisplayList(t *testing.T) {
+	b := &Browser{}
+	b.SetProfiling(true)
+	t.Cleanup(func() { b.SetProfiling(false) })
+	assert.True(t, b.Profiling())
+
+	root := buildLayout(`<p>Hello</p>`, ``, 400)
+	normal, fixed := BuildDisplayLists(root, InputState{}, LinkStyler{})
+
+	report := b.ProfileReport()
+	assert.Equal(t, 1, report.Phase(utils.PhaseLayout).Runs)
+	assert.Equal(t, 1, report.Phase(utils.PhaseDisplayList).Runs)
+	assert.Equal(t, len(normal.Commands)+len(fixed.Commands), report.Phase(utils.PhaseDisplayList).Count)
+
+	b.SetProfiling(false)
+	BuildDisplayLists(root, InputState{}, LinkStyler{})
+	assert.Equal(t, 1, b.ProfileReport().Phase(utils.PhaseDisplayList).Runs, "off again")
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"browser/dom"
+)
+
+// Declarative refresh. A page may ask, with a Refresh header or a
+// <meta http-equiv="refresh">, to be loaded again or replaced by another
+// after a delay; without one it is a redirect, as older sites use them.
+// The page it leads to takes the refreshing page's place in the history,
+// and leaving the page first cancels it.
+
+// ScheduleRefresh loads refresh's URL, resolved against pageURL, or pageURL
+// again, once its delay is over, unless CancelRefresh is called first. Only
+// http and https pages refresh, and only to http and https URLs, so that a
+// page cannot open local files or internal pages by itself.
+func (b *Browser) ScheduleRefresh(pageURL string, refresh dom.Refresh) {
+	base, err := url.Parse(pageURL)
+	if err != nil || !webScheme(base) {
+		return
+	}
+	target := base
+	if refresh.URL != "" {
+		if target, err = base.Parse(refresh.URL); err != nil || !webScheme(target) {
+			fmt.Printf("Ignoring refresh to %s\n", refresh.URL)
+			return
+		}
+	}
+	fmt.Printf("Refreshing to %s in %v\n", target, refresh.Delay)
+
+	b.refreshMu.Lock()
+	defer b.refreshMu.Unlock()
+	if b.refreshTimer != nil {
+		b.refreshTimer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(refresh.Delay, func() {
+		b.refreshMu.Lock()
+		current := b.refreshTimer == timer
+		if current {
+			b.refreshTimer = nil
+		}
+		b.refreshMu.Unlock()
+		if current && b.OnNavigate != nil {
+			b.OnNavigate(NavigationRequest{URL: target.String(), Method: "GET", FromURL: pageURL, Replace: true})
+		}
+	})
+	b.refreshTimer = timer
+}
+
+// CancelRefresh cancels the pending refresh of the page, as navigating
+// away from it does.
+func (b *Browser) CancelRefresh() {
+	b.refreshMu.Lock()
+	defer b.refreshMu.Unlock()
+	if b.refreshTimer != nil {
+		b.refreshTimer.Stop()
+		b.refreshTimer = nil
+	}
+}
+
+// webScheme reports whether u is an http or https URL.
+func webScheme(u *url.URL) bool {
+	return u.Scheme == "http" || u.Scheme == "https"
+}
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleRefresh(t *testing.T) {
+	navigations := make(chan NavigationRequest, 1)
+	b := &Browser{OnNavigate: func(req NavigationRequest) { navigations <- req }}
+
+	b.ScheduleRefresh("https://example.com/old/page", dom.Refresh{URL: "../new"})
+	select {
+	case req := <-navigations:
+		assert.Equal(t, NavigationRequest{URL: "https://example.com/new", Method: "GET", FromURL: "https://example.com/old/page", Replace: true}, req)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no refresh")
+	}
+
+	b.ScheduleRefresh("https://example.com/", dom.Refresh{Delay: 50 * time.Millisecond})
+	b.CancelRefresh()
+	b.ScheduleRefresh("https://example.com/a", dom.Refresh{Delay: time.Hour})
+	b.ScheduleRefresh("https://example.com/b", dom.Refresh{Delay: 20 * time.Millisecond})
+	select {
+	case req := <-navigations:
+		assert.Equal(t, "https://example.com/b", req.URL, "a later refresh replaces the pending one")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "no refresh")
+	}
+	select {
+	case req := <-navigations:
+		assert.Fail(t, "cancelled refresh ran", req.URL)
+	case <-time.After(100 * time.Millisecond):
+	}
+	b.CancelRefresh()
+}
+
+func TestScheduleRefreshSchemes(t *testing.T) {
+	navigations := make(chan NavigationRequest, 1)
+	b := &Browser{OnNavigate: func(req NavigationRequest) { navigations <- req }}
+
+	b.ScheduleRefresh("https://example.com/", dom.Refresh{URL: "about:privacy?site=example.com"})
+	b.ScheduleRefresh("http://example.com/", dom.Refresh{URL: "file:///etc/passwd"})
+	b.ScheduleRefresh("file:///home/user/page.html", dom.Refresh{})
+	b.ScheduleRefresh("about:history", dom.Refresh{URL: "https://example.com/"})
+	select {
+	case req := <-navigations:
+		assert.Fail(t, "refreshed outside the web", req.URL)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReplaceHistory(t *testing.T) {
+	b := &Browser{historyPos: -1}
+	b.ReplaceHistory("https://example.com/first")
+	b.AddToHistory("https://example.com/refreshing")
+	b.ReplaceHistory("https://example.com/target")
+	assert.Equal(t, []string{"https://example.com/first", "https://example.com/target"}, b.history)
+	assert.Equal(t, 1, b.historyPos)
+}
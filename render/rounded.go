@@ -26,11 +26,13 @@ func drawRoundedRectImage(w, h int, col color.Color, tl, tr, br, bl float64) ima
 // insideRoundedRect returns true if pixel (x, y) is inside a rectangle with
 // independent corner radii tl, tr, br, bl (top-left, top-right, bottom-right, bottom-left).
 func insideRoundedRect(x, y, w, h int, tl, tr, br, bl float64) bool {
-	fx := float64(x) + 0.5
-	fy := float64(y) + 0.5
-	fw := float64(w)
-	fh := float64(h)
+	return insideRoundedRectAt(float64(x)+0.5, float64(y)+0.5, float64(w), float64(h), tl, tr, br, bl)
+}
 
+// insideRoundedRectAt is insideRoundedRect for any point (fx, fy) of a
+// fw x fh rectangle, such as the center of a device pixel smaller than a
+// canvas unit.
+func insideRoundedRectAt(fx, fy, fw, fh, tl, tr, br, bl float64) bool {
 	// top-left corner
 	if fx < tl && fy < tl {
 		return circDist(fx, fy, tl, tl) <= tl
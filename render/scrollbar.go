@@ -0,0 +1,157 @@
+package render
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// pageScrollbar is the page's vertical scrollbar, drawn like those of
+// scrolling boxes along the right edge of the content. Its thumb is as long,
+// relative to the track, as the viewport is to the page. Dragging the thumb
+// scrolls the page and clicking the track scrolls a page toward the click.
+// It covers the scroll container's own bar.
+type pageScrollbar struct {
+	widget.BaseWidget
+	browser  *Browser
+	dragging bool
+	grab     float32 // where along the thumb the drag holds it
+}
+
+func newPageScrollbar(b *Browser) *pageScrollbar {
+	s := &pageScrollbar{browser: b}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// scrollbarLayer returns the page scrollbar laid out along the right edge
+// of the content, for the top of the content's stack.
+func (b *Browser) scrollbarLayer() fyne.CanvasObject {
+	if b.scrollbar == nil {
+		b.scrollbar = newPageScrollbar(b)
+	}
+	return container.NewBorder(nil, nil, nil, b.scrollbar)
+}
+
+// scrollbarThumb returns the position and length of the thumb on a track
+// of length track, for a page of length page seen through a viewport of
+// length view and scrolled to offset.
+func scrollbarThumb(track, view, page, offset float32) (pos, length float32) {
+	if page <= view || track <= 0 {
+		return 0, max(track, 0)
+	}
+	length = min(max(track*view/page, ScrollbarThumbMinHeight), track)
+	ratio := min(max(offset/(page-view), 0), 1)
+	return (track - length) * ratio, length
+}
+
+// scrollbarOffset returns the offset that puts the thumb at pos, the
+// inverse of scrollbarThumb.
+func scrollbarOffset(track, view, page, pos float32) float32 {
+	_, length := scrollbarThumb(track, view, page, 0)
+	if page <= view || track <= length {
+		return 0
+	}
+	return min(max(pos/(track-length), 0), 1) * (page - view)
+}
+
+// metrics returns the track length, viewport and page heights and the
+// offset the scrollbar shows. ok is false when the page fits its viewport.
+func (s *pageScrollbar) metrics() (track, view, page, offset float32, ok bool) {
+	scroll := s.browser.contentScroll
+	if scroll == nil || scroll.Content == nil {
+		return 0, 0, 0, 0, false
+	}
+	view, page = scroll.Size().Height, scroll.Content.MinSize().Height
+	track = s.Size().Height - 2*scrollbarThumbPadding
+	return track, view, page, scroll.Offset.Y, page > view && track > 0
+}
+
+// Tapped scrolls a page up or down toward a click on the track.
+func (s *pageScrollbar) Tapped(ev *fyne.PointEvent) {
+	track, view, page, offset, ok := s.metrics()
+	if !ok {
+		return
+	}
+	pos, length := scrollbarThumb(track, view, page, offset)
+	y := ev.Position.Y - scrollbarThumbPadding
+	switch {
+	case y < pos:
+		s.browser.scrollPageBy(0, -view*pageScrollFraction, true)
+	case y > pos+length:
+		s.browser.scrollPageBy(0, view*pageScrollFraction, true)
+	}
+}
+
+// Dragged moves the thumb with the pointer. A drag starting off the thumb
+// first centers it on the pointer.
+func (s *pageScrollbar) Dragged(ev *fyne.DragEvent) {
+	track, view, page, offset, ok := s.metrics()
+	if !ok {
+		return
+	}
+	pos, length := scrollbarThumb(track, view, page, offset)
+	y := ev.Position.Y - scrollbarThumbPadding
+	if !s.dragging {
+		s.dragging = true
+		s.grab = length / 2
+		if y >= pos && y <= pos+length {
+			s.grab = y - pos
+		}
+	}
+	target := s.browser.contentScroll.Offset
+	target.Y = scrollbarOffset(track, view, page, y-s.grab)
+	s.browser.scrollPageTo(target, false)
+}
+
+// DragEnd lets go of the thumb.
+func (s *pageScrollbar) DragEnd() {
+	s.dragging = false
+}
+
+func (s *pageScrollbar) CreateRenderer() fyne.WidgetRenderer {
+	r := &pageScrollbarRenderer{
+		bar:   s,
+		track: canvas.NewRectangle(ColorScrollbarTrack),
+		thumb: canvas.NewRectangle(ColorScrollbarThumb),
+	}
+	r.thumb.CornerRadius = (ScrollbarWidth - 2*scrollbarThumbPadding) / 2
+	return r
+}
+
+type pageScrollbarRenderer struct {
+	bar          *pageScrollbar
+	track, thumb *canvas.Rectangle
+}
+
+func (r *pageScrollbarRenderer) Layout(size fyne.Size) {
+	track, view, page, offset, ok := r.bar.metrics()
+	if !ok {
+		r.track.Hide()
+		r.thumb.Hide()
+		return
+	}
+	pos, length := scrollbarThumb(track, view, page, offset)
+	r.track.Resize(size)
+	r.thumb.Resize(fyne.NewSize(size.Width-2*scrollbarThumbPadding, length))
+	r.thumb.Move(fyne.NewPos(scrollbarThumbPadding, scrollbarThumbPadding+pos))
+	r.track.Show()
+	r.thumb.Show()
+}
+
+func (r *pageScrollbarRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(ScrollbarWidth, 0)
+}
+
+func (r *pageScrollbarRenderer) Refresh() {
+	r.Layout(r.bar.Size())
+	r.track.Refresh()
+	r.thumb.Refresh()
+}
+
+func (r *pageScrollbarRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.track, r.thumb}
+}
+
+func (r *pageScrollbarRenderer) Destroy() {}
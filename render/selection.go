@@ -0,0 +1,512 @@
+package render
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"browser/css"
+	"browser/dom"
+	"browser/layout"
+)
+
+// Text selection. A selection runs from an anchor to a focus, each a caret
+// position between two grapheme clusters of a line of a text box as it is
+// drawn. Dragging moves the focus away from the anchor set at mouse down; a
+// double click selects a word and a triple click its paragraph. Copying
+// takes the selected text in document order, with wrapped lines joined by
+// spaces and blocks by line feeds.
+
+const (
+	multiClickInterval = 500 * time.Millisecond
+	multiClickSlop     = 4 // how far, in CSS pixels, a repeated click may move
+)
+
+// TextPosition is a caret position in the page's text: Offset bytes into
+// line Line of the text box of Node.
+type TextPosition struct {
+	Node   *dom.Node
+	Line   int
+	Offset int
+}
+
+// TextRange is the selected part of a line of a text box: bytes Start to End.
+type TextRange struct {
+	Line       int
+	Start, End int
+}
+
+// textSelection is the selection the user is making. Positions name text
+// nodes rather than boxes so they survive reflows.
+type textSelection struct {
+	anchor, focus TextPosition
+}
+
+// clickCounter counts rapid clicks on one spot: 1 for a click, 2 for a
+// double click and 3 for a triple click, after which it starts over.
+type clickCounter struct {
+	count int
+	at    time.Time
+	x, y  float64
+}
+
+// press records a mouse down at (x, y) and returns its click count.
+func (c *clickCounter) press(x, y float64, now time.Time) int {
+	if c.count == 0 || c.count >= 3 || now.Sub(c.at) > multiClickInterval ||
+		math.Abs(x-c.x) > multiClickSlop || math.Abs(y-c.y) > multiClickSlop {
+		c.count = 0
+	}
+	c.count++
+	c.at, c.x, c.y = now, x, y
+	return c.count
+}
+
+// startSelection starts a selection at (x, y) for the clicks-th of rapid
+// clicks: an empty one to drag out, the word under a double click or the
+// paragraph under a triple click.
+func (b *Browser) startSelection(x, y float64, clicks int) {
+	boxes := textBoxes(b.layoutTree)
+	caret, ok := caretAt(boxes, x, y)
+	if !ok {
+		return
+	}
+	sel := textSelection{anchor: caret, focus: caret}
+	switch clicks {
+	case 2:
+		box := boxes[boxIndex(boxes, caret.Node)]
+		line := textLines(box)[caret.Line]
+		start, end := wordBounds(line.text, metricsOf(box).clusterAt(line, x))
+		sel.anchor.Offset, sel.focus.Offset = start, end
+	case 3:
+		sel, _ = paragraphRange(boxes, caret.Node)
+	}
+	b.selection = &sel
+	b.selectedText = sel.text(boxes)
+}
+
+// selectedRanges returns the selected part of each text box's lines.
+func (b *Browser) selectedRanges() map[*dom.Node][]TextRange {
+	if b.selection == nil || b.selectedText == "" {
+		return nil
+	}
+	return b.selection.ranges(textBoxes(b.layoutTree))
+}
+
+// textLine is a line of a text box where paint draws it, in layout
+// coordinates.
+type textLine struct {
+	text        string
+	x, y        float64
+	height      float64
+	wordSpacing float64 // justification added to the style's word-spacing
+	softWrap    bool    // the line wraps onto the next rather than ending at a line feed
+}
+
+// textMetrics measures a text box's text as layout does, in the style of
+// the element it belongs to.
+type textMetrics struct {
+	style css.Style
+	size  float64
+	font  layout.Font
+}
+
+func metricsOf(box *layout.LayoutBox) textMetrics {
+	m := textMetrics{size: css.DefaultFontSize}
+	if box.Parent != nil {
+		m.style = box.Parent.Style
+	}
+	if m.style.FontSize > 0 {
+		m.size = m.style.FontSize
+	}
+	m.font = layout.StyleFont(m.style)
+	for p := box.Parent; p != nil; p = p.Parent {
+		if p.Node != nil && p.Node.TagName == dom.TagPre {
+			m.font.Monospace = true
+			break
+		}
+	}
+	return m
+}
+
+// width returns the width text takes up drawn on line.
+func (m textMetrics) width(text string, line textLine) float64 {
+	text = css.ApplyTextTransform(text, m.style.TextTransform, m.style.FontVariant)
+	if m.font.Monospace {
+		text = dom.ExpandTabs(text, 8)
+	}
+	return layout.MeasureStyledText(text, m.size, m.font, m.style.LetterSpacing, m.style.WordSpacing+line.wordSpacing)
+}
+
+// textLines returns the lines of a text box, laid out as paint draws them.
+func textLines(box *layout.LayoutBox) []textLine {
+	if box.Type != layout.TextBox || box.Text == "" {
+		return nil
+	}
+	m := metricsOf(box)
+	x, y := box.Rect.X, box.Rect.Y
+
+	if strings.Contains(box.Text, "\n") || len(box.WrappedLines) <= 1 {
+		if isListItem, _, index, listType := getListInfo(box); isListItem {
+			x += m.width(formatListMarker(index, listType)+" ", textLine{})
+		}
+	}
+
+	if strings.Contains(box.Text, "\n") {
+		var lines []textLine
+		lineHeight := m.size * 1.5
+		for i, text := range strings.Split(box.Text, "\n") {
+			lines = append(lines, textLine{text: text, x: x, y: y + float64(i)*lineHeight, height: lineHeight})
+			x = box.Rect.X
+		}
+		return lines
+	}
+	if len(box.WrappedLines) > 1 {
+		lineHeight := m.style.LineHeight
+		if lineHeight <= 0 {
+			lineHeight = layout.NormalLineHeight(m.size)
+		}
+		lines := make([]textLine, len(box.WrappedLines))
+		for i, text := range box.WrappedLines {
+			line := textLine{text: text, x: x, y: y + float64(i)*lineHeight, height: lineHeight, softWrap: i < len(box.WrappedLines)-1}
+			if i == 0 {
+				line.x += box.TextIndentPx
+			}
+			if i < len(box.LineOffsets) {
+				line.x += box.LineOffsets[i]
+			}
+			if i < len(box.JustifyWordSpacings) {
+				line.wordSpacing = box.JustifyWordSpacings[i]
+			}
+			lines[i] = line
+		}
+		return lines
+	}
+	return []textLine{{text: box.Text, x: x, y: y, height: box.Rect.Height}}
+}
+
+// caretX returns the x of the caret offset bytes into line.
+func (m textMetrics) caretX(line textLine, offset int) float64 {
+	return line.x + m.width(line.text[:offset], line)
+}
+
+// offsetAt returns the cluster boundary on line nearest to x.
+func (m textMetrics) offsetAt(line textLine, x float64) int {
+	best, bestDist := 0, math.Abs(x-line.x)
+	offset := 0
+	for _, cluster := range layout.GraphemeClusters(line.text) {
+		offset += len(cluster)
+		if d := math.Abs(x - m.caretX(line, offset)); d < bestDist {
+			best, bestDist = offset, d
+		}
+	}
+	return best
+}
+
+// clusterAt returns the offset of the cluster on line under x, the last
+// one past its end.
+func (m textMetrics) clusterAt(line textLine, x float64) int {
+	offset := 0
+	for _, cluster := range layout.GraphemeClusters(line.text) {
+		if x < m.caretX(line, offset+len(cluster)) {
+			return offset
+		}
+		offset += len(cluster)
+	}
+	return max(offset-1, 0)
+}
+
+// textBoxes returns the text boxes under root in document order.
+func textBoxes(root *layout.LayoutBox) []*layout.LayoutBox {
+	var boxes []*layout.LayoutBox
+	var walk func(box *layout.LayoutBox)
+	walk = func(box *layout.LayoutBox) {
+		if box.Type == layout.TextBox && box.Node != nil && box.Text != "" {
+			boxes = append(boxes, box)
+		}
+		for _, child := range box.Children {
+			walk(child)
+		}
+	}
+	if root != nil {
+		walk(root)
+	}
+	return boxes
+}
+
+// caretAt returns the caret position nearest to (x, y): in the line under
+// it, or else the line closest to it, vertically first.
+func caretAt(boxes []*layout.LayoutBox, x, y float64) (TextPosition, bool) {
+	var (
+		found      bool
+		best       TextPosition
+		bestDY     float64
+		bestDX     float64
+		bestBox    *layout.LayoutBox
+		bestLine   textLine
+		bestMetric textMetrics
+	)
+	for _, box := range boxes {
+		var m textMetrics
+		for i, line := range textLines(box) {
+			if m.size == 0 {
+				m = metricsOf(box)
+			}
+			dy := distance(y, line.y, line.y+line.height)
+			if found && dy > bestDY {
+				continue
+			}
+			dx := distance(x, line.x, line.x+m.width(line.text, line))
+			if !found || dy < bestDY || dx < bestDX {
+				found = true
+				bestDY, bestDX = dy, dx
+				best = TextPosition{Node: box.Node, Line: i}
+				bestBox, bestLine, bestMetric = box, line, m
+			}
+		}
+	}
+	if bestBox != nil {
+		best.Offset = bestMetric.offsetAt(bestLine, x)
+	}
+	return best, found
+}
+
+// distance returns how far v lies outside [lo, hi].
+func distance(v, lo, hi float64) float64 {
+	switch {
+	case v < lo:
+		return lo - v
+	case v > hi:
+		return v - hi
+	}
+	return 0
+}
+
+// boxIndex returns the index in boxes of the text box of node, or -1.
+func boxIndex(boxes []*layout.LayoutBox, node *dom.Node) int {
+	for i, box := range boxes {
+		if box.Node == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// orderedRange returns the selection's start and end in document order as
+// indexes into boxes with their positions. ok is false when either end is
+// no longer on the page.
+func (s textSelection) orderedRange(boxes []*layout.LayoutBox) (startBox, endBox int, start, end TextPosition, ok bool) {
+	a, f := boxIndex(boxes, s.anchor.Node), boxIndex(boxes, s.focus.Node)
+	if a < 0 || f < 0 {
+		return 0, 0, TextPosition{}, TextPosition{}, false
+	}
+	start, end = s.anchor, s.focus
+	if f < a || f == a && (end.Line < start.Line || end.Line == start.Line && end.Offset < start.Offset) {
+		a, f = f, a
+		start, end = end, start
+	}
+	return a, f, start, end, true
+}
+
+// ranges returns the selected part of each line of each text box.
+func (s textSelection) ranges(boxes []*layout.LayoutBox) map[*dom.Node][]TextRange {
+	first, last, start, end, ok := s.orderedRange(boxes)
+	if !ok {
+		return nil
+	}
+	selected := make(map[*dom.Node][]TextRange)
+	for i := first; i <= last; i++ {
+		lines := textLines(boxes[i])
+		for l, line := range lines {
+			r := TextRange{Line: l, Start: 0, End: len(line.text)}
+			if i == first {
+				if l < start.Line {
+					continue
+				}
+				if l == start.Line {
+					r.Start = min(start.Offset, r.End)
+				}
+			}
+			if i == last {
+				if l > end.Line {
+					break
+				}
+				if l == end.Line {
+					r.End = min(end.Offset, r.End)
+				}
+			}
+			if r.Start < r.End {
+				selected[boxes[i].Node] = append(selected[boxes[i].Node], r)
+			}
+		}
+	}
+	return selected
+}
+
+// text returns the selected text in document order. Lines that wrap are
+// joined by a space, lines ending at a line feed and text in different
+// blocks by a line feed.
+func (s textSelection) text(boxes []*layout.LayoutBox) string {
+	first, last, start, end, ok := s.orderedRange(boxes)
+	if !ok {
+		return ""
+	}
+	var sb strings.Builder
+	var prevBlock *layout.LayoutBox
+	for i := first; i <= last; i++ {
+		box := boxes[i]
+		if block := textBlock(box); i > first && block != prevBlock {
+			sb.WriteByte('\n')
+		}
+		prevBlock = textBlock(box)
+
+		lines := textLines(box)
+		from, to := 0, len(lines)-1
+		if i == first {
+			from = min(start.Line, to)
+		}
+		if i == last {
+			to = min(end.Line, to)
+		}
+		for l := from; l <= to; l++ {
+			line := lines[l]
+			lo, hi := 0, len(line.text)
+			if i == first && l == start.Line {
+				lo = min(start.Offset, hi)
+			}
+			if i == last && l == end.Line {
+				hi = min(end.Offset, hi)
+			}
+			if lo < hi {
+				sb.WriteString(line.text[lo:hi])
+			}
+			if l < to {
+				sb.WriteString(lineBreak(line))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// lineBreak returns what stands between line and the next line of its box
+// in copied text.
+func lineBreak(line textLine) string {
+	if !line.softWrap {
+		return "\n"
+	}
+	// Lines broken after a hyphen or between ideographs join as they are
+	if r, _ := utf8.DecodeLastRuneInString(line.text); r == '-' || r == '/' || r == '\u00ad' || unicode.Is(unicode.Han, r) {
+		return ""
+	}
+	return " "
+}
+
+// textBlock returns the block a text box flows in: its nearest ancestor
+// that is not an inline box.
+func textBlock(box *layout.LayoutBox) *layout.LayoutBox {
+	p := box.Parent
+	for p != nil && p.Type == layout.InlineBox {
+		p = p.Parent
+	}
+	return p
+}
+
+// wordBounds returns the word around offset in text: a run of letters and
+// digits, or of spaces, or a single other cluster.
+func wordBounds(text string, offset int) (start, end int) {
+	clusters := layout.GraphemeClusters(text)
+	if len(clusters) == 0 {
+		return 0, 0
+	}
+	starts := make([]int, len(clusters)+1)
+	at := len(clusters) - 1
+	for i, c := range clusters {
+		starts[i+1] = starts[i] + len(c)
+		if offset >= starts[i] && offset < starts[i+1] {
+			at = i
+		}
+	}
+	class := clusterClass(clusters[at])
+	lo, hi := at, at+1
+	if class != 0 {
+		for lo > 0 && clusterClass(clusters[lo-1]) == class {
+			lo--
+		}
+		for hi < len(clusters) && clusterClass(clusters[hi]) == class {
+			hi++
+		}
+	}
+	return starts[lo], starts[hi]
+}
+
+// clusterClass groups clusters into words: 1 for word characters, 2 for
+// spaces and 0 for anything else, which stands alone.
+func clusterClass(cluster string) int {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\'':
+		return 1
+	case unicode.IsSpace(r):
+		return 2
+	}
+	return 0
+}
+
+// paragraphRange returns the selection of all the text in the block that
+// the text box of node flows in.
+func paragraphRange(boxes []*layout.LayoutBox, node *dom.Node) (textSelection, bool) {
+	i := boxIndex(boxes, node)
+	if i < 0 {
+		return textSelection{}, false
+	}
+	block := textBlock(boxes[i])
+	first, last := i, i
+	for first > 0 && isInside(boxes[first-1], block) {
+		first--
+	}
+	for last < len(boxes)-1 && isInside(boxes[last+1], block) {
+		last++
+	}
+	lines := textLines(boxes[last])
+	end := TextPosition{Node: boxes[last].Node, Line: len(lines) - 1}
+	if len(lines) > 0 {
+		end.Offset = len(lines[len(lines)-1].text)
+	}
+	return textSelection{anchor: TextPosition{Node: boxes[first].Node}, focus: end}, true
+}
+
+// isInside reports whether box is a descendant of ancestor.
+func isInside(box, ancestor *layout.LayoutBox) bool {
+	for p := box.Parent; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionHighlights returns the highlights behind the selected parts of
+// a text box drawn at rect.
+func selectionHighlights(box *layout.LayoutBox, rect layout.Rect, selected []TextRange) []DisplayCommand {
+	if len(selected) == 0 {
+		return nil
+	}
+	m := metricsOf(box)
+	lines := textLines(box)
+	dx, dy := rect.X-box.Rect.X, rect.Y-box.Rect.Y
+	var commands []DisplayCommand
+	for _, r := range selected {
+		if r.Line >= len(lines) {
+			continue
+		}
+		line := lines[r.Line]
+		start := m.caretX(line, min(r.Start, len(line.text)))
+		end := m.caretX(line, min(r.End, len(line.text)))
+		commands = append(commands, DrawRect{
+			Rect:  layout.Rect{X: start + dx, Y: line.y + dy, Width: end - start, Height: line.height},
+			Color: ColorSelection,
+		})
+	}
+	return commands
+}
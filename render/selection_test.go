@@ -0,0 +1,145 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"browser/css"
+	"browser/dom"
+	"browser/layout"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// selectionPage lays out two paragraphs at 16px, where text measures 8px a
+// character: a wrapped one with a bold word, then a single line.
+//
+//	y 0  Hello  (wrapped, line height 20)
+//	y 20 world <b>big</b>
+//	y 40 Second para
+func selectionPage(t *testing.T) (boxes []*layout.LayoutBox, first, bold, second *layout.LayoutBox) {
+	originalMeasurer := layout.TextMeasurer
+	layout.TextMeasurer = nil
+	t.Cleanup(func() { layout.TextMeasurer = originalMeasurer })
+
+	style := css.Style{FontSize: 16, LineHeight: 20}
+	root := &layout.LayoutBox{Type: layout.BlockBox}
+	p1 := &layout.LayoutBox{Type: layout.BlockBox, Style: style, Parent: root}
+	first = &layout.LayoutBox{
+		Type: layout.TextBox, Node: &dom.Node{Type: dom.Text}, Parent: p1, Text: "Hello world ",
+		WrappedLines: []string{"Hello", "world "}, Rect: layout.Rect{Width: 48, Height: 40},
+	}
+	b := &layout.LayoutBox{Type: layout.InlineBox, Style: style, Parent: p1}
+	bold = &layout.LayoutBox{Type: layout.TextBox, Node: &dom.Node{Type: dom.Text}, Parent: b, Text: "big", Rect: layout.Rect{X: 48, Y: 20, Width: 24, Height: 20}}
+	b.Children = []*layout.LayoutBox{bold}
+	p1.Children = []*layout.LayoutBox{first, b}
+	p2 := &layout.LayoutBox{Type: layout.BlockBox, Style: style, Parent: root}
+	second = &layout.LayoutBox{Type: layout.TextBox, Node: &dom.Node{Type: dom.Text}, Parent: p2, Text: "Second para", Rect: layout.Rect{Y: 40, Width: 88, Height: 20}}
+	p2.Children = []*layout.LayoutBox{second}
+	root.Children = []*layout.LayoutBox{p1, p2}
+	return textBoxes(root), first, bold, second
+}
+
+func TestCaretAt(t *testing.T) {
+	boxes, first, bold, second := selectionPage(t)
+	tests := []struct {
+		name string
+		x, y float64
+		want TextPosition
+	}{
+		{"nearest cluster boundary", 19, 5, TextPosition{first.Node, 0, 2}},
+		{"second wrapped line", 3, 25, TextPosition{first.Node, 1, 0}},
+		{"inline box", 61, 30, TextPosition{bold.Node, 0, 2}},
+		{"past the end of a line", 200, 45, TextPosition{second.Node, 0, 11}},
+		{"below the text", 10, 500, TextPosition{second.Node, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := caretAt(boxes, tt.x, tt.y)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSelectionText(t *testing.T) {
+	boxes, first, bold, second := selectionPage(t)
+	tests := []struct {
+		name          string
+		anchor, focus TextPosition
+		want          string
+	}{
+		{"part of a word", TextPosition{first.Node, 0, 1}, TextPosition{first.Node, 0, 4}, "ell"},
+		{"across a wrap", TextPosition{first.Node, 0, 3}, TextPosition{first.Node, 1, 3}, "lo wor"},
+		{"into an inline box", TextPosition{first.Node, 1, 0}, TextPosition{bold.Node, 0, 2}, "world bi"},
+		{"across blocks", TextPosition{bold.Node, 0, 1}, TextPosition{second.Node, 0, 3}, "ig\nSec"},
+		{"dragged backward", TextPosition{second.Node, 0, 3}, TextPosition{bold.Node, 0, 1}, "ig\nSec"},
+		{"collapsed", TextPosition{first.Node, 0, 2}, TextPosition{first.Node, 0, 2}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := textSelection{anchor: tt.anchor, focus: tt.focus}
+			assert.Equal(t, tt.want, sel.text(boxes))
+		})
+	}
+}
+
+func TestSelectionRanges(t *testing.T) {
+	boxes, first, bold, second := selectionPage(t)
+	sel := textSelection{anchor: TextPosition{second.Node, 0, 3}, focus: TextPosition{first.Node, 0, 3}}
+	assert.Equal(t, map[*dom.Node][]TextRange{
+		first.Node:  {{Line: 0, Start: 3, End: 5}, {Line: 1, Start: 0, End: 6}},
+		bold.Node:   {{Line: 0, Start: 0, End: 3}},
+		second.Node: {{Line: 0, Start: 0, End: 3}},
+	}, sel.ranges(boxes))
+
+	highlights := selectionHighlights(first, first.Rect, sel.ranges(boxes)[first.Node])
+	assert.Equal(t, []DisplayCommand{
+		DrawRect{Rect: layout.Rect{X: 24, Y: 0, Width: 16, Height: 20}, Color: ColorSelection},
+		DrawRect{Rect: layout.Rect{X: 0, Y: 20, Width: 48, Height: 20}, Color: ColorSelection},
+	}, highlights)
+}
+
+func TestWordBounds(t *testing.T) {
+	tests := []struct {
+		name       string
+		offset     int
+		start, end int
+	}{
+		{"inside a word", 8, 6, 11},
+		{"start of a word", 0, 0, 5},
+		{"run of spaces", 13, 12, 15},
+		{"punctuation stands alone", 11, 11, 12},
+		{"past the end", 25, 15, 19},
+	}
+	const text = "Hello world,   it's"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := wordBounds(text, tt.offset)
+			assert.Equal(t, text[tt.start:tt.end], text[start:end])
+		})
+	}
+}
+
+func TestParagraphRange(t *testing.T) {
+	boxes, first, bold, second := selectionPage(t)
+
+	sel, ok := paragraphRange(boxes, bold.Node)
+	assert.True(t, ok)
+	assert.Equal(t, first.Node, sel.anchor.Node)
+	assert.Equal(t, "Hello world big", sel.text(boxes))
+
+	sel, _ = paragraphRange(boxes, second.Node)
+	assert.Equal(t, "Second para", sel.text(boxes))
+}
+
+func TestClickCounter(t *testing.T) {
+	var c clickCounter
+	now := time.Now()
+	assert.Equal(t, 1, c.press(10, 10, now))
+	assert.Equal(t, 2, c.press(11, 10, now.Add(200*time.Millisecond)))
+	assert.Equal(t, 3, c.press(11, 11, now.Add(400*time.Millisecond)))
+	assert.Equal(t, 1, c.press(11, 11, now.Add(600*time.Millisecond)), "starts over after a triple click")
+	assert.Equal(t, 1, c.press(40, 11, now.Add(700*time.Millisecond)), "moved away")
+	assert.Equal(t, 1, c.press(40, 11, now.Add(2*time.Second)), "too slow")
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"browser/layout"
+	"sort"
+)
+
+// stackingContext collects the descendants of a stacking context root that
+// paint above or below its normal flow (CSS 2.1 Appendix E).
+type stackingContext struct {
+	negative []stackedBox // z-index < 0, painted before the flow
+	positive []stackedBox // z-index > 0, painted after everything else
+}
+
+// stackedBox is a box whose painting is deferred, with the inherited style
+// and fixed state it would have been painted with in tree order.
+type stackedBox struct {
+	box     *layout.LayoutBox
+	style   TextStyle
+	isFixed bool
+}
+
+// isPositionedBox reports whether box is positioned (CSS 2.1 §9.3.1).
+func isPositionedBox(box *layout.LayoutBox) bool {
+	switch box.Position {
+	case "relative", "absolute", "fixed", "sticky":
+		return true
+	}
+	return false
+}
+
+// stackLevel returns the z-index a box paints at within its parent stacking
+// context; z-index only applies to positioned boxes.
+func stackLevel(box *layout.LayoutBox) int {
+	if isPositionedBox(box) && box.Style.ZIndexSet {
+		return box.Style.ZIndex
+	}
+	return 0
+}
+
+// createsStackingContext reports whether box is the root of a stacking
+// context: positioned with an integer z-index, fixed, translucent or
+// filtered.
+func createsStackingContext(box *layout.LayoutBox) bool {
+	if (isPositionedBox(box) && box.Style.ZIndexSet) || len(box.Style.Filters) > 0 {
+		return true
+	}
+	return box.Position == "fixed" || (box.Style.Opacity > 0 && box.Style.Opacity < 1)
+}
+
+// paintsAboveFlow reports whether box paints after the normal flow of its
+// stacking context: positioned boxes and stacking contexts at level 0.
+func paintsAboveFlow(box *layout.LayoutBox) bool {
+	return isPositionedBox(box) || createsStackingContext(box)
+}
+
+// paintStacked paints deferred boxes in order of z-index, keeping tree order
+// among equal levels.
+func paintStacked(boxes []stackedBox, commands *[]DisplayCommand, state InputState, linkStyler LinkStyler, layer paintLayer) {
+	sort.SliceStable(boxes, func(i, j int) bool {
+		return stackLevel(boxes[i].box) < stackLevel(boxes[j].box)
+	})
+	for _, s := range boxes {
+		paintLayoutBox(s.box, commands, s.style, state, linkStyler, layer, s.isFixed)
+	}
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// paintOrder names the background rects in commands by their width, in
+// the order they are painted.
+func paintOrder(commands []DisplayCommand, names map[float64]string) []string {
+	var order []string
+	for _, cmd := range commands {
+		if dr, ok := cmd.(DrawRect); ok {
+			if name, ok := names[dr.Width]; ok {
+				order = append(order, name)
+			}
+		}
+	}
+	return order
+}
+
+func TestStackingOrder(t *testing.T) {
+	names := map[float64]string{101: "a", 102: "b", 103: "c", 104: "inner"}
+	base := `div { height: 20px; background: gray; } #a { width: 101px; } #b { width: 102px; } #c { width: 103px; } #inner { width: 104px; } `
+
+	tests := []struct {
+		name string
+		html string
+		css  string
+		want []string
+	}{
+		{
+			name: "tree order without positioning",
+			html: `<div id="a"></div><div id="b"></div><div id="c"></div>`,
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "positioned boxes paint above the flow",
+			html: `<div id="a"></div><div id="b"></div><div id="c"></div>`,
+			css:  `#a { position: relative; }`,
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "higher z-index paints later",
+			html: `<div id="a"></div><div id="b"></div><div id="c"></div>`,
+			css:  `#a { position: relative; z-index: 2; } #b { position: relative; z-index: 1; }`,
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "negative z-index paints below the flow",
+			html: `<div id="a"></div><div id="b"></div><div id="c"></div>`,
+			css:  `#c { position: relative; z-index: -1; }`,
+			want: []string{"c", "a", "b"},
+		},
+		{
+			name: "z-index is ignored on static boxes",
+			html: `<div id="a"></div><div id="b"></div><div id="c"></div>`,
+			css:  `#a { z-index: 5; }`,
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "a stacking context keeps its descendants together",
+			html: `<div id="a"><div id="inner"></div></div><div id="b"></div>`,
+			css:  `#a { position: relative; z-index: 1; } #inner { position: relative; z-index: 100; } #b { position: relative; z-index: 2; }`,
+			want: []string{"a", "inner", "b"},
+		},
+		{
+			name: "positioned descendants of static boxes join the outer context",
+			html: `<div id="a"><div id="inner"></div></div><div id="b"></div>`,
+			css:  `#inner { position: relative; }`,
+			want: []string{"a", "b", "inner"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildLayout(tt.html, base+tt.css, 800)
+			commands := BuildDisplayList(root, InputState{}, LinkStyler{})
+			assert.Equal(t, tt.want, paintOrder(commands, names))
+		})
+	}
+}
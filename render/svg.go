@@ -0,0 +1,353 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"browser/css"
+	"browser/dom"
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"github.com/fyne-io/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// SVG images, from <img src="*.svg"> and inline <svg> elements alike.
+// oksvg parses and draws the shapes: paths, rects, circles, ellipses,
+// lines, polygons, gradients, the viewBox and fill and stroke. Text, which
+// it skips, is drawn over them here in the theme font.
+
+// DrawSVG draws an inline <svg> element, serialized as markup, fitted into
+// Rect. currentColor in the markup is Color.
+type DrawSVG struct {
+	layout.Rect
+	Markup string
+	Color  color.Color
+}
+
+// defaultSVGSize is the size of an SVG image with neither a viewBox nor a
+// width and height.
+const defaultSVGSize = 64
+
+// svgMarkup serializes the inline <svg> element node and its content as
+// an SVG document. Its width and height are left out, as it is drawn at
+// the size of its box.
+func svgMarkup(node *dom.Node) string {
+	var sb strings.Builder
+	writeSVGNode(&sb, node, true)
+	return sb.String()
+}
+
+func writeSVGNode(sb *strings.Builder, node *dom.Node, root bool) {
+	if node.Type == dom.Text {
+		xml.EscapeText(sb, []byte(node.Text))
+		return
+	}
+	if node.Type != dom.Element {
+		return
+	}
+	sb.WriteString("<" + node.TagName)
+	if root {
+		sb.WriteString(` xmlns="http://www.w3.org/2000/svg"`)
+	}
+	names := make([]string, 0, len(node.Attributes))
+	for name := range node.Attributes {
+		if root && (name == "width" || name == "height" || name == "xmlns") {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names) // the same element always gives the same markup
+	for _, name := range names {
+		sb.WriteString(" " + name + `="`)
+		xml.EscapeText(sb, []byte(node.Attributes[name]))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(">")
+	for _, child := range node.Children {
+		writeSVGNode(sb, child, false)
+	}
+	sb.WriteString("</" + node.TagName + ">")
+}
+
+// decodeSVG draws an SVG image at its natural size: that of its viewBox,
+// or failing that its width and height.
+func decodeSVG(data []byte) (image.Image, error) {
+	return rasterizeSVG(data, 0, 0, color.Black)
+}
+
+// rasterizeSVG draws an SVG document into a width by height image, its
+// viewBox scaled to fit and centered (preserveAspectRatio's default,
+// xMidYMid meet). A zero size draws at the natural size. currentColor is
+// the color fills and strokes of currentColor take.
+func rasterizeSVG(data []byte, width, height int, currentColor color.Color) (image.Image, error) {
+	icon, err := oksvg.ReadReplacingCurrentColor(bytes.NewReader(data), hexColor(currentColor))
+	if err != nil {
+		return nil, err
+	}
+	vb := icon.ViewBox
+	if width <= 0 || height <= 0 {
+		width, height = int(math.Ceil(vb.W)), int(math.Ceil(vb.H))
+		if width <= 0 {
+			width = defaultSVGSize
+		}
+		if height <= 0 {
+			height = defaultSVGSize
+		}
+	}
+	if vb.W <= 0 || vb.H <= 0 {
+		vb.X, vb.Y, vb.W, vb.H = 0, 0, float64(width), float64(height)
+	}
+
+	fit := svgFit{scale: min(float64(width)/vb.W, float64(height)/vb.H)}
+	fit.x = (float64(width)-vb.W*fit.scale)/2 - vb.X*fit.scale
+	fit.y = (float64(height)-vb.H*fit.scale)/2 - vb.Y*fit.scale
+	icon.Transform = rasterx.Identity.Translate(fit.x, fit.y).Scale(fit.scale, fit.scale)
+
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	scanner := rasterx.NewScannerGV(width, height, rgba, rgba.Bounds())
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1.0)
+	drawSVGText(rgba, data, fit, currentColor)
+	return rgba, nil
+}
+
+// svgFit maps SVG user units to image pixels: scaled, then offset.
+type svgFit struct {
+	scale, x, y float64
+}
+
+func (f svgFit) point(x, y float64) (float64, float64) {
+	return f.x + x*f.scale, f.y + y*f.scale
+}
+
+// hexColor formats c as #rrggbb, the form oksvg takes for currentColor.
+func hexColor(c color.Color) string {
+	if c == nil {
+		c = color.Black
+	}
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	if rgba.A > 0 && rgba.A < 255 {
+		// Undo the premultiplication
+		rgba.R = uint8(uint16(rgba.R) * 255 / uint16(rgba.A))
+		rgba.G = uint8(uint16(rgba.G) * 255 / uint16(rgba.A))
+		rgba.B = uint8(uint16(rgba.B) * 255 / uint16(rgba.A))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
+
+// svgTextStyle is what a <text> element draws with, inherited down the
+// document.
+type svgTextStyle struct {
+	fill   string
+	size   float64
+	anchor string
+	bold   bool
+	dx, dy float64 // translate() of the enclosing groups
+	hidden bool    // inside <defs> and the like, which draw nothing
+}
+
+// svgText is a <text> element: its characters and where they start.
+type svgText struct {
+	style svgTextStyle
+	x, y  float64
+	text  strings.Builder
+}
+
+// drawSVGText draws the <text> elements of the SVG document data into
+// img. Each draws as a single line at its x and y; of transforms, only
+// translations are followed, and text under any other is left out.
+func drawSVGText(img *image.RGBA, data []byte, fit svgFit, currentColor color.Color) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	stack := []svgTextStyle{{fill: "black", size: 16, anchor: "start"}}
+	var text *svgText
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF || err != nil {
+			return
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			style := stack[len(stack)-1]
+			applySVGTextStyle(&style, t)
+			stack = append(stack, style)
+			if t.Name.Local == "text" && text == nil {
+				text = &svgText{style: style}
+				text.x, _ = svgNumber(svgAttr(t, "x"))
+				text.y, _ = svgNumber(svgAttr(t, "y"))
+			}
+		case xml.CharData:
+			if text != nil {
+				text.text.Write(t)
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if t.Name.Local == "text" && text != nil {
+				drawSVGTextRun(img, text, fit, currentColor)
+				text = nil
+			}
+		}
+	}
+}
+
+// applySVGTextStyle updates style with the presentation attributes and
+// style declarations of element that text drawing uses.
+func applySVGTextStyle(style *svgTextStyle, element xml.StartElement) {
+	switch element.Name.Local {
+	case "defs", "symbol", "clipPath", "mask", "pattern", "marker", "title", "desc":
+		style.hidden = true
+	}
+	properties := map[string]string{}
+	for _, attr := range element.Attr {
+		properties[attr.Name.Local] = attr.Value
+	}
+	for _, decl := range strings.Split(svgAttr(element, "style"), ";") {
+		if name, value, ok := strings.Cut(decl, ":"); ok {
+			properties[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	if fill, ok := properties["fill"]; ok {
+		style.fill = fill
+	}
+	if size, ok := svgNumber(properties["font-size"]); ok {
+		style.size = size
+	}
+	if anchor, ok := properties["text-anchor"]; ok {
+		style.anchor = anchor
+	}
+	if weight, ok := properties["font-weight"]; ok {
+		n, err := strconv.Atoi(weight)
+		style.bold = weight == "bold" || weight == "bolder" || (err == nil && css.IsBold(n))
+	}
+	if properties["display"] == "none" {
+		style.hidden = true
+	}
+	if transform, ok := properties["transform"]; ok {
+		dx, dy, ok := svgTranslation(transform)
+		style.dx += dx
+		style.dy += dy
+		style.hidden = style.hidden || !ok
+	}
+}
+
+// svgTranslation returns the offset of a transform made of translations
+// only, and false for any other.
+func svgTranslation(transform string) (dx, dy float64, ok bool) {
+	rest := strings.TrimSpace(transform)
+	for rest != "" {
+		args, ok := strings.CutPrefix(rest, "translate(")
+		if !ok {
+			return 0, 0, false
+		}
+		args, rest, ok = strings.Cut(args, ")")
+		if !ok {
+			return 0, 0, false
+		}
+		fields := strings.Fields(strings.ReplaceAll(args, ",", " "))
+		if len(fields) == 0 || len(fields) > 2 {
+			return 0, 0, false
+		}
+		x, okX := svgNumber(fields[0])
+		y := 0.0
+		okY := true
+		if len(fields) == 2 {
+			y, okY = svgNumber(fields[1])
+		}
+		if !okX || !okY {
+			return 0, 0, false
+		}
+		dx, dy = dx+x, dy+y
+		rest = strings.TrimSpace(rest)
+	}
+	return dx, dy, true
+}
+
+// drawSVGTextRun draws one <text> element into img.
+func drawSVGTextRun(img *image.RGBA, text *svgText, fit svgFit, currentColor color.Color) {
+	style := text.style
+	content := strings.Join(strings.Fields(text.text.String()), " ")
+	if style.hidden || content == "" || style.fill == "none" {
+		return
+	}
+	fill := currentColor
+	if style.fill != "currentColor" {
+		fill = css.ParseColor(style.fill)
+	}
+	if fill == nil {
+		return
+	}
+	face := svgFontFace(style.bold, style.size*fit.scale)
+	if face == nil {
+		return
+	}
+	x, y := fit.point(text.x+style.dx, text.y+style.dy)
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(fill), Face: face}
+	width := float64(drawer.MeasureString(content)) / 64
+	switch style.anchor {
+	case "middle":
+		x -= width / 2
+	case "end":
+		x -= width
+	}
+	drawer.Dot = fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}
+	drawer.DrawString(content)
+}
+
+// svgFonts holds the parsed theme fonts SVG text is drawn in.
+var svgFonts sync.Map // fyne.Resource name -> *opentype.Font
+
+// svgFontFace returns the theme font at size pixels, or nil if it cannot
+// be read.
+func svgFontFace(bold bool, size float64) font.Face {
+	var res fyne.Resource = theme.TextFont()
+	if bold {
+		res = theme.TextBoldFont()
+	}
+	parsed, ok := svgFonts.Load(res.Name())
+	if !ok {
+		f, err := opentype.Parse(res.Content())
+		if err != nil {
+			return nil
+		}
+		parsed, _ = svgFonts.LoadOrStore(res.Name(), f)
+	}
+	face, err := opentype.NewFace(parsed.(*opentype.Font), &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	return face
+}
+
+// svgAttr returns the value of element's attribute name, "" if missing.
+func svgAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// svgNumber parses a length in user units, dropping a px unit. Lists of
+// numbers give their first.
+func svgNumber(value string) (float64, bool) {
+	fields := strings.Fields(strings.ReplaceAll(value, ",", " "))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "px"), 64)
+	return n, err == nil
+}
@@ -0,0 +1,116 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"browser/dom"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRasterizeSVG(t *testing.T) {
+	pixel := func(img image.Image, x, y int) color.NRGBA {
+		return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+	}
+	red := color.NRGBA{255, 0, 0, 255}
+
+	t.Run("shapes", func(t *testing.T) {
+		img, err := rasterizeSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">
+			<rect x="0" y="0" width="50" height="50" fill="red"/>
+			<circle cx="75" cy="75" r="20" fill="blue" stroke="black" stroke-width="4"/>
+		</svg>`), 100, 100, color.Black)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 100, 100), img.Bounds())
+		assert.Equal(t, red, pixel(img, 25, 25))
+		assert.Equal(t, color.NRGBA{0, 0, 255, 255}, pixel(img, 75, 75))
+		assert.Equal(t, color.NRGBA{0, 0, 0, 255}, pixel(img, 75, 55), "stroke around the circle")
+		assert.Zero(t, pixel(img, 75, 25).A, "nothing drawn there")
+	})
+
+	t.Run("viewBox scales to fit", func(t *testing.T) {
+		img, err := rasterizeSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="10 10 20 20"><rect x="10" y="10" width="10" height="10" fill="red"/></svg>`), 200, 100, color.Black)
+		require.NoError(t, err)
+		// Scaled by 5 and centered across the width
+		assert.Equal(t, red, pixel(img, 75, 25))
+		assert.Zero(t, pixel(img, 25, 25).A)
+		assert.Zero(t, pixel(img, 125, 75).A)
+	})
+
+	t.Run("natural size", func(t *testing.T) {
+		img, err := decodeSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 12"/>`))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 24, 12), img.Bounds())
+	})
+
+	t.Run("currentColor", func(t *testing.T) {
+		img, err := rasterizeSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect width="10" height="10" fill="currentColor"/></svg>`), 10, 10, color.RGBA{0, 128, 0, 255})
+		require.NoError(t, err)
+		assert.Equal(t, color.NRGBA{0, 128, 0, 255}, pixel(img, 5, 5))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		const doc = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 40"><g transform="translate(0 5)"><text x="50" y="25" font-size="20" text-anchor="middle" fill="red">Hi</text></g></svg>`
+		img, err := rasterizeSVG([]byte(doc), 100, 40, color.Black)
+		require.NoError(t, err)
+		inked := image.Rectangle{Min: image.Pt(100, 40)}
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 100; x++ {
+				if p := pixel(img, x, y); p.A > 128 {
+					assert.Equal(t, uint8(255), p.R)
+					inked = inked.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+		require.False(t, inked.Empty(), "the text is drawn")
+		assert.LessOrEqual(t, inked.Max.Y, 31, "on a baseline of 30")
+		assert.InDelta(t, 50, (inked.Min.X+inked.Max.X)/2, 4, "centered on x")
+	})
+
+	t.Run("text in defs is not drawn", func(t *testing.T) {
+		img, err := rasterizeSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 40"><defs><text x="0" y="30" font-size="30">Hidden</text></defs></svg>`), 100, 40, color.Black)
+		require.NoError(t, err)
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 100; x++ {
+				assert.Zero(t, pixel(img, x, y).A)
+			}
+		}
+	})
+}
+
+func TestSVGMarkup(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<p><svg width="20" height="10" viewBox="0 0 20 10"><a xlink:href="#x"><text y="8">a &lt; b</text></a></svg></p>`))
+	var svg *dom.Node
+	var find func(*dom.Node)
+	find = func(n *dom.Node) {
+		if n.TagName == dom.TagSvg {
+			svg = n
+		}
+		for _, c := range n.Children {
+			find(c)
+		}
+	}
+	find(doc)
+	require.NotNil(t, svg)
+	assert.Equal(t, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10"><a xlink:href="#x"><text y="8">a &lt; b</text></a></svg>`, svgMarkup(svg))
+}
+
+func TestPaintInlineSVG(t *testing.T) {
+	root := buildLayout(`<svg width="40" height="20" viewBox="0 0 40 20"><rect width="40" height="20"/></svg>`, `svg { color: red }`, 400)
+	normal, _ := BuildDisplayLists(root, InputState{}, LinkStyler{})
+	var svgs []DrawSVG
+	for _, cmd := range normal.Commands {
+		if c, ok := cmd.(DrawSVG); ok {
+			svgs = append(svgs, c)
+		}
+	}
+	require.Len(t, svgs, 1)
+	assert.Equal(t, 40.0, svgs[0].Width)
+	assert.Equal(t, 20.0, svgs[0].Height)
+	assert.Contains(t, svgs[0].Markup, `<rect height="20" width="40"></rect>`)
+	r, g, b, _ := svgs[0].Color.RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b})
+}
@@ -0,0 +1,388 @@
+package render
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"browser/dom"
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// Text field editing. The focused input or textarea has a caret and a
+// selection running from an anchor to the caret, both byte offsets into its
+// value on grapheme cluster boundaries. Arrow keys move the caret a cluster,
+// Up and Down a line in a textarea, Home and End to the ends of the line;
+// with Shift held they extend the selection instead. Typing, pasting and
+// deleting replace the selection. A single-line field scrolls its text
+// sideways to keep the caret in view, and the caret blinks while the field
+// has focus.
+
+const (
+	caretBlinkInterval = 530 * time.Millisecond
+	fieldTextSize      = 14 // font size of field text (see renderTextFieldObjects)
+	fieldPadding       = 6  // from a field's edge to its text
+	fieldLineHeight    = 18 // between the lines of a textarea
+)
+
+// FieldEdit is the caret and selection of a text field. Nothing is selected
+// when Anchor equals Caret.
+type FieldEdit struct {
+	Caret, Anchor int
+	ScrollX       float64 // how far a single-line field's text is scrolled left
+}
+
+// Selection returns the selected bytes of the value, start to end.
+func (e FieldEdit) Selection() (start, end int) {
+	return min(e.Caret, e.Anchor), max(e.Caret, e.Anchor)
+}
+
+// clamp keeps the caret and anchor within a value of length n.
+func (e FieldEdit) clamp(n int) FieldEdit {
+	e.Caret, e.Anchor = min(max(e.Caret, 0), n), min(max(e.Anchor, 0), n)
+	return e
+}
+
+// collapse moves both caret and anchor to offset.
+func (e FieldEdit) collapse(offset int) FieldEdit {
+	e.Caret, e.Anchor = offset, offset
+	return e
+}
+
+// replaceSelection replaces the selection in value with text, leaving the
+// caret after it.
+func replaceSelection(value string, e FieldEdit, text string) (string, FieldEdit) {
+	start, end := e.Selection()
+	return value[:start] + text + value[end:], e.collapse(start + len(text))
+}
+
+// deleteBackward deletes the selection, or else the cluster before the caret.
+func deleteBackward(value string, e FieldEdit) (string, FieldEdit) {
+	if e.Caret == e.Anchor {
+		e.Anchor = prevClusterStart(value, e.Caret)
+	}
+	return replaceSelection(value, e, "")
+}
+
+// deleteForward deletes the selection, or else the cluster after the caret.
+func deleteForward(value string, e FieldEdit) (string, FieldEdit) {
+	if e.Caret == e.Anchor {
+		e.Anchor = nextClusterEnd(value, e.Caret)
+	}
+	return replaceSelection(value, e, "")
+}
+
+// prevClusterStart returns the start of the cluster that ends at offset.
+func prevClusterStart(value string, offset int) int {
+	clusters := layout.GraphemeClusters(value[:offset])
+	if len(clusters) == 0 {
+		return 0
+	}
+	return offset - len(clusters[len(clusters)-1])
+}
+
+// nextClusterEnd returns the end of the cluster that starts at offset.
+func nextClusterEnd(value string, offset int) int {
+	clusters := layout.GraphemeClusters(value[offset:])
+	if len(clusters) == 0 {
+		return offset
+	}
+	return offset + len(clusters[0])
+}
+
+// lineBounds returns the start and end of the line of value around offset.
+func lineBounds(value string, offset int) (start, end int) {
+	start = strings.LastIndexByte(value[:offset], '\n') + 1
+	end = len(value)
+	if i := strings.IndexByte(value[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	return start, end
+}
+
+// moveCaret returns the edit after key moves the caret in value, extending
+// the selection when extend is set. Up and Down move between lines when
+// multiline is set. ok is false for keys that do not move the caret.
+func moveCaret(value string, e FieldEdit, key fyne.KeyName, extend, multiline bool) (edit FieldEdit, ok bool) {
+	start, end := e.Selection()
+	lineStart, lineEnd := lineBounds(value, e.Caret)
+	caret := e.Caret
+	switch key {
+	case fyne.KeyLeft:
+		caret = prevClusterStart(value, caret)
+		if !extend && start != end {
+			caret = start // a selection collapses to its start
+		}
+	case fyne.KeyRight:
+		caret = nextClusterEnd(value, caret)
+		if !extend && start != end {
+			caret = end
+		}
+	case fyne.KeyHome:
+		caret = lineStart
+	case fyne.KeyEnd:
+		caret = lineEnd
+	case fyne.KeyUp, fyne.KeyDown:
+		if !multiline {
+			return e, false
+		}
+		column := layout.ClusterCount(value[lineStart:caret])
+		if key == fyne.KeyUp {
+			if lineStart == 0 {
+				caret = 0
+				break
+			}
+			lineStart, lineEnd = lineBounds(value, lineStart-1)
+		} else {
+			if lineEnd == len(value) {
+				caret = len(value)
+				break
+			}
+			lineStart, lineEnd = lineBounds(value, lineEnd+1)
+		}
+		caret = lineStart
+		for range column {
+			if caret >= lineEnd {
+				break
+			}
+			caret = nextClusterEnd(value[:lineEnd], caret)
+		}
+	default:
+		return e, false
+	}
+	e.Caret = caret
+	if !extend {
+		e.Anchor = caret
+	}
+	return e, true
+}
+
+// scrollToCaret returns the edit with its ScrollX moved just enough that the
+// caret shows in a single-line field whose text area is width wide.
+func scrollToCaret(value string, e FieldEdit, width float64) FieldEdit {
+	caretX := fieldTextWidth(value[:e.Caret])
+	if caretX-e.ScrollX > width {
+		e.ScrollX = caretX - width
+	}
+	if caretX < e.ScrollX {
+		e.ScrollX = caretX
+	}
+	// Text that got shorter pulls back so the field stays full
+	e.ScrollX = max(min(e.ScrollX, fieldTextWidth(value)-width), 0)
+	return e
+}
+
+// fieldOffsetAt returns the cluster boundary of line nearest to x, measured
+// from the start of the line's text.
+func fieldOffsetAt(line string, x float64) int {
+	best, bestDist, offset := 0, math.Abs(x), 0
+	for _, cluster := range layout.GraphemeClusters(line) {
+		offset += len(cluster)
+		if d := math.Abs(x - fieldTextWidth(line[:offset])); d < bestDist {
+			best, bestDist = offset, d
+		}
+	}
+	return best
+}
+
+// fieldTextWidth returns the width of text drawn in a text field.
+func fieldTextWidth(text string) float64 {
+	return float64(measureTextWithFallback(text, fieldTextSize, fyne.TextStyle{}, nil))
+}
+
+// isMultiline reports whether node is a field whose value has lines.
+func isMultiline(node *dom.Node) bool {
+	return node != nil && node.TagName == "textarea"
+}
+
+// isPassword reports whether node is a password field.
+func isPassword(node *dom.Node) bool {
+	return node != nil && strings.EqualFold(node.Attributes["type"], "password")
+}
+
+// passwordBullet stands for each character of a password.
+const passwordBullet = "•"
+
+// maskPassword returns a password as drawn, a bullet a character, with the
+// edit's offsets moved onto the bullets.
+func maskPassword(value string, e FieldEdit) (string, FieldEdit) {
+	mask := func(offset int) int {
+		return len(passwordBullet) * utf8.RuneCountInString(value[:min(offset, len(value))])
+	}
+	e.Caret, e.Anchor = mask(e.Caret), mask(e.Anchor)
+	return strings.Repeat(passwordBullet, utf8.RuneCountInString(value)), e
+}
+
+// unmaskOffset returns the offset into a password of the caret offset bytes
+// into its bullets.
+func unmaskOffset(value string, offset int) int {
+	runes := offset / len(passwordBullet)
+	for i := range value {
+		if runes == 0 {
+			return i
+		}
+		runes--
+	}
+	return len(value)
+}
+
+// isShiftKey reports whether key is either Shift key.
+func isShiftKey(key fyne.KeyName) bool {
+	return key == desktop.KeyShiftLeft || key == desktop.KeyShiftRight
+}
+
+// fieldEdit returns the caret and selection of the focused field. A field
+// that just gained focus gets its caret after its value.
+func (b *Browser) fieldEdit() FieldEdit {
+	node := b.focusedInputNode
+	if node == nil {
+		return FieldEdit{}
+	}
+	n := len(b.inputValues[node])
+	if b.editNode != node {
+		b.editNode = node
+		b.edit = FieldEdit{}.collapse(n)
+	}
+	b.edit = b.edit.clamp(n)
+	return b.edit
+}
+
+// setFieldEdit sets the focused field's value and edit, scrolls its caret
+// into view and shows the caret while the user works.
+func (b *Browser) setFieldEdit(value string, e FieldEdit) {
+	node := b.focusedInputNode
+	if node == nil {
+		return
+	}
+	b.inputValues[node] = value
+	if !isMultiline(node) {
+		if box := findBoxByNode(b.layoutTree, node); box != nil {
+			shown, shownEdit := value, e
+			if isPassword(node) {
+				shown, shownEdit = maskPassword(value, e)
+			}
+			e.ScrollX = scrollToCaret(shown, shownEdit, box.Rect.Width-2*fieldPadding).ScrollX
+		}
+	}
+	b.editNode, b.edit = node, e
+	b.blinkCaret()
+}
+
+// editable reports whether the focused field takes changes to its value.
+func (b *Browser) editable() bool {
+	node := b.focusedInputNode
+	return node != nil && !isNodeDisabled(node) && !isNodeReadonly(node)
+}
+
+// insertText replaces the focused field's selection with text.
+func (b *Browser) insertText(text string) {
+	if !b.editable() {
+		return
+	}
+	if !isMultiline(b.focusedInputNode) {
+		text = strings.NewReplacer("\r\n", "", "\n", "", "\r", "").Replace(text)
+	}
+	b.setFieldEdit(replaceSelection(b.inputValues[b.focusedInputNode], b.fieldEdit(), text))
+}
+
+// editKey handles a key pressed in the focused field. It reports whether
+// the key edits or moves the caret.
+func (b *Browser) editKey(key fyne.KeyName) bool {
+	node := b.focusedInputNode
+	value := b.inputValues[node]
+	switch key {
+	case fyne.KeyBackspace, fyne.KeyDelete:
+		if !b.editable() {
+			return true
+		}
+		if key == fyne.KeyBackspace {
+			b.setFieldEdit(deleteBackward(value, b.fieldEdit()))
+		} else {
+			b.setFieldEdit(deleteForward(value, b.fieldEdit()))
+		}
+		return true
+	}
+	if e, ok := moveCaret(value, b.fieldEdit(), key, b.shiftHeld, isMultiline(node)); ok {
+		b.setFieldEdit(value, e)
+		return true
+	}
+	return false
+}
+
+// selectedFieldText returns the text selected in the focused field.
+// Passwords never leave their field.
+func (b *Browser) selectedFieldText() string {
+	node := b.focusedInputNode
+	if node == nil || isPassword(node) {
+		return ""
+	}
+	start, end := b.fieldEdit().Selection()
+	return b.inputValues[node][start:end]
+}
+
+// cutFieldText removes the focused field's selection and returns it.
+func (b *Browser) cutFieldText() string {
+	text := b.selectedFieldText()
+	if text != "" && b.editable() {
+		b.setFieldEdit(replaceSelection(b.inputValues[b.focusedInputNode], b.fieldEdit(), ""))
+	}
+	return text
+}
+
+// selectAllFieldText selects the focused field's whole value.
+func (b *Browser) selectAllFieldText() {
+	value := b.inputValues[b.focusedInputNode]
+	b.setFieldEdit(value, FieldEdit{Anchor: 0, Caret: len(value), ScrollX: b.fieldEdit().ScrollX})
+}
+
+// placeCaret puts the caret of the field in box, just focused by a click at
+// (x, y), at the cluster boundary nearest to the click.
+func (b *Browser) placeCaret(box *layout.LayoutBox, x, y float64) {
+	value := b.inputValues[box.Node]
+	e := b.fieldEdit()
+	lineStart := 0
+	line := value
+	if isMultiline(box.Node) {
+		row := int((y - box.Rect.Y - fieldPadding) / fieldLineHeight)
+		for range max(row, 0) {
+			i := strings.IndexByte(value[lineStart:], '\n')
+			if i < 0 {
+				break
+			}
+			lineStart += i + 1
+		}
+		_, lineEnd := lineBounds(value, lineStart)
+		line = value[lineStart:lineEnd]
+	}
+	x = x - box.Rect.X - fieldPadding + e.ScrollX
+	offset := lineStart + fieldOffsetAt(line, x)
+	if isPassword(box.Node) {
+		masked, _ := maskPassword(value, e)
+		offset = unmaskOffset(value, fieldOffsetAt(masked, x))
+	}
+	b.setFieldEdit(value, e.collapse(offset))
+}
+
+// blinkCaret shows the caret and restarts its blinking, so it stays lit
+// while the user types or moves it.
+func (b *Browser) blinkCaret() {
+	b.caretBlinks++
+	b.caretHidden = false
+	blink := b.caretBlinks
+	var toggle func()
+	toggle = func() {
+		fyne.Do(func() {
+			if blink != b.caretBlinks || b.focusedInputNode == nil {
+				return
+			}
+			b.caretHidden = !b.caretHidden
+			b.scheduleRepaint()
+			time.AfterFunc(caretBlinkInterval, toggle)
+		})
+	}
+	time.AfterFunc(caretBlinkInterval, toggle)
+}
@@ -0,0 +1,152 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"browser/dom"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveCaret(t *testing.T) {
+	const value = "héllo\nab\nwörld"
+	tests := []struct {
+		name      string
+		edit      FieldEdit
+		key       fyne.KeyName
+		extend    bool
+		multiline bool
+		want      FieldEdit
+	}{
+		{"left over a two-byte character", FieldEdit{Caret: 3, Anchor: 3}, fyne.KeyLeft, false, false, FieldEdit{Caret: 1, Anchor: 1}},
+		{"right", FieldEdit{Caret: 1, Anchor: 1}, fyne.KeyRight, false, false, FieldEdit{Caret: 3, Anchor: 3}},
+		{"left at the start stays", FieldEdit{}, fyne.KeyLeft, false, false, FieldEdit{}},
+		{"shift extends", FieldEdit{Caret: 3, Anchor: 3}, fyne.KeyRight, true, false, FieldEdit{Caret: 4, Anchor: 3}},
+		{"left collapses a selection to its start", FieldEdit{Caret: 5, Anchor: 1}, fyne.KeyLeft, false, false, FieldEdit{Caret: 1, Anchor: 1}},
+		{"right collapses a selection to its end", FieldEdit{Caret: 1, Anchor: 5}, fyne.KeyRight, false, false, FieldEdit{Caret: 5, Anchor: 5}},
+		{"home goes to the line start", FieldEdit{Caret: 9, Anchor: 9}, fyne.KeyHome, false, true, FieldEdit{Caret: 7, Anchor: 7}},
+		{"shift end selects to the line end", FieldEdit{Caret: 1, Anchor: 1}, fyne.KeyEnd, true, true, FieldEdit{Caret: 6, Anchor: 1}},
+		{"down keeps the column", FieldEdit{Caret: 8, Anchor: 8}, fyne.KeyDown, false, true, FieldEdit{Caret: 11, Anchor: 11}},
+		{"up to a shorter line", FieldEdit{Caret: 14, Anchor: 14}, fyne.KeyUp, false, true, FieldEdit{Caret: 9, Anchor: 9}},
+		{"up from the first line", FieldEdit{Caret: 3, Anchor: 3}, fyne.KeyUp, false, true, FieldEdit{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := moveCaret(value, tt.edit, tt.key, tt.extend, tt.multiline)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := moveCaret(value, FieldEdit{}, fyne.KeyDown, false, false)
+	assert.False(t, ok, "inputs have one line")
+}
+
+func TestFieldEditing(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		edit      FieldEdit
+		apply     func(string, FieldEdit) (string, FieldEdit)
+		want      string
+		wantCaret int
+	}{
+		{"insert at the caret", "ac", FieldEdit{Caret: 1, Anchor: 1}, func(v string, e FieldEdit) (string, FieldEdit) { return replaceSelection(v, e, "b") }, "abc", 2},
+		{"typing replaces the selection", "a123d", FieldEdit{Caret: 1, Anchor: 4}, func(v string, e FieldEdit) (string, FieldEdit) { return replaceSelection(v, e, "bc") }, "abcd", 3},
+		{"backspace removes a whole cluster", "aéb", FieldEdit{Caret: 4, Anchor: 4}, deleteBackward, "ab", 1},
+		{"delete removes the cluster after", "abc", FieldEdit{Caret: 1, Anchor: 1}, deleteForward, "ac", 1},
+		{"delete removes the selection", "abcd", FieldEdit{Caret: 3, Anchor: 1}, deleteForward, "ad", 1},
+		{"backspace at the start", "ab", FieldEdit{}, deleteBackward, "ab", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, edit := tt.apply(tt.value, tt.edit)
+			assert.Equal(t, tt.want, value)
+			assert.Equal(t, FieldEdit{Caret: tt.wantCaret, Anchor: tt.wantCaret}, edit)
+		})
+	}
+}
+
+func TestScrollToCaret(t *testing.T) {
+	test.NewApp()
+	const value = "a long value that overflows its field"
+	width := fieldTextWidth("a long")
+
+	e := scrollToCaret(value, FieldEdit{Caret: len(value), Anchor: len(value)}, width)
+	assert.InDelta(t, fieldTextWidth(value)-width, e.ScrollX, 0.01, "caret at the end shows at the right edge")
+
+	e = scrollToCaret(value, e.collapse(2), width)
+	assert.InDelta(t, fieldTextWidth("a "), e.ScrollX, 0.01, "scrolls back just to the caret")
+
+	e = scrollToCaret(value, e.collapse(4), width)
+	assert.InDelta(t, fieldTextWidth("a "), e.ScrollX, 0.01, "a caret in view does not scroll")
+
+	e = scrollToCaret("short", FieldEdit{Caret: 5, Anchor: 5, ScrollX: 40}, width)
+	assert.Zero(t, e.ScrollX, "text that fits is not scrolled")
+}
+
+func TestMaskPassword(t *testing.T) {
+	shown, e := maskPassword("pä55", FieldEdit{Caret: 3, Anchor: 1})
+	assert.Equal(t, "••••", shown)
+	assert.Equal(t, FieldEdit{Caret: 2 * len(passwordBullet), Anchor: len(passwordBullet)}, e)
+	assert.Equal(t, 3, unmaskOffset("pä55", 2*len(passwordBullet)))
+}
+
+func TestBrowserFieldEditing(t *testing.T) {
+	test.NewApp()
+	input := &dom.Node{Type: dom.Element, TagName: "input", Attributes: map[string]string{}}
+	b := &Browser{inputValues: map[*dom.Node]string{input: "hello"}, focusedInputNode: input}
+	// Editing blinks the caret, which repaints
+	b.frames = newFrameScheduler(func(time.Time, bool) {})
+
+	assert.Equal(t, FieldEdit{Caret: 5, Anchor: 5}, b.fieldEdit(), "caret starts after the value")
+
+	b.editKey(fyne.KeyHome)
+	b.shiftHeld = true
+	b.editKey(fyne.KeyRight)
+	b.editKey(fyne.KeyRight)
+	b.shiftHeld = false
+	assert.Equal(t, "he", b.selectedFieldText())
+
+	b.insertText("J\nj")
+	assert.Equal(t, "Jjllo", b.inputValues[input], "line breaks are dropped from inputs")
+
+	b.selectAllFieldText()
+	assert.Equal(t, "Jjllo", b.cutFieldText())
+	assert.Equal(t, "", b.inputValues[input])
+
+	input.Attributes["type"] = "password"
+	b.insertText("secret")
+	b.selectAllFieldText()
+	assert.Empty(t, b.selectedFieldText(), "passwords are not copied")
+
+	input.Attributes["readonly"] = ""
+	b.editKey(fyne.KeyBackspace)
+	assert.Equal(t, "secret", b.inputValues[input])
+}
+
+func TestRenderTextFieldCaret(t *testing.T) {
+	test.NewApp()
+	caretX := func(objects []fyne.CanvasObject) []float32 {
+		var xs []float32
+		for _, obj := range objects {
+			if r, ok := obj.(*canvas.Rectangle); ok && r.Size().Width == 1 && r.Size().Height == 16 {
+				xs = append(xs, r.Position().X)
+			}
+		}
+		return xs
+	}
+
+	objects := renderTextFieldObjects(0, 0, 200, 30, "abcd", "", FieldEdit{Caret: 2, Anchor: 2}, true, true, false, true)
+	assert.Equal(t, []float32{float32(fieldPadding + fieldTextWidth("ab"))}, caretX(objects))
+
+	objects = renderTextFieldObjects(0, 0, 200, 30, "abcd", "", FieldEdit{Caret: 2, Anchor: 2}, false, true, false, true)
+	assert.Empty(t, caretX(objects), "hidden between blinks")
+
+	objects = renderTextFieldObjects(0, 0, 200, 60, "ab\ncd", "", FieldEdit{Caret: 4, Anchor: 4}, true, true, false, true)
+	assert.Equal(t, []float32{float32(fieldPadding + fieldTextWidth("c"))}, caretX(objects), "on the second line")
+}
@@ -0,0 +1,90 @@
+package render
+
+import "math"
+
+// TextHinting controls how glyph geometry is fitted to the device pixel grid.
+type TextHinting int
+
+const (
+	HintingNone   TextHinting = iota // keep exact outlines (best on high-DPI)
+	HintingSlight                    // snap baselines only
+	HintingFull                      // snap baselines, origins and font sizes
+)
+
+// highDPIScale is the canvas scale at or above which glyphs are dense enough
+// that hinting hurts letter shapes more than it helps contrast.
+const highDPIScale = 1.5
+
+// TextRasterOptions configures how text is positioned and rasterized.
+type TextRasterOptions struct {
+	Hinting             TextHinting
+	SubpixelPositioning bool    // allow fractional glyph origins
+	Scale               float32 // device pixels per canvas unit
+}
+
+// TextRaster holds the active text raster options. NewBrowser replaces it
+// with DefaultTextRasterOptions for the window's scale.
+var TextRaster = DefaultTextRasterOptions(1)
+
+// DefaultTextRasterOptions returns per-DPI defaults. Low-DPI screens get full
+// hinting so stems stay crisp, high-DPI screens keep exact outlines and
+// fractional positioning. Glyph coverage is the canvas's own grayscale
+// antialiasing either way.
+func DefaultTextRasterOptions(scale float32) TextRasterOptions {
+	if scale <= 0 {
+		scale = 1
+	}
+	if scale >= highDPIScale {
+		return TextRasterOptions{
+			Hinting:             HintingNone,
+			SubpixelPositioning: true,
+			Scale:               scale,
+		}
+	}
+	return TextRasterOptions{
+		Hinting:             HintingFull,
+		SubpixelPositioning: false,
+		Scale:               scale,
+	}
+}
+
+// DeviceScale returns the device pixels per canvas unit that software
+// rasters are drawn at, so they stay sharp on high-DPI screens.
+func (o TextRasterOptions) DeviceScale() float64 {
+	if o.Scale <= 0 {
+		return 1
+	}
+	return float64(o.Scale)
+}
+
+// snapToDevice rounds a canvas coordinate to the nearest device pixel.
+func (o TextRasterOptions) snapToDevice(v float64) float64 {
+	scale := o.DeviceScale()
+	return math.Round(v*scale) / scale
+}
+
+// GlyphOrigin returns the position a text run should be drawn at. Origins are
+// snapped horizontally unless subpixel positioning is enabled, and the
+// baseline is snapped whenever hinting is on.
+func (o TextRasterOptions) GlyphOrigin(x, y float64) (float64, float64) {
+	if !o.SubpixelPositioning || o.Hinting == HintingFull {
+		x = o.snapToDevice(x)
+	}
+	if o.Hinting != HintingNone {
+		y = o.snapToDevice(y)
+	}
+	return x, y
+}
+
+// TextSize returns the font size to rasterize at. Full hinting rounds the
+// size to whole device pixels so glyph stems land on the pixel grid.
+func (o TextRasterOptions) TextSize(size float32) float32 {
+	if o.Hinting != HintingFull || size <= 0 {
+		return size
+	}
+	hinted := float32(o.snapToDevice(float64(size)))
+	if hinted <= 0 {
+		return size
+	}
+	return hinted
+}
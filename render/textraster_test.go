@@ -0,0 +1,102 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"browser/layout"
+
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTextRasterOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		scale       float32
+		hinting     TextHinting
+		subpixelPos bool
+	}{
+		{"standard DPI", 1, HintingFull, false},
+		{"zero scale treated as 1", 0, HintingFull, false},
+		{"just below high DPI", 1.25, HintingFull, false},
+		{"retina", 2, HintingNone, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultTextRasterOptions(tt.scale)
+			assert.Equal(t, tt.hinting, opts.Hinting)
+			assert.Equal(t, tt.subpixelPos, opts.SubpixelPositioning)
+		})
+	}
+}
+
+func TestGlyphOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    TextRasterOptions
+		x, y    float64
+		expectX float64
+		expectY float64
+	}{
+		{"full hinting snaps both axes", TextRasterOptions{Hinting: HintingFull, Scale: 1}, 10.4, 20.6, 10, 21},
+		{"slight hinting keeps subpixel x", TextRasterOptions{Hinting: HintingSlight, SubpixelPositioning: true, Scale: 1}, 10.4, 20.6, 10.4, 21},
+		{"no hinting keeps exact position", TextRasterOptions{Hinting: HintingNone, SubpixelPositioning: true, Scale: 2}, 10.3, 20.3, 10.3, 20.3},
+		{"no subpixel positioning snaps x to device pixels", TextRasterOptions{Hinting: HintingNone, Scale: 2}, 10.3, 20.3, 10.5, 20.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := tt.opts.GlyphOrigin(tt.x, tt.y)
+			assert.InDelta(t, tt.expectX, x, 0.001)
+			assert.InDelta(t, tt.expectY, y, 0.001)
+		})
+	}
+}
+
+func TestHintedTextSize(t *testing.T) {
+	full := TextRasterOptions{Hinting: HintingFull, Scale: 1}
+	none := TextRasterOptions{Hinting: HintingNone, Scale: 1}
+
+	assert.Equal(t, float32(13), full.TextSize(13.3))
+	assert.Equal(t, float32(13.3), none.TextSize(13.3))
+	assert.Equal(t, float32(0.3), full.TextSize(0.3), "sizes that would round to zero are kept")
+}
+
+func TestRastersAtDeviceScale(t *testing.T) {
+	test.NewApp()
+	saved := TextRaster
+	TextRaster = DefaultTextRasterOptions(2)
+	t.Cleanup(func() { TextRaster = saved })
+
+	t.Run("rounded rects", func(t *testing.T) {
+		objects := RenderToCanvas([]DisplayCommand{DrawRect{
+			Rect:          layout.Rect{X: 10, Y: 10, Width: 40, Height: 30},
+			Color:         color.RGBA{255, 0, 0, 255},
+			TopLeftRadius: 10,
+		}}, "", "", false, nil)
+		require.Len(t, objects, 1)
+		img, ok := objects[0].(*canvas.Image)
+		require.True(t, ok)
+		assert.Equal(t, image.Rect(0, 0, 80, 60), img.Image.Bounds())
+		assert.Equal(t, float32(40), img.Size().Width)
+		_, _, _, a := img.Image.At(2, 2).RGBA()
+		assert.Equal(t, uint32(0), a, "the corner is cut at device resolution")
+	})
+
+	t.Run("clipped fills", func(t *testing.T) {
+		clips := clipStack{{Rect: layout.Rect{X: 0, Y: 0, Width: 40, Height: 40}, TopLeftRadius: 20}}
+		img, visible, ok := clips.clipFill(color.RGBA{255, 0, 0, 255}, layout.Rect{X: 20, Y: 0, Width: 60, Height: 60}, 0)
+		require.True(t, ok)
+		assert.Equal(t, layout.Rect{X: 20, Y: 0, Width: 20, Height: 40}, visible)
+		assert.Equal(t, image.Rect(0, 0, 40, 80), img.Bounds())
+	})
+
+	t.Run("scale defaults to one", func(t *testing.T) {
+		assert.Equal(t, 1.0, TextRasterOptions{}.DeviceScale())
+	})
+}
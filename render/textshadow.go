@@ -0,0 +1,47 @@
+package render
+
+import (
+	"image/color"
+
+	"browser/css"
+)
+
+// shadowCopy is one copy of a text run drawn to paint a shadow.
+type shadowCopy struct {
+	dx, dy float64
+	color  color.Color
+}
+
+// blurCopyAlpha is the share of the shadow's alpha each blurred copy gets.
+// The copies overlap in the middle of the glyphs, so the shadow stays dark
+// there and fades towards the blur radius.
+const blurCopyAlpha = 0.2
+
+// textShadowCopies returns the copies that paint a text shadow. A sharp
+// shadow is a single copy at the shadow offset; a blurred one is
+// approximated by nine fainter copies spread over half the blur radius,
+// which matches the standard deviation CSS uses for the blur.
+func textShadowCopies(shadow css.TextShadow) []shadowCopy {
+	if shadow.Color == nil {
+		return nil
+	}
+	if shadow.Blur <= 0 {
+		return []shadowCopy{{dx: shadow.OffsetX, dy: shadow.OffsetY, color: shadow.Color}}
+	}
+
+	r, g, b, a := shadow.Color.RGBA()
+	faint := color.NRGBA{
+		R: uint8(r * 0xffff / max(a, 1) >> 8),
+		G: uint8(g * 0xffff / max(a, 1) >> 8),
+		B: uint8(b * 0xffff / max(a, 1) >> 8),
+		A: uint8(float64(a>>8) * blurCopyAlpha),
+	}
+	spread := shadow.Blur / 2
+	copies := make([]shadowCopy, 0, 9)
+	for _, dy := range []float64{-spread, 0, spread} {
+		for _, dx := range []float64{-spread, 0, spread} {
+			copies = append(copies, shadowCopy{dx: shadow.OffsetX + dx, dy: shadow.OffsetY + dy, color: faint})
+		}
+	}
+	return copies
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"browser/css"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextShadowCopies(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+
+	t.Run("sharp shadow is one copy at the offset", func(t *testing.T) {
+		copies := textShadowCopies(css.TextShadow{OffsetX: 2, OffsetY: 3, Color: black})
+		assert.Equal(t, []shadowCopy{{dx: 2, dy: 3, color: black}}, copies)
+	})
+
+	t.Run("blurred shadow spreads faint copies around the offset", func(t *testing.T) {
+		copies := textShadowCopies(css.TextShadow{OffsetX: 1, OffsetY: 1, Blur: 4, Color: color.RGBA{255, 0, 0, 255}})
+		assert.Len(t, copies, 9)
+		var minX, maxX float64 = copies[0].dx, copies[0].dx
+		for _, c := range copies {
+			minX, maxX = min(minX, c.dx), max(maxX, c.dx)
+			assert.Equal(t, color.NRGBA{255, 0, 0, 51}, c.color)
+		}
+		assert.Equal(t, []float64{-1, 3}, []float64{minX, maxX})
+	})
+
+	t.Run("translucent colors keep their hue", func(t *testing.T) {
+		copies := textShadowCopies(css.TextShadow{Blur: 2, Color: color.NRGBA{0, 0, 255, 100}})
+		assert.Equal(t, color.NRGBA{0, 0, 255, 20}, copies[0].color)
+	})
+}
+
+func TestTextShadowPaint(t *testing.T) {
+	root := buildLayout(`<div><p>shadow</p><span>plain</span></div>`,
+		`div { color: #112233; text-shadow: 1px 1px red, 2px 2px 3px; } span { text-shadow: none; }`, 800)
+
+	shadows := map[string][]css.TextShadow{}
+	for _, cmd := range BuildDisplayList(root, InputState{}, LinkStyler{}) {
+		if dt, ok := cmd.(DrawText); ok {
+			shadows[dt.Text] = dt.Shadows
+		}
+	}
+
+	// Shadows inherit, and currentColor resolves to the text color
+	assert.Equal(t, []css.TextShadow{
+		{OffsetX: 1, OffsetY: 1, Color: color.RGBA{255, 0, 0, 255}},
+		{OffsetX: 2, OffsetY: 2, Blur: 3, Color: color.RGBA{0x11, 0x22, 0x33, 255}},
+	}, shadows["shadow"])
+	assert.Empty(t, shadows["plain"])
+}
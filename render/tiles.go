@@ -0,0 +1,179 @@
+package render
+
+import (
+	"math"
+	"sync"
+
+	"browser/layout"
+	"browser/utils"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/software"
+)
+
+// Retained scrolling. The page layer is painted into tiles, images of
+// square pieces of the page, so scrolling moves a few images instead of
+// drawing every text and image of the page again. Only the tiles around
+// the viewport are kept. A repaint drops the tiles its damage touches
+// (see damage.go), and they are painted again when next shown.
+
+// tileSize is the side of a tile in canvas pixels.
+const tileSize = 256
+
+// tileMargin is how many tiles past each edge of the viewport are painted
+// ahead of scrolling, and kept when scrolled away from.
+const tileMargin = 1
+
+// tileIndex locates a tile: its column and row on the page.
+type tileIndex struct {
+	col, row int
+}
+
+// rect returns the canvas rect the tile covers.
+func (i tileIndex) rect() layout.Rect {
+	return layout.Rect{X: float64(i.col * tileSize), Y: float64(i.row * tileSize), Width: tileSize, Height: tileSize}
+}
+
+// pageTiles is the page layer painted into tiles.
+type pageTiles struct {
+	mu      sync.Mutex
+	objects []fyne.CanvasObject
+	bounds  []layout.Rect // of each object, by filterBounds
+	size    fyne.Size     // of the page, to the far edge of its objects
+	tiles   map[tileIndex]*canvas.Image
+	layer   *fyne.Container // the tiles shown
+}
+
+// update makes objects the page the tiles show, dropping the tiles that
+// damage touches.
+func (t *pageTiles) update(objects []fyne.CanvasObject, damage []layout.Rect) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+	t.objects = objects
+	t.bounds = make([]layout.Rect, len(objects))
+	t.size = fyne.Size{}
+	for i, obj := range objects {
+		t.bounds[i], _ = filterBounds([]fyne.CanvasObject{obj})
+		t.size = t.size.Max(fyne.NewSize(float32(t.bounds[i].X+t.bounds[i].Width), float32(t.bounds[i].Y+t.bounds[i].Height)))
+	}
+	t.layer.Resize(t.size)
+
+	for index := range t.tiles {
+		r := index.rect()
+		for _, d := range damage {
+			// Damage is where commands draw, which antialiased edges
+			// may overstep by a pixel
+			d = layout.Rect{X: d.X - 1, Y: d.Y - 1, Width: d.Width + 2, Height: d.Height + 2}
+			if overlaps(r, d) {
+				delete(t.tiles, index)
+				break
+			}
+		}
+	}
+}
+
+// reset drops every tile, for a page drawn from scratch.
+func (t *pageTiles) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+	clear(t.tiles)
+}
+
+// init makes the tile map and layer of a new pageTiles.
+func (t *pageTiles) init() {
+	if t.layer == nil {
+		t.layer = container.NewWithoutLayout()
+		t.tiles = make(map[tileIndex]*canvas.Image)
+	}
+}
+
+// show shows the tiles around view, the part of the page in the viewport,
+// painting those missing and dropping those further away.
+func (t *pageTiles) show(view layout.Rect) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+	first := tileIndex{
+		col: max(int(math.Floor(view.X/tileSize))-tileMargin, 0),
+		row: max(int(math.Floor(view.Y/tileSize))-tileMargin, 0),
+	}
+	last := tileIndex{
+		col: min(int(math.Ceil((view.X+view.Width)/tileSize))+tileMargin, int(math.Ceil(float64(t.size.Width)/tileSize))) - 1,
+		row: min(int(math.Ceil((view.Y+view.Height)/tileSize))+tileMargin, int(math.Ceil(float64(t.size.Height)/tileSize))) - 1,
+	}
+	inRange := func(i tileIndex) bool {
+		return i.col >= first.col && i.col <= last.col && i.row >= first.row && i.row <= last.row
+	}
+	for index := range t.tiles {
+		if !inRange(index) {
+			delete(t.tiles, index)
+		}
+	}
+
+	start := utils.Profile.Start()
+	painted := 0
+	var shown []fyne.CanvasObject
+	for row := first.row; row <= last.row; row++ {
+		for col := first.col; col <= last.col; col++ {
+			index := tileIndex{col, row}
+			tile, ok := t.tiles[index]
+			if !ok {
+				tile = t.paint(index)
+				t.tiles[index] = tile
+				painted++
+			}
+			shown = append(shown, tile)
+		}
+	}
+	if painted > 0 {
+		utils.Profile.Record(utils.PhaseRaster, start, painted)
+	}
+	t.layer.Objects = shown
+	t.layer.Refresh()
+}
+
+// paint draws the objects over the tile at index into an image, at device
+// resolution.
+func (t *pageTiles) paint(index tileIndex) *canvas.Image {
+	r := index.rect()
+	var objects []fyne.CanvasObject
+	for i, obj := range t.objects {
+		if overlaps(t.bounds[i], r) {
+			objects = append(objects, obj)
+		}
+	}
+	// The objects keep their page positions, their container moved so the
+	// tile's corner is at the origin
+	page := container.NewWithoutLayout(objects...)
+	page.Move(fyne.NewPos(-float32(r.X), -float32(r.Y)))
+
+	scale := float32(TextRaster.DeviceScale())
+	offscreen := software.NewTransparentCanvas()
+	offscreen.SetPadded(false)
+	offscreen.SetScale(scale)
+	offscreen.SetContent(container.NewWithoutLayout(page))
+	offscreen.Resize(fyne.NewSize(tileSize, tileSize))
+
+	tile := canvas.NewImageFromImage(offscreen.Capture())
+	tile.FillMode = canvas.ImageFillStretch
+	tile.Resize(fyne.NewSize(tileSize, tileSize))
+	tile.Move(fyne.NewPos(float32(r.X), float32(r.Y)))
+	return tile
+}
+
+// showTiles shows the page tiles in the viewport.
+func (b *Browser) showTiles() {
+	if b.contentScroll == nil {
+		return
+	}
+	size := b.contentScroll.Size()
+	if size.Width <= 0 || size.Height <= 0 {
+		size = fyne.NewSize(b.Width, b.Height) // not laid out yet
+	}
+	offset := b.contentScroll.Offset
+	b.tiles.show(layout.Rect{X: float64(offset.X), Y: float64(offset.Y), Width: float64(size.Width), Height: float64(size.Height)})
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageTiles(t *testing.T) {
+	app := test.NewApp()
+	defer app.Quit()
+
+	rect := func(x, y, w, h float32, c color.Color) fyne.CanvasObject {
+		r := canvas.NewRectangle(c)
+		r.Move(fyne.NewPos(x, y))
+		r.Resize(fyne.NewSize(w, h))
+		return r
+	}
+	red := color.NRGBA{255, 0, 0, 255}
+	objects := []fyne.CanvasObject{
+		rect(0, 0, 500, 4000, color.White),
+		rect(300, 300, 100, 100, red),
+	}
+	var tiles pageTiles
+	tiles.update(objects, nil)
+	assert.Equal(t, fyne.NewSize(500, 4000), tiles.layer.Size())
+
+	tiles.show(layout.Rect{Width: 400, Height: 300})
+	// Two columns and two rows in view, and a row of margin below; the page
+	// is too narrow for a column of margin
+	assert.Len(t, tiles.layer.Objects, 6)
+
+	t.Run("tiles hold the page", func(t *testing.T) {
+		tile := tiles.tiles[tileIndex{1, 1}]
+		assert.Equal(t, fyne.NewPos(256, 256), tile.Position())
+		assert.Equal(t, red, color.NRGBAModel.Convert(tile.Image.At(300-256+50, 300-256+50)))
+		assert.Equal(t, color.NRGBA{255, 255, 255, 255}, color.NRGBAModel.Convert(tile.Image.At(10, 10)))
+	})
+
+	t.Run("damage repaints the tiles it touches", func(t *testing.T) {
+		before := map[tileIndex]*canvas.Image{}
+		for i, tile := range tiles.tiles {
+			before[i] = tile
+		}
+		tiles.update(objects, []layout.Rect{{X: 300, Y: 100, Width: 10, Height: 10}})
+		tiles.show(layout.Rect{Width: 400, Height: 300})
+		assert.NotSame(t, before[tileIndex{1, 0}], tiles.tiles[tileIndex{1, 0}])
+		assert.Same(t, before[tileIndex{0, 0}], tiles.tiles[tileIndex{0, 0}])
+		assert.Same(t, before[tileIndex{1, 1}], tiles.tiles[tileIndex{1, 1}])
+	})
+
+	t.Run("scrolling away drops tiles", func(t *testing.T) {
+		tiles.show(layout.Rect{Y: 2000, Width: 400, Height: 300})
+		for i := range tiles.tiles {
+			assert.GreaterOrEqual(t, i.row, 6, "tile %v is far above the viewport", i)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package render
+
+import (
+	"browser/dom"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Translator translates page text for TranslatePage. texts and the result
+// are index-aligned; implementations may batch them to a remote service.
+type Translator interface {
+	Translate(texts []string, targetLang string) ([]string, error)
+}
+
+// TranslationLanguage is a target language offered in the Translate menu.
+type TranslationLanguage struct {
+	Code string // BCP 47 tag passed to the Translator
+	Name string
+}
+
+// TranslationLanguages lists the Translate menu's target languages.
+var TranslationLanguages = []TranslationLanguage{
+	{"en", "English"},
+	{"es", "Spanish"},
+	{"fr", "French"},
+	{"de", "German"},
+	{"pt", "Portuguese"},
+	{"ja", "Japanese"},
+	{"zh", "Chinese"},
+}
+
+var errNoTranslator = errors.New("no translation provider")
+
+// Elements whose text is not page prose
+var untranslatedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "textarea": true,
+	"code": true, "pre": true, "template": true,
+}
+
+// SetTranslator installs the provider used by TranslatePage.
+func (b *Browser) SetTranslator(t Translator) {
+	b.translator = t
+	b.refreshMainMenu()
+}
+
+// TranslatePage re-renders the page with its text translated to lang. The
+// original text is kept so ShowOriginal (or another language) can swap it
+// back; the DOM structure is untouched.
+func (b *Browser) TranslatePage(lang string) error {
+	if b.translator == nil {
+		return errNoTranslator
+	}
+	if b.document == nil {
+		return nil
+	}
+	b.restoreOriginalText()
+
+	nodes := translatableTextNodes(b.document)
+	texts := make([]string, len(nodes))
+	for i, n := range nodes {
+		texts[i] = strings.TrimSpace(n.Text)
+	}
+	translated, err := b.translator.Translate(texts, lang)
+	if err != nil {
+		return err
+	}
+	if len(translated) != len(nodes) {
+		return fmt.Errorf("translator returned %d texts for %d", len(translated), len(nodes))
+	}
+
+	b.originalText = make(map[*dom.Node]string, len(nodes))
+	for i, n := range nodes {
+		b.originalText[n] = n.Text
+		n.Text = withSurroundingSpace(n.Text, translated[i])
+	}
+	b.translatedTo = lang
+	b.refreshMainMenu()
+	b.Reflow(b.Width)
+	return nil
+}
+
+// ShowOriginal undoes TranslatePage.
+func (b *Browser) ShowOriginal() {
+	if b.restoreOriginalText() {
+		b.refreshMainMenu()
+		b.Reflow(b.Width)
+	}
+}
+
+// TranslatedTo returns the language the page is shown in, or "" for the
+// original text.
+func (b *Browser) TranslatedTo() string {
+	return b.translatedTo
+}
+
+// restoreOriginalText puts back the text swapped out by TranslatePage and
+// reports whether anything changed.
+func (b *Browser) restoreOriginalText() bool {
+	if b.originalText == nil {
+		return false
+	}
+	for n, text := range b.originalText {
+		n.Text = text
+	}
+	b.originalText = nil
+	b.translatedTo = ""
+	return true
+}
+
+// translatableTextNodes returns the non-blank text nodes under root in
+// document order, skipping scripts, styles and preformatted code.
+func translatableTextNodes(root *dom.Node) []*dom.Node {
+	var nodes []*dom.Node
+	var walk func(n *dom.Node)
+	walk = func(n *dom.Node) {
+		if n.Type == dom.Element && untranslatedElements[n.TagName] {
+			return
+		}
+		if n.Type == dom.Text && strings.TrimSpace(n.Text) != "" {
+			nodes = append(nodes, n)
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return nodes
+}
+
+// withSurroundingSpace keeps original's leading and trailing whitespace
+// around text, so translated runs still separate from adjacent inline text.
+func withSurroundingSpace(original, text string) string {
+	trimmed := strings.TrimSpace(original)
+	if trimmed == "" {
+		return original
+	}
+	start := strings.Index(original, trimmed)
+	return original[:start] + strings.TrimSpace(text) + original[start+len(trimmed):]
+}
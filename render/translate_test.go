@@ -0,0 +1,46 @@
+package render
+
+import (
+	"browser/dom"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslatableTextNodes(t *testing.T) {
+	doc := dom.Parse(strings.NewReader(`<html><head><title>T</title><style>p{}</style></head>
+		<body><p>Hello <b>world</b></p><script>var x;</script><pre>code</pre><p>  </p></body></html>`))
+
+	var texts []string
+	for _, n := range translatableTextNodes(doc) {
+		texts = append(texts, n.Text)
+	}
+	assert.Equal(t, []string{"T", "Hello ", "world"}, texts)
+}
+
+func TestWithSurroundingSpace(t *testing.T) {
+	tests := []struct {
+		original, text, expected string
+	}{
+		{"Hello ", "Hola", "Hola "},
+		{" and ", "y", " y "},
+		{"\n  word\n", " palabra ", "\n  palabra\n"},
+		{"plain", "llano", "llano"},
+		{"   ", "x", "   "},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, withSurroundingSpace(tt.original, tt.text))
+	}
+}
+
+func TestRestoreOriginalText(t *testing.T) {
+	node := dom.NewText("Hola ")
+	b := &Browser{originalText: map[*dom.Node]string{node: "Hello "}, translatedTo: "es"}
+
+	assert.True(t, b.restoreOriginalText())
+	assert.Equal(t, "Hello ", node.Text)
+	assert.Equal(t, "", b.TranslatedTo())
+	assert.False(t, b.restoreOriginalText(), "nothing left to restore")
+}
@@ -0,0 +1,138 @@
+package render
+
+import (
+	"browser/css"
+	"browser/layout"
+
+	"fyne.io/fyne/v2"
+)
+
+// LayoutViewport returns the viewport the page is laid out in within a
+// width by height window: the window in CSS pixels of the site's page
+// zoom, resized by the page's viewport <meta>. The scale of both is
+// recorded for painting and hit testing.
+func (b *Browser) LayoutViewport(width, height float32) layout.Viewport {
+	pageZoom := b.PageZoom()
+	layoutWidth, scale := float64(width)/pageZoom, 1.0
+	if meta, ok := layout.FindViewportMeta(b.document); ok {
+		layoutWidth, scale = meta.Resolve(layoutWidth)
+	}
+	b.pageScale = scale * pageZoom
+	return layout.Viewport{Width: layoutWidth, Height: float64(height) / b.pageScale}
+}
+
+// zoom returns the scale the page is drawn at, 1 unless the page zoom or
+// its viewport <meta> asks otherwise.
+func (b *Browser) zoom() float64 {
+	if b.pageScale <= 0 {
+		return 1
+	}
+	return b.pageScale
+}
+
+// toPage converts a position on the scaled page to layout coordinates.
+// Event positions are in canvas units, which do not change with the
+// device scale, so hit testing needs only the page's zoom.
+func (b *Browser) toPage(x, y float32) (float64, float64) {
+	z := b.zoom()
+	return float64(x) / z, float64(y) / z
+}
+
+// viewport returns the size of the page's view, in layout coordinates.
+func (b *Browser) viewport() layout.Rect {
+	width, height := b.toPage(b.Width, b.Height)
+	return layout.Rect{Width: width, Height: height}
+}
+
+// fromPage converts layout coordinates to a position on the scaled page.
+func (b *Browser) fromPage(x, y float64) fyne.Position {
+	z := b.zoom()
+	return fyne.NewPos(float32(x*z), float32(y*z))
+}
+
+// scaleCommands multiplies the geometry and text sizes of commands by
+// scale in place, drawing a page laid out at its viewport width at the
+// window's size.
+func scaleCommands(commands []DisplayCommand, scale float64) {
+	r := func(rect layout.Rect) layout.Rect {
+		return layout.Rect{X: rect.X * scale, Y: rect.Y * scale, Width: rect.Width * scale, Height: rect.Height * scale}
+	}
+	for i, cmd := range commands {
+		switch c := cmd.(type) {
+		case DrawRect:
+			c.Rect = r(c.Rect)
+			c.CornerRadius *= scale
+			c.TopLeftRadius *= scale
+			c.TopRightRadius *= scale
+			c.BottomRightRadius *= scale
+			c.BottomLeftRadius *= scale
+			commands[i] = c
+		case DrawText:
+			c.X, c.Y, c.Width = c.X*scale, c.Y*scale, c.Width*scale
+			c.LetterSpacing *= scale
+			c.WordSpacing *= scale
+			c.ClipLeftOffset *= scale
+			c.Size *= float32(scale)
+			if len(c.Shadows) > 0 {
+				shadows := make([]css.TextShadow, len(c.Shadows))
+				for j, shadow := range c.Shadows {
+					shadow.OffsetX, shadow.OffsetY, shadow.Blur = shadow.OffsetX*scale, shadow.OffsetY*scale, shadow.Blur*scale
+					shadows[j] = shadow
+				}
+				c.Shadows = shadows
+			}
+			commands[i] = c
+		case DrawImage:
+			c.Rect, c.Area = r(c.Rect), r(c.Area)
+			commands[i] = c
+		case DrawSVG:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawCanvas:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawHR:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawInput:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawButton:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawTextarea:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawSelect:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawRadio:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawCheckbox:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawFileInput:
+			c.Rect = r(c.Rect)
+			commands[i] = c
+		case DrawFieldset:
+			c.Rect = r(c.Rect)
+			c.LegendX, c.LegendY = c.LegendX*scale, c.LegendY*scale
+			c.LegendWidth, c.LegendHeight = c.LegendWidth*scale, c.LegendHeight*scale
+			commands[i] = c
+		case DrawFixedBackground:
+			c.Rect = r(c.Rect)
+			for j, layer := range c.Layers {
+				c.Layers[j].Rect, c.Layers[j].Area = r(layer.Rect), r(layer.Area)
+			}
+			commands[i] = c
+		case PushClip:
+			c.Rect = r(c.Rect)
+			c.TopLeftRadius *= scale
+			c.TopRightRadius *= scale
+			c.BottomRightRadius *= scale
+			c.BottomLeftRadius *= scale
+			commands[i] = c
+		}
+	}
+}
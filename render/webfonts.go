@@ -0,0 +1,255 @@
+package render
+
+import (
+	"browser/css"
+	"browser/layout"
+	"browser/utils"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// webFontFace is a downloaded @font-face font.
+type webFontFace struct {
+	weight int
+	italic bool
+	font   fyne.Resource
+}
+
+// webFontRegistry holds the current page's @font-face fonts. Text is drawn
+// with the next family of its font-family list until a face finishes
+// loading, so it never waits invisibly on the network.
+type webFontRegistry struct {
+	mu         sync.Mutex
+	generation int                      // bumped per document; stale downloads are dropped
+	faces      map[string][]webFontFace // lowercased family -> loaded faces
+	requested  map[string]bool          // faces whose download has started
+}
+
+// webFonts is the registry consulted by resolveFontFamily.
+var webFonts = newWebFontRegistry()
+
+func newWebFontRegistry() *webFontRegistry {
+	return &webFontRegistry{
+		faces:     make(map[string][]webFontFace),
+		requested: make(map[string]bool),
+	}
+}
+
+// reset forgets the fonts of the previous document.
+func (r *webFontRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	r.faces = make(map[string][]webFontFace)
+	r.requested = make(map[string]bool)
+}
+
+// request marks face as loading and returns the current generation. ok is
+// false if the face was already requested for this document.
+func (r *webFontRegistry) request(face css.FontFace) (generation int, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := fmt.Sprintf("%s|%d|%t|%v|%s", strings.ToLower(face.Family), face.Weight, face.Italic, face.Sources, face.BaseURL)
+	if r.requested[key] {
+		return r.generation, false
+	}
+	r.requested[key] = true
+	return r.generation, true
+}
+
+// add registers a loaded face. It reports false if the document changed
+// since the face was requested.
+func (r *webFontRegistry) add(generation int, face css.FontFace, font fyne.Resource) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if generation != r.generation {
+		return false
+	}
+	key := strings.ToLower(strings.TrimSpace(face.Family))
+	r.faces[key] = append(r.faces[key], webFontFace{weight: face.Weight, italic: face.Italic, font: font})
+	return true
+}
+
+// lookup returns the loaded face of family closest to the requested
+// weight and slant, or nil if none has loaded.
+func (r *webFontRegistry) lookup(family string, weight int, italic bool) fyne.Resource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	faces := r.faces[strings.ToLower(strings.TrimSpace(family))]
+	if len(faces) == 0 {
+		return nil
+	}
+	best := faces[0]
+	for _, face := range faces[1:] {
+		if faceDistance(face.weight, face.italic, weight, italic) < faceDistance(best.weight, best.italic, weight, italic) {
+			best = face
+		}
+	}
+	return best.font
+}
+
+// LoadWebFonts starts downloading the page's @font-face fonts. Each face is
+// fetched once per document; the page reflows as faces arrive.
+func (b *Browser) LoadWebFonts(faces []css.FontFace) {
+	for _, face := range faces {
+		generation, ok := webFonts.request(face)
+		if !ok {
+			continue
+		}
+		go func() {
+			font := b.fetchWebFont(face)
+			if font == nil {
+				fmt.Printf("No usable source for font %q\n", face.Family)
+				return
+			}
+			if webFonts.add(generation, face, font) {
+				// Text in this family was measured in a fallback face
+				fyne.Do(func() {
+					layout.InvalidateTextCache()
+					b.resetFrames()
+					b.Reflow(b.Width)
+				})
+			}
+		}()
+	}
+}
+
+// fetchWebFont returns the font of the first of face's sources that loads.
+// Relative URLs resolve against the stylesheet the rule came from.
+func (b *Browser) fetchWebFont(face css.FontFace) fyne.Resource {
+	for _, source := range face.Sources {
+		if source.Local != "" {
+			if face := systemFonts().lookup(source.Local, css.FontWeightNormal, false); face != nil {
+				if font := loadFontResource(face.Path); font != nil {
+					return font
+				}
+			}
+			continue
+		}
+		if !webFontFormatSupported(source.Format, source.URL) {
+			continue
+		}
+		fullURL := source.URL
+		if !strings.HasPrefix(fullURL, "data:") {
+			fullURL = b.resolveURL(fullURL)
+			if base, err := url.Parse(face.BaseURL); err == nil && face.BaseURL != "" {
+				if ref, err := url.Parse(source.URL); err == nil {
+					fullURL = base.ResolveReference(ref).String()
+				}
+			}
+		}
+		if font := fetchWebFontURL(fullURL, b.GetCurrentURL()); font != nil {
+			return font
+		}
+	}
+	return nil
+}
+
+// fetchWebFontURL downloads a font file for the page at pageURL, or decodes
+// a data: URL, caching the result in the HTTP cache.
+func fetchWebFontURL(fullURL, pageURL string) fyne.Resource {
+	if cached, ok := utils.HTTPCache.Get(fullURL); ok {
+		if font, ok := cached.(fyne.Resource); ok {
+			return font
+		}
+	}
+
+	var data []byte
+	if strings.HasPrefix(fullURL, "data:") {
+		decoded, ok := decodeDataURL(fullURL)
+		if !ok {
+			return nil
+		}
+		data = decoded
+	} else {
+		fmt.Println("Fetching font:", fullURL)
+		resp, err := utils.Subresources.Load(utils.PriorityMedium, utils.HTTPRequest{
+			Method:  "GET",
+			URL:     fullURL,
+			FromURL: pageURL,
+		})
+		if err != nil {
+			fmt.Println("Error fetching font:", err)
+			return nil
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			fmt.Println("Error fetching font:", resp.Status)
+			return nil
+		}
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			fmt.Println("Error reading font:", err)
+			return nil
+		}
+	}
+	if !isLoadableFont(data) {
+		return nil
+	}
+
+	// The text renderer caches faces per resource, so each URL keeps one
+	font := fyne.NewStaticResource(fullURL, data)
+	utils.HTTPCache.Put(fullURL, font)
+	return font
+}
+
+// webFontFormatSupported reports whether a source can be used, judging by
+// its format() hint or, without one, its file extension. The text renderer
+// reads TrueType, OpenType and WOFF; WOFF2 needs Brotli and is skipped in
+// favour of the next source.
+func webFontFormatSupported(format, rawURL string) bool {
+	switch format {
+	case "truetype", "opentype", "woff", "collection":
+		return true
+	case "":
+	default:
+		return false
+	}
+	if strings.HasPrefix(rawURL, "data:") {
+		return !strings.HasPrefix(rawURL, "data:font/woff2") && !strings.HasPrefix(rawURL, "data:application/font-woff2")
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		rawURL = u.Path
+	}
+	return strings.ToLower(path.Ext(rawURL)) != ".woff2"
+}
+
+// isLoadableFont reports whether data starts with a TrueType, OpenType,
+// collection or WOFF signature.
+func isLoadableFont(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "OTTO", "true", "ttcf", "wOFF":
+		return true
+	}
+	return false
+}
+
+// decodeDataURL returns the payload of a data: URL.
+func decodeDataURL(rawURL string) ([]byte, bool) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(rawURL, "data:"), ",")
+	if !ok {
+		return nil, false
+	}
+	if strings.HasSuffix(header, ";base64") {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, false
+		}
+		return data, true
+	}
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, false
+	}
+	return []byte(data), true
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"browser/css"
+	"browser/utils"
+
+	"fyne.io/fyne/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebFontRegistry(t *testing.T) {
+	regular := fyne.NewStaticResource("regular.ttf", []byte("true"))
+	bold := fyne.NewStaticResource("bold.ttf", []byte("true"))
+	brand := css.FontFace{Family: "Brand", Sources: []css.FontSource{{URL: "brand.ttf"}}, Weight: 400}
+	brandBold := css.FontFace{Family: "Brand", Sources: []css.FontSource{{URL: "brand-bold.ttf"}}, Weight: 700}
+
+	r := newWebFontRegistry()
+	generation, ok := r.request(brand)
+	assert.True(t, ok)
+	_, ok = r.request(brand)
+	assert.False(t, ok, "each face is fetched once")
+	assert.Nil(t, r.lookup("Brand", 400, false), "nothing is used before it loads")
+
+	assert.True(t, r.add(generation, brand, regular))
+	assert.Equal(t, regular, r.lookup("brand", 700, false), "the closest face stands in for a missing weight")
+
+	boldGeneration, _ := r.request(brandBold)
+	r.add(boldGeneration, brandBold, bold)
+	assert.Equal(t, bold, r.lookup("Brand", 700, false))
+	assert.Equal(t, bold, r.lookup("Brand", 600, false), "semibold falls back to the heavier face")
+	assert.Equal(t, regular, r.lookup("Brand", 500, false), "medium falls back to the lighter face")
+	assert.Equal(t, regular, r.lookup("Brand", 400, true))
+	assert.Nil(t, r.lookup("Other", 400, false))
+
+	staleGeneration, _ := r.request(css.FontFace{Family: "Late", Sources: []css.FontSource{{URL: "late.ttf"}}})
+	r.reset()
+	assert.Nil(t, r.lookup("Brand", 400, false), "reset forgets the previous document's fonts")
+	assert.False(t, r.add(staleGeneration, css.FontFace{Family: "Late"}, regular), "downloads for an old document are dropped")
+	assert.Nil(t, r.lookup("Late", 400, false))
+	_, ok = r.request(brand)
+	assert.True(t, ok, "a new document fetches its fonts again")
+}
+
+func TestFetchWebFontResolvesAgainstItsStylesheet(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/assets/css/fonts/brand.ttf" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("true font data"))
+	}))
+	defer server.Close()
+	defer utils.HTTPCache.Clear(utils.SiteOf(server.URL), time.Time{})
+
+	page, err := url.Parse(server.URL + "/blog/post.html")
+	require.NoError(t, err)
+	b := &Browser{currentURL: page}
+	face := css.FontFace{Family: "Brand", Sources: []css.FontSource{{URL: "fonts/brand.ttf"}}, BaseURL: server.URL + "/assets/css/site.css"}
+
+	assert.NotNil(t, b.fetchWebFont(face))
+	assert.Equal(t, []string{"/assets/css/fonts/brand.ttf"}, paths, "not /blog/fonts/brand.ttf")
+}
+
+func TestWebFontFormatSupported(t *testing.T) {
+	tests := []struct {
+		format   string
+		url      string
+		expected bool
+	}{
+		{"woff2", "/f.woff2", false},
+		{"woff", "/f.woff", true},
+		{"truetype", "/f", true},
+		{"opentype", "/f.otf", true},
+		{"embedded-opentype", "/f.eot", false},
+		{"svg", "/f.svg", false},
+		{"", "/fonts/f.ttf?v=2", true},
+		{"", "/fonts/f.woff2?v=2", false},
+		{"", "data:font/woff2;base64,AAAA", false},
+		{"", "data:font/ttf;base64,AAAA", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+" "+tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.expected, webFontFormatSupported(tt.format, tt.url))
+		})
+	}
+}
+
+func TestIsLoadableFont(t *testing.T) {
+	assert.True(t, isLoadableFont([]byte("\x00\x01\x00\x00rest")))
+	assert.True(t, isLoadableFont([]byte("OTTO....")))
+	assert.True(t, isLoadableFont([]byte("wOFF....")))
+	assert.False(t, isLoadableFont([]byte("wOF2....")), "WOFF2 needs Brotli")
+	assert.False(t, isLoadableFont([]byte("<!DOCTYPE html>")), "error pages are not fonts")
+	assert.False(t, isLoadableFont(nil))
+}
+
+func TestDecodeDataURL(t *testing.T) {
+	data, ok := decodeDataURL("data:font/ttf;base64,T1RUTw==")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("OTTO"), data)
+
+	data, ok = decodeDataURL("data:text/plain,a%20b")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("a b"), data)
+
+	_, ok = decodeDataURL("data:font/ttf;base64")
+	assert.False(t, ok)
+	_, ok = decodeDataURL("data:font/ttf;base64,!!!")
+	assert.False(t, ok)
+}
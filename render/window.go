@@ -5,19 +5,19 @@ import (
 	"browser/dom"
 	"browser/layout"
 	"browser/utils"
-	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"image"
 	"image/color"
 	"io"
-	"mime/multipart"
-	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -26,6 +26,7 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -35,7 +36,14 @@ type NavigationRequest struct {
 	Data           url.Values
 	Body           []byte
 	ContentType    string
-	ReferrerPolicy string
+	ReferrerPolicy string // the link's referrerpolicy, if any
+	// FromURL is the page the navigation was started from, which may be
+	// sent as the referrer; empty for those the user started, from the
+	// address bar, the history or a menu
+	FromURL  string
+	Replace  bool   // the page replaces the current one in the history
+	Encoding string // character encoding override label, empty = detect
+	Redecode bool   // re-decode the last response instead of fetching
 }
 
 type Browser struct {
@@ -45,7 +53,10 @@ type Browser struct {
 	Height      float32
 	layoutTree  *layout.LayoutBox
 	currentURL  *url.URL
-	externalCSS string // CSS from <link> tags, stored for reflow
+	externalCSS string         // CSS from <link> tags, stored for reflow
+	styleSource string         // page CSS the layout tree was built with
+	stylesheet  css.Stylesheet // styleSource parsed, reused by ReflowChanged
+	pageScale   float64        // scale the page is drawn at: its page zoom times its viewport <meta> scale (see viewport.go)
 	OnNavigate  func(req NavigationRequest)
 
 	urlEntry    *widget.Entry
@@ -53,10 +64,16 @@ type Browser struct {
 	history     []string
 	historyPos  int
 	visitedURLs map[string]bool
+	visits      []visit // pages loaded this session, for about:history
+	title       string
+
+	privacyOnce  sync.Once
+	privacyToken string // secret the about:privacy forms carry to clear data (see privacyNonce)
 
 	document *dom.Node
 
 	// Input state - keyed by DOM node (stable across reflow)
+	focusedNode      *dom.Node // element with focus; drives :focus and the focus ring
 	focusedInputNode *dom.Node
 	inputValues      map[*dom.Node]string
 	openSelectNode   *dom.Node // Which select dropdown is open
@@ -64,6 +81,7 @@ type Browser struct {
 	checkboxValue    map[*dom.Node]bool
 	fileInputValues  map[*dom.Node]string
 	invalidNodes     map[*dom.Node]bool
+	formHistory      map[string][]string // submitted values by autofill key (see autofill.go)
 
 	scrollOffsets    map[*dom.Node]float64 // Horizontal scroll offset per overflow container
 	scrollDragNode   *dom.Node             // Which node's scrollbar is being dragged
@@ -76,19 +94,100 @@ type Browser struct {
 	scrollDragStartOffY float64              // Vertical scroll offset at drag start
 
 	onJSClick        func(node *dom.Node) bool // Returns true if preventDefault was called
+	onJSToggle       func(node *dom.Node)      // fires toggle at a <details> element (see details.go)
+	onJSDialogCancel func(node *dom.Node)      // fires cancel at a modal <dialog> on Escape (see dialog_element.go)
 	onBeforeNavigate func() bool               // Returns true if navigation should proceed
 
-	selectionStart *SelectionPoint
-	selectionEnd   *SelectionPoint
-	selectedText   string
+	// Script dialogs (see dialogs.go)
+	dialogs     dialogQueue
+	pageDialogs pageDialogs
+
+	// Popup blocking (see popups.go)
+	onJSActivation func()         // key presses grant user activation
+	popupSites     map[string]bool // sites allowed to open popups freely
+	blockedPopups  []string        // popups blocked on the current page
+
+	// Color scheme and forced dark mode (see darkmode.go)
+	colorScheme   string // matched by prefers-color-scheme
+	forceDark     bool   // darken pages without dark styles while the scheme is dark
+	hasDarkStyles bool   // the current page has prefers-color-scheme: dark rules
+
+	// Frame scheduling (see frames.go)
+	frames           *frameScheduler
+	onAnimationFrame func(frameTime time.Time) bool
+
+	// Each display layer as last drawn, for repaints to redraw only what
+	// changed (see damage.go)
+	normalFrame layerFrame
+	fixedFrame  layerFrame
+	tiles       pageTiles // the page layer as scrolled (see tiles.go)
+
+	// Page scrolling (see pagescroll.go, scrollbar.go)
+	smoothScroll  smoothScroll
+	scrollbar     *pageScrollbar
+	scrollPending atomic.Bool // the page scrolled since the last frame
+	reflowPending atomic.Bool // a reflow is queued on the UI thread (see scheduleReflow)
+	onJSScroll    func()
+	onJSPrint     func()
+	// Backgrounds shown beneath the page, moved as it scrolls (see
+	// fixedbackground.go)
+	fixedBackgrounds []*fixedBackground
+
+	// Page icon (see favicon.go)
+	favicon   image.Image
+	onFavicon func(image.Image)
+
+	// Server certificate of the page (see certerror.go)
+	certificate   *utils.CertificateInfo
+	onCertificate func(*utils.CertificateInfo)
+
+	// Pending Refresh of the page (see refresh.go)
+	refreshMu    sync.Mutex
+	refreshTimer *time.Timer
+
+	// Profiling overlay (see profile.go)
+	profileOverlay *fyne.Container
+	profileStop    chan struct{} // stops refreshing the overlay
+
+	// Caret and selection in the focused text field (see textedit.go)
+	editNode    *dom.Node // field edit belongs to
+	edit        FieldEdit
+	shiftHeld   bool
+	caretHidden bool // the caret is between blinks
+	caretBlinks int  // restarts of the caret's blinking, to stop stale ones
+
+	// Text selection (see selection.go)
+	selection    *textSelection
+	selectedText string
+	clicks       clickCounter
 
 	toastContainer *fyne.Container
 	toastBg        *canvas.Rectangle
 	toastLabel     *canvas.Text
 	toastTimer     *time.Timer
 
+	// Hover state: hoveredNode drives both tooltips and :hover matching
+	hasHoverRules     bool
+	hoverRulesChecked bool
+
 	// Tooltip support
 	hoveredNode    *dom.Node
+	anchorHeading  *dom.Node         // heading showing the copy-link button
+	anchorOverlay  fyne.CanvasObject // copy-link button (see anchors.go)
+
+	// Table of contents sidebar (see outline.go)
+	outlinePanel  *fyne.Container
+	outlineList   *widget.List
+	outlineEmpty  *widget.Label
+	outlineItems  []*Section // flattened outline shown in the list
+	outlineDepths []int      // nesting depth of each item
+
+	// Text Encoding and Translate menus (see menu.go, translate.go)
+	encoding           string // canonical name of the page's encoding
+	encodingOverridden bool
+	translator         Translator
+	originalText       map[*dom.Node]string // text swapped out by TranslatePage
+	translatedTo       string
 	tooltipTimer   *time.Timer
 	tooltipOverlay *fyne.Container
 	tooltipPos     fyne.Position
@@ -96,26 +195,37 @@ type Browser struct {
 	toolbarHeight  float32           // Height of toolbar for tooltip positioning
 }
 
-type SelectionPoint struct {
-	X, Y float64
-	Box  *layout.LayoutBox
-}
-
 func NewBrowser(width, height float32) *Browser {
 	a := app.New()
 	w := a.NewWindow("Go Browser")
 	// Maximize window to fill screen (Fyne clamps to screen bounds)
 	w.Resize(fyne.NewSize(9999, 9999))
 
+	// Pick hinting/antialiasing defaults for the display's pixel density
+	TextRaster = DefaultTextRasterOptions(w.Canvas().Scale())
+
 	// Set up accurate text measurement using Fyne
-	layout.TextMeasurer = func(text string, fontSize float64, bold bool, italic bool) float64 {
-		style := fyne.TextStyle{Bold: bold, Italic: italic}
-		size := fyne.MeasureText(text, float32(fontSize), style)
-		return float64(size.Width)
+	layout.TextMeasurer = func(text string, fontSize float64, font layout.Font) float64 {
+		style := fyne.TextStyle{Bold: css.IsBold(font.Weight), Italic: font.Italic, Monospace: font.Monospace}
+		primary := resolveFontFamily(font.Families, font.Weight, font.Italic)
+		return float64(measureTextWithFallback(text, TextRaster.TextSize(float32(fontSize)), style, primary))
+	}
+	layout.InvalidateTextCache()
+
+	// Derive line-height: normal and leading from the theme font's metrics
+	if m, ok := readFontMetrics(theme.TextFont().Content()); ok {
+		layout.BaseFontMetrics = m
+	}
+
+	// Pages start in the desktop's light or dark appearance
+	colorScheme := css.ColorSchemeLight
+	if a.Settings().ThemeVariant() == theme.VariantDark {
+		colorScheme = css.ColorSchemeDark
 	}
 
 	b := &Browser{
 		App:             a,
+		colorScheme:     colorScheme,
 		Window:          w,
 		Width:           width,
 		Height:          height,
@@ -127,9 +237,11 @@ func NewBrowser(width, height float32) *Browser {
 		checkboxValue:   make(map[*dom.Node]bool),
 		fileInputValues: make(map[*dom.Node]string),
 		invalidNodes:    make(map[*dom.Node]bool),
+		formHistory:     make(map[string][]string),
 		scrollOffsets:   make(map[*dom.Node]float64),
 		scrollOffsetsY:  make(map[*dom.Node]float64),
 	}
+	b.frames = newFrameScheduler(b.runFrame)
 	// Create URL entry
 	b.urlEntry = widget.NewEntry()
 	b.urlEntry.SetPlaceHolder("Enter URL...")
@@ -147,18 +259,60 @@ func NewBrowser(width, height float32) *Browser {
 		}
 	}
 
-	// Handle Ctrl+C for text copy using Fyne's built-in ShortcutCopy
+	// Handle Ctrl+C for text copy using Fyne's built-in ShortcutCopy. The
+	// focused text field's selection goes before the page's
 	w.Canvas().AddShortcut(&fyne.ShortcutCopy{}, func(_ fyne.Shortcut) {
-		if b.selectedText != "" {
+		if text := b.selectedFieldText(); text != "" {
+			b.Window.Clipboard().SetContent(text)
+		} else if b.selectedText != "" {
 			b.Window.Clipboard().SetContent(b.selectedText)
 		}
 	})
 
+	// Handle Ctrl+X, Ctrl+V and Ctrl+A in the focused text field
+	w.Canvas().AddShortcut(&fyne.ShortcutCut{}, func(_ fyne.Shortcut) {
+		if text := b.cutFieldText(); text != "" {
+			b.Window.Clipboard().SetContent(text)
+			b.repaint()
+		}
+	})
+	w.Canvas().AddShortcut(&fyne.ShortcutPaste{}, func(_ fyne.Shortcut) {
+		if text := b.Window.Clipboard().Content(); text != "" && b.editable() {
+			b.insertText(text)
+			b.repaint()
+		}
+	})
+	w.Canvas().AddShortcut(&fyne.ShortcutSelectAll{}, func(_ fyne.Shortcut) {
+		if b.focusedInputNode != nil {
+			b.selectAllFieldText()
+			b.repaint()
+		}
+	})
+
 	// Handle Ctrl+W to close the application
 	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyW, Modifier: fyne.KeyModifierControl}, func(_ fyne.Shortcut) {
 		a.Quit()
 	})
 
+	// Handle Ctrl+Plus, Ctrl+Minus and Ctrl+0 to zoom the page. Plus is
+	// Shift+= on most layouts, so Ctrl+= zooms in as well
+	zoomIn := []*desktop.CustomShortcut{
+		{KeyName: fyne.KeyEqual, Modifier: fyne.KeyModifierControl},
+		{KeyName: fyne.KeyEqual, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift},
+		{KeyName: fyne.KeyPlus, Modifier: fyne.KeyModifierControl},
+	}
+	for _, shortcut := range zoomIn {
+		w.Canvas().AddShortcut(shortcut, func(_ fyne.Shortcut) {
+			go b.ZoomIn()
+		})
+	}
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyMinus, Modifier: fyne.KeyModifierControl}, func(_ fyne.Shortcut) {
+		go b.ZoomOut()
+	})
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.Key0, Modifier: fyne.KeyModifierControl}, func(_ fyne.Shortcut) {
+		go b.ResetZoom()
+	})
+
 	// Create buttons
 	goBtn := widget.NewButton("Go", func() {
 		url := b.urlEntry.Text
@@ -182,10 +336,24 @@ func NewBrowser(width, height float32) *Browser {
 		b.Refresh()
 	})
 
-	// Toolbar: [Back] [URL Entry] [Go]
+	outlineBtn := widget.NewButton("☰", func() {
+		b.ToggleOutlinePanel()
+	})
+
+	// Handle Ctrl+P to print the page to a PDF
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyP, Modifier: fyne.KeyModifierControl}, func(_ fyne.Shortcut) {
+		go b.printPage()
+	})
+
+	// Handle Ctrl+Shift+O to toggle the table of contents
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyO, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}, func(_ fyne.Shortcut) {
+		b.ToggleOutlinePanel()
+	})
+
+	// Toolbar: [Back] [Refresh] [Contents] [URL Entry] [Go]
 	toolbar := container.NewBorder(
 		nil, nil, // top, bottom
-		container.NewHBox(backBtn, refreshBtn), goBtn, // left, right
+		container.NewHBox(backBtn, refreshBtn, outlineBtn), goBtn, // left, right
 		b.urlEntry, // center (fills remaining space)
 	)
 
@@ -200,12 +368,15 @@ func NewBrowser(width, height float32) *Browser {
 	b.toastBg.Hide()
 	b.toastLabel.Hide()
 
-	// Main layout: toolbar on top, content below
+	b.outlinePanel = b.newOutlinePanel()
+
+	// Main layout: toolbar on top, contents sidebar on the left, content below
 	base := container.NewBorder(
-		toolbar, nil, nil, nil, // top, bottom, left, right
+		toolbar, nil, b.outlinePanel, nil, // top, bottom, left, right
 		b.content, // center
 	)
-	main := container.NewMax(base, b.toastContainer)
+	b.profileOverlay = b.newProfileOverlay()
+	main := container.NewMax(base, b.profileOverlay, b.toastContainer)
 
 	w.Canvas().SetOnTypedRune(func(r rune) {
 		b.handleTypedRune(r)
@@ -213,29 +384,61 @@ func NewBrowser(width, height float32) *Browser {
 	w.Canvas().SetOnTypedKey(func(key *fyne.KeyEvent) {
 		b.handleTypedKey(key)
 	})
+	// Typed keys carry no modifiers, so Shift is followed here for
+	// extending the selection in text fields
+	if dc, ok := w.Canvas().(desktop.Canvas); ok {
+		dc.SetOnKeyDown(func(key *fyne.KeyEvent) {
+			if isShiftKey(key.Name) {
+				b.shiftHeld = true
+			}
+		})
+		dc.SetOnKeyUp(func(key *fyne.KeyEvent) {
+			if isShiftKey(key.Name) {
+				b.shiftHeld = false
+			}
+		})
+	}
 
 	go func() {
-		var lastWidth float32
+		var lastWidth, lastHeight float32
+		lastScale := TextRaster.Scale
 		for {
 			size := w.Canvas().Size()
-			if size.Width != lastWidth && size.Width > 0 {
-				lastWidth = size.Width
-				b.Reflow(size.Width)
+			// The page scrollbar keeps its strip along the right edge
+			width := size.Width - b.sidebarWidth() - ScrollbarWidth
+			if scale := w.Canvas().Scale(); scale != lastScale && scale > 0 {
+				// Moved to a monitor of another pixel density
+				lastScale, lastWidth = scale, width
+				fyne.Do(func() {
+					b.setDeviceScale(scale)
+					b.Reflow(width)
+				})
+			} else if width != lastWidth && width > 0 {
+				lastWidth = width
+				fyne.Do(func() { b.Reflow(width) })
+			} else if size.Height != lastHeight {
+				// A taller window shows tiles not painted yet
+				fyne.Do(b.showTiles)
 			}
+			lastHeight = size.Height
 			// Check every 100ms
 			time.Sleep(100 * time.Millisecond)
 		}
 	}()
 
 	w.SetContent(main)
+	w.SetMainMenu(b.buildMainMenu())
 
 	return b
 }
 
 func (b *Browser) SetContent(layoutTree *layout.LayoutBox) {
 	b.layoutTree = layoutTree // Save it so handleClick can use it
+	if b.document != nil {
+		b.hasDarkStyles = b.pageStylesheet().HasColorSchemeRules(css.ColorSchemeDark)
+	}
 
-	normalCommands, fixedCommands := BuildDisplayLayers(layoutTree, InputState{}, LinkStyler{
+	normal, fixed := b.displayLayers(layoutTree, InputState{}, LinkStyler{
 		IsVisited:  b.IsVisited,
 		ResolveURL: b.resolveURL,
 	})
@@ -248,12 +451,13 @@ func (b *Browser) SetContent(layoutTree *layout.LayoutBox) {
 		pageURL = b.currentURL.String()
 	}
 
-	normalObjects := RenderToCanvas(normalCommands, baseURL, pageURL, false, b.triggerRepaint)
-	fixedObjects := RenderToCanvas(fixedCommands, baseURL, pageURL, false, b.triggerRepaint)
+	b.resetFrames()
+	b.tiles.reset()
+	normalObjects, _, fixedObjects, _ := b.renderLayers(normal, fixed, baseURL, pageURL, false, b.imageLoaded)
 
-	scroll := b.createContentScroll(normalObjects)
+	scroll := b.createContentScroll(normalObjects, nil, fyne.Position{})
 	overlay := container.NewWithoutLayout(fixedObjects...)
-	stack := container.NewStack(scroll, overlay)
+	stack := container.NewStack(b.fixedBackdrop(b.fixedFrame.fixedBackgrounds()), scroll, overlay, b.scrollbarLayer())
 	b.content.Objects = []fyne.CanvasObject{stack}
 	b.content.Refresh()
 }
@@ -265,6 +469,18 @@ func (b *Browser) AddToHistory(url string) {
 
 	b.history = append(b.history, url)
 	b.historyPos = len(b.history) - 1
+	b.recordVisit(url)
+}
+
+// ReplaceHistory puts url in place of the current history entry, for
+// navigations that replace the page rather than leave it.
+func (b *Browser) ReplaceHistory(url string) {
+	if b.historyPos < 0 || b.historyPos >= len(b.history) {
+		b.AddToHistory(url)
+		return
+	}
+	b.history[b.historyPos] = url
+	b.recordVisit(url)
 }
 
 func (b *Browser) IsVisited(url string) bool {
@@ -297,6 +513,10 @@ func (b *Browser) SetCurrentURL(rawURL string) {
 	if err == nil {
 		b.currentURL = parsed
 	}
+	b.setCertificate(utils.CertificateFor(rawURL))
+	b.blockedPopups = nil
+	// The Zoom menu shows the new site's zoom
+	b.refreshMainMenu()
 }
 
 func (b *Browser) GetCurrentURL() string {
@@ -314,9 +534,16 @@ func (b *Browser) handleClick(x, y float64) {
 		return
 	}
 
+	// Autofill suggestions are drawn over the page, so they take the click
+	// before whatever lies beneath them
+	if b.acceptSuggestionAt(x, y) {
+		return
+	}
+
 	// Hit test: prioritize fixed elements at viewport coordinates
 	hit := b.hitTestWithFixedPriority(x, y)
 	if hit == nil {
+		b.setFocus(nil)
 		fmt.Println("  No hit found")
 		if b.focusedInputNode != nil {
 			b.focusedInputNode = nil
@@ -325,11 +552,26 @@ func (b *Browser) handleClick(x, y float64) {
 		return
 	}
 	fmt.Printf("  Hit: %+v\n", hit.Text)
+	// A modal dialog makes the rest of the page inert
+	if hit.Node != nil && dom.BlockedByModal(hit.Node) {
+		return
+	}
+	b.setFocus(dom.FocusTarget(hit.Node))
+
+	if b.loadDeferredImage(hit) {
+		return
+	}
+
+	// A summary toggles its details once click handlers had their say
+	if summary := dom.ClickedSummary(hit.Node); summary != nil {
+		go b.clickSummary(hit.Node, summary)
+		return
+	}
 
 	// JS click dispatch moved to link handling section (for preventDefault support)
 	// For non-link elements, fire-and-forget is fine
 	isLinkClick := hit.FindLinkInfo() != nil
-	if b.onJSClick != nil && hit.Node != nil && !isLinkClick {
+	if b.onJSClick != nil && hit.Node != nil && !isLinkClick && dom.ClickedButton(hit.Node) == nil {
 		go b.onJSClick(hit.Node) // Fire and forget for non-links
 	}
 
@@ -368,6 +610,7 @@ func (b *Browser) handleClick(x, y float64) {
 
 		fmt.Print("click input box")
 		b.focusedInputNode = hit.Node // Store DOM node, not LayoutBox
+		b.placeCaret(hit, x, y)
 		b.repaint()
 		return
 	}
@@ -390,7 +633,7 @@ func (b *Browser) handleClick(x, y float64) {
 			return
 		}
 		fmt.Println("click checkbox")
-		b.checkboxValue[hit.Node] = !b.checkboxValue[hit.Node]
+		b.checkboxValue[hit.Node] = !b.isChecked(hit.Node)
 		b.repaint()
 		return
 	}
@@ -422,6 +665,7 @@ func (b *Browser) handleClick(x, y float64) {
 		fmt.Println("click textarea")
 		b.focusedInputNode = hit.Node
 		b.openSelectNode = nil // Close any open select
+		b.placeCaret(hit, x, y)
 		b.repaint()
 		return
 	}
@@ -469,20 +713,12 @@ func (b *Browser) handleClick(x, y float64) {
 		}
 	}
 
-	// Check if this is a form submission
-	if hit.Node != nil && (hit.Node.TagName == "button" ||
-		(hit.Node.TagName == "input" && hit.Node.Attributes["type"] == "submit")) {
-		if isNodeDisabled(hit.Node) {
-			return
-		}
-		buttonType := hit.Node.Attributes["type"]
-		// Default button type inside form is "submit"
-		if buttonType == "" || buttonType == "submit" {
-			if formNode := findParentForm(hit.Node); formNode != nil {
-				b.submitForm(formNode)
-				return
-			}
+	// A button submits or resets its form once click handlers had their say
+	if button := dom.ClickedButton(hit.Node); button != nil {
+		if !dom.IsControlDisabled(button) {
+			go b.activateButton(hit.Node, button)
 		}
+		return
 	}
 
 	linkInfo := hit.FindLinkInfo()
@@ -540,8 +776,8 @@ func (b *Browser) handleClick(x, y float64) {
 			return
 		}
 
-		if u, err := url.Parse(fullURL); err == nil && u.Fragment != "" {
-			if b.scrollToID(u.Fragment) {
+		if u, err := url.Parse(fullURL); err == nil && u.Fragment != "" && b.isCurrentDocument(u) {
+			if b.GoToSection(u.Fragment) {
 				return
 			}
 		}
@@ -551,26 +787,42 @@ func (b *Browser) handleClick(x, y float64) {
 			if b.onBeforeNavigate != nil && !b.onBeforeNavigate() {
 				return
 			}
-			b.OnNavigate(NavigationRequest{URL: fullURL, Method: "GET", ReferrerPolicy: linkInfo.ReferrerPolicy})
+			b.OnNavigate(NavigationRequest{URL: fullURL, Method: "GET", ReferrerPolicy: linkReferrerPolicy(linkInfo), FromURL: b.GetCurrentURL()})
 		}
 
 	}()
 }
 
+// linkReferrerPolicy returns the referrer policy of following a link: none
+// for rel=noreferrer, else its referrerpolicy attribute.
+func linkReferrerPolicy(link *layout.LinkInfo) string {
+	for _, rel := range strings.Fields(link.Rel) {
+		if strings.EqualFold(rel, "noreferrer") {
+			return "no-referrer"
+		}
+	}
+	return link.ReferrerPolicy
+}
+
 func (b *Browser) scrollToID(id string) bool {
 	node := dom.FindByID(b.document, id)
 	if node == nil {
 		return false
 	}
+	return b.scrollToNode(node)
+}
 
+// scrollToNode scrolls the page so node's box is at the top of the viewport.
+func (b *Browser) scrollToNode(node *dom.Node) bool {
 	box := findLayoutBoxByNode(b.layoutTree, node)
 	if box == nil {
 		return false
 	}
 
 	if b.contentScroll != nil {
-		b.contentScroll.Offset.Y = float32(box.Rect.Y)
+		b.contentScroll.Offset.Y = b.fromPage(0, box.Rect.Y).Y
 		b.contentScroll.Refresh()
+		b.showTiles()
 	}
 	return true
 }
@@ -602,9 +854,13 @@ func (b *Browser) openNewWindow(targetURL string) {
 
 	// For now, just show the URL - user can copy/paste
 	// Full implementation would create another Browser instance
-	label := canvas.NewText("New window: "+targetURL, ColorBlack)
-	label.TextSize = 16
-	newWindow.SetContent(container.NewCenter(label))
+	if targetURL == BlankURL {
+		newWindow.SetContent(canvas.NewRectangle(ColorWhite))
+	} else {
+		label := canvas.NewText("New window: "+targetURL, ColorBlack)
+		label.TextSize = 16
+		newWindow.SetContent(container.NewCenter(label))
+	}
 
 	newWindow.Show()
 }
@@ -673,6 +929,7 @@ func (b *Browser) Run() {
 }
 
 func (b *Browser) SetTitle(title string) {
+	b.title = title
 	if title == "" {
 		title = "Go Browser"
 	}
@@ -681,10 +938,70 @@ func (b *Browser) SetTitle(title string) {
 
 func (b *Browser) SetDocument(doc *dom.Node) {
 	b.document = doc
+	b.focusedNode = nil
+	webFonts.reset()
+	layout.InvalidateTextCache()
+	b.hoverRulesChecked = false
+	b.hideHeadingAnchor()
+	b.originalText = nil
+	b.translatedTo = ""
+	if b.outlinePanel != nil && b.outlinePanel.Visible() {
+		b.refreshOutlinePanel()
+	}
 }
 
 func (b *Browser) SetExternalCSS(cssContent string) {
 	b.externalCSS = cssContent
+	b.hoverRulesChecked = false
+}
+
+// pageHasHoverRules reports whether the current page's CSS uses :hover.
+// The result is cached until the document or external CSS changes.
+func (b *Browser) pageHasHoverRules() bool {
+	if b.document == nil {
+		return false
+	}
+	if !b.hoverRulesChecked {
+		fullCSS := b.externalCSS + "\n" + dom.FindActiveStyleContent(b.document)
+		b.hasHoverRules = css.Parse(fullCSS).HasPseudoClass("hover")
+		b.hoverRulesChecked = true
+	}
+	return b.hasHoverRules
+}
+
+// onHoverChanged restyles the page after the hovered element changes so
+// :hover rules apply. Pages without :hover rules skip the restyle entirely.
+func (b *Browser) onHoverChanged() {
+	if !b.pageHasHoverRules() {
+		return
+	}
+	b.scheduleReflow()
+}
+
+// setFocus moves focus to node, or clears it when node is nil, and restyles
+// the page so :focus rules and the focus ring follow. Text entry still goes
+// to focusedInputNode, which callers update themselves.
+func (b *Browser) setFocus(node *dom.Node) {
+	if node == b.focusedNode {
+		return
+	}
+	b.focusedNode = node
+	b.scheduleReflow()
+}
+
+// focusNext moves focus to the next element in tab order. Text fields
+// reached this way accept typed text.
+func (b *Browser) focusNext() {
+	if b.document == nil {
+		return
+	}
+	next := dom.NextInTabOrder(b.document, b.focusedNode)
+	b.focusedInputNode = nil
+	if next != nil && (next.TagName == "input" || next.TagName == "textarea") {
+		b.focusedInputNode = next
+	}
+	b.openSelectNode = nil
+	b.setFocus(next)
 }
 
 // findScrollbarAt walks the layout tree and returns the LayoutBox whose horizontal
@@ -761,8 +1078,7 @@ func (b *Browser) handleMouseDown(x, y float64) {
 	// Clear previous selection
 	hadSelection := b.selectedText != ""
 	b.selectedText = ""
-	b.selectionStart = nil
-	b.selectionEnd = nil
+	b.selection = nil
 
 	if b.layoutTree == nil {
 		if hadSelection {
@@ -843,17 +1159,12 @@ func (b *Browser) handleMouseDown(x, y float64) {
 		return
 	}
 
-	hit := b.hitTestWithFixedPriority(x, y)
-	if hit != nil {
-		b.selectionStart = &SelectionPoint{
-			X:   x,
-			Y:   y,
-			Box: hit,
-		}
+	if b.hitTestWithFixedPriority(x, y) != nil {
+		b.startSelection(x, y, b.clicks.press(x, y, time.Now()))
 	}
 
-	// Repaint to clear previous selection highlight
-	if hadSelection {
+	// Repaint to clear previous selection highlight or show the new one
+	if hadSelection || b.selectedText != "" {
 		b.repaint()
 	}
 }
@@ -887,7 +1198,7 @@ func (b *Browser) handleDrag(x, y float64) {
 			newOffset = maxScroll
 		}
 		b.scrollOffsetsY[b.scrollDragNodeY] = newOffset
-		b.repaint()
+		b.scheduleRepaint()
 		return
 	}
 
@@ -919,22 +1230,20 @@ func (b *Browser) handleDrag(x, y float64) {
 			newOffset = maxScroll
 		}
 		b.scrollOffsets[b.scrollDragNode] = newOffset
-		b.repaint()
+		b.scheduleRepaint()
 		return
 	}
 
-	if b.selectionStart == nil || b.layoutTree == nil {
+	if b.selection == nil || b.layoutTree == nil {
 		return
 	}
 
-	hit := b.hitTestWithFixedPriority(x, y)
-	b.selectionEnd = &SelectionPoint{
-		X:   x,
-		Y:   y,
-		Box: hit,
+	focus, ok := caretAt(textBoxes(b.layoutTree), x, y)
+	if !ok || focus == b.selection.focus {
+		return
 	}
-
-	b.selectedText = b.collectSelectedText()
+	b.selection.focus = focus
+	b.selectedText = b.selection.text(textBoxes(b.layoutTree))
 	b.repaint()
 }
 
@@ -946,61 +1255,25 @@ func (b *Browser) findLayoutBoxByNode(node *dom.Node) *layout.LayoutBox {
 	return findBoxByNode(b.layoutTree, node)
 }
 
-func (b *Browser) collectSelectedText() string {
-	if b.selectionStart == nil || b.selectionEnd == nil {
-		return ""
-	}
-
-	var textParts []string
-	b.collectTextInRange(b.layoutTree, &textParts)
-	return strings.Join(textParts, " ")
-}
-
-func (b *Browser) collectTextInRange(box *layout.LayoutBox, parts *[]string) {
-	if box == nil {
-		return
-	}
-
-	// Check if this text box is within selection bounds
-	if box.Type == layout.TextBox && b.isBoxInSelection(box) {
-		*parts = append(*parts, box.Text)
-	}
-
-	for _, child := range box.Children {
-		b.collectTextInRange(child, parts)
-	}
-}
-
-func (b *Browser) isBoxInSelection(box *layout.LayoutBox) bool {
-	if b.selectionStart == nil || b.selectionEnd == nil {
-		return false
-	}
-
-	// Calculate selection bounds (handle reverse selection)
-	minY := min(b.selectionStart.Y, b.selectionEnd.Y)
-	maxY := max(b.selectionStart.Y, b.selectionEnd.Y)
-
-	boxTop := box.Rect.Y
-	boxBottom := box.Rect.Y + box.Rect.Height
-
-	// Box overlaps selection vertically
-	return boxBottom >= minY && boxTop <= maxY
-}
-
 // createContentScroll creates a scrollable container with all event handlers wired up.
 // This is the single source of truth for creating clickable content — always use this
 // instead of manually creating ClickableContainer to avoid missing handler bugs.
-func (b *Browser) createContentScroll(objects []fyne.CanvasObject) *container.Scroll {
-	clickable := NewClickableContainer(objects, func(x, y float32) {
-		b.handleClick(float64(x), float64(y))
+// The scroll starts at offset; damage is what changed since the page was last shown.
+func (b *Browser) createContentScroll(objects []fyne.CanvasObject, damage []layout.Rect, offset fyne.Position) *container.Scroll {
+	// The page is shown as tiles, those damage touches painted again
+	b.tiles.update(objects, damage)
+
+	// Handlers get layout coordinates, the page being drawn scaled
+	clickable := NewClickableContainer([]fyne.CanvasObject{b.tiles.layer}, func(x, y float32) {
+		b.handleClick(b.toPage(x, y))
 	}, b.layoutTree, b)
 
 	// Wire up handlers for text selection and scrollbar interaction
 	clickable.onDrag = func(x, y float32) {
-		b.handleDrag(float64(x), float64(y))
+		b.handleDrag(b.toPage(x, y))
 	}
 	clickable.onMouseDown = func(x, y float32) {
-		b.handleMouseDown(float64(x), float64(y))
+		b.handleMouseDown(b.toPage(x, y))
 	}
 	clickable.onDragEnd = func() {
 		b.scrollDragNode = nil
@@ -1008,36 +1281,68 @@ func (b *Browser) createContentScroll(objects []fyne.CanvasObject) *container.Sc
 	}
 
 	scroll := container.NewScroll(clickable)
+	clickable.onScroll = func(ev *fyne.ScrollEvent) {
+		x, y := b.toPage(ev.Position.X, ev.Position.Y)
+		if !b.handleWheel(x, y, float64(ev.Scrolled.DX), float64(ev.Scrolled.DY)) {
+			// Wheel deltas are positive when scrolling up or left
+			b.scrollPageBy(-ev.Scrolled.DX, -ev.Scrolled.DY, true)
+		}
+	}
+	scroll.Offset = offset
+	scroll.OnScrolled = func(fyne.Position) { b.pageScrolled() }
 	b.contentScroll = scroll // Store reference for tooltip positioning
+	b.showTiles()
+	if b.scrollbar != nil {
+		b.scrollbar.Refresh()
+	}
 	return scroll
 }
 
+// pageStylesheet parses the current page's CSS: external stylesheets plus
+// the active internal styles (respects disabled)
+func (b *Browser) pageStylesheet() css.Stylesheet {
+	return css.Parse(b.pageStyleSource())
+}
+
+// pageStyleSource returns the page's CSS: linked stylesheets, then the
+// contents of its <style> elements, whose URLs resolve against the document.
+func (b *Browser) pageStyleSource() string {
+	return b.externalCSS + "\n" + css.SheetBase("") + dom.FindActiveStyleContent(b.document)
+}
+
+// matchContext returns the browser state selectors are matched against.
+func (b *Browser) matchContext() css.MatchContext {
+	return css.MatchContext{
+		IsVisited:   func(url string) bool { return b.IsVisited(url) },
+		Hovered:     b.hoveredNode,
+		Focused:     b.focusedNode,
+		ColorScheme: b.ColorScheme(),
+	}
+}
+
 // Reflow re-computes layout with new width and repaints
 func (b *Browser) Reflow(width float32) {
 	if b.document == nil {
 		return
 	}
 
-	// Re-collect CSS: external + active internal styles (respects disabled)
-	fullCSS := b.externalCSS + "\n" + dom.FindActiveStyleContent(b.document)
-	stylesheet := css.Parse(fullCSS)
+	source := b.pageStyleSource()
+	stylesheet := css.Parse(source)
+	b.LoadWebFonts(stylesheet.FontFaces)
+	b.hasDarkStyles = stylesheet.HasColorSchemeRules(css.ColorSchemeDark)
 
 	// Re-build layout tree with updated stylesheet
-	matchCtx := css.MatchContext{
-		IsVisited: func(url string) bool { return b.IsVisited(url) },
-	}
-	layoutTree := layout.BuildLayoutTree(b.document, stylesheet, layout.Viewport{
-		Width:  float64(width),
-		Height: float64(b.Window.Canvas().Size().Height),
-	}, matchCtx)
-	layout.ComputeLayout(layoutTree, float64(width))
+	viewport := b.LayoutViewport(width, b.Window.Canvas().Size().Height)
+	layoutTree := layout.BuildLayoutTree(b.document, stylesheet, viewport, b.matchContext())
+	layout.ComputeLayout(layoutTree, viewport.Width)
 
 	// Update stored values
 	b.Width = width
 	b.layoutTree = layoutTree
+	b.styleSource, b.stylesheet = source, stylesheet
 
 	// Repaint with input state preserved (uses DOM node keys, stable across reflow)
-	normalCommands, fixedCommands := BuildDisplayLayers(layoutTree, InputState{
+	normal, fixed := b.displayLayers(layoutTree, InputState{
 		InputValues:     b.inputValues,
 		FocusedNode:     b.focusedInputNode,
 		OpenSelectNode:  b.openSelectNode,
@@ -1045,6 +1350,7 @@ func (b *Browser) Reflow(width float32) {
 		CheckboxValues:  b.checkboxValue,
 		FileInputValues: b.fileInputValues,
 		InvalidNodes:    b.invalidNodes,
+		Suggestions:     b.AutofillSuggestions(b.focusedInputNode),
 	}, LinkStyler{
 		IsVisited:  b.IsVisited,
 		ResolveURL: b.resolveURL,
@@ -1058,8 +1364,8 @@ func (b *Browser) Reflow(width float32) {
 	}
 
 	// Use cached images on reflow (don't re-fetch)
-	normalObjects := RenderToCanvas(normalCommands, baseURL, pageURL, true, b.triggerRepaint) // true = use cache
-	fixedObjects := RenderToCanvas(fixedCommands, baseURL, pageURL, true, b.triggerRepaint)
+	normalObjects, normalDamage, fixedObjects, _ := b.renderLayers(normal, fixed, baseURL, pageURL, true, b.imageLoaded) // true = use cache
+	backgrounds := b.fixedFrame.fixedBackgrounds()
 
 	// UI updates must be on main thread
 	fyne.Do(func() {
@@ -1069,16 +1375,48 @@ func (b *Browser) Reflow(width float32) {
 			scrollOffset = b.contentScroll.Offset
 		}
 
-		scroll := b.createContentScroll(normalObjects)
-		scroll.Offset = scrollOffset // Restore scroll position
+		scroll := b.createContentScroll(normalObjects, normalDamage, scrollOffset) // Restore scroll position
 		overlay := container.NewWithoutLayout(fixedObjects...)
-		stack := container.NewStack(scroll, overlay)
+		stack := container.NewStack(b.fixedBackdrop(backgrounds), scroll, overlay, b.scrollbarLayer())
 
 		b.content.Objects = []fyne.CanvasObject{stack}
 		b.content.Refresh()
 	})
 }
 
+// scheduleReflow lays the page out again at its current width on the UI
+// thread, which input handlers and the frame scheduler read the layout on.
+// Requests made before the reflow runs share it.
+func (b *Browser) scheduleReflow() {
+	if !b.reflowPending.CompareAndSwap(false, true) {
+		return
+	}
+	fyne.Do(func() {
+		b.reflowPending.Store(false)
+		b.Reflow(b.Width)
+	})
+}
+
+// ReflowChanged lays the page out again after scripts changed the DOM
+// under node, or anywhere when node is nil. When the change cannot move
+// the boxes around it, only the enclosing block is rebuilt; otherwise the
+// whole page reflows.
+func (b *Browser) ReflowChanged(node *dom.Node) {
+	if b.document == nil {
+		return
+	}
+	if node == nil || b.layoutTree == nil || b.pageStyleSource() != b.styleSource {
+		b.Reflow(b.Width)
+		return
+	}
+	viewport := b.LayoutViewport(b.Width, b.Window.Canvas().Size().Height)
+	if !layout.Relayout(b.layoutTree, node, b.stylesheet, viewport, b.matchContext()) {
+		b.Reflow(b.Width)
+		return
+	}
+	b.repaint()
+}
+
 func (b *Browser) ShowError(message string) {
 	fyne.Do(func() {
 		bg := canvas.NewRectangle(ColorWhite)
@@ -1103,12 +1441,6 @@ func (b *Browser) ShowError(message string) {
 	})
 }
 
-func (b *Browser) ShowAlert(message string) {
-	fyne.Do(func() {
-		dialog.ShowInformation("Alert", message, b.Window)
-	})
-}
-
 func (b *Browser) showToast(message string) {
 	fyne.Do(func() {
 		if b.toastTimer != nil {
@@ -1151,6 +1483,9 @@ func (b *Browser) Refresh() {
 }
 
 func (b *Browser) handleTypedRune(r rune) {
+	if b.onJSActivation != nil {
+		b.onJSActivation()
+	}
 	if b.focusedInputNode == nil {
 		return
 	}
@@ -1165,16 +1500,27 @@ func (b *Browser) handleTypedRune(r rune) {
 		return // Ignore non-numeric input
 	}
 
-	// Add character to input value
-	current := b.inputValues[b.focusedInputNode]
-	b.inputValues[b.focusedInputNode] = current + string(r)
+	// Replace the selection, or insert at the caret
+	b.insertText(string(r))
 
 	// Re-render to show new text
 	b.refreshContent()
 }
 
 func (b *Browser) handleTypedKey(key *fyne.KeyEvent) {
+	if b.onJSActivation != nil {
+		b.onJSActivation()
+	}
+	if key.Name == fyne.KeyTab {
+		b.focusNext()
+		return
+	}
+	// Escape closes a modal dialog
+	if key.Name == fyne.KeyEscape && b.cancelModalDialog() {
+		return
+	}
 	if b.focusedInputNode == nil {
+		b.scrollPageByKey(key.Name)
 		return
 	}
 
@@ -1183,27 +1529,30 @@ func (b *Browser) handleTypedKey(key *fyne.KeyEvent) {
 		if isNodeDisabled(b.focusedInputNode) || isNodeReadonly(b.focusedInputNode) {
 			return
 		}
-		current := b.inputValues[b.focusedInputNode]
-		if len(current) > 0 {
-			// Remove last character (handle UTF-8)
-			runes := []rune(current)
-			b.inputValues[b.focusedInputNode] = string(runes[:len(runes)-1])
-			b.repaint()
-		}
+		b.editKey(key.Name)
+		b.repaint()
 	case fyne.KeyReturn, fyne.KeyEnter:
-		if isNodeDisabled(b.focusedInputNode) || isNodeReadonly(b.focusedInputNode) {
+		if isNodeDisabled(b.focusedInputNode) {
+			return
+		}
+		if b.focusedInputNode.TagName != "textarea" {
+			b.submitImplicitly(b.focusedInputNode)
 			return
 		}
-		if b.focusedInputNode.TagName == "textarea" {
-			current := b.inputValues[b.focusedInputNode]
-			b.inputValues[b.focusedInputNode] = current + "\n"
+		if !isNodeReadonly(b.focusedInputNode) {
+			b.insertText("\n")
 			b.repaint()
 		}
 	case fyne.KeyEscape:
 		// Unfocus on escape
 		b.focusedInputNode = nil
 		b.openSelectNode = nil
+		b.setFocus(nil)
 		b.repaint()
+	default:
+		if b.editKey(key.Name) {
+			b.repaint()
+		}
 	}
 }
 
@@ -1247,7 +1596,7 @@ func (b *Browser) getSelectOptionByIndex(selectNode *dom.Node, index int) string
 				// Get text content
 				for _, textNode := range child.Children {
 					if textNode.Type == dom.Text {
-						return textNode.Text
+						return dom.CollapseWhitespace(textNode.Text)
 					}
 				}
 				return ""
@@ -1264,7 +1613,7 @@ func (b *Browser) repaint() {
 		return
 	}
 
-	normalCommands, fixedCommands := BuildDisplayLayers(b.layoutTree, InputState{
+	normal, fixed := b.displayLayers(b.layoutTree, InputState{
 		InputValues:     b.inputValues,
 		FocusedNode:     b.focusedInputNode,
 		OpenSelectNode:  b.openSelectNode,
@@ -1272,10 +1621,12 @@ func (b *Browser) repaint() {
 		CheckboxValues:  b.checkboxValue,
 		FileInputValues: b.fileInputValues,
 		InvalidNodes:    b.invalidNodes,
+		Suggestions:     b.AutofillSuggestions(b.focusedInputNode),
 		ScrollOffsets:   b.scrollOffsets,
 		ScrollOffsetsY:  b.scrollOffsetsY,
-		SelectionStart:  b.selectionStart,
-		SelectionEnd:    b.selectionEnd,
+		Selection:       b.selectedRanges(),
+		Edit:            b.fieldEdit(),
+		CaretHidden:     b.caretHidden,
 	}, LinkStyler{
 		IsVisited:  b.IsVisited,
 		ResolveURL: b.resolveURL,
@@ -1288,8 +1639,11 @@ func (b *Browser) repaint() {
 		pageURL = b.currentURL.String()
 	}
 
-	normalObjects := RenderToCanvas(normalCommands, baseURL, pageURL, true, nil)
-	fixedObjects := RenderToCanvas(fixedCommands, baseURL, pageURL, true, nil)
+	normalObjects, normalDamage, fixedObjects, fixedDamage := b.renderLayers(normal, fixed, baseURL, pageURL, true, nil)
+	if len(normalDamage) == 0 && len(fixedDamage) == 0 {
+		return // the page looks as it did
+	}
+	backgrounds := b.fixedFrame.fixedBackgrounds()
 
 	fyne.Do(func() {
 		// Preserve scroll position
@@ -1298,139 +1652,56 @@ func (b *Browser) repaint() {
 			scrollOffset = b.contentScroll.Offset
 		}
 
-		scroll := b.createContentScroll(normalObjects)
-		scroll.Offset = scrollOffset // Restore scroll position
+		scroll := b.createContentScroll(normalObjects, normalDamage, scrollOffset) // Restore scroll position
 		overlay := container.NewWithoutLayout(fixedObjects...)
-		stack := container.NewStack(scroll, overlay)
+		stack := container.NewStack(b.fixedBackdrop(backgrounds), scroll, overlay, b.scrollbarLayer())
 		b.content.Objects = []fyne.CanvasObject{stack}
 		b.content.Refresh()
 	})
 }
 
-func hasFixedPosition(box *layout.LayoutBox) bool {
-	for current := box; current != nil; current = current.Parent {
-		if current.Position == "fixed" {
-			return true
-		}
-	}
-	return false
-}
-
+// hitTestWithFixedPriority returns the box at page point (x, y). Fixed
+// boxes, which do not scroll with the page, are found where they show in
+// the viewport and win over the page content beneath them.
 func (b *Browser) hitTestWithFixedPriority(x, y float64) *layout.LayoutBox {
 	if b.layoutTree == nil {
 		return nil
 	}
+	return b.layoutTree.HitTestScrolled(x, y, b.elementScrollOffsets())
+}
 
+// elementScrollOffsets returns the scroll offsets of the page and its inner
+// scroll containers.
+func (b *Browser) elementScrollOffsets() layout.ScrollOffsets {
 	scrollY := 0.0
 	if b.contentScroll != nil {
-		scrollY = float64(b.contentScroll.Offset.Y)
-	}
-
-	if fixedHit := b.layoutTree.HitTest(x, y-scrollY); fixedHit != nil && hasFixedPosition(fixedHit) {
-		return fixedHit
+		scrollY = float64(b.contentScroll.Offset.Y) / b.zoom()
 	}
-
-	return b.layoutTree.HitTest(x, y)
-}
-
-// refreshContent is an alias for repaint (called by keyboard handlers)
-func (b *Browser) refreshContent() {
-	b.repaint()
+	return layout.ScrollOffsets{X: b.scrollOffsets, Y: b.scrollOffsetsY, PageY: scrollY}
 }
 
-// collectFormData gathers all input name/value pairs from a form
-func (b *Browser) collectFormData(formNode *dom.Node) url.Values {
-	data := url.Values{}
-	b.collectInputs(formNode, data)
-	return data
-}
-
-// collectInputs recursively collects inputs from the DOM tree
-func (b *Browser) collectInputs(node *dom.Node, data url.Values) {
-	if node == nil {
-		return
+// handleWheel scrolls the innermost scroll container under (x, y) that can
+// still move in the wheel's direction. It reports false when none can, so
+// the page itself scrolls instead. Wheel deltas are positive when scrolling
+// up or left, as in fyne.
+func (b *Browser) handleWheel(x, y, dx, dy float64) bool {
+	if b.layoutTree == nil {
+		return false
 	}
-
-	if node.Type == dom.Element {
-		name := node.Attributes["name"]
-		if name != "" {
-			switch node.TagName {
-			case "input":
-				inputType := node.Attributes["type"]
-				switch inputType {
-				case "checkbox", "radio":
-					// Only include if checked
-					if node.Attributes["checked"] != "" || b.isChecked(node) {
-						value := node.Attributes["value"]
-						if value == "" {
-							value = "on" // Default value for checkboxes
-						}
-						data.Add(name, value)
-					}
-				case "file":
-					// Skip file inputs - they require multipart encoding
-				case "submit", "button":
-					// Don't include submit buttons in data
-				default:
-					// text, password, email, number, hidden, etc.
-					value := b.inputValues[node]
-					if value == "" {
-						value = node.Attributes["value"]
-					}
-					data.Add(name, value)
-				}
-			case "textarea":
-				value := b.inputValues[node]
-				data.Add(name, value)
-			case "select":
-				value := b.getSelectedValue(node)
-				data.Add(name, value)
-			}
-		}
+	offsets := b.elementScrollOffsets()
+	target := b.layoutTree.ScrollTarget(x, y, -dx, -dy, offsets)
+	if target == nil {
+		return false
 	}
-
-	// Recurse into children
-	for _, child := range node.Children {
-		b.collectInputs(child, data)
+	if offsets.ScrollBy(target, -dx, -dy) {
+		b.scheduleRepaint()
 	}
+	return true
 }
 
-func (b *Browser) ShowConfirm(message string) bool {
-	result := make(chan bool)
-
-	fyne.Do(func() {
-		dialog.ShowConfirm("Confirm", message, func(ok bool) {
-			result <- ok
-		}, b.Window)
-	})
-
-	return <-result
-}
-
-func (b *Browser) ShowPrompt(message, defaultValue string) *string {
-	result := make(chan *string)
-
-	fyne.Do(func() {
-		entry := widget.NewEntry()
-		entry.SetText(defaultValue)
-
-		// Create a wider container for the entry
-		label := widget.NewLabel(message)
-		entryContainer := container.NewGridWrap(fyne.NewSize(300, 36), entry)
-		content := container.NewVBox(label, entryContainer)
-
-		d := dialog.NewCustomConfirm("Prompt", "OK", "Cancel", content, func(ok bool) {
-			if ok {
-				text := entry.Text
-				result <- &text
-			} else {
-				result <- nil
-			}
-		}, b.Window)
-		d.Show()
-	})
-
-	return <-result
+// refreshContent is an alias for repaint (called by keyboard handlers)
+func (b *Browser) refreshContent() {
+	b.repaint()
 }
 
 // isNodeDisabled checks if a DOM node has the disabled attribute
@@ -1474,271 +1745,6 @@ func isNumericRune(r rune) bool {
 	return (r >= '0' && r <= '9') || r == '-'
 }
 
-// findParentForm walks up the DOM tree to find the parent <form> element
-func findParentForm(node *dom.Node) *dom.Node {
-	current := node
-	for current != nil {
-		if current.Type == dom.Element && current.TagName == "form" {
-			return current
-		}
-		current = current.Parent
-	}
-	return nil
-}
-
-// submitForm handles form submission
-func (b *Browser) submitForm(formNode *dom.Node) {
-
-	invalid := b.validateForm(formNode)
-	if len(invalid) > 0 {
-		b.invalidNodes = make(map[*dom.Node]bool)
-		for _, node := range invalid {
-			b.invalidNodes[node] = true
-		}
-		b.repaint()
-		return
-	}
-	b.invalidNodes = make(map[*dom.Node]bool)
-
-	// Get form attributes
-	action := formNode.Attributes["action"]
-	method := strings.ToUpper(formNode.Attributes["method"])
-	enctype := formNode.Attributes["enctype"]
-
-	if method == "" {
-		method = "GET" // Default method
-	}
-
-	// Collect form data
-	data := b.collectFormData(formNode)
-
-	// Build target URL
-	var targetURL string
-	if action == "" {
-		// Submit to current page
-		if b.currentURL != nil {
-			targetURL = b.currentURL.String()
-		}
-	} else {
-		// Resolve relative URL
-		targetURL = b.resolveURL(action)
-	}
-
-	switch method {
-	case "GET":
-		// Append query string to URL
-		if len(data) > 0 {
-			if strings.Contains(targetURL, "?") {
-				targetURL += "&" + data.Encode()
-			} else {
-				targetURL += "?" + data.Encode()
-			}
-		}
-
-		// Navigate to the URL
-		if b.OnNavigate != nil {
-			go func() {
-				if b.onBeforeNavigate != nil && !b.onBeforeNavigate() {
-					return
-				}
-				b.OnNavigate(NavigationRequest{URL: targetURL, Method: "GET"})
-			}()
-		}
-	case "POST":
-		if enctype == "multipart/form-data" {
-			body, contentType, err := b.buildMultipartBody(formNode)
-			if err != nil {
-				fmt.Println("Error building multipart body:", err)
-				return
-			}
-			if b.OnNavigate != nil {
-				go func() {
-					if b.onBeforeNavigate != nil && !b.onBeforeNavigate() {
-						return
-					}
-					b.OnNavigate(NavigationRequest{
-						URL:         targetURL,
-						Method:      "POST",
-						Body:        body,
-						ContentType: contentType,
-					})
-				}()
-			}
-		} else {
-			data := b.collectFormData(formNode)
-			if b.OnNavigate != nil {
-				go func() {
-					if b.onBeforeNavigate != nil && !b.onBeforeNavigate() {
-						return
-					}
-					b.OnNavigate(NavigationRequest{
-						URL:    targetURL,
-						Method: "POST",
-						Data:   data,
-					})
-				}()
-			}
-		}
-	}
-}
-
-// isChecked checks if a checkbox/radio is currently checked
-func (b *Browser) isChecked(node *dom.Node) bool {
-	inputType := node.Attributes["type"]
-	if inputType == "checkbox" {
-		return b.checkboxValue[node]
-	}
-	if inputType == "radio" {
-		name := node.Attributes["name"]
-		return b.radioValues[name] == node
-	}
-	return false
-}
-
-// getSelectedValue gets the selected option value from a select element
-func (b *Browser) getSelectedValue(selectNode *dom.Node) string {
-	// Check if we have a stored value
-	if val, ok := b.inputValues[selectNode]; ok {
-		return val
-	}
-
-	// Otherwise check DOM for selected attribute
-	for _, child := range selectNode.Children {
-		if child.TagName == "option" {
-			if child.Attributes["selected"] != "" {
-				value := child.Attributes["value"]
-				if value == "" {
-					// Use text content if no value attribute
-					for _, textNode := range child.Children {
-						if textNode.Type == dom.Text {
-							return textNode.Text
-						}
-					}
-				}
-				return value
-			}
-		}
-	}
-
-	// Return first option if none selected
-	for _, child := range selectNode.Children {
-		if child.TagName == "option" {
-			value := child.Attributes["value"]
-			if value == "" {
-				for _, textNode := range child.Children {
-					if textNode.Type == dom.Text {
-						return textNode.Text
-					}
-				}
-			}
-			return value
-		}
-	}
-	return ""
-}
-
-func (b *Browser) buildMultipartBody(formNode *dom.Node) ([]byte, string, error) {
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
-
-	b.addMultipartFields(writer, formNode)
-	b.addmultipartFiles(writer, formNode)
-
-	err := writer.Close()
-	if err != nil {
-		return nil, "", err
-	}
-
-	return body.Bytes(), writer.FormDataContentType(), nil
-}
-
-// addMultipartFields adds non-file form fields to the multipart writer
-func (b *Browser) addMultipartFields(writer *multipart.Writer, node *dom.Node) {
-	if node == nil {
-		return
-	}
-
-	if node.Type == dom.Element {
-		name := node.Attributes["name"]
-		if name != "" {
-			switch node.TagName {
-			case "input":
-				inputType := node.Attributes["type"]
-				switch inputType {
-				case "file":
-					// Skip - handled separately
-				case "checkbox", "radio":
-					if b.isChecked(node) {
-						value := node.Attributes["value"]
-						if value == "" {
-							value = "on"
-						}
-						field, _ := writer.CreateFormField(name)
-						field.Write([]byte(value))
-					}
-				case "submit", "button":
-					// Skip submit buttons
-				default:
-					value := b.inputValues[node]
-					if value == "" {
-						value = node.Attributes["value"]
-					}
-					field, _ := writer.CreateFormField(name)
-					field.Write([]byte(value))
-				}
-			case "textarea":
-				value := b.inputValues[node]
-				field, _ := writer.CreateFormField(name)
-				field.Write([]byte(value))
-			case "select":
-				value := b.getSelectedValue(node)
-				field, _ := writer.CreateFormField(name)
-				field.Write([]byte(value))
-			}
-		}
-	}
-
-	for _, child := range node.Children {
-		b.addMultipartFields(writer, child)
-	}
-}
-
-func (b *Browser) addmultipartFiles(writer *multipart.Writer, node *dom.Node) {
-	if node == nil {
-		return
-	}
-
-	if node.Type == dom.Element && node.TagName == "input" {
-		inputType := node.Attributes["type"]
-		name := node.Attributes["name"]
-
-		if inputType == "file" && name != "" {
-			filePath := b.fileInputValues[node]
-			if filePath != "" {
-				fileData, err := os.ReadFile(filePath)
-				if err != nil {
-					fmt.Println("Error reading file for upload:", err)
-					return
-				}
-
-				filename := filepath.Base(filePath)
-
-				fileWriter, err := writer.CreateFormFile(name, filename)
-				if err != nil {
-					fmt.Println("Error creating form file field:", err)
-					return
-				}
-
-				fileWriter.Write(fileData)
-			}
-		}
-	}
-
-	for _, child := range node.Children {
-		b.addmultipartFiles(writer, child)
-	}
-}
-
 func (b *Browser) validateForm(formNode *dom.Node) []*dom.Node {
 	var invalidNodes []*dom.Node
 	b.validateInputs(formNode, &invalidNodes)
@@ -1793,8 +1799,45 @@ func (b *Browser) SetJSClickHandler(handler func(node *dom.Node) bool) {
 	b.onJSClick = handler
 }
 
-func (b *Browser) triggerRepaint() {
-	b.repaint()
+// imageLoaded runs when an image has loaded. Images whose natural size
+// changes their used size are laid out again, only around themselves when
+// there is one; otherwise the page repaints with the image in place.
+func (b *Browser) imageLoaded() {
+	baseURL := ""
+	if b.currentURL != nil {
+		baseURL = b.currentURL.Scheme + "://" + b.currentURL.Host
+	}
+	resized := staleImages(b.layoutTree, baseURL)
+	switch {
+	case len(resized) == 1:
+		b.ReflowChanged(resized[0])
+	case len(resized) > 1:
+		b.Reflow(b.Width)
+	default:
+		b.scheduleRepaint()
+	}
+}
+
+// staleImages gives the image boxes in tree the natural size of their
+// images that have loaded, and returns the nodes of those laid out at
+// another size.
+func staleImages(tree *layout.LayoutBox, baseURL string) []*dom.Node {
+	if tree == nil {
+		return nil
+	}
+	var stale []*dom.Node
+	if tree.Type == layout.ImageBox && tree.Node != nil {
+		if img, ok := cachedImage(resolveImageURL(tree.Node.Attributes["src"], baseURL)); ok {
+			setImageNaturalSize(tree.Node, img)
+		}
+		if layout.ImageNeedsReflow(tree) {
+			stale = append(stale, tree.Node)
+		}
+	}
+	for _, child := range tree.Children {
+		stale = append(stale, staleImages(child, baseURL)...)
+	}
+	return stale
 }
 
 func (b *Browser) SetBeforeNavigateHandler(handler func() bool) {
@@ -1836,7 +1879,7 @@ func (b *Browser) downloadURL(rawURL string) {
 		return
 	}
 
-	resp, err := http.Get(rawURL)
+	resp, err := utils.Get(rawURL)
 	if err != nil {
 		fmt.Println("Download error:", err)
 		return
@@ -1884,6 +1927,23 @@ func (b *Browser) hideTooltip() {
 	}
 }
 
+// contentToWindow converts scroll content coordinates to overlay (window)
+// coordinates.
+func (b *Browser) contentToWindow(pos fyne.Position) fyne.Position {
+	var scrollOffsetY float32 = 0
+	if b.contentScroll != nil {
+		scrollOffsetY = b.contentScroll.Offset.Y
+	}
+
+	// Account for toolbar height (approximately 40px)
+	toolbarHeight := b.toolbarHeight
+	if toolbarHeight == 0 {
+		toolbarHeight = 40
+	}
+	// and the contents sidebar left of the page
+	return fyne.NewPos(pos.X+b.sidebarWidth(), pos.Y-scrollOffsetY+toolbarHeight)
+}
+
 // showTooltip displays a tooltip at the given position
 func (b *Browser) showTooltip(text string, pos fyne.Position) {
 	b.hideTooltip()
@@ -1903,18 +1963,10 @@ func (b *Browser) showTooltip(text string, pos fyne.Position) {
 	tooltipWidth := textSize.Width + padding*2
 	tooltipHeight := textSize.Height + padding*2
 
-	// Convert scroll content coordinates to window coordinates
-	var scrollOffsetY float32 = 0
-	if b.contentScroll != nil {
-		scrollOffsetY = b.contentScroll.Offset.Y
-	}
-
-	// Account for toolbar height (approximately 40px)
-	toolbarHeight := float32(40)
-
 	// Position tooltip below and right of cursor, adjusted for scroll
-	tooltipX := pos.X + 12
-	tooltipY := pos.Y - scrollOffsetY + toolbarHeight + 20
+	windowPos := b.contentToWindow(pos)
+	tooltipX := windowPos.X + 12
+	tooltipY := windowPos.Y + 20
 
 	// Create container for tooltip
 	bg.Resize(fyne.NewSize(tooltipWidth, tooltipHeight))
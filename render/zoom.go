@@ -0,0 +1,131 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"browser/utils"
+
+	"fyne.io/fyne/v2"
+)
+
+// Page zoom scales the size of a CSS pixel. A zoomed page is laid out in
+// a viewport that many times narrower and shorter, so its text and boxes
+// grow, and then drawn scaled up to the window, as a viewport <meta> scale
+// is (see LayoutViewport). Zoom is chosen per site and remembered for the
+// rest of the session.
+
+// zoomLevels are the zoom factors Ctrl+Plus and Ctrl+Minus step through.
+var zoomLevels = []float64{0.25, 0.33, 0.5, 0.67, 0.75, 0.8, 0.9, 1, 1.1, 1.25, 1.5, 1.75, 2, 2.5, 3, 4, 5}
+
+// siteZoomState remembers the zoom of each site. It is shared by every
+// window, so a site opens at the zoom last chosen for it anywhere.
+type siteZoomState struct {
+	mu    sync.Mutex
+	zooms map[string]float64 // site -> zoom, absent when 1
+}
+
+var siteZooms = &siteZoomState{zooms: make(map[string]float64)}
+
+// get returns the zoom of site, 1 when none was chosen.
+func (s *siteZoomState) get(site string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if z, ok := s.zooms[site]; ok {
+		return z
+	}
+	return 1
+}
+
+// set makes zoom the zoom of site.
+func (s *siteZoomState) set(site string, zoom float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if zoom == 1 {
+		delete(s.zooms, site)
+		return
+	}
+	s.zooms[site] = zoom
+}
+
+// nextZoomLevel returns the zoom level after zoom, the next larger one when
+// in is true and the next smaller one otherwise. Past either end, the zoom
+// stays at the last level.
+func nextZoomLevel(zoom float64, in bool) float64 {
+	if in {
+		for _, level := range zoomLevels {
+			if level > zoom+1e-9 {
+				return level
+			}
+		}
+		return zoomLevels[len(zoomLevels)-1]
+	}
+	for i := len(zoomLevels) - 1; i >= 0; i-- {
+		if zoomLevels[i] < zoom-1e-9 {
+			return zoomLevels[i]
+		}
+	}
+	return zoomLevels[0]
+}
+
+// PageZoom returns the zoom of the current page's site.
+func (b *Browser) PageZoom() float64 {
+	return siteZooms.get(utils.SiteOf(b.GetCurrentURL()))
+}
+
+// SetPageZoom zooms the current site's pages to zoom, clamped to the zoom
+// levels, and lays the page out again with the same content at the top of
+// the window.
+func (b *Browser) SetPageZoom(zoom float64) {
+	zoom = min(max(zoom, zoomLevels[0]), zoomLevels[len(zoomLevels)-1])
+	if zoom == b.PageZoom() {
+		return
+	}
+	siteZooms.set(utils.SiteOf(b.GetCurrentURL()), zoom)
+	b.refreshMainMenu()
+	b.showToast(fmt.Sprintf("Zoom %d%%", int(math.Round(zoom*100))))
+
+	var top float64
+	if b.contentScroll != nil {
+		_, top = b.toPage(0, b.contentScroll.Offset.Y)
+	}
+	b.Reflow(b.Width)
+	fyne.Do(func() {
+		if b.contentScroll != nil {
+			b.contentScroll.Offset.Y = b.fromPage(0, top).Y
+			b.contentScroll.Refresh()
+			b.showTiles()
+		}
+	})
+}
+
+// ZoomIn zooms the current site to the next larger zoom level.
+func (b *Browser) ZoomIn() {
+	b.SetPageZoom(nextZoomLevel(b.PageZoom(), true))
+}
+
+// ZoomOut zooms the current site to the next smaller zoom level.
+func (b *Browser) ZoomOut() {
+	b.SetPageZoom(nextZoomLevel(b.PageZoom(), false))
+}
+
+// ResetZoom shows the current site at its actual size.
+func (b *Browser) ResetZoom() {
+	b.SetPageZoom(1)
+}
+
+// zoomMenuItem returns the View menu's Zoom submenu.
+func (b *Browser) zoomMenuItem() *fyne.MenuItem {
+	in := fyne.NewMenuItem("Zoom In", func() { go b.ZoomIn() })
+	out := fyne.NewMenuItem("Zoom Out", func() { go b.ZoomOut() })
+	actual := fyne.NewMenuItem("Actual Size", func() { go b.ResetZoom() })
+	zoom := b.PageZoom()
+	in.Disabled = zoom >= zoomLevels[len(zoomLevels)-1]
+	out.Disabled = zoom <= zoomLevels[0]
+	actual.Disabled = zoom == 1
+
+	item := fyne.NewMenuItem(fmt.Sprintf("Zoom (%d%%)", int(math.Round(zoom*100))), nil)
+	item.ChildMenu = fyne.NewMenu("", in, out, fyne.NewMenuItemSeparator(), actual)
+	return item
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"browser/dom"
+	"browser/layout"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextZoomLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		zoom float64
+		in   bool
+		want float64
+	}{
+		{"in from actual size", 1, true, 1.1},
+		{"out from actual size", 1, false, 0.9},
+		{"in from between levels", 1.2, true, 1.25},
+		{"out from between levels", 1.2, false, 1.1},
+		{"stays at the largest", 5, true, 5},
+		{"stays at the smallest", 0.25, false, 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextZoomLevel(tt.zoom, tt.in))
+		})
+	}
+}
+
+func TestPageZoomPerSite(t *testing.T) {
+	page, _ := url.Parse("https://docs.example.com/guide")
+	other, _ := url.Parse("https://other.org/")
+	siteZooms.set("docs.example.com", 1.5)
+	t.Cleanup(func() { siteZooms.set("docs.example.com", 1) })
+
+	assert.Equal(t, 1.5, (&Browser{currentURL: page}).PageZoom())
+	assert.Equal(t, 1.0, (&Browser{currentURL: other}).PageZoom())
+	assert.Equal(t, 1.0, (&Browser{}).PageZoom())
+}
+
+func TestZoomedLayoutViewport(t *testing.T) {
+	page, _ := url.Parse("https://example.com/")
+	siteZooms.set("example.com", 2)
+	t.Cleanup(func() { siteZooms.set("example.com", 1) })
+
+	t.Run("CSS pixels grow", func(t *testing.T) {
+		b := &Browser{currentURL: page, document: dom.Parse(strings.NewReader(`<p>Hi</p>`))}
+		assert.Equal(t, layout.Viewport{Width: 400, Height: 300}, b.LayoutViewport(800, 600))
+		assert.Equal(t, 2.0, b.zoom())
+
+		x, y := b.toPage(100, 50)
+		assert.Equal(t, 50.0, x)
+		assert.Equal(t, 25.0, y)
+	})
+
+	t.Run("combined with the viewport meta scale", func(t *testing.T) {
+		b := &Browser{currentURL: page, document: dom.Parse(strings.NewReader(
+			`<meta name="viewport" content="width=device-width, initial-scale=2"><p>Hi</p>`))}
+		assert.Equal(t, layout.Viewport{Width: 200, Height: 150}, b.LayoutViewport(800, 600))
+		assert.Equal(t, 4.0, b.zoom())
+	})
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"container/list"
+	"image"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache holds fetched resources, or values decoded from them, keyed by URL.
+// Each entry remembers when it was stored so browsing data can be cleared
+// by site and time range.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+
+	// A limited cache drops the least recently used of the values size
+	// counts once they hold more than limit bytes
+	limit  int64
+	size   func(value any) int64
+	used   int64
+	recent *list.List // URLs of the counted entries, most recent first
+}
+
+type cacheEntry struct {
+	value  any
+	stored time.Time
+	bytes  int64
+	recent *list.Element
+}
+
+// CacheEntry describes a cached resource.
+type CacheEntry struct {
+	URL    string
+	Stored time.Time
+}
+
+// DecodedImageLimit is how many bytes of decoded images HTTPCache keeps.
+const DecodedImageLimit = 256 << 20
+
+// HTTPCache is the browser's resource cache. Decoded images are dropped
+// least recently used first past DecodedImageLimit, to be fetched again
+// when next shown; other resources stay.
+var HTTPCache = NewLimitedCache(DecodedImageLimit, decodedImageSize)
+
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]cacheEntry)}
+}
+
+// NewLimitedCache returns a cache that holds at most limit bytes of the
+// values size counts, dropping the least recently used past it. Values
+// size counts as 0 are never dropped.
+func NewLimitedCache(limit int64, size func(value any) int64) *Cache {
+	c := NewCache()
+	c.limit, c.size, c.recent = limit, size, list.New()
+	return c
+}
+
+// decodedImageSize counts the bytes of a decoded image's pixels.
+func decodedImageSize(value any) int64 {
+	if img, ok := value.(image.Image); ok && img != nil {
+		b := img.Bounds()
+		return int64(b.Dx()) * int64(b.Dy()) * 4
+	}
+	return 0
+}
+
+// Get returns the value cached for url.
+func (c *Cache) Get(url string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	if ok && entry.recent != nil {
+		c.recent.MoveToFront(entry.recent)
+	}
+	return entry.value, ok
+}
+
+// Put caches value for url.
+func (c *Cache) Put(url string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(url)
+	entry := cacheEntry{value: value, stored: now()}
+	if c.size != nil {
+		entry.bytes = c.size(value)
+	}
+	if entry.bytes > 0 {
+		entry.recent = c.recent.PushFront(url)
+		c.used += entry.bytes
+	}
+	c.entries[url] = entry
+
+	// The newest entry stays, however large
+	for c.used > c.limit && c.recent.Len() > 1 {
+		c.remove(c.recent.Back().Value.(string))
+	}
+}
+
+// remove drops the entry for url.
+func (c *Cache) remove(url string) {
+	entry, ok := c.entries[url]
+	if !ok {
+		return
+	}
+	if entry.recent != nil {
+		c.recent.Remove(entry.recent)
+		c.used -= entry.bytes
+	}
+	delete(c.entries, url)
+}
+
+// Entries lists the cached resources ordered by URL.
+func (c *Cache) Entries() []CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for url, entry := range c.entries {
+		entries = append(entries, CacheEntry{URL: url, Stored: entry.stored})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return entries
+}
+
+// Clear removes entries for site (any site when empty) stored at or after
+// since, and returns how many were removed.
+func (c *Cache) Clear(site string, since time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for url, entry := range c.entries {
+		if matchesSite(SiteOf(url), site) && !entry.stored.Before(since) {
+			c.remove(url)
+			removed++
+		}
+	}
+	return removed
+}
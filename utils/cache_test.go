@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitedCache(t *testing.T) {
+	img := func(w, h int) image.Image { return image.NewRGBA(image.Rect(0, 0, w, h)) }
+	cached := func(c *Cache, url string) bool {
+		_, ok := c.Get(url)
+		return ok
+	}
+
+	c := NewLimitedCache(3*100*100*4, decodedImageSize)
+	c.Put("a.png", img(100, 100))
+	c.Put("b.png", img(100, 100))
+	c.Put("font.woff2", []byte("font"))
+	c.Put("c.png", img(100, 100))
+	c.Get("a.png") // a.png is now more recent than b.png
+
+	c.Put("d.png", img(100, 100))
+	assert.False(t, cached(c, "b.png"), "the least recently used image is dropped")
+	assert.True(t, cached(c, "a.png"))
+	assert.True(t, cached(c, "c.png"))
+	assert.True(t, cached(c, "d.png"))
+	assert.True(t, cached(c, "font.woff2"), "values without a size stay")
+
+	c.Put("huge.png", img(1000, 1000))
+	assert.True(t, cached(c, "huge.png"), "the newest image stays however large")
+	assert.Len(t, c.Entries(), 2)
+
+	c.Clear("", time.Time{})
+	assert.Empty(t, c.Entries())
+	assert.Zero(t, c.used)
+}
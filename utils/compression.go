@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Content codings (RFC 9110 §8.4.1). Requests offer gzip, deflate and
+// brotli, and compressed responses are decoded before anything else sees
+// them, so the cache stores decoded bodies and callers never see
+// Content-Encoding.
+
+// acceptEncoding is the Accept-Encoding header sent with every request.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodingTransport asks for compressed responses and decodes them.
+type decodingTransport struct {
+	base http.RoundTripper
+}
+
+func (t decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		// A RoundTripper must not change the request it is given
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	var codings []string
+	for _, field := range resp.Header.Values("Content-Encoding") {
+		for _, coding := range strings.Split(field, ",") {
+			if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" && coding != "identity" {
+				codings = append(codings, coding)
+			}
+		}
+	}
+	if len(codings) == 0 || req.Method == http.MethodHead {
+		return resp, nil
+	}
+	for _, coding := range codings {
+		if !knownCoding(coding) {
+			return resp, nil // left for the caller to make what it can of
+		}
+	}
+	resp.Body = &decodingBody{src: resp.Body, codings: codings}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+func knownCoding(coding string) bool {
+	switch coding {
+	case "gzip", "x-gzip", "deflate", "br":
+		return true
+	}
+	return false
+}
+
+// decodingBody decodes a body compressed with codings, applied in order.
+// Decoding starts at the first Read, so empty bodies are not an error.
+type decodingBody struct {
+	src     io.ReadCloser
+	codings []string
+	r       io.Reader
+	err     error
+}
+
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = decodeContent(b.src, b.codings)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+func (b *decodingBody) Close() error {
+	return b.src.Close()
+}
+
+// decodeContent returns a reader of r with codings undone, the last applied
+// first.
+func decodeContent(r io.Reader, codings []string) (io.Reader, error) {
+	for i := len(codings) - 1; i >= 0; i-- {
+		switch codings[i] {
+		case "gzip", "x-gzip":
+			buffered := bufio.NewReader(r)
+			// An empty body decodes to nothing
+			if _, err := buffered.Peek(1); err == io.EOF {
+				return buffered, nil
+			}
+			gz, err := gzip.NewReader(buffered)
+			if err != nil {
+				return nil, err
+			}
+			r = gz
+		case "deflate":
+			r = newDeflateReader(r)
+		case "br":
+			r = brotli.NewReader(r)
+		}
+	}
+	return r, nil
+}
+
+// newDeflateReader decodes the deflate coding: zlib data, though some
+// servers send a raw deflate stream instead (RFC 9110 §8.4.1.2).
+func newDeflateReader(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if z, err := zlib.NewReader(buffered); err == nil {
+			return z
+		}
+	}
+	return flate.NewReader(buffered)
+}
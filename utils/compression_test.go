@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, coding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecodingTransport(t *testing.T) {
+	page := []byte("<p>compressed page</p>")
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		want     []byte
+	}{
+		{"gzip", "gzip", compress(t, "gzip", page), page},
+		{"deflate", "deflate", compress(t, "deflate", page), page},
+		{"raw deflate", "deflate", compress(t, "raw-deflate", page), page},
+		{"brotli", "br", compress(t, "br", page), page},
+		{"several codings", "gzip, br", compress(t, "br", compress(t, "gzip", page)), page},
+		{"identity", "identity", page, page},
+		{"an empty body", "gzip", nil, []byte{}},
+		{"unknown codings are left alone", "zstd", []byte("raw"), []byte("raw")},
+	}
+	var accepted string
+	var current []byte
+	var encoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(current)
+	}))
+	defer server.Close()
+	c := &http.Client{Transport: decodingTransport{base: http.DefaultTransport}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, encoding = tt.body, tt.encoding
+			resp, err := c.Get(server.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, body)
+			assert.Equal(t, "gzip, deflate, br", accepted)
+			if tt.encoding != "zstd" && tt.encoding != "identity" {
+				assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			}
+		})
+	}
+}
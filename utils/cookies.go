@@ -0,0 +1,448 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// StoredCookie is a cookie held by a CookieJar.
+type StoredCookie struct {
+	Name     string
+	Value    string
+	Domain   string // without a leading dot
+	Path     string
+	HostOnly bool // sent to Domain only, not its subdomains
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+	Expires  time.Time // zero for session cookies
+	Created  time.Time
+}
+
+// CookieJar stores cookies set by responses and scripts and returns them
+// for matching requests (RFC 6265 §5.3–§5.4), withholding SameSite cookies
+// from cross-site requests (RFC 6265bis §5.8.3). Unlike net/http/cookiejar
+// it can list and remove what it holds, and keep its persistent cookies in
+// a file. A site is a registrable domain, a public suffix and the label
+// before it.
+type CookieJar struct {
+	mu        sync.Mutex
+	cookies   map[string]*StoredCookie // keyed by domain, path and name
+	file      string                   // where persistent cookies are saved, if anywhere
+	saveTimer *time.Timer
+	saveMu    sync.Mutex // serializes writes to file
+}
+
+// Cookies is the jar every browser request uses.
+var Cookies = NewCookieJar()
+
+// cookieSaveDelay is how long after a change the jar is saved, so a burst
+// of Set-Cookie headers is written once.
+const cookieSaveDelay = time.Second
+
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: make(map[string]*StoredCookie)}
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.storeCookies(u, cookies, nil, true)
+}
+
+// storeCookies stores cookies set by a response to a request for u made
+// by initiator, or by a script when fromHTTP is false (RFC 6265bis §5.7).
+func (j *CookieJar) storeCookies(u *url.URL, cookies []*http.Cookie, initiator *requestInitiator, fromHTTP bool) {
+	host := strings.ToLower(u.Hostname())
+	secure := u.Scheme == "https"
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+		hostOnly := domain == ""
+		if hostOnly {
+			domain = host
+		} else if !domainMatch(host, domain) {
+			continue
+		} else if isPublicSuffix(domain) {
+			// Such a cookie would be sent to every site under the suffix;
+			// only the suffix itself may set one, for itself (RFC 6265 §5.3)
+			if domain != host {
+				continue
+			}
+			hostOnly = true
+		}
+		if c.Secure && !secure || c.HttpOnly && !fromHTTP {
+			continue
+		}
+		// SameSite=None is only honored on Secure cookies, and cross-site
+		// subresources cannot set the others
+		if c.SameSite == http.SameSiteNoneMode && !c.Secure || !initiator.mayStore(c.SameSite, u) {
+			continue
+		}
+		cookiePath := c.Path
+		if !strings.HasPrefix(cookiePath, "/") {
+			cookiePath = defaultCookiePath(u.Path)
+		}
+		key := domain + ";" + cookiePath + ";" + c.Name
+		old := j.cookies[key]
+		// Scripts cannot replace HttpOnly cookies, nor insecure pages
+		// Secure ones
+		if old != nil && (old.HttpOnly && !fromHTTP || old.Secure && !secure) {
+			continue
+		}
+
+		var expires time.Time
+		switch {
+		case c.MaxAge < 0:
+			j.remove(key)
+			continue
+		case c.MaxAge > 0:
+			expires = now().Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			if !c.Expires.After(now()) {
+				j.remove(key)
+				continue
+			}
+			expires = c.Expires
+		}
+
+		created := now()
+		if old != nil {
+			created = old.Created
+		}
+		j.cookies[key] = &StoredCookie{
+			Name: c.Name, Value: c.Value, Domain: domain, Path: cookiePath,
+			HostOnly: hostOnly, Secure: c.Secure, HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite, Expires: expires, Created: created,
+		}
+		j.changed()
+	}
+}
+
+// remove deletes the cookie stored under key, if any.
+func (j *CookieJar) remove(key string) {
+	if _, ok := j.cookies[key]; ok {
+		delete(j.cookies, key)
+		j.changed()
+	}
+}
+
+// Cookies implements http.CookieJar. Longer paths come first, then older
+// cookies (RFC 6265 §5.4).
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.requestCookies(u, http.MethodGet, nil, true)
+}
+
+// requestCookies returns the cookies for a request to u made with method
+// by initiator, or for a script when fromHTTP is false.
+func (j *CookieJar) requestCookies(u *url.URL, method string, initiator *requestInitiator, fromHTTP bool) []*http.Cookie {
+	host := strings.ToLower(u.Hostname())
+	requestPath := u.Path
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.removeExpired()
+
+	var matched []*StoredCookie
+	for _, c := range j.cookies {
+		if c.HostOnly && host != c.Domain || !c.HostOnly && !domainMatch(host, c.Domain) {
+			continue
+		}
+		if !pathMatch(requestPath, c.Path) || c.Secure && u.Scheme != "https" {
+			continue
+		}
+		if c.HttpOnly && !fromHTTP || !initiator.maySend(c.SameSite, u, method) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].Path) != len(matched[b].Path) {
+			return len(matched[a].Path) > len(matched[b].Path)
+		}
+		return matched[a].Created.Before(matched[b].Created)
+	})
+
+	cookies := make([]*http.Cookie, len(matched))
+	for i, c := range matched {
+		cookies[i] = &http.Cookie{Name: c.Name, Value: c.Value}
+	}
+	return cookies
+}
+
+// DocumentCookie returns document.cookie for a page at u: the cookies it
+// would be sent, less HttpOnly ones.
+func (j *CookieJar) DocumentCookie(u *url.URL) string {
+	var pairs []string
+	for _, c := range j.requestCookies(u, http.MethodGet, nil, false) {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// SetDocumentCookie stores a cookie assigned to document.cookie by a page
+// at u, given as a Set-Cookie header value. Scripts cannot set HttpOnly
+// cookies.
+func (j *CookieJar) SetDocumentCookie(u *url.URL, line string) {
+	c, err := http.ParseSetCookie(line)
+	if err != nil {
+		return
+	}
+	j.storeCookies(u, []*http.Cookie{c}, nil, false)
+}
+
+// All returns copies of the unexpired cookies ordered by domain, path and name.
+func (j *CookieJar) All() []StoredCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.removeExpired()
+	all := make([]StoredCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		all = append(all, *c)
+	}
+	sort.Slice(all, func(a, b int) bool {
+		if all[a].Domain != all[b].Domain {
+			return all[a].Domain < all[b].Domain
+		}
+		if all[a].Path != all[b].Path {
+			return all[a].Path < all[b].Path
+		}
+		return all[a].Name < all[b].Name
+	})
+	return all
+}
+
+// Clear removes cookies for site (any site when empty) created at or after
+// since, and returns how many were removed.
+func (j *CookieJar) Clear(site string, since time.Time) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	removed := 0
+	for key, c := range j.cookies {
+		if matchesSite(c.Domain, site) && !c.Created.Before(since) {
+			j.remove(key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Persist loads the cookies saved in file, then keeps the jar's persistent
+// cookies there, saving shortly after they change. A missing file is an
+// empty jar.
+func (j *CookieJar) Persist(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	var saved []StoredCookie
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return err
+		}
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.file = file
+	for _, c := range saved {
+		if c.Expires.IsZero() || !c.Expires.After(now()) {
+			continue
+		}
+		key := c.Domain + ";" + c.Path + ";" + c.Name
+		if j.cookies[key] == nil {
+			j.cookies[key] = &c
+		}
+	}
+	return nil
+}
+
+// Save writes the jar's persistent cookies to the file given to Persist
+// now, rather than after the next change.
+func (j *CookieJar) Save() error {
+	j.mu.Lock()
+	file := j.file
+	if j.saveTimer != nil {
+		j.saveTimer.Stop()
+		j.saveTimer = nil
+	}
+	j.removeExpired()
+	saved := []StoredCookie{}
+	for _, c := range j.cookies {
+		// Session cookies end with the browser
+		if !c.Expires.IsZero() {
+			saved = append(saved, *c)
+		}
+	}
+	j.mu.Unlock()
+	if file == "" {
+		return nil
+	}
+	sort.Slice(saved, func(a, b int) bool { return saved[a].Created.Before(saved[b].Created) })
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	j.saveMu.Lock()
+	defer j.saveMu.Unlock()
+	return writeProfileFile(file, data)
+}
+
+// changed schedules a save after the jar changed. j.mu must be held.
+func (j *CookieJar) changed() {
+	if j.file == "" || j.saveTimer != nil {
+		return
+	}
+	j.saveTimer = time.AfterFunc(cookieSaveDelay, func() { j.Save() })
+}
+
+func (j *CookieJar) removeExpired() {
+	for key, c := range j.cookies {
+		if !c.Expires.IsZero() && !c.Expires.After(now()) {
+			delete(j.cookies, key)
+		}
+	}
+}
+
+// domainMatch reports whether host is domain or a subdomain of it (RFC 6265 §5.1.3).
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil
+}
+
+// isPublicSuffix reports whether domain is a public suffix, such as com or
+// co.uk, under which unrelated sites register their names.
+func isPublicSuffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// pathMatch reports whether a request path falls under a cookie path (RFC 6265 §5.1.4).
+func pathMatch(requestPath, cookiePath string) bool {
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return len(requestPath) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") ||
+		requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath is the directory of the request path (RFC 6265 §5.1.4).
+func defaultCookiePath(requestPath string) string {
+	if !strings.HasPrefix(requestPath, "/") || strings.Count(requestPath, "/") == 1 {
+		return "/"
+	}
+	return path.Dir(requestPath)
+}
+
+// requestInitiator is the page a request is made for (RFC 6265bis §5.2).
+type requestInitiator struct {
+	site       string // the page's site
+	navigation bool   // the request navigates the top-level page
+}
+
+type initiatorKey struct{}
+
+// WithInitiator returns req marked as made by the page at pageURL, and as
+// a top-level navigation when navigation is set, so SameSite cookies go
+// only with the requests they allow. Unmarked requests, such as those for
+// URLs the user typed, are same-site with every page.
+func WithInitiator(req *http.Request, pageURL string, navigation bool) *http.Request {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return req
+	}
+	initiator := &requestInitiator{site: cookieSite(u.Hostname()), navigation: navigation}
+	return req.WithContext(context.WithValue(req.Context(), initiatorKey{}, initiator))
+}
+
+// sameSite reports whether a request to u is same-site with i.
+func (i *requestInitiator) sameSite(u *url.URL) bool {
+	return i == nil || i.site == cookieSite(u.Hostname())
+}
+
+// maySend reports whether a cookie whose SameSite attribute is sameSite is
+// sent with a request to u made with method: cross-site, Strict cookies
+// never are, and Lax ones only with top-level navigations by safe methods.
+func (i *requestInitiator) maySend(sameSite http.SameSite, u *url.URL, method string) bool {
+	if i.sameSite(u) {
+		return true
+	}
+	switch sameSite {
+	case http.SameSiteStrictMode:
+		return false
+	case http.SameSiteLaxMode:
+		return i.navigation && (method == http.MethodGet || method == http.MethodHead)
+	}
+	return true
+}
+
+// mayStore reports whether a response to a request to u may set a cookie
+// whose SameSite attribute is sameSite: cross-site, only top-level
+// navigations can set Strict and Lax cookies.
+func (i *requestInitiator) mayStore(sameSite http.SameSite, u *url.URL) bool {
+	if i.sameSite(u) || sameSite != http.SameSiteStrictMode && sameSite != http.SameSiteLaxMode {
+		return true
+	}
+	return i.navigation
+}
+
+// cookieSite returns the site of host for SameSite checks: its public
+// suffix and the label before it, or all of it for IP addresses and hosts
+// that are a public suffix themselves.
+func cookieSite(host string) string {
+	host = strings.ToLower(host)
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	site, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return site
+}
+
+// cookieTransport sends the jar's cookies with every request, redirects
+// included, and stores those the responses set. Script requests do so as
+// their credentials mode allows.
+type cookieTransport struct {
+	jar  *CookieJar
+	base http.RoundTripper
+}
+
+func (t cookieTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !credentialsAllowed(req) {
+		return t.base.RoundTrip(req)
+	}
+	initiator, _ := req.Context().Value(initiatorKey{}).(*requestInitiator)
+	if cookies := t.jar.requestCookies(req.URL, req.Method, initiator, true); len(cookies) > 0 {
+		// A RoundTripper must not change the request it is given
+		req = req.Clone(req.Context())
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.jar.storeCookies(req.URL, resp.Cookies(), initiator, true)
+	return resp, nil
+}
@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParse(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func cookieNames(cookies []*http.Cookie) []string {
+	names := []string{}
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCookieJarMatching(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(mustParse("https://www.example.com/app/login"), []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com"},
+		{Name: "root", Value: "3", Path: "/"},
+		{Name: "secure", Value: "4", Path: "/", Secure: true},
+		{Name: "foreign", Value: "5", Domain: "other.com"},
+		{Name: "tld", Value: "6", Domain: "com"},
+	})
+	jar.SetCookies(mustParse("https://shop.example.co.uk/"), []*http.Cookie{
+		{Name: "suffix", Value: "7", Domain: ".co.uk"},
+		{Name: "site", Value: "8", Domain: "example.co.uk"},
+	})
+
+	tests := []struct {
+		url      string
+		expected []string
+	}{
+		{"https://www.example.com/app/page", []string{"domain", "host", "root", "secure"}},
+		{"http://www.example.com/app/page", []string{"domain", "host", "root"}},
+		{"https://www.example.com/", []string{"root", "secure"}},
+		{"https://api.example.com/app/x", []string{"domain"}},
+		{"https://www.example.com/application", []string{"root", "secure"}},
+		{"https://other.com/", []string{}},
+		{"https://com/", []string{}},
+		{"https://other.co.uk/", []string{}},
+		{"https://www.example.co.uk/", []string{"site"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cookieNames(jar.Cookies(mustParse(tt.url))))
+		})
+	}
+}
+
+func TestCookieJarExpiry(t *testing.T) {
+	defer func() { now = time.Now }()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return base }
+
+	jar := NewCookieJar()
+	u := mustParse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "short", Value: "1", MaxAge: 60},
+		{Name: "session", Value: "2"},
+		{Name: "past", Value: "3", Expires: base.Add(-time.Hour)},
+	})
+	assert.Equal(t, []string{"session", "short"}, cookieNames(jar.Cookies(u)))
+
+	now = func() time.Time { return base.Add(2 * time.Minute) }
+	assert.Equal(t, []string{"session"}, cookieNames(jar.Cookies(u)))
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", MaxAge: -1}})
+	assert.Empty(t, jar.All())
+}
+
+func TestCookieJarClear(t *testing.T) {
+	defer func() { now = time.Now }()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	jar := NewCookieJar()
+	now = func() time.Time { return base }
+	jar.SetCookies(mustParse("https://example.com/"), []*http.Cookie{{Name: "old", Value: "1"}})
+	now = func() time.Time { return base.Add(time.Hour) }
+	jar.SetCookies(mustParse("https://shop.example.com/"), []*http.Cookie{{Name: "new", Value: "2"}})
+	jar.SetCookies(mustParse("https://other.com/"), []*http.Cookie{{Name: "other", Value: "3"}})
+
+	assert.Equal(t, 1, jar.Clear("example.com", base.Add(30*time.Minute)), "only cookies in the time range")
+	assert.Equal(t, 1, jar.Clear("example.com", time.Time{}))
+	assert.Len(t, jar.All(), 1)
+	assert.Equal(t, "other", jar.All()[0].Name)
+}
+
+func TestCookieJarSameSite(t *testing.T) {
+	jar := NewCookieJar()
+	u := mustParse("https://shop.example.com/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "none", Value: "1", SameSite: http.SameSiteNoneMode, Secure: true},
+		{Name: "lax", Value: "2", SameSite: http.SameSiteLaxMode},
+		{Name: "strict", Value: "3", SameSite: http.SameSiteStrictMode},
+		{Name: "default", Value: "4"},
+		{Name: "insecure-none", Value: "5", SameSite: http.SameSiteNoneMode},
+	})
+
+	from := func(pageURL string, navigation bool) *requestInitiator {
+		req, _ := http.NewRequest("GET", u.String(), nil)
+		initiator, _ := WithInitiator(req, pageURL, navigation).Context().Value(initiatorKey{}).(*requestInitiator)
+		return initiator
+	}
+	tests := []struct {
+		name      string
+		initiator *requestInitiator
+		method    string
+		expected  []string
+	}{
+		{"typed by the user", nil, "GET", []string{"default", "lax", "none", "strict"}},
+		{"same site", from("https://www.example.com/", false), "GET", []string{"default", "lax", "none", "strict"}},
+		{"cross-site subresource", from("https://other.com/", false), "GET", []string{"default", "none"}},
+		{"cross-site navigation", from("https://other.com/", true), "GET", []string{"default", "lax", "none"}},
+		{"cross-site form post", from("https://other.com/", true), "POST", []string{"default", "none"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cookieNames(jar.requestCookies(u, tt.method, tt.initiator, true)))
+		})
+	}
+
+	jar.storeCookies(u, []*http.Cookie{{Name: "tracker", Value: "1", SameSite: http.SameSiteLaxMode}}, from("https://other.com/", false), true)
+	jar.storeCookies(u, []*http.Cookie{{Name: "landing", Value: "1", SameSite: http.SameSiteLaxMode}}, from("https://other.com/", true), true)
+	assert.NotContains(t, cookieNames(jar.Cookies(u)), "tracker", "cross-site subresources cannot set Lax cookies")
+	assert.Contains(t, cookieNames(jar.Cookies(u)), "landing")
+	assert.Equal(t, "example.com", cookieSite("a.b.example.com"))
+	assert.Equal(t, "127.0.0.1", cookieSite("127.0.0.1"))
+	assert.Equal(t, "a.co.uk", cookieSite("x.a.co.uk"))
+	assert.NotEqual(t, cookieSite("a.co.uk"), cookieSite("b.co.uk"))
+	assert.Equal(t, "localhost", cookieSite("localhost"))
+}
+
+func TestDocumentCookie(t *testing.T) {
+	jar := NewCookieJar()
+	u := mustParse("https://example.com/app/")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "secret", Path: "/", HttpOnly: true},
+		{Name: "theme", Value: "dark", Path: "/"},
+	})
+	assert.Equal(t, "theme=dark", jar.DocumentCookie(u), "HttpOnly cookies are hidden from scripts")
+
+	jar.SetDocumentCookie(u, "lang=en; path=/")
+	jar.SetDocumentCookie(u, "session=stolen; path=/")
+	jar.SetDocumentCookie(u, "sneaky=1; HttpOnly")
+	jar.SetDocumentCookie(u, "theme=light; path=/; max-age=-1")
+	assert.Equal(t, "lang=en", jar.DocumentCookie(u))
+	assert.Equal(t, []string{"lang", "session"}, cookieNames(jar.Cookies(u)))
+	for _, c := range jar.All() {
+		if c.Name == "session" {
+			assert.Equal(t, "secret", c.Value, "scripts cannot replace HttpOnly cookies")
+		}
+	}
+
+	insecure := mustParse("http://example.com/")
+	jar.SetDocumentCookie(insecure, "token=1; Secure")
+	assert.NotContains(t, jar.DocumentCookie(mustParse("https://example.com/x")), "token", "only secure pages set Secure cookies")
+}
+
+func TestCookieJarPersist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profile", "cookies.json")
+	u := mustParse("https://example.com/")
+
+	jar := NewCookieJar()
+	assert.NoError(t, jar.Persist(file), "a missing file is an empty jar")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "remember", Value: "1", MaxAge: 3600, SameSite: http.SameSiteStrictMode, HttpOnly: true},
+		{Name: "session", Value: "2"},
+	})
+	assert.NoError(t, jar.Save())
+
+	reopened := NewCookieJar()
+	assert.NoError(t, reopened.Persist(file))
+	all := reopened.All()
+	if assert.Len(t, all, 1, "session cookies are not kept") {
+		assert.Equal(t, "remember", all[0].Name)
+		assert.Equal(t, http.SameSiteStrictMode, all[0].SameSite)
+		assert.True(t, all[0].HttpOnly)
+	}
+
+	reopened.Clear("", time.Time{})
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(file)
+		return err == nil && strings.TrimSpace(string(data)) == "[]"
+	}, 5*time.Second, 50*time.Millisecond, "changes are saved")
+}
+
+func TestCookieTransport(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Path+" "+r.Header.Get("Cookie"))
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "id", Value: "7", Path: "/"})
+			http.Redirect(w, r, "/home", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	jar := NewCookieJar()
+	c := &http.Client{Transport: cookieTransport{jar: jar, base: http.DefaultTransport}}
+	resp, err := c.Get(server.URL + "/login")
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+	assert.Equal(t, []string{"/login ", "/home id=7"}, got, "cookies set by a redirect go with the next request")
+}
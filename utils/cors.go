@@ -0,0 +1,451 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Requests made by scripts, with fetch() and XMLHttpRequest (Fetch §4). A
+// script reads responses from its page's own origin freely; one from
+// another origin only when the server allows it with
+// Access-Control-Allow-Origin, and then only the headers it exposes. A
+// request a plain form could not have made — another method, or headers
+// other than the safelisted ones — is first asked about with an OPTIONS
+// preflight. Cookies go with same-origin requests only, unless the script
+// asks to include them and the server allows it.
+
+// RequestMode is how a script request treats other origins.
+type RequestMode string
+
+const (
+	ModeCORS       RequestMode = "cors"        // readable if the server allows it
+	ModeNoCORS     RequestMode = "no-cors"     // sent, but the response is opaque
+	ModeSameOrigin RequestMode = "same-origin" // fails for other origins
+)
+
+// CredentialsMode is when a script request sends and stores cookies.
+type CredentialsMode string
+
+const (
+	CredentialsOmit       CredentialsMode = "omit"
+	CredentialsSameOrigin CredentialsMode = "same-origin"
+	CredentialsInclude    CredentialsMode = "include"
+)
+
+// Response types, as Response.type reports them.
+const (
+	ResponseBasic  = "basic"  // same-origin
+	ResponseCORS   = "cors"   // cross-origin, allowed by the server
+	ResponseOpaque = "opaque" // cross-origin no-cors; nothing can be read
+)
+
+// defaultPreflightMaxAge is how long a preflight's answer is reused when it
+// does not say, as in Chromium.
+const defaultPreflightMaxAge = 5 * time.Second
+
+// maxPreflightMaxAge caps how long a preflight's answer is reused.
+const maxPreflightMaxAge = 2 * time.Hour
+
+// ScriptRequest is a request a page's script makes.
+type ScriptRequest struct {
+	Method      string
+	URL         string // absolute
+	Header      http.Header
+	Body        []byte
+	Mode        RequestMode     // ModeCORS if empty
+	Credentials CredentialsMode // CredentialsSameOrigin if empty
+	PageURL     string          // the page whose script makes it
+	// ReferrerPolicy is the request's own referrer policy; the page's
+	// applies if it names none.
+	ReferrerPolicy string
+	// Context cancels the request, e.g. when the page is left; nil for none.
+	Context context.Context
+}
+
+// ScriptResponse is what a script may see of a response.
+type ScriptResponse struct {
+	Type       string // ResponseBasic, ResponseCORS or ResponseOpaque
+	URL        string // after redirects; "" when opaque
+	Redirected bool
+	Status     int // 0 when opaque
+	StatusText string
+	Header     http.Header // the headers the script may read
+	Body       []byte
+}
+
+// CORSError is the error of a script request the same-origin policy
+// blocked. Scripts are only told the request failed; Reason is for the
+// console.
+type CORSError struct {
+	URL    string
+	Reason string
+}
+
+func (e *CORSError) Error() string {
+	return fmt.Sprintf("request to %s blocked by CORS policy: %s", e.URL, e.Reason)
+}
+
+// forbiddenHeaders are the request headers scripts may not set; the
+// browser sets them itself.
+var forbiddenHeaders = map[string]bool{
+	"accept-charset": true, "accept-encoding": true, "access-control-request-headers": true,
+	"access-control-request-method": true, "connection": true, "content-length": true,
+	"cookie": true, "cookie2": true, "date": true, "dnt": true, "expect": true, "host": true,
+	"keep-alive": true, "origin": true, "referer": true, "te": true, "trailer": true,
+	"transfer-encoding": true, "upgrade": true, "via": true,
+}
+
+// ForbiddenHeader reports whether scripts are forbidden to set the request
+// header name.
+func ForbiddenHeader(name string) bool {
+	name = strings.ToLower(name)
+	return forbiddenHeaders[name] || strings.HasPrefix(name, "proxy-") || strings.HasPrefix(name, "sec-")
+}
+
+// simpleMethod reports whether a form could send method.
+func simpleMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPost
+}
+
+// unsafeHeaders returns, lower-cased and sorted, the names of the headers
+// in header a form could not send.
+func unsafeHeaders(header http.Header) []string {
+	var names []string
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		value := strings.Join(values, ", ")
+		safe := len(value) <= 128
+		switch lower {
+		case "accept", "accept-language", "content-language":
+		case "content-type":
+			mediaType, _, _ := strings.Cut(strings.ToLower(value), ";")
+			switch strings.TrimSpace(mediaType) {
+			case "application/x-www-form-urlencoded", "multipart/form-data", "text/plain":
+			default:
+				safe = false
+			}
+		default:
+			safe = false
+		}
+		if !safe {
+			names = append(names, lower)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// safelistedResponseHeaders are the headers of a cross-origin response
+// scripts may read without the server exposing them.
+var safelistedResponseHeaders = []string{
+	"Cache-Control", "Content-Language", "Content-Length", "Content-Type", "Expires", "Last-Modified", "Pragma",
+}
+
+// FetchFromScript makes req on behalf of a page's script and returns what
+// the script may see of the response. Requests the same-origin policy
+// blocks fail with a *CORSError.
+func FetchFromScript(req ScriptRequest) (*ScriptResponse, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, &CORSError{URL: req.URL, Reason: fmt.Sprintf("scheme %q is not supported", target.Scheme)}
+	}
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	mode, credentials := req.Mode, req.Credentials
+	if mode == "" {
+		mode = ModeCORS
+	}
+	if credentials == "" {
+		credentials = CredentialsSameOrigin
+	}
+	header := http.Header{}
+	for name, values := range req.Header {
+		if !ForbiddenHeader(name) {
+			header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	origin := OriginOf(req.PageURL)
+	if origin == "" {
+		origin = "null" // file: and about: pages have an opaque origin
+	}
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	crossOrigin := OriginOf(req.URL) != origin
+	if crossOrigin {
+		switch mode {
+		case ModeSameOrigin:
+			return nil, &CORSError{URL: req.URL, Reason: "the request's mode is same-origin"}
+		case ModeNoCORS:
+			if !simpleMethod(method) {
+				return nil, &CORSError{URL: req.URL, Reason: fmt.Sprintf("method %s is not allowed in no-cors mode", method)}
+			}
+			for _, name := range unsafeHeaders(header) {
+				header.Del(name)
+			}
+		case ModeCORS:
+			if err := preflight(ctx, req.URL, method, header, origin, credentials); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header = header
+	if crossOrigin && mode == ModeCORS || method != http.MethodGet && method != http.MethodHead {
+		httpReq.Header.Set("Origin", origin)
+	}
+	httpReq = withReferrer(withCredentials(httpReq, credentials, origin), req.PageURL, req.ReferrerPolicy)
+	resp, err := Send(WithInitiator(httpReq, req.PageURL, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	finalURL := resp.Request.URL.String()
+	if OriginOf(finalURL) != origin {
+		crossOrigin = true
+	}
+	if crossOrigin && mode == ModeNoCORS {
+		return &ScriptResponse{Type: ResponseOpaque, Header: http.Header{}}, nil
+	}
+	if crossOrigin && mode == ModeSameOrigin {
+		return nil, &CORSError{URL: req.URL, Reason: "redirected to another origin in same-origin mode"}
+	}
+	if crossOrigin {
+		if err := checkCORS(resp.Header, finalURL, origin, credentials); err != nil {
+			return nil, err
+		}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScriptResponse{
+		Type:       ResponseBasic,
+		URL:        finalURL,
+		Redirected: finalURL != httpReq.URL.String(),
+		Status:     resp.StatusCode,
+		StatusText: http.StatusText(resp.StatusCode),
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	}
+	result.Header.Del("Set-Cookie")
+	result.Header.Del("Set-Cookie2")
+	if crossOrigin {
+		result.Type = ResponseCORS
+		result.Header = exposedHeaders(resp.Header, credentials)
+	}
+	return result, nil
+}
+
+// checkCORS checks that the response headers of a cross-origin request from
+// origin allow the script to read it.
+func checkCORS(header http.Header, rawURL, origin string, credentials CredentialsMode) error {
+	allowed := header.Get("Access-Control-Allow-Origin")
+	switch {
+	case allowed == "":
+		return &CORSError{URL: rawURL, Reason: "no Access-Control-Allow-Origin header is present"}
+	case allowed == "*" && credentials == CredentialsInclude:
+		return &CORSError{URL: rawURL, Reason: "Access-Control-Allow-Origin must not be * when credentials are included"}
+	case allowed != "*" && allowed != origin:
+		return &CORSError{URL: rawURL, Reason: fmt.Sprintf("Access-Control-Allow-Origin %q does not match origin %q", allowed, origin)}
+	case credentials == CredentialsInclude && header.Get("Access-Control-Allow-Credentials") != "true":
+		return &CORSError{URL: rawURL, Reason: "Access-Control-Allow-Credentials is not true"}
+	}
+	return nil
+}
+
+// exposedHeaders returns the headers of a cross-origin response the script
+// may read: the safelisted ones and those Access-Control-Expose-Headers
+// names.
+func exposedHeaders(header http.Header, credentials CredentialsMode) http.Header {
+	names := slices.Clone(safelistedResponseHeaders)
+	exposed := headerList(header.Values("Access-Control-Expose-Headers"))
+	if slices.Contains(exposed, "*") && credentials != CredentialsInclude {
+		names = nil
+		for name := range header {
+			names = append(names, name)
+		}
+	} else {
+		names = append(names, exposed...)
+	}
+
+	result := http.Header{}
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(name)
+		if values := header.Values(name); len(values) > 0 && name != "Set-Cookie" && name != "Set-Cookie2" {
+			result[name] = values
+		}
+	}
+	return result
+}
+
+// headerList splits the comma separated values of a list header.
+func headerList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// preflightAnswer is what a preflight allowed, reused until it expires.
+type preflightAnswer struct {
+	methods []string // upper-cased; "*" for any
+	headers []string // lower-cased; "*" for any
+	expires time.Time
+}
+
+// preflights caches preflight answers by origin, URL and credentials mode.
+var preflights = struct {
+	sync.Mutex
+	answers map[string]preflightAnswer
+}{answers: make(map[string]preflightAnswer)}
+
+// preflight asks the server at rawURL whether origin's script may make a
+// request with method and header, unless a form could have made it or an
+// earlier answer already allows it.
+func preflight(ctx context.Context, rawURL, method string, header http.Header, origin string, credentials CredentialsMode) error {
+	unsafe := unsafeHeaders(header)
+	if simpleMethod(method) && len(unsafe) == 0 {
+		return nil
+	}
+	key := origin + " " + rawURL + " " + string(credentials)
+	preflights.Lock()
+	answer, ok := preflights.answers[key]
+	preflights.Unlock()
+	if ok && time.Now().Before(answer.expires) && answer.allows(method, unsafe, credentials) {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, noRedirectKey{}, true), http.MethodOptions, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if len(unsafe) > 0 {
+		req.Header.Set("Access-Control-Request-Headers", strings.Join(unsafe, ","))
+	}
+	// Preflights never carry cookies
+	resp, err := Send(withCredentials(req, CredentialsOmit, origin))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &CORSError{URL: rawURL, Reason: fmt.Sprintf("the preflight response has status %d", resp.StatusCode)}
+	}
+	if err := checkCORS(resp.Header, rawURL, origin, credentials); err != nil {
+		return err
+	}
+
+	answer = preflightAnswer{
+		methods: headerList(resp.Header.Values("Access-Control-Allow-Methods")),
+		headers: headerList(resp.Header.Values("Access-Control-Allow-Headers")),
+		expires: time.Now().Add(defaultPreflightMaxAge),
+	}
+	for i := range answer.methods {
+		answer.methods[i] = strings.ToUpper(answer.methods[i])
+	}
+	for i := range answer.headers {
+		answer.headers[i] = strings.ToLower(answer.headers[i])
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Access-Control-Max-Age")); err == nil {
+		answer.expires = time.Now().Add(min(time.Duration(seconds)*time.Second, maxPreflightMaxAge))
+	}
+	if !answer.allowsMethod(method, credentials) {
+		return &CORSError{URL: rawURL, Reason: fmt.Sprintf("method %s is not allowed by Access-Control-Allow-Methods", method)}
+	}
+	for _, name := range unsafe {
+		if !answer.allowsHeader(name, credentials) {
+			return &CORSError{URL: rawURL, Reason: fmt.Sprintf("request header %s is not allowed by Access-Control-Allow-Headers", name)}
+		}
+	}
+	preflights.Lock()
+	preflights.answers[key] = answer
+	preflights.Unlock()
+	return nil
+}
+
+func (a preflightAnswer) allows(method string, headers []string, credentials CredentialsMode) bool {
+	if !a.allowsMethod(method, credentials) {
+		return false
+	}
+	for _, name := range headers {
+		if !a.allowsHeader(name, credentials) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsMethod reports whether the answer allows method. Forms' methods
+// need no permission; "*" is a wildcard only without credentials.
+func (a preflightAnswer) allowsMethod(method string, credentials CredentialsMode) bool {
+	return simpleMethod(method) || slices.Contains(a.methods, method) ||
+		credentials != CredentialsInclude && slices.Contains(a.methods, "*")
+}
+
+// allowsHeader reports whether the answer allows the request header name.
+// "*" is a wildcard only without credentials, and never for Authorization.
+func (a preflightAnswer) allowsHeader(name string, credentials CredentialsMode) bool {
+	return slices.Contains(a.headers, name) ||
+		credentials != CredentialsInclude && name != "authorization" && slices.Contains(a.headers, "*")
+}
+
+// credentialsKey marks a request whose cookies are limited by its
+// credentials mode.
+type credentialsKey struct{}
+
+type requestCredentials struct {
+	mode   CredentialsMode
+	origin string // the origin of the page making the request
+}
+
+// withCredentials returns req marked to send and store cookies as mode
+// allows for a request from origin.
+func withCredentials(req *http.Request, mode CredentialsMode, origin string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), credentialsKey{}, requestCredentials{mode: mode, origin: origin}))
+}
+
+// credentialsAllowed reports whether req, or a redirect of it, sends and
+// stores cookies.
+func credentialsAllowed(req *http.Request) bool {
+	c, ok := req.Context().Value(credentialsKey{}).(requestCredentials)
+	if !ok {
+		return true
+	}
+	switch c.mode {
+	case CredentialsOmit:
+		return false
+	case CredentialsSameOrigin:
+		return OriginOf(req.URL.String()) == c.origin
+	}
+	return true
+}
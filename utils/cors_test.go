@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchFromScriptSameOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Secret", "shown")
+		w.Header().Add("Set-Cookie", "sid=1")
+		io.WriteString(w, r.Method+" "+r.Header.Get("Origin"))
+	}))
+	defer server.Close()
+	t.Cleanup(func() { Cookies.Clear("127.0.0.1", time.Time{}) })
+
+	resp, err := FetchFromScript(ScriptRequest{URL: server.URL + "/data", PageURL: server.URL + "/page"})
+	require.NoError(t, err)
+	assert.Equal(t, ResponseBasic, resp.Type)
+	assert.Equal(t, 200, resp.Status)
+	assert.Equal(t, "GET ", string(resp.Body), "no Origin on same-origin GETs")
+	assert.Equal(t, "shown", resp.Header.Get("X-Secret"))
+	assert.Empty(t, resp.Header.Values("Set-Cookie"), "scripts never see Set-Cookie")
+
+	resp, err = FetchFromScript(ScriptRequest{Method: "delete", URL: server.URL + "/data", PageURL: server.URL + "/page"})
+	require.NoError(t, err)
+	assert.Equal(t, "DELETE "+server.URL, string(resp.Body), "no preflight, but an Origin")
+}
+
+func TestFetchFromScriptCrossOrigin(t *testing.T) {
+	page := "https://app.example/page"
+	var allowOrigin, allowCredentials string
+	var gotOrigin, gotCookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOrigin, gotCookie = r.Header.Get("Origin"), r.Header.Get("Cookie")
+		if allowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+		}
+		if allowCredentials != "" {
+			w.Header().Set("Access-Control-Allow-Credentials", allowCredentials)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Hidden", "1")
+		w.Header().Set("X-Exposed", "2")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Exposed")
+		io.WriteString(w, "data")
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	Cookies.SetCookies(target, []*http.Cookie{{Name: "sid", Value: "1"}})
+	t.Cleanup(func() { Cookies.Clear(target.Hostname(), time.Time{}) })
+
+	_, err := FetchFromScript(ScriptRequest{URL: server.URL, PageURL: page})
+	var corsErr *CORSError
+	require.True(t, errors.As(err, &corsErr), "got %v", err)
+	assert.Contains(t, corsErr.Reason, "no Access-Control-Allow-Origin")
+	assert.Equal(t, "https://app.example", gotOrigin)
+	assert.Empty(t, gotCookie, "cookies go to the page's own origin only")
+
+	allowOrigin = "*"
+	resp, err := FetchFromScript(ScriptRequest{URL: server.URL, PageURL: page})
+	require.NoError(t, err)
+	assert.Equal(t, ResponseCORS, resp.Type)
+	assert.Equal(t, "data", string(resp.Body))
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "2", resp.Header.Get("X-Exposed"))
+	assert.Empty(t, resp.Header.Get("X-Hidden"))
+
+	_, err = FetchFromScript(ScriptRequest{URL: server.URL, PageURL: page, Credentials: CredentialsInclude})
+	require.True(t, errors.As(err, &corsErr), "* does not allow credentials")
+	assert.Equal(t, "sid=1", gotCookie)
+
+	allowOrigin, allowCredentials = "https://app.example", "true"
+	_, err = FetchFromScript(ScriptRequest{URL: server.URL, PageURL: page, Credentials: CredentialsInclude})
+	require.NoError(t, err)
+
+	allowOrigin = "https://other.example"
+	_, err = FetchFromScript(ScriptRequest{URL: server.URL, PageURL: page})
+	require.True(t, errors.As(err, &corsErr))
+	assert.Contains(t, corsErr.Reason, "does not match")
+
+	resp, err = FetchFromScript(ScriptRequest{URL: server.URL, PageURL: page, Mode: ModeNoCORS})
+	require.NoError(t, err)
+	assert.Equal(t, ResponseOpaque, resp.Type)
+	assert.Zero(t, resp.Status)
+	assert.Empty(t, resp.Body)
+
+	_, err = FetchFromScript(ScriptRequest{URL: server.URL, PageURL: page, Mode: ModeSameOrigin})
+	require.True(t, errors.As(err, &corsErr))
+}
+
+func TestFetchFromScriptPreflight(t *testing.T) {
+	var mu sync.Mutex
+	var preflightCount, requests int
+	var requestedHeaders string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Access-Control-Allow-Origin", "https://app.example")
+		if r.Method == http.MethodOptions {
+			preflightCount++
+			requestedHeaders = r.Header.Get("Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", "PUT")
+			w.Header().Set("Access-Control-Allow-Headers", "X-Token, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		requests++
+		io.WriteString(w, r.Method+" "+r.Header.Get("X-Token"))
+	}))
+	defer server.Close()
+	t.Cleanup(func() {
+		preflights.Lock()
+		preflights.answers = make(map[string]preflightAnswer)
+		preflights.Unlock()
+	})
+	page := "https://app.example/"
+	header := http.Header{"X-Token": {"abc"}, "Content-Type": {"application/json"}}
+
+	resp, err := FetchFromScript(ScriptRequest{Method: "PUT", URL: server.URL + "/item", Header: header, Body: []byte("{}"), PageURL: page})
+	require.NoError(t, err)
+	assert.Equal(t, "PUT abc", string(resp.Body))
+	assert.Equal(t, "content-type,x-token", requestedHeaders)
+
+	_, err = FetchFromScript(ScriptRequest{Method: "PUT", URL: server.URL + "/item", Header: header, PageURL: page})
+	require.NoError(t, err)
+	assert.Equal(t, 1, preflightCount, "the answer is reused until Access-Control-Max-Age")
+	assert.Equal(t, 2, requests)
+
+	_, err = FetchFromScript(ScriptRequest{Method: "PATCH", URL: server.URL + "/item", PageURL: page})
+	var corsErr *CORSError
+	require.True(t, errors.As(err, &corsErr), "got %v", err)
+	assert.Contains(t, corsErr.Reason, "method PATCH")
+	assert.Equal(t, 2, requests, "a failed preflight stops the request")
+
+	_, err = FetchFromScript(ScriptRequest{Method: "PUT", URL: server.URL + "/item", Header: http.Header{"X-Other": {"1"}}, PageURL: page})
+	require.True(t, errors.As(err, &corsErr))
+	assert.Contains(t, corsErr.Reason, "x-other")
+}
+
+func TestForbiddenHeader(t *testing.T) {
+	for _, name := range []string{"Cookie", "host", "Proxy-Authorization", "Sec-Fetch-Mode", "Origin"} {
+		assert.True(t, ForbiddenHeader(name), name)
+	}
+	for _, name := range []string{"X-Token", "Content-Type", "Authorization"} {
+		assert.False(t, ForbiddenHeader(name), name)
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DataDir returns the browser profile's directory, where data that outlives
+// a session is kept: $BROWSER_PROFILE when set, else browser-go in the
+// user's configuration directory. It is created if missing.
+func DataDir() (string, error) {
+	dir := os.Getenv("BROWSER_PROFILE")
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "browser-go")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// writeProfileFile replaces file, in the profile, with data readable only
+// by the user. It is written aside and renamed, so a crash leaves the old
+// file whole.
+func writeProfileFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
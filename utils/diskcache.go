@@ -0,0 +1,519 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP disk cache (RFC 9111), a private cache of the responses to GET
+// requests. A fresh response is answered from disk without a request; a
+// stale one is revalidated with If-None-Match or If-Modified-Since, and a
+// 304 renews it. When the network fails, stale responses are served
+// rather than an error unless they must be revalidated. Past its limit,
+// the least recently used responses are dropped.
+
+// DefaultDiskCacheLimit is how many bytes DiskCache keeps unless told
+// otherwise.
+const DefaultDiskCacheLimit = 256 << 20
+
+// DiskCache is the browser's HTTP cache. It caches nothing until opened.
+var DiskCache = NewResponseCache()
+
+// heuristicFreshnessLimit caps how long a response without an explicit
+// lifetime is fresh for, guessed from its Last-Modified (RFC 9111 §4.2.2).
+const heuristicFreshnessLimit = 24 * time.Hour
+
+// cacheableStatus lists the status codes stored (RFC 9110 §15.1).
+var cacheableStatus = map[int]bool{
+	http.StatusOK: true, http.StatusNonAuthoritativeInfo: true, http.StatusMovedPermanently: true,
+	http.StatusPermanentRedirect: true, http.StatusNotFound: true, http.StatusGone: true,
+}
+
+// ResponseCache stores HTTP responses in a directory, one file each.
+type ResponseCache struct {
+	mu      sync.Mutex
+	dir     string // "" while closed
+	limit   int64
+	used    int64
+	entries map[string]*diskEntry // URL -> entry
+}
+
+// diskEntry indexes a stored response.
+type diskEntry struct {
+	file   string
+	size   int64
+	stored time.Time
+	used   time.Time // last served, for dropping the least recently used
+}
+
+// storedResponse is a response as kept on disk: this header as a line of
+// JSON, then the body.
+type storedResponse struct {
+	URL          string
+	Status       int
+	Header       http.Header
+	Vary         map[string]string // the request headers the response varies on
+	RequestTime  time.Time
+	ResponseTime time.Time
+
+	body []byte
+}
+
+func NewResponseCache() *ResponseCache {
+	return &ResponseCache{entries: make(map[string]*diskEntry)}
+}
+
+// Open starts caching in dir, keeping at most limit bytes, and indexes the
+// responses a previous run left there.
+func (c *ResponseCache) Open(dir string, limit int64) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir, c.limit, c.used = dir, limit, 0
+	c.entries = make(map[string]*diskEntry)
+	for _, f := range files {
+		if f.IsDir() || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		r, err := readStoredResponse(path, false)
+		info, statErr := f.Info()
+		if err != nil || statErr != nil {
+			os.Remove(path)
+			continue
+		}
+		c.entries[r.URL] = &diskEntry{file: path, size: info.Size(), stored: r.ResponseTime, used: info.ModTime()}
+		c.used += info.Size()
+	}
+	c.evict()
+	return nil
+}
+
+// SetLimit changes how many bytes the cache keeps.
+func (c *ResponseCache) SetLimit(limit int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limit = limit
+	c.evict()
+}
+
+// Size returns how many bytes the cache holds.
+func (c *ResponseCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used
+}
+
+// Entries lists the cached responses ordered by URL.
+func (c *ResponseCache) Entries() []CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for url, entry := range c.entries {
+		entries = append(entries, CacheEntry{URL: url, Stored: entry.stored})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return entries
+}
+
+// Clear removes responses from site (any site when empty) stored at or
+// after since, and returns how many were removed.
+func (c *ResponseCache) Clear(site string, since time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := 0
+	for url, entry := range c.entries {
+		if matchesSite(SiteOf(url), site) && !entry.stored.Before(since) {
+			c.remove(url)
+			removed++
+		}
+	}
+	return removed
+}
+
+// lookup returns the response stored for req, nil if there is none or it
+// varies on request headers that differ.
+func (c *ResponseCache) lookup(req *http.Request) *storedResponse {
+	url := req.URL.String()
+	c.mu.Lock()
+	entry := c.entries[url]
+	c.mu.Unlock()
+	if entry == nil {
+		return nil
+	}
+	r, err := readStoredResponse(entry.file, true)
+	if err != nil || r.URL != url {
+		c.mu.Lock()
+		c.remove(url)
+		c.mu.Unlock()
+		return nil
+	}
+	for name, value := range r.Vary {
+		if req.Header.Get(name) != value {
+			return nil
+		}
+	}
+	return r
+}
+
+// touch marks the response for url as just served.
+func (c *ResponseCache) touch(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry := c.entries[url]; entry != nil {
+		entry.used = now()
+	}
+}
+
+// store writes r to disk, replacing any response stored for its URL.
+func (c *ResponseCache) store(r *storedResponse) {
+	header, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		return
+	}
+	sum := sha256.Sum256([]byte(r.URL))
+	file := filepath.Join(c.dir, hex.EncodeToString(sum[:16]))
+	data := append(append(header, '\n'), r.body...)
+	// Written aside and renamed, so readers never see half a response
+	if os.WriteFile(file+".tmp", data, 0o600) != nil || os.Rename(file+".tmp", file) != nil {
+		return
+	}
+	// The file replaced the old response's, if any
+	if old := c.entries[r.URL]; old != nil {
+		c.used -= old.size
+	}
+	c.entries[r.URL] = &diskEntry{file: file, size: int64(len(data)), stored: r.ResponseTime, used: now()}
+	c.used += int64(len(data))
+	c.evict()
+}
+
+// invalidate drops the response stored for url.
+func (c *ResponseCache) invalidate(url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(url)
+}
+
+// remove deletes the response for url. c.mu must be held.
+func (c *ResponseCache) remove(url string) {
+	entry := c.entries[url]
+	if entry == nil {
+		return
+	}
+	delete(c.entries, url)
+	c.used -= entry.size
+	os.Remove(entry.file)
+}
+
+// evict drops the least recently used responses until the cache is within
+// its limit. c.mu must be held.
+func (c *ResponseCache) evict() {
+	for c.used > c.limit && len(c.entries) > 0 {
+		oldest := ""
+		for url, entry := range c.entries {
+			if oldest == "" || entry.used.Before(c.entries[oldest].used) {
+				oldest = url
+			}
+		}
+		c.remove(oldest)
+	}
+}
+
+// maxEntrySize returns the largest response the cache stores, an eighth
+// of its limit so one download cannot empty it, or -1 while it is closed.
+func (c *ResponseCache) maxEntrySize() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		return -1
+	}
+	return c.limit / 8
+}
+
+// readStoredResponse reads the response stored in file, its body only if
+// withBody is set.
+func readStoredResponse(file string, withBody bool) (*storedResponse, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var r storedResponse
+	if err := json.Unmarshal(line, &r); err != nil {
+		return nil, err
+	}
+	if withBody {
+		if r.body, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+// cacheControl returns the directives of a Cache-Control header, lower
+// cased, with their values unquoted.
+func cacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, field := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(field, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// headerSeconds returns a delta-seconds value, false when it is not one.
+func headerSeconds(value string) (time.Duration, bool) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// freshnessLifetime returns how long after it was generated r is fresh
+// (RFC 9111 §4.2.1).
+func (r *storedResponse) freshnessLifetime() time.Duration {
+	cc := cacheControl(r.Header)
+	if maxAge, ok := headerSeconds(cc["max-age"]); ok {
+		return maxAge
+	}
+	date, dateErr := http.ParseTime(r.Header.Get("Date"))
+	if dateErr != nil {
+		date = r.ResponseTime
+	}
+	if expires := r.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			return 0 // an invalid date is in the past
+		}
+		return max(t.Sub(date), 0)
+	}
+	if modified, err := http.ParseTime(r.Header.Get("Last-Modified")); err == nil {
+		return min(max(date.Sub(modified)/10, 0), heuristicFreshnessLimit)
+	}
+	return 0
+}
+
+// age returns how old r is now (RFC 9111 §4.2.3).
+func (r *storedResponse) age() time.Duration {
+	apparent := time.Duration(0)
+	if date, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+		apparent = max(r.ResponseTime.Sub(date), 0)
+	}
+	ageValue, _ := headerSeconds(r.Header.Get("Age"))
+	corrected := ageValue + r.ResponseTime.Sub(r.RequestTime)
+	return max(apparent, corrected) + now().Sub(r.ResponseTime)
+}
+
+// fresh reports whether r can be served without asking the server.
+func (r *storedResponse) fresh() bool {
+	if _, noCache := cacheControl(r.Header)["no-cache"]; noCache {
+		return false
+	}
+	return r.freshnessLifetime() > r.age()
+}
+
+// servableStale reports whether r may be served stale when the server
+// cannot be reached.
+func (r *storedResponse) servableStale() bool {
+	cc := cacheControl(r.Header)
+	_, must := cc["must-revalidate"]
+	_, noCache := cc["no-cache"]
+	return !must && !noCache
+}
+
+// response returns r as the response to req.
+func (r *storedResponse) response(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(r.age().Seconds())))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
+
+// storable reports whether resp, answering req, may be stored and reused.
+func storable(req *http.Request, resp *http.Response) bool {
+	if !cacheableStatus[resp.StatusCode] || resp.Header.Get("Vary") == "*" {
+		return false
+	}
+	if _, ok := cacheControl(req.Header)["no-store"]; ok {
+		return false
+	}
+	cc := cacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	// Without a lifetime or validators it could never be used
+	_, maxAge := cc["max-age"]
+	return maxAge || resp.Header.Get("Expires") != "" || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// cacheTransport answers GET requests from a ResponseCache where it can,
+// and stores what the network answers.
+type cacheTransport struct {
+	cache *ResponseCache
+	base  http.RoundTripper
+}
+
+func (t cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	maxSize := t.cache.maxEntrySize()
+	if maxSize < 0 {
+		return t.base.RoundTrip(req)
+	}
+	if req.Method != http.MethodGet {
+		resp, err := t.base.RoundTrip(req)
+		// Unsafe methods make what was stored for the URL out of date
+		if err == nil && req.Method != http.MethodHead && resp.StatusCode < 400 {
+			t.cache.invalidate(req.URL.String())
+		}
+		return resp, err
+	}
+	reqCC := cacheControl(req.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		return t.base.RoundTrip(req)
+	}
+
+	cached := t.cache.lookup(req)
+	_, noCache := reqCC["no-cache"]
+	if cached != nil && !noCache && reqCC["max-age"] != "0" && cached.fresh() {
+		t.cache.touch(cached.URL)
+		return cached.response(req), nil
+	}
+
+	out := req
+	if cached != nil && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		etag, modified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+		if etag != "" || modified != "" {
+			out = req.Clone(req.Context())
+			if etag != "" {
+				out.Header.Set("If-None-Match", etag)
+			}
+			if modified != "" {
+				out.Header.Set("If-Modified-Since", modified)
+			}
+		}
+	}
+
+	requestTime := now()
+	resp, err := t.base.RoundTrip(out)
+	if err != nil || resp.StatusCode >= 500 {
+		if cached != nil && cached.servableStale() {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			t.cache.touch(cached.URL)
+			return cached.response(req), nil
+		}
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && out != req {
+		resp.Body.Close()
+		// The 304's headers update the stored ones (RFC 9111 §4.3.4)
+		for name, values := range resp.Header {
+			if name != "Content-Length" && name != "Set-Cookie" {
+				cached.Header[name] = values
+			}
+		}
+		cached.RequestTime, cached.ResponseTime = requestTime, now()
+		t.cache.store(cached)
+		// Its cookies are still set, but only this once
+		revalidated := cached.response(req)
+		if cookies := resp.Header.Values("Set-Cookie"); len(cookies) > 0 {
+			revalidated.Header["Set-Cookie"] = cookies
+		}
+		return revalidated, nil
+	}
+
+	if storable(req, resp) {
+		stored := &storedResponse{
+			URL:          req.URL.String(),
+			Status:       resp.StatusCode,
+			Header:       resp.Header.Clone(),
+			RequestTime:  requestTime,
+			ResponseTime: now(),
+		}
+		// Cookies are set once, by the response that carried them
+		stored.Header.Del("Set-Cookie")
+		for _, name := range strings.Split(resp.Header.Get("Vary"), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				if stored.Vary == nil {
+					stored.Vary = make(map[string]string)
+				}
+				stored.Vary[name] = req.Header.Get(name)
+			}
+		}
+		resp.Body = &cachingBody{ReadCloser: resp.Body, limit: maxSize, done: func(body []byte) {
+			stored.body = body
+			t.cache.store(stored)
+		}}
+	}
+	return resp, nil
+}
+
+// cachingBody passes a response body through, keeping a copy that is
+// stored once the body has been read to its end.
+type cachingBody struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	limit int64
+	done  func(body []byte)
+	over  bool
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.over {
+		b.buf.Write(p[:n])
+		if int64(b.buf.Len()) > b.limit {
+			b.over = true
+			b.buf = bytes.Buffer{}
+		}
+	}
+	if err == io.EOF && !b.over && b.done != nil {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+	return n, err
+}
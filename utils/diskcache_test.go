@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheClient returns a client caching in a fresh directory, and the
+// cache.
+func cacheClient(t *testing.T, limit int64) (*http.Client, *ResponseCache) {
+	cache := NewResponseCache()
+	require.NoError(t, cache.Open(t.TempDir(), limit))
+	return &http.Client{Transport: cacheTransport{cache: cache, base: http.DefaultTransport}}, cache
+}
+
+func fetchBody(t *testing.T, c *http.Client, method, url string, header http.Header) (int, string) {
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestDiskCache(t *testing.T) {
+	defer func() { now = time.Now }()
+	clock := time.Now()
+	now = func() time.Time { return clock }
+
+	hits := make(map[string]int)
+	var lastConditional http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		lastConditional = http.Header{"If-None-Match": r.Header.Values("If-None-Match"), "If-Modified-Since": r.Header.Values("If-Modified-Since")}
+		switch r.URL.Path {
+		case "/fresh.css":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag.png":
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "no-cache")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.Header().Set("Set-Cookie", "seen=1")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/modified.js":
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Header().Set("Cache-Control", "max-age=0")
+			if r.Header.Get("If-Modified-Since") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/private":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/lang":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			io.WriteString(w, r.Header.Get("Accept-Language"))
+			return
+		}
+		io.WriteString(w, "body of "+r.URL.Path)
+	}))
+	defer server.Close()
+	c, cache := cacheClient(t, DefaultDiskCacheLimit)
+
+	t.Run("fresh responses are served from disk", func(t *testing.T) {
+		for range 2 {
+			status, body := fetchBody(t, c, "GET", server.URL+"/fresh.css", nil)
+			assert.Equal(t, 200, status)
+			assert.Equal(t, "body of /fresh.css", body)
+		}
+		assert.Equal(t, 1, hits["/fresh.css"])
+
+		clock = clock.Add(2 * time.Minute)
+		fetchBody(t, c, "GET", server.URL+"/fresh.css", nil)
+		assert.Equal(t, 2, hits["/fresh.css"], "fetched again once stale")
+	})
+
+	t.Run("an ETag revalidates", func(t *testing.T) {
+		fetchBody(t, c, "GET", server.URL+"/etag.png", nil)
+		status, body := fetchBody(t, c, "GET", server.URL+"/etag.png", nil)
+		assert.Equal(t, 2, hits["/etag.png"], "no-cache asks every time")
+		assert.Equal(t, []string{`"v1"`}, lastConditional["If-None-Match"])
+		assert.Equal(t, 200, status, "a 304 is answered with the stored response")
+		assert.Equal(t, "body of /etag.png", body)
+
+		resp, err := c.Get(server.URL + "/etag.png")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, []string{"seen=1"}, resp.Header.Values("Set-Cookie"), "the 304's cookies are passed on")
+		req, _ := http.NewRequest("GET", server.URL+"/etag.png", nil)
+		assert.Empty(t, cache.lookup(req).Header.Values("Set-Cookie"), "but not stored")
+	})
+
+	t.Run("Last-Modified revalidates", func(t *testing.T) {
+		fetchBody(t, c, "GET", server.URL+"/modified.js", nil)
+		_, body := fetchBody(t, c, "GET", server.URL+"/modified.js", nil)
+		assert.Equal(t, []string{"Mon, 02 Jan 2006 15:04:05 GMT"}, lastConditional["If-Modified-Since"])
+		assert.Equal(t, "body of /modified.js", body)
+	})
+
+	t.Run("no-store is not stored", func(t *testing.T) {
+		fetchBody(t, c, "GET", server.URL+"/private", nil)
+		fetchBody(t, c, "GET", server.URL+"/private", nil)
+		assert.Equal(t, 2, hits["/private"])
+	})
+
+	t.Run("Vary", func(t *testing.T) {
+		_, en := fetchBody(t, c, "GET", server.URL+"/lang", http.Header{"Accept-Language": {"en"}})
+		_, fr := fetchBody(t, c, "GET", server.URL+"/lang", http.Header{"Accept-Language": {"fr"}})
+		assert.Equal(t, "en", en)
+		assert.Equal(t, "fr", fr)
+	})
+
+	t.Run("unsafe methods invalidate", func(t *testing.T) {
+		clock = clock.Add(-2 * time.Minute)
+		before := hits["/fresh.css"]
+		fetchBody(t, c, "POST", server.URL+"/fresh.css", nil)
+		fetchBody(t, c, "GET", server.URL+"/fresh.css", nil)
+		assert.Equal(t, before+2, hits["/fresh.css"])
+	})
+
+	t.Run("stale responses are served offline", func(t *testing.T) {
+		clock = clock.Add(time.Hour)
+		server.Close()
+		status, body := fetchBody(t, c, "GET", server.URL+"/fresh.css", nil)
+		assert.Equal(t, 200, status)
+		assert.Equal(t, "body of /fresh.css", body)
+
+		_, err := c.Get(server.URL + "/etag.png")
+		assert.Error(t, err, "no-cache responses are never served unchecked")
+	})
+
+	t.Run("entries persist", func(t *testing.T) {
+		reopened := NewResponseCache()
+		require.NoError(t, reopened.Open(cache.dir, DefaultDiskCacheLimit))
+		urls := func(c *ResponseCache) []string {
+			var urls []string
+			for _, entry := range c.Entries() {
+				urls = append(urls, entry.URL)
+			}
+			return urls
+		}
+		assert.Equal(t, urls(cache), urls(reopened))
+		assert.Equal(t, cache.Size(), reopened.Size())
+
+		assert.Zero(t, reopened.Clear("other.test", time.Time{}))
+		assert.Equal(t, len(urls(cache)), reopened.Clear(SiteOf(server.URL), time.Time{}))
+		assert.Zero(t, reopened.Size())
+	})
+}
+
+func TestDiskCacheLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, strings.Repeat("x", 1000))
+	}))
+	defer server.Close()
+	c, cache := cacheClient(t, 6*1500)
+
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e", "/f", "/g", "/h", "/i"} {
+		fetchBody(t, c, "GET", server.URL+path, nil)
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, cache.Size(), int64(6*1500))
+	urls := []string{}
+	for _, entry := range cache.Entries() {
+		urls = append(urls, strings.TrimPrefix(entry.URL, server.URL))
+	}
+	assert.NotContains(t, urls, "/a", "the least recently used went first")
+	assert.Contains(t, urls, "/i")
+
+	cache.SetLimit(1000)
+	assert.Empty(t, cache.Entries())
+}
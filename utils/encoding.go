@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+)
+
+// DecodeHTML converts an HTML response body to UTF-8 (HTML §13.2.3.2). A
+// non-empty override label (e.g. from the Text Encoding menu) takes
+// precedence over the BOM, the Content-Type charset and <meta charset>.
+// It returns the decoded text and the canonical name of the encoding used.
+func DecodeHTML(body []byte, contentType, override string) (string, string) {
+	enc, name := charset.Lookup(override)
+	if enc == nil {
+		enc, name, _ = charset.DetermineEncoding(body, contentType)
+	}
+	return decodeText(body, enc, name)
+}
+
+// RedecodeHTML changes the encoding of a document decoded by DecodeHTML to
+// the one a <meta> found while parsing declares, as the prescan of the
+// first 1024 bytes missed it (HTML §13.2.6.4.4, "change the encoding").
+// Only an encoding DecodeHTML guessed changes: not one from an override,
+// a BOM or the Content-Type. It reports false when nothing changes.
+func RedecodeHTML(body []byte, contentType, override, current, declared string) (string, string, bool) {
+	if override != "" || declared == "" {
+		return "", "", false
+	}
+	if _, _, certain := charset.DetermineEncoding(body, contentType); certain {
+		return "", "", false
+	}
+	enc, name := charset.Lookup(declared)
+	switch name {
+	case "":
+		return "", "", false
+	case "utf-16be", "utf-16le":
+		enc, name = charset.Lookup("utf-8")
+	case "x-user-defined":
+		enc, name = charset.Lookup("windows-1252")
+	}
+	if name == current {
+		return "", "", false
+	}
+	text, name := decodeText(body, enc, name)
+	return text, name, true
+}
+
+// DecodeCSS converts a stylesheet to UTF-8 (CSS Syntax §3.2): by its BOM,
+// else its Content-Type charset, else an @charset rule at its very start,
+// else fallback, the encoding of the document or stylesheet that linked
+// it, else UTF-8. It returns the decoded text and the canonical name of the
+// encoding used.
+func DecodeCSS(body []byte, contentType, fallback string) (string, string) {
+	enc, name := charset.Lookup(declaredCharset(body, contentType))
+	if enc == nil {
+		if declared, ok := cssCharsetRule(body); ok {
+			enc, name = charset.Lookup(declared)
+			// A stylesheet readable as ASCII cannot be UTF-16
+			if name == "utf-16be" || name == "utf-16le" {
+				enc, name = charset.Lookup("utf-8")
+			}
+		}
+	}
+	if enc == nil {
+		enc, name = charset.Lookup(fallback)
+	}
+	if enc == nil {
+		enc, name = charset.Lookup("utf-8")
+	}
+	return decodeText(body, enc, name)
+}
+
+// DecodeScript converts a classic script to UTF-8 (HTML §8.1.3.2): by its
+// BOM, else its Content-Type charset, else fallback, the document's
+// encoding, else UTF-8. It returns the decoded text and the canonical name
+// of the encoding used.
+func DecodeScript(body []byte, contentType, fallback string) (string, string) {
+	enc, name := charset.Lookup(declaredCharset(body, contentType))
+	if enc == nil {
+		enc, name = charset.Lookup(fallback)
+	}
+	if enc == nil {
+		enc, name = charset.Lookup("utf-8")
+	}
+	return decodeText(body, enc, name)
+}
+
+// declaredCharset returns the encoding label of a subresource's BOM, else
+// of its Content-Type charset, "" if it has neither.
+func declaredCharset(body []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(body, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(body, []byte("\xfe\xff")):
+		return "utf-16be"
+	case bytes.HasPrefix(body, []byte("\xff\xfe")):
+		return "utf-16le"
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		return params["charset"]
+	}
+	return ""
+}
+
+// cssCharsetRule returns the label of the @charset rule body starts with,
+// matched byte for byte as CSS Syntax §3.2 requires.
+func cssCharsetRule(body []byte) (string, bool) {
+	const prefix = `@charset "`
+	if !bytes.HasPrefix(body, []byte(prefix)) {
+		return "", false
+	}
+	rest := body[len(prefix):]
+	end := bytes.Index(rest, []byte(`";`))
+	if end < 0 || end > 64 {
+		return "", false
+	}
+	return string(rest[:end]), true
+}
+
+// decodeText decodes body with enc, dropping a leading BOM. Bodies enc
+// cannot decode are taken as UTF-8.
+func decodeText(body []byte, enc encoding.Encoding, name string) (string, string) {
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return string(body), "utf-8"
+	}
+	return strings.TrimPrefix(string(decoded), "\uFEFF"), name
+}
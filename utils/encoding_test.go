@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		override    string
+		wantText    string
+		wantName    string
+	}{
+		{"utf-8 meta", []byte(`<meta charset="utf-8"><p>caf` + "\xc3\xa9"), "text/html", "", `<meta charset="utf-8"><p>café`, "utf-8"},
+		{"content-type charset", []byte("<p>caf\xe9"), "text/html; charset=iso-8859-1", "", "<p>café", "windows-1252"},
+		{"meta charset", []byte(`<meta charset="shift_jis"><p>` + "\x93\xfa\x96\x7b"), "text/html", "", `<meta charset="shift_jis"><p>日本`, "shift_jis"},
+		{"utf-8 bom stripped", []byte("\xef\xbb\xbf<p>hi"), "", "", "<p>hi", "utf-8"},
+		{"override beats content-type", []byte("<p>caf\xc3\xa9"), "text/html; charset=windows-1252", "utf-8", "<p>café", "utf-8"},
+		{"override mojibake", []byte("<p>caf\xc3\xa9"), "text/html; charset=utf-8", "windows-1252", "<p>cafÃ©", "windows-1252"},
+		{"unknown override ignored", []byte("<p>caf\xc3\xa9"), "text/html; charset=utf-8", "bogus", "<p>café", "utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, name := DecodeHTML(tt.body, tt.contentType, tt.override)
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
+func TestRedecodeHTML(t *testing.T) {
+	late := []byte("<p>caf\xe9</p>" + strings.Repeat(" ", 1100) + `<meta charset="windows-1252">`)
+
+	text, name, ok := RedecodeHTML(late, "text/html", "", "utf-8", "windows-1252")
+	assert.True(t, ok)
+	assert.Equal(t, "windows-1252", name)
+	assert.True(t, strings.HasPrefix(text, "<p>café</p>"))
+
+	_, _, ok = RedecodeHTML(late, "text/html", "", "windows-1252", "latin1")
+	assert.False(t, ok, "same encoding")
+	_, _, ok = RedecodeHTML(late, "text/html; charset=utf-8", "", "utf-8", "windows-1252")
+	assert.False(t, ok, "content-type is certain")
+	_, _, ok = RedecodeHTML(late, "text/html", "utf-8", "utf-8", "windows-1252")
+	assert.False(t, ok, "override wins")
+	_, _, ok = RedecodeHTML(late, "text/html", "", "utf-8", "bogus")
+	assert.False(t, ok, "unknown label")
+
+	_, name, ok = RedecodeHTML(late, "text/html", "", "windows-1252", "utf-16le")
+	assert.True(t, ok)
+	assert.Equal(t, "utf-8", name, "utf-16 declared in ASCII means utf-8")
+}
+
+func TestDecodeCSS(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		fallback    string
+		wantText    string
+		wantName    string
+	}{
+		{"default utf-8", []byte(`p::before{content:"caf` + "\xc3\xa9" + `"}`), "text/css", "", `p::before{content:"café"}`, "utf-8"},
+		{"bom", []byte("\xef\xbb\xbfp{}"), "text/css; charset=windows-1252", "", "p{}", "utf-8"},
+		{"content-type charset", []byte(`p{content:"caf` + "\xe9" + `"}`), "text/css; charset=iso-8859-1", "utf-8", `p{content:"café"}`, "windows-1252"},
+		{"charset rule", []byte(`@charset "shift_jis"; p{content:"` + "\x93\xfa" + `"}`), "text/css", "utf-8", `@charset "shift_jis"; p{content:"日"}`, "shift_jis"},
+		{"charset rule must be exact", []byte(`@charset 'shift_jis'; p{}`), "text/css", "", `@charset 'shift_jis'; p{}`, "utf-8"},
+		{"charset rule utf-16 means utf-8", []byte(`@charset "utf-16"; p{}`), "", "windows-1252", `@charset "utf-16"; p{}`, "utf-8"},
+		{"referrer encoding", []byte(`p{content:"caf` + "\xe9" + `"}`), "text/css", "windows-1252", `p{content:"café"}`, "windows-1252"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, name := DecodeCSS(tt.body, tt.contentType, tt.fallback)
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
+func TestDecodeScript(t *testing.T) {
+	text, name := DecodeScript([]byte("alert('caf\xe9')"), "text/javascript; charset=iso-8859-1", "utf-8")
+	assert.Equal(t, "alert('café')", text)
+	assert.Equal(t, "windows-1252", name)
+
+	text, name = DecodeScript([]byte("alert('caf\xe9')"), "text/javascript", "windows-1252")
+	assert.Equal(t, "alert('café')", text)
+	assert.Equal(t, "windows-1252", name)
+
+	// No @charset rule for scripts
+	_, name = DecodeScript([]byte(`@charset "shift_jis";`), "", "")
+	assert.Equal(t, "utf-8", name)
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Local files. file: URLs are answered from the filesystem like any other
+// request, so a page opened from disk loads its stylesheets, scripts and
+// fonts by relative URL. A folder answers with a generated listing of its
+// files. Pages served over HTTP may link to local files but not load them.
+
+// fileTransport answers file: requests from the filesystem, and passes
+// every other to base.
+type fileTransport struct {
+	base http.RoundTripper
+}
+
+func (t fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "file" {
+		return t.base.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if initiator, _ := req.Context().Value(initiatorKey{}).(*requestInitiator); initiator != nil && !initiator.navigation {
+		return fileResponse(req, http.StatusForbidden, "Web pages cannot load local files."), nil
+	}
+
+	name := filepath.FromSlash(req.URL.Path)
+	info, err := os.Stat(name)
+	switch {
+	case os.IsNotExist(err):
+		return fileResponse(req, http.StatusNotFound, "File not found: "+name), nil
+	case err != nil:
+		return fileResponse(req, http.StatusForbidden, err.Error()), nil
+	case info.IsDir():
+		listing, err := DirectoryListing(name)
+		if err != nil {
+			return fileResponse(req, http.StatusForbidden, err.Error()), nil
+		}
+		resp := newFileResponse(req, http.StatusOK, "text/html; charset=utf-8", io.NopCloser(bytes.NewReader(listing)))
+		resp.ContentLength = int64(len(listing))
+		return resp, nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return fileResponse(req, http.StatusForbidden, err.Error()), nil
+	}
+	resp := newFileResponse(req, http.StatusOK, fileContentType(f, name), f)
+	resp.ContentLength = info.Size()
+	resp.Header.Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	if req.Method == http.MethodHead {
+		f.Close()
+		resp.Body = http.NoBody
+	}
+	return resp, nil
+}
+
+// fileContentType returns the type of the file f at name, by its extension
+// or else its first bytes.
+func fileContentType(f *os.File, name string) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	f.Seek(0, io.SeekStart)
+	return http.DetectContentType(head[:n])
+}
+
+// fileResponse returns an HTML response with status explaining why a file
+// could not be read.
+func fileResponse(req *http.Request, status int, message string) *http.Response {
+	body := fmt.Sprintf("<html><head><title>%s</title></head><body>\n<h1>%s</h1>\n<p>%s</p>\n</body></html>\n",
+		http.StatusText(status), http.StatusText(status), html.EscapeString(message))
+	resp := newFileResponse(req, status, "text/html; charset=utf-8", io.NopCloser(strings.NewReader(body)))
+	resp.ContentLength = int64(len(body))
+	return resp
+}
+
+func newFileResponse(req *http.Request, status int, contentType string, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.0",
+		ProtoMajor: 1,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       body,
+		Request:    req,
+	}
+}
+
+// FileURL returns the file: URL of the file or folder at name, relative
+// to the working directory if not absolute.
+func FileURL(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	return fileURLOf(abs), nil
+}
+
+func fileURLOf(abs string) string {
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // a Windows drive
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// LocalFileURL returns the file: URL of target when it is the path of an
+// existing file or folder rather than a URL, as typed in the address bar
+// or given on the command line.
+func LocalFileURL(target string) (string, bool) {
+	if target == "" || strings.Contains(target, "://") || strings.HasPrefix(target, "about:") {
+		return "", false
+	}
+	if strings.HasPrefix(target, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		target = filepath.Join(home, target[2:])
+	}
+	if _, err := os.Stat(target); err != nil {
+		return "", false
+	}
+	fileURL, err := FileURL(target)
+	return fileURL, err == nil
+}
+
+// DirectoryListing returns an HTML page listing the folder at dir: a link
+// to its parent, then its folders and files by name, with their sizes and
+// modification times.
+func DirectoryListing(dir string) ([]byte, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(entries, func(a, b os.DirEntry) int {
+		if a.IsDir() != b.IsDir() {
+			if a.IsDir() {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name()), strings.ToLower(b.Name()))
+	})
+
+	title := html.EscapeString("Index of " + filepath.ToSlash(abs))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<html><head><title>%s</title></head><body>\n<h1>%s</h1>\n", title, title)
+	sb.WriteString("<table cellpadding=\"4\">\n<tr><th align=\"left\">Name</th><th align=\"right\">Size</th><th align=\"left\">Modified</th></tr>\n")
+	if parent := filepath.Dir(abs); parent != abs {
+		fmt.Fprintf(&sb, "<tr><td><a href=\"%s\">../</a></td><td></td><td></td></tr>\n", html.EscapeString(fileURLOf(parent)))
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name, size := entry.Name(), FormatSize(info.Size())
+		href := fileURLOf(filepath.Join(abs, name))
+		if entry.IsDir() {
+			name, size, href = name+"/", "", href+"/"
+		}
+		fmt.Fprintf(&sb, "<tr><td><a href=\"%s\">%s</a></td><td align=\"right\">%s</td><td>%s</td></tr>\n",
+			html.EscapeString(href), html.EscapeString(name), size, info.ModTime().Format("2006-01-02 15:04"))
+	}
+	sb.WriteString("</table>\n</body></html>\n")
+	return []byte(sb.String()), nil
+}
+
+// FormatSize formats n bytes for people: 512 B, 1.5 KB, 12 MB.
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size, suffix := float64(n)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if size < unit {
+			break
+		}
+		size, suffix = size/unit, next
+	}
+	if size < 10 {
+		return fmt.Sprintf("%.1f %s", size, suffix)
+	}
+	return fmt.Sprintf("%.0f %s", size, suffix)
+}
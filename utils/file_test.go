@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileURLs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>local</p>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "my style.css"), []byte("p{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes"), []byte("plain words"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "assets"), 0o755))
+	dirURL, err := FileURL(dir)
+	require.NoError(t, err)
+
+	get := func(t *testing.T, rawURL string) (*http.Response, string) {
+		t.Helper()
+		resp, err := Get(rawURL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	t.Run("file", func(t *testing.T) {
+		resp, body := get(t, dirURL+"/index.html")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "<p>local</p>", body)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+	})
+
+	t.Run("escaped name", func(t *testing.T) {
+		resp, body := get(t, dirURL+"/my%20style.css")
+		assert.Equal(t, "p{}", body)
+		assert.Equal(t, "text/css; charset=utf-8", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("sniffed type", func(t *testing.T) {
+		resp, body := get(t, dirURL+"/notes")
+		assert.Equal(t, "plain words", body)
+		assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("folder", func(t *testing.T) {
+		resp, body := get(t, dirURL)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Contains(t, body, "<title>Index of "+filepath.ToSlash(dir)+"</title>")
+		assert.Contains(t, body, `<a href="`+dirURL+`/assets/">assets/</a>`)
+		assert.Contains(t, body, `<a href="`+dirURL+`/my%20style.css">my style.css</a>`)
+		assert.Contains(t, body, `<a href="`+strings.TrimSuffix(dirURL, "/"+filepath.Base(dir))+`">../</a>`)
+		// Folders first, then files by name
+		assert.Less(t, strings.Index(body, "assets/"), strings.Index(body, "index.html"))
+		assert.Less(t, strings.Index(body, "index.html"), strings.Index(body, "my style.css"))
+		assert.Less(t, strings.Index(body, "my style.css"), strings.Index(body, "notes"))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		resp, body := get(t, dirURL+"/gone.html")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Contains(t, body, "gone.html")
+	})
+
+	t.Run("web pages cannot load local files", func(t *testing.T) {
+		resp, err := DoRequest(HTTPRequest{Method: "GET", URL: dirURL + "/index.html", FromURL: "https://example.com/"})
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// but may link to them
+		resp, err = DoRequest(HTTPRequest{Method: "GET", URL: dirURL + "/index.html", FromURL: "https://example.com/", Navigation: true})
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err = DoRequest(HTTPRequest{Method: "GET", URL: dirURL + "/my%20style.css", FromURL: dirURL + "/index.html"})
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestLocalFileURL(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile("page.html", nil, 0o644))
+
+	fileURL, ok := LocalFileURL("page.html")
+	assert.True(t, ok)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "page.html")), fileURL)
+
+	fileURL, ok = LocalFileURL(dir)
+	assert.True(t, ok)
+	assert.Equal(t, "file://"+filepath.ToSlash(dir), fileURL)
+
+	for _, target := range []string{"missing.html", "https://example.com/", "about:privacy", ""} {
+		_, ok := LocalFileURL(target)
+		assert.False(t, ok, target)
+	}
+}
+
+func TestFormatFileSize(t *testing.T) {
+	assert.Equal(t, "512 B", FormatSize(512))
+	assert.Equal(t, "1.5 KB", FormatSize(1536))
+	assert.Equal(t, "12 MB", FormatSize(12<<20))
+	assert.Equal(t, "2.0 GB", FormatSize(2<<30))
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"net/http"
+	"sync"
+)
+
+// RequestHook adjusts an outgoing request before it is sent, for example to
+// set a per-site User-Agent or an Accept-Language. Hooks run in the order
+// they were added, after the browser's own headers (Content-Type, Referer)
+// are set, so they can override those too.
+type RequestHook func(req *http.Request)
+
+// ResponseHook observes the response to a request once its headers have
+// arrived. The body is left for the caller and must not be read.
+type ResponseHook func(req *http.Request, resp *http.Response)
+
+// client sends all browser requests, keeping cookies in Cookies and
+// responses in DiskCache, decoding compressed responses and following
+// redirects, through the configured proxy and checking certificates.
+// file: URLs are read from the filesystem.
+var client = &http.Client{
+	Transport: fileTransport{base: cookieTransport{
+		jar: Cookies,
+		base: cacheTransport{
+			cache: DiskCache,
+			base:  decodingTransport{base: secureTransport},
+		},
+	}},
+	CheckRedirect: checkRedirect,
+}
+
+var (
+	hooksMu       sync.RWMutex
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+)
+
+// AddRequestHook registers a hook run on every request the browser sends:
+// documents, form submissions, stylesheets, images, downloads and pings.
+func AddRequestHook(hook RequestHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	requestHooks = append(requestHooks, hook)
+}
+
+// AddResponseHook registers a hook run on every response the browser
+// receives.
+func AddResponseHook(hook ResponseHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	responseHooks = append(responseHooks, hook)
+}
+
+// ClearHooks removes all request and response hooks.
+func ClearHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	requestHooks = nil
+	responseHooks = nil
+}
+
+// SetHeaderForHost returns a request hook that sets header to value on
+// requests to host, or on every request when host is empty.
+func SetHeaderForHost(host, header, value string) RequestHook {
+	return func(req *http.Request) {
+		if host == "" || req.URL.Hostname() == host {
+			req.Header.Set(header, value)
+		}
+	}
+}
+
+// Send performs req with the browser's cookie jar, running the registered hooks
+// around it. All browser network traffic goes through here.
+func Send(req *http.Request) (*http.Response, error) {
+	hooksMu.RLock()
+	reqHooks, respHooks := requestHooks, responseHooks
+	hooksMu.RUnlock()
+
+	for _, hook := range reqHooks {
+		hook(req)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range respHooks {
+		hook(req, resp)
+	}
+	return resp, nil
+}
+
+// Get fetches url like http.Get, through Send.
+func Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Send(req)
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestHooks(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("X-Served-By", "test")
+	}))
+	defer server.Close()
+	host, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		name     string
+		hooks    []RequestHook
+		header   string
+		expected string
+	}{
+		{
+			name:     "sets header for matching host",
+			hooks:    []RequestHook{SetHeaderForHost(host.Hostname(), "Accept-Language", "fr-FR")},
+			header:   "Accept-Language",
+			expected: "fr-FR",
+		},
+		{
+			name:     "skips other hosts",
+			hooks:    []RequestHook{SetHeaderForHost("example.com", "Accept-Language", "fr-FR")},
+			header:   "Accept-Language",
+			expected: "",
+		},
+		{
+			name:     "empty host matches every request",
+			hooks:    []RequestHook{SetHeaderForHost("", "User-Agent", "Custom/1.0")},
+			header:   "User-Agent",
+			expected: "Custom/1.0",
+		},
+		{
+			name: "later hooks win",
+			hooks: []RequestHook{
+				SetHeaderForHost("", "User-Agent", "First/1.0"),
+				SetHeaderForHost("", "User-Agent", "Second/1.0"),
+			},
+			header:   "User-Agent",
+			expected: "Second/1.0",
+		},
+		{
+			name:     "overrides the browser's own headers",
+			hooks:    []RequestHook{SetHeaderForHost("", "Referer", "")},
+			header:   "Referer",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer ClearHooks()
+			for _, hook := range tt.hooks {
+				AddRequestHook(hook)
+			}
+
+			resp, err := DoRequest(HTTPRequest{Method: "GET", URL: server.URL, FromURL: server.URL + "/from"})
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, tt.expected, got.Get(tt.header))
+		})
+	}
+}
+
+func TestResponseHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "test")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+	defer ClearHooks()
+
+	var seenURL, seenHeader string
+	var seenStatus int
+	AddResponseHook(func(req *http.Request, resp *http.Response) {
+		seenURL = req.URL.String()
+		seenStatus = resp.StatusCode
+		seenHeader = resp.Header.Get("X-Served-By")
+	})
+
+	resp, err := Get(server.URL + "/style.css")
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, server.URL+"/style.css", seenURL)
+	assert.Equal(t, http.StatusTeapot, seenStatus)
+	assert.Equal(t, "test", seenHeader)
+}
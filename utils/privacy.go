@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BrowsingData selects the kinds of stored data ClearBrowsingData removes.
+type BrowsingData int
+
+const (
+	BrowsingCookies BrowsingData = 1 << iota
+	BrowsingCache
+	BrowsingLocalStorage
+
+	AllBrowsingData = BrowsingCookies | BrowsingCache | BrowsingLocalStorage
+)
+
+// SiteData counts what the browser stores for one site (host name).
+type SiteData struct {
+	Site         string
+	Cookies      int
+	CacheEntries int
+	StorageItems int
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+// StoredSiteData summarizes stored cookies, cache entries and localStorage
+// items per site, ordered by site.
+func StoredSiteData() []SiteData {
+	sites := make(map[string]*SiteData)
+	site := func(name string) *SiteData {
+		if sites[name] == nil {
+			sites[name] = &SiteData{Site: name}
+		}
+		return sites[name]
+	}
+	for _, c := range Cookies.All() {
+		site(c.Domain).Cookies++
+	}
+	for _, entry := range append(HTTPCache.Entries(), DiskCache.Entries()...) {
+		site(SiteOf(entry.URL)).CacheEntries++
+	}
+	for origin, count := range LocalStorage.Origins() {
+		site(SiteOf(origin)).StorageItems += count
+	}
+
+	summary := make([]SiteData, 0, len(sites))
+	for _, data := range sites {
+		summary = append(summary, *data)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Site < summary[j].Site })
+	return summary
+}
+
+// ClearBrowsingData removes the selected kinds of data for site and its
+// subdomains (every site when empty) stored at or after since (all time
+// when zero). It returns how many cookies, entries and items were removed.
+func ClearBrowsingData(kinds BrowsingData, site string, since time.Time) int {
+	site = SiteOf(site)
+	removed := 0
+	if kinds&BrowsingCookies != 0 {
+		removed += Cookies.Clear(site, since)
+	}
+	if kinds&BrowsingCache != 0 {
+		removed += HTTPCache.Clear(site, since) + DiskCache.Clear(site, since)
+	}
+	if kinds&BrowsingLocalStorage != 0 {
+		removed += LocalStorage.Clear(site, since)
+	}
+	return removed
+}
+
+// OriginOf returns the scheme://host[:port] origin of rawURL, or "" when it
+// has none.
+func OriginOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// SiteOf returns the host name of a URL or origin; a bare host name is
+// returned as is.
+func SiteOf(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return strings.ToLower(strings.TrimPrefix(s, "."))
+}
+
+// matchesSite reports whether host belongs to site: the host itself or a
+// subdomain. An empty site matches every host.
+func matchesSite(host, site string) bool {
+	return site == "" || host == site || strings.HasSuffix(host, "."+site)
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebStorage(t *testing.T) {
+	s := NewWebStorage()
+	s.SetItem("https://a.com", "theme", "dark")
+	s.SetItem("https://a.com", "lang", "en")
+	s.SetItem("https://a.com", "theme", "light")
+	s.SetItem("https://b.com", "theme", "dark")
+
+	value, ok := s.GetItem("https://a.com", "theme")
+	assert.True(t, ok)
+	assert.Equal(t, "light", value)
+	assert.Equal(t, []string{"theme", "lang"}, s.Keys("https://a.com"), "keys keep insertion order")
+
+	_, ok = s.GetItem("https://c.com", "theme")
+	assert.False(t, ok, "origins are partitioned")
+
+	s.RemoveItem("https://a.com", "theme")
+	assert.Equal(t, []string{"lang"}, s.Keys("https://a.com"))
+
+	s.ClearOrigin("https://a.com")
+	assert.Equal(t, map[string]int{"https://b.com": 1}, s.Origins())
+}
+
+func TestClearBrowsingData(t *testing.T) {
+	defer func() {
+		now = time.Now
+		Cookies, HTTPCache, LocalStorage = NewCookieJar(), NewLimitedCache(DecodedImageLimit, decodedImageSize), NewWebStorage()
+	}()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	setup := func() {
+		Cookies, HTTPCache, LocalStorage = NewCookieJar(), NewCache(), NewWebStorage()
+		now = func() time.Time { return base }
+		Cookies.SetCookies(mustParse("https://example.com/"), []*http.Cookie{{Name: "id", Value: "1"}})
+		HTTPCache.Put("https://example.com/logo.png", nil)
+		LocalStorage.SetItem("https://example.com", "k", "v")
+		now = func() time.Time { return base.Add(time.Hour) }
+		HTTPCache.Put("https://cdn.example.com/app.css", nil)
+		Cookies.SetCookies(mustParse("https://other.org/"), []*http.Cookie{{Name: "id", Value: "2"}})
+		LocalStorage.SetItem("https://other.org:8080", "k", "v")
+	}
+
+	tests := []struct {
+		name     string
+		kinds    BrowsingData
+		site     string
+		since    time.Time
+		removed  int
+		expected []SiteData
+	}{
+		{
+			name:     "everything",
+			kinds:    AllBrowsingData,
+			removed:  6,
+			expected: []SiteData{},
+		},
+		{
+			name:    "one kind",
+			kinds:   BrowsingCache,
+			removed: 2,
+			expected: []SiteData{
+				{Site: "example.com", Cookies: 1, StorageItems: 1},
+				{Site: "other.org", Cookies: 1, StorageItems: 1},
+			},
+		},
+		{
+			name:    "site includes subdomains",
+			kinds:   AllBrowsingData,
+			site:    "https://example.com/page",
+			removed: 4,
+			expected: []SiteData{
+				{Site: "other.org", Cookies: 1, StorageItems: 1},
+			},
+		},
+		{
+			name:    "time range",
+			kinds:   AllBrowsingData,
+			since:   base.Add(30 * time.Minute),
+			removed: 3,
+			expected: []SiteData{
+				{Site: "example.com", Cookies: 1, CacheEntries: 1, StorageItems: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup()
+			assert.Equal(t, tt.removed, ClearBrowsingData(tt.kinds, tt.site, tt.since))
+			assert.Equal(t, tt.expected, StoredSiteData())
+		})
+	}
+}
+
+func TestOriginOf(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://Example.com/path?q=1", "https://example.com"},
+		{"http://localhost:8080/", "http://localhost:8080"},
+		{"file:///tmp/index.html", ""},
+		{"about:privacy", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, OriginOf(tt.url), tt.url)
+	}
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Rendering profiling. When enabled, each phase of turning a document into
+// pixels records how long its runs take and how much work they do, and the
+// layout records how many boxes of each type it placed. It is off by
+// default, costing a flag check per run.
+
+// Phase is a step of rendering a page.
+type Phase int
+
+const (
+	PhaseStyle       Phase = iota // building the box tree, resolving each element's style
+	PhaseLayout                   // placing the boxes
+	PhaseDisplayList              // painting the boxes into display lists
+	PhaseRaster                   // drawing display lists into canvas objects and page tiles
+
+	phaseCount = iota
+)
+
+var phaseNames = [phaseCount]string{"style", "layout", "display list", "raster"}
+
+func (p Phase) String() string {
+	if p < 0 || p >= phaseCount {
+		return "unknown"
+	}
+	return phaseNames[p]
+}
+
+// PhaseTiming is what a phase's runs cost. Count is the work of the last
+// run: boxes built or laid out, display commands painted or drawn, or page
+// tiles painted.
+type PhaseTiming struct {
+	Runs  int
+	Last  time.Duration
+	Total time.Duration
+	Count int
+}
+
+// Average returns the mean time of a run.
+func (t PhaseTiming) Average() time.Duration {
+	if t.Runs == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Runs)
+}
+
+// ProfileReport is a snapshot of what has been recorded since profiling
+// was last reset.
+type ProfileReport struct {
+	Phases [phaseCount]PhaseTiming
+	Boxes  map[string]int // box type -> boxes in the last layout
+}
+
+// Phase returns the timing of p.
+func (r ProfileReport) Phase(p Phase) PhaseTiming {
+	if p < 0 || p >= phaseCount {
+		return PhaseTiming{}
+	}
+	return r.Phases[p]
+}
+
+// Profiler records rendering timings.
+type Profiler struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	phases  [phaseCount]PhaseTiming
+	boxes   map[string]int
+}
+
+// Profile is the browser's profiler.
+var Profile = &Profiler{}
+
+// SetEnabled turns recording on or off.
+func (p *Profiler) SetEnabled(on bool) {
+	p.enabled.Store(on)
+}
+
+// Enabled reports whether recording is on.
+func (p *Profiler) Enabled() bool {
+	return p.enabled.Load()
+}
+
+// Start returns the start time of a run to pass to Record, or the zero
+// time when recording is off.
+func (p *Profiler) Start() time.Time {
+	if !p.Enabled() {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// Record records a run of phase that began at start and did count units
+// of work. Runs started while recording was off are ignored.
+func (p *Profiler) Record(phase Phase, start time.Time, count int) {
+	if start.IsZero() || phase < 0 || phase >= phaseCount {
+		return
+	}
+	elapsed := time.Since(start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t := &p.phases[phase]
+	t.Runs++
+	t.Last = elapsed
+	t.Total += elapsed
+	t.Count = count
+}
+
+// SetBoxCounts records how many boxes of each type the last layout placed.
+func (p *Profiler) SetBoxCounts(boxes map[string]int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.boxes = boxes
+}
+
+// Report returns what has been recorded.
+func (p *Profiler) Report() ProfileReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return ProfileReport{Phases: p.phases, Boxes: maps.Clone(p.boxes)}
+}
+
+// Reset forgets what has been recorded.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phases = [phaseCount]PhaseTiming{}
+	p.boxes = nil
+}
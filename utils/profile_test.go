@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	p := &Profiler{}
+	p.Record(PhaseLayout, p.Start(), 10)
+	assert.Zero(t, p.Report().Phase(PhaseLayout).Runs, "nothing is recorded while off")
+
+	p.SetEnabled(true)
+	p.Record(PhaseLayout, time.Now().Add(-3*time.Millisecond), 10)
+	p.Record(PhaseLayout, time.Now().Add(-time.Millisecond), 4)
+	p.SetBoxCounts(map[string]int{"block": 3})
+
+	report := p.Report()
+	layout := report.Phase(PhaseLayout)
+	assert.Equal(t, 2, layout.Runs)
+	assert.Equal(t, 4, layout.Count, "the count of the last run")
+	assert.GreaterOrEqual(t, layout.Last, time.Millisecond)
+	assert.Less(t, layout.Last, 3*time.Millisecond)
+	assert.GreaterOrEqual(t, layout.Average(), 2*time.Millisecond)
+	assert.Zero(t, report.Phase(PhaseRaster).Average())
+	assert.Equal(t, map[string]int{"block": 3}, report.Boxes)
+	assert.Equal(t, "display list", PhaseDisplayList.String())
+
+	report.Boxes["block"] = 0
+	assert.Equal(t, 3, p.Report().Boxes["block"], "reports are copies")
+
+	p.Reset()
+	assert.Equal(t, ProfileReport{}, p.Report())
+}
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Proxies. Every request the browser makes over the network goes through
+// the proxy its profile is set to: the one the environment names by
+// default (HTTP_PROXY, HTTPS_PROXY, ALL_PROXY and NO_PROXY, in either
+// case), none, or one given by URL. HTTP and HTTPS proxies are sent their
+// credentials with Proxy-Authorization, SOCKS5 proxies by username and
+// password authentication. Loopback hosts are never proxied.
+
+// ProxyMode chooses where the proxy comes from.
+type ProxyMode string
+
+const (
+	ProxySystem ProxyMode = "system" // from the environment
+	ProxyDirect ProxyMode = "direct" // none
+	ProxyManual ProxyMode = "manual" // ProxyConfig.URL
+)
+
+// ProxyConfig is a profile's proxy setting.
+type ProxyConfig struct {
+	Mode ProxyMode `json:"mode"`
+	// URL is the manual proxy: http://, https:// or socks5://host:port,
+	// with its credentials in the URL or in Username and Password.
+	URL      string `json:"url,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Bypass lists the hosts the manual proxy is not used for, in NO_PROXY
+	// form: host names, ".domain" suffixes, IP addresses and CIDR ranges.
+	Bypass []string `json:"bypass,omitempty"`
+}
+
+// proxyURL returns the manual proxy's URL, credentials included.
+func (c ProxyConfig) proxyURL() (*url.URL, error) {
+	raw := strings.TrimSpace(c.URL)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", c.URL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", c.URL)
+	}
+	if c.Username != "" && u.User == nil {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return u, nil
+}
+
+// ProxySettings holds the browser's proxy configuration.
+type ProxySettings struct {
+	mu     sync.RWMutex
+	config ProxyConfig
+	proxy  func(*url.URL) (*url.URL, error) // the proxy for a request URL, nil for none
+	file   string                           // where the configuration is saved, if anywhere
+}
+
+// Proxies is the browser's proxy configuration, the environment's until
+// set otherwise.
+var Proxies = NewProxySettings()
+
+// NewProxySettings returns settings using the environment's proxy.
+func NewProxySettings() *ProxySettings {
+	p := &ProxySettings{}
+	p.apply(ProxyConfig{Mode: ProxySystem})
+	return p
+}
+
+// Config returns the current configuration.
+func (p *ProxySettings) Config() ProxyConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config
+}
+
+// Set changes the configuration, saving it to the file given to Persist.
+// Connections kept alive through the old proxy are closed.
+func (p *ProxySettings) Set(config ProxyConfig) error {
+	if err := p.apply(config); err != nil {
+		return err
+	}
+	secureTransport.CloseIdleConnections()
+	return p.Save()
+}
+
+// apply validates config and makes it current.
+func (p *ProxySettings) apply(config ProxyConfig) error {
+	var env httpproxy.Config
+	switch config.Mode {
+	case "", ProxySystem:
+		config.Mode = ProxySystem
+		env = proxyEnvironment()
+	case ProxyDirect:
+	case ProxyManual:
+		u, err := config.proxyURL()
+		if err != nil {
+			return err
+		}
+		env = httpproxy.Config{HTTPProxy: u.String(), HTTPSProxy: u.String()}
+	default:
+		return fmt.Errorf("unknown proxy mode %q", config.Mode)
+	}
+	if config.Mode == ProxyManual && len(config.Bypass) > 0 {
+		env.NoProxy = strings.Join(config.Bypass, ",")
+	}
+
+	var proxy func(*url.URL) (*url.URL, error)
+	if env.HTTPProxy != "" || env.HTTPSProxy != "" {
+		proxy = env.ProxyFunc()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config, p.proxy = config, proxy
+	return nil
+}
+
+// proxyEnvironment returns the proxies the environment names. ALL_PROXY
+// stands in for the scheme specific variables when they are unset.
+func proxyEnvironment() httpproxy.Config {
+	env := *httpproxy.FromEnvironment()
+	all := os.Getenv("ALL_PROXY")
+	if all == "" {
+		all = os.Getenv("all_proxy")
+	}
+	if env.HTTPProxy == "" {
+		env.HTTPProxy = all
+	}
+	if env.HTTPSProxy == "" {
+		env.HTTPSProxy = all
+	}
+	return env
+}
+
+// ProxyFor returns the proxy req goes through, nil when it goes directly.
+func (p *ProxySettings) ProxyFor(req *http.Request) (*url.URL, error) {
+	p.mu.RLock()
+	proxy := p.proxy
+	p.mu.RUnlock()
+	if proxy == nil {
+		return nil, nil
+	}
+	return proxy(req.URL)
+}
+
+// Persist loads the configuration saved in file, if there is one, and
+// saves it there when it changes. A saved configuration that is not valid
+// leaves the current one.
+func (p *ProxySettings) Persist(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	config := p.Config()
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	p.file = file
+	p.mu.Unlock()
+	return p.apply(config)
+}
+
+// Save writes the configuration to the file given to Persist.
+func (p *ProxySettings) Save() error {
+	p.mu.RLock()
+	file, config := p.file, p.config
+	p.mu.RUnlock()
+	if file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeProfileFile(file, data)
+}
+
+// networkTransport makes the browser's connections, through Proxies.
+var networkTransport = newNetworkTransport()
+
+func newNetworkTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		return Proxies.ProxyFor(req)
+	}
+	return t
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Redirects (Fetch §4.4, "HTTP-redirect fetch"). The client follows 301,
+// 302, 303, 307 and 308 responses itself: 303s, and 301s and 302s to a
+// POST, become GETs without a body, while 307s and 308s repeat the request
+// with its method and body. Every request the browser builds has a body it
+// can send again, so those are never cut short.
+
+// MaxRedirects is how many requests a redirect chain may make, counting
+// the first, before failing.
+const MaxRedirects = 20
+
+// ErrTooManyRedirects is the error of a request whose redirects would have
+// made more than MaxRedirects requests, likely in a loop.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// checkRedirect decides whether the client follows a redirect to req, via
+// being the requests made so far, oldest first.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if req.Context().Value(noRedirectKey{}) != nil {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= MaxRedirects {
+		return ErrTooManyRedirects
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	// A Location without a fragment keeps the one of the URL redirected from
+	if req.URL.Fragment == "" {
+		req.URL.Fragment = via[len(via)-1].URL.Fragment
+	}
+	redirectReferrer(req)
+	return nil
+}
+
+// noRedirectKey marks a request whose redirects are returned rather than
+// followed, such as a CORS preflight.
+type noRedirectKey struct{}
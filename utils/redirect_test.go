@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoServer redirects /redirect/<status> to /echo and /loop/<n> to
+// /loop/<n+1> up to /loop/100, and answers /echo with the method,
+// Content-Type and body it received.
+func echoServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect/{status}", func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.PathValue("status"))
+		http.Redirect(w, r, "/echo", status)
+	})
+	mux.HandleFunc("/loop/{n}", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.PathValue("n"))
+		if n >= 100 {
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/loop/%d", n+1), http.StatusFound)
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Content-Type"), body)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRedirectMethods(t *testing.T) {
+	server := echoServer(t)
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusMovedPermanently, "GET  "},
+		{http.StatusFound, "GET  "},
+		{http.StatusSeeOther, "GET  "},
+		{http.StatusTemporaryRedirect, "POST application/x-www-form-urlencoded q=go"},
+		{http.StatusPermanentRedirect, "POST application/x-www-form-urlencoded q=go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			resp, err := DoRequest(HTTPRequest{
+				Method:   "POST",
+				URL:      fmt.Sprintf("%s/redirect/%d", server.URL, tt.status),
+				FormData: url.Values{"q": {"go"}},
+			})
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.want, string(body))
+			assert.Equal(t, server.URL+"/echo", resp.Request.URL.String())
+		})
+	}
+}
+
+func TestRedirectPreservesUploads(t *testing.T) {
+	server := echoServer(t)
+	resp, err := DoRequest(HTTPRequest{
+		Method:      "POST",
+		URL:         server.URL + "/redirect/307",
+		Body:        []byte("--b\r\n"),
+		ContentType: "multipart/form-data; boundary=b",
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "POST multipart/form-data; boundary=b --b\r\n", string(body))
+}
+
+func TestRedirectLimit(t *testing.T) {
+	server := echoServer(t)
+	var requests int
+	AddRequestHook(func(*http.Request) { requests++ })
+	t.Cleanup(ClearHooks)
+
+	// /loop/n redirects until n reaches 100
+	resp, err := Get(server.URL + fmt.Sprintf("/loop/%d", 101-MaxRedirects))
+	require.NoError(t, err, "%d requests are made", MaxRedirects)
+	resp.Body.Close()
+	assert.Equal(t, 1, requests, "hooks run once per request, not per redirect")
+
+	_, err = Get(server.URL + fmt.Sprintf("/loop/%d", 100-MaxRedirects))
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+}
+
+func TestRedirectFragment(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new#top", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := Get(server.URL + "/old#section")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, server.URL+"/new#section", resp.Request.URL.String())
+
+	resp, err = Get(server.URL + "/elsewhere#section")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, server.URL+"/new#top", resp.Request.URL.String())
+}
+
+func TestRedirectUnsupportedScheme(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := Get(server.URL)
+	assert.ErrorContains(t, err, "unsupported scheme")
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Referrers (Referrer Policy §8). A request made for a page tells the
+// server which page it came from in the Referer header, as much of its URL
+// as the policy in force allows. That is the element's referrerpolicy
+// attribute, else the document's (its Referrer-Policy header or
+// <meta name="referrer">), else strict-origin-when-cross-origin. Pages not
+// served over HTTP never send one, and neither do navigations the user
+// starts. Redirects keep the original page as the referrer, under the
+// same policy.
+
+// DefaultReferrerPolicy is the policy of documents and elements that do
+// not set one.
+const DefaultReferrerPolicy = "strict-origin-when-cross-origin"
+
+// referrerPolicies are the policies the browser knows.
+var referrerPolicies = []string{
+	"no-referrer", "no-referrer-when-downgrade", "same-origin", "origin",
+	"strict-origin", "origin-when-cross-origin", "strict-origin-when-cross-origin", "unsafe-url",
+}
+
+// ParseReferrerPolicy returns the policy value names: the last of its comma
+// separated tokens the browser knows, so a page may list a newer policy
+// first with a fallback after it; "" if it names none. Legacy <meta>
+// keywords count as their modern policies.
+func ParseReferrerPolicy(value string) string {
+	policy := ""
+	for _, token := range strings.Split(value, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		switch token {
+		case "never":
+			token = "no-referrer"
+		case "default":
+			token = DefaultReferrerPolicy
+		case "always":
+			token = "unsafe-url"
+		case "origin-when-crossorigin":
+			token = "origin-when-cross-origin"
+		}
+		if slices.Contains(referrerPolicies, token) {
+			policy = token
+		}
+	}
+	return policy
+}
+
+// documentPolicies holds the referrer policies of recently loaded
+// documents, by URL, oldest first.
+var documentPolicies = struct {
+	sync.Mutex
+	urls     []string
+	policies map[string]string
+}{policies: make(map[string]string)}
+
+// documentPolicyLimit is how many documents' policies are kept: enough for
+// every open window and its frames.
+const documentPolicyLimit = 64
+
+// SetDocumentReferrerPolicy makes policy, as ParseReferrerPolicy reads it,
+// the policy of requests made for the document at pageURL that set none of
+// their own. An empty or unknown policy leaves the default.
+func SetDocumentReferrerPolicy(pageURL, policy string) {
+	key := documentKey(pageURL)
+	documentPolicies.Lock()
+	defer documentPolicies.Unlock()
+	if i := slices.Index(documentPolicies.urls, key); i >= 0 {
+		documentPolicies.urls = slices.Delete(documentPolicies.urls, i, i+1)
+	}
+	policy = ParseReferrerPolicy(policy)
+	if policy == "" {
+		delete(documentPolicies.policies, key)
+		return
+	}
+	documentPolicies.urls = append(documentPolicies.urls, key)
+	documentPolicies.policies[key] = policy
+	if len(documentPolicies.urls) > documentPolicyLimit {
+		delete(documentPolicies.policies, documentPolicies.urls[0])
+		documentPolicies.urls = documentPolicies.urls[1:]
+	}
+}
+
+// DocumentReferrerPolicy returns the referrer policy of the document at
+// pageURL.
+func DocumentReferrerPolicy(pageURL string) string {
+	documentPolicies.Lock()
+	defer documentPolicies.Unlock()
+	if policy, ok := documentPolicies.policies[documentKey(pageURL)]; ok {
+		return policy
+	}
+	return DefaultReferrerPolicy
+}
+
+// documentKey is pageURL without its fragment, which does not change the
+// document.
+func documentKey(pageURL string) string {
+	key, _, _ := strings.Cut(pageURL, "#")
+	return key
+}
+
+// Referrer returns the Referer header of a request to target made for the
+// page at fromURL, "" for none. policy is the requesting element's
+// referrerpolicy attribute; when it names no policy, the document's
+// applies.
+func Referrer(fromURL string, target *url.URL, policy string) string {
+	from, err := url.Parse(fromURL)
+	if err != nil || from.Scheme != "http" && from.Scheme != "https" || from.Host == "" {
+		return ""
+	}
+	if policy = ParseReferrerPolicy(policy); policy == "" {
+		policy = DocumentReferrerPolicy(fromURL)
+	}
+	return referrerFor(from, target, policy)
+}
+
+// referrerFor applies policy to a request to target from the page at from.
+func referrerFor(from, target *url.URL, policy string) string {
+	// The URL a server is sent never carries credentials or a fragment
+	full := *from
+	full.User, full.Fragment, full.RawFragment = nil, "", ""
+	origin := (&url.URL{Scheme: from.Scheme, Host: from.Host, Path: "/"}).String()
+	sameOrigin := strings.EqualFold(from.Scheme, target.Scheme) && strings.EqualFold(from.Host, target.Host)
+	downgrade := from.Scheme == "https" && target.Scheme != "https"
+
+	switch policy {
+	case "no-referrer":
+		return ""
+	case "no-referrer-when-downgrade":
+		if downgrade {
+			return ""
+		}
+		return full.String()
+	case "same-origin":
+		if sameOrigin {
+			return full.String()
+		}
+		return ""
+	case "origin":
+		return origin
+	case "strict-origin":
+		if downgrade {
+			return ""
+		}
+		return origin
+	case "origin-when-cross-origin":
+		if sameOrigin {
+			return full.String()
+		}
+		return origin
+	case "unsafe-url":
+		return full.String()
+	}
+	// strict-origin-when-cross-origin
+	switch {
+	case sameOrigin:
+		return full.String()
+	case downgrade:
+		return ""
+	}
+	return origin
+}
+
+// referrerKey marks a request with where its referrer comes from, so
+// redirects of it can be given theirs.
+type referrerKey struct{}
+
+type requestReferrer struct {
+	fromURL string
+	policy  string
+}
+
+// withReferrer returns req with the Referer header the page at fromURL
+// sends under policy, marked so its redirects get theirs too.
+func withReferrer(req *http.Request, fromURL, policy string) *http.Request {
+	setReferrer(req, fromURL, policy)
+	return req.WithContext(context.WithValue(req.Context(), referrerKey{}, requestReferrer{fromURL: fromURL, policy: policy}))
+}
+
+// redirectReferrer sets the Referer header of req, a redirect, as the
+// request it redirects was given its own. The client would otherwise send
+// the URL redirected from.
+func redirectReferrer(req *http.Request) {
+	r, _ := req.Context().Value(referrerKey{}).(requestReferrer)
+	setReferrer(req, r.fromURL, r.policy)
+}
+
+func setReferrer(req *http.Request, fromURL, policy string) {
+	if referrer := Referrer(fromURL, req.URL, policy); referrer != "" {
+		req.Header.Set("Referer", referrer)
+	} else {
+		req.Header.Del("Referer")
+	}
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// WebStorage holds Web Storage (localStorage) items partitioned by origin.
+type WebStorage struct {
+	mu      sync.Mutex
+	origins map[string]*originStorage
+}
+
+type originStorage struct {
+	keys  []string // insertion order, for Storage.key(n)
+	items map[string]storageItem
+}
+
+type storageItem struct {
+	value    string
+	modified time.Time
+}
+
+// LocalStorage backs window.localStorage for every page.
+var LocalStorage = NewWebStorage()
+
+func NewWebStorage() *WebStorage {
+	return &WebStorage{origins: make(map[string]*originStorage)}
+}
+
+// GetItem returns the value stored under key for origin.
+func (s *WebStorage) GetItem(origin, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if area := s.origins[origin]; area != nil {
+		item, ok := area.items[key]
+		return item.value, ok
+	}
+	return "", false
+}
+
+// SetItem stores value under key for origin.
+func (s *WebStorage) SetItem(origin, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	area := s.origins[origin]
+	if area == nil {
+		area = &originStorage{items: make(map[string]storageItem)}
+		s.origins[origin] = area
+	}
+	if _, ok := area.items[key]; !ok {
+		area.keys = append(area.keys, key)
+	}
+	area.items[key] = storageItem{value: value, modified: now()}
+}
+
+// RemoveItem deletes key from origin's storage.
+func (s *WebStorage) RemoveItem(origin, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if area := s.origins[origin]; area != nil {
+		area.remove(key)
+		if len(area.keys) == 0 {
+			delete(s.origins, origin)
+		}
+	}
+}
+
+// Keys returns origin's keys in insertion order.
+func (s *WebStorage) Keys(origin string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if area := s.origins[origin]; area != nil {
+		return append([]string(nil), area.keys...)
+	}
+	return nil
+}
+
+// Origins lists the origins with stored items and their item counts.
+func (s *WebStorage) Origins() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]int, len(s.origins))
+	for origin, area := range s.origins {
+		counts[origin] = len(area.keys)
+	}
+	return counts
+}
+
+// ClearOrigin removes all of origin's items, as Storage.clear() does.
+func (s *WebStorage) ClearOrigin(origin string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.origins, origin)
+}
+
+// Clear removes items of origins on site (any site when empty) modified at
+// or after since, and returns how many were removed.
+func (s *WebStorage) Clear(site string, since time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := 0
+	for origin, area := range s.origins {
+		if !matchesSite(SiteOf(origin), site) {
+			continue
+		}
+		for _, key := range append([]string(nil), area.keys...) {
+			if !area.items[key].modified.Before(since) {
+				area.remove(key)
+				removed++
+			}
+		}
+		if len(area.keys) == 0 {
+			delete(s.origins, origin)
+		}
+	}
+	return removed
+}
+
+func (area *originStorage) remove(key string) {
+	if _, ok := area.items[key]; !ok {
+		return
+	}
+	delete(area.items, key)
+	for i, k := range area.keys {
+		if k == key {
+			area.keys = append(area.keys[:i], area.keys[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+// Subresource loading. A page's stylesheets, scripts, fonts and images are
+// fetched concurrently by a bounded number of workers. Waiting loads start
+// by priority, then in the order they were asked for, passing over those
+// to hosts that already have as many loads in flight as they may, the way
+// browsers cap connections per host. Each load belongs to the document
+// current when it was asked for; navigating to another cancels them, queued
+// or in flight.
+
+// Priority orders waiting loads, most urgent first.
+type Priority int
+
+const (
+	PriorityHigh   Priority = iota // stylesheets and scripts, which block rendering
+	PriorityMedium                 // fonts, which reflow the text using them
+	PriorityLow                    // images
+
+	priorityCount = iota
+)
+
+const (
+	// DefaultLoadWorkers is how many loads run at once.
+	DefaultLoadWorkers = 16
+	// DefaultHostLoads is how many loads run at once against one host.
+	DefaultHostLoads = 6
+)
+
+// Subresources loads the current document's subresources.
+var Subresources = NewResourceLoader(DefaultLoadWorkers, DefaultHostLoads)
+
+// ResourceLoader schedules subresource loads.
+type ResourceLoader struct {
+	mu        sync.Mutex
+	workers   int
+	hostLimit int
+	active    int
+	hosts     map[string]int // host -> loads in flight
+	queue     [priorityCount][]*loadTicket
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// loadTicket is a load waiting for a worker. ready is closed once it has
+// one.
+type loadTicket struct {
+	host  string
+	ready chan struct{}
+}
+
+// NewResourceLoader returns a loader running up to workers loads at once,
+// and up to hostLimit against the same host.
+func NewResourceLoader(workers, hostLimit int) *ResourceLoader {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ResourceLoader{
+		workers:   max(workers, 1),
+		hostLimit: max(hostLimit, 1),
+		hosts:     make(map[string]int),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// BeginDocument cancels the loads of the previous document and returns the
+// context the new one's run in.
+func (l *ResourceLoader) BeginDocument() context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cancel()
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	return l.ctx
+}
+
+// Context returns the context the current document's loads run in.
+func (l *ResourceLoader) Context() context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ctx
+}
+
+// Acquire waits for a worker to load from host at priority, and returns the
+// function freeing it. It fails with ctx's error if ctx ends first.
+func (l *ResourceLoader) Acquire(ctx context.Context, priority Priority, host string) (func(), error) {
+	priority = min(max(priority, PriorityHigh), PriorityLow)
+	ticket := &loadTicket{host: host, ready: make(chan struct{})}
+	l.mu.Lock()
+	l.queue[priority] = append(l.queue[priority], ticket)
+	l.dispatch()
+	l.mu.Unlock()
+
+	var once sync.Once
+	release := func() { once.Do(func() { l.release(host) }) }
+	select {
+	case <-ticket.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-ticket.ready:
+		// Given a worker meanwhile; hand it on
+		l.free(host)
+	default:
+		i := slices.Index(l.queue[priority], ticket)
+		l.queue[priority] = append(l.queue[priority][:i], l.queue[priority][i+1:]...)
+	}
+	return nil, ctx.Err()
+}
+
+// Load performs req, a subresource of the current document, once a worker
+// is free for it at priority. The worker is held until the response body
+// is closed.
+func (l *ResourceLoader) Load(priority Priority, req HTTPRequest) (*http.Response, error) {
+	ctx := l.Context()
+	host := ""
+	if u, err := url.Parse(req.URL); err == nil {
+		host = u.Host
+	}
+	release, err := l.Acquire(ctx, priority, host)
+	if err != nil {
+		return nil, err
+	}
+	req.Context = ctx
+	resp, err := DoRequest(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// release frees a worker that loaded from host.
+func (l *ResourceLoader) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.free(host)
+}
+
+// free frees a worker that loaded from host, for the next waiting load.
+// The caller holds l.mu.
+func (l *ResourceLoader) free(host string) {
+	l.active--
+	if l.hosts[host]--; l.hosts[host] <= 0 {
+		delete(l.hosts, host)
+	}
+	l.dispatch()
+}
+
+// dispatch gives free workers to the waiting loads that may start. The
+// caller holds l.mu.
+func (l *ResourceLoader) dispatch() {
+	for priority := range l.queue {
+		waiting := l.queue[priority][:0]
+		for _, ticket := range l.queue[priority] {
+			if l.active < l.workers && l.hosts[ticket.host] < l.hostLimit {
+				l.active++
+				l.hosts[ticket.host]++
+				close(ticket.ready)
+				continue
+			}
+			waiting = append(waiting, ticket)
+		}
+		clear(l.queue[priority][len(waiting):])
+		l.queue[priority] = waiting
+	}
+}
+
+// releasingBody frees its load's worker when closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceLoaderPriority(t *testing.T) {
+	l := NewResourceLoader(1, 1)
+	ctx := context.Background()
+	hold, err := l.Acquire(ctx, PriorityHigh, "a")
+	require.NoError(t, err)
+
+	// Queued while the only worker is busy, they start most urgent first
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	queue := func(name string, priority Priority, queued int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(ctx, priority, "a")
+			require.NoError(t, err)
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		}()
+		waitQueued(t, l, priority, queued)
+	}
+	queue("image", PriorityLow, 1)
+	queue("font", PriorityMedium, 1)
+	queue("script", PriorityHigh, 1)
+	queue("image 2", PriorityLow, 2)
+
+	hold()
+	wg.Wait()
+	assert.Equal(t, []string{"script", "font", "image", "image 2"}, order)
+}
+
+// waitQueued waits until n loads wait at priority.
+func waitQueued(t *testing.T, l *ResourceLoader, priority Priority, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.queue[priority]) == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestResourceLoaderHostLimit(t *testing.T) {
+	l := NewResourceLoader(3, 2)
+	ctx := context.Background()
+	a1, err := l.Acquire(ctx, PriorityHigh, "a")
+	require.NoError(t, err)
+	_, err = l.Acquire(ctx, PriorityHigh, "a")
+	require.NoError(t, err)
+
+	// A third load from host a waits, and does not hold up host b
+	started := make(chan string, 2)
+	go func() {
+		release, _ := l.Acquire(ctx, PriorityHigh, "a")
+		started <- "a"
+		release()
+	}()
+	go func() {
+		release, _ := l.Acquire(ctx, PriorityLow, "b")
+		started <- "b"
+		release()
+	}()
+	assert.Equal(t, "b", <-started)
+	select {
+	case host := <-started:
+		t.Fatalf("load from %s started over the host limit", host)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	a1()
+	assert.Equal(t, "a", <-started)
+}
+
+func TestResourceLoaderCancel(t *testing.T) {
+	l := NewResourceLoader(1, 1)
+	hold, err := l.Acquire(context.Background(), PriorityHigh, "a")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := l.Acquire(ctx, PriorityHigh, "a")
+		done <- err
+	}()
+	waitQueued(t, l, PriorityHigh, 1)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// The cancelled load gave up its place in the queue
+	hold()
+	release, err := l.Acquire(context.Background(), PriorityHigh, "a")
+	require.NoError(t, err)
+	release()
+	assert.Equal(t, 0, l.active)
+	assert.Empty(t, l.hosts)
+}
+
+func TestResourceLoaderLoad(t *testing.T) {
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			select {
+			case <-unblock:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		io.WriteString(w, "body{}")
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	l := NewResourceLoader(1, 1)
+	l.BeginDocument()
+	resp, err := l.Load(PriorityHigh, HTTPRequest{Method: "GET", URL: server.URL + "/style.css"})
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "body{}", string(data))
+	assert.Equal(t, 1, l.active, "the worker is held until the body is closed")
+	resp.Body.Close()
+	assert.Equal(t, 0, l.active)
+
+	// Leaving the document cancels its loads, in flight and queued
+	slow, err := l.Load(PriorityLow, HTTPRequest{Method: "GET", URL: server.URL + "/slow"})
+	require.NoError(t, err)
+	defer slow.Body.Close()
+	queued := make(chan error)
+	go func() {
+		_, err := l.Load(PriorityLow, HTTPRequest{Method: "GET", URL: server.URL + "/style.css"})
+		queued <- err
+	}()
+	waitQueued(t, l, PriorityLow, 1)
+	l.BeginDocument()
+	assert.ErrorIs(t, <-queued, context.Canceled)
+	_, err = io.ReadAll(slow.Body)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TLS certificates. A connection whose certificate fails validation fails
+// with a CertificateError, for the browser to show an interstitial in place
+// of the page. The user may proceed anyway: the host's connections then
+// trust that certificate, and only it, until the browser exits. The
+// certificate each host last presented is kept for the padlock.
+
+// CertificateInfo describes a server's certificate.
+type CertificateInfo struct {
+	Subject     string
+	Issuer      string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	Names       []string // the DNS names and IP addresses it is valid for
+	Fingerprint string   // SHA-256 of the certificate, in hex
+	// Error is why the certificate failed validation, "" if it passed. A
+	// certificate with an error is only in use because the user proceeded.
+	Error string
+}
+
+// Trusted reports whether the certificate passed validation.
+func (c *CertificateInfo) Trusted() bool {
+	return c.Error == ""
+}
+
+func certificateInfo(cert *x509.Certificate) CertificateInfo {
+	sum := sha256.Sum256(cert.Raw)
+	info := CertificateInfo{
+		Subject:     certificateName(cert.Subject.CommonName, cert.Subject.Organization),
+		Issuer:      certificateName(cert.Issuer.CommonName, cert.Issuer.Organization),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Names:       append([]string(nil), cert.DNSNames...),
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.Names = append(info.Names, ip.String())
+	}
+	return info
+}
+
+// certificateName names a certificate's subject or issuer by its common
+// name, else its organization.
+func certificateName(commonName string, organization []string) string {
+	if commonName == "" && len(organization) > 0 {
+		return organization[0]
+	}
+	return commonName
+}
+
+// CertificateError is the error of a request to a host whose certificate
+// failed validation.
+type CertificateError struct {
+	Host        string // host[:port] of the request
+	Certificate CertificateInfo
+	Err         error
+}
+
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("certificate of %s is not trusted: %v", e.Host, e.Err)
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// Reason explains the failure for people.
+func (e *CertificateError) Reason() string {
+	return describeCertificateError(e.Err, e.Host)
+}
+
+func describeCertificateError(err error, host string) string {
+	var unknown x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknown):
+		return "The certificate is not issued by a trusted authority."
+	case errors.As(err, &hostname):
+		name, _, splitErr := net.SplitHostPort(host)
+		if splitErr != nil {
+			name = host
+		}
+		return fmt.Sprintf("The certificate is not valid for %s.", name)
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return "The certificate has expired or is not yet valid."
+	}
+	return err.Error()
+}
+
+// certificateStore holds the certificates hosts presented and those the
+// user chose to trust.
+type certificateStore struct {
+	mu      sync.Mutex
+	seen    map[string]CertificateInfo // host -> certificate last presented
+	allowed map[string]string          // host -> fingerprint trusted despite failing validation
+}
+
+var certificates = &certificateStore{seen: make(map[string]CertificateInfo), allowed: make(map[string]string)}
+
+// CertificateFor returns the certificate rawURL's host last presented, nil
+// if rawURL is not an https URL or its host has not been connected to.
+func CertificateFor(rawURL string) *CertificateInfo {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return nil
+	}
+	certificates.mu.Lock()
+	defer certificates.mu.Unlock()
+	info, ok := certificates.seen[u.Host]
+	if !ok {
+		return nil
+	}
+	return &info
+}
+
+// AllowCertificate makes connections to host trust cert although it failed
+// validation, until the browser exits. A different certificate still fails.
+func AllowCertificate(host string, cert CertificateInfo) {
+	certificates.mu.Lock()
+	certificates.allowed[host] = cert.Fingerprint
+	certificates.mu.Unlock()
+	secureTransport.CloseIdleConnections()
+}
+
+// certificateTransport makes connections with base, and records the
+// certificate each host presents. Connections to a host whose certificate
+// the user allowed go through a transport of their own, trusting just it.
+type certificateTransport struct {
+	base *http.Transport
+
+	mu         sync.Mutex
+	exceptions map[string]*http.Transport // host and fingerprint -> transport trusting that certificate
+}
+
+// secureTransport makes the browser's connections.
+var secureTransport = &certificateTransport{base: networkTransport, exceptions: make(map[string]*http.Transport)}
+
+func (t *certificateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	transport := t.base
+	certificates.mu.Lock()
+	fingerprint, excepted := certificates.allowed[host]
+	certificates.mu.Unlock()
+	if excepted && req.URL.Scheme == "https" {
+		transport = t.exception(host, fingerprint)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	var verifyErr *tls.CertificateVerificationError
+	if errors.As(err, &verifyErr) && len(verifyErr.UnverifiedCertificates) > 0 {
+		return nil, &CertificateError{Host: host, Certificate: certificateInfo(verifyErr.UnverifiedCertificates[0]), Err: verifyErr.Err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		info := certificateInfo(resp.TLS.PeerCertificates[0])
+		if excepted {
+			info.Error = "Trusted by you despite failing validation."
+		}
+		certificates.mu.Lock()
+		certificates.seen[host] = info
+		certificates.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// exception returns the transport for host, trusting only the certificate
+// whose SHA-256 is fingerprint.
+func (t *certificateTransport) exception(host, fingerprint string) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := host + " " + fingerprint
+	if transport := t.exceptions[key]; transport != nil {
+		return transport
+	}
+	transport := t.base.Clone()
+	transport.TLSClientConfig = &tls.Config{
+		// Verified below instead, against the allowed certificate
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("tls: server sent no certificate")
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if hex.EncodeToString(sum[:]) != fingerprint {
+				return &tls.CertificateVerificationError{
+					UnverifiedCertificates: state.PeerCertificates,
+					Err:                    errors.New("the certificate changed since it was allowed"),
+				}
+			}
+			return nil
+		},
+	}
+	t.exceptions[key] = transport
+	return transport
+}
+
+// CloseIdleConnections closes the connections kept alive for reuse.
+func (t *certificateTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, transport := range t.exceptions {
+		transport.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetCertificates(t *testing.T) {
+	t.Cleanup(func() {
+		certificates.mu.Lock()
+		certificates.seen = make(map[string]CertificateInfo)
+		certificates.allowed = make(map[string]string)
+		certificates.mu.Unlock()
+		secureTransport.CloseIdleConnections()
+	})
+}
+
+func TestUntrustedCertificate(t *testing.T) {
+	resetCertificates(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secret page")
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	_, err := Get(server.URL)
+	var certErr *CertificateError
+	require.True(t, errors.As(err, &certErr), "got %v", err)
+	assert.Equal(t, host, certErr.Host)
+	assert.Equal(t, "The certificate is not issued by a trusted authority.", certErr.Reason())
+	assert.Len(t, certErr.Certificate.Fingerprint, 64)
+	assert.Contains(t, certErr.Certificate.Names, "127.0.0.1")
+	assert.Nil(t, CertificateFor(server.URL), "no page was loaded")
+
+	// Another certificate than the one allowed still fails
+	AllowCertificate(host, CertificateInfo{Fingerprint: "00"})
+	_, err = Get(server.URL)
+	require.True(t, errors.As(err, &certErr), "got %v", err)
+
+	AllowCertificate(host, certErr.Certificate)
+	resp, err := Get(server.URL)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "secret page", string(body))
+
+	info := CertificateFor(server.URL + "/other")
+	require.NotNil(t, info)
+	assert.False(t, info.Trusted())
+	assert.Equal(t, certErr.Certificate.Fingerprint, info.Fingerprint)
+	assert.Nil(t, CertificateFor(strings.Replace(server.URL, "https:", "http:", 1)))
+}
+
+func TestTrustedCertificate(t *testing.T) {
+	resetCertificates(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+	saved := networkTransport.TLSClientConfig
+	networkTransport.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	t.Cleanup(func() { networkTransport.TLSClientConfig = saved })
+
+	resp, err := Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	info := CertificateFor(server.URL)
+	require.NotNil(t, info)
+	assert.True(t, info.Trusted())
+	assert.Equal(t, "Acme Co", info.Issuer)
+	assert.Contains(t, info.Names, "example.com")
+	assert.True(t, info.NotAfter.After(info.NotBefore))
+}
@@ -2,7 +2,7 @@ package utils
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,7 +16,9 @@ func DoPost(url string, body string) {
 		return
 	}
 	req.Header.Set("Content-Type", "text/ping")
-	http.DefaultClient.Do(req)
+	if resp, err := Send(req); err == nil {
+		resp.Body.Close()
+	}
 }
 
 // HTTPRequest holds the parameters for DoRequest.
@@ -26,8 +28,13 @@ type HTTPRequest struct {
 	Body           []byte
 	ContentType    string
 	FormData       url.Values
-	ReferrerPolicy string
-	FromURL        string
+	ReferrerPolicy string // the requesting element's referrerpolicy, if any; see Referrer
+	FromURL        string // the page the request is made for, "" for none
+	Accept         string // the Accept header, if set
+	Navigation     bool   // the request loads a page rather than a subresource
+	// Context cancels the request, e.g. when the page it was made for is
+	// left; nil for none.
+	Context context.Context
 }
 
 // DoRequest performs an HTTP request (GET or POST).
@@ -40,85 +47,42 @@ func DoRequest(req HTTPRequest) (*http.Response, error) {
 	referrerPolicy := req.ReferrerPolicy
 	fromURL := req.FromURL
 
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
 	var httpReq *http.Request
 	var err error
 
 	if method == "POST" {
 		if body != nil && contentType != "" {
 			// Multipart form data (file upload)
-			httpReq, err = http.NewRequest("POST", pageURL, bytes.NewReader(body))
+			httpReq, err = http.NewRequestWithContext(ctx, "POST", pageURL, bytes.NewReader(body))
 			if err != nil {
 				return nil, err
 			}
 			httpReq.Header.Set("Content-Type", contentType)
 		} else {
-			httpReq, err = http.NewRequest("POST", pageURL, strings.NewReader(formData.Encode()))
+			httpReq, err = http.NewRequestWithContext(ctx, "POST", pageURL, strings.NewReader(formData.Encode()))
 			if err != nil {
 				return nil, err
 			}
 			httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		}
 	} else {
-		httpReq, err = http.NewRequest("GET", pageURL, nil)
+		httpReq, err = http.NewRequestWithContext(ctx, "GET", pageURL, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	parsed, err := url.Parse(fromURL)
-	if err == nil {
-		fmt.Println(parsed)
-		originURL := fmt.Sprintf("%s://%s", parsed.Scheme, parsed.Host)
-
-		isDowngrade := parsed.Scheme == "https" && httpReq.URL.Scheme == "http"
-		isSameOrigin := strings.EqualFold(parsed.Host, httpReq.URL.Host)
-
-		switch referrerPolicy {
-		case "no-referrer":
-			// Never send Referer
-		case "no-referrer-when-downgrade":
-			if !isDowngrade {
-				httpReq.Header.Set("Referer", fromURL)
-			}
-		case "origin":
-			httpReq.Header.Set("Referer", originURL)
-		case "origin-when-cross-origin":
-			if isSameOrigin {
-				httpReq.Header.Set("Referer", fromURL)
-			} else {
-				httpReq.Header.Set("Referer", originURL)
-			}
-		case "same-origin":
-			if isSameOrigin {
-				httpReq.Header.Set("Referer", fromURL)
-			}
-		case "strict-origin":
-			if !isDowngrade {
-				httpReq.Header.Set("Referer", originURL)
-			}
-		case "strict-origin-when-cross-origin":
-			if !isDowngrade {
-				if isSameOrigin {
-					httpReq.Header.Set("Referer", fromURL)
-				} else {
-					httpReq.Header.Set("Referer", originURL)
-				}
-			}
-		case "unsafe-url":
-			httpReq.Header.Set("Referer", fromURL)
-		default:
-			// Empty or unrecognized — browser default: strict-origin-when-cross-origin
-			if !isDowngrade {
-				if isSameOrigin {
-					httpReq.Header.Set("Referer", fromURL)
-				} else {
-					httpReq.Header.Set("Referer", originURL)
-				}
-			}
-		}
+	if req.Accept != "" {
+		httpReq.Header.Set("Accept", req.Accept)
 	}
 
-	return http.DefaultClient.Do(httpReq)
+	httpReq = withReferrer(httpReq, fromURL, referrerPolicy)
+	return Send(WithInitiator(httpReq, fromURL, req.Navigation))
 }
 
 // ParseHTMLSizeAttribute parses width/height attributes.